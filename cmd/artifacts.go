@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/pyama86/alterguard/internal/artifacts"
+	"github.com/pyama86/alterguard/internal/audit"
+	"github.com/pyama86/alterguard/internal/task"
+)
+
+// setupArtifacts は --artifacts-dir が指定されていれば成果物の記録を開始する。
+// GitHub Actions 上ではジョブサマリのため、指定がなくても結果をメモリ上に記録する。
+func setupArtifacts(command string, taskManager *task.Manager, start time.Time) (*artifacts.Recorder, error) {
+	if artifactsDir == "" {
+		if githubReporter == nil {
+			return nil, nil
+		}
+		recorder := artifacts.NewMemoryRecorder()
+		taskManager.SetArtifactsRecorder(recorder)
+		return recorder, nil
+	}
+
+	recorder, err := artifacts.NewRecorder(artifactsDir, command, start)
+	if err != nil {
+		return nil, err
+	}
+	taskManager.SetArtifactsRecorder(recorder)
+	logger.Infof("Writing run artifacts to %s", recorder.Dir())
+	return recorder, nil
+}
+
+// finishArtifacts は最終レポートを書き出す。書き出しの失敗で実行結果は変えない。
+// runIdentity はこの実行を始めた人で、CLI の実行では起動時に集めた identity を渡す
+func finishArtifacts(recorder *artifacts.Recorder, command string, runIdentity audit.Identity, start time.Time, runErr error) {
+	report := artifacts.Report{
+		Command:     command,
+		Operator:    runIdentity,
+		Environment: environment,
+		BatchHash:   batchHash,
+		DryRun:      dryRun,
+		StartedAt:   start,
+	}
+	if err := recorder.WriteReport(report, runErr); err != nil {
+		logger.Errorf("Failed to write run report: %v", err)
+	}
+
+	if githubReporter != nil {
+		if err := githubReporter.Finish(recorder.CompleteReport(report, runErr), recorder.Plan()); err != nil {
+			logger.Errorf("Failed to write GitHub Actions job summary: %v", err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var benchmarkRows int64
+
+var benchmarkCmd = &cobra.Command{
+	Use:   "benchmark [table_name]",
+	Short: "Measure the safe copy rate of a table before running pt-osc",
+	Long: `Copy a bounded sample of rows (default 1,000,000) from the table into a
+scratch table _table_name_bench, in primary key order and in chunks of
+pt_osc.chunk_size, the same way pt-online-schema-change copies rows.
+
+The copy rate and the replica lag it causes are measured, and suggested
+pt_osc.chunk_size and pt_osc.max_lag values are printed and sent to Slack.
+Replica lag is read from REPLICA_DSNS if set, otherwise from Aurora replicas
+when pt_osc.aurora_replica_check is enabled. Copying pauses while the lag is
+above pt_osc.max_lag, like pt-osc does. The scratch table is always dropped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return benchmarkTable(args[0])
+	},
+}
+
+func init() {
+	benchmarkCmd.Flags().Int64Var(&benchmarkRows, "rows", task.DefaultBenchmarkSampleRows, "Maximum number of rows to copy")
+	rootCmd.AddCommand(benchmarkCmd)
+}
+
+func benchmarkTable(tableName string) error {
+	logger.Infof("Starting copy benchmark for %s", tableName)
+
+	// Load configuration
+	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	// Initialize database client
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	// Initialize database clients for the replicas to measure lag
+	var replicas []task.RollingHost
+	for _, dsn := range cfg.ReplicaDSNs {
+		name := database.DescribeDSN(dsn)
+		replicaClient, err := database.NewMySQLClientWithConfig(dsn, logger, cfg.Common.Database)
+		if err != nil {
+			logger.Errorf("Failed to connect to replica %s: %v", name, err)
+			return fmt.Errorf("replica connection failed: %w", err)
+		}
+		defer func() {
+			if closeErr := replicaClient.Close(); closeErr != nil {
+				logger.Errorf("Failed to close replica connection: %v", closeErr)
+			}
+		}()
+		replicas = append(replicas, task.RollingHost{Name: name, DB: replicaClient})
+	}
+
+	logger.Info("Database connections established")
+
+	// Initialize pt-osc executor (not used for benchmark but required for manager)
+	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
+
+	// Initialize pt-archiver executor (not used for benchmark but required for manager)
+	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
+
+	// Initialize Slack notifier
+	slackNotifier, err := newNotifier(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize Slack notifier: %v", err)
+		return fmt.Errorf("slack notifier initialization failed: %w", err)
+	}
+
+	slackNotifier.SetOperator(identity.Summary())
+
+	logger.Info("Slack notifier initialized")
+
+	// Initialize task manager
+	taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, cfg, dryRun)
+
+	result, err := taskManager.BenchmarkTable(tableName, benchmarkRows, replicas)
+	if err != nil {
+		logger.Errorf("Benchmark failed: %v", err)
+		return fmt.Errorf("benchmark failed: %w", err)
+	}
+	if result != nil {
+		fmt.Printf("Copy benchmark for %s:\n%s\n", tableName, result.Summary())
+	}
+
+	logger.Info("Benchmark completed successfully")
+	return nil
+}
@@ -1,15 +1,16 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
-	"github.com/pyama86/alterguard/internal/config"
 	"github.com/pyama86/alterguard/internal/database"
 	"github.com/pyama86/alterguard/internal/ptarchiver"
 	"github.com/pyama86/alterguard/internal/ptosc"
-	"github.com/pyama86/alterguard/internal/slack"
 	"github.com/pyama86/alterguard/internal/task"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -19,7 +20,7 @@ var (
 )
 
 var cleanupCmd = &cobra.Command{
-	Use:   "cleanup [table_name]",
+	Use:   "cleanup [table_name | --from-tasks]",
 	Short: "Clean up backup tables and triggers",
 	Long: `Clean up resources created by pt-online-schema-change.
 
@@ -28,13 +29,16 @@ Available cleanup operations:
 - --drop-new-table: Drop the new table (_table_name_new)
 - --drop-triggers: Drop pt-osc triggers (pt_osc_table_name_*)
 
-At least one cleanup operation must be specified.`,
-	Args: cobra.ExactArgs(1),
+At least one cleanup operation must be specified.
+
+With --from-tasks, every table altered in the tasks file is cleaned up instead of
+a single table.`,
+	Args: tableArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !dropTable && !dropNewTable && !dropTriggers {
 			return fmt.Errorf("at least one cleanup operation must be specified (--drop-table, --drop-new-table, or --drop-triggers)")
 		}
-		return cleanupTable(args[0])
+		return cleanupTables(cmd.Flags(), args)
 	},
 }
 
@@ -42,21 +46,26 @@ func init() {
 	cleanupCmd.Flags().BoolVar(&dropTable, "drop-table", false, "Drop backup table")
 	cleanupCmd.Flags().BoolVar(&dropNewTable, "drop-new-table", false, "Drop new table")
 	cleanupCmd.Flags().BoolVar(&dropTriggers, "drop-triggers", false, "Drop pt-osc triggers")
+	addLockTimeoutFlags(cleanupCmd)
+	addFromTasksFlag(cleanupCmd)
+	addExecuteFlag(cleanupCmd)
 	rootCmd.AddCommand(cleanupCmd)
 }
 
-func cleanupTable(tableName string) error {
-	logger.Infof("Starting cleanup for %s", tableName)
-
+func cleanupTables(flags *pflag.FlagSet, args []string) error {
 	// Load configuration
-	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	cfg, err := loadConfigWithLockTimeouts(flags)
 	if err != nil {
-		logger.Errorf("Failed to load configuration: %v", err)
-		return fmt.Errorf("configuration load failed: %w", err)
+		return err
+	}
+
+	if _, err := applyExecuteMode(cfg.Common); err != nil {
+		logger.Errorf("Flag validation failed: %v", err)
+		return err
 	}
 
 	// Initialize database client
-	dbClient, err := database.NewMySQLClient(cfg.DSN, logger)
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
 	if err != nil {
 		logger.Errorf("Failed to connect to database: %v", err)
 		return fmt.Errorf("database connection failed: %w", err)
@@ -69,6 +78,20 @@ func cleanupTable(tableName string) error {
 
 	logger.Info("Database connection established")
 
+	// Initialize database clients for the replicas whose lag is reported
+	lagReplicas, closeLagReplicas, err := connectLagReplicas(cfg)
+	if err != nil {
+		logger.Errorf("Replica lag configuration is invalid: %v", err)
+		return err
+	}
+	defer closeLagReplicas()
+
+	// DROP TABLE / DROP TRIGGER もメタデータロックを待つため、swap と同じロック待ちタイムアウトを使う
+	if err := dbClient.SetSessionConfig(cfg.Common.SessionConfig.LockWaitTimeout, cfg.Common.SessionConfig.InnodbLockWaitTimeout); err != nil {
+		logger.Errorf("Failed to set session config: %v", err)
+		return fmt.Errorf("failed to set session config: %w", err)
+	}
+
 	// Initialize pt-osc executor (not used for cleanup but required for manager)
 	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
 
@@ -76,17 +99,59 @@ func cleanupTable(tableName string) error {
 	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
 
 	// Initialize Slack notifier
-	slackNotifier, err := slack.NewSlackNotifierWithEnvironment(logger, cfg.Environment)
+	slackNotifier, err := newNotifier(cfg)
 	if err != nil {
 		logger.Errorf("Failed to initialize Slack notifier: %v", err)
 		return fmt.Errorf("slack notifier initialization failed: %w", err)
 	}
 
+	slackNotifier.SetOperator(identity.Summary())
+
 	logger.Info("Slack notifier initialized")
 
 	// Initialize task manager
 	taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, cfg, dryRun)
+	taskManager.SetLagReplicas(lagReplicas)
+
+	tableNames, err := targetTables(taskManager, args, "_old")
+	if err != nil {
+		logger.Errorf("Failed to resolve target tables: %v", err)
+		return err
+	}
+
+	// Initialize run artifacts
+	start := time.Now()
+	recorder, err := setupArtifacts("cleanup", taskManager, start)
+	if err != nil {
+		logger.Errorf("Failed to initialize artifacts directory: %v", err)
+		return fmt.Errorf("artifacts initialization failed: %w", err)
+	}
+
+	// 1つのテーブルで失敗しても、残りのテーブルの後始末は続ける
+	var failed []string
+	var errs []error
+	for _, tableName := range tableNames {
+		logger.Infof("Starting cleanup for %s", tableName)
+		if err := runCleanupOperations(taskManager, tableName); err != nil {
+			failed = append(failed, tableName)
+			errs = append(errs, fmt.Errorf("%s: %w", tableName, err))
+			continue
+		}
+		logger.Infof("Cleanup completed successfully for %s", tableName)
+	}
+	err = errors.Join(errs...)
+	finishArtifacts(recorder, "cleanup", identity, start, err)
+	if err != nil {
+		if len(tableNames) > 1 {
+			logger.Errorf("Cleanup failed for %d of %d tables: %v", len(failed), len(tableNames), failed)
+		}
+		return err
+	}
+
+	return nil
+}
 
+func runCleanupOperations(taskManager *task.Manager, tableName string) error {
 	if dropTriggers {
 		logger.Infof("Dropping triggers for %s", tableName)
 		if err := taskManager.CleanupTriggers(tableName); err != nil {
@@ -115,6 +180,5 @@ func cleanupTable(tableName string) error {
 		logger.Infof("New table cleanup completed for %s", tableName)
 	}
 
-	logger.Infof("Cleanup completed successfully for %s", tableName)
 	return nil
 }
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/slack"
+	"github.com/sirupsen/logrus"
+)
+
+// exitCodeCrash は panic で異常終了したときの終了コード
+const exitCodeCrash = 4
+
+var (
+	// runID はこの実行を識別する ID。ログと異常終了の通知に載せる
+	runID string
+	// crashNotifier はコマンドが作った Notifier。panic したときの通知に使う
+	crashNotifier *slack.SlackNotifier
+)
+
+// newRunID は開始時刻と乱数から実行の ID を作る (例: 20250101-120000-1a2b3c4d)
+func newRunID(now time.Time) string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return now.Format("20060102-150405")
+	}
+	return fmt.Sprintf("%s-%s", now.Format("20060102-150405"), hex.EncodeToString(b))
+}
+
+// recoverCrash はコマンドの実行中の panic を捕まえ、スタックトレースと実行の ID をログに出して通知してから異常終了する。
+// 本番の実行中に不具合で落ちても、Pod と一緒に痕跡が消えないようにする
+func recoverCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := string(debug.Stack())
+
+	log := logger
+	if log == nil {
+		log = logrus.StandardLogger()
+	}
+	log.Errorf("alterguard crashed (run ID: %s): %v\n%s", runID, r, stack)
+
+	notifier := crashNotifier
+	if notifier == nil && !noNotify {
+		// 設定を読む前に落ちた場合は SLACK_WEBHOOK_URL にだけ通知する
+		fallback, err := slack.NewSlackNotifierWithEnvironment(log, environment)
+		if err == nil {
+			fallback.SetOperator(identity.Summary())
+			notifier = fallback
+		}
+	}
+	if notifier != nil {
+		if err := notifier.NotifyCrash(runID, fmt.Sprint(r), stack); err != nil {
+			log.Errorf("Failed to send crash notification: %v", err)
+		}
+	}
+	os.Exit(exitCodeCrash)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pyama86/alterguard/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var diffEnvironments []string
+
+var diffEnvCmd = &cobra.Command{
+	Use:   "diff-env",
+	Short: "Report statements applied in some environments but missing in others",
+	Long: `Compare which statements have been applied in each environment, using the
+run reports (report.json) under --artifacts-dir as the history.
+
+Statements are compared by a hash that ignores whitespace differences. Only
+successful, non dry-run tasks count as applied. Each environment lists the
+statements applied in another environment but not in it, such as a hotfix
+applied only in prod. Exits with an error when any statement is missing.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDiffEnv()
+	},
+}
+
+func init() {
+	diffEnvCmd.Flags().StringSliceVar(&diffEnvironments, "environments", nil, "Environments to compare, e.g. dev,qa,prod")
+	rootCmd.AddCommand(diffEnvCmd)
+	// diff-env は実行履歴だけを読み、データベースに接続しないため設定ファイルを必須にしない
+	diffEnvCmd.PersistentFlags().StringVar(&commonConfigPath, "common-config", "", "Path to common configuration file (not used)")
+}
+
+func runDiffEnv() error {
+	if artifactsDir == "" {
+		return fmt.Errorf("--artifacts-dir must point to the directory where run reports are stored")
+	}
+	if len(diffEnvironments) < 2 {
+		return fmt.Errorf("--environments must list at least two environments")
+	}
+
+	reports, err := history.LoadReports(artifactsDir)
+	if err != nil {
+		logger.Errorf("Failed to load run history: %v", err)
+		return err
+	}
+	logger.Infof("Loaded %d run reports from %s", len(reports), artifactsDir)
+
+	drifts := history.DiffEnvironments(reports, diffEnvironments)
+	fmt.Println(history.FormatDrift(drifts))
+
+	if missing := history.CountMissing(drifts); missing > 0 {
+		return fmt.Errorf("drift detected: %d statements missing across environments", missing)
+	}
+	return nil
+}
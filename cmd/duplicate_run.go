@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/history"
+)
+
+// batchHash は run で実行するクエリ全体のハッシュ。report.json に記録する
+var batchHash string
+
+// checkDuplicateRun は duplicate_run_window の間に同じ環境で同じバッチが成功しているか、まだ実行中なら実行を拒否する。
+// CI のパイプラインが二重に起動されたときに、同じ変更をもう一度流さないため。--force で無視できる。
+// 実行中の印を書いた場合は、run の終了時に呼んで印を消す関数を返す
+func checkDuplicateRun(cfg *config.Config) (func(), error) {
+	batchHash = history.BatchHash(cfg.Queries)
+	noop := func() {}
+
+	window := cfg.Common.DuplicateRunWindow
+	if window == "" || dryRun {
+		return noop, nil
+	}
+	duration, err := time.ParseDuration(window)
+	if err != nil || duration <= 0 {
+		return noop, fmt.Errorf("invalid duplicate_run_window %q (must be a positive duration such as 24h)", window)
+	}
+	if artifactsDir == "" {
+		return noop, fmt.Errorf("duplicate_run_window requires --artifacts-dir, where the run history is kept")
+	}
+	since := time.Now().Add(-duration)
+
+	reports, err := history.LoadReports(artifactsDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return noop, fmt.Errorf("failed to load run history: %w", err)
+	}
+	if runs := history.FindRecentRuns(reports, cfg.Environment, batchHash, since); len(runs) > 0 {
+		message := fmt.Sprintf("the same batch (hash %s) already ran successfully in environment %q at %s, within duplicate_run_window %s",
+			batchHash, cfg.Environment, runs[0].StartedAt.Format(time.RFC3339), window)
+		if !forceRun {
+			return noop, fmt.Errorf("%s; use --force to run it again", message)
+		}
+		logger.Warnf("%s; running again because of --force", message)
+	}
+
+	// 同時に起動された run はまだ report.json を書いていないため、開始時に印を書いて検出する
+	host, _ := os.Hostname()
+	marker := history.RunMarker{Environment: cfg.Environment, BatchHash: batchHash, StartedAt: time.Now(), Host: host, PID: os.Getpid()}
+	release, existing, err := history.AcquireRunMarker(artifactsDir, marker, since, forceRun)
+	if err != nil {
+		return noop, fmt.Errorf("failed to mark the run as started: %w", err)
+	}
+	if release == nil {
+		return noop, fmt.Errorf("the same batch (hash %s) is already running in environment %q since %s (host %s, pid %d); if that run died, remove %s or use --force",
+			batchHash, cfg.Environment, existing.StartedAt.Format(time.RFC3339), existing.Host, existing.PID,
+			history.MarkerPath(artifactsDir, cfg.Environment, batchHash))
+	}
+	if existing != nil {
+		logger.Warnf("Replacing the started marker of an unfinished run of the same batch from %s (host %s, pid %d)",
+			existing.StartedAt.Format(time.RFC3339), existing.Host, existing.PID)
+	}
+	return func() {
+		if err := release(); err != nil {
+			logger.Warnf("Failed to remove the started marker: %v", err)
+		}
+	}, nil
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var executeChanges bool
+
+// addExecuteFlag は require_execute_flag が有効なときに実際に変更するためのフラグを追加する
+func addExecuteFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&executeChanges, "execute", false, "Actually make changes when require_execute_flag is set in the common config (otherwise the command runs as --dry-run)")
+}
+
+// applyExecuteMode は require_execute_flag が有効で --execute がなければ dry-run に切り替える。
+// シェルの履歴から呼び出したコマンドで、意図せず本番を変更しないようにするため。
+// dry-run に切り替えた場合は true を返す
+func applyExecuteMode(common config.CommonConfig) (bool, error) {
+	if executeChanges && dryRun {
+		return false, fmt.Errorf("--execute cannot be combined with --dry-run")
+	}
+	if !common.RequireExecuteFlag || executeChanges || dryRun {
+		return false, nil
+	}
+	logger.Warn("require_execute_flag is set and --execute was not given; running in dry-run mode. Pass --execute to make changes")
+	dryRun = true
+	return true, nil
+}
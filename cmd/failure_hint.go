@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/pyama86/alterguard/internal/task"
+)
+
+// logFailureHint は失敗の種類に応じて、次にすべきことをログに出す
+func logFailureHint(err error) {
+	switch {
+	case errors.Is(err, task.ErrNewTableExists):
+		logger.Warn("A previous pt-osc run left its _new table behind. Check it, then remove it with `alterguard cleanup <table> --drop-triggers --drop-table` before rerunning")
+	case errors.Is(err, task.ErrConnectionCheckFailed):
+		logger.Warn("Other sessions of the same MySQL user were running. Rerun when they finish, or set connection_check.wait_timeout to wait for them")
+	case errors.Is(err, task.ErrRowCountMismatch):
+		logger.Warn("The table was not swapped. Compare the original and _new tables before swapping, or set swap_remediation to repair the difference with pt-table-sync")
+	case errors.Is(err, task.ErrPtOscFailed):
+		logger.Warn("pt-online-schema-change failed. Check its output logged above, and whether a _new table was left behind with `alterguard status <table>`")
+	}
+}
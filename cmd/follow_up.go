@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/followup"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/pttablesync"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var followUpCmd = &cobra.Command{
+	Use:   "follow-up [file]",
+	Short: "Execute the swap/cleanup steps generated by run --follow-up-file",
+	Long: `Execute the swap and cleanup steps written by "run --follow-up-file".
+
+The file lists the steps in order (swap first, then cleanup of triggers and
+the old table). Review and edit it before running; execution stops at the
+first failing step. The file must have been generated for the same
+--environment.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runFollowUp(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(followUpCmd)
+}
+
+func runFollowUp(path string) error {
+	logger.Infof("Starting follow-up steps from %s", path)
+
+	// Load follow-up steps
+	file, err := followup.Load(path)
+	if err != nil {
+		logger.Errorf("Failed to load follow-up file: %v", err)
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	if file.Environment != cfg.Environment {
+		return fmt.Errorf("follow-up file was generated for environment %q but the current environment is %q", file.Environment, cfg.Environment)
+	}
+
+	// Initialize database client
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	logger.Info("Database connection established")
+
+	// Initialize pt-osc executor (not used for follow-up but required for manager)
+	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
+
+	// Initialize pt-archiver executor
+	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
+
+	// Initialize Slack notifier
+	slackNotifier, err := newNotifier(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize Slack notifier: %v", err)
+		return fmt.Errorf("slack notifier initialization failed: %w", err)
+	}
+
+	slackNotifier.SetOperator(identity.Summary())
+
+	logger.Info("Slack notifier initialized")
+
+	// Initialize task manager
+	taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, cfg, dryRun)
+	taskManager.SetTableSyncExecutor(pttablesync.NewPtTableSyncExecutor(logger))
+
+	// Execute follow-up steps
+	if err := taskManager.ExecuteFollowUp(file); err != nil {
+		logger.Errorf("Follow-up failed: %v", err)
+		return fmt.Errorf("follow-up failed: %w", err)
+	}
+
+	logger.Infof("All %d follow-up steps completed", len(file.Steps))
+	return nil
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var fromTasks bool
+
+// addFromTasksFlag はテーブル名の代わりにタスクファイルの全テーブルを対象にするフラグを追加する
+func addFromTasksFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&fromTasks, "from-tasks", false, "Process every table altered in the tasks file (--tasks-config) instead of a single table")
+}
+
+// tableArgs はテーブル名の引数と --from-tasks のどちらか一方だけが指定されていることを確認する
+func tableArgs(cmd *cobra.Command, args []string) error {
+	if fromTasks {
+		if len(args) > 0 {
+			return fmt.Errorf("table_name cannot be given together with --from-tasks")
+		}
+		if len(tasksConfigPaths) == 0 {
+			return fmt.Errorf("--from-tasks requires --tasks-config")
+		}
+		return nil
+	}
+	return cobra.ExactArgs(1)(cmd, args)
+}
+
+// targetTables は処理するテーブルを返す。--from-tasks ならタスクファイルの ALTER 対象のテーブルのうち、
+// pt-osc が作った _<table><suffix> テーブルがあるものを実行順に返す。直接 ALTER したテーブルなどは飛ばしてログに残す
+func targetTables(taskManager *task.Manager, args []string, suffix string) ([]string, error) {
+	if !fromTasks {
+		return args, nil
+	}
+
+	tables, skipped, err := taskManager.ConfiguredTablesWithTable(suffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tables from tasks file: %w", err)
+	}
+	if len(tables) == 0 && len(skipped) == 0 {
+		return nil, fmt.Errorf("no ALTER TABLE statements found in tasks file %s", strings.Join(tasksConfigPaths, ", "))
+	}
+	if len(skipped) > 0 {
+		logger.Infof("Skipping %d tables from tasks file without a _<table>%s table: %v", len(skipped), suffix, skipped)
+	}
+	logger.Infof("Processing %d tables from tasks file: %v", len(tables), tables)
+	return tables, nil
+}
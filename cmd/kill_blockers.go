@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var killBlockersYes bool
+
+var killBlockersCmd = &cobra.Command{
+	Use:   "kill-blockers [table_name]",
+	Short: "Kill sessions holding metadata locks on a table",
+	Long: `List sessions that hold metadata locks on the table (and therefore block
+RENAME TABLE / ALTER TABLE on it) and kill them after confirmation.
+
+Sessions owned by users listed in kill_blockers.protected_users are never killed.
+A summary of killed, skipped and failed sessions is sent to Slack.
+
+Use --dry-run to only list the sessions, or --yes to skip the confirmation prompt.
+Requires performance_schema with the metadata lock instrument enabled.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return killBlockers(args[0])
+	},
+}
+
+func init() {
+	killBlockersCmd.Flags().BoolVarP(&killBlockersYes, "yes", "y", false, "Kill without asking for confirmation")
+	rootCmd.AddCommand(killBlockersCmd)
+}
+
+func killBlockers(tableName string) error {
+	logger.Infof("Looking for sessions blocking %s", tableName)
+
+	// Load configuration
+	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	// Initialize database client
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	logger.Info("Database connection established")
+
+	// Initialize pt-osc executor (not used for kill-blockers but required for manager)
+	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
+
+	// Initialize pt-archiver executor (not used for kill-blockers but required for manager)
+	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
+
+	// Initialize Slack notifier
+	slackNotifier, err := newNotifier(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize Slack notifier: %v", err)
+		return fmt.Errorf("slack notifier initialization failed: %w", err)
+	}
+
+	slackNotifier.SetOperator(identity.Summary())
+
+	logger.Info("Slack notifier initialized")
+
+	// Initialize task manager
+	taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, cfg, dryRun)
+
+	// Find blocking sessions
+	plan, err := taskManager.FindBlockers(tableName)
+	if err != nil {
+		logger.Errorf("Failed to find blocking sessions: %v", err)
+		return fmt.Errorf("failed to find blocking sessions: %w", err)
+	}
+
+	printBlockerPlan(plan)
+
+	if len(plan.Killable) == 0 {
+		logger.Infof("No killable sessions are blocking %s", tableName)
+		return nil
+	}
+
+	if dryRun {
+		logger.Info("Dry run: no sessions were killed")
+		return nil
+	}
+
+	if !killBlockersYes && !confirm(fmt.Sprintf("Kill %d session(s) blocking %s? [y/N]: ", len(plan.Killable), tableName)) {
+		logger.Info("Aborted by operator")
+		return nil
+	}
+
+	if err := taskManager.KillBlockers(plan); err != nil {
+		logger.Errorf("Kill blockers failed: %v", err)
+		return fmt.Errorf("kill blockers failed: %w", err)
+	}
+
+	logger.Infof("Killed %d session(s) blocking %s", len(plan.Killable), tableName)
+	return nil
+}
+
+func printBlockerPlan(plan *task.BlockerPlan) {
+	fmt.Printf("Sessions holding metadata locks on %s:\n", plan.TableName)
+	if len(plan.Killable) == 0 && len(plan.Protected) == 0 {
+		fmt.Println("  (none)")
+		return
+	}
+	for _, session := range plan.Killable {
+		fmt.Printf("  [kill] %s\n", formatBlockingSession(session))
+	}
+	for _, session := range plan.Protected {
+		fmt.Printf("  [skip] %s (protected user)\n", formatBlockingSession(session))
+	}
+}
+
+func formatBlockingSession(session database.BlockingSession) string {
+	line := fmt.Sprintf("id=%d user=%s host=%s command=%s time=%ds state=%q locks=%s",
+		session.ID, session.User, session.Host, session.Command, session.Time, session.State, session.LockTypes)
+	if session.Info != "" {
+		line += fmt.Sprintf(" query=%q", session.Info)
+	}
+	return line
+}
+
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/discord"
+	"github.com/pyama86/alterguard/internal/email"
+	"github.com/pyama86/alterguard/internal/mattermost"
+	"github.com/pyama86/alterguard/internal/slack"
+)
+
+// newNotifier は Slack の通知に、設定された他の通知先 (メールなど) を加えた Notifier を作る。
+// --no-notify か notifications.enabled: false のときは何も送らない Notifier を返す
+func newNotifier(cfg *config.Config) (*slack.SlackNotifier, error) {
+	if noNotify || !cfg.Common.Notifications.IsEnabled() {
+		logger.Info("Notifications are disabled by --no-notify or notifications.enabled, nothing will be sent")
+		crashNotifier = slack.NewDisabledNotifier(logger)
+		return crashNotifier, nil
+	}
+
+	notifier, err := slack.NewSlackNotifierWithEnvironment(logger, cfg.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	notifier.SetQueryRedaction(cfg.Common.Redaction.Policy())
+
+	smtpNotifier, err := email.NewSMTPNotifier(cfg.Common.Email, cfg.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("email notifier: %w", err)
+	}
+	if smtpNotifier != nil {
+		notifier.AddSink(smtpNotifier)
+		logger.Infof("Email notifications enabled via %s", cfg.Common.Email.Host)
+	}
+
+	for i, notifierConfig := range cfg.Common.Notifiers {
+		sink, err := newWebhookSink(notifierConfig, cfg.Environment)
+		if err != nil {
+			return nil, fmt.Errorf("notifiers[%d]: %w", i, err)
+		}
+		notifier.AddSink(sink)
+		logger.Infof("%s notifications enabled", notifierConfig.Type)
+	}
+	crashNotifier = notifier
+	return notifier, nil
+}
+
+// newWebhookSink は notifiers の1件から Webhook の通知先を作る
+func newWebhookSink(nc config.NotifierConfig, environment string) (slack.Sink, error) {
+	url := nc.WebhookURL
+	if nc.WebhookURLEnv != "" {
+		url = os.Getenv(nc.WebhookURLEnv)
+		if url == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", nc.WebhookURLEnv)
+		}
+	}
+	if url == "" {
+		return nil, fmt.Errorf("webhook_url or webhook_url_env is required")
+	}
+
+	switch nc.Type {
+	case "mattermost":
+		return mattermost.NewNotifier(url, nc.Username, environment), nil
+	case "discord":
+		return discord.NewNotifier(url, nc.Username, environment), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q (must be mattermost or discord)", nc.Type)
+	}
+}
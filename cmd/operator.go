@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/audit"
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/events"
+	kube "github.com/pyama86/alterguard/internal/operator"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/pttablesync"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var (
+	operatorNamespace string
+	operatorInterval  time.Duration
+)
+
+var operatorCmd = &cobra.Command{
+	Use:   "operator",
+	Short: "Run as a Kubernetes operator that reconciles SchemaChange resources",
+	Long: `Run inside a Kubernetes cluster and watch SchemaChange custom resources
+(alterguard.pyama86.github.io/v1alpha1) in a namespace.
+
+A SchemaChange whose spec.environment matches --environment is executed
+like "alterguard run" with spec.queries once a SchemaChangeApproval names it
+and its current metadata.generation. The
+phase, per-table progress, row counts and chosen methods are written back
+to the resource status. Schema changes run one at a time.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runOperator()
+	},
+}
+
+func init() {
+	operatorCmd.Flags().StringVar(&operatorNamespace, "namespace", "", "Namespace to watch (default: namespace of the Pod)")
+	operatorCmd.Flags().DurationVar(&operatorInterval, "interval", 30*time.Second, "Interval between reconciliations")
+	rootCmd.AddCommand(operatorCmd)
+}
+
+func runOperator() error {
+	logger.Info("Starting alterguard operator")
+
+	// Load configuration
+	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	// Initialize Kubernetes client
+	namespace := operatorNamespace
+	if namespace == "" {
+		namespace, err = kube.InClusterNamespace()
+		if err != nil {
+			return fmt.Errorf("--namespace is required outside a Pod: %w", err)
+		}
+	}
+	kubeClient, err := kube.NewInClusterClient(namespace)
+	if err != nil {
+		logger.Errorf("Failed to initialize Kubernetes client: %v", err)
+		return fmt.Errorf("kubernetes client initialization failed: %w", err)
+	}
+
+	// Initialize database client
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	logger.Info("Database connection established")
+
+	// Initialize pt-osc executor
+	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
+
+	// Initialize pt-archiver executor
+	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
+
+	// Initialize Slack notifier
+	slackNotifier, err := newNotifier(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize Slack notifier: %v", err)
+		return fmt.Errorf("slack notifier initialization failed: %w", err)
+	}
+
+	slackNotifier.SetOperator(identity.Summary())
+
+	logger.Info("Slack notifier initialized")
+
+	// Initialize event publisher
+	eventPublisher, err := events.NewPublisher(cfg.Common.Events)
+	if err != nil {
+		logger.Errorf("Failed to initialize event publisher: %v", err)
+		return fmt.Errorf("event publisher initialization failed: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reconciler := kube.NewReconciler(kubeClient, cfg.Environment, func(ctx context.Context, change kube.SchemaChange, approval kube.SchemaChangeApproval, progress func(task.RunProgress)) error {
+		runConfig := *cfg
+		runConfig.Queries = change.Spec.Queries
+
+		// SchemaChange ごとに task manager を作り、クエリと進捗の通知先を切り替える
+		// 通知とレポートには、どの SchemaChange による実行かを実行者と一緒に残す
+		changeIdentity := schemaChangeIdentity(namespace, change, approval)
+		slackNotifier.SetOperator(fmt.Sprintf("%s for SchemaChange %s/%s", identity.Summary(), namespace, change.Metadata.Name))
+		taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, &runConfig, dryRun || change.Spec.DryRun)
+		taskManager.SetCommandPrefix(followUpCommandPrefix())
+		taskManager.SetProgressFunc(progress)
+		taskManager.SetEventPublisher(eventPublisher)
+		taskManager.SetTableSyncExecutor(pttablesync.NewPtTableSyncExecutor(logger))
+
+		start := time.Now()
+		recorder, err := setupArtifacts("operator", taskManager, start)
+		if err != nil {
+			return fmt.Errorf("artifacts initialization failed: %w", err)
+		}
+		// operator の停止 (SIGTERM) で実行中のタスクも中断する
+		err = taskManager.ExecuteAllTasksContext(ctx)
+		finishArtifacts(recorder, "operator", changeIdentity, start, err)
+		return err
+	}, logger)
+
+	logger.Infof("Watching SchemaChange resources in namespace %s every %s", namespace, operatorInterval)
+	if err := reconciler.Run(ctx, operatorInterval); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	logger.Info("alterguard operator stopped")
+	return nil
+}
+
+// schemaChangeIdentity は operator の実行者に、実行する SchemaChange の名前と世代、承認を付け加える
+func schemaChangeIdentity(namespace string, change kube.SchemaChange, approval kube.SchemaChangeApproval) audit.Identity {
+	changeIdentity := identity
+	changeIdentity.CommandLine = fmt.Sprintf("%s (SchemaChange %s/%s, generation %d, approved by %s)",
+		identity.CommandLine, namespace, change.Metadata.Name, change.Metadata.Generation, kube.DescribeApproval(approval))
+	return changeIdentity
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/task"
+)
+
+// replicaLagDSN は replica_lag.replicas の1台の接続先を返す。dsn がなければ DATABASE_DSN のホストを host に置き換える
+func replicaLagDSN(cfg *config.Config, target config.ReplicaLagTarget) (string, error) {
+	if target.Name == "" {
+		return "", fmt.Errorf("replica_lag.replicas: name is required")
+	}
+	switch {
+	case target.DSN != "" && target.Host != "":
+		return "", fmt.Errorf("replica_lag.replicas %s: set either dsn or host, not both", target.Name)
+	case target.DSN != "":
+		return target.DSN, nil
+	case target.Host != "":
+		dsn, err := database.DeriveDSN(cfg.DSN, target.Host)
+		if err != nil {
+			return "", fmt.Errorf("replica_lag.replicas %s: failed to derive DSN from DATABASE_DSN: %w", target.Name, err)
+		}
+		return dsn, nil
+	default:
+		return "", fmt.Errorf("replica_lag.replicas %s: dsn or host is required", target.Name)
+	}
+}
+
+// connectLagReplicas は replica_lag.replicas のレプリカに接続する。遅延を表示するためだけなので、
+// 接続できないレプリカは警告して飛ばす。返り値の関数で接続を閉じる
+func connectLagReplicas(cfg *config.Config) ([]task.RollingHost, func(), error) {
+	var replicas []task.RollingHost
+	closeAll := func() {
+		for _, replica := range replicas {
+			if closeErr := replica.DB.Close(); closeErr != nil {
+				logger.Errorf("Failed to close replica connection: %v", closeErr)
+			}
+		}
+	}
+
+	for _, target := range cfg.Common.ReplicaLag.Replicas {
+		dsn, err := replicaLagDSN(cfg, target)
+		if err != nil {
+			closeAll()
+			return nil, func() {}, err
+		}
+		replicaClient, err := database.NewMySQLClientWithConfig(dsn, logger, cfg.Common.Database)
+		if err != nil {
+			logger.Warnf("Failed to connect to replica %s, its lag will not be reported: %v", target.Name, err)
+			continue
+		}
+		replicas = append(replicas, task.RollingHost{Name: target.Name, DB: replicaClient})
+	}
+	if len(replicas) > 0 {
+		logger.Infof("Reporting replica lag of %d replicas", len(replicas))
+	}
+	return replicas, closeAll, nil
+}
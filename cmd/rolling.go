@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var rollingCmd = &cobra.Command{
+	Use:   "rolling",
+	Short: "Apply schema changes host-by-host across replicas, then the primary",
+	Long: `Apply all queries directly (ALTER TABLE, without pt-online-schema-change) to each
+replica listed in the REPLICA_DSNS environment variable, one host at a time.
+
+After each replica, alterguard waits until its replication lag drops below
+rolling.max_lag_seconds before moving on. The primary (DATABASE_DSN) is changed last.
+
+By default statements are executed with sql_log_bin=0 so they are not replicated
+again; set rolling.enable_binlog to true to keep binary logging enabled.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRolling()
+	},
+}
+
+func init() {
+	rollingCmd.Flags().BoolVar(&useStdin, "stdin", false, "Read queries from standard input")
+	rootCmd.AddCommand(rollingCmd)
+}
+
+func runRolling() error {
+	logger.Info("Starting alterguard rolling command")
+
+	if err := validateFlags(); err != nil {
+		logger.Errorf("Flag validation failed: %v", err)
+		return err
+	}
+
+	// Load configuration
+	var cfg *config.Config
+	var err error
+
+	if useStdin {
+		cfg, err = config.LoadConfigWithStdinAndEnvironment(commonConfigPath, tasksConfigPaths, useStdin, environment)
+	} else {
+		cfg, err = config.LoadConfigWithEnvironment(commonConfigPath, tasksConfigPaths, environment)
+	}
+
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	if len(cfg.ReplicaDSNs) == 0 {
+		return fmt.Errorf("REPLICA_DSNS environment variable is not set")
+	}
+
+	logger.Infof("Loaded configuration with %d queries and %d replicas", len(cfg.Queries), len(cfg.ReplicaDSNs))
+
+	// Initialize database client for the primary
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	// Initialize database clients for the replicas
+	var replicas []task.RollingHost
+	for _, dsn := range cfg.ReplicaDSNs {
+		name := database.DescribeDSN(dsn)
+		replicaClient, err := database.NewMySQLClientWithConfig(dsn, logger, cfg.Common.Database)
+		if err != nil {
+			logger.Errorf("Failed to connect to replica %s: %v", name, err)
+			return fmt.Errorf("replica connection failed: %w", err)
+		}
+		defer func() {
+			if closeErr := replicaClient.Close(); closeErr != nil {
+				logger.Errorf("Failed to close replica connection: %v", closeErr)
+			}
+		}()
+		replicas = append(replicas, task.RollingHost{Name: name, DB: replicaClient})
+	}
+
+	logger.Info("Database connections established")
+
+	// Initialize pt-osc executor (not used for rolling but required for manager)
+	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
+
+	// Initialize pt-archiver executor (not used for rolling but required for manager)
+	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
+
+	// Initialize Slack notifier
+	slackNotifier, err := newNotifier(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize Slack notifier: %v", err)
+		return fmt.Errorf("slack notifier initialization failed: %w", err)
+	}
+
+	slackNotifier.SetOperator(identity.Summary())
+
+	logger.Info("Slack notifier initialized")
+
+	// Initialize task manager
+	taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, cfg, dryRun)
+
+	// SIGINT/SIGTERM でレプリカ遅延の待機をやめ、次のホストに進まない
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := taskManager.ExecuteRollingTasksContext(ctx, replicas); err != nil {
+		logger.Errorf("Rolling execution failed: %v", err)
+		return fmt.Errorf("rolling execution failed: %w", err)
+	}
+
+	logger.Info("Rolling execution completed successfully")
+	return nil
+}
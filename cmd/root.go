@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/pyama86/alterguard/internal/audit"
+	"github.com/pyama86/alterguard/internal/ghactions"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -43,11 +46,23 @@ func (f *JSTFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 
 var (
 	commonConfigPath string
-	tasksConfigPath  string
+	tasksConfigPaths []string
 	dryRun           bool
 	environment      string
+	artifactsDir     string
+	operator         string
+	identity         audit.Identity
 	logger           *logrus.Logger
+	githubReporter   *ghactions.Reporter
 	version          string
+	quiet            bool
+	verbose          bool
+	logLevel         string
+	syslogEnabled    bool
+	syslogFacility   string
+	syslogTag        string
+	syslogAddress    string
+	noNotify         bool
 )
 
 var rootCmd = &cobra.Command{
@@ -62,35 +77,118 @@ It supports:
 - Slack notifications for status updates
 - Kubernetes job execution
 - Dry run mode for testing`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		setupLogger()
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupLogger(); err != nil {
+			return err
+		}
+		identity = audit.Capture(operator, os.Args)
+		runID = newRunID(time.Now())
+		logger.Infof("Run ID: %s, operator: %s, command: %s", runID, identity.Summary(), identity.CommandLine)
+		return nil
 	},
 }
 
+// exitCodeError は 1 以外の終了コードで終了させたいエラー
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
 func Execute() {
+	defer recoverCrash()
 	err := rootCmd.Execute()
 	if err != nil {
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&commonConfigPath, "common-config", "", "Path to common configuration file (required)")
-	rootCmd.PersistentFlags().StringVar(&tasksConfigPath, "tasks-config", "", "Path to tasks configuration file (required unless --stdin is used)")
+	rootCmd.PersistentFlags().StringArrayVar(&tasksConfigPaths, "tasks-config", nil, "Path, https:// URL, or git::<repo>//<path>@<ref> of tasks configuration file (required unless --stdin is used). Repeat or use a glob to merge several files in order")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Force pt-osc to run in dry-run mode")
 	rootCmd.PersistentFlags().StringVarP(&environment, "environment", "e", "", "Environment name (e.g., dev, qa, prod)")
+	rootCmd.PersistentFlags().StringVar(&operator, "operator", "", "Name of the person running this command, recorded in logs and notifications (defaults to OPERATOR env)")
+	rootCmd.PersistentFlags().StringVar(&artifactsDir, "artifacts-dir", "", "Directory to write run artifacts (tool logs, task results, plan, report)")
+	rootCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Log errors only (overrides DEBUG env)")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log debug messages (overrides DEBUG env)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Log level: trace, debug, info, warn, error (overrides DEBUG env)")
+	rootCmd.PersistentFlags().BoolVar(&syslogEnabled, "syslog", false, "Also send logs to syslog")
+	rootCmd.PersistentFlags().StringVar(&syslogFacility, "syslog-facility", "local0", "Syslog facility (kern, user, daemon, auth, syslog, local0-local7)")
+	rootCmd.PersistentFlags().StringVar(&syslogTag, "syslog-tag", "alterguard", "Syslog tag")
+	rootCmd.PersistentFlags().StringVar(&syslogAddress, "syslog-address", "", "Remote syslog server as udp://host:port or tcp://host:port (default: local syslog)")
+	rootCmd.PersistentFlags().BoolVar(&noNotify, "no-notify", false, "Do not send Slack or other notifications, even if SLACK_WEBHOOK_URL is set")
 
 	if err := rootCmd.MarkPersistentFlagRequired("common-config"); err != nil {
 		logrus.Fatalf("Error marking common-config flag as required: %v", err)
 	}
 }
 
-func setupLogger() {
+func setupLogger() error {
+	level, err := resolveLogLevel()
+	if err != nil {
+		return err
+	}
+
 	logger = logrus.New()
 	logger.SetFormatter(&JSTFormatter{})
-	logger.SetLevel(logrus.InfoLevel)
+	logger.SetLevel(level)
 
-	if os.Getenv("DEBUG") == "true" {
-		logger.SetLevel(logrus.DebugLevel)
+	if syslogEnabled {
+		hook, err := newSyslogHook(syslogAddress, syslogFacility, syslogTag)
+		if err != nil {
+			return err
+		}
+		logger.AddHook(hook)
+	}
+
+	// GitHub Actions 上では警告をアノテーションとして出力し、終了時にジョブサマリを書き出す
+	githubReporter = ghactions.NewReporterFromEnv(os.Stdout)
+	if githubReporter != nil {
+		logger.AddHook(githubReporter)
+	}
+	return nil
+}
+
+// resolveLogLevel は --quiet / --verbose / --log-level からログレベルを決める。
+// どれも指定されていなければ DEBUG 環境変数に従う。フラグは同時に1つしか指定できない。
+func resolveLogLevel() (logrus.Level, error) {
+	specified := 0
+	for _, set := range []bool{quiet, verbose, logLevel != ""} {
+		if set {
+			specified++
+		}
+	}
+	if specified > 1 {
+		return 0, fmt.Errorf("--quiet, --verbose and --log-level cannot be combined")
+	}
+
+	switch {
+	case quiet:
+		return logrus.ErrorLevel, nil
+	case verbose:
+		return logrus.DebugLevel, nil
+	case logLevel != "":
+		switch logLevel {
+		case "trace", "debug", "info", "warn", "warning", "error":
+			return logrus.ParseLevel(logLevel)
+		default:
+			return 0, fmt.Errorf("invalid --log-level %q (must be trace, debug, info, warn or error)", logLevel)
+		}
+	case os.Getenv("DEBUG") == "true":
+		return logrus.DebugLevel, nil
+	default:
+		return logrus.InfoLevel, nil
 	}
 }
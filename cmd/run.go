@@ -1,19 +1,37 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/pyama86/alterguard/internal/approval"
 	"github.com/pyama86/alterguard/internal/config"
 	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/events"
 	"github.com/pyama86/alterguard/internal/ptarchiver"
 	"github.com/pyama86/alterguard/internal/ptosc"
-	"github.com/pyama86/alterguard/internal/slack"
+	"github.com/pyama86/alterguard/internal/pttablesync"
 	"github.com/pyama86/alterguard/internal/task"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
-	useStdin bool
+	useStdin         bool
+	followUpFile     string
+	planFile         string
+	fromPlanFile     string
+	planRowTolerance float64
+
+	ptOscThresholdOverride     int64
+	ptOscSizeThresholdOverride float64
+	forceMethod                string
+	forceRun                   bool
 )
 
 var runCmd = &cobra.Command{
@@ -26,41 +44,135 @@ Tasks with row count > threshold will be executed using pt-online-schema-change.
 
 If multiple tasks exceed the threshold, the command will fail with an error.
 
-Use --stdin flag to read queries from standard input instead of or in addition to the tasks file.`,
+Use --stdin flag to read queries from standard input instead of or in addition to the tasks file.
+
+With --dry-run --plan-file plan.json, the method chosen for each table and the
+exact pt-osc arguments are written to plan.json for review. run --from-plan
+plan.json then executes the queries in the plan, refusing to touch a table whose
+method, pt-osc arguments or definition changed, or whose row count moved by more
+than --plan-row-tolerance percent, since the plan was generated.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runTasks()
+		return runTasks(cmd.Flags())
 	},
 }
 
 func init() {
 	runCmd.Flags().BoolVar(&useStdin, "stdin", false, "Read queries from standard input")
+	runCmd.Flags().StringVar(&followUpFile, "follow-up-file", "", "Write the remaining swap/cleanup steps as YAML to this path (run them with the follow-up command)")
+	runCmd.Flags().StringVar(&planFile, "plan-file", "", "With --dry-run, write the planned methods and pt-osc arguments to this JSON file for approval")
+	runCmd.Flags().StringVar(&fromPlanFile, "from-plan", "", "Execute the queries of an approved plan file written by --plan-file")
+	runCmd.Flags().Float64Var(&planRowTolerance, "plan-row-tolerance", task.DefaultPlanRowTolerance, "Allowed change of row counts from the approved plan in percent")
+	runCmd.Flags().Int64Var(&ptOscThresholdOverride, "pt-osc-threshold", 0, "Override pt_osc_threshold (rows) for this run only")
+	runCmd.Flags().StringVar(&forceMethod, "force-method", "", "Skip the row count decision and change every table with ptosc or direct (overrides force_method)")
+	runCmd.Flags().BoolVar(&forceRun, "force", false, "Run even if the same batch already succeeded in this environment within duplicate_run_window")
+	runCmd.Flags().Float64Var(&ptOscSizeThresholdOverride, "pt-osc-size-threshold", 0, "Override pt_osc_size_threshold_mb (MB, 0 = disabled) for this run only")
+	addExecuteFlag(runCmd)
+	runCmd.Flags().StringVar(&runProfile, "profile", "", "Use the tasks file, flags and notification settings of this run_profiles entry in the common config")
 	rootCmd.AddCommand(runCmd)
 }
 
 func validateFlags() error {
-	if !useStdin && tasksConfigPath == "" {
+	if !useStdin && len(tasksConfigPaths) == 0 {
 		return fmt.Errorf("either --tasks-config or --stdin must be specified")
 	}
 	return nil
 }
 
-func runTasks() error {
+func validatePlanFlags() error {
+	if planFile != "" && !dryRun {
+		return fmt.Errorf("--plan-file requires --dry-run")
+	}
+	if fromPlanFile == "" {
+		return nil
+	}
+	if planFile != "" || dryRun {
+		return fmt.Errorf("--from-plan cannot be combined with --plan-file or --dry-run")
+	}
+	if useStdin {
+		return fmt.Errorf("--from-plan cannot be combined with --stdin; the queries are read from the plan")
+	}
+	return nil
+}
+
+// applyThresholdOverrides は --pt-osc-threshold / --pt-osc-size-threshold / --force-method を設定ファイルや環境変数より優先して反映し、
+// 通知に載せる閾値の変更内容を返す。--force-method は実行開始時に別途警告する
+func applyThresholdOverrides(flags *pflag.FlagSet, common *config.CommonConfig) ([]string, error) {
+	var overrides []string
+	if flags.Changed("pt-osc-threshold") {
+		if ptOscThresholdOverride < 0 {
+			return nil, fmt.Errorf("--pt-osc-threshold must not be negative")
+		}
+		overrides = append(overrides, fmt.Sprintf("pt_osc_threshold %d -> %d rows (--pt-osc-threshold)", common.PtOscThreshold, ptOscThresholdOverride))
+		common.PtOscThreshold = ptOscThresholdOverride
+	}
+	if flags.Changed("pt-osc-size-threshold") {
+		if ptOscSizeThresholdOverride < 0 {
+			return nil, fmt.Errorf("--pt-osc-size-threshold must not be negative")
+		}
+		overrides = append(overrides, fmt.Sprintf("pt_osc_size_threshold_mb %g -> %g MB (--pt-osc-size-threshold)", common.PtOscSizeThresholdMB, ptOscSizeThresholdOverride))
+		common.PtOscSizeThresholdMB = ptOscSizeThresholdOverride
+	}
+	if flags.Changed("force-method") {
+		if forceMethod != "ptosc" && forceMethod != "direct" {
+			return nil, fmt.Errorf("--force-method must be ptosc or direct")
+		}
+		common.ForceMethod = forceMethod
+	}
+	return overrides, nil
+}
+
+// followUpCommandPrefix は後続の swap/cleanup コマンドをそのまま貼り付けて実行できるよう、
+// 今回の実行と同じ設定ファイル・環境の指定を組み立てる
+func followUpCommandPrefix() string {
+	parts := []string{"alterguard"}
+	if commonConfigPath != "" {
+		parts = append(parts, "--common-config", commonConfigPath)
+	}
+	if environment != "" {
+		parts = append(parts, "--environment", environment)
+	}
+	return strings.Join(parts, " ")
+}
+
+func runTasks(flags *pflag.FlagSet) error {
 	logger.Info("Starting alterguard run command")
 
-	// Validate flags
-	if err := validateFlags(); err != nil {
-		logger.Errorf("Flag validation failed: %v", err)
+	profile, err := applyRunProfile(flags)
+	if err != nil {
+		logger.Errorf("Failed to apply run profile: %v", err)
 		return err
 	}
 
+	// Validate flags
+	if fromPlanFile == "" {
+		if err := validateFlags(); err != nil {
+			logger.Errorf("Flag validation failed: %v", err)
+			return err
+		}
+	}
+
 	// Load configuration
 	var cfg *config.Config
-	var err error
+	var approvedPlan *approval.Plan
 
-	if useStdin {
-		cfg, err = config.LoadConfigWithStdinAndEnvironment(commonConfigPath, tasksConfigPath, useStdin, environment)
+	if fromPlanFile != "" {
+		approvedPlan, err = approval.Load(fromPlanFile)
+		if err != nil {
+			logger.Errorf("Failed to load plan: %v", err)
+			return err
+		}
+		cfg, err = config.LoadConfigWithoutTasks(commonConfigPath, environment)
+		if err == nil {
+			if approvedPlan.Environment != cfg.Environment {
+				return fmt.Errorf("plan file was generated for environment %q but the current environment is %q", approvedPlan.Environment, cfg.Environment)
+			}
+			cfg.Queries = approvedPlan.Queries
+			cfg.Tasks = approvedPlan.Tasks
+		}
+	} else if useStdin {
+		cfg, err = config.LoadConfigWithStdinAndEnvironment(commonConfigPath, tasksConfigPaths, useStdin, environment)
 	} else {
-		cfg, err = config.LoadConfigWithEnvironment(commonConfigPath, tasksConfigPath, environment)
+		cfg, err = config.LoadConfigWithEnvironment(commonConfigPath, tasksConfigPaths, environment)
 	}
 
 	if err != nil {
@@ -70,8 +182,43 @@ func runTasks() error {
 
 	logger.Infof("Loaded configuration with %d queries", len(cfg.Queries))
 
+	// require_execute_flag で dry-run になるかどうかは設定を読むまで分からないため、プランのフラグはその後で確かめる
+	forcedDryRun, err := applyExecuteMode(cfg.Common)
+	if err != nil {
+		logger.Errorf("Flag validation failed: %v", err)
+		return err
+	}
+	if forcedDryRun && fromPlanFile != "" {
+		return fmt.Errorf("--from-plan executes an approved plan; pass --execute because require_execute_flag is set")
+	}
+	if err := validatePlanFlags(); err != nil {
+		logger.Errorf("Flag validation failed: %v", err)
+		return err
+	}
+
+	if err := applyRunProfileNotifications(profile, cfg); err != nil {
+		logger.Errorf("Failed to apply run profile: %v", err)
+		return err
+	}
+
+	thresholdOverrides, err := applyThresholdOverrides(flags, &cfg.Common)
+	if err != nil {
+		logger.Errorf("Flag validation failed: %v", err)
+		return err
+	}
+	for _, override := range thresholdOverrides {
+		logger.Infof("Threshold override: %s", override)
+	}
+
+	releaseRunMarker, err := checkDuplicateRun(cfg)
+	if err != nil {
+		logger.Errorf("Duplicate run check failed: %v", err)
+		return err
+	}
+	defer releaseRunMarker()
+
 	// Initialize database client
-	dbClient, err := database.NewMySQLClient(cfg.DSN, logger)
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
 	if err != nil {
 		logger.Errorf("Failed to connect to database: %v", err)
 		return fmt.Errorf("database connection failed: %w", err)
@@ -84,6 +231,14 @@ func runTasks() error {
 
 	logger.Info("Database connection established")
 
+	// Initialize database clients for the replicas whose lag is reported
+	lagReplicas, closeLagReplicas, err := connectLagReplicas(cfg)
+	if err != nil {
+		logger.Errorf("Replica lag configuration is invalid: %v", err)
+		return err
+	}
+	defer closeLagReplicas()
+
 	// Initialize pt-osc executor
 	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
 
@@ -91,24 +246,84 @@ func runTasks() error {
 	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
 
 	// Initialize Slack notifier
-	slackNotifier, err := slack.NewSlackNotifierWithEnvironment(logger, cfg.Environment)
+	slackNotifier, err := newNotifier(cfg)
 	if err != nil {
 		logger.Errorf("Failed to initialize Slack notifier: %v", err)
 		return fmt.Errorf("slack notifier initialization failed: %w", err)
 	}
 
+	slackNotifier.SetOperator(identity.Summary())
+
 	logger.Info("Slack notifier initialized")
 
+	// Initialize event publisher
+	eventPublisher, err := events.NewPublisher(cfg.Common.Events)
+	if err != nil {
+		logger.Errorf("Failed to initialize event publisher: %v", err)
+		return fmt.Errorf("event publisher initialization failed: %w", err)
+	}
+
 	// Initialize task manager
 	taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, cfg, dryRun)
+	taskManager.SetLagReplicas(lagReplicas)
+	taskManager.SetTableSyncExecutor(pttablesync.NewPtTableSyncExecutor(logger))
+	taskManager.SetCommandPrefix(followUpCommandPrefix())
+	taskManager.SetFollowUpPath(followUpFile)
+	taskManager.SetEventPublisher(eventPublisher)
+	taskManager.SetPlanFile(planFile)
+	taskManager.SetThresholdOverrides(thresholdOverrides)
+	if approvedPlan != nil {
+		taskManager.SetApprovedPlan(approvedPlan, planRowTolerance)
+	}
+
+	// Initialize run artifacts
+	start := time.Now()
+	recorder, err := setupArtifacts("run", taskManager, start)
+	if err != nil {
+		logger.Errorf("Failed to initialize artifacts directory: %v", err)
+		return fmt.Errorf("artifacts initialization failed: %w", err)
+	}
+
+	// SIGINT/SIGTERM で実行中の pt-osc を止め、タイムアウトと同じ後片付けを行う
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Execute all tasks
 	logger.Info("Starting task execution")
-	if err := taskManager.ExecuteAllTasks(); err != nil {
+	result, err := taskManager.ExecuteAllTasksWithResultContext(ctx)
+	finishArtifacts(recorder, "run", identity, start, err)
+	if result != nil {
+		fmt.Println(result.Format())
+	}
+	if dryRun {
+		return dryRunExitStatus(result, err)
+	}
+	if err != nil {
 		logger.Errorf("Task execution failed: %v", err)
+		logFailureHint(err)
 		return fmt.Errorf("task execution failed: %w", err)
 	}
 
 	logger.Info("All tasks completed successfully")
 	return nil
 }
+
+// dry-run の結果に応じた終了コード。CI で警告のない dry-run だけを通すために使う
+const (
+	exitCodeDryRunWarnings  = 2
+	exitCodeDryRunWouldFail = 3
+)
+
+// dryRunExitStatus は dry-run の結果を clean / warnings / would-fail に分け、clean 以外は専用の終了コードのエラーを返す
+func dryRunExitStatus(result *task.RunResult, err error) error {
+	if err != nil {
+		logger.Errorf("Dry run would fail: %v", err)
+		return &exitCodeError{code: exitCodeDryRunWouldFail, err: fmt.Errorf("dry run would fail: %w", err)}
+	}
+	if result == nil || result.DryRunStatus() == task.DryRunClean {
+		logger.Info("Dry run completed without problems")
+		return nil
+	}
+	logger.Warnf("Dry run completed with %d warnings", len(result.Warnings))
+	return &exitCodeError{code: exitCodeDryRunWarnings, err: fmt.Errorf("dry run found %d warnings", len(result.Warnings))}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/spf13/pflag"
+)
+
+var runProfile string
+
+// profileReservedFlags はプロファイルで指定できないフラグ。プロファイルを読む前に使われるか、プロファイルの選択そのものに関わる
+var profileReservedFlags = map[string]string{
+	"profile":         "profiles cannot select other profiles",
+	"common-config":   "the profile is read from the common config",
+	"environment":     "the profile is chosen after the environment",
+	"tasks-config":    "use tasks_config instead",
+	"operator":        "the operator is recorded before the profile is read",
+	"log-level":       "logging is set up before the profile is read",
+	"quiet":           "logging is set up before the profile is read",
+	"verbose":         "logging is set up before the profile is read",
+	"syslog":          "logging is set up before the profile is read",
+	"syslog-facility": "logging is set up before the profile is read",
+	"syslog-tag":      "logging is set up before the profile is read",
+	"syslog-address":  "logging is set up before the profile is read",
+}
+
+// applyRunProfile は --profile のプロファイルのタスクファイルとフラグを反映する。
+// コマンドラインで指定したフラグはプロファイルより優先する。--profile がなければ nil を返す
+func applyRunProfile(flags *pflag.FlagSet) (*config.RunProfile, error) {
+	if runProfile == "" {
+		return nil, nil
+	}
+
+	profile, err := config.LoadRunProfile(commonConfigPath, environment, runProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.TasksConfig != "" && !flags.Changed("tasks-config") {
+		tasksConfigPaths = []string{profile.TasksConfig}
+		logger.Infof("Run profile %s: tasks-config=%s", runProfile, profile.TasksConfig)
+	}
+
+	for _, name := range profile.SortedFlagNames() {
+		if reason, ok := profileReservedFlags[name]; ok {
+			return nil, fmt.Errorf("run profile %s: flag %q cannot be set by a profile (%s)", runProfile, name, reason)
+		}
+		if flags.Lookup(name) == nil {
+			return nil, fmt.Errorf("run profile %s: unknown flag %q", runProfile, name)
+		}
+		if flags.Changed(name) {
+			logger.Infof("Run profile %s: %s is set on the command line, ignoring the profile value", runProfile, name)
+			continue
+		}
+		value := profile.Flags[name]
+		if err := flags.Set(name, value); err != nil {
+			return nil, fmt.Errorf("run profile %s: invalid value %q for --%s: %w", runProfile, value, name, err)
+		}
+		logger.Infof("Run profile %s: %s=%s", runProfile, name, value)
+	}
+	return profile, nil
+}
+
+// applyRunProfileNotifications はプロファイルの通知先を設定に反映する。通知の初期化より前に呼ぶ
+func applyRunProfileNotifications(profile *config.RunProfile, cfg *config.Config) error {
+	if profile == nil {
+		return nil
+	}
+	if profile.SlackWebhookURLEnv != "" {
+		url := os.Getenv(profile.SlackWebhookURLEnv)
+		if url == "" {
+			return fmt.Errorf("run profile %s: environment variable %s is not set", runProfile, profile.SlackWebhookURLEnv)
+		}
+		// Slack の通知は送信のたびに SLACK_WEBHOOK_URL を読むため、この実行の間だけ置き換える
+		if err := os.Setenv("SLACK_WEBHOOK_URL", url); err != nil {
+			return fmt.Errorf("run profile %s: failed to set SLACK_WEBHOOK_URL: %w", runProfile, err)
+		}
+		logger.Infof("Run profile %s: Slack notifications go to the webhook in %s", runProfile, profile.SlackWebhookURLEnv)
+	}
+	if profile.Notifiers != nil {
+		cfg.Common.Notifiers = profile.Notifiers
+		logger.Infof("Run profile %s: using %d notifiers from the profile", runProfile, len(profile.Notifiers))
+	}
+	return nil
+}
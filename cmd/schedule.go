@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/events"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/pttablesync"
+	"github.com/pyama86/alterguard/internal/schedule"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Run recurring jobs defined in the schedule section",
+	Long: `Run as a long-lived process that executes the jobs listed under schedule.jobs
+on their cron expressions, e.g. nightly purges, deferred cleanups or weekly
+OPTIMIZE TABLE.
+
+Jobs run one at a time. Each run takes a MySQL named lock for the job, so
+when several schedule processes share a database only one of them executes
+a given job. Results are reported through the usual Slack notifications.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSchedule()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+}
+
+// buildScheduledJobs は設定を検証し、スケジューラのジョブに変換する
+func buildScheduledJobs(scheduleConfig config.ScheduleConfig) ([]schedule.Job, map[string]config.ScheduledJobConfig, *time.Location, error) {
+	if len(scheduleConfig.Jobs) == 0 {
+		return nil, nil, nil, fmt.Errorf("no jobs are defined in schedule.jobs")
+	}
+
+	location := time.Local
+	if scheduleConfig.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(scheduleConfig.Timezone)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid schedule.timezone %q: %w", scheduleConfig.Timezone, err)
+		}
+	}
+
+	jobs := make([]schedule.Job, 0, len(scheduleConfig.Jobs))
+	jobConfigs := make(map[string]config.ScheduledJobConfig, len(scheduleConfig.Jobs))
+	for _, jobConfig := range scheduleConfig.Jobs {
+		if err := task.ValidateScheduledJob(jobConfig); err != nil {
+			return nil, nil, nil, err
+		}
+		if _, exists := jobConfigs[jobConfig.Name]; exists {
+			return nil, nil, nil, fmt.Errorf("duplicate scheduled job name %q", jobConfig.Name)
+		}
+
+		expression, err := schedule.Parse(jobConfig.Cron)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("scheduled job %s: %w", jobConfig.Name, err)
+		}
+		var jitter time.Duration
+		if jobConfig.Jitter != "" {
+			jitter, err = time.ParseDuration(jobConfig.Jitter)
+			if err != nil || jitter < 0 {
+				return nil, nil, nil, fmt.Errorf("scheduled job %s: invalid jitter %q", jobConfig.Name, jobConfig.Jitter)
+			}
+		}
+
+		jobs = append(jobs, schedule.Job{Name: jobConfig.Name, Expression: expression, Jitter: jitter})
+		jobConfigs[jobConfig.Name] = jobConfig
+	}
+	return jobs, jobConfigs, location, nil
+}
+
+func runSchedule() error {
+	logger.Info("Starting alterguard schedule command")
+
+	// Load configuration
+	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	jobs, jobConfigs, location, err := buildScheduledJobs(cfg.Common.Schedule)
+	if err != nil {
+		logger.Errorf("Invalid schedule configuration: %v", err)
+		return err
+	}
+
+	// Initialize database client
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	logger.Info("Database connection established")
+
+	// Initialize database clients for the replicas whose lag is reported (and used by pt_archiver.adaptive)
+	lagReplicas, closeLagReplicas, err := connectLagReplicas(cfg)
+	if err != nil {
+		logger.Errorf("Replica lag configuration is invalid: %v", err)
+		return err
+	}
+	defer closeLagReplicas()
+
+	// Initialize pt-osc executor (not used for schedule but required for manager)
+	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
+
+	// Initialize pt-archiver executor
+	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
+
+	// Initialize Slack notifier
+	slackNotifier, err := newNotifier(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize Slack notifier: %v", err)
+		return fmt.Errorf("slack notifier initialization failed: %w", err)
+	}
+
+	slackNotifier.SetOperator(identity.Summary())
+
+	logger.Info("Slack notifier initialized")
+
+	// Initialize event publisher
+	eventPublisher, err := events.NewPublisher(cfg.Common.Events)
+	if err != nil {
+		logger.Errorf("Failed to initialize event publisher: %v", err)
+		return fmt.Errorf("event publisher initialization failed: %w", err)
+	}
+
+	// Initialize task manager
+	taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, cfg, dryRun)
+	taskManager.SetLagReplicas(lagReplicas)
+	taskManager.SetTableSyncExecutor(pttablesync.NewPtTableSyncExecutor(logger))
+	taskManager.SetEventPublisher(eventPublisher)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scheduler := schedule.NewScheduler(jobs, location, func(ctx context.Context, job schedule.Job) error {
+		return taskManager.RunScheduledJob(ctx, jobConfigs[job.Name])
+	}, logger)
+
+	if err := scheduler.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	logger.Info("alterguard schedule stopped")
+	return nil
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/selftest"
+	"github.com/pyama86/alterguard/internal/slack"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var (
+	selftestImage        string
+	selftestRows         int
+	selftestKeep         bool
+	selftestReadyTimeout time.Duration
+)
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Run an end-to-end migration against a throwaway MySQL container",
+	Long: `Start a disposable MySQL container with docker, then run a canned migration
+through alterguard: create and fill a table, pt-osc dry run, pt-osc with
+no_swap_tables, swap, and cleanup of the triggers and the _old table.
+Each step is reported as PASS/FAIL and the command fails if any step fails.
+
+Use it to validate a new alterguard image or Percona Toolkit version before
+using it in production. The pt_osc options from --common-config are used,
+except those that need replicas or would swap/drop tables on their own.
+DATABASE_DSN is not used and no Slack notifications are sent.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSelftest()
+	},
+}
+
+func init() {
+	selftestCmd.Flags().StringVar(&selftestImage, "image", "mysql:8.0", "MySQL image to start")
+	selftestCmd.Flags().IntVar(&selftestRows, "rows", 1000, "Number of rows to create in the test table")
+	selftestCmd.Flags().BoolVar(&selftestKeep, "keep", false, "Keep the container after the test for inspection")
+	selftestCmd.Flags().DurationVar(&selftestReadyTimeout, "ready-timeout", 3*time.Minute, "How long to wait for MySQL in the container to accept connections")
+	rootCmd.AddCommand(selftestCmd)
+}
+
+func runSelftest() error {
+	logger.Info("Starting alterguard selftest")
+
+	// Load configuration
+	common, err := config.LoadCommonConfig(commonConfigPath, environment)
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start MySQL container
+	docker, err := selftest.NewDocker()
+	if err != nil {
+		return err
+	}
+	container, err := docker.StartMySQL(ctx, selftestImage)
+	if err != nil {
+		logger.Errorf("Failed to start MySQL container: %v", err)
+		return fmt.Errorf("selftest container start failed: %w", err)
+	}
+	logger.Infof("Started MySQL container %s from %s", container.ID, selftestImage)
+	defer func() {
+		if selftestKeep {
+			logger.Infof("Keeping container %s (DSN: %s); remove it with: docker rm -f %s", container.ID, container.DSN, container.ID)
+			return
+		}
+		if err := docker.Remove(context.WithoutCancel(ctx), container.ID); err != nil {
+			logger.Errorf("Failed to remove container %s: %v", container.ID, err)
+		}
+	}()
+
+	// Initialize database client
+	dbClient, err := selftest.WaitForMySQL(ctx, container.DSN, common.Database, logger, selftestReadyTimeout)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	logger.Info("Database connection established")
+
+	cfg := &config.Config{
+		Common:      selftest.PrepareConfig(*common),
+		DSN:         container.DSN,
+		Environment: "selftest",
+	}
+
+	// Initialize pt-osc executor
+	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
+
+	// Initialize pt-archiver executor (not used for selftest but required for manager)
+	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
+
+	// Initialize Slack notifier (disabled for selftest)
+	slackNotifier := slack.NewDisabledNotifier(logger)
+
+	newManager := func(queries []string, dryRun bool) *task.Manager {
+		runConfig := *cfg
+		runConfig.Queries = queries
+		return task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, &runConfig, dryRun)
+	}
+
+	results := selftest.RunSteps(selftest.Scenario(dbClient, newManager, selftestRows), logger)
+	fmt.Println(selftest.FormatResults(results))
+	if !selftest.Passed(results) {
+		return fmt.Errorf("selftest failed")
+	}
+
+	logger.Info("alterguard selftest passed")
+	return nil
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/audit"
+	"github.com/pyama86/alterguard/internal/chatops"
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/events"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/pttablesync"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+const defaultChatOpsListenAddr = ":8080"
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve Slack slash commands for post-migration operations",
+	Long: `Start an HTTP server that handles Slack slash commands such as:
+
+  /alterguard swap users
+  /alterguard status orders
+
+Requests are verified with the SLACK_SIGNING_SECRET environment variable and are
+only accepted from channels listed in chatops.allowed_channels. Results of swap
+operations are reported through the usual Slack notifications.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serve()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+}
+
+// managerRunner はスラッシュコマンドの操作を task.Manager に委譲する。
+// 前のコマンドのキャッシュや状態を持ち越さないよう、コマンドごとに Manager を作り直す
+type managerRunner struct {
+	cfg                *config.Config
+	dbClient           database.Client
+	ptoscExecutor      ptosc.Executor
+	ptarchiverExecutor ptarchiver.Executor
+	eventPublisher     events.Publisher
+}
+
+// newManager は Slack でコマンドを実行したユーザーを実行者として通知する Manager を作る
+func (r *managerRunner) newManager(userName string, args ...string) (*task.Manager, error) {
+	commandIdentity := audit.Capture("slack:"+userName, append([]string{"/alterguard"}, args...))
+	logger.Infof("Slash command operator: %s, command: %s", commandIdentity.Summary(), commandIdentity.CommandLine)
+
+	slackNotifier, err := newNotifier(r.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("slack notifier initialization failed: %w", err)
+	}
+	slackNotifier.SetOperator(commandIdentity.Summary())
+
+	taskManager := task.NewManager(r.dbClient, r.ptoscExecutor, r.ptarchiverExecutor, slackNotifier, logger, r.cfg, dryRun)
+	taskManager.SetTableSyncExecutor(pttablesync.NewPtTableSyncExecutor(logger))
+	taskManager.SetEventPublisher(r.eventPublisher)
+	return taskManager, nil
+}
+
+func (r *managerRunner) Swap(userName, tableName string) error {
+	taskManager, err := r.newManager(userName, "swap", tableName)
+	if err != nil {
+		return err
+	}
+	return taskManager.SwapTable(tableName)
+}
+
+func (r *managerRunner) Status(userName, tableName string) (string, error) {
+	taskManager, err := r.newManager(userName, "status", tableName)
+	if err != nil {
+		return "", err
+	}
+	status, err := taskManager.GetTableStatus(tableName)
+	if err != nil {
+		return "", err
+	}
+	return status.String(), nil
+}
+
+func serve() error {
+	logger.Info("Starting alterguard serve command")
+
+	// Load configuration
+	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	// Initialize database client
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	logger.Info("Database connection established")
+
+	// Initialize pt-osc executor (not used for serve but required for manager)
+	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
+
+	// Initialize pt-archiver executor (not used for serve but required for manager)
+	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
+
+	// Initialize event publisher
+	eventPublisher, err := events.NewPublisher(cfg.Common.Events)
+	if err != nil {
+		logger.Errorf("Failed to initialize event publisher: %v", err)
+		return fmt.Errorf("event publisher initialization failed: %w", err)
+	}
+
+	// Slack notifier and task manager are created per command for the Slack user who ran it
+	runner := &managerRunner{
+		cfg:                cfg,
+		dbClient:           dbClient,
+		ptoscExecutor:      ptoscExecutor,
+		ptarchiverExecutor: ptarchiverExecutor,
+		eventPublisher:     eventPublisher,
+	}
+
+	// Initialize slash command handler
+	handler, err := chatops.NewHandler(os.Getenv("SLACK_SIGNING_SECRET"), cfg.Common.ChatOps.AllowedChannels, runner, logger)
+	if err != nil {
+		return fmt.Errorf("chatops initialization failed: %w", err)
+	}
+
+	listenAddr := cfg.Common.ChatOps.ListenAddr
+	if listenAddr == "" {
+		listenAddr = defaultChatOpsListenAddr
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/slack/commands", handler)
+	server := &http.Server{
+		Addr:              listenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Infof("Listening for Slack slash commands on %s", listenAddr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server failed: %w", err)
+		}
+	case <-ctx.Done():
+		logger.Info("Shutting down, waiting for running operations to finish")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("Failed to shut down server: %v", err)
+		}
+	}
+
+	handler.Wait()
+	logger.Info("alterguard serve stopped")
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/snapshot"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotOutput string
+	snapshotTables []string
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save SHOW CREATE TABLE of all (or listed) tables",
+	Long: `Save SHOW CREATE TABLE of every table in the database, or only of the tables
+given with --tables, so the schema before and after a migration window can be
+kept for audits and compared with diff-snapshot.
+
+If --output ends with .json, a single JSON document is written. Otherwise it
+is treated as a directory and snapshot.json plus one <table>.sql per table are
+written there.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return takeSnapshot()
+	},
+}
+
+var diffSnapshotCmd = &cobra.Command{
+	Use:   "diff-snapshot [before] [after]",
+	Short: "Compare two schema snapshots",
+	Long: `Compare two snapshots written by the snapshot command (JSON files or
+directories) and list the tables that were added, removed or changed, with the
+changed lines of their CREATE TABLE. AUTO_INCREMENT values are ignored.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return diffSnapshots(args[0], args[1])
+	},
+}
+
+func init() {
+	snapshotCmd.Flags().StringVar(&snapshotOutput, "output", "", "JSON file (*.json) or directory to write the snapshot to")
+	snapshotCmd.Flags().StringSliceVar(&snapshotTables, "tables", nil, "Tables to include (default: all tables)")
+	if err := snapshotCmd.MarkFlagRequired("output"); err != nil {
+		logrus.Fatalf("Error marking output flag as required: %v", err)
+	}
+	rootCmd.AddCommand(snapshotCmd)
+
+	rootCmd.AddCommand(diffSnapshotCmd)
+	// diff-snapshot はファイルだけを読み、データベースに接続しないため設定ファイルを必須にしない
+	diffSnapshotCmd.PersistentFlags().StringVar(&commonConfigPath, "common-config", "", "Path to common configuration file (not used)")
+}
+
+func takeSnapshot() error {
+	logger.Info("Starting alterguard snapshot command")
+
+	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	snap, err := snapshot.Take(dbClient, snapshotTables, time.Now())
+	if err != nil {
+		logger.Errorf("Failed to take snapshot: %v", err)
+		return err
+	}
+	snap.Environment = cfg.Environment
+	snap.Database = database.DescribeDSN(cfg.DSN)
+
+	if err := snapshot.Write(snapshotOutput, snap); err != nil {
+		logger.Errorf("Failed to write snapshot: %v", err)
+		return err
+	}
+	logger.Infof("Saved snapshot of %d tables to %s", len(snap.Tables), snapshotOutput)
+	return nil
+}
+
+func diffSnapshots(beforePath, afterPath string) error {
+	before, err := snapshot.Load(beforePath)
+	if err != nil {
+		return err
+	}
+	after, err := snapshot.Load(afterPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(snapshot.FormatDiff(before, after, snapshot.Diff(before, after)))
+	return nil
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/history"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var statusAll bool
+
+var statusCmd = &cobra.Command{
+	Use:   "status [table_name | --all]",
+	Short: "Show the state of a table's migration, including how far an interrupted pt-osc copy had gotten",
+	Long: `Show whether the table, _table_name_new and table_name_old exist and how many
+rows they have.
+
+With --artifacts-dir, the pt-osc copy progress recorded by the last run is
+shown as well: the percentage copied, the last chunk boundary (the last key
+in _table_name_new) and an estimate of how long a re-run would take. This
+is written every 30 seconds while pt-osc runs, so it survives a crash of
+alterguard or the host it ran on.
+
+With --all, every pt-osc running anywhere on the instance is listed instead,
+including tables outside the tasks file and other teams' migrations. Runs are
+detected from pt_osc_* triggers and from PROCESSLIST entries carrying pt-osc's
+query comment or referencing a _table_name_new table.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if statusAll {
+			if len(args) > 0 {
+				return fmt.Errorf("table_name cannot be given together with --all")
+			}
+			return nil
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if statusAll {
+			return showStatus("")
+		}
+		return showStatus(args[0])
+	},
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusAll, "all", false, "List every pt-osc running on the instance instead of showing a single table")
+	rootCmd.AddCommand(statusCmd)
+}
+
+// showStatus はテーブルの状態を表示する。tableName が空ならインスタンス全体で実行中の pt-osc を表示する
+func showStatus(tableName string) error {
+	if artifactsDir != "" && tableName != "" {
+		progress, runDir, err := history.LatestProgress(artifactsDir, tableName)
+		if err != nil {
+			logger.Errorf("Failed to load copy progress: %v", err)
+			return err
+		}
+		if progress == nil {
+			fmt.Printf("No copy progress recorded for %s under %s\n", tableName, artifactsDir)
+		} else {
+			fmt.Printf("Run: %s\n%s\n\n", runDir, task.FormatCopyProgress(progress))
+		}
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	// Initialize database client
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	slackNotifier, err := newNotifier(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize Slack notifier: %v", err)
+		return fmt.Errorf("slack notifier initialization failed: %w", err)
+	}
+
+	// Initialize task manager (pt-osc and pt-archiver are not used for status but required for manager)
+	taskManager := task.NewManager(dbClient, ptosc.NewPtOscExecutor(logger, dbClient), ptarchiver.NewPtArchiverExecutor(logger), slackNotifier, logger, cfg, dryRun)
+
+	if tableName == "" {
+		inventory, err := taskManager.GetPtOscInventory()
+		if err != nil {
+			logger.Errorf("Failed to list running pt-osc operations: %v", err)
+			return err
+		}
+		fmt.Println(inventory.String())
+		return nil
+	}
+
+	status, err := taskManager.GetTableStatus(tableName)
+	if err != nil {
+		logger.Errorf("Failed to get table status: %v", err)
+		return err
+	}
+	fmt.Println(status.String())
+	return nil
+}
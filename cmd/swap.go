@@ -2,18 +2,19 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
-	"github.com/pyama86/alterguard/internal/config"
 	"github.com/pyama86/alterguard/internal/database"
 	"github.com/pyama86/alterguard/internal/ptarchiver"
 	"github.com/pyama86/alterguard/internal/ptosc"
-	"github.com/pyama86/alterguard/internal/slack"
+	"github.com/pyama86/alterguard/internal/pttablesync"
 	"github.com/pyama86/alterguard/internal/task"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var swapCmd = &cobra.Command{
-	Use:   "swap [table_name]",
+	Use:   "swap [table_name | --from-tasks]",
 	Short: "Swap backup table with original table",
 	Long: `Swap the backup table created by pt-online-schema-change with the original table.
 
@@ -21,29 +22,37 @@ This command performs a RENAME TABLE operation to swap:
 - original_table -> original_table_old
 - _original_table_new -> original_table
 
-It also monitors for metadata locks and sends warnings if they exceed the configured threshold.`,
-	Args: cobra.ExactArgs(1),
+It also monitors for metadata locks and sends warnings if they exceed the configured threshold.
+
+With --from-tasks, every table altered in the tasks file is swapped in the order of
+the tasks file. The swap stops at the first table that fails.`,
+	Args: tableArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return swapTable(args[0])
+		return swapTables(cmd.Flags(), args)
 	},
 }
 
 func init() {
+	addLockTimeoutFlags(swapCmd)
+	addFromTasksFlag(swapCmd)
+	addExecuteFlag(swapCmd)
 	rootCmd.AddCommand(swapCmd)
 }
 
-func swapTable(tableName string) error {
-	logger.Infof("Starting table swap for %s", tableName)
-
+func swapTables(flags *pflag.FlagSet, args []string) error {
 	// Load configuration
-	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	cfg, err := loadConfigWithLockTimeouts(flags)
 	if err != nil {
-		logger.Errorf("Failed to load configuration: %v", err)
-		return fmt.Errorf("configuration load failed: %w", err)
+		return err
+	}
+
+	if _, err := applyExecuteMode(cfg.Common); err != nil {
+		logger.Errorf("Flag validation failed: %v", err)
+		return err
 	}
 
 	// Initialize database client
-	dbClient, err := database.NewMySQLClient(cfg.DSN, logger)
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
 	if err != nil {
 		logger.Errorf("Failed to connect to database: %v", err)
 		return fmt.Errorf("database connection failed: %w", err)
@@ -56,6 +65,14 @@ func swapTable(tableName string) error {
 
 	logger.Info("Database connection established")
 
+	// Initialize database clients for the replicas whose lag is reported
+	lagReplicas, closeLagReplicas, err := connectLagReplicas(cfg)
+	if err != nil {
+		logger.Errorf("Replica lag configuration is invalid: %v", err)
+		return err
+	}
+	defer closeLagReplicas()
+
 	// Initialize pt-osc executor (not used for swap but required for manager)
 	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
 
@@ -63,24 +80,54 @@ func swapTable(tableName string) error {
 	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
 
 	// Initialize Slack notifier
-	slackNotifier, err := slack.NewSlackNotifierWithEnvironment(logger, cfg.Environment)
+	slackNotifier, err := newNotifier(cfg)
 	if err != nil {
 		logger.Errorf("Failed to initialize Slack notifier: %v", err)
 		return fmt.Errorf("slack notifier initialization failed: %w", err)
 	}
 
+	slackNotifier.SetOperator(identity.Summary())
+
 	logger.Info("Slack notifier initialized")
 
 	// Initialize task manager
 	taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, cfg, dryRun)
+	taskManager.SetLagReplicas(lagReplicas)
+	taskManager.SetTableSyncExecutor(pttablesync.NewPtTableSyncExecutor(logger))
 
-	// Execute table swap
-	logger.Infof("Starting table swap for %s", tableName)
-	if err := taskManager.SwapTable(tableName); err != nil {
-		logger.Errorf("Table swap failed: %v", err)
-		return fmt.Errorf("table swap failed: %w", err)
+	tableNames, err := targetTables(taskManager, args, "_new")
+	if err != nil {
+		logger.Errorf("Failed to resolve target tables: %v", err)
+		return err
 	}
 
-	logger.Infof("Table swap completed successfully for %s", tableName)
+	// Initialize run artifacts
+	start := time.Now()
+	recorder, err := setupArtifacts("swap", taskManager, start)
+	if err != nil {
+		logger.Errorf("Failed to initialize artifacts directory: %v", err)
+		return fmt.Errorf("artifacts initialization failed: %w", err)
+	}
+
+	// Execute table swap
+	err = swapEach(taskManager, tableNames)
+	finishArtifacts(recorder, "swap", identity, start, err)
+	return err
+}
+
+// swapEach は tableNames を順に swap する。
+// 途中のテーブルで失敗したら、新旧のテーブルが混在した状態を広げないようにそこで止める
+func swapEach(taskManager *task.Manager, tableNames []string) error {
+	for i, tableName := range tableNames {
+		logger.Infof("Starting table swap for %s", tableName)
+		if err := taskManager.SwapTable(tableName); err != nil {
+			logger.Errorf("Table swap failed: %v", err)
+			if remaining := tableNames[i+1:]; len(remaining) > 0 {
+				logger.Errorf("Tables not swapped: %v", remaining)
+			}
+			return fmt.Errorf("table swap failed for %s: %w", tableName, err)
+		}
+		logger.Infof("Table swap completed successfully for %s", tableName)
+	}
 	return nil
 }
@@ -0,0 +1,83 @@
+//go:build !windows && !plan9
+
+package cmd
+
+import (
+	"fmt"
+	"log/syslog"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern":   syslog.LOG_KERN,
+	"user":   syslog.LOG_USER,
+	"daemon": syslog.LOG_DAEMON,
+	"auth":   syslog.LOG_AUTH,
+	"syslog": syslog.LOG_SYSLOG,
+	"local0": syslog.LOG_LOCAL0,
+	"local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4,
+	"local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6,
+	"local7": syslog.LOG_LOCAL7,
+}
+
+// syslogHook はログを標準出力に加えて syslog にも送る logrus のフック
+type syslogHook struct {
+	writer *syslog.Writer
+}
+
+// newSyslogHook は facility と tag を指定して syslog に接続する。
+// address が空ならローカルの syslog (journald が動いていれば journald) に、
+// udp://host:514 や tcp://host:514 ならそのサーバーに送る。
+func newSyslogHook(address, facility, tag string) (logrus.Hook, error) {
+	priority, ok := syslogFacilities[strings.ToLower(facility)]
+	if !ok {
+		return nil, fmt.Errorf("invalid --syslog-facility %q", facility)
+	}
+
+	var network, raddr string
+	if address != "" {
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" {
+			return nil, fmt.Errorf("invalid --syslog-address %q (expected udp://host:port or tcp://host:port)", address)
+		}
+		network, raddr = u.Scheme, u.Host
+	}
+
+	writer, err := syslog.Dial(network, raddr, priority|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &syslogHook{writer: writer}, nil
+}
+
+func (h *syslogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire はログレベルを syslog の重要度に対応させて送る。時刻は syslog 側で付くため含めない。
+func (h *syslogHook) Fire(entry *logrus.Entry) error {
+	message := entry.Message
+	for key, value := range entry.Data {
+		message += fmt.Sprintf(" %s=%v", key, value)
+	}
+
+	switch entry.Level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return h.writer.Crit(message)
+	case logrus.ErrorLevel:
+		return h.writer.Err(message)
+	case logrus.WarnLevel:
+		return h.writer.Warning(message)
+	case logrus.InfoLevel:
+		return h.writer.Info(message)
+	default:
+		return h.writer.Debug(message)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newSyslogHook(address, facility, tag string) (logrus.Hook, error) {
+	return nil, fmt.Errorf("syslog output is not supported on this platform")
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var shadowSchema string
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate all queries against empty copies of the target tables",
+	Long: `Create a scratch schema, copy the structure (no data) of every table the tasks
+touch into it with CREATE TABLE ... LIKE, and execute every query there first.
+
+Syntax errors and changes the server rejects (unknown columns, duplicate
+names, incompatible type changes, ...) are caught before any production table
+is touched. The result is reported per statement and the command fails if any
+statement fails. The scratch schema is dropped at the end.
+
+The scratch schema must not exist yet. Statements that are not about a table,
+or that use schema-qualified table names, are skipped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return validateTasks()
+	},
+}
+
+func init() {
+	validateCmd.Flags().BoolVar(&useStdin, "stdin", false, "Read queries from standard input")
+	validateCmd.Flags().StringVar(&shadowSchema, "shadow-schema", task.DefaultShadowSchema, "Name of the scratch schema to create for validation")
+	rootCmd.AddCommand(validateCmd)
+}
+
+func validateTasks() error {
+	logger.Info("Starting alterguard validate command")
+
+	// Validate flags
+	if err := validateFlags(); err != nil {
+		logger.Errorf("Flag validation failed: %v", err)
+		return err
+	}
+
+	// Load configuration
+	var cfg *config.Config
+	var err error
+
+	if useStdin {
+		cfg, err = config.LoadConfigWithStdinAndEnvironment(commonConfigPath, tasksConfigPaths, useStdin, environment)
+	} else {
+		cfg, err = config.LoadConfigWithEnvironment(commonConfigPath, tasksConfigPaths, environment)
+	}
+
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	// Initialize database client
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	logger.Info("Database connection established")
+
+	// Initialize pt-osc executor (not used for validate but required for manager)
+	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
+
+	// Initialize pt-archiver executor (not used for validate but required for manager)
+	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
+
+	// Initialize Slack notifier
+	slackNotifier, err := newNotifier(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize Slack notifier: %v", err)
+		return fmt.Errorf("slack notifier initialization failed: %w", err)
+	}
+
+	slackNotifier.SetOperator(identity.Summary())
+
+	logger.Info("Slack notifier initialized")
+
+	// Initialize task manager
+	taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, cfg, dryRun)
+
+	results, err := taskManager.ValidateInShadowSchema(shadowSchema)
+	if err != nil {
+		logger.Errorf("Shadow validation failed: %v", err)
+		return fmt.Errorf("shadow validation failed: %w", err)
+	}
+
+	fmt.Println(task.FormatShadowValidation(results))
+	if !task.ShadowValidationPassed(results) {
+		return fmt.Errorf("shadow validation found failing statements")
+	}
+
+	logger.Info("Shadow validation completed successfully")
+	return nil
+}
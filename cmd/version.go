@@ -26,6 +26,6 @@ func init() {
 	rootCmd.AddCommand(versionCmd)
 	// versionコマンドでは必須フラグを無効にする
 	versionCmd.PersistentFlags().StringVar(&commonConfigPath, "common-config", "", "Path to common configuration file")
-	versionCmd.PersistentFlags().StringVar(&tasksConfigPath, "tasks-config", "", "Path to tasks configuration file")
+	versionCmd.PersistentFlags().StringArrayVar(&tasksConfigPaths, "tasks-config", nil, "Path to tasks configuration file")
 	versionCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Force pt-osc to run in dry-run mode")
 }
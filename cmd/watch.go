@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var watchInterval time.Duration
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [table_name]",
+	Short: "Attach to an already-running migration and report its progress",
+	Long: `Attach to a pt-online-schema-change that was started elsewhere (another
+terminal, a CI job, a previous alterguard run) and report its progress.
+
+The migration is detected from pt-osc triggers on the table, or from the
+_table_name_new table being written by a running process. Progress is read
+from the database side (row count and size of _table_name_new) and sent to
+Slack every --interval until the triggers are gone.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return watchTable(args[0])
+	},
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", time.Minute, "Interval between progress notifications")
+	rootCmd.AddCommand(watchCmd)
+}
+
+func watchTable(tableName string) error {
+	logger.Infof("Starting watch for %s", tableName)
+
+	// Load configuration
+	cfg, err := config.LoadConfigWithoutTasks(commonConfigPath, environment)
+	if err != nil {
+		logger.Errorf("Failed to load configuration: %v", err)
+		return fmt.Errorf("configuration load failed: %w", err)
+	}
+
+	// Initialize database client
+	dbClient, err := database.NewMySQLClientWithConfig(cfg.DSN, logger, cfg.Common.Database)
+	if err != nil {
+		logger.Errorf("Failed to connect to database: %v", err)
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			logger.Errorf("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	logger.Info("Database connection established")
+
+	// Initialize pt-osc executor (not used for watch but required for manager)
+	ptoscExecutor := ptosc.NewPtOscExecutor(logger, dbClient)
+
+	// Initialize pt-archiver executor (not used for watch but required for manager)
+	ptarchiverExecutor := ptarchiver.NewPtArchiverExecutor(logger)
+
+	// Initialize Slack notifier
+	slackNotifier, err := newNotifier(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize Slack notifier: %v", err)
+		return fmt.Errorf("slack notifier initialization failed: %w", err)
+	}
+
+	slackNotifier.SetOperator(identity.Summary())
+
+	logger.Info("Slack notifier initialized")
+
+	// Initialize task manager
+	taskManager := task.NewManager(dbClient, ptoscExecutor, ptarchiverExecutor, slackNotifier, logger, cfg, dryRun)
+
+	// Watch until the migration finishes or the command is interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := taskManager.WatchMigration(ctx, tableName, watchInterval); err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Infof("Watch for %s interrupted", tableName)
+			return nil
+		}
+		logger.Errorf("Watch failed: %v", err)
+		return fmt.Errorf("watch failed: %w", err)
+	}
+
+	logger.Infof("Watch completed for %s", tableName)
+	return nil
+}
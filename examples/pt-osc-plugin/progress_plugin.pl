@@ -0,0 +1,111 @@
+package pt_online_schema_change_plugin;
+
+# alterguard に行コピーの進み具合を送る pt-online-schema-change のプラグイン。
+#
+#   pt_osc:
+#     plugin:
+#       path: /etc/alterguard/progress_plugin.pl
+#
+# alterguard は ALTERGUARD_PROGRESS_SOCKET の Unix ドメインソケットで待ち受けており、
+# このプラグインはイベントを1行1つの JSON で送る。
+# ソケットに繋がらなくても pt-osc の実行は止めない。
+
+use strict;
+use warnings FATAL => 'all';
+use IO::Socket::UNIX;
+use Socket qw(SOCK_STREAM);
+
+sub new {
+   my ($class, %args) = @_;
+   my $self = {
+      %args,
+      chunks => 0,
+   };
+   return bless $self, $class;
+}
+
+sub init {
+   my ($self, %args) = @_;
+   my $path = $ENV{ALTERGUARD_PROGRESS_SOCKET};
+   return unless $path;
+
+   my $sock = IO::Socket::UNIX->new(
+      Type => SOCK_STREAM(),
+      Peer => $path,
+   );
+   if ( !$sock ) {
+      warn "alterguard progress plugin: cannot connect to $path: $!\n";
+      return;
+   }
+   $sock->autoflush(1);
+   $self->{sock} = $sock;
+   return;
+}
+
+sub before_copy_rows {
+   my ($self, %args) = @_;
+   $self->_send('copy_start');
+   return;
+}
+
+# --chunk-size / --chunk-time の1チャンクをコピーするたびに呼ばれる
+sub on_copy_rows_after_nibble {
+   my ($self, %args) = @_;
+   my $tbl = $args{tbl} || {};
+   $self->{chunks}++;
+   $self->_send(
+      'chunk',
+      chunk   => $self->{chunks},
+      rows    => $tbl->{row_cnt}     || 0,
+      seconds => $tbl->{nibble_time} || 0,
+   );
+   return;
+}
+
+sub after_copy_rows {
+   my ($self, %args) = @_;
+   $self->_send('copy_end');
+   return;
+}
+
+sub before_swap_tables {
+   my ($self, %args) = @_;
+   $self->_send('swap_start');
+   return;
+}
+
+sub after_swap_tables {
+   my ($self, %args) = @_;
+   $self->_send('swap_end');
+   return;
+}
+
+sub before_exit {
+   my ($self, %args) = @_;
+   if ( $self->{sock} ) {
+      close $self->{sock};
+      delete $self->{sock};
+   }
+   return;
+}
+
+sub _send {
+   my ($self, $event, %fields) = @_;
+   my $sock = $self->{sock};
+   return unless $sock;
+
+   my $line = qq({"event":"$event");
+   foreach my $key ( sort keys %fields ) {
+      $line .= sprintf(',"%s":%s', $key, $fields{$key} + 0);
+   }
+   $line .= "}\n";
+
+   # alterguard が先に終わっていても pt-osc は続ける
+   local $SIG{PIPE} = 'IGNORE';
+   if ( !print {$sock} $line ) {
+      delete $self->{sock};
+   }
+   return;
+}
+
+1;
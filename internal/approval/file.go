@@ -0,0 +1,76 @@
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+)
+
+// Table は承認対象となる1テーブル分の変更と、計画を作った時点のテーブルの状態
+type Table struct {
+	TableName  string   `json:"table_name"`
+	Method     string   `json:"method"`
+	AlterParts []string `json:"alter_parts"`
+	RowCount   int64    `json:"row_count"`
+	// AUTO_INCREMENT の値を除いた SHOW CREATE TABLE の SHA-256
+	SchemaFingerprint string `json:"schema_fingerprint"`
+	// pt-osc で実行する場合の引数。パスワードは含まない
+	PtOscArgs []string `json:"pt_osc_args,omitempty"`
+}
+
+// Plan は run --dry-run --plan-file が生成し、run --from-plan が読み込む承認用の実行計画
+type Plan struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Environment string    `json:"environment,omitempty"`
+	Queries     []string  `json:"queries"`
+	// v2 形式のタスクファイルから作った場合のタスク。依存関係の順序を実行時にも再現するために残す
+	Tasks  []config.TaskDefinition `json:"tasks,omitempty"`
+	Tables []Table                 `json:"tables"`
+}
+
+// FindTable はテーブル名で計画を探す
+func (p *Plan) FindTable(tableName string) (*Table, bool) {
+	for i := range p.Tables {
+		if p.Tables[i].TableName == tableName {
+			return &p.Tables[i], true
+		}
+	}
+	return nil, false
+}
+
+// Write は計画をレビューしやすいようインデント付きの JSON で書き出す
+func Write(path string, plan *Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write plan file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Load は計画ファイルを読み込む
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file %s: %w", path, err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file %s: %w", path, err)
+	}
+	if len(plan.Queries) == 0 {
+		return nil, fmt.Errorf("plan file %s has no queries", path)
+	}
+	for i, table := range plan.Tables {
+		if table.TableName == "" || table.Method == "" {
+			return nil, fmt.Errorf("invalid table %d in %s: table_name and method are required", i+1, path)
+		}
+	}
+	return &plan, nil
+}
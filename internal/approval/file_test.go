@@ -0,0 +1,58 @@
+package approval
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	plan := &Plan{
+		GeneratedAt: time.Date(2024, 5, 10, 2, 0, 0, 0, time.UTC),
+		Environment: "prod",
+		Queries:     []string{"ALTER TABLE users ADD COLUMN age INT"},
+		Tables: []Table{
+			{
+				TableName:         "users",
+				Method:            "pt-osc",
+				AlterParts:        []string{"ADD COLUMN age INT"},
+				RowCount:          5000000,
+				SchemaFingerprint: "abc",
+				PtOscArgs:         []string{"--alter=ADD COLUMN age INT", "--execute", "h=db,P=3306,D=app,t=users,u=app"},
+			},
+		},
+	}
+
+	require.NoError(t, Write(path, plan))
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, plan, loaded)
+
+	table, ok := loaded.FindTable("users")
+	require.True(t, ok)
+	assert.Equal(t, "pt-osc", table.Method)
+	_, ok = loaded.FindTable("orders")
+	assert.False(t, ok)
+}
+
+func TestLoadRejectsInvalidPlan(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"empty.json":   `{"queries": []}`,
+		"invalid.json": `{"queries": ["ALTER TABLE users ADD COLUMN age INT"], "tables": [{"table_name": "users"}]}`,
+		"broken.json":  `{`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			_, err := Load(path)
+			assert.Error(t, err)
+		})
+	}
+}
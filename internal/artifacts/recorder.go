@@ -0,0 +1,310 @@
+package artifacts
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/audit"
+)
+
+// TaskResult はテーブル単位(またはテーブル指定のないクエリ単位)の実行結果
+type TaskResult struct {
+	Task         string   `json:"task"`
+	TableName    string   `json:"table_name,omitempty"`
+	ShardPattern string   `json:"shard_pattern,omitempty"`
+	Method       string   `json:"method"`
+	Queries      []string `json:"queries"`
+	// 伏せる前のクエリのハッシュ。redaction.mode で Queries を伏せても環境間で適用済みの文を比べられるようにする
+	QueryHashes     []string  `json:"query_hashes,omitempty"`
+	RowCount        int64     `json:"row_count"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Success         bool      `json:"success"`
+	Error           string    `json:"error,omitempty"`
+	LogFile         string    `json:"log_file,omitempty"`
+}
+
+// Report は実行全体の最終結果
+type Report struct {
+	Command         string         `json:"command"`
+	Operator        audit.Identity `json:"operator"`
+	Environment     string         `json:"environment,omitempty"`
+	DryRun          bool           `json:"dry_run"`
+	StartedAt       time.Time      `json:"started_at"`
+	FinishedAt      time.Time      `json:"finished_at"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Success         bool           `json:"success"`
+	Error           string         `json:"error,omitempty"`
+	Tasks           []TaskResult   `json:"tasks"`
+	// run で実行したクエリ全体のハッシュ。同じバッチの二重実行を見つけるのに使う
+	BatchHash string `json:"batch_hash,omitempty"`
+}
+
+// CopyProgress は pt-osc の行コピーの進み具合。異常終了した後でもどこまでコピーしたか分かるよう、
+// 実行中に定期的に progress/ に書き出す
+type CopyProgress struct {
+	TableName string `json:"table_name"`
+	// pt-osc の --progress で報告された進み具合(%)と残り時間の見込み
+	Percent   int    `json:"percent"`
+	Remaining string `json:"remaining,omitempty"`
+	// "Copying approximately N rows" の N と、進み具合から見積もったコピー済みの行数
+	ApproximateRows int64 `json:"approximate_rows"`
+	CopiedRows      int64 `json:"copied_rows"`
+	// pt_osc.plugin から受け取ったコピー済みのチャンク数と直近のチャンクのコピー時間(秒)。CopiedRows もプラグインの値になる
+	Chunks           int64   `json:"chunks,omitempty"`
+	LastChunkSeconds float64 `json:"last_chunk_seconds,omitempty"`
+	// コピー済みのチャンクの境界 (_new テーブルの最後の行のキー)
+	KeyColumns        []string  `json:"key_columns,omitempty"`
+	LastChunkBoundary []string  `json:"last_chunk_boundary,omitempty"`
+	CopyStartedAt     time.Time `json:"copy_started_at,omitempty"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// pt-osc が正常に終わったら true
+	Completed bool `json:"completed"`
+}
+
+// RowsPerSecond は書き出した時点までの平均のコピー速度を返す。分からない場合は0を返す
+func (p CopyProgress) RowsPerSecond() float64 {
+	elapsed := p.UpdatedAt.Sub(p.CopyStartedAt)
+	if p.CopyStartedAt.IsZero() || elapsed <= 0 || p.CopiedRows <= 0 {
+		return 0
+	}
+	return float64(p.CopiedRows) / elapsed.Seconds()
+}
+
+// EstimatedCopyDuration は同じ速度で最初からコピーし直した場合にかかる時間を返す。分からない場合は0を返す
+func (p CopyProgress) EstimatedCopyDuration() time.Duration {
+	rate := p.RowsPerSecond()
+	if rate <= 0 || p.ApproximateRows <= 0 {
+		return 0
+	}
+	return time.Duration(float64(p.ApproximateRows) / rate * float64(time.Second))
+}
+
+// ProgressPath は実行ごとのディレクトリ runDir の中で、テーブルの進み具合を書き出すファイルのパスを返す
+func ProgressPath(runDir, tableName string) string {
+	return filepath.Join(runDir, "progress", sanitize(tableName)+".json")
+}
+
+var unsafeFileNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Recorder は1回の実行の成果物(ログ・計画・タスク結果・最終レポート)をディレクトリに書き出す。
+// nil の Recorder に対する呼び出しは何もしないため、--artifacts-dir 未指定時もそのまま呼び出せる。
+type Recorder struct {
+	dir     string
+	mu      sync.Mutex
+	plan    any
+	results []TaskResult
+}
+
+// NewRecorder は baseDir の下に実行ごとのディレクトリを作成する
+func NewRecorder(baseDir, command string, now time.Time) (*Recorder, error) {
+	dir := filepath.Join(baseDir, fmt.Sprintf("%s-%s", now.Format("20060102-150405"), command))
+	for i := 2; ; i++ {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			break
+		}
+		dir = filepath.Join(baseDir, fmt.Sprintf("%s-%s-%d", now.Format("20060102-150405"), command, i))
+	}
+
+	for _, sub := range []string{"logs", "tasks", "progress"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create artifacts directory: %w", err)
+		}
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+// NewMemoryRecorder はファイルに書き出さず、計画とタスク結果をメモリ上にだけ保持する Recorder を作る。
+// --artifacts-dir を指定していなくても実行結果を集計したい場合(GitHub Actions のサマリなど)に使う。
+func NewMemoryRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Dir() string {
+	if r == nil {
+		return ""
+	}
+	return r.dir
+}
+
+// WritePlan は実行前に解決した実行計画を plan.json に書き出す
+func (r *Recorder) WritePlan(plan any) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	r.plan = plan
+	r.mu.Unlock()
+
+	return r.writeJSON("plan.json", plan)
+}
+
+// Plan は WritePlan で記録した実行計画を返す
+func (r *Recorder) Plan() any {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.plan
+}
+
+// RecordTask はタスクの結果を tasks/ に書き出し、最終レポート用に保持する
+func (r *Recorder) RecordTask(result TaskResult) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	r.results = append(r.results, result)
+	index := len(r.results)
+	r.mu.Unlock()
+
+	name := result.Task
+	if result.TableName != "" {
+		name = result.TableName
+	}
+	return r.writeJSON(filepath.Join("tasks", fmt.Sprintf("%03d-%s.json", index, sanitize(name))), result)
+}
+
+// WriteProgress は pt-osc の進み具合を progress/ に書き出す。書き込み中に異常終了しても直前の内容が残るよう、
+// 一時ファイルに書いてから置き換える
+func (r *Recorder) WriteProgress(progress CopyProgress) error {
+	if r == nil || r.dir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode progress of %s: %w", progress.TableName, err)
+	}
+	path := ProgressPath(r.dir, progress.TableName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o640); err != nil {
+		return fmt.Errorf("failed to write progress of %s: %w", progress.TableName, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write progress of %s: %w", progress.TableName, err)
+	}
+	return nil
+}
+
+// CopyLog はツールの全出力を logs/ にコピーし、成果物ディレクトリからの相対パスを返す
+func (r *Recorder) CopyLog(name, srcPath string) (string, error) {
+	return r.CopyLogWith(name, srcPath, nil)
+}
+
+// CopyLogWith は CopyLog と同じくログを logs/ に複製する。transform を指定すると各行を変換してから書く
+func (r *Recorder) CopyLogWith(name, srcPath string, transform func(line string) string) (string, error) {
+	if r == nil || r.dir == "" || srcPath == "" {
+		return "", nil
+	}
+
+	src, err := os.Open(srcPath) // #nosec G304
+	if err != nil {
+		return "", fmt.Errorf("failed to open log %s: %w", srcPath, err)
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	rel := filepath.Join("logs", sanitize(name)+".log")
+	dst, err := os.OpenFile(filepath.Join(r.dir, rel), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640) // #nosec G304
+	if err != nil {
+		return "", fmt.Errorf("failed to create log artifact: %w", err)
+	}
+	if err := copyLines(dst, src, transform); err != nil {
+		_ = dst.Close()
+		return "", fmt.Errorf("failed to copy log artifact: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("failed to write log artifact: %w", err)
+	}
+	return rel, nil
+}
+
+// WriteReport は記録したタスク結果を含む最終レポートを report.json に書き出す
+func (r *Recorder) WriteReport(report Report, runErr error) error {
+	if r == nil {
+		return nil
+	}
+	return r.writeJSON("report.json", r.CompleteReport(report, runErr))
+}
+
+// CompleteReport は記録したタスク結果と実行結果を report に埋めて返す
+func (r *Recorder) CompleteReport(report Report, runErr error) Report {
+	if r != nil {
+		r.mu.Lock()
+		report.Tasks = append([]TaskResult{}, r.results...)
+		r.mu.Unlock()
+	}
+
+	if report.FinishedAt.IsZero() {
+		report.FinishedAt = time.Now()
+	}
+	report.DurationSeconds = report.FinishedAt.Sub(report.StartedAt).Seconds()
+	report.Success = runErr == nil
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	return report
+}
+
+func (r *Recorder) writeJSON(name string, v any) error {
+	if r.dir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, name), append(data, '\n'), 0o640); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func sanitize(name string) string {
+	if name == "" {
+		return "task"
+	}
+	return unsafeFileNameRe.ReplaceAllString(name, "_")
+}
+
+func copyLines(dst io.Writer, src io.Reader, transform func(line string) string) error {
+	if transform == nil {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+	reader := bufio.NewReader(src)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			newline := strings.HasSuffix(line, "\n")
+			line = transform(strings.TrimSuffix(line, "\n"))
+			if newline {
+				line += "\n"
+			}
+			if _, writeErr := io.WriteString(dst, line); writeErr != nil {
+				return writeErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	baseDir := t.TempDir()
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	recorder, err := NewRecorder(baseDir, "run", start)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(baseDir, "20250102-030405-run"), recorder.Dir())
+
+	// 同じ時刻の実行でもディレクトリが衝突しない
+	second, err := NewRecorder(baseDir, "run", start)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(baseDir, "20250102-030405-run-2"), second.Dir())
+
+	require.NoError(t, recorder.WritePlan(map[string]string{"table": "users"}))
+
+	logPath := filepath.Join(t.TempDir(), "pt-osc.log")
+	require.NoError(t, os.WriteFile(logPath, []byte("full output\n"), 0o600))
+	rel, err := recorder.CopyLog("users.pt-osc", logPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("logs", "users.pt-osc.log"), rel)
+
+	require.NoError(t, recorder.RecordTask(TaskResult{Task: "pt-osc", TableName: "users", Method: "pt-osc", Success: true, LogFile: rel}))
+	require.NoError(t, recorder.RecordTask(TaskResult{Task: "non-table-query", Method: "non-table-query", Error: "boom"}))
+	require.NoError(t, recorder.WriteReport(Report{Command: "run", StartedAt: start, FinishedAt: start.Add(time.Minute)}, errors.New("boom")))
+
+	for _, name := range []string{"plan.json", "tasks/001-users.json", "tasks/002-non-table-query.json", "logs/users.pt-osc.log"} {
+		assert.FileExists(t, filepath.Join(recorder.Dir(), name))
+	}
+
+	data, err := os.ReadFile(filepath.Join(recorder.Dir(), "report.json"))
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.False(t, report.Success)
+	assert.Equal(t, "boom", report.Error)
+	assert.Equal(t, float64(60), report.DurationSeconds)
+	assert.Len(t, report.Tasks, 2)
+}
+
+func TestRecorderWriteProgress(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	recorder, err := NewRecorder(t.TempDir(), "run", start)
+	require.NoError(t, err)
+
+	progress := CopyProgress{
+		TableName:         "users",
+		Percent:           25,
+		ApproximateRows:   400000,
+		CopiedRows:        100000,
+		KeyColumns:        []string{"id"},
+		LastChunkBoundary: []string{"100000"},
+		CopyStartedAt:     start,
+		UpdatedAt:         start.Add(100 * time.Second),
+	}
+	require.NoError(t, recorder.WriteProgress(progress))
+	progress.Percent = 50
+	require.NoError(t, recorder.WriteProgress(progress))
+
+	data, err := os.ReadFile(ProgressPath(recorder.Dir(), "users"))
+	require.NoError(t, err)
+	var got CopyProgress
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, 50, got.Percent)
+	assert.Equal(t, []string{"100000"}, got.LastChunkBoundary)
+	assert.NoFileExists(t, ProgressPath(recorder.Dir(), "users")+".tmp")
+
+	assert.Equal(t, float64(1000), got.RowsPerSecond())
+	assert.Equal(t, 400*time.Second, got.EstimatedCopyDuration())
+	assert.Zero(t, CopyProgress{}.EstimatedCopyDuration())
+}
+
+func TestNilRecorder(t *testing.T) {
+	var recorder *Recorder
+	assert.NoError(t, recorder.WritePlan(nil))
+	assert.NoError(t, recorder.RecordTask(TaskResult{}))
+	assert.NoError(t, recorder.WriteReport(Report{}, nil))
+	assert.NoError(t, recorder.WriteProgress(CopyProgress{}))
+	rel, err := recorder.CopyLog("x", "/nonexistent")
+	assert.NoError(t, err)
+	assert.Empty(t, rel)
+}
+
+func TestMemoryRecorder(t *testing.T) {
+	recorder := NewMemoryRecorder()
+	assert.Empty(t, recorder.Dir())
+
+	require.NoError(t, recorder.WritePlan(map[string]string{"table": "users"}))
+	require.NoError(t, recorder.RecordTask(TaskResult{Task: "pt-osc", TableName: "users", Success: true}))
+	rel, err := recorder.CopyLog("users.pt-osc", "/nonexistent")
+	require.NoError(t, err)
+	assert.Empty(t, rel)
+
+	assert.Equal(t, map[string]string{"table": "users"}, recorder.Plan())
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	report := recorder.CompleteReport(Report{Command: "run", StartedAt: start, FinishedAt: start.Add(time.Minute)}, nil)
+	assert.True(t, report.Success)
+	assert.Len(t, report.Tasks, 1)
+	assert.NoError(t, recorder.WriteReport(report, nil))
+}
+
+func TestRecorderCopyLogWith(t *testing.T) {
+	recorder, err := NewRecorder(t.TempDir(), "run", time.Now())
+	require.NoError(t, err)
+
+	logPath := filepath.Join(t.TempDir(), "pt-archiver.log")
+	require.NoError(t, os.WriteFile(logPath, []byte("--where=id = 1\nlast line"), 0o600))
+	rel, err := recorder.CopyLogWith("users.pt-archiver", logPath, func(line string) string {
+		return "masked: " + line
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(recorder.Dir(), rel))
+	require.NoError(t, err)
+	assert.Equal(t, "masked: --where=id = 1\nmasked: last line", string(data))
+}
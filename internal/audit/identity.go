@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+)
+
+// Identity は誰がどこから何を実行したかを表す
+type Identity struct {
+	Operator    string `json:"operator,omitempty"`
+	OSUser      string `json:"os_user"`
+	SudoUser    string `json:"sudo_user,omitempty"`
+	Host        string `json:"host"`
+	CommandLine string `json:"command_line"`
+}
+
+// Capture は実行環境から実行者の情報を集める。operator が空の場合は OPERATOR 環境変数を使う。
+func Capture(operator string, args []string) Identity {
+	if operator == "" {
+		operator = os.Getenv("OPERATOR")
+	}
+
+	osUser := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		osUser = u.Username
+	}
+	if osUser == "" {
+		osUser = "unknown"
+	}
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+
+	return Identity{
+		Operator:    strings.TrimSpace(operator),
+		OSUser:      osUser,
+		SudoUser:    os.Getenv("SUDO_USER"),
+		Host:        host,
+		CommandLine: strings.Join(args, " "),
+	}
+}
+
+// Summary はSlackやログに載せる1行の表記を返す
+func (i Identity) Summary() string {
+	source := fmt.Sprintf("%s@%s", i.OSUser, i.Host)
+	if i.SudoUser != "" {
+		source += " via sudo from " + i.SudoUser
+	}
+
+	if i.Operator == "" {
+		return source
+	}
+	return fmt.Sprintf("%s (%s)", i.Operator, source)
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapture(t *testing.T) {
+	t.Run("flag takes precedence over environment", func(t *testing.T) {
+		t.Setenv("OPERATOR", "env-operator")
+		identity := Capture("alice", []string{"alterguard", "run", "--dry-run"})
+
+		assert.Equal(t, "alice", identity.Operator)
+		assert.NotEmpty(t, identity.OSUser)
+		assert.NotEmpty(t, identity.Host)
+		assert.Equal(t, "alterguard run --dry-run", identity.CommandLine)
+	})
+
+	t.Run("falls back to OPERATOR", func(t *testing.T) {
+		t.Setenv("OPERATOR", "bob")
+		assert.Equal(t, "bob", Capture("", nil).Operator)
+	})
+}
+
+func TestIdentitySummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity Identity
+		want     string
+	}{
+		{
+			name:     "without operator",
+			identity: Identity{OSUser: "deploy", Host: "job-1"},
+			want:     "deploy@job-1",
+		},
+		{
+			name:     "with operator",
+			identity: Identity{Operator: "alice", OSUser: "deploy", Host: "job-1"},
+			want:     "alice (deploy@job-1)",
+		},
+		{
+			name:     "with sudo",
+			identity: Identity{Operator: "alice", OSUser: "root", SudoUser: "alice", Host: "bastion"},
+			want:     "alice (root@bastion via sudo from alice)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.identity.Summary())
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package chatops
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Slack の署名検証で許容するリクエスト時刻のずれ(リプレイ対策)
+	maxRequestAge  = 5 * time.Minute
+	maxRequestSize = 1 << 20
+)
+
+var tableNameRe = regexp.MustCompile(`^[A-Za-z0-9_$]+$`)
+
+// Runner はスラッシュコマンドから呼び出す操作。userName はコマンドを実行した Slack のユーザー名で、実行者として通知に載せる
+type Runner interface {
+	Swap(userName, tableName string) error
+	Status(userName, tableName string) (string, error)
+}
+
+// Handler はSlackのスラッシュコマンド(/alterguard swap users など)を受け付ける。
+// 署名を検証し、許可されたチャンネルからのリクエストだけを実行する。
+type Handler struct {
+	signingSecret   []byte
+	allowedChannels map[string]bool
+	runner          Runner
+	logger          *logrus.Logger
+	now             func() time.Time
+
+	// 変更系の操作は同時に1つだけ実行する
+	running sync.Mutex
+	wg      sync.WaitGroup
+}
+
+type response struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+func NewHandler(signingSecret string, allowedChannels []string, runner Runner, logger *logrus.Logger) (*Handler, error) {
+	if signingSecret == "" {
+		return nil, fmt.Errorf("slack signing secret is required")
+	}
+	if len(allowedChannels) == 0 {
+		return nil, fmt.Errorf("chatops.allowed_channels must not be empty")
+	}
+
+	channels := make(map[string]bool, len(allowedChannels))
+	for _, channel := range allowedChannels {
+		channels[channel] = true
+	}
+
+	return &Handler{
+		signingSecret:   []byte(signingSecret),
+		allowedChannels: channels,
+		runner:          runner,
+		logger:          logger,
+		now:             time.Now,
+	}, nil
+}
+
+// Wait は実行中の操作が終わるまで待つ
+func (h *Handler) Wait() {
+	h.wg.Wait()
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.verifySignature(r.Header, body); err != nil {
+		h.logger.Warnf("Rejected slash command: %v", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	channelID := form.Get("channel_id")
+	userName := form.Get("user_name")
+	text := strings.TrimSpace(form.Get("text"))
+
+	if !h.allowedChannels[channelID] {
+		h.logger.Warnf("Rejected slash command from %s in unauthorized channel %s: %s", userName, channelID, text)
+		h.respond(w, "ephemeral", "This channel is not allowed to run alterguard commands.")
+		return
+	}
+
+	h.logger.Infof("Slash command from %s in %s: %s", userName, channelID, text)
+	h.dispatch(w, userName, strings.Fields(text))
+}
+
+func (h *Handler) dispatch(w http.ResponseWriter, userName string, args []string) {
+	if len(args) != 2 {
+		h.respond(w, "ephemeral", usage())
+		return
+	}
+
+	action, tableName := args[0], args[1]
+	if !tableNameRe.MatchString(tableName) {
+		h.respond(w, "ephemeral", fmt.Sprintf("Invalid table name: %s", tableName))
+		return
+	}
+
+	switch action {
+	case "swap":
+		if !h.running.TryLock() {
+			h.respond(w, "ephemeral", "Another operation is in progress. Try again later.")
+			return
+		}
+		h.wg.Add(1)
+		// Slack は3秒以内の応答を求めるため非同期で実行し、結果は通常の通知で送る
+		go func() {
+			defer h.wg.Done()
+			defer h.running.Unlock()
+			if err := h.runner.Swap(userName, tableName); err != nil {
+				h.logger.Errorf("Swap requested by %s failed for %s: %v", userName, tableName, err)
+			}
+		}()
+		h.respond(w, "in_channel", fmt.Sprintf("Swap of %s requested by %s has started.", tableName, userName))
+	case "status":
+		status, err := h.runner.Status(userName, tableName)
+		if err != nil {
+			h.logger.Errorf("Status requested by %s failed for %s: %v", userName, tableName, err)
+			h.respond(w, "ephemeral", fmt.Sprintf("Failed to get status of %s: %v", tableName, err))
+			return
+		}
+		h.respond(w, "in_channel", "```\n"+status+"\n```")
+	default:
+		h.respond(w, "ephemeral", usage())
+	}
+}
+
+// verifySignature は https://api.slack.com/authentication/verifying-requests-from-slack の方式で署名を検証する
+func (h *Handler) verifySignature(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	signature := header.Get("X-Slack-Signature")
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("missing signature headers")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	age := h.now().Sub(time.Unix(ts, 0))
+	if age > maxRequestAge || age < -maxRequestAge {
+		return fmt.Errorf("request timestamp is too old or in the future")
+	}
+
+	if !hmac.Equal([]byte(sign(h.signingSecret, timestamp, body)), []byte(signature)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (h *Handler) respond(w http.ResponseWriter, responseType, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response{ResponseType: responseType, Text: text}); err != nil {
+		h.logger.Errorf("Failed to write slash command response: %v", err)
+	}
+}
+
+func usage() string {
+	return "Usage:\n• `/alterguard swap <table>` - swap _<table>_new into place\n• `/alterguard status <table>` - show the original, _new and _old tables"
+}
@@ -0,0 +1,197 @@
+package chatops
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "test-signing-secret"
+
+type MockRunner struct {
+	mock.Mock
+}
+
+func (m *MockRunner) Swap(userName, tableName string) error {
+	args := m.Called(userName, tableName)
+	return args.Error(0)
+}
+
+func (m *MockRunner) Status(userName, tableName string) (string, error) {
+	args := m.Called(userName, tableName)
+	return args.String(0), args.Error(1)
+}
+
+func newTestHandler(t *testing.T, runner Runner, now time.Time) *Handler {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	h, err := NewHandler(testSecret, []string{"C123"}, runner, logger)
+	require.NoError(t, err)
+	h.now = func() time.Time { return now }
+	return h
+}
+
+func newSlashCommandRequest(channelID, text string, timestamp time.Time, secret string) *http.Request {
+	body := url.Values{
+		"command":    {"/alterguard"},
+		"channel_id": {channelID},
+		"user_name":  {"alice"},
+		"text":       {text},
+	}.Encode()
+
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/slack/commands", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", sign([]byte(secret), ts, []byte(body)))
+	return req
+}
+
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder) response {
+	t.Helper()
+	var resp response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestNewHandler(t *testing.T) {
+	logger := logrus.New()
+
+	_, err := NewHandler("", []string{"C123"}, &MockRunner{}, logger)
+	assert.Error(t, err)
+
+	_, err = NewHandler(testSecret, nil, &MockRunner{}, logger)
+	assert.Error(t, err)
+}
+
+func TestHandlerRejectsInvalidRequests(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{
+			name:       "wrong secret",
+			req:        newSlashCommandRequest("C123", "status users", now, "other-secret"),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "stale timestamp",
+			req:        newSlashCommandRequest("C123", "status users", now.Add(-10*time.Minute), testSecret),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "GET request",
+			req:        httptest.NewRequest(http.MethodGet, "/slack/commands", nil),
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &MockRunner{}
+			rec := httptest.NewRecorder()
+			newTestHandler(t, runner, now).ServeHTTP(rec, tt.req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			runner.AssertNotCalled(t, "Swap", mock.Anything, mock.Anything)
+			runner.AssertNotCalled(t, "Status", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandlerCommands(t *testing.T) {
+	now := time.Now()
+
+	t.Run("unauthorized channel", func(t *testing.T) {
+		runner := &MockRunner{}
+		rec := httptest.NewRecorder()
+		newTestHandler(t, runner, now).ServeHTTP(rec, newSlashCommandRequest("C999", "swap users", now, testSecret))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "ephemeral", decodeResponse(t, rec).ResponseType)
+		runner.AssertNotCalled(t, "Swap", mock.Anything, mock.Anything)
+	})
+
+	t.Run("status", func(t *testing.T) {
+		runner := &MockRunner{}
+		runner.On("Status", "alice", "users").Return("Table: users", nil)
+		rec := httptest.NewRecorder()
+		newTestHandler(t, runner, now).ServeHTTP(rec, newSlashCommandRequest("C123", "status users", now, testSecret))
+
+		resp := decodeResponse(t, rec)
+		assert.Equal(t, "in_channel", resp.ResponseType)
+		assert.Contains(t, resp.Text, "Table: users")
+		runner.AssertExpectations(t)
+	})
+
+	t.Run("status failure", func(t *testing.T) {
+		runner := &MockRunner{}
+		runner.On("Status", "alice", "users").Return("", errors.New("boom"))
+		rec := httptest.NewRecorder()
+		newTestHandler(t, runner, now).ServeHTTP(rec, newSlashCommandRequest("C123", "status users", now, testSecret))
+
+		assert.Contains(t, decodeResponse(t, rec).Text, "boom")
+	})
+
+	t.Run("swap runs asynchronously", func(t *testing.T) {
+		runner := &MockRunner{}
+		runner.On("Swap", "alice", "users").Return(nil)
+		h := newTestHandler(t, runner, now)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newSlashCommandRequest("C123", "swap users", now, testSecret))
+		h.Wait()
+
+		resp := decodeResponse(t, rec)
+		assert.Equal(t, "in_channel", resp.ResponseType)
+		assert.Contains(t, resp.Text, "requested by alice")
+		runner.AssertExpectations(t)
+	})
+
+	t.Run("swap while another operation is running", func(t *testing.T) {
+		release := make(chan struct{})
+		runner := &MockRunner{}
+		runner.On("Swap", "alice", "users").Run(func(mock.Arguments) { <-release }).Return(nil).Once()
+		h := newTestHandler(t, runner, now)
+
+		h.ServeHTTP(httptest.NewRecorder(), newSlashCommandRequest("C123", "swap users", now, testSecret))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newSlashCommandRequest("C123", "swap orders", now, testSecret))
+		close(release)
+		h.Wait()
+
+		assert.Contains(t, decodeResponse(t, rec).Text, "Another operation is in progress")
+		runner.AssertNotCalled(t, "Swap", mock.Anything, "orders")
+	})
+
+	t.Run("invalid table name", func(t *testing.T) {
+		runner := &MockRunner{}
+		rec := httptest.NewRecorder()
+		newTestHandler(t, runner, now).ServeHTTP(rec, newSlashCommandRequest("C123", "swap users;drop", now, testSecret))
+
+		assert.Contains(t, decodeResponse(t, rec).Text, "Invalid table name")
+		runner.AssertNotCalled(t, "Swap", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown command shows usage", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestHandler(t, &MockRunner{}, now).ServeHTTP(rec, newSlashCommandRequest("C123", "help", now, testSecret))
+
+		assert.Contains(t, decodeResponse(t, rec).Text, "Usage")
+	})
+}
@@ -1,29 +1,96 @@
 package config
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/pyama86/alterguard/internal/redact"
 	"gopkg.in/yaml.v3"
 )
 
 type CommonConfig struct {
-	PtOsc                     PtOscConfig           `yaml:"pt_osc"`
-	PtArchiver                PtArchiverConfig      `yaml:"pt_archiver"`
-	Alert                     AlertConfig           `yaml:"alert"`
-	PtOscThreshold            int64                 `yaml:"pt_osc_threshold"`
-	SessionConfig             SessionConfig         `yaml:"session_config"`
-	ConnectionCheck           ConnectionCheckConfig `yaml:"connection_check"`
-	DisableAnalyzeTable       bool                  `yaml:"disable_analyze_table"`
-	BufferPoolSizeThresholdMB float64               `yaml:"buffer_pool_size_threshold_mb"`
+	PtOsc                     PtOscConfig             `yaml:"pt_osc"`
+	PtArchiver                PtArchiverConfig        `yaml:"pt_archiver"`
+	Alert                     AlertConfig             `yaml:"alert"`
+	PtOscThreshold            int64                   `yaml:"pt_osc_threshold"`
+	SessionConfig             SessionConfig           `yaml:"session_config"`
+	ConnectionCheck           ConnectionCheckConfig   `yaml:"connection_check"`
+	DisableAnalyzeTable       bool                    `yaml:"disable_analyze_table"`
+	BufferPoolSizeThresholdMB float64                 `yaml:"buffer_pool_size_threshold_mb"`
+	Rolling                   RollingConfig           `yaml:"rolling"`
+	Database                  DatabaseConfig          `yaml:"database"`
+	SessionVars               map[string]string       `yaml:"session_vars"`
+	TaskTimeout               string                  `yaml:"task_timeout"`
+	RunTimeout                string                  `yaml:"run_timeout"`
+	ChatOps                   ChatOpsConfig           `yaml:"chatops"`
+	KillBlockers              KillBlockersConfig      `yaml:"kill_blockers"`
+	SwapSoak                  SwapSoakConfig          `yaml:"swap_soak"`
+	SwapFreshness             SwapFreshnessConfig     `yaml:"swap_freshness_check"`
+	DependencyCheck           DependencyCheckConfig   `yaml:"dependency_check"`
+	SwapAutoIncrement         SwapAutoIncrementConfig `yaml:"swap_auto_increment"`
+	Schedule                  ScheduleConfig          `yaml:"schedule"`
+	Events                    EventsConfig            `yaml:"events"`
+	DuplicateErrors           DuplicateErrorsConfig   `yaml:"duplicate_errors"`
+	SwapOldTable              SwapOldTableConfig      `yaml:"swap_old_table"`
+	// pt-osc が失敗したときに、残ったトリガーと _new テーブルを自動で削除する
+	AutoCleanupOnFailure bool               `yaml:"auto_cleanup_on_failure"`
+	AnalyzeTable         AnalyzeTableConfig `yaml:"analyze_table"`
+	SwapWarmup           SwapWarmupConfig   `yaml:"swap_warmup"`
+	// テーブルサイズ(MB)がこれを超えたら行数に関わらず pt-osc を使う。0 なら無効
+	PtOscSizeThresholdMB float64 `yaml:"pt_osc_size_threshold_mb"`
+	// 行数・サイズによる判定をせず、全テーブルをこの方式で変更する (ptosc または direct)
+	ForceMethod     string                `yaml:"force_method"`
+	SwapRemediation SwapRemediationConfig `yaml:"swap_remediation"`
+	Email           EmailConfig           `yaml:"email"`
+	Notifiers       []NotifierConfig      `yaml:"notifiers"`
+	Redaction       RedactionConfig       `yaml:"redaction"`
+	// 実行前にテーブルの統計情報から binlog 量・一時ディスク・レプリカ遅延・バッファプールへの影響を見積もって通知する
+	ImpactEstimate ImpactEstimateConfig `yaml:"impact_estimate"`
+	// pt-osc / pt-archiver を始める前に、max_connections までの空きがこの割合(%)未満なら中止する。0 なら確認しない
+	MinConnectionHeadroomPercent float64                `yaml:"min_connection_headroom_percent"`
+	ReplicationCheck             ReplicationCheckConfig `yaml:"replication_check"`
+	BinlogCheck                  BinlogCheckConfig      `yaml:"binlog_check"`
+	ConflictCheck                ConflictCheckConfig    `yaml:"conflict_check"`
+	// 同じ環境で同じバッチ (クエリの並び) がこの時間内に成功していたら run を拒否する (例: 24h)。--force で実行できる
+	DuplicateRunWindow string `yaml:"duplicate_run_window"`
+	// 方式を決めた統計情報の行数を COUNT(*) で確かめる
+	RowCountVerify RowCountVerifyConfig `yaml:"row_count_verify"`
+	// 重いフェーズ (pt-osc, pt-archiver, swap など) の開始時と終了時にレプリカ遅延を測って通知する
+	ReplicaLag ReplicaLagConfig `yaml:"replica_lag"`
+	// run --profile で選ぶ、タスクファイル・フラグ・通知先の組み合わせ
+	RunProfiles map[string]RunProfile `yaml:"run_profiles"`
+	// run の開始時に、実際に使われる設定 (閾値・pt-osc の設定・安全確認の有効/無効など) を通知する。ログには常に出す
+	NotifyConfigSummary bool `yaml:"notify_config_summary"`
+	// false にすると SLACK_WEBHOOK_URL や notifiers が設定されていても通知しない
+	Notifications NotificationsConfig `yaml:"notifications"`
+	// true にすると run / swap / cleanup は --execute を付けない限り dry-run で動く
+	RequireExecuteFlag bool `yaml:"require_execute_flag"`
+	// --stdin とタスクファイルが同じテーブルを変更する場合の扱い (warn または abort)。既定 warn
+	StdinOverlap string `yaml:"stdin_overlap"`
+	// swap の前に行数チェックに加えて実行する検証
+	SwapValidators SwapValidatorsConfig `yaml:"swap_validators"`
+}
+
+// NotificationsConfig は通知全体の設定
+type NotificationsConfig struct {
+	// 通知するか。未指定なら通知する
+	Enabled *bool `yaml:"enabled"`
+}
+
+// IsEnabled は通知が無効にされていなければ true を返す
+func (c NotificationsConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
 }
 
 type PtOscConfig struct {
 	Charset                string                   `yaml:"charset"`
 	RecursionMethod        string                   `yaml:"recursion_method"`
+	RecursionDSN           string                   `yaml:"recursion_dsn"`
 	NoSwapTables           bool                     `yaml:"no_swap_tables"`
 	ChunkSize              int                      `yaml:"chunk_size"`
 	MaxLag                 float64                  `yaml:"max_lag"`
@@ -35,6 +102,328 @@ type PtOscConfig struct {
 	NoCheckUniqueKeyChange bool                     `yaml:"no_check_unique_key_change"`
 	NoCheckAlter           bool                     `yaml:"no_check_alter"`
 	AuroraReplicaCheck     AuroraReplicaCheckConfig `yaml:"aurora_replica_check"`
+	AutoSwap               AutoSwapConfig           `yaml:"auto_swap"`
+	// recursion_method: none でレプリカの遅延確認を無効にすることを明示的に了承する
+	AcknowledgeNoReplicaCheck bool `yaml:"acknowledge_no_replica_check"`
+	// _new テーブルを DATA DIRECTORY で別のディレクトリに作成する(--data-dir)。絶対パスで指定する
+	DataDir string `yaml:"data_dir"`
+	// 元テーブルの DATA DIRECTORY を引き継がずデフォルトのディレクトリに作成するか(--[no]remove-data-dir)。
+	// 未指定なら pt-osc のデフォルトに従う
+	RemoveDataDir *bool `yaml:"remove_data_dir"`
+	// テーブルごとに上書きする設定
+	Tables map[string]PtOscTableConfig `yaml:"tables"`
+	// --plugin で読み込ませる pt-osc のプラグイン
+	Plugin PtOscPluginConfig `yaml:"plugin"`
+	// session_vars から引き継ぐ。--set-vars として渡す
+	SessionVars map[string]string `yaml:"-"`
+}
+
+// PtOscTableConfig は pt_osc.tables でテーブルごとに上書きできる設定。
+// レプリカのないクラスタのテーブルだけ recursion_method: none にする、といった用途に使う。
+type PtOscTableConfig struct {
+	RecursionMethod           string `yaml:"recursion_method"`
+	RecursionDSN              string `yaml:"recursion_dsn"`
+	AcknowledgeNoReplicaCheck bool   `yaml:"acknowledge_no_replica_check"`
+	// 意図して行数を変える場合 (論理削除済みの行を落とすなど) に、swap 前の行数チェックの代わりに使う行数の増減(%)。
+	// 例: -20 は _new テーブルが 20% 少ない想定。実際の増減がこの値から 5 ポイント以内なら swap する
+	ExpectedRowDeltaPercent *float64 `yaml:"expected_row_delta_percent"`
+}
+
+// ForTable は pt_osc.tables の上書きを反映した設定を返す
+func (c PtOscConfig) ForTable(tableName string) PtOscConfig {
+	override, ok := c.Tables[tableName]
+	if !ok {
+		return c
+	}
+	if override.RecursionMethod != "" {
+		c.RecursionMethod = override.RecursionMethod
+		c.RecursionDSN = override.RecursionDSN
+	} else if override.RecursionDSN != "" {
+		c.RecursionDSN = override.RecursionDSN
+	}
+	if override.AcknowledgeNoReplicaCheck {
+		c.AcknowledgeNoReplicaCheck = true
+	}
+	return c
+}
+
+// ChatOpsConfig は serve コマンドで受け付けるSlackスラッシュコマンドの設定
+type ChatOpsConfig struct {
+	ListenAddr      string   `yaml:"listen_addr"`
+	AllowedChannels []string `yaml:"allowed_channels"`
+}
+
+// ScheduleConfig は schedule コマンドで定期実行するジョブの設定
+type ScheduleConfig struct {
+	// cron 式を解釈するタイムゾーン。未指定ならローカルタイム
+	Timezone string               `yaml:"timezone"`
+	Jobs     []ScheduledJobConfig `yaml:"jobs"`
+}
+
+// ScheduledJobConfig は定期実行するジョブ。action は purge, cleanup, swap, optimize, follow-up のいずれか。
+type ScheduledJobConfig struct {
+	Name   string `yaml:"name"`
+	Cron   string `yaml:"cron"`
+	Jitter string `yaml:"jitter"`
+	Action string `yaml:"action"`
+	Table  string `yaml:"table"`
+	// cleanup で削除するもの
+	DropTriggers bool `yaml:"drop_triggers"`
+	DropTable    bool `yaml:"drop_table"`
+	// follow-up で実行するファイル
+	File string `yaml:"file"`
+}
+
+// EventsConfig は pt-osc による大きなマイグレーションの開始・終了をイベントとして外部に送る設定。
+// provider が datadog なら Datadog Events API (API キーは DD_API_KEY 環境変数)、webhook なら url に JSON を POST する。
+// sns / eventbridge なら arn の SNS トピックまたは EventBridge バスに送る (認証は AWS_ACCESS_KEY_ID などの環境変数)。
+type EventsConfig struct {
+	Provider string   `yaml:"provider"`
+	URL      string   `yaml:"url"`
+	Site     string   `yaml:"site"`
+	Tags     []string `yaml:"tags"`
+	ARN      string   `yaml:"arn"`
+	// 空なら arn のリージョン、なければ AWS_REGION 環境変数
+	Region string `yaml:"region"`
+}
+
+// DuplicateErrorsConfig は重複系のエラー (1050/1060/1061/1062) で処理を続けるか失敗にするかの設定。
+// 値は warn か fail で、environments に書いた環境ごとの設定が codes より優先される。
+type DuplicateErrorsConfig struct {
+	Codes        map[int]string            `yaml:"codes"`
+	Environments map[string]map[int]string `yaml:"environments"`
+}
+
+// duplicateErrorCodes は duplicate_errors に書けるエラー番号
+var duplicateErrorCodes = map[int]bool{1050: true, 1060: true, 1061: true, 1062: true}
+
+// validate は書き間違えた値が実行中に初めてエラーになることのないよう、エラー番号と warn / fail を読み込み時に確かめる
+func (c DuplicateErrorsConfig) validate() error {
+	check := func(location string, policies map[int]string) error {
+		codes := make([]int, 0, len(policies))
+		for code := range policies {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			if !duplicateErrorCodes[code] {
+				return fmt.Errorf("invalid error code %d in %s: must be one of 1050, 1060, 1061 or 1062", code, location)
+			}
+			if policy := policies[code]; policy != "warn" && policy != "fail" {
+				return fmt.Errorf("invalid policy %q for %d in %s: must be warn or fail", policy, code, location)
+			}
+		}
+		return nil
+	}
+
+	if err := check("duplicate_errors.codes", c.Codes); err != nil {
+		return err
+	}
+	environments := make([]string, 0, len(c.Environments))
+	for environment := range c.Environments {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+	for _, environment := range environments {
+		if err := check("duplicate_errors.environments."+environment, c.Environments[environment]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SwapAutoIncrementConfig は swap 後の AUTO_INCREMENT が _old テーブルの最大値より
+// headroom 以上先に進んでいるかを確認する設定。bump を有効にすると不足分を ALTER TABLE で引き上げる。
+type SwapAutoIncrementConfig struct {
+	Disabled bool  `yaml:"disabled"`
+	Headroom int64 `yaml:"headroom"`
+	Bump     bool  `yaml:"bump"`
+}
+
+// AnalyzeTableConfig は swap の前後に実行する ANALYZE TABLE の設定
+type AnalyzeTableConfig struct {
+	// swap 前に _new テーブルに実行するか。未指定なら disable_analyze_table の逆
+	BeforeSwap *bool `yaml:"before_swap"`
+	// swap 後に入れ替わったテーブルに実行するか (既定: false)
+	AfterSwap *bool `yaml:"after_swap"`
+	// 待つ最大時間 (例: 5m)。未設定なら無制限
+	Timeout string `yaml:"timeout"`
+	// 失敗したときの扱い。warn(既定: ログに残して続行) か fail(エラーにする)
+	OnFailure string `yaml:"on_failure"`
+	// テーブルごとに上書きする設定
+	Tables map[string]AnalyzeTableOverride `yaml:"tables"`
+}
+
+// AnalyzeTableOverride は analyze_table.tables でテーブルごとに上書きする設定
+type AnalyzeTableOverride struct {
+	BeforeSwap *bool `yaml:"before_swap"`
+	AfterSwap  *bool `yaml:"after_swap"`
+}
+
+// SwapOldTableConfig は swap 前に前回の移行の _old テーブルが残っていた場合の扱い。
+// policy は abort(既定: 中止), suffix(_old_<日時> に RENAME する), cleanup(先に cleanup --drop-table と同じ手順で削除する) のいずれか。
+type SwapOldTableConfig struct {
+	Policy string `yaml:"policy"`
+}
+
+// DependencyCheckConfig は swap/cleanup 前に対象テーブルを参照するビュー・トリガー・ルーチンを調べる設定。
+// policy は warn(既定: 通知して続行), block(見つかったら中止), ignore(調べない) のいずれか。
+type DependencyCheckConfig struct {
+	Policy string `yaml:"policy"`
+}
+
+// SwapFreshnessConfig は no_swap_tables で残した _new テーブルが
+// トリガーで追従し続けているかを swap 前に確認する設定
+type SwapFreshnessConfig struct {
+	Disabled        bool   `yaml:"disabled"`
+	TimestampColumn string `yaml:"timestamp_column"`
+}
+
+// SwapSoakConfig は swap 後にヘルスチェックを続け、失敗したら元に戻すための設定
+type SwapSoakConfig struct {
+	Duration      string `yaml:"duration"`
+	CheckInterval string `yaml:"check_interval"`
+	HealthQuery   string `yaml:"health_query"`
+	HealthURL     string `yaml:"health_url"`
+	// 連続で何回失敗したら元に戻すか
+	MaxFailures int `yaml:"max_failures"`
+	// swap 後のエラー量を監視し、急増したらヘルスチェック失敗として扱う
+	ErrorLog SwapErrorLogConfig `yaml:"error_log"`
+	// 元に戻すと swap 後の書き込みが元のテーブルに反映されないことを了承する。true でなければ swap_soak は使えない
+	AllowWriteLoss bool `yaml:"allow_write_loss"`
+}
+
+// SwapErrorLogConfig は swap 後のエラー量の監視設定
+type SwapErrorLogConfig struct {
+	// performance_schema.error_log (MySQL 8.0) から対象テーブルに触れたエラーを数える
+	Enabled bool `yaml:"enabled"`
+	// 指定すると、チェック間隔ごとのエラー数を数値で返すエンドポイントも参照する
+	RateURL string `yaml:"rate_url"`
+	// チェック間隔あたりに許容するエラー数。これを超えると失敗とみなす
+	MaxErrors int `yaml:"max_errors"`
+}
+
+// SwapWarmupConfig は swap 前に _new テーブルのインデックスを読み込み、バッファプールを温めておくための設定
+type SwapWarmupConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// 読み込むインデックス名。空なら全インデックス
+	Indexes []string `yaml:"indexes"`
+	// ウォームアップ全体の上限時間。超えたら残りのインデックスを読まずに swap する
+	Timeout string `yaml:"timeout"`
+}
+
+// SwapRemediationConfig は swap 前の行数チェックで差分が見つかったときに、
+// pt-table-sync で _new テーブルを元テーブルに合わせてから再確認するための設定
+type SwapRemediationConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// pt-table-sync の実行時間の上限 (既定: 30m)。超えたら止めて swap を中止する
+	Timeout string `yaml:"timeout"`
+	// 1回の比較・修復の単位にする行数(--chunk-size)
+	ChunkSize int `yaml:"chunk_size"`
+}
+
+// EmailConfig は Slack と同じ通知を SMTP でメールとしても送る設定。host が空なら送らない。
+// パスワードは SMTP_PASSWORD 環境変数から読む。
+type EmailConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	From     string `yaml:"from"`
+	// starttls (既定)、tls (接続時から TLS)、none のいずれか
+	TLS string `yaml:"tls"`
+	// text/template で .Title .Message .Severity .Environment .Timestamp を使える
+	SubjectTemplate string                `yaml:"subject_template"`
+	BodyTemplate    string                `yaml:"body_template"`
+	Recipients      EmailRecipientsConfig `yaml:"recipients"`
+}
+
+// NotifierConfig は Slack と同じ通知を送る Webhook の通知先。type は mattermost か discord。
+// URL を設定ファイルに書かない場合は webhook_url_env に URL を入れた環境変数名を書く。
+type NotifierConfig struct {
+	Type          string `yaml:"type"`
+	WebhookURL    string `yaml:"webhook_url"`
+	WebhookURLEnv string `yaml:"webhook_url_env"`
+	// 投稿者名 (既定: alterguard)
+	Username string `yaml:"username"`
+}
+
+type ImpactEstimateConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// BinlogCheckConfig は実行開始時に binlog_format / binlog_row_image を確認する設定。
+// policy は warn(開始通知に載せて続行), block(問題があれば中止), ignore(調べない) のいずれか。未設定なら調べない
+type BinlogCheckConfig struct {
+	Policy string `yaml:"policy"`
+}
+
+// ConflictCheckConfig は pt-osc・swap・purge の前に、同じテーブルに触れる MySQL の EVENT や
+// 既知の cron ジョブが実行予定の時間帯に動かないかを調べる設定。
+// policy は warn(通知して続行), block(見つかったら中止), ignore(調べない) のいずれか。未設定なら調べない
+type ConflictCheckConfig struct {
+	Policy string `yaml:"policy"`
+	// 操作を始めてから終わるまでに見込む時間 (既定: 1h)
+	Window string `yaml:"window"`
+	// jobs の cron 式を解釈するタイムゾーン。未指定ならローカルタイム
+	Timezone string           `yaml:"timezone"`
+	Jobs     []KnownJobConfig `yaml:"jobs"`
+}
+
+// KnownJobConfig は alterguard の外で動いている定期ジョブ
+type KnownJobConfig struct {
+	Name   string   `yaml:"name"`
+	Cron   string   `yaml:"cron"`
+	Tables []string `yaml:"tables"`
+	// ジョブが動き続ける時間の目安。開始前から動いているジョブも衝突とみなすのに使う
+	Duration string `yaml:"duration"`
+}
+
+// ReplicationCheckConfig は実行前に GTID の設定とレプリケーションフィルタが計画したクエリと矛盾しないかを確認する設定
+type ReplicationCheckConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// RedactionConfig は通知と実行結果の記録に載せるクエリから、WHERE 句などの値を伏せる設定。
+// mode は elide (? に置き換える) か hash (値のハッシュに置き換える)。空なら伏せない。ログには元のまま出す。
+type RedactionConfig struct {
+	Mode string `yaml:"mode"`
+	// hash の HMAC の鍵。環境変数 REDACTION_HASH_KEY でも指定できる
+	HashKey string `yaml:"hash_key"`
+}
+
+// Policy は redact パッケージに渡す伏せ方を返す
+func (c RedactionConfig) Policy() redact.Policy {
+	return redact.Policy{Mode: c.Mode, HashKey: c.HashKey}
+}
+
+// EmailRecipientsConfig は通知の重要度ごとの宛先。宛先が空の重要度の通知はメールにしない
+type EmailRecipientsConfig struct {
+	Info    []string `yaml:"info"`
+	Warning []string `yaml:"warning"`
+	Error   []string `yaml:"error"`
+}
+
+// KillBlockersConfig は kill-blockers コマンドで KILL 対象から除外するユーザーの設定
+type KillBlockersConfig struct {
+	ProtectedUsers []string `yaml:"protected_users"`
+}
+
+// AutoSwapConfig は no_swap_tables で実行したテーブルを run の最後に自動でswapする設定。
+// window_start/window_end (HH:MM) を指定すると、その時間帯になるまで待ってからswapする。
+type AutoSwapConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	WindowStart string `yaml:"window_start"`
+	WindowEnd   string `yaml:"window_end"`
+	Timezone    string `yaml:"timezone"`
+}
+
+// PtOscPluginConfig は pt-osc の --plugin の設定。
+// alterguard は socket_path で待ち受け、プラグインから送られたチャンクごとの進み具合を受け取る
+type PtOscPluginConfig struct {
+	// プラグインのファイル (Perl) のパス
+	Path string `yaml:"path"`
+	// 進み具合を受け取る Unix ドメインソケットのパス。未指定なら一時ディレクトリに作る
+	SocketPath string `yaml:"socket_path"`
 }
 
 type AuroraReplicaCheckConfig struct {
@@ -55,10 +444,80 @@ type PtArchiverConfig struct {
 	Statistics     bool    `yaml:"statistics"`
 	Where          string  `yaml:"where"`
 	Enabled        bool    `yaml:"enabled"`
+	// 削除の間に挟む秒数(--sleep)
+	Sleep int `yaml:"sleep"`
+	// 1トランザクションで削除する行数(--txn-size)。commit_each とは同時に指定できない
+	TxnSize int `yaml:"txn_size"`
+	// ロック待ちタイムアウトやデッドロック時のリトライ回数(--retries)
+	Retries int `yaml:"retries"`
+	// max_lag を確認する間隔(--check-interval)。例: 1s
+	CheckInterval string `yaml:"check_interval"`
+	// 削除の進捗をSlackに通知する間隔。progress か statistics の出力から削除行数を読み取る
+	NotifyInterval string `yaml:"notify_interval"`
+	// pt-archiver を終了させるまでの時間(--run-time)。例: 10m
+	RunTime string `yaml:"run_time"`
+	// レプリカ遅延を見ながら --limit と --sleep を調整する
+	Adaptive PtArchiverAdaptiveConfig `yaml:"adaptive"`
+	// _old テーブルが空になるまで pt-archiver を繰り返す
+	UntilEmpty PtArchiverUntilEmptyConfig `yaml:"until_empty"`
+	// session_vars から引き継ぐ。--set-vars として渡す
+	SessionVars map[string]string `yaml:"-"`
+}
+
+// PtArchiverAdaptiveConfig は pt-archiver の速度をレプリカ遅延に合わせる設定。
+// pt-archiver を pass_duration ごとに区切って実行し、その合間に replica_lag.replicas の遅延を測って次の --limit と --sleep を決める
+type PtArchiverAdaptiveConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// 1回の pt-archiver の実行時間 (--run-time)。既定 1m
+	PassDuration string `yaml:"pass_duration"`
+	// 目標のレプリカ遅延(秒)。これを超えたら遅くし、半分を下回ったら速くする
+	TargetLagSeconds float64 `yaml:"target_lag_seconds"`
+	// --limit の下限と上限。既定は 10 と limit の10倍 (最低 1000)
+	MinLimit int `yaml:"min_limit"`
+	MaxLimit int `yaml:"max_limit"`
+	// --sleep の上限(秒)。既定 10
+	MaxSleep int `yaml:"max_sleep"`
+}
+
+// PtArchiverUntilEmptyConfig は _old テーブルの行がなくなるまで pt-archiver を繰り返す設定。
+// パスごとに統計情報で残りの行数を見積もり、0 になったら COUNT(*) で確かめて、空になるか上限に達するまで続ける
+type PtArchiverUntilEmptyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// pt-archiver を実行する回数の上限。0 なら無制限
+	MaxIterations int `yaml:"max_iterations"`
+	// 繰り返しを続ける時間の上限。例: 2h。空なら無制限 (task_timeout は別に効く)
+	MaxDuration string `yaml:"max_duration"`
+	// 空になったことを確かめる COUNT(*) の実行時間の上限。例: 1m。空なら無制限
+	CountTimeout string `yaml:"count_timeout"`
+}
+
+// SwapValidatorsConfig は swap の前に実行する組み込みの検証と、SQL で書く独自の検証の設定
+type SwapValidatorsConfig struct {
+	// 元テーブルと _new テーブルの共通カラムのチェックサムを比べる。全行を読むので既定は無効
+	Checksum bool `yaml:"checksum"`
+	// チェックサムのクエリの実行時間の上限。例: 10m。空なら無制限
+	ChecksumTimeout string `yaml:"checksum_timeout"`
+	// replica_lag.replicas の遅延(秒)がこれを超えていたら swap しない。0 なら確認しない
+	MaxReplicaLagSeconds float64 `yaml:"max_replica_lag_seconds"`
+	// テーブルのメタデータロックを保持しているセッションがあれば swap しない。無効なら dry run でだけ確認する
+	Blockers bool `yaml:"blockers"`
+	// SQL の結果で判定する独自の検証。書いた順に実行する
+	Assertions []SwapAssertionConfig `yaml:"assertions"`
+}
+
+// SwapAssertionConfig は SQL で書く swap 前の検証。1行1列目が真なら通る
+type SwapAssertionConfig struct {
+	Name string `yaml:"name"`
+	// <table> は元テーブル、<new_table> は _new テーブルの名前に置き換える
+	Query string `yaml:"query"`
+	// この検証を行うテーブル。空なら全テーブル
+	Tables []string `yaml:"tables"`
 }
 
 type AlertConfig struct {
 	ExecutionTimeThresholdSeconds int `yaml:"execution_time_threshold_seconds"`
+	// フェーズ (swap, pt-osc, pt-archiver, analyze, cleanup) ごとの閾値(秒)。0 ならそのフェーズは監視しない
+	Phases map[string]int `yaml:"phases"`
 }
 
 type SessionConfig struct {
@@ -66,28 +525,124 @@ type SessionConfig struct {
 	InnodbLockWaitTimeout int `yaml:"innodb_lock_wait_timeout"`
 }
 
+type DatabaseConfig struct {
+	Retry           RetryConfig    `yaml:"retry"`
+	MaxOpenConns    int            `yaml:"max_open_conns"`
+	MaxIdleConns    int            `yaml:"max_idle_conns"`
+	ConnMaxLifetime string         `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime string         `yaml:"conn_max_idle_time"`
+	PingBeforeUse   bool           `yaml:"ping_before_use"`
+	RowCount        RowCountConfig `yaml:"row_count"`
+	// session_vars から引き継ぐ。全コネクションの接続時に設定される
+	SessionVars map[string]string `yaml:"-"`
+}
+
+// RowCountConfig は pt-osc を使うかの判定に使う行数の数え方の設定
+type RowCountConfig struct {
+	// stats-only, stats-then-count (既定), count-always, estimate のいずれか
+	Strategy string `yaml:"strategy"`
+	// COUNT(*) の実行時間の上限 (例: 30s)。超えたら統計情報や EXPLAIN の推定値を使う。未設定なら上限なし
+	CountTimeout string `yaml:"count_timeout"`
+	// テーブルごとの上書き
+	Tables map[string]RowCountTableConfig `yaml:"tables"`
+}
+
+// RowCountVerifyConfig は方式の判定に使った統計情報の行数を、実行前に COUNT(*) で確かめる設定
+type RowCountVerifyConfig struct {
+	// 統計情報と COUNT(*) の差がこの割合(%)を超えたら警告する。0 なら確かめない
+	MaxDivergencePercent float64 `yaml:"max_divergence_percent"`
+	// 差が超えたとき、COUNT(*) の行数で方式を選び直す
+	Reevaluate bool `yaml:"reevaluate"`
+	// COUNT(*) の実行時間の上限 (例: 30s)。超えたら確かめずに続ける。未設定なら上限なし
+	CountTimeout string `yaml:"count_timeout"`
+}
+
+type RowCountTableConfig struct {
+	Strategy     string `yaml:"strategy"`
+	CountTimeout string `yaml:"count_timeout"`
+}
+
+// RetryConfig はデッドロック等の一時的なエラーに対するリトライ設定
+type RetryConfig struct {
+	MaxAttempts    int    `yaml:"max_attempts"`
+	InitialBackoff string `yaml:"initial_backoff"`
+	MaxBackoff     string `yaml:"max_backoff"`
+}
+
 type ConnectionCheckConfig struct {
 	Enabled bool `yaml:"enabled"`
+	// 他のセッションがあっても即座に失敗せず、いなくなるまで待つ最大時間 (例: 10m)。未設定なら待たない
+	WaitTimeout string `yaml:"wait_timeout"`
+	// 待っている間に processlist を確認する間隔 (既定: 10s)
+	PollInterval string `yaml:"poll_interval"`
+	// 待っている間に残っているセッションを通知する間隔 (既定: 1m)
+	NotifyInterval string `yaml:"notify_interval"`
+}
+
+// RollingConfig はレプリカを1台ずつ順番に変更していくローリング実行の設定
+type RollingConfig struct {
+	MaxLagSeconds    float64 `yaml:"max_lag_seconds"`
+	LagCheckInterval string  `yaml:"lag_check_interval"`
+	LagWaitTimeout   string  `yaml:"lag_wait_timeout"`
+	EnableBinlog     bool    `yaml:"enable_binlog"`
+}
+
+// ReplicaLagConfig は遅延を表示するレプリカの一覧
+type ReplicaLagConfig struct {
+	Replicas []ReplicaLagTarget `yaml:"replicas"`
+}
+
+// ReplicaLagTarget は遅延を表示するレプリカ1台。dsn を省略すると DATABASE_DSN のホストを host に置き換えた DSN で接続する
+type ReplicaLagTarget struct {
+	Name string `yaml:"name"`
+	DSN  string `yaml:"dsn"`
+	Host string `yaml:"host"`
 }
 
 type Config struct {
-	Common      CommonConfig
-	Queries     []string
+	Common  CommonConfig
+	Queries []string
+	// v2 形式のタスクファイルから読み込んだタスク。v1 形式では nil。Queries には全タスクのクエリがファイル順に入る
+	Tasks       []TaskDefinition
 	DSN         string
+	ReplicaDSNs []string
 	Environment string
+	// Queries のうち標準入力から読み込んだもの。Queries の末尾に同じ順で入っている
+	StdinQueries []string
+}
+
+// TaskDefinition は v2 形式のタスクファイルに書く1タスク。depends_on に書いたタスクの後に実行される。
+type TaskDefinition struct {
+	Name      string   `yaml:"name" json:"name"`
+	Query     string   `yaml:"query" json:"query"`
+	DependsOn []string `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	// false にすると、このタスクの ALTER を同じテーブルの他の ALTER とまとめずに単独で実行する。省略時は true
+	Batch *bool `yaml:"batch,omitempty" json:"batch,omitempty"`
+	// このタスクのクエリで失敗しても警告にとどめて続行する MySQL のエラー番号 (例: 1091)
+	IgnoreErrors []int `yaml:"ignore_errors,omitempty" json:"ignore_errors,omitempty"`
+}
+
+// tasksFileV2 は version: 2 のタスクファイル
+type tasksFileV2 struct {
+	Version int              `yaml:"version"`
+	Tasks   []TaskDefinition `yaml:"tasks"`
 }
 
-func LoadConfig(commonConfigPath, tasksConfigPath string) (*Config, error) {
-	return LoadConfigWithEnvironment(commonConfigPath, tasksConfigPath, "")
+func LoadConfig(commonConfigPath string, tasksConfigPaths []string) (*Config, error) {
+	return LoadConfigWithEnvironment(commonConfigPath, tasksConfigPaths, "")
 }
 
-func LoadConfigWithEnvironment(commonConfigPath, tasksConfigPath, environment string) (*Config, error) {
-	common, err := loadCommonConfig(commonConfigPath)
+// LoadConfigWithEnvironment は共通設定とタスクファイルを読み込む。
+// タスクファイルが複数あれば、loadTasksConfigs の順序で1つにまとめる
+func LoadConfigWithEnvironment(commonConfigPath string, tasksConfigPaths []string, environment string) (*Config, error) {
+	env := resolveEnvironment(environment)
+
+	common, err := loadCommonConfig(commonConfigPath, env)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load common config: %w", err)
 	}
 
-	queries, err := loadQueriesConfig(tasksConfigPath)
+	queries, tasks, err := loadTasksConfigs(tasksConfigPaths)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load queries config: %w", err)
 	}
@@ -97,18 +652,20 @@ func LoadConfigWithEnvironment(commonConfigPath, tasksConfigPath, environment st
 		return nil, fmt.Errorf("DATABASE_DSN environment variable is not set")
 	}
 
-	env := resolveEnvironment(environment)
-
 	return &Config{
 		Common:      *common,
 		Queries:     queries,
+		Tasks:       tasks,
 		DSN:         dsn,
+		ReplicaDSNs: resolveReplicaDSNs(),
 		Environment: env,
 	}, nil
 }
 
 func LoadConfigWithoutTasks(commonConfigPath, environment string) (*Config, error) {
-	common, err := loadCommonConfig(commonConfigPath)
+	env := resolveEnvironment(environment)
+
+	common, err := loadCommonConfig(commonConfigPath, env)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load common config: %w", err)
 	}
@@ -118,41 +675,50 @@ func LoadConfigWithoutTasks(commonConfigPath, environment string) (*Config, erro
 		return nil, fmt.Errorf("DATABASE_DSN environment variable is not set")
 	}
 
-	env := resolveEnvironment(environment)
-
 	return &Config{
 		Common:      *common,
 		Queries:     []string{},
 		DSN:         dsn,
+		ReplicaDSNs: resolveReplicaDSNs(),
 		Environment: env,
 	}, nil
 }
 
-func LoadConfigWithStdin(commonConfigPath, tasksConfigPath string, useStdin bool) (*Config, error) {
-	return LoadConfigWithStdinAndEnvironment(commonConfigPath, tasksConfigPath, useStdin, "")
+func LoadConfigWithStdin(commonConfigPath string, tasksConfigPaths []string, useStdin bool) (*Config, error) {
+	return LoadConfigWithStdinAndEnvironment(commonConfigPath, tasksConfigPaths, useStdin, "")
 }
 
-func LoadConfigWithStdinAndEnvironment(commonConfigPath, tasksConfigPath string, useStdin bool, environment string) (*Config, error) {
-	common, err := loadCommonConfig(commonConfigPath)
+func LoadConfigWithStdinAndEnvironment(commonConfigPath string, tasksConfigPaths []string, useStdin bool, environment string) (*Config, error) {
+	env := resolveEnvironment(environment)
+
+	common, err := loadCommonConfig(commonConfigPath, env)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load common config: %w", err)
 	}
 
-	var queries []string
-	if tasksConfigPath != "" {
-		fileQueries, err := loadQueriesConfig(tasksConfigPath)
+	var queries, stdinQueries []string
+	var tasks []TaskDefinition
+	if len(tasksConfigPaths) > 0 {
+		fileQueries, fileTasks, err := loadTasksConfigs(tasksConfigPaths)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load queries config: %w", err)
 		}
 		queries = append(queries, fileQueries...)
+		tasks = fileTasks
 	}
 
 	if useStdin {
-		stdinQueries, err := loadQueriesFromStdin()
+		stdinQueries, err = loadQueriesFromStdin()
 		if err != nil {
 			return nil, fmt.Errorf("failed to load queries from stdin: %w", err)
 		}
 		queries = append(queries, stdinQueries...)
+		// v2 形式と併用した場合、標準入力のクエリは依存関係のないタスクとして扱う
+		if tasks != nil {
+			for i, query := range stdinQueries {
+				tasks = append(tasks, TaskDefinition{Name: fmt.Sprintf("stdin-%d", i+1), Query: query})
+			}
+		}
 	}
 
 	if len(queries) == 0 {
@@ -164,13 +730,14 @@ func LoadConfigWithStdinAndEnvironment(commonConfigPath, tasksConfigPath string,
 		return nil, fmt.Errorf("DATABASE_DSN environment variable is not set")
 	}
 
-	env := resolveEnvironment(environment)
-
 	return &Config{
-		Common:      *common,
-		Queries:     queries,
-		DSN:         dsn,
-		Environment: env,
+		Common:       *common,
+		Queries:      queries,
+		Tasks:        tasks,
+		DSN:          dsn,
+		ReplicaDSNs:  resolveReplicaDSNs(),
+		Environment:  env,
+		StdinQueries: stdinQueries,
 	}, nil
 }
 
@@ -190,32 +757,114 @@ func ResolveEnvironment(cmdLineEnv string) string {
 	return resolveEnvironment(cmdLineEnv)
 }
 
-func loadCommonConfig(path string) (*CommonConfig, error) {
+// REPLICA_DSNS はカンマ区切りでレプリカのDSNを列挙する（ローリング実行用）
+func resolveReplicaDSNs() []string {
+	var dsns []string
+	for _, dsn := range strings.Split(os.Getenv("REPLICA_DSNS"), ",") {
+		dsn = strings.TrimSpace(dsn)
+		if dsn != "" {
+			dsns = append(dsns, dsn)
+		}
+	}
+	return dsns
+}
+
+// LoadCommonConfig はデータベースに接続しないコマンドのために共通設定だけを読み込む。
+// environment (空なら ALTERGUARD_ENVIRONMENT) の profile を反映する
+func LoadCommonConfig(path, environment string) (*CommonConfig, error) {
+	return loadCommonConfig(path, resolveEnvironment(environment))
+}
+
+func loadCommonConfig(path, environment string) (*CommonConfig, error) {
 	data, err := os.ReadFile(path) // #nosec G304
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file [%s]: %w", path, err)
 	}
+	data, err = decryptIfSOPS(data, path)
+	if err != nil {
+		return nil, err
+	}
+	// 環境ごとの profile を重ねてから読み込む。PT_OSC_THRESHOLD などの環境変数は profile より優先される
+	data, err = applyProfile(data, path, environment)
+	if err != nil {
+		return nil, err
+	}
 
 	var config CommonConfig
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML [%s]: %w", path, err)
 	}
+	if err := config.DuplicateErrors.validate(); err != nil {
+		return nil, fmt.Errorf("%w [%s]", err, path)
+	}
+	if key := os.Getenv("REDACTION_HASH_KEY"); key != "" {
+		config.Redaction.HashKey = key
+	}
+	if err := redact.Validate(config.Redaction.Policy()); err != nil {
+		return nil, fmt.Errorf("%w [%s]", err, path)
+	}
 
 	// デフォルト値を設定（YAMLで明示的にfalseが設定されていない限りtrueにする）
 	if !isConnectionCheckExplicitlyDisabled(data) {
 		config.ConnectionCheck.Enabled = true
 	}
 
+	// session_vars はalterguard自身のセッションとpt-osc/pt-archiverの全てに適用する
+	config.Database.SessionVars = config.SessionVars
+	config.PtOsc.SessionVars = config.SessionVars
+	config.PtArchiver.SessionVars = config.SessionVars
+
 	// 環境変数でpt_osc_thresholdをオーバーライド
 	if envThreshold := os.Getenv("PT_OSC_THRESHOLD"); envThreshold != "" {
 		if threshold, err := strconv.ParseInt(envThreshold, 10, 64); err == nil {
 			config.PtOscThreshold = threshold
 		}
 	}
+	if envThreshold := os.Getenv("PT_OSC_SIZE_THRESHOLD_MB"); envThreshold != "" {
+		if threshold, err := strconv.ParseFloat(envThreshold, 64); err == nil {
+			config.PtOscSizeThresholdMB = threshold
+		}
+	}
 
 	return &config, nil
 }
 
+// FormatSessionVarValue は数値以外の値をシングルクォートで囲む
+func FormatSessionVarValue(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// SortedSessionVarNames は出力順を安定させるため変数名をソートして返す
+func SortedSessionVarNames(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetVarsArgument は pt-osc/pt-archiver の --set-vars に渡す値を組み立てる。
+// Percona Toolkit はカンマで分割するため、カンマを含む値は渡せず skipped として返す。
+func SetVarsArgument(vars map[string]string) (string, []string) {
+	var pairs, skipped []string
+	for _, name := range SortedSessionVarNames(vars) {
+		value := vars[name]
+		if strings.Contains(value, ",") {
+			skipped = append(skipped, name)
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, FormatSessionVarValue(value)))
+	}
+	return strings.Join(pairs, ","), skipped
+}
+
 func isConnectionCheckExplicitlyDisabled(data []byte) bool {
 	content := string(data)
 	return strings.Contains(content, "connection_check:") &&
@@ -223,64 +872,191 @@ func isConnectionCheckExplicitlyDisabled(data []byte) bool {
 }
 
 func loadQueriesConfig(path string) ([]string, error) {
-	data, err := os.ReadFile(path) // #nosec G304
+	queries, _, err := loadTasksConfig(path)
+	return queries, err
+}
+
+// loadTasksConfigs は複数のタスクファイルを指定した順に読み込んで1つにまとめる。
+// ローカルのパスにワイルドカードがあれば、一致したファイルを名前順に展開する。
+// どれかが v2 形式なら、v1 形式のクエリは「ファイル名:番号」という名前の依存関係のないタスクにする。
+// タスク名が重複していればエラーにする (depends_on は他のファイルのタスクも指定できる)
+func loadTasksConfigs(paths []string) ([]string, []TaskDefinition, error) {
+	paths, err := expandTasksPaths(paths)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file [%s]: %w", path, err)
+		return nil, nil, err
+	}
+	if len(paths) == 1 {
+		return loadTasksConfig(paths[0])
 	}
 
-	var queries []string
-	if err := yaml.Unmarshal(data, &queries); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML [%s]: %w", path, err)
+	type tasksFile struct {
+		path    string
+		queries []string
+		tasks   []TaskDefinition
+	}
+	files := make([]tasksFile, 0, len(paths))
+	hasV2 := false
+	for _, path := range paths {
+		queries, tasks, err := loadTasksConfig(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, tasksFile{path: path, queries: queries, tasks: tasks})
+		hasV2 = hasV2 || tasks != nil
 	}
 
-	if len(queries) == 0 {
-		return nil, fmt.Errorf("no queries defined in [%s]", path)
+	var queries []string
+	var tasks []TaskDefinition
+	definedIn := make(map[string]string)
+	for _, file := range files {
+		queries = append(queries, file.queries...)
+		if !hasV2 {
+			continue
+		}
+		fileTasks := file.tasks
+		if fileTasks == nil {
+			for i, query := range file.queries {
+				fileTasks = append(fileTasks, TaskDefinition{Name: fmt.Sprintf("%s:%d", filepath.Base(file.path), i+1), Query: query})
+			}
+		}
+		for _, task := range fileTasks {
+			if other, ok := definedIn[task.Name]; ok {
+				return nil, nil, fmt.Errorf("task %s is defined in both [%s] and [%s]", task.Name, other, file.path)
+			}
+			definedIn[task.Name] = file.path
+		}
+		tasks = append(tasks, fileTasks...)
 	}
+	return queries, tasks, nil
+}
 
-	for i, query := range queries {
-		if strings.TrimSpace(query) == "" {
-			return nil, fmt.Errorf("query is empty [index: %d]", i)
+// expandTasksPaths はローカルのパスのワイルドカードを展開する。
+// 一致するファイルがないパターンや、同じファイルを2回指定した場合はエラーにする
+func expandTasksPaths(paths []string) ([]string, error) {
+	var expanded []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		matches := []string{path}
+		if isGlobTasksSource(path) {
+			var err error
+			matches, err = filepath.Glob(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tasks file pattern %s: %w", path, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no tasks file matches %s", path)
+			}
+			sort.Strings(matches)
 		}
+		for _, match := range matches {
+			if seen[match] {
+				return nil, fmt.Errorf("tasks file %s is given more than once", match)
+			}
+			seen[match] = true
+			expanded = append(expanded, match)
+		}
+	}
+	if len(expanded) == 0 {
+		return nil, fmt.Errorf("no tasks file given")
 	}
+	return expanded, nil
+}
 
-	return queries, nil
+// loadTasksConfig はタスクファイルを読み込む。クエリのリストであれば v1 形式、
+// version: 2 のマッピングであれば v2 形式として、名前と依存関係を持つタスクも返す。
+func loadTasksConfig(path string) ([]string, []TaskDefinition, error) {
+	data, err := readTasksSource(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err = decryptIfSOPS(data, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML [%s]: %w", path, err)
+	}
+	if len(node.Content) > 0 && node.Content[0].Kind == yaml.MappingNode {
+		return loadTasksV2(path, data)
+	}
+
+	queries, err := parseQueryEntries(path, data)
+	return queries, nil, err
 }
 
-func loadQueriesFromStdin() ([]string, error) {
-	var queries []string
-	var currentQuery strings.Builder
+func loadTasksV2(path string, data []byte) ([]string, []TaskDefinition, error) {
+	var file tasksFileV2
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML [%s]: %w", path, err)
+	}
+	if file.Version != 2 {
+		return nil, nil, fmt.Errorf("unsupported tasks file version %d in [%s]: use a list of queries or version: 2", file.Version, path)
+	}
+	if len(file.Tasks) == 0 {
+		return nil, nil, fmt.Errorf("no tasks defined in [%s]", path)
+	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
+	var queries []string
+	for i, task := range file.Tasks {
+		if task.Name == "" {
+			return nil, nil, fmt.Errorf("task name is required [index: %d]", i)
 		}
-
-		currentQuery.WriteString(line)
-		if strings.HasSuffix(line, ";") {
-			query := strings.TrimSuffix(currentQuery.String(), ";")
-			query = strings.TrimSpace(query)
-			if query != "" {
-				queries = append(queries, query)
+		statements, err := SplitStatements(task.Query)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse query of task %s: %w", task.Name, err)
+		}
+		if len(statements) == 0 {
+			return nil, nil, fmt.Errorf("query of task %s is empty", task.Name)
+		}
+		for _, code := range task.IgnoreErrors {
+			if code <= 0 {
+				return nil, nil, fmt.Errorf("invalid error code %d in ignore_errors of task %s", code, task.Name)
 			}
-			currentQuery.Reset()
-		} else {
-			currentQuery.WriteString(" ")
 		}
+		queries = append(queries, statements...)
+	}
+	return queries, file.Tasks, nil
+}
+
+func parseQueryEntries(path string, data []byte) ([]string, error) {
+	var entries []string
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML [%s]: %w", path, err)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no queries defined in [%s]", path)
 	}
 
-	if currentQuery.Len() > 0 {
-		query := strings.TrimSpace(currentQuery.String())
-		if query != "" {
-			queries = append(queries, query)
+	// 1つのエントリに複数の文が書かれている場合は文ごとに分割する
+	var queries []string
+	for i, entry := range entries {
+		statements, err := SplitStatements(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse query [index: %d]: %w", i, err)
 		}
+		if len(statements) == 0 {
+			return nil, fmt.Errorf("query is empty [index: %d]", i)
+		}
+		queries = append(queries, statements...)
 	}
 
-	if err := scanner.Err(); err != nil {
+	return queries, nil
+}
+
+func loadQueriesFromStdin() ([]string, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
 		return nil, fmt.Errorf("failed to read from stdin: %w", err)
 	}
 
+	queries, err := SplitStatements(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse queries from stdin: %w", err)
+	}
+
 	if len(queries) == 0 {
 		return nil, fmt.Errorf("no queries provided from stdin")
 	}
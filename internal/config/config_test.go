@@ -1,6 +1,9 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -294,3 +297,212 @@ pt_osc_threshold: 1000
 		})
 	}
 }
+
+func TestResolveReplicaDSNs(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{
+			name:  "not set",
+			value: "",
+			want:  nil,
+		},
+		{
+			name:  "multiple DSNs with whitespace",
+			value: "u:p@tcp(r1:3306)/app, u:p@tcp(r2:3306)/app ,",
+			want:  []string{"u:p@tcp(r1:3306)/app", "u:p@tcp(r2:3306)/app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REPLICA_DSNS", tt.value)
+
+			got := resolveReplicaDSNs()
+			if len(got) != len(tt.want) {
+				t.Fatalf("resolveReplicaDSNs() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("resolveReplicaDSNs()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSetVarsArgument(t *testing.T) {
+	tests := []struct {
+		name        string
+		vars        map[string]string
+		want        string
+		wantSkipped []string
+	}{
+		{
+			name: "empty",
+			vars: nil,
+			want: "",
+		},
+		{
+			name: "numeric and string values sorted by name",
+			vars: map[string]string{
+				"wait_timeout":             "28800",
+				"innodb_lock_wait_timeout": "5",
+				"time_zone":                "+09:00",
+			},
+			want: "innodb_lock_wait_timeout=5,time_zone='+09:00',wait_timeout=28800",
+		},
+		{
+			name: "values containing commas are skipped",
+			vars: map[string]string{
+				"sql_mode":     "STRICT_TRANS_TABLES,NO_ZERO_DATE",
+				"lock_timeout": "'10'",
+			},
+			want:        "lock_timeout='10'",
+			wantSkipped: []string{"sql_mode"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped := SetVarsArgument(tt.vars)
+			if got != tt.want {
+				t.Errorf("SetVarsArgument() = %q, want %q", got, tt.want)
+			}
+			if len(skipped) != len(tt.wantSkipped) {
+				t.Fatalf("SetVarsArgument() skipped = %v, want %v", skipped, tt.wantSkipped)
+			}
+			for i := range skipped {
+				if skipped[i] != tt.wantSkipped[i] {
+					t.Errorf("SetVarsArgument() skipped[%d] = %v, want %v", i, skipped[i], tt.wantSkipped[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSessionVarsAreInherited(t *testing.T) {
+	tmpDir := t.TempDir()
+	commonConfigPath := filepath.Join(tmpDir, "common.yaml")
+	commonConfigContent := `pt_osc:
+  charset: utf8mb4
+session_vars:
+  innodb_lock_wait_timeout: 5
+`
+	if err := os.WriteFile(commonConfigPath, []byte(commonConfigContent), 0644); err != nil {
+		t.Fatalf("Failed to write common config: %v", err)
+	}
+	t.Setenv("DATABASE_DSN", "user:pass@tcp(localhost:3306)/testdb")
+
+	cfg, err := LoadConfigWithoutTasks(commonConfigPath, "test")
+	if err != nil {
+		t.Fatalf("LoadConfigWithoutTasks() error = %v", err)
+	}
+
+	for name, vars := range map[string]map[string]string{
+		"database":    cfg.Common.Database.SessionVars,
+		"pt_osc":      cfg.Common.PtOsc.SessionVars,
+		"pt_archiver": cfg.Common.PtArchiver.SessionVars,
+	} {
+		if vars["innodb_lock_wait_timeout"] != "5" {
+			t.Errorf("%s session vars = %v, want innodb_lock_wait_timeout=5", name, vars)
+		}
+	}
+}
+
+func TestNotificationsEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		yamlData string
+		want     bool
+	}{
+		{name: "not specified", yamlData: "pt_osc_threshold: 100\n", want: true},
+		{name: "enabled", yamlData: "notifications:\n  enabled: true\n", want: true},
+		{name: "disabled", yamlData: "notifications:\n  enabled: false\n", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &CommonConfig{}
+			if err := yaml.Unmarshal([]byte(tt.yamlData), config); err != nil {
+				t.Fatalf("Failed to unmarshal YAML: %v", err)
+			}
+			if got := config.Notifications.IsEnabled(); got != tt.want {
+				t.Errorf("IsEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactionValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		yamlData string
+		envKey   string
+		wantErr  string
+		wantKey  string
+	}{
+		{name: "elide", yamlData: "redaction:\n  mode: elide\n"},
+		{name: "hash with key", yamlData: "redaction:\n  mode: hash\n  hash_key: s3cret\n", wantKey: "s3cret"},
+		{name: "hash with key from environment", yamlData: "redaction:\n  mode: hash\n", envKey: "from-env", wantKey: "from-env"},
+		{name: "hash without key", yamlData: "redaction:\n  mode: hash\n", wantErr: "redaction.hash_key is required"},
+		{name: "unknown mode", yamlData: "redaction:\n  mode: mask\n", wantErr: `invalid redaction.mode "mask"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REDACTION_HASH_KEY", tt.envKey)
+			path := filepath.Join(t.TempDir(), "common.yaml")
+			if err := os.WriteFile(path, []byte(tt.yamlData), 0644); err != nil {
+				t.Fatalf("Failed to write common config: %v", err)
+			}
+			cfg, err := loadCommonConfig(path, "")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("loadCommonConfig() error = %v", err)
+				}
+				if cfg.Redaction.HashKey != tt.wantKey {
+					t.Errorf("Redaction.HashKey = %q, want %q", cfg.Redaction.HashKey, tt.wantKey)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadCommonConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDuplicateErrorsValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		yamlData string
+		wantErr  string
+	}{
+		{name: "valid", yamlData: "duplicate_errors:\n  codes:\n    1061: warn\n  environments:\n    prod:\n      1050: fail\n"},
+		{name: "typo in codes", yamlData: "duplicate_errors:\n  codes:\n    1061: warning\n", wantErr: `invalid policy "warning" for 1061 in duplicate_errors.codes`},
+		{name: "typo in environment", yamlData: "duplicate_errors:\n  environments:\n    prod:\n      1050: Fail\n", wantErr: `invalid policy "Fail" for 1050 in duplicate_errors.environments.prod`},
+		{name: "unknown code", yamlData: "duplicate_errors:\n  codes:\n    1146: warn\n", wantErr: "invalid error code 1146 in duplicate_errors.codes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "common.yaml")
+			if err := os.WriteFile(path, []byte(tt.yamlData), 0644); err != nil {
+				t.Fatalf("Failed to write common config: %v", err)
+			}
+			_, err := loadCommonConfig(path, "")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("loadCommonConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadCommonConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
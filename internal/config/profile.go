@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profilesKey は共通設定のうち、環境名ごとの上書きを書くキー
+const profilesKey = "profiles"
+
+// applyProfile は共通設定の profiles から environment の上書きを取り出し、トップレベルの設定に重ねた YAML を返す。
+// マッピングはキーごとに再帰的に重ね、それ以外 (値・リスト) は profile の値で置き換える。
+// profiles がなければ data をそのまま返す。profiles があるのに environment の profile がない場合はエラーにする。
+func applyProfile(data []byte, path, environment string) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML [%s]: %w", path, err)
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return data, nil
+	}
+	root := resolveAlias(document.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return data, nil
+	}
+
+	profiles := removeMappingKey(root, profilesKey)
+	if profiles == nil {
+		return data, nil
+	}
+	profiles = resolveAlias(profiles)
+	if profiles.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("profiles must be a mapping of environment names to settings [%s]", path)
+	}
+
+	if environment != "" {
+		profile := mappingValue(profiles, environment)
+		if profile == nil {
+			return nil, fmt.Errorf("no profile for environment %q in [%s] (defined: %s); add an empty profile (%s: {}) to use the defaults", environment, path, strings.Join(profileNames(profiles), ", "), environment)
+		}
+		profile = resolveAlias(profile)
+		if profile.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("profile %q must be a mapping [%s]", environment, path)
+		}
+		mergeMapping(root, profile)
+	}
+
+	merged, err := yaml.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply profile %q [%s]: %w", environment, path, err)
+	}
+	return merged, nil
+}
+
+// mergeMapping は override のキーを base に重ねる
+func mergeMapping(base, override *yaml.Node) {
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], resolveAlias(override.Content[i+1])
+		current := mappingValue(base, key.Value)
+		if current != nil {
+			current = resolveAlias(current)
+		}
+		if current != nil && current.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			mergeMapping(current, value)
+			continue
+		}
+		setMappingValue(base, key, value)
+	}
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(mapping, key, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key.Value {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, key, value)
+}
+
+// removeMappingKey は key を mapping から取り除き、その値を返す
+func removeMappingKey(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+func profileNames(profiles *yaml.Node) []string {
+	var names []string
+	for i := 0; i+1 < len(profiles.Content); i += 2 {
+		names = append(names, profiles.Content[i].Value)
+	}
+	sort.Strings(names)
+	return names
+}
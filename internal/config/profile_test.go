@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profilesConfig = `pt_osc_threshold: 1000
+pt_osc:
+  chunk_size: 500
+  max_lag: 1.5
+conflict_check:
+  policy: warn
+notifiers:
+  - type: slack
+    webhook_url: https://example.com/base
+profiles:
+  prod: &prod
+    pt_osc_threshold: 100000
+    pt_osc:
+      chunk_size: 2000
+    conflict_check:
+      policy: block
+    notifiers:
+      - type: slack
+        webhook_url: https://example.com/prod
+  prod-replica: *prod
+  dev: {}
+`
+
+func writeProfilesConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(profilesConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadCommonConfigAppliesProfile(t *testing.T) {
+	path := writeProfilesConfig(t)
+
+	for _, environment := range []string{"prod", "prod-replica"} {
+		common, err := loadCommonConfig(path, environment)
+		if err != nil {
+			t.Fatalf("loadCommonConfig(%s) error = %v", environment, err)
+		}
+		if common.PtOscThreshold != 100000 {
+			t.Errorf("%s: PtOscThreshold = %d, want 100000", environment, common.PtOscThreshold)
+		}
+		// マッピングはキーごとに重なり、profile にないキーは元の値のまま
+		if common.PtOsc.ChunkSize != 2000 || common.PtOsc.MaxLag != 1.5 {
+			t.Errorf("%s: PtOsc = %+v, want chunk_size 2000 and max_lag 1.5", environment, common.PtOsc)
+		}
+		if common.ConflictCheck.Policy != "block" {
+			t.Errorf("%s: ConflictCheck.Policy = %q, want block", environment, common.ConflictCheck.Policy)
+		}
+		if len(common.Notifiers) != 1 || common.Notifiers[0].WebhookURL != "https://example.com/prod" {
+			t.Errorf("%s: Notifiers = %+v, want only the prod webhook", environment, common.Notifiers)
+		}
+	}
+
+	for _, environment := range []string{"dev", ""} {
+		common, err := loadCommonConfig(path, environment)
+		if err != nil {
+			t.Fatalf("loadCommonConfig(%q) error = %v", environment, err)
+		}
+		if common.PtOscThreshold != 1000 || common.PtOsc.ChunkSize != 500 || common.ConflictCheck.Policy != "warn" {
+			t.Errorf("%q: got %+v, want the top-level settings", environment, common)
+		}
+	}
+}
+
+func TestLoadCommonConfigRejectsUnknownProfile(t *testing.T) {
+	path := writeProfilesConfig(t)
+
+	_, err := loadCommonConfig(path, "prd")
+	if err == nil {
+		t.Fatal("loadCommonConfig() expected error for an unknown profile")
+	}
+	if !strings.Contains(err.Error(), `no profile for environment "prd"`) || !strings.Contains(err.Error(), "dev, prod, prod-replica") {
+		t.Errorf("loadCommonConfig() error = %v", err)
+	}
+}
+
+func TestLoadCommonConfigEnvironmentVariableOverridesProfile(t *testing.T) {
+	path := writeProfilesConfig(t)
+	t.Setenv("PT_OSC_THRESHOLD", "5")
+
+	common, err := loadCommonConfig(path, "prod")
+	if err != nil {
+		t.Fatalf("loadCommonConfig() error = %v", err)
+	}
+	if common.PtOscThreshold != 5 {
+		t.Errorf("PtOscThreshold = %d, want 5 from PT_OSC_THRESHOLD", common.PtOscThreshold)
+	}
+}
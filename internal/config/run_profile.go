@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RunProfile は run --profile で選ぶ設定の組み合わせ。CronJob などで毎回同じ長いコマンドラインを書かずに済むようにする
+type RunProfile struct {
+	// --tasks-config の代わりに使うタスクファイル
+	TasksConfig string `yaml:"tasks_config"`
+	// run のフラグ名と値 (例: force-method: direct)。コマンドラインで指定したフラグが優先される
+	Flags map[string]string `yaml:"flags"`
+	// Slack の Webhook URL を読む環境変数。指定すると SLACK_WEBHOOK_URL の代わりに使う
+	SlackWebhookURLEnv string `yaml:"slack_webhook_url_env"`
+	// 指定すると notifiers をこの一覧で置き換える
+	Notifiers []NotifierConfig `yaml:"notifiers"`
+}
+
+// LoadRunProfile は共通設定の run_profiles から name のプロファイルを返す。環境ごとの profiles を重ねた後の設定から探す
+func LoadRunProfile(path, environment, name string) (*RunProfile, error) {
+	common, err := loadCommonConfig(path, resolveEnvironment(environment))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load common config: %w", err)
+	}
+
+	profile, ok := common.RunProfiles[name]
+	if !ok {
+		names := make([]string, 0, len(common.RunProfiles))
+		for n := range common.RunProfiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("run profile %q is not defined: run_profiles is empty in [%s]", name, path)
+		}
+		return nil, fmt.Errorf("run profile %q is not defined in [%s] (defined: %s)", name, path, strings.Join(names, ", "))
+	}
+	return &profile, nil
+}
+
+// SortedFlagNames は Flags のフラグ名を名前順に返す
+func (p *RunProfile) SortedFlagNames() []string {
+	names := make([]string, 0, len(p.Flags))
+	for name := range p.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const runProfilesConfig = `pt_osc_threshold: 1000
+run_profiles:
+  nightly-purge:
+    tasks_config: /etc/alterguard/purge.yaml
+    flags:
+      force-method: direct
+      artifacts-dir: /var/lib/alterguard
+    slack_webhook_url_env: SLACK_WEBHOOK_URL_PURGE
+    notifiers:
+      - type: discord
+        webhook_url_env: DISCORD_WEBHOOK_URL
+profiles:
+  prod:
+    run_profiles:
+      nightly-purge:
+        flags:
+          artifacts-dir: /mnt/prod/alterguard
+  dev: {}
+`
+
+func writeRunProfilesConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(runProfilesConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadRunProfile(t *testing.T) {
+	path := writeRunProfilesConfig(t)
+
+	profile, err := LoadRunProfile(path, "dev", "nightly-purge")
+	if err != nil {
+		t.Fatalf("LoadRunProfile() error = %v", err)
+	}
+	if profile.TasksConfig != "/etc/alterguard/purge.yaml" {
+		t.Errorf("TasksConfig = %q", profile.TasksConfig)
+	}
+	if got := profile.SortedFlagNames(); !reflect.DeepEqual(got, []string{"artifacts-dir", "force-method"}) {
+		t.Errorf("SortedFlagNames() = %v", got)
+	}
+	if profile.Flags["artifacts-dir"] != "/var/lib/alterguard" {
+		t.Errorf("artifacts-dir = %q", profile.Flags["artifacts-dir"])
+	}
+	if profile.SlackWebhookURLEnv != "SLACK_WEBHOOK_URL_PURGE" {
+		t.Errorf("SlackWebhookURLEnv = %q", profile.SlackWebhookURLEnv)
+	}
+	if len(profile.Notifiers) != 1 || profile.Notifiers[0].Type != "discord" {
+		t.Errorf("Notifiers = %+v", profile.Notifiers)
+	}
+}
+
+func TestLoadRunProfileWithEnvironmentProfile(t *testing.T) {
+	path := writeRunProfilesConfig(t)
+
+	profile, err := LoadRunProfile(path, "prod", "nightly-purge")
+	if err != nil {
+		t.Fatalf("LoadRunProfile() error = %v", err)
+	}
+	if profile.Flags["artifacts-dir"] != "/mnt/prod/alterguard" {
+		t.Errorf("artifacts-dir = %q, want the prod override", profile.Flags["artifacts-dir"])
+	}
+	if profile.Flags["force-method"] != "direct" {
+		t.Errorf("force-method = %q, want the base value", profile.Flags["force-method"])
+	}
+}
+
+func TestLoadRunProfileUnknown(t *testing.T) {
+	path := writeRunProfilesConfig(t)
+
+	_, err := LoadRunProfile(path, "dev", "weekly")
+	if err == nil || !strings.Contains(err.Error(), "defined: nightly-purge") {
+		t.Errorf("LoadRunProfile() error = %v, want the defined profiles", err)
+	}
+}
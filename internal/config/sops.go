@@ -0,0 +1,430 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"gopkg.in/yaml.v3"
+)
+
+const sopsDecryptTimeout = 2 * time.Minute
+
+// sopsValuePattern は SOPS が暗号化した値の形式
+var sopsValuePattern = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.*),tag:(.*),type:(.*)\]$`)
+
+// sopsMetadata は SOPS で暗号化したファイルのトップレベルの sops の内容のうち、復号に使うもの
+type sopsMetadata struct {
+	Age              []sopsAgeKey `yaml:"age"`
+	KMS              []sopsKMSKey `yaml:"kms"`
+	KeyGroups        []yaml.Node  `yaml:"key_groups"`
+	ShamirThreshold  int          `yaml:"shamir_threshold"`
+	LastModified     string       `yaml:"lastmodified"`
+	MAC              string       `yaml:"mac"`
+	MACOnlyEncrypted bool         `yaml:"mac_only_encrypted"`
+}
+
+type sopsAgeKey struct {
+	Recipient string `yaml:"recipient"`
+	Enc       string `yaml:"enc"`
+}
+
+type sopsKMSKey struct {
+	ARN        string            `yaml:"arn"`
+	Role       string            `yaml:"role"`
+	Context    map[string]string `yaml:"context"`
+	Enc        string            `yaml:"enc"`
+	AWSProfile string            `yaml:"aws_profile"`
+}
+
+// inProcess はプロセス内で復号できるかを返す。
+// age と AWS KMS の鍵だけを扱い、鍵グループ (Shamir) と mac_only_encrypted は sops コマンドに任せる
+func (m sopsMetadata) inProcess() bool {
+	return (len(m.Age) > 0 || len(m.KMS) > 0) && len(m.KeyGroups) == 0 && m.ShamirThreshold == 0 && !m.MACOnlyEncrypted
+}
+
+// sopsCommand は復号に使う sops コマンド。SOPS_BINARY 環境変数で差し替えられる
+func sopsCommand() string {
+	if path := os.Getenv("SOPS_BINARY"); path != "" {
+		return path
+	}
+	return "sops"
+}
+
+// isSOPSEncrypted は YAML が SOPS で暗号化されている (トップレベルに sops.mac を持つ) かを返す
+func isSOPSEncrypted(data []byte) bool {
+	var doc struct {
+		SOPS *struct {
+			MAC string `yaml:"mac"`
+		} `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	return doc.SOPS != nil && doc.SOPS.MAC != ""
+}
+
+// decryptIfSOPS は SOPS で暗号化された YAML であれば復号した内容を返し、そうでなければそのまま返す。
+// age と AWS KMS の鍵で暗号化されたファイルはプロセス内で復号し、MAC を確かめる。
+// それ以外の鍵 (PGP・GCP KMS など) や鍵グループを使うファイルは sops コマンドで復号する。
+// どちらの場合も平文がディスクに書かれることはない。
+func decryptIfSOPS(data []byte, location string) ([]byte, error) {
+	if !isSOPSEncrypted(data) {
+		return data, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML [%s]: %w", redactURL(location), err)
+	}
+	root, metadata, err := splitSOPSMetadata(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SOPS metadata of %s: %w", redactURL(location), err)
+	}
+	if !metadata.inProcess() {
+		return decryptWithSOPSCommand(data, location)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sopsDecryptTimeout)
+	defer cancel()
+	key, err := sopsDataKey(ctx, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s with SOPS: %w", redactURL(location), err)
+	}
+	if err := decryptSOPSTree(root, key, metadata); err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s with SOPS: %w", redactURL(location), err)
+	}
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s with SOPS: %w", redactURL(location), err)
+	}
+	return out, nil
+}
+
+// decryptWithSOPSCommand は sops コマンドで復号する。
+// 暗号化された内容は標準入力で渡し、復号した内容は標準出力から受け取る
+func decryptWithSOPSCommand(data []byte, location string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sopsDecryptTimeout)
+	defer cancel()
+
+	command := sopsCommand()
+	cmd := exec.CommandContext(ctx, command, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", "/dev/stdin") // #nosec G204
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s is encrypted with SOPS keys that are not age or AWS KMS, but the %s command was not found: install sops (https://github.com/getsops/sops) or set SOPS_BINARY", redactURL(location), command)
+		}
+		return nil, fmt.Errorf("failed to decrypt %s with sops: %w: %s", redactURL(location), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// splitSOPSMetadata はトップレベルのマッピングから sops を取り除き、そのマッピングと sops の内容を返す
+func splitSOPSMetadata(doc *yaml.Node) (*yaml.Node, sopsMetadata, error) {
+	var metadata sopsMetadata
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, metadata, fmt.Errorf("top level must be a mapping")
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "sops" {
+			continue
+		}
+		if err := root.Content[i+1].Decode(&metadata); err != nil {
+			return nil, metadata, err
+		}
+		root.Content = append(root.Content[:i], root.Content[i+2:]...)
+		return root, metadata, nil
+	}
+	return nil, metadata, fmt.Errorf("sops metadata not found")
+}
+
+// sopsDataKey は age の鍵、AWS KMS の鍵の順に試してデータキーを復号する
+func sopsDataKey(ctx context.Context, metadata sopsMetadata) ([]byte, error) {
+	var errs []error
+	if len(metadata.Age) > 0 {
+		identities, err := loadAgeIdentities()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, key := range metadata.Age {
+			if len(identities) == 0 {
+				break
+			}
+			dataKey, err := decryptAgeDataKey(key, identities)
+			if err == nil {
+				return dataKey, nil
+			}
+			errs = append(errs, fmt.Errorf("age %s: %w", key.Recipient, err))
+		}
+	}
+	for _, key := range metadata.KMS {
+		dataKey, err := decryptKMSDataKey(ctx, key)
+		if err == nil {
+			return dataKey, nil
+		}
+		errs = append(errs, fmt.Errorf("kms %s: %w", key.ARN, err))
+	}
+	return nil, fmt.Errorf("no key could decrypt the data key: %w", errors.Join(errs...))
+}
+
+// loadAgeIdentities は sops と同じく SOPS_AGE_KEY、SOPS_AGE_KEY_FILE、
+// ユーザー設定ディレクトリの sops/age/keys.txt から age の秘密鍵を読み込む
+func loadAgeIdentities() ([]age.Identity, error) {
+	var identities []age.Identity
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		parsed, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SOPS_AGE_KEY: %w", err)
+		}
+		identities = append(identities, parsed...)
+	}
+
+	path := os.Getenv("SOPS_AGE_KEY_FILE")
+	if path == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "sops", "age", "keys.txt")
+		}
+	}
+	if path != "" {
+		data, err := os.ReadFile(path) // #nosec G304
+		switch {
+		case err == nil:
+			parsed, err := age.ParseIdentities(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse age keys in %s: %w", path, err)
+			}
+			identities = append(identities, parsed...)
+		case errors.Is(err, os.ErrNotExist) && os.Getenv("SOPS_AGE_KEY_FILE") == "":
+		default:
+			return nil, fmt.Errorf("failed to read age keys: %w", err)
+		}
+	}
+
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no age identity found: set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE")
+	}
+	return identities, nil
+}
+
+func decryptAgeDataKey(key sopsAgeKey, identities []age.Identity) ([]byte, error) {
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(key.Enc)), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// decryptKMSDataKey は AWS KMS でデータキーを復号する。
+// 認証情報は AWS SDK の通常の順序で探し、aws_profile と role があればそれを使う
+func decryptKMSDataKey(ctx context.Context, key sopsKMSKey) ([]byte, error) {
+	blob, err := base64.StdEncoding.DecodeString(key.Enc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid enc: %w", err)
+	}
+	parts := strings.Split(key.ARN, ":")
+	if len(parts) < 6 || parts[3] == "" {
+		return nil, fmt.Errorf("invalid arn")
+	}
+
+	options := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(parts[3])}
+	if key.AWSProfile != "" {
+		options = append(options, awsconfig.WithSharedConfigProfile(key.AWSProfile))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	if key.Role != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), key.Role, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = "alterguard"
+		}))
+	}
+
+	input := &kms.DecryptInput{
+		KeyId:          aws.String(key.ARN),
+		CiphertextBlob: blob,
+	}
+	if len(key.Context) > 0 {
+		input.EncryptionContext = key.Context
+	}
+	out, err := kms.NewFromConfig(cfg).Decrypt(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
+
+// decryptSOPSTree は暗号化された値をその場で復号し、sops と同じ手順で計算した MAC が sops.mac と一致するかを確かめる。
+// MAC は sops を除くすべての値を文書の順に連結した SHA-512 で、値を書き換えたり入れ替えたりすると一致しなくなる
+func decryptSOPSTree(root *yaml.Node, key []byte, metadata sopsMetadata) error {
+	hash := sha512.New()
+	err := walkSOPSValues(root, nil, func(node *yaml.Node, path []string) error {
+		if match := sopsValuePattern.FindStringSubmatch(node.Value); match != nil {
+			plaintext, err := decryptSOPSValue(match, key, strings.Join(path, ":")+":")
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", strings.Join(path, "."), err)
+			}
+			if err := setSOPSValue(node, plaintext, match[4]); err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", strings.Join(path, "."), err)
+			}
+		}
+		value, err := sopsMACBytes(node)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", strings.Join(path, "."), err)
+		}
+		hash.Write(value)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	match := sopsValuePattern.FindStringSubmatch(metadata.MAC)
+	if match == nil {
+		return fmt.Errorf("invalid sops.mac")
+	}
+	lastModified, err := time.Parse(time.RFC3339, metadata.LastModified)
+	if err != nil {
+		return fmt.Errorf("invalid sops.lastmodified: %w", err)
+	}
+	mac, err := decryptSOPSValue(match, key, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt sops.mac: %w", err)
+	}
+	if string(mac) != fmt.Sprintf("%X", hash.Sum(nil)) {
+		return fmt.Errorf("MAC mismatch: the file was modified after it was encrypted")
+	}
+	return nil
+}
+
+// walkSOPSValues は sops と同じ順序でスカラー値をたどる。
+// path はマッピングのキーの並びで、リストの要素はリストと同じ path を持つ。暗号化されたコメントは取り除く
+func walkSOPSValues(node *yaml.Node, path []string, visit func(*yaml.Node, []string) error) error {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			node.Content[i].HeadComment, node.Content[i].LineComment, node.Content[i].FootComment = "", "", ""
+			if err := walkSOPSValues(node.Content[i+1], append(path[:len(path):len(path)], node.Content[i].Value), visit); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := walkSOPSValues(item, path, visit); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return visit(node, path)
+	default:
+		return fmt.Errorf("%s: anchors and aliases are not supported in SOPS-encrypted files", strings.Join(path, "."))
+	}
+	return nil
+}
+
+// decryptSOPSValue は ENC[AES256_GCM,...] の値を AES-GCM で復号する。additionalData は値の path
+func decryptSOPSValue(match []string, key []byte, additionalData string) ([]byte, error) {
+	var parts [3][]byte
+	for i := range parts {
+		decoded, err := base64.StdEncoding.DecodeString(match[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid encrypted value: %w", err)
+		}
+		parts[i] = decoded
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	return plaintext, nil
+}
+
+// setSOPSValue は復号した値を暗号化前の型のスカラーに戻す
+func setSOPSValue(node *yaml.Node, plaintext []byte, valueType string) error {
+	value := string(plaintext)
+	switch valueType {
+	case "str", "bytes":
+		node.Tag = "!!str"
+	case "int":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("invalid int: %w", err)
+		}
+		node.Tag = "!!int"
+	case "float":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("invalid float: %w", err)
+		}
+		node.Tag = "!!float"
+	case "bool":
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool: %w", err)
+		}
+		node.Tag, value = "!!bool", strconv.FormatBool(parsed)
+	default:
+		return fmt.Errorf("unsupported type %q", valueType)
+	}
+	node.Value = value
+	node.Style = 0
+	return nil
+}
+
+// sopsMACBytes は MAC の計算に使う値のバイト列を sops と同じ形式で返す
+func sopsMACBytes(node *yaml.Node) ([]byte, error) {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case int:
+		return []byte(strconv.Itoa(v)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case bool:
+		if v {
+			return []byte("True"), nil
+		}
+		return []byte("False"), nil
+	default:
+		return []byte(fmt.Sprintf("%v", v)), nil
+	}
+}
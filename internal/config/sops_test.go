@@ -0,0 +1,330 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+const sopsEncryptedTasks = `version: 2
+tasks:
+  - name: purge
+    query: ENC[AES256_GCM,data:abc,iv:def,tag:ghi,type:str]
+sops:
+  pgp:
+    - fp: 85D77543B3D624B63CEA9E6DBC17301B491B3F21
+  lastmodified: "2024-01-01T00:00:00Z"
+  mac: ENC[AES256_GCM,data:mac,iv:iv,tag:tag,type:str]
+  version: 3.8.1
+`
+
+// writeFakeSOPS は受け取った引数を記録し、決まった平文を出力する sops の代わりのスクリプトを作る
+func writeFakeSOPS(t *testing.T, output string, exitCode int) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	plainFile := filepath.Join(dir, "plain.yaml")
+	if err := os.WriteFile(plainFile, []byte(output), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "sops")
+	content := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat > /dev/null\ncat " + plainFile + "\necho 'decrypt failed' >&2\nexit " + strconv.Itoa(exitCode) + "\n"
+	if err := os.WriteFile(script, []byte(content), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return script, argsFile
+}
+
+func TestIsSOPSEncrypted(t *testing.T) {
+	if !isSOPSEncrypted([]byte(sopsEncryptedTasks)) {
+		t.Error("expected SOPS-encrypted file to be detected")
+	}
+	for _, data := range []string{"- ALTER TABLE users ADD COLUMN age INT\n", "version: 2\ntasks: []\n", "sops: plain\n", ":"} {
+		if isSOPSEncrypted([]byte(data)) {
+			t.Errorf("expected %q not to be detected as SOPS-encrypted", data)
+		}
+	}
+}
+
+func TestLoadTasksConfigDecryptsSOPS(t *testing.T) {
+	plain := "version: 2\ntasks:\n  - name: purge\n    query: DELETE FROM users WHERE id = 1\n"
+	script, argsFile := writeFakeSOPS(t, plain, 0)
+	t.Setenv("SOPS_BINARY", script)
+
+	path := filepath.Join(t.TempDir(), "tasks.enc.yaml")
+	if err := os.WriteFile(path, []byte(sopsEncryptedTasks), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	queries, tasks, err := loadTasksConfig(path)
+	if err != nil {
+		t.Fatalf("loadTasksConfig() error = %v", err)
+	}
+	if len(tasks) != 1 || len(queries) != 1 || queries[0] != "DELETE FROM users WHERE id = 1" {
+		t.Errorf("unexpected result: queries=%v tasks=%v", queries, tasks)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(args)); got != "--decrypt --input-type yaml --output-type yaml /dev/stdin" {
+		t.Errorf("unexpected sops arguments %q", got)
+	}
+}
+
+func TestLoadCommonConfigSOPSErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "common.enc.yaml")
+	if err := os.WriteFile(path, []byte("pt_osc_threshold: ENC[AES256_GCM,data:x,iv:y,tag:z,type:int]\n"+sopsEncryptedTasks[strings.Index(sopsEncryptedTasks, "sops:"):]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	script, _ := writeFakeSOPS(t, "", 1)
+	t.Setenv("SOPS_BINARY", script)
+	if _, err := loadCommonConfig(path, ""); err == nil || !strings.Contains(err.Error(), "decrypt failed") {
+		t.Errorf("expected sops failure to be reported, got %v", err)
+	}
+
+	t.Setenv("SOPS_BINARY", filepath.Join(t.TempDir(), "missing-sops"))
+	if _, err := loadCommonConfig(path, ""); err == nil || !strings.Contains(err.Error(), "encrypted with SOPS") || !strings.Contains(err.Error(), "SOPS_BINARY") {
+		t.Errorf("expected missing sops command to be reported, got %v", err)
+	}
+}
+
+// sealSOPSValue は sops と同じ形式で値を AES-GCM で暗号化する
+func sealSOPSValue(t *testing.T, key []byte, plaintext, additionalData, valueType string) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := make([]byte, 32)
+	if _, err := rand.Read(iv); err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := gcm.Seal(nil, iv, []byte(plaintext), []byte(additionalData))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data), base64.StdEncoding.EncodeToString(iv), base64.StdEncoding.EncodeToString(tag), valueType)
+}
+
+// encryptSOPS は plain のうち encrypted に挙げたキーの値を sops と同じ形式で暗号化し、MAC と metadata を付けた YAML を返す
+func encryptSOPS(t *testing.T, plain string, dataKey []byte, encrypted []string, metadata map[string]interface{}) string {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(plain), &doc); err != nil {
+		t.Fatal(err)
+	}
+	root := doc.Content[0]
+	hash := sha512.New()
+	err := walkSOPSValues(root, nil, func(node *yaml.Node, path []string) error {
+		value, err := sopsMACBytes(node)
+		if err != nil {
+			return err
+		}
+		hash.Write(value)
+		for _, name := range encrypted {
+			if path[len(path)-1] != name {
+				continue
+			}
+			valueType := map[string]string{"!!int": "int", "!!float": "float", "!!bool": "bool"}[node.ShortTag()]
+			if valueType == "" {
+				valueType = "str"
+			}
+			node.Value = sealSOPSValue(t, dataKey, string(value), strings.Join(path, ":")+":", valueType)
+			node.Tag, node.Style = "!!str", 0
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lastModified := "2024-01-01T00:00:00Z"
+	metadata["lastmodified"] = lastModified
+	metadata["mac"] = sealSOPSValue(t, dataKey, fmt.Sprintf("%X", hash.Sum(nil)), lastModified, "str")
+	metadata["version"] = "3.9.4"
+	var metadataNode yaml.Node
+	if err := metadataNode.Encode(metadata); err != nil {
+		t.Fatal(err)
+	}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "sops"}, &metadataNode)
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// encryptAgeDataKey は sops と同じくデータキーを age で暗号化して ASCII armor にする
+func encryptAgeDataKey(t *testing.T, dataKey []byte, recipient age.Recipient) string {
+	t.Helper()
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(dataKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := armored.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func newSOPSDataKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestLoadTasksConfigDecryptsSOPSWithAgeInProcess(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey := newSOPSDataKey(t)
+	plain := "version: 2\ntasks:\n  - name: purge\n    query: DELETE FROM users WHERE email = 'user@db.internal'\n"
+	encrypted := encryptSOPS(t, plain, dataKey, []string{"query"}, map[string]interface{}{
+		"age": []map[string]string{{"recipient": identity.Recipient().String(), "enc": encryptAgeDataKey(t, dataKey, identity.Recipient())}},
+	})
+	if strings.Contains(encrypted, "user@db.internal") {
+		t.Fatalf("query was not encrypted:\n%s", encrypted)
+	}
+
+	// sops コマンドがなくても復号できる
+	t.Setenv("SOPS_BINARY", filepath.Join(t.TempDir(), "missing-sops"))
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("SOPS_AGE_KEY", identity.String())
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.enc.yaml")
+	if err := os.WriteFile(path, []byte(encrypted), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	queries, tasks, err := loadTasksConfig(path)
+	if err != nil {
+		t.Fatalf("loadTasksConfig() error = %v", err)
+	}
+	if len(tasks) != 1 || len(queries) != 1 || queries[0] != "DELETE FROM users WHERE email = 'user@db.internal'" {
+		t.Errorf("unexpected result: queries=%v tasks=%v", queries, tasks)
+	}
+
+	keyFile := filepath.Join(dir, "keys.txt")
+	if err := os.WriteFile(keyFile, []byte("# created: 2024-01-01T00:00:00Z\n"+identity.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", keyFile)
+	if _, _, err := loadTasksConfig(path); err != nil {
+		t.Errorf("expected SOPS_AGE_KEY_FILE to be used, got %v", err)
+	}
+
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	t.Setenv("SOPS_AGE_KEY", other.String())
+	if _, _, err := loadTasksConfig(path); err == nil || !strings.Contains(err.Error(), "no key could decrypt the data key") {
+		t.Errorf("expected a wrong age key to be reported, got %v", err)
+	}
+
+	t.Setenv("SOPS_AGE_KEY", identity.String())
+	tampered := strings.Replace(encrypted, "name: purge", "name: other", 1)
+	if err := os.WriteFile(path, []byte(tampered), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadTasksConfig(path); err == nil || !strings.Contains(err.Error(), "MAC mismatch") {
+		t.Errorf("expected tampering with an unencrypted value to be detected, got %v", err)
+	}
+}
+
+func TestLoadCommonConfigDecryptsSOPSWithKMSInProcess(t *testing.T) {
+	dataKey := newSOPSDataKey(t)
+	const arn = "arn:aws:kms:ap-northeast-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	blob := []byte("encrypted-data-key")
+
+	var target string
+	var request struct {
+		KeyId             string
+		CiphertextBlob    []byte
+		EncryptionContext map[string]string
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.Header.Get("X-Amz-Target")
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"KeyId": arn, "Plaintext": dataKey})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for _, key := range []string{"AWS_SESSION_TOKEN", "AWS_REGION", "AWS_PROFILE", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "AWS_CA_BUNDLE", "AWS_ENDPOINT_URL",
+		"AWS_IGNORE_CONFIGURED_ENDPOINT_URLS", "SOPS_AGE_KEY", "SOPS_AGE_KEY_FILE", "PT_OSC_THRESHOLD"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv("AWS_ENDPOINT_URL_KMS", server.URL)
+	t.Setenv("SOPS_BINARY", filepath.Join(dir, "missing-sops"))
+
+	encrypted := encryptSOPS(t, "pt_osc_threshold: 12345\n", dataKey, []string{"pt_osc_threshold"}, map[string]interface{}{
+		"kms": []map[string]interface{}{{
+			"arn":        arn,
+			"context":    map[string]string{"app": "alterguard"},
+			"created_at": "2024-01-01T00:00:00Z",
+			"enc":        base64.StdEncoding.EncodeToString(blob),
+		}},
+	})
+	path := filepath.Join(dir, "common.enc.yaml")
+	if err := os.WriteFile(path, []byte(encrypted), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadCommonConfig(path, "")
+	if err != nil {
+		t.Fatalf("loadCommonConfig() error = %v", err)
+	}
+	if cfg.PtOscThreshold != 12345 {
+		t.Errorf("expected pt_osc_threshold 12345, got %d", cfg.PtOscThreshold)
+	}
+	if target != "TrentService.Decrypt" || request.KeyId != arn || string(request.CiphertextBlob) != string(blob) || request.EncryptionContext["app"] != "alterguard" {
+		t.Errorf("unexpected KMS request: target=%q request=%+v", target, request)
+	}
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	remoteFetchTimeout = 2 * time.Minute
+	// 誤って巨大なファイルを読み込まないための上限
+	maxRemoteTasksSize = 10 * 1024 * 1024
+)
+
+var httpClient = &http.Client{Timeout: remoteFetchTimeout}
+
+// ?checksum=sha256:<hex> (go-getter と同じ書式)
+var checksumParamRe = regexp.MustCompile(`([?&])checksum=sha256:([0-9a-fA-F]{64})(&|$)`)
+
+// readTasksSource はタスク定義をローカルファイル・https URL・git::<repo>//<path>@<ref> のいずれかから読み込む。
+// ?checksum=sha256:<hex> が付いている場合は内容のハッシュが一致することを確認する。
+func readTasksSource(location string) ([]byte, error) {
+	location, checksum := extractChecksum(location)
+
+	var data []byte
+	var err error
+	switch {
+	case strings.HasPrefix(location, "git::"):
+		data, err = readGitSource(location)
+	case strings.HasPrefix(location, "https://"):
+		data, err = readHTTPSource(location)
+	case strings.HasPrefix(location, "http://"):
+		return nil, fmt.Errorf("refusing to load tasks over plain http, use https: %s", redactURL(location))
+	default:
+		data, err = os.ReadFile(location) // #nosec G304
+		if err != nil {
+			err = fmt.Errorf("failed to read file [%s]: %w", location, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if checksum != "" {
+		sum := sha256.Sum256(data)
+		if actual := hex.EncodeToString(sum[:]); actual != checksum {
+			return nil, fmt.Errorf("checksum mismatch for %s: expected sha256:%s, got sha256:%s", redactURL(location), checksum, actual)
+		}
+	}
+
+	return data, nil
+}
+
+// extractChecksum はロケーションから checksum パラメータを取り除き、期待するハッシュ値を返す
+// isGlobTasksSource は location がワイルドカードを含むローカルのパスなら true を返す。
+// URL や git:: と、?checksum= を付けたパスは展開しない
+func isGlobTasksSource(location string) bool {
+	if strings.HasPrefix(location, "git::") || strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://") {
+		return false
+	}
+	if _, checksum := extractChecksum(location); checksum != "" {
+		return false
+	}
+	return strings.ContainsAny(location, "*?[")
+}
+
+func extractChecksum(location string) (string, string) {
+	matches := checksumParamRe.FindStringSubmatchIndex(location)
+	if matches == nil {
+		return location, ""
+	}
+
+	checksum := strings.ToLower(location[matches[4]:matches[5]])
+	separator := location[matches[2]:matches[3]]
+	rest := location[matches[6]:matches[7]]
+	if rest == "&" {
+		// 後続のパラメータを残す
+		return location[:matches[0]] + separator + location[matches[1]:], checksum
+	}
+	return location[:matches[0]], checksum
+}
+
+func readHTTPSource(location string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tasks URL %s: %w", redactURL(location), err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", redactURL(location), err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: unexpected status %s", redactURL(location), resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteTasksSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", redactURL(location), err)
+	}
+	if len(data) > maxRemoteTasksSize {
+		return nil, fmt.Errorf("tasks file at %s exceeds %d bytes", redactURL(location), maxRemoteTasksSize)
+	}
+	return data, nil
+}
+
+type gitSource struct {
+	repo string
+	path string
+	ref  string
+}
+
+// parseGitSource は git::<repo>//<path>@<ref> を分解する。ref を省略した場合はリモートの HEAD を使う。
+func parseGitSource(location string) (gitSource, error) {
+	rest := strings.TrimPrefix(location, "git::")
+
+	searchFrom := 0
+	if i := strings.Index(rest, "://"); i >= 0 {
+		searchFrom = i + len("://")
+	}
+	sep := strings.Index(rest[searchFrom:], "//")
+	if sep < 0 {
+		return gitSource{}, fmt.Errorf("invalid git locator %s: expected git::<repo>//<path>[@<ref>]", location)
+	}
+
+	src := gitSource{repo: rest[:searchFrom+sep]}
+	pathRef := rest[searchFrom+sep+2:]
+	if at := strings.LastIndex(pathRef, "@"); at >= 0 {
+		src.path, src.ref = pathRef[:at], pathRef[at+1:]
+	} else {
+		src.path = pathRef
+	}
+
+	if src.repo == "" || src.path == "" {
+		return gitSource{}, fmt.Errorf("invalid git locator %s: expected git::<repo>//<path>[@<ref>]", location)
+	}
+	if src.ref == "" {
+		src.ref = "HEAD"
+	}
+	if strings.HasPrefix(src.ref, "-") {
+		return gitSource{}, fmt.Errorf("invalid git ref %q", src.ref)
+	}
+	return src, nil
+}
+
+func readGitSource(location string) ([]byte, error) {
+	src, err := parseGitSource(location)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "alterguard-tasks-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteFetchTimeout)
+	defer cancel()
+
+	// ブランチ・タグ・コミットのいずれでも取得できるよう、clone ではなく fetch を使う
+	if _, err := runGit(ctx, dir, "init", "--quiet"); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(ctx, dir, "fetch", "--quiet", "--depth", "1", "--", src.repo, src.ref); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from %s: %w", src.ref, redactURL(src.repo), err)
+	}
+	data, err := runGit(ctx, dir, "show", "FETCH_HEAD:"+src.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at %s from %s: %w", src.path, src.ref, redactURL(src.repo), err)
+	}
+	return data, nil
+}
+
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...) // #nosec G204
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// redactURL はエラーメッセージに認証情報が残らないようにする
+func redactURL(location string) string {
+	u, err := url.Parse(location)
+	if err != nil || u.User == nil {
+		return location
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const remoteTasks = "- ALTER TABLE users ADD COLUMN age INT\n"
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestExtractChecksum(t *testing.T) {
+	sum := sha256Hex("x")
+	tests := []struct {
+		name         string
+		location     string
+		wantLocation string
+		wantChecksum string
+	}{
+		{
+			name:         "no checksum",
+			location:     "https://example.com/tasks.yaml",
+			wantLocation: "https://example.com/tasks.yaml",
+		},
+		{
+			name:         "only parameter",
+			location:     "https://example.com/tasks.yaml?checksum=sha256:" + sum,
+			wantLocation: "https://example.com/tasks.yaml",
+			wantChecksum: sum,
+		},
+		{
+			name:         "keeps other parameters",
+			location:     "https://example.com/tasks.yaml?checksum=sha256:" + strings.ToUpper(sum) + "&token=abc",
+			wantLocation: "https://example.com/tasks.yaml?token=abc",
+			wantChecksum: sum,
+		},
+		{
+			name:         "git locator",
+			location:     "git::https://github.com/org/repo.git//db/tasks.yaml@main?checksum=sha256:" + sum,
+			wantLocation: "git::https://github.com/org/repo.git//db/tasks.yaml@main",
+			wantChecksum: sum,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLocation, gotChecksum := extractChecksum(tt.location)
+			if gotLocation != tt.wantLocation {
+				t.Errorf("extractChecksum() location = %v, want %v", gotLocation, tt.wantLocation)
+			}
+			if gotChecksum != tt.wantChecksum {
+				t.Errorf("extractChecksum() checksum = %v, want %v", gotChecksum, tt.wantChecksum)
+			}
+		})
+	}
+}
+
+func TestParseGitSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		want     gitSource
+		wantErr  bool
+	}{
+		{
+			name:     "https repository with branch",
+			location: "git::https://github.com/org/repo.git//db/tasks.yaml@main",
+			want:     gitSource{repo: "https://github.com/org/repo.git", path: "db/tasks.yaml", ref: "main"},
+		},
+		{
+			name:     "ssh repository with commit",
+			location: "git::git@github.com:org/repo.git//tasks.yaml@0123abcd",
+			want:     gitSource{repo: "git@github.com:org/repo.git", path: "tasks.yaml", ref: "0123abcd"},
+		},
+		{
+			name:     "ref omitted",
+			location: "git::https://github.com/org/repo.git//tasks.yaml",
+			want:     gitSource{repo: "https://github.com/org/repo.git", path: "tasks.yaml", ref: "HEAD"},
+		},
+		{
+			name:     "missing path",
+			location: "git::https://github.com/org/repo.git",
+			wantErr:  true,
+		},
+		{
+			name:     "option-like ref",
+			location: "git::https://github.com/org/repo.git//tasks.yaml@--upload-pack=x",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGitSource(tt.location)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGitSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseGitSource() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTasksSourceHTTPS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tasks.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(remoteTasks))
+	}))
+	defer server.Close()
+
+	originalClient := httpClient
+	httpClient = server.Client()
+	defer func() { httpClient = originalClient }()
+
+	tests := []struct {
+		name     string
+		location string
+		wantErr  bool
+	}{
+		{name: "without checksum", location: server.URL + "/tasks.yaml"},
+		{name: "matching checksum", location: server.URL + "/tasks.yaml?checksum=sha256:" + sha256Hex(remoteTasks)},
+		{name: "checksum mismatch", location: server.URL + "/tasks.yaml?checksum=sha256:" + sha256Hex("other"), wantErr: true},
+		{name: "not found", location: server.URL + "/missing.yaml", wantErr: true},
+		{name: "plain http", location: strings.Replace(server.URL, "https://", "http://", 1) + "/tasks.yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := readTasksSource(tt.location)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readTasksSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(data) != remoteTasks {
+				t.Errorf("readTasksSource() = %q, want %q", data, remoteTasks)
+			}
+		})
+	}
+}
+
+func TestReadTasksSourceGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+
+	git("init", "--quiet", "--initial-branch=main")
+	if err := os.MkdirAll(filepath.Join(repo, "db"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "db", "tasks.yaml"), []byte(remoteTasks), 0644); err != nil {
+		t.Fatalf("Failed to write tasks: %v", err)
+	}
+	git("add", ".")
+	git("commit", "--quiet", "-m", "add tasks")
+
+	location := "git::file://" + repo + "//db/tasks.yaml@main?checksum=sha256:" + sha256Hex(remoteTasks)
+	queries, err := loadQueriesConfig(location)
+	if err != nil {
+		t.Fatalf("loadQueriesConfig() error = %v", err)
+	}
+	if len(queries) != 1 || queries[0] != "ALTER TABLE users ADD COLUMN age INT" {
+		t.Errorf("loadQueriesConfig() = %q", queries)
+	}
+
+	if _, err := readTasksSource("git::file://" + repo + "//db/missing.yaml@main"); err == nil {
+		t.Error("readTasksSource() expected error for missing path")
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitStatements はSQLをセミコロンで文ごとに分割する。
+// 文字列リテラル・識別子のクォート・コメント中のセミコロンでは分割しない。
+// コメントは取り除き(/*! ... */ の実行コメントと /* alterguard:... */ の指定は残す)、クォート外の連続する空白は1つにまとめる。
+func SplitStatements(sql string) ([]string, error) {
+	var statements []string
+	var current strings.Builder
+	pendingSpace := false
+
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		if statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+		pendingSpace = false
+	}
+	write := func(s string) {
+		if pendingSpace && current.Len() > 0 {
+			current.WriteByte(' ')
+		}
+		pendingSpace = false
+		current.WriteString(s)
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end, err := findQuoteEnd(sql, i)
+			if err != nil {
+				return nil, err
+			}
+			write(sql[i:end])
+			i = end
+		case c == '#' || isDashComment(sql, i):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end
+			}
+			pendingSpace = true
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment starting at offset %d", i)
+			}
+			end += i + 4
+			if strings.HasPrefix(sql[i:], "/*!") || isDirectiveComment(sql[i:end]) {
+				write(sql[i:end])
+			} else {
+				pendingSpace = true
+			}
+			i = end
+		case c == ';':
+			flush()
+			i++
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pendingSpace = true
+			i++
+		default:
+			write(sql[i : i+1])
+			i++
+		}
+	}
+	flush()
+
+	return statements, nil
+}
+
+// isDirectiveComment は alterguard への指定を書いたコメント (例: /* alterguard:no-batch */) かどうかを判定する
+func isDirectiveComment(comment string) bool {
+	body := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/"))
+	return strings.HasPrefix(strings.ToLower(body), "alterguard:")
+}
+
+// isDashComment は "-- " 形式のコメントかどうかを判定する。MySQLでは "--" の後に空白か行末が必要。
+func isDashComment(sql string, i int) bool {
+	if !strings.HasPrefix(sql[i:], "--") {
+		return false
+	}
+	if i+2 == len(sql) {
+		return true
+	}
+	next := sql[i+2]
+	return next == ' ' || next == '\t' || next == '\n' || next == '\r'
+}
+
+// findQuoteEnd はクォートの終端の次の位置を返す。
+// クォート文字を2つ重ねたエスケープと、文字列リテラル中のバックスラッシュエスケープを扱う。
+func findQuoteEnd(sql string, start int) (int, error) {
+	quote := sql[start]
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted string starting at offset %d", start)
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "multiple statements across lines",
+			sql:  "ALTER TABLE users\n  ADD COLUMN age INT;\nCREATE TABLE foo (id INT);\n",
+			want: []string{"ALTER TABLE users ADD COLUMN age INT", "CREATE TABLE foo (id INT)"},
+		},
+		{
+			name: "alterguard directive comments are kept",
+			sql:  "/* alterguard:no-batch */ ALTER TABLE users ADD UNIQUE INDEX uq_email (email); /* note */ DROP TABLE foo",
+			want: []string{"/* alterguard:no-batch */ ALTER TABLE users ADD UNIQUE INDEX uq_email (email)", "DROP TABLE foo"},
+		},
+		{
+			name: "last statement without semicolon",
+			sql:  "ALTER TABLE users ADD INDEX idx_age (age)",
+			want: []string{"ALTER TABLE users ADD INDEX idx_age (age)"},
+		},
+		{
+			name: "semicolons inside string literals",
+			sql:  `ALTER TABLE users ADD COLUMN note VARCHAR(20) DEFAULT 'a;b' COMMENT "it''s; \"ok\"";`,
+			want: []string{`ALTER TABLE users ADD COLUMN note VARCHAR(20) DEFAULT 'a;b' COMMENT "it''s; \"ok\""`},
+		},
+		{
+			name: "escaped quotes",
+			sql:  `ALTER TABLE users ALTER COLUMN note SET DEFAULT 'it''s;\';x'; DROP TABLE foo;`,
+			want: []string{`ALTER TABLE users ALTER COLUMN note SET DEFAULT 'it''s;\';x'`, "DROP TABLE foo"},
+		},
+		{
+			name: "semicolon inside quoted identifier",
+			sql:  "ALTER TABLE `weird;name` ADD COLUMN foo INT;",
+			want: []string{"ALTER TABLE `weird;name` ADD COLUMN foo INT"},
+		},
+		{
+			name: "comments are removed",
+			sql:  "-- add age; later\nALTER TABLE users /* temp; */ ADD COLUMN age INT; # done;\n",
+			want: []string{"ALTER TABLE users ADD COLUMN age INT"},
+		},
+		{
+			name: "executable comments are kept",
+			sql:  "CREATE TABLE foo (id INT) /*!50100 PARTITION BY HASH(id) */;",
+			want: []string{"CREATE TABLE foo (id INT) /*!50100 PARTITION BY HASH(id) */"},
+		},
+		{
+			name: "double dash without space is not a comment",
+			sql:  "ALTER TABLE users ALTER COLUMN n SET DEFAULT 1--1;",
+			want: []string{"ALTER TABLE users ALTER COLUMN n SET DEFAULT 1--1"},
+		},
+		{
+			name: "whitespace inside literals is preserved",
+			sql:  "ALTER TABLE users ADD COLUMN note TEXT COMMENT 'a   b';",
+			want: []string{"ALTER TABLE users ADD COLUMN note TEXT COMMENT 'a   b'"},
+		},
+		{
+			name: "only comments and separators",
+			sql:  "-- nothing\n;;\n",
+			want: nil,
+		},
+		{
+			name:    "unterminated string",
+			sql:     "ALTER TABLE users ADD COLUMN note TEXT DEFAULT 'oops;",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated comment",
+			sql:     "ALTER TABLE users /* oops",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitStatements(tt.sql)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitStatements() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadQueriesConfigSplitsMultiStatementEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.yaml")
+	content := `- |
+  ALTER TABLE users ADD COLUMN note VARCHAR(10) DEFAULT 'x;y';
+  ALTER TABLE users ADD INDEX idx_note (note);
+- DROP TABLE old_users
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write tasks config: %v", err)
+	}
+
+	got, err := loadQueriesConfig(path)
+	if err != nil {
+		t.Fatalf("loadQueriesConfig() error = %v", err)
+	}
+	want := []string{
+		"ALTER TABLE users ADD COLUMN note VARCHAR(10) DEFAULT 'x;y'",
+		"ALTER TABLE users ADD INDEX idx_note (note)",
+		"DROP TABLE old_users",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadQueriesConfig() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadTasksConfigV2(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.yaml")
+	content := `version: 2
+tasks:
+  - name: add-fk
+    query: ALTER TABLE orders ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id)
+    depends_on: [create-users]
+  - name: create-users
+    query: |
+      CREATE TABLE users (id INT PRIMARY KEY);
+      ALTER TABLE users ADD COLUMN name VARCHAR(10);
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write tasks config: %v", err)
+	}
+
+	queries, tasks, err := loadTasksConfig(path)
+	if err != nil {
+		t.Fatalf("loadTasksConfig() error = %v", err)
+	}
+	if len(queries) != 3 {
+		t.Errorf("loadTasksConfig() queries = %q, want 3 statements", queries)
+	}
+	if len(tasks) != 2 || tasks[0].Name != "add-fk" || !reflect.DeepEqual(tasks[0].DependsOn, []string{"create-users"}) {
+		t.Errorf("loadTasksConfig() tasks = %+v", tasks)
+	}
+
+	for _, invalid := range []string{
+		"version: 3\ntasks:\n  - name: a\n    query: DROP TABLE a\n",
+		"version: 2\ntasks:\n  - query: DROP TABLE a\n",
+		"version: 2\ntasks:\n  - name: a\n    query: ''\n",
+		"version: 2\ntasks:\n  - name: a\n    query: DROP TABLE a\n    ignore_errors: [0]\n",
+	} {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatalf("Failed to write tasks config: %v", err)
+		}
+		if _, _, err := loadTasksConfig(path); err == nil {
+			t.Errorf("loadTasksConfig(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestLoadTasksConfigsMergesFilesInOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"20-payments.yaml": "- ALTER TABLE payments ADD COLUMN note TEXT\n",
+		"10-users.yaml":    "- ALTER TABLE users ADD COLUMN name VARCHAR(10)\n- ALTER TABLE users ADD INDEX idx_name (name)\n",
+		"orders.yaml":      "version: 2\ntasks:\n  - name: orders-fk\n    query: ALTER TABLE orders ADD INDEX idx_user (user_id)\n    depends_on: [10-users.yaml:1]\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write tasks config: %v", err)
+		}
+	}
+
+	// v1 形式だけなら、ワイルドカードの一致を名前順に並べてクエリをつなげる
+	queries, tasks, err := loadTasksConfigs([]string{filepath.Join(dir, "*-*.yaml")})
+	if err != nil {
+		t.Fatalf("loadTasksConfigs() error = %v", err)
+	}
+	want := []string{
+		"ALTER TABLE users ADD COLUMN name VARCHAR(10)",
+		"ALTER TABLE users ADD INDEX idx_name (name)",
+		"ALTER TABLE payments ADD COLUMN note TEXT",
+	}
+	if !reflect.DeepEqual(queries, want) || tasks != nil {
+		t.Errorf("loadTasksConfigs() = %q, %+v, want %q and no tasks", queries, tasks, want)
+	}
+
+	// v2 形式が混ざれば、v1 形式のクエリもタスクにする
+	_, tasks, err = loadTasksConfigs([]string{filepath.Join(dir, "10-users.yaml"), filepath.Join(dir, "orders.yaml")})
+	if err != nil {
+		t.Fatalf("loadTasksConfigs() error = %v", err)
+	}
+	var names []string
+	for _, task := range tasks {
+		names = append(names, task.Name)
+	}
+	if wantNames := []string{"10-users.yaml:1", "10-users.yaml:2", "orders-fk"}; !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("loadTasksConfigs() task names = %q, want %q", names, wantNames)
+	}
+
+	for _, invalid := range [][]string{
+		{filepath.Join(dir, "missing-*.yaml")},
+		{filepath.Join(dir, "orders.yaml"), filepath.Join(dir, "orders.yaml")},
+		{filepath.Join(dir, "10-users.yaml"), filepath.Join(dir, "1*.yaml")},
+	} {
+		if _, _, err := loadTasksConfigs(invalid); err == nil {
+			t.Errorf("loadTasksConfigs(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestLoadTasksConfigsRejectsDuplicateTaskNames(t *testing.T) {
+	dir := t.TempDir()
+	content := "version: 2\ntasks:\n  - name: add-index\n    query: ALTER TABLE users ADD INDEX idx_name (name)\n"
+	paths := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write tasks config: %v", err)
+		}
+	}
+
+	_, _, err := loadTasksConfigs(paths)
+	if err == nil || !strings.Contains(err.Error(), "task add-index is defined in both") {
+		t.Errorf("loadTasksConfigs() error = %v, want duplicate task error", err)
+	}
+}
@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	AlterAlgorithmInstant = "INSTANT"
+	AlterAlgorithmInplace = "INPLACE"
+	AlterAlgorithmCopy    = "COPY"
+	// テーブルは作り直されなかったが、INSTANT か INPLACE かは区別できなかった
+	AlterAlgorithmNoRebuild = "NO_REBUILD"
+)
+
+// AlterAlgorithm はダイレクトALTERでサーバーが実際に使ったアルゴリズムとロック
+type AlterAlgorithm struct {
+	Algorithm string
+	Lock      string
+	// テーブルが作り直されたか(InnoDBのTABLE_IDが変わったか)
+	Rebuilt bool
+	// ALTER文にALGORITHM/LOCKが明示されていたか
+	Specified bool
+}
+
+func (a *AlterAlgorithm) String() string {
+	var description string
+	switch a.Algorithm {
+	case AlterAlgorithmInstant:
+		description = "instant"
+	case AlterAlgorithmInplace:
+		description = "in-place"
+		if a.Rebuilt {
+			description = "in-place with table rebuild"
+		}
+	case AlterAlgorithmCopy:
+		description = "table rebuild (copy)"
+	case AlterAlgorithmNoRebuild:
+		description = "instant or in-place without table rebuild"
+	default:
+		description = "unknown"
+		if a.Rebuilt {
+			description = "table rebuild"
+		}
+	}
+	if a.Lock != "" {
+		description += fmt.Sprintf(", LOCK=%s", a.Lock)
+	}
+	if a.Specified {
+		description += " (as specified)"
+	}
+	return description
+}
+
+var (
+	algorithmClauseRegex = regexp.MustCompile(`(?i)\bALGORITHM\s*=\s*(\w+)`)
+	lockClauseRegex      = regexp.MustCompile(`(?i)\bLOCK\s*=\s*(\w+)`)
+)
+
+// innodbTableState はALTERの前後で比べるInnoDBのテーブルの状態
+type innodbTableState struct {
+	tableID int64
+	// INSTANT で追加・削除したカラムの数(8.0.29以降は行バージョン)。取得できなければ -1
+	instantVersion int64
+}
+
+// ExecuteAlterWithAlgorithm はALTERを書かれたとおりに1回だけ実行し、サーバーが使ったアルゴリズムを
+// 影響行数とInnoDBのテーブルの状態の前後比較から判定して返す。
+// ALGORITHM/LOCKを付け替えて再実行すると、失敗の理由によっては二重に適用しかねないため行わない。
+func (c *MySQLClient) ExecuteAlterWithAlgorithm(ctx context.Context, tableName, alterStatement string) (*AlterAlgorithm, error) {
+	before, stateErr := c.getInnoDBTableState(tableName)
+	if stateErr != nil {
+		c.logger.Debugf("Failed to get InnoDB table id for %s, rebuild detection disabled: %v", tableName, stateErr)
+	}
+
+	execResult, err := c.executeAlter(ctx, alterStatement)
+	if err != nil {
+		return nil, err
+	}
+
+	var after *innodbTableState
+	if stateErr == nil {
+		after, err = c.getInnoDBTableState(tableName)
+		if err != nil {
+			c.logger.Debugf("Failed to get InnoDB table id for %s after ALTER: %v", tableName, err)
+		}
+	}
+	affectedRows, err := execResult.RowsAffected()
+	if err != nil {
+		affectedRows = 0
+	}
+
+	result := detectAlterAlgorithm(alterStatement, affectedRows, before, after)
+	c.logger.Infof("ALTER on %s used algorithm: %s", tableName, result.String())
+	return result, nil
+}
+
+// detectAlterAlgorithm は実行後の情報からアルゴリズムを判定する。
+// COPY は複製した行数を影響行数として返し、INPLACE/INSTANT は 0 を返す。
+// テーブルが作り直されれば TABLE_ID が変わり、INSTANT でカラムを変えればそのカウンタが増える。
+func detectAlterAlgorithm(alterStatement string, affectedRows int64, before, after *innodbTableState) *AlterAlgorithm {
+	result := &AlterAlgorithm{}
+	if match := algorithmClauseRegex.FindStringSubmatch(alterStatement); match != nil {
+		result.Algorithm = strings.ToUpper(match[1])
+		result.Specified = true
+	}
+	if match := lockClauseRegex.FindStringSubmatch(alterStatement); match != nil {
+		result.Lock = strings.ToUpper(match[1])
+		result.Specified = true
+	}
+
+	compared := before != nil && after != nil
+	if compared {
+		result.Rebuilt = after.tableID != before.tableID
+	}
+	if affectedRows > 0 {
+		result.Rebuilt = true
+	}
+	if result.Algorithm != "" && result.Algorithm != "DEFAULT" {
+		if result.Algorithm == AlterAlgorithmCopy {
+			result.Rebuilt = true
+		}
+		return result
+	}
+
+	switch {
+	case affectedRows > 0:
+		result.Algorithm = AlterAlgorithmCopy
+	case !compared:
+		// 前後を比べられなければ不明のままにする
+	case result.Rebuilt:
+		result.Algorithm = AlterAlgorithmInplace
+	case before.instantVersion >= 0 && after.instantVersion > before.instantVersion:
+		result.Algorithm = AlterAlgorithmInstant
+	default:
+		result.Algorithm = AlterAlgorithmNoRebuild
+	}
+	return result
+}
+
+// getInnoDBTableState はテーブル再作成とINSTANTの検出に使う。8.0はINNODB_TABLES、5.7はINNODB_SYS_TABLES。
+func (c *MySQLClient) getInnoDBTableState(tableName string) (*innodbTableState, error) {
+	sources := []struct {
+		table   string
+		version string
+	}{
+		{table: "INNODB_TABLES", version: "TOTAL_ROW_VERSIONS"},
+		{table: "INNODB_TABLES", version: "INSTANT_COLS"},
+		{table: "INNODB_SYS_TABLES", version: "-1"},
+	}
+	var lastErr error
+	for _, source := range sources {
+		var state struct {
+			TableID        int64 `db:"table_id"`
+			InstantVersion int64 `db:"instant_version"`
+		}
+		query := fmt.Sprintf("SELECT TABLE_ID AS table_id, %s AS instant_version FROM information_schema.%s WHERE NAME = CONCAT(DATABASE(), '/', ?)",
+			source.version, source.table)
+		if err := c.get(&state, query, tableName); err != nil {
+			lastErr = err
+			continue
+		}
+		return &innodbTableState{tableID: state.TableID, instantVersion: state.InstantVersion}, nil
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlterAlgorithmString(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm AlterAlgorithm
+		expected  string
+	}{
+		{name: "instant", algorithm: AlterAlgorithm{Algorithm: AlterAlgorithmInstant}, expected: "instant"},
+		{name: "in-place", algorithm: AlterAlgorithm{Algorithm: AlterAlgorithmInplace, Lock: "NONE"}, expected: "in-place, LOCK=NONE"},
+		{name: "in-place rebuild", algorithm: AlterAlgorithm{Algorithm: AlterAlgorithmInplace, Lock: "NONE", Rebuilt: true}, expected: "in-place with table rebuild, LOCK=NONE"},
+		{name: "copy", algorithm: AlterAlgorithm{Algorithm: AlterAlgorithmCopy, Lock: "SHARED", Rebuilt: true}, expected: "table rebuild (copy), LOCK=SHARED"},
+		{name: "specified", algorithm: AlterAlgorithm{Algorithm: AlterAlgorithmInplace, Lock: "SHARED", Specified: true}, expected: "in-place, LOCK=SHARED (as specified)"},
+		{name: "no rebuild", algorithm: AlterAlgorithm{Algorithm: AlterAlgorithmNoRebuild}, expected: "instant or in-place without table rebuild"},
+		{name: "unknown", algorithm: AlterAlgorithm{}, expected: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.algorithm.String())
+		})
+	}
+}
+
+func TestDetectAlterAlgorithm(t *testing.T) {
+	before := &innodbTableState{tableID: 10, instantVersion: 0}
+	tests := []struct {
+		name         string
+		statement    string
+		affectedRows int64
+		after        *innodbTableState
+		expected     AlterAlgorithm
+	}{
+		{name: "copy", statement: "ALTER TABLE users MODIFY age BIGINT", affectedRows: 100, after: &innodbTableState{tableID: 11},
+			expected: AlterAlgorithm{Algorithm: AlterAlgorithmCopy, Rebuilt: true}},
+		{name: "in-place rebuild", statement: "ALTER TABLE users ADD PRIMARY KEY (id)", after: &innodbTableState{tableID: 11},
+			expected: AlterAlgorithm{Algorithm: AlterAlgorithmInplace, Rebuilt: true}},
+		{name: "instant", statement: "ALTER TABLE users ADD COLUMN age INT", after: &innodbTableState{tableID: 10, instantVersion: 1},
+			expected: AlterAlgorithm{Algorithm: AlterAlgorithmInstant}},
+		{name: "no rebuild", statement: "ALTER TABLE users ADD INDEX idx_age (age)", after: &innodbTableState{tableID: 10},
+			expected: AlterAlgorithm{Algorithm: AlterAlgorithmNoRebuild}},
+		{name: "not compared", statement: "ALTER TABLE users ADD INDEX idx_age (age)",
+			expected: AlterAlgorithm{}},
+		{name: "specified", statement: "ALTER TABLE users ADD INDEX idx_age (age), ALGORITHM=INPLACE, LOCK=NONE", after: &innodbTableState{tableID: 10},
+			expected: AlterAlgorithm{Algorithm: AlterAlgorithmInplace, Lock: "NONE", Specified: true}},
+		{name: "specified lock only", statement: "ALTER TABLE users ADD INDEX idx_age (age), LOCK=SHARED", after: &innodbTableState{tableID: 10},
+			expected: AlterAlgorithm{Algorithm: AlterAlgorithmNoRebuild, Lock: "SHARED", Specified: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := tt.after
+			b := before
+			if after == nil {
+				b = nil
+			}
+			assert.Equal(t, tt.expected, *detectAlterAlgorithm(tt.statement, tt.affectedRows, b, after))
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksumExpression(t *testing.T) {
+	got := checksumExpression("_users_new", []string{"id", "name"})
+	assert.Equal(t, "SELECT CONCAT(COUNT(*), '/', COALESCE(BIT_XOR(CRC32(CONCAT_WS('#', `id`, `name`, CONCAT(ISNULL(`id`), ISNULL(`name`))))), 0)) FROM `_users_new`", got)
+}
@@ -1,128 +1,409 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"net"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	"github.com/pyama86/alterguard/internal/config"
 	"github.com/sirupsen/logrus"
 )
 
 type Client interface {
 	GetTableRowCount(table string) (int64, error)
+	GetTableRowCounts(tables []string) (map[string]int64, error)
 	GetNewTableRowCount(tableName string) (int64, error)
 	GetTableRowCountForSwap(table string) (int64, error)
+	CountTableRows(table string, timeout time.Duration) (int64, error)
+	CompareTableChecksums(tableName, newTableName string, columns []string, timeout time.Duration) (string, string, error)
 	GetNewTableRowCountForSwap(tableName string) (int64, error)
 	ExecuteAlter(alterStatement string) error
+	ExecuteAlterContext(ctx context.Context, alterStatement string) error
 	ExecuteAlterWithDryRun(alterStatement string, dryRun bool) error
 	SetSessionConfig(lockWaitTimeout, innodbLockWaitTimeout int) error
 	TableExists(tableName string) (bool, error)
 	CheckNewTableExists(tableName string) (bool, error)
-	HasOtherActiveConnections() (bool, string, error)
+	HasOtherActiveConnections(excludeIDs []int64) (bool, string, error)
+	GetOtherActiveConnections(excludeIDs []int64) ([]ActiveConnection, string, error)
 	GetCurrentUser() (string, error)
-	AnalyzeTable(tableName string) error
+	AnalyzeTable(tableName string, timeout time.Duration) error
+	WarmupIndex(tableName, indexName string, timeout time.Duration) (int64, error)
 	GetTableBufferPoolSizeMB(schemaName, tableName string) (float64, error)
 	GetMaxAuroraReplicaLagMs() (float64, error)
+	GetReplicaLagSeconds() (float64, error)
+	ExecuteAlterWithoutBinlog(alterStatement string) error
+	ExecuteAlterWithAlgorithm(ctx context.Context, tableName, alterStatement string) (*AlterAlgorithm, error)
+	GetTriggerNames(tableName string) ([]string, error)
+	GetTableSizeMB(tableName string) (float64, error)
+	GetTableStorage(tableName string) (*TableStorage, error)
+	GetBufferPoolSizeMB() (float64, error)
+	CountProcessesReferencingTable(tableName string) (int, error)
+	GetRunningPtOscOperations() ([]PtOscOperation, error)
+	GetBlockingSessions(tableName string) ([]BlockingSession, error)
+	EvaluateHealthQuery(query string) (bool, error)
+	CountErrorLogEntries(tableName string, window time.Duration) (int64, error)
+	GetConnectionUsage() (connected int64, maxConnections int64, err error)
+	GetReplicationSettings() (*ReplicationSettings, error)
+	GetBinlogSettings() (*BinlogSettings, error)
+	GetScheduledEvents(tableName string) ([]ScheduledEvent, error)
+	GetAutoIncrementColumn(tableName string) (string, error)
+	GetMaxIntValue(tableName, column string) (int64, error)
+	GetAutoIncrementValue(tableName string) (int64, error)
+	GetTableCharsetInfo(tableName string) (*TableCharsetInfo, error)
+	GetMaxUnixTime(tableName, column string) (int64, error)
+	GetTableDependencies(tableName string) ([]TableDependency, error)
+	KillSession(id int64) error
+	AcquireNamedLock(name string) (lock NamedLock, acquired bool, err error)
+	GetPrimaryKeyColumns(tableName string) ([]string, error)
+	GetTableStructure(tableName string) (*TableStructure, error)
+	GetCreateTable(tableName string) (string, error)
+	ListTables() ([]string, error)
+	SchemaExists(schema string) (bool, error)
+	CreateShadowSchema(schema string, tables []string) error
+	ExecuteInSchema(schema, statement string) error
+	DropSchema(schema string) error
+	CopyChunk(source, target string, keyColumns []string, after []any, chunkSize int) (next []any, copied int64, err error)
+	GetLastKey(tableName string, keyColumns []string) ([]string, error)
 	Close() error
 }
 
+// IsTransientError はリトライで回復しうる一時的なエラーかどうかを判定する。
+// デッドロック・ロック待ちタイムアウト・接続断が対象で、構文エラー等の意味的なエラーは含まない。
+func IsTransientError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1213, // Deadlock found when trying to get lock
+			1205, // Lock wait timeout exceeded
+			2013, // Lost connection to MySQL server during query
+			2006: // MySQL server has gone away
+			return true
+		}
+		return false
+	}
+	return errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, driver.ErrBadConn)
+}
+
+// IsDuplicateError は既定で警告扱いにする重複系のエラーかどうかを判定する
 func IsDuplicateError(err error) bool {
-	if mysqlErr, ok := err.(*mysql.MySQLError); ok {
-		return mysqlErr.Number == 1062 || // Duplicate entry
-			mysqlErr.Number == 1061 || // Duplicate key name
-			mysqlErr.Number == 1050 // Table already exists
+	code, ok := DuplicateErrorCode(err)
+	return ok && code != 1060
+}
+
+// DuplicateErrorCode は重複系のエラーであればそのエラー番号を返す。
+// 1060 (Duplicate column name) は既定では失敗扱いだが、duplicate_errors で警告扱いにできるため含める。
+func DuplicateErrorCode(err error) (int, bool) {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return 0, false
+	}
+	switch mysqlErr.Number {
+	case 1062, // Duplicate entry
+		1061, // Duplicate key name
+		1060, // Duplicate column name
+		1050: // Table already exists
+		return int(mysqlErr.Number), true
+	}
+	return 0, false
+}
+
+// DescribeDSN はパスワードを含まないホスト表記(host:port/db)を返す。ログや通知用。
+func DescribeDSN(dsn string) string {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "unknown-host"
+	}
+	if cfg.DBName != "" {
+		return fmt.Sprintf("%s/%s", cfg.Addr, cfg.DBName)
 	}
-	return false
+	return cfg.Addr
+}
+
+// DeriveDSN は dsn の接続先を host に置き換えた DSN を返す。ユーザー・パスワード・DB名・パラメータは dsn のものを使う。
+// host にポートがなければ dsn のポートを引き継ぐ
+func DeriveDSN(dsn, host string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse DSN: %w", err)
+	}
+	if host == "" {
+		return "", fmt.Errorf("host is empty")
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		port := "3306"
+		if _, p, err := net.SplitHostPort(cfg.Addr); err == nil {
+			port = p
+		}
+		host = net.JoinHostPort(host, port)
+	}
+	cfg.Net = "tcp"
+	cfg.Addr = host
+	return cfg.FormatDSN(), nil
 }
 
 type MySQLClient struct {
-	db     *sqlx.DB
-	logger *logrus.Logger
+	db            *sqlx.DB
+	logger        *logrus.Logger
+	retry         retryPolicy
+	rowCount      rowCountPolicy
+	pingBeforeUse bool
 }
 
 func NewMySQLClient(dsn string, logger *logrus.Logger) (*MySQLClient, error) {
+	return NewMySQLClientWithConfig(dsn, logger, config.DatabaseConfig{})
+}
+
+func NewMySQLClientWithConfig(dsn string, logger *logrus.Logger, dbConfig config.DatabaseConfig) (*MySQLClient, error) {
+	retry, err := newRetryPolicy(dbConfig.Retry)
+	if err != nil {
+		return nil, err
+	}
+	rowCount, err := newRowCountPolicy(dbConfig.RowCount)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn, err = applySessionVars(dsn, dbConfig.SessionVars)
+	if err != nil {
+		return nil, err
+	}
+
 	db, err := sqlx.Connect("mysql", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := applyPoolConfig(db, dbConfig); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &MySQLClient{db: db, logger: logger}, nil
+	return &MySQLClient{db: db, logger: logger, retry: retry, rowCount: rowCount, pingBeforeUse: dbConfig.PingBeforeUse}, nil
 }
 
-func (c *MySQLClient) GetTableRowCount(table string) (int64, error) {
-	var count int64
-	var usedMethod string
-
-	// 第一選択: INNODB_SYS_TABLESTATS (MySQL 5.7)
-	query := `
-		SELECT NUM_ROWS
-		FROM information_schema.INNODB_SYS_TABLESTATS
-		WHERE NAME = CONCAT(DATABASE(), '/', ?)
-	`
+// applySessionVars はセッション変数をDSNのパラメータとして追加する。
+// ドライバが新しいコネクションごとに SET を発行するため、プール内の全コネクションに反映される。
+func applySessionVars(dsn string, vars map[string]string) (string, error) {
+	if len(vars) == 0 {
+		return dsn, nil
+	}
 
-	err := c.db.Get(&count, query, table)
+	cfg, err := mysql.ParseDSN(dsn)
 	if err != nil {
-		// 第二選択: INNODB_TABLESTATS (MySQL 8.0+)
-		c.logger.Debugf("Failed to get row count from INNODB_SYS_TABLESTATS for %s, trying INNODB_TABLESTATS: %v", table, err)
-		query = `
-			SELECT NUM_ROWS
-			FROM information_schema.INNODB_TABLESTATS
-			WHERE NAME = CONCAT(DATABASE(), '/', ?)
-		`
-		err = c.db.Get(&count, query, table)
+		return "", fmt.Errorf("failed to parse DSN: %w", err)
+	}
+	if cfg.Params == nil {
+		cfg.Params = make(map[string]string)
+	}
+	for _, name := range config.SortedSessionVarNames(vars) {
+		cfg.Params[name] = config.FormatSessionVarValue(vars[name])
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// applyPoolConfig はコネクションプールの設定を反映する。0/未設定の項目はdatabase/sqlのデフォルトのまま。
+func applyPoolConfig(db *sqlx.DB, dbConfig config.DatabaseConfig) error {
+	if dbConfig.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	}
+	if dbConfig.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	}
+	if dbConfig.ConnMaxLifetime != "" {
+		d, err := time.ParseDuration(dbConfig.ConnMaxLifetime)
+		if err != nil {
+			return fmt.Errorf("invalid database.conn_max_lifetime: %w", err)
+		}
+		db.SetConnMaxLifetime(d)
+	}
+	if dbConfig.ConnMaxIdleTime != "" {
+		d, err := time.ParseDuration(dbConfig.ConnMaxIdleTime)
 		if err != nil {
-			// 第三選択: information_schema.TABLES
-			c.logger.Debugf("Failed to get row count from INNODB_TABLESTATS for %s, trying information_schema.TABLES: %v", table, err)
-			query = `
-				SELECT TABLE_ROWS
-				FROM information_schema.TABLES
-				WHERE table_schema = DATABASE() AND table_name = ?
-			`
-			err = c.db.Get(&count, query, table)
-			if err != nil {
-				// フォールバック: COUNT(*)
-				c.logger.Warnf("Failed to get row count from all stats tables for %s, falling back to COUNT(*): %v", table, err)
-
-				countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", table)
-				err = c.db.Get(&count, countQuery)
-				if err != nil {
-					return 0, fmt.Errorf("failed to get table row count for %s: %w", table, err)
-				}
-				c.logger.Infof("Used COUNT(*) for table %s: %d rows", table, count)
-				return count, nil
+			return fmt.Errorf("invalid database.conn_max_idle_time: %w", err)
+		}
+		db.SetConnMaxIdleTime(d)
+	}
+	return nil
+}
+
+// pingIfNeeded はアイドル中にNATやプロキシで切断された接続を使う前に検出し、プールから破棄させる
+func (c *MySQLClient) pingIfNeeded() {
+	if !c.pingBeforeUse {
+		return
+	}
+	if err := c.db.Ping(); err != nil {
+		c.logger.Warnf("Ping before use failed, connection will be re-established: %v", err)
+	}
+}
+
+func (c *MySQLClient) get(dest any, query string, args ...any) error {
+	return c.retry.do(c.logger, func() error {
+		c.pingIfNeeded()
+		return c.db.Get(dest, query, args...)
+	})
+}
+
+func (c *MySQLClient) selectRows(dest any, query string, args ...any) error {
+	return c.retry.do(c.logger, func() error {
+		c.pingIfNeeded()
+		return c.db.Select(dest, query, args...)
+	})
+}
+
+// exec は書き込みを実行する。接続が切れたエラー(2006/2013)でもサーバー側では実行済みのことがあり、
+// DDL や KILL を再送すると二重に適用されるため、get/selectRows と違ってリトライしない
+func (c *MySQLClient) exec(query string, args ...any) (sql.Result, error) {
+	c.pingIfNeeded()
+	return c.db.Exec(query, args...)
+}
+
+// execContext は exec と同じく1回だけ実行する。ctx がキャンセルされたら、コネクションを閉じるだけでは
+// サーバー側の ALTER が止まらないため、実行中のコネクションに KILL QUERY を送って中断させる
+func (c *MySQLClient) execContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if ctx.Done() == nil {
+		return c.exec(query, args...)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.pingIfNeeded()
+	conn, err := c.db.Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var connectionID int64
+	if err := conn.GetContext(ctx, &connectionID, "SELECT CONNECTION_ID()"); err != nil {
+		return nil, fmt.Errorf("failed to get connection id: %w", err)
+	}
+
+	done := make(chan struct{})
+	killed := make(chan struct{})
+	go func() {
+		defer close(killed)
+		select {
+		case <-ctx.Done():
+			c.logger.Warnf("Interrupting query on connection %d: %v", connectionID, ctx.Err())
+			if _, err := c.db.Exec(fmt.Sprintf("KILL QUERY %d", connectionID)); err != nil {
+				c.logger.Errorf("Failed to kill query on connection %d: %v", connectionID, err)
 			}
-			usedMethod = "information_schema.TABLES"
-			c.logger.Debugf("Used information_schema.TABLES for table %s: %d rows", table, count)
-		} else {
-			usedMethod = "INNODB_TABLESTATS"
-			c.logger.Debugf("Used INNODB_TABLESTATS for table %s: %d rows", table, count)
+		case <-done:
 		}
-	} else {
-		usedMethod = "INNODB_SYS_TABLESTATS"
-		c.logger.Debugf("Used INNODB_SYS_TABLESTATS for table %s: %d rows", table, count)
+	}()
+
+	// ctx を渡すとドライバーがコネクションを閉じてしまい KILL QUERY の結果を待てないため、ここでは渡さない
+	result, err := conn.ExecContext(context.Background(), query, args...)
+	close(done)
+	<-killed
+	if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+		return nil, fmt.Errorf("%w (interrupted: %w)", err, ctxErr)
 	}
+	return result, err
+}
 
-	// 統計情報が0件の場合は、COUNT(*)で正確な件数を確認
-	if count == 0 {
-		c.logger.Infof("Stats show 0 rows for table %s (from %s), verifying with COUNT(*)", table, usedMethod)
-		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", table)
-		var actualCount int64
-		err = c.db.Get(&actualCount, countQuery)
+func (c *MySQLClient) GetTableRowCount(table string) (int64, error) {
+	return c.getTableRowCountWithDB(retryingExecutor{c}, table)
+}
+
+// batchRowCountQueries は GetTableRowCount と同じ優先順位で統計情報を参照するクエリ
+var batchRowCountQueries = []struct {
+	source string
+	query  string
+}{
+	{
+		source: "INNODB_SYS_TABLESTATS",
+		query: `
+			SELECT SUBSTRING_INDEX(NAME, '/', -1) AS table_name, NUM_ROWS AS row_count
+			FROM information_schema.INNODB_SYS_TABLESTATS
+			WHERE NAME IN (?)
+		`,
+	},
+	{
+		source: "INNODB_TABLESTATS",
+		query: `
+			SELECT SUBSTRING_INDEX(NAME, '/', -1) AS table_name, NUM_ROWS AS row_count
+			FROM information_schema.INNODB_TABLESTATS
+			WHERE NAME IN (?)
+		`,
+	},
+	{
+		source: "information_schema.TABLES",
+		query: `
+			SELECT table_name AS table_name, TABLE_ROWS AS row_count
+			FROM information_schema.TABLES
+			WHERE table_schema = DATABASE() AND CONCAT(table_schema, '/', table_name) IN (?)
+		`,
+	},
+}
+
+// GetTableRowCounts は複数テーブルの行数を統計情報から1往復でまとめて取得する。
+// 統計情報が取れなかったテーブルや0件のテーブル、count-always / estimate のテーブルは結果に含めないため、
+// 呼び出し側で GetTableRowCount にフォールバックする。
+func (c *MySQLClient) GetTableRowCounts(tables []string) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	if len(tables) == 0 {
+		return counts, nil
+	}
+
+	var schema string
+	if err := c.get(&schema, "SELECT DATABASE()"); err != nil {
+		return nil, fmt.Errorf("failed to get current database: %w", err)
+	}
+
+	names := make([]string, 0, len(tables))
+	for _, table := range tables {
+		// 統計情報を使わない数え方のテーブルは GetTableRowCount で数える
+		if strategy := c.rowCount.forTable(table).strategy; strategy == RowCountCountAlways || strategy == RowCountEstimate {
+			continue
+		}
+		names = append(names, fmt.Sprintf("%s/%s", schema, table))
+	}
+	if len(names) == 0 {
+		return counts, nil
+	}
+
+	var lastErr error
+	for _, source := range batchRowCountQueries {
+		query, args, err := sqlx.In(source.query, names)
 		if err != nil {
-			return 0, fmt.Errorf("failed to verify table row count with COUNT(*) for %s: %w", table, err)
+			return nil, fmt.Errorf("failed to build row count query: %w", err)
 		}
-		c.logger.Infof("Verified with COUNT(*) for table %s: %d rows", table, actualCount)
-		return actualCount, nil
+
+		var rows []struct {
+			TableName string `db:"table_name"`
+			RowCount  int64  `db:"row_count"`
+		}
+		if err := c.selectRows(&rows, query, args...); err != nil {
+			c.logger.Debugf("Failed to get row counts from %s, trying next source: %v", source.source, err)
+			lastErr = err
+			continue
+		}
+
+		for _, row := range rows {
+			if row.RowCount > 0 {
+				counts[row.TableName] = row.RowCount
+			}
+		}
+		c.logger.Debugf("Fetched row counts for %d of %d tables from %s", len(counts), len(tables), source.source)
+		return counts, nil
 	}
 
-	return count, nil
+	return nil, fmt.Errorf("failed to get table row counts: %w", lastErr)
 }
 
 func (c *MySQLClient) GetNewTableRowCount(tableName string) (int64, error) {
@@ -136,7 +417,7 @@ func (c *MySQLClient) GetTableRowCountForSwap(table string) (int64, error) {
 
 	c.logger.Infof("Getting exact row count for swap using COUNT(*): %s", table)
 
-	err := c.db.Get(&count, countQuery)
+	err := c.get(&count, countQuery)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get exact table row count for swap %s: %w", table, err)
 	}
@@ -151,19 +432,30 @@ func (c *MySQLClient) GetNewTableRowCountForSwap(tableName string) (int64, error
 }
 
 func (c *MySQLClient) ExecuteAlter(alterStatement string) error {
+	return c.ExecuteAlterContext(context.Background(), alterStatement)
+}
+
+// ExecuteAlterContext は ctx がキャンセルされたら実行中の文を KILL QUERY で止める ExecuteAlter
+func (c *MySQLClient) ExecuteAlterContext(ctx context.Context, alterStatement string) error {
+	_, err := c.executeAlter(ctx, alterStatement)
+	return err
+}
+
+// executeAlter は ALTER を1回だけ実行し、影響行数を読むための結果を返す
+func (c *MySQLClient) executeAlter(ctx context.Context, alterStatement string) (sql.Result, error) {
 	c.logger.Infof("Executing SQL: %s", alterStatement)
 	start := time.Now()
 
-	_, err := c.db.Exec(alterStatement)
+	result, err := c.execContext(ctx, alterStatement)
 	duration := time.Since(start)
 
 	if err != nil {
 		c.logger.Errorf("SQL execution failed (duration: %v): %s - Error: %v", duration, alterStatement, err)
-		return fmt.Errorf("failed to execute ALTER statement [%s]: %w", alterStatement, err)
+		return nil, fmt.Errorf("failed to execute ALTER statement [%s]: %w", alterStatement, err)
 	}
 
 	c.logger.Infof("SQL execution completed (duration: %v): %s", duration, alterStatement)
-	return nil
+	return result, nil
 }
 
 func (c *MySQLClient) ExecuteAlterWithDryRun(alterStatement string, dryRun bool) error {
@@ -179,7 +471,7 @@ func (c *MySQLClient) SetSessionConfig(lockWaitTimeout, innodbLockWaitTimeout in
 		c.logger.Infof("Executing SQL: %s", query)
 		start := time.Now()
 
-		if _, err := c.db.Exec(query); err != nil {
+		if _, err := c.exec(query); err != nil {
 			duration := time.Since(start)
 			c.logger.Errorf("SQL execution failed (duration: %v): %s - Error: %v", duration, query, err)
 			return fmt.Errorf("failed to set lock_wait_timeout: %w", err)
@@ -194,7 +486,7 @@ func (c *MySQLClient) SetSessionConfig(lockWaitTimeout, innodbLockWaitTimeout in
 		c.logger.Infof("Executing SQL: %s", query)
 		start := time.Now()
 
-		if _, err := c.db.Exec(query); err != nil {
+		if _, err := c.exec(query); err != nil {
 			duration := time.Since(start)
 			c.logger.Errorf("SQL execution failed (duration: %v): %s - Error: %v", duration, query, err)
 			return fmt.Errorf("failed to set innodb_lock_wait_timeout: %w", err)
@@ -215,7 +507,7 @@ func (c *MySQLClient) TableExists(tableName string) (bool, error) {
 		WHERE table_schema = DATABASE() AND table_name = ?
 	`
 
-	err := c.db.Get(&count, query, tableName)
+	err := c.get(&count, query, tableName)
 	if err != nil {
 		return false, fmt.Errorf("failed to check table existence for %s: %w", tableName, err)
 	}
@@ -228,14 +520,15 @@ func (c *MySQLClient) CheckNewTableExists(tableName string) (bool, error) {
 	return c.TableExists(newTableName)
 }
 
-func (c *MySQLClient) HasOtherActiveConnections() (bool, string, error) {
+// HasOtherActiveConnections は同じユーザーの、自分と excludeIDs 以外のセッションがあるかを返す
+func (c *MySQLClient) HasOtherActiveConnections(excludeIDs []int64) (bool, string, error) {
 	currentUser, err := c.GetCurrentUser()
 	if err != nil {
 		return false, "", fmt.Errorf("failed to get current user: %w", err)
 	}
 
 	var currentConnectionID int64
-	err = c.db.Get(&currentConnectionID, "SELECT CONNECTION_ID()")
+	err = c.get(&currentConnectionID, "SELECT CONNECTION_ID()")
 	if err != nil {
 		return false, currentUser, fmt.Errorf("failed to get current connection ID: %w", err)
 	}
@@ -246,8 +539,10 @@ func (c *MySQLClient) HasOtherActiveConnections() (bool, string, error) {
 		FROM information_schema.PROCESSLIST
 		WHERE USER = ? AND ID != ?
 	`
+	args := []any{currentUser, currentConnectionID}
+	exclusion, exclusionArgs := excludeConnectionsClause(excludeIDs)
 
-	err = c.db.Get(&otherConnections, query, currentUser, currentConnectionID)
+	err = c.get(&otherConnections, query+exclusion, append(args, exclusionArgs...)...)
 	if err != nil {
 		return false, currentUser, fmt.Errorf("failed to check other active connections: %w", err)
 	}
@@ -255,9 +550,58 @@ func (c *MySQLClient) HasOtherActiveConnections() (bool, string, error) {
 	return otherConnections > 0, currentUser, nil
 }
 
+// excludeConnectionsClause は PROCESSLIST から ids のセッションを除く条件を返す
+func excludeConnectionsClause(ids []int64) (string, []any) {
+	if len(ids) == 0 {
+		return "", nil
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return fmt.Sprintf(" AND ID NOT IN (%s)", strings.Join(placeholders, ", ")), args
+}
+
+// ActiveConnection は processlist 上の1セッション
+type ActiveConnection struct {
+	ID      int64  `db:"id"`
+	Host    string `db:"host"`
+	Command string `db:"command"`
+	Time    int64  `db:"time"`
+	Info    string `db:"info"`
+}
+
+// GetOtherActiveConnections は同じユーザーの、自分と excludeIDs 以外のセッションとユーザー名を返す
+func (c *MySQLClient) GetOtherActiveConnections(excludeIDs []int64) ([]ActiveConnection, string, error) {
+	currentUser, err := c.GetCurrentUser()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	var connections []ActiveConnection
+	query := `
+		SELECT
+			ID AS id,
+			COALESCE(HOST, '') AS host,
+			COALESCE(COMMAND, '') AS command,
+			COALESCE(TIME, 0) AS time,
+			COALESCE(INFO, '') AS info
+		FROM information_schema.PROCESSLIST
+		WHERE USER = ? AND ID != CONNECTION_ID()%s
+		ORDER BY TIME DESC, ID
+	`
+	exclusion, exclusionArgs := excludeConnectionsClause(excludeIDs)
+	if err := c.selectRows(&connections, fmt.Sprintf(query, exclusion), append([]any{currentUser}, exclusionArgs...)...); err != nil {
+		return nil, currentUser, fmt.Errorf("failed to list other active connections: %w", err)
+	}
+	return connections, currentUser, nil
+}
+
 func (c *MySQLClient) GetCurrentUser() (string, error) {
 	var user string
-	err := c.db.Get(&user, "SELECT USER()")
+	err := c.get(&user, "SELECT USER()")
 	if err != nil {
 		return "", fmt.Errorf("failed to get current user: %w", err)
 	}
@@ -270,12 +614,21 @@ func (c *MySQLClient) GetCurrentUser() (string, error) {
 	return user, nil
 }
 
-func (c *MySQLClient) AnalyzeTable(tableName string) error {
+// AnalyzeTable は ANALYZE TABLE を実行する。timeout が正なら、その時間で待つのをやめてエラーを返す
+// (サーバー側の ANALYZE TABLE は接続が切れても最後まで実行されることがある)。
+func (c *MySQLClient) AnalyzeTable(tableName string, timeout time.Duration) error {
 	analyzeSQL := fmt.Sprintf("ANALYZE TABLE `%s`", tableName)
 	c.logger.Infof("Executing ANALYZE TABLE: %s", analyzeSQL)
 	start := time.Now()
 
-	_, err := c.db.Exec(analyzeSQL)
+	var err error
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		_, err = c.db.ExecContext(ctx, analyzeSQL)
+	} else {
+		_, err = c.exec(analyzeSQL)
+	}
 	duration := time.Since(start)
 
 	if err != nil {
@@ -287,6 +640,483 @@ func (c *MySQLClient) AnalyzeTable(tableName string) error {
 	return nil
 }
 
+// WarmupIndex はインデックスを FORCE INDEX で全件走査し、ページをバッファプールに読み込む。
+// 走査した行数を返す。timeout が正なら、その時間で走査をやめてエラーを返す
+func (c *MySQLClient) WarmupIndex(tableName, indexName string, timeout time.Duration) (int64, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s` FORCE INDEX (`%s`)", tableName, indexName)
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var rows int64
+	if err := c.db.GetContext(ctx, &rows, query); err != nil {
+		return 0, fmt.Errorf("failed to warm up index %s of %s: %w", indexName, tableName, err)
+	}
+	return rows, nil
+}
+
+// GetTriggerNames はテーブルに定義されているトリガー名を返す
+func (c *MySQLClient) GetTriggerNames(tableName string) ([]string, error) {
+	var triggers []string
+	query := `
+		SELECT TRIGGER_NAME
+		FROM information_schema.TRIGGERS
+		WHERE EVENT_OBJECT_SCHEMA = DATABASE() AND EVENT_OBJECT_TABLE = ?
+		ORDER BY TRIGGER_NAME
+	`
+
+	if err := c.selectRows(&triggers, query, tableName); err != nil {
+		return nil, fmt.Errorf("failed to get triggers for %s: %w", tableName, err)
+	}
+	return triggers, nil
+}
+
+// GetTableSizeMB はテーブルのデータとインデックスの合計サイズ(統計情報)を返す
+func (c *MySQLClient) GetTableSizeMB(tableName string) (float64, error) {
+	var sizeMB float64
+	query := `
+		SELECT COALESCE(ROUND((DATA_LENGTH + INDEX_LENGTH) / 1024 / 1024, 2), 0)
+		FROM information_schema.TABLES
+		WHERE table_schema = DATABASE() AND table_name = ?
+	`
+
+	if err := c.get(&sizeMB, query, tableName); err != nil {
+		return 0, fmt.Errorf("failed to get table size for %s: %w", tableName, err)
+	}
+	return sizeMB, nil
+}
+
+// TableStorage はテーブルのストレージエンジンと行フォーマット
+type TableStorage struct {
+	Engine    string
+	RowFormat string
+	// CREATE TABLE で ROW_FORMAT を明示しているか。明示していなければ作り直すと DefaultRowFormat になる
+	ExplicitRowFormat bool
+	// innodb_default_row_format
+	DefaultRowFormat string
+}
+
+// String は通知用に "InnoDB, ROW_FORMAT=Dynamic" の形式で返す
+func (s *TableStorage) String() string {
+	if s.RowFormat == "" {
+		return s.Engine
+	}
+	return fmt.Sprintf("%s, ROW_FORMAT=%s", s.Engine, s.RowFormat)
+}
+
+// GetTableStorage はテーブルのストレージエンジンと行フォーマットを返す。ビューなどエンジンのないものは空文字になる
+func (c *MySQLClient) GetTableStorage(tableName string) (*TableStorage, error) {
+	var row struct {
+		Engine           sql.NullString `db:"engine"`
+		RowFormat        sql.NullString `db:"row_format"`
+		CreateOptions    sql.NullString `db:"create_options"`
+		DefaultRowFormat sql.NullString `db:"default_row_format"`
+	}
+	query := `
+		SELECT ENGINE AS engine, ROW_FORMAT AS row_format, CREATE_OPTIONS AS create_options,
+			@@innodb_default_row_format AS default_row_format
+		FROM information_schema.TABLES
+		WHERE table_schema = DATABASE() AND table_name = ?
+	`
+
+	if err := c.get(&row, query, tableName); err != nil {
+		return nil, fmt.Errorf("failed to get storage engine for %s: %w", tableName, err)
+	}
+	return &TableStorage{
+		Engine:            row.Engine.String,
+		RowFormat:         row.RowFormat.String,
+		ExplicitRowFormat: strings.Contains(strings.ToLower(row.CreateOptions.String), "row_format="),
+		DefaultRowFormat:  row.DefaultRowFormat.String,
+	}, nil
+}
+
+// GetBufferPoolSizeMB は innodb_buffer_pool_size を MB で返す
+func (c *MySQLClient) GetBufferPoolSizeMB() (float64, error) {
+	var sizeMB float64
+	if err := c.get(&sizeMB, "SELECT @@innodb_buffer_pool_size / 1024 / 1024"); err != nil {
+		return 0, fmt.Errorf("failed to get innodb_buffer_pool_size: %w", err)
+	}
+	return sizeMB, nil
+}
+
+// GetConnectionUsage は現在の Threads_connected と max_connections を返す
+func (c *MySQLClient) GetConnectionUsage() (int64, int64, error) {
+	var usage struct {
+		Connected      int64 `db:"connected"`
+		MaxConnections int64 `db:"max_connections"`
+	}
+	query := `
+		SELECT
+			(SELECT VARIABLE_VALUE FROM performance_schema.global_status WHERE VARIABLE_NAME = 'Threads_connected') AS connected,
+			@@max_connections AS max_connections`
+	if err := c.get(&usage, query); err != nil {
+		return 0, 0, fmt.Errorf("failed to get connection usage: %w", err)
+	}
+	return usage.Connected, usage.MaxConnections, nil
+}
+
+// CountProcessesReferencingTable は実行中のクエリのうちテーブル名を含むものの数を返す。
+// 手動で起動されたpt-oscのコピー処理を検出するために使う。
+func (c *MySQLClient) CountProcessesReferencingTable(tableName string) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(*)
+		FROM information_schema.PROCESSLIST
+		WHERE ID <> CONNECTION_ID() AND DB = DATABASE() AND INFO LIKE CONCAT('%', ?, '%')
+	`
+
+	if err := c.get(&count, query, tableName); err != nil {
+		return 0, fmt.Errorf("failed to check processlist for %s: %w", tableName, err)
+	}
+	return count, nil
+}
+
+// EvaluateHealthQuery はヘルスチェック用のクエリを実行し、1行1列目が真であれば true を返す。
+// NULL・0・空文字・false は失敗とみなす。
+func (c *MySQLClient) EvaluateHealthQuery(query string) (bool, error) {
+	var value sql.NullString
+	if err := c.get(&value, query); err != nil {
+		return false, fmt.Errorf("failed to run health query: %w", err)
+	}
+	if !value.Valid {
+		return false, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(value.String)) {
+	case "", "0", "false":
+		return false, nil
+	}
+	return true, nil
+}
+
+// CountErrorLogEntries は直近 window の間に performance_schema.error_log (MySQL 8.0) に記録された、
+// テーブル名を含むエラーの件数を返す
+func (c *MySQLClient) CountErrorLogEntries(tableName string, window time.Duration) (int64, error) {
+	var count int64
+	query := `
+		SELECT COUNT(*)
+		FROM performance_schema.error_log
+		WHERE PRIO = 'Error'
+			AND LOGGED >= NOW(6) - INTERVAL ? MICROSECOND
+			AND DATA LIKE CONCAT('%', ?, '%')`
+	if err := c.get(&count, query, window.Microseconds(), tableName); err != nil {
+		return 0, fmt.Errorf("failed to read performance_schema.error_log: %w", err)
+	}
+	return count, nil
+}
+
+// GetAutoIncrementColumn は AUTO_INCREMENT 列の名前を返す。なければ空文字を返す。
+func (c *MySQLClient) GetAutoIncrementColumn(tableName string) (string, error) {
+	var columns []string
+	query := `
+		SELECT COLUMN_NAME
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND EXTRA LIKE '%auto_increment%'
+	`
+
+	if err := c.selectRows(&columns, query, tableName); err != nil {
+		return "", fmt.Errorf("failed to get auto increment column for %s: %w", tableName, err)
+	}
+	if len(columns) == 0 {
+		return "", nil
+	}
+	return columns[0], nil
+}
+
+// GetMaxIntValue は整数列の最大値を返す。行がなければ0を返す。
+func (c *MySQLClient) GetMaxIntValue(tableName, column string) (int64, error) {
+	var value int64
+	query := fmt.Sprintf("SELECT COALESCE(MAX(`%s`), 0) FROM `%s`", column, tableName)
+
+	if err := c.get(&value, query); err != nil {
+		return 0, fmt.Errorf("failed to get max(%s) of %s: %w", column, tableName, err)
+	}
+	return value, nil
+}
+
+// GetPrimaryKeyColumns は主キーの列名を順番に返す。主キーがなければ空を返す。
+func (c *MySQLClient) GetPrimaryKeyColumns(tableName string) ([]string, error) {
+	var columns []string
+	query := `
+		SELECT COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER BY ORDINAL_POSITION
+	`
+
+	if err := c.selectRows(&columns, query, tableName); err != nil {
+		return nil, fmt.Errorf("failed to get primary key columns of %s: %w", tableName, err)
+	}
+	return columns, nil
+}
+
+// TableStructure はテーブルのカラム名とインデックス名
+type TableStructure struct {
+	Columns []string
+	Indexes []string
+}
+
+// GetTableStructure はテーブルのカラム名とインデックス名を information_schema から返す。
+// ALTER の各句が適用済みかどうかの判定に使う。
+func (c *MySQLClient) GetTableStructure(tableName string) (*TableStructure, error) {
+	structure := &TableStructure{}
+	columnQuery := `
+		SELECT COLUMN_NAME
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`
+	if err := c.selectRows(&structure.Columns, columnQuery, tableName); err != nil {
+		return nil, fmt.Errorf("failed to get columns of %s: %w", tableName, err)
+	}
+
+	indexQuery := `
+		SELECT DISTINCT INDEX_NAME
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME
+	`
+	if err := c.selectRows(&structure.Indexes, indexQuery, tableName); err != nil {
+		return nil, fmt.Errorf("failed to get indexes of %s: %w", tableName, err)
+	}
+	return structure, nil
+}
+
+// GetMaxUnixTime は日時列の最大値をUNIX時刻で返す。行がなければ0を返す。
+func (c *MySQLClient) GetMaxUnixTime(tableName, column string) (int64, error) {
+	var value int64
+	query := fmt.Sprintf("SELECT COALESCE(UNIX_TIMESTAMP(MAX(`%s`)), 0) FROM `%s`", column, tableName)
+
+	if err := c.get(&value, query); err != nil {
+		return 0, fmt.Errorf("failed to get max(%s) of %s: %w", column, tableName, err)
+	}
+	return value, nil
+}
+
+var autoIncrementPattern = regexp.MustCompile(`AUTO_INCREMENT=(\d+)`)
+
+// GetCreateTable は SHOW CREATE TABLE の結果を返す
+func (c *MySQLClient) GetCreateTable(tableName string) (string, error) {
+	var result struct {
+		Table       string `db:"Table"`
+		CreateTable string `db:"Create Table"`
+	}
+	query := fmt.Sprintf("SHOW CREATE TABLE `%s`", tableName)
+
+	if err := c.get(&result, query); err != nil {
+		return "", fmt.Errorf("failed to get create table statement of %s: %w", tableName, err)
+	}
+	return result.CreateTable, nil
+}
+
+// ListTables は現在のデータベースのテーブル名(ビューを除く)を名前順に返す
+func (c *MySQLClient) ListTables() ([]string, error) {
+	var tables []string
+	query := `
+		SELECT TABLE_NAME
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME
+	`
+	if err := c.selectRows(&tables, query); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	return tables, nil
+}
+
+// GetAutoIncrementValue はテーブルの次の AUTO_INCREMENT 値を返す。
+// information_schema.TABLES はキャッシュされた値を返すことがあるため SHOW CREATE TABLE から読む。
+// 値が表示されない(まだ採番されていない)場合は1を返す。
+func (c *MySQLClient) GetAutoIncrementValue(tableName string) (int64, error) {
+	createTable, err := c.GetCreateTable(tableName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get auto increment value of %s: %w", tableName, err)
+	}
+	matches := autoIncrementPattern.FindStringSubmatch(createTable)
+	if matches == nil {
+		return 1, nil
+	}
+	value, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse auto increment value of %s: %w", tableName, err)
+	}
+	return value, nil
+}
+
+// TableCharsetInfo はテーブルオプションと文字列カラムの照合順序
+type TableCharsetInfo struct {
+	Engine        string `db:"engine"`
+	Collation     string `db:"collation"`
+	RowFormat     string `db:"row_format"`
+	CreateOptions string `db:"create_options"`
+	// カラム名 -> 照合順序。照合順序を持たないカラムは含まない
+	Columns map[string]string `db:"-"`
+}
+
+// GetTableCharsetInfo はテーブルの照合順序・エンジン等のオプションと、カラムごとの照合順序を返す
+func (c *MySQLClient) GetTableCharsetInfo(tableName string) (*TableCharsetInfo, error) {
+	info := &TableCharsetInfo{}
+	tableQuery := `
+		SELECT
+			COALESCE(ENGINE, '') AS engine,
+			COALESCE(TABLE_COLLATION, '') AS collation,
+			COALESCE(ROW_FORMAT, '') AS row_format,
+			COALESCE(CREATE_OPTIONS, '') AS create_options
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+	`
+	if err := c.get(info, tableQuery, tableName); err != nil {
+		return nil, fmt.Errorf("failed to get table options of %s: %w", tableName, err)
+	}
+
+	var columns []struct {
+		Name      string `db:"name"`
+		Collation string `db:"collation"`
+	}
+	columnQuery := `
+		SELECT COLUMN_NAME AS name, COLLATION_NAME AS collation
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLLATION_NAME IS NOT NULL
+		ORDER BY ORDINAL_POSITION
+	`
+	if err := c.selectRows(&columns, columnQuery, tableName); err != nil {
+		return nil, fmt.Errorf("failed to get column collations of %s: %w", tableName, err)
+	}
+
+	info.Columns = make(map[string]string, len(columns))
+	for _, column := range columns {
+		info.Columns[column.Name] = column.Collation
+	}
+	return info, nil
+}
+
+// BlockingSession はテーブルのメタデータロックを保持しているセッション
+type BlockingSession struct {
+	ID        int64  `db:"id"`
+	User      string `db:"user"`
+	Host      string `db:"host"`
+	Command   string `db:"command"`
+	Time      int64  `db:"time"`
+	State     string `db:"state"`
+	Info      string `db:"info"`
+	LockTypes string `db:"lock_types"`
+}
+
+// GetBlockingSessions はテーブルのメタデータロックを取得済みのセッションを返す。
+// RENAME TABLE や ALTER TABLE はこれらのセッションが終わるまで待たされる。
+// performance_schema の mdl instrument が有効である必要がある。
+func (c *MySQLClient) GetBlockingSessions(tableName string) ([]BlockingSession, error) {
+	var sessions []BlockingSession
+	query := `
+		SELECT
+			t.PROCESSLIST_ID AS id,
+			COALESCE(t.PROCESSLIST_USER, '') AS user,
+			COALESCE(t.PROCESSLIST_HOST, '') AS host,
+			COALESCE(t.PROCESSLIST_COMMAND, '') AS command,
+			COALESCE(t.PROCESSLIST_TIME, 0) AS time,
+			COALESCE(t.PROCESSLIST_STATE, '') AS state,
+			COALESCE(t.PROCESSLIST_INFO, '') AS info,
+			GROUP_CONCAT(DISTINCT ml.LOCK_TYPE ORDER BY ml.LOCK_TYPE) AS lock_types
+		FROM performance_schema.metadata_locks ml
+		JOIN performance_schema.threads t ON t.THREAD_ID = ml.OWNER_THREAD_ID
+		WHERE ml.OBJECT_TYPE = 'TABLE'
+			AND ml.OBJECT_SCHEMA = DATABASE()
+			AND ml.OBJECT_NAME = ?
+			AND ml.LOCK_STATUS = 'GRANTED'
+			AND t.PROCESSLIST_ID IS NOT NULL
+			AND t.PROCESSLIST_ID <> CONNECTION_ID()
+		GROUP BY t.PROCESSLIST_ID, t.PROCESSLIST_USER, t.PROCESSLIST_HOST, t.PROCESSLIST_COMMAND,
+			t.PROCESSLIST_TIME, t.PROCESSLIST_STATE, t.PROCESSLIST_INFO
+		ORDER BY time DESC, id
+	`
+
+	if err := c.selectRows(&sessions, query, tableName); err != nil {
+		return nil, fmt.Errorf("failed to get blocking sessions for %s: %w", tableName, err)
+	}
+	return sessions, nil
+}
+
+// TableDependency はテーブルを名前で参照しているビュー・トリガー・ストアドルーチン
+type TableDependency struct {
+	// VIEW, TRIGGER, PROCEDURE, FUNCTION のいずれか
+	Type       string `db:"type"`
+	Name       string `db:"name"`
+	Definition string `db:"definition"`
+	// トリガーの場合、トリガーが定義されているテーブル
+	OnTable string `db:"on_table"`
+}
+
+// GetTableDependencies はテーブルを参照しているビュー・トリガー・ストアドルーチンを返す。
+// pt-osc が作成したトリガーは含めない。
+// 定義文の LIKE 検索だけだと部分一致を拾うため、識別子として現れているものだけに絞り込む。
+func (c *MySQLClient) GetTableDependencies(tableName string) ([]TableDependency, error) {
+	var candidates []TableDependency
+	query := `
+		SELECT 'VIEW' AS type, TABLE_NAME AS name, COALESCE(VIEW_DEFINITION, '') AS definition, '' AS on_table
+		FROM information_schema.VIEWS
+		WHERE TABLE_SCHEMA = DATABASE() AND VIEW_DEFINITION LIKE CONCAT('%', ?, '%')
+		UNION ALL
+		SELECT 'TRIGGER', TRIGGER_NAME, COALESCE(ACTION_STATEMENT, ''), EVENT_OBJECT_TABLE
+		FROM information_schema.TRIGGERS
+		WHERE EVENT_OBJECT_SCHEMA = DATABASE()
+			AND (EVENT_OBJECT_TABLE = ? OR ACTION_STATEMENT LIKE CONCAT('%', ?, '%'))
+			AND TRIGGER_NAME NOT LIKE 'pt\\_osc\\_%'
+		UNION ALL
+		SELECT ROUTINE_TYPE, ROUTINE_NAME, COALESCE(ROUTINE_DEFINITION, ''), ''
+		FROM information_schema.ROUTINES
+		WHERE ROUTINE_SCHEMA = DATABASE() AND ROUTINE_DEFINITION LIKE CONCAT('%', ?, '%')
+		ORDER BY type, name
+	`
+
+	if err := c.selectRows(&candidates, query, tableName, tableName, tableName, tableName); err != nil {
+		return nil, fmt.Errorf("failed to get dependencies of %s: %w", tableName, err)
+	}
+
+	var dependencies []TableDependency
+	for _, dependency := range candidates {
+		if dependency.OnTable == tableName || ReferencesTable(dependency.Definition, tableName) {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	return dependencies, nil
+}
+
+// ReferencesTable は SQL の定義文に tableName が識別子として現れるかどうかを返す。
+// users に対して users_old や app_users は一致しない。
+func ReferencesTable(definition, tableName string) bool {
+	lowerDefinition := strings.ToLower(definition)
+	lowerName := strings.ToLower(tableName)
+	for offset := 0; ; {
+		index := strings.Index(lowerDefinition[offset:], lowerName)
+		if index < 0 {
+			return false
+		}
+		start := offset + index
+		end := start + len(lowerName)
+		if (start == 0 || !isIdentifierByte(lowerDefinition[start-1])) &&
+			(end == len(lowerDefinition) || !isIdentifierByte(lowerDefinition[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b == '$' || b >= 0x80 ||
+		('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
+// KillSession は指定したコネクションを KILL する
+func (c *MySQLClient) KillSession(id int64) error {
+	if _, err := c.exec(fmt.Sprintf("KILL %d", id)); err != nil {
+		return fmt.Errorf("failed to kill session %d: %w", id, err)
+	}
+	c.logger.Infof("Killed session %d", id)
+	return nil
+}
+
 func (c *MySQLClient) GetTableBufferPoolSizeMB(schemaName, tableName string) (float64, error) {
 	var sizeMB float64
 
@@ -301,7 +1131,7 @@ func (c *MySQLClient) GetTableBufferPoolSizeMB(schemaName, tableName string) (fl
 
 	c.logger.Debugf("Getting buffer pool size for table %s", fullTableName)
 
-	err := c.db.Get(&sizeMB, query, fullTableName)
+	err := c.get(&sizeMB, query, fullTableName)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get buffer pool size for %s: %w", fullTableName, err)
 	}
@@ -319,7 +1149,7 @@ func (c *MySQLClient) GetMaxAuroraReplicaLagMs() (float64, error) {
 		WHERE session_id <> 'MASTER_SESSION_ID'
 	`
 
-	err := c.db.Get(&lagMs, query)
+	err := c.get(&lagMs, query)
 	if err != nil {
 		return 0, fmt.Errorf("failed to query REPLICA_HOST_STATUS: %w", err)
 	}
@@ -330,6 +1160,104 @@ func (c *MySQLClient) GetMaxAuroraReplicaLagMs() (float64, error) {
 	return lagMs.Float64, nil
 }
 
+func (c *MySQLClient) GetReplicaLagSeconds() (float64, error) {
+	// 第一選択: SHOW REPLICA STATUS (MySQL 8.0.22+)
+	status, err := c.showReplicaStatus("SHOW REPLICA STATUS")
+	if err != nil {
+		// フォールバック: SHOW SLAVE STATUS (MySQL 5.7, 8.4で削除)
+		c.logger.Debugf("SHOW REPLICA STATUS failed, trying SHOW SLAVE STATUS: %v", err)
+		status, err = c.showReplicaStatus("SHOW SLAVE STATUS")
+		if err != nil {
+			return 0, fmt.Errorf("failed to get replica status: %w", err)
+		}
+	}
+	if status == nil {
+		return 0, fmt.Errorf("host is not configured as a replica")
+	}
+
+	var raw any
+	var found bool
+	for _, column := range []string{"Seconds_Behind_Source", "Seconds_Behind_Master"} {
+		if v, ok := status[column]; ok {
+			raw, found = v, true
+			break
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("replica status does not contain Seconds_Behind_Source/Seconds_Behind_Master")
+	}
+	if raw == nil {
+		return 0, fmt.Errorf("replication is not running (Seconds_Behind_Source is NULL)")
+	}
+
+	var lagStr string
+	switch v := raw.(type) {
+	case []byte:
+		lagStr = string(v)
+	default:
+		lagStr = fmt.Sprint(v)
+	}
+
+	lag, err := strconv.ParseFloat(lagStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse replica lag %v: %w", raw, err)
+	}
+	return lag, nil
+}
+
+func (c *MySQLClient) showReplicaStatus(query string) (map[string]any, error) {
+	rows, err := c.db.Queryx(query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	status := make(map[string]any)
+	if err := rows.MapScan(status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ExecuteAlterWithoutBinlog は sql_log_bin=0 を設定した同一コネクション上でSQLを実行する。
+// コネクションプールの別接続でSETしても効かないため、専用コネクションを確保している。
+func (c *MySQLClient) ExecuteAlterWithoutBinlog(alterStatement string) error {
+	ctx := context.Background()
+	conn, err := c.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.ExecContext(ctx, "SET SESSION sql_log_bin = 0"); err != nil {
+		return fmt.Errorf("failed to disable sql_log_bin: %w", err)
+	}
+	// コネクションをプールに戻す前に元に戻す
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SET SESSION sql_log_bin = 1"); err != nil {
+			c.logger.Warnf("Failed to restore sql_log_bin: %v", err)
+		}
+	}()
+
+	c.logger.Infof("Executing SQL (sql_log_bin=0): %s", alterStatement)
+	start := time.Now()
+
+	_, err = conn.ExecContext(ctx, alterStatement)
+	duration := time.Since(start)
+
+	if err != nil {
+		c.logger.Errorf("SQL execution failed (duration: %v): %s - Error: %v", duration, alterStatement, err)
+		return fmt.Errorf("failed to execute ALTER statement [%s]: %w", alterStatement, err)
+	}
+
+	c.logger.Infof("SQL execution completed (duration: %v): %s", duration, alterStatement)
+	return nil
+}
+
 func (c *MySQLClient) Close() error {
 	if c.db != nil {
 		return c.db.Close()
@@ -342,9 +1270,9 @@ type DBExecutor interface {
 	Exec(query string, args ...any) (sql.Result, error)
 }
 
-func (c *MySQLClient) getTableRowCountWithDB(db DBExecutor, table string) (int64, error) {
+// getStatsRowCountWithDB は統計情報から行数を取得し、参照した情報源の名前と一緒に返す
+func (c *MySQLClient) getStatsRowCountWithDB(db DBExecutor, table string) (int64, string, error) {
 	var count int64
-	var usedMethod string
 
 	// 第一選択: INNODB_SYS_TABLESTATS (MySQL 5.7)
 	query := `
@@ -352,55 +1280,74 @@ func (c *MySQLClient) getTableRowCountWithDB(db DBExecutor, table string) (int64
 		FROM information_schema.INNODB_SYS_TABLESTATS
 		WHERE NAME = CONCAT(DATABASE(), '/', ?)
 	`
-
 	err := db.Get(&count, query, table)
+	if err == nil {
+		c.logger.Debugf("Used INNODB_SYS_TABLESTATS for table %s: %d rows", table, count)
+		return count, "INNODB_SYS_TABLESTATS", nil
+	}
+
+	// 第二選択: INNODB_TABLESTATS (MySQL 8.0+)
+	c.logger.Debugf("Failed to get row count from INNODB_SYS_TABLESTATS for %s, trying INNODB_TABLESTATS: %v", table, err)
+	query = `
+		SELECT NUM_ROWS
+		FROM information_schema.INNODB_TABLESTATS
+		WHERE NAME = CONCAT(DATABASE(), '/', ?)
+	`
+	err = db.Get(&count, query, table)
+	if err == nil {
+		c.logger.Debugf("Used INNODB_TABLESTATS for table %s: %d rows", table, count)
+		return count, "INNODB_TABLESTATS", nil
+	}
+
+	// 第三選択: information_schema.TABLES
+	c.logger.Debugf("Failed to get row count from INNODB_TABLESTATS for %s, trying information_schema.TABLES: %v", table, err)
+	query = `
+		SELECT TABLE_ROWS
+		FROM information_schema.TABLES
+		WHERE table_schema = DATABASE() AND table_name = ?
+	`
+	err = db.Get(&count, query, table)
+	if err == nil {
+		c.logger.Debugf("Used information_schema.TABLES for table %s: %d rows", table, count)
+		return count, "information_schema.TABLES", nil
+	}
+	return 0, "", err
+}
+
+// getTableRowCountWithDB は database.row_count の数え方に従って行数を返す
+func (c *MySQLClient) getTableRowCountWithDB(db DBExecutor, table string) (int64, error) {
+	rule := c.rowCount.forTable(table)
+	switch rule.strategy {
+	case RowCountEstimate:
+		return c.estimateRowCountWithDB(db, table)
+	case RowCountCountAlways:
+		count, err := c.countRowsWithDB(db, table, rule)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get table row count for %s: %w", table, err)
+		}
+		c.logger.Infof("Used COUNT(*) for table %s: %d rows", table, count)
+		return count, nil
+	}
+
+	count, usedMethod, err := c.getStatsRowCountWithDB(db, table)
 	if err != nil {
-		// 第二選択: INNODB_TABLESTATS (MySQL 8.0+)
-		c.logger.Debugf("Failed to get row count from INNODB_SYS_TABLESTATS for %s, trying INNODB_TABLESTATS: %v", table, err)
-		query = `
-			SELECT NUM_ROWS
-			FROM information_schema.INNODB_TABLESTATS
-			WHERE NAME = CONCAT(DATABASE(), '/', ?)
-		`
-		err = db.Get(&count, query, table)
+		if rule.strategy == RowCountStatsOnly {
+			return 0, fmt.Errorf("failed to get table row count for %s from statistics: %w", table, err)
+		}
+		// フォールバック: COUNT(*)
+		c.logger.Warnf("Failed to get row count from all stats tables for %s, falling back to COUNT(*): %v", table, err)
+		count, err = c.countRowsWithDB(db, table, rule)
 		if err != nil {
-			// 第三選択: information_schema.TABLES
-			c.logger.Debugf("Failed to get row count from INNODB_TABLESTATS for %s, trying information_schema.TABLES: %v", table, err)
-			query = `
-				SELECT TABLE_ROWS
-				FROM information_schema.TABLES
-				WHERE table_schema = DATABASE() AND table_name = ?
-			`
-			err = db.Get(&count, query, table)
-			if err != nil {
-				// フォールバック: COUNT(*)
-				c.logger.Warnf("Failed to get row count from all stats tables for %s, falling back to COUNT(*): %v", table, err)
-
-				countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", table)
-				err = db.Get(&count, countQuery)
-				if err != nil {
-					return 0, fmt.Errorf("failed to get table row count for %s: %w", table, err)
-				}
-				c.logger.Infof("Used COUNT(*) for table %s: %d rows", table, count)
-				return count, nil
-			}
-			usedMethod = "information_schema.TABLES"
-			c.logger.Debugf("Used information_schema.TABLES for table %s: %d rows", table, count)
-		} else {
-			usedMethod = "INNODB_TABLESTATS"
-			c.logger.Debugf("Used INNODB_TABLESTATS for table %s: %d rows", table, count)
+			return 0, fmt.Errorf("failed to get table row count for %s: %w", table, err)
 		}
-	} else {
-		usedMethod = "INNODB_SYS_TABLESTATS"
-		c.logger.Debugf("Used INNODB_SYS_TABLESTATS for table %s: %d rows", table, count)
+		c.logger.Infof("Used COUNT(*) for table %s: %d rows", table, count)
+		return count, nil
 	}
 
 	// 統計情報が0件の場合は、COUNT(*)で正確な件数を確認
-	if count == 0 {
+	if count == 0 && rule.strategy != RowCountStatsOnly {
 		c.logger.Infof("Stats show 0 rows for table %s (from %s), verifying with COUNT(*)", table, usedMethod)
-		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", table)
-		var actualCount int64
-		err = db.Get(&actualCount, countQuery)
+		actualCount, err := c.countRowsWithDB(db, table, rule)
 		if err != nil {
 			return 0, fmt.Errorf("failed to verify table row count with COUNT(*) for %s: %w", table, err)
 		}
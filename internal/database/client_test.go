@@ -6,9 +6,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/pyama86/alterguard/internal/config"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockDB struct {
@@ -517,3 +521,148 @@ func TestGetTableBufferPoolSizeMB(t *testing.T) {
 		assert.Contains(t, query, "TABLE_NAME = ?")
 	})
 }
+
+func TestDescribeDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{name: "with database", dsn: "user:secret@tcp(replica1:3306)/app", want: "replica1:3306/app"},
+		{name: "without database", dsn: "user:secret@tcp(replica1:3306)/", want: "replica1:3306"},
+		{name: "invalid", dsn: "not a dsn", want: "unknown-host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DescribeDSN(tt.dsn)
+			assert.Equal(t, tt.want, got)
+			assert.NotContains(t, got, "secret")
+		})
+	}
+}
+
+func TestDeriveDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		host    string
+		want    string
+		wantErr bool
+	}{
+		{name: "host with port", dsn: "user:secret@tcp(primary:3306)/app?parseTime=true", host: "reader-1:3307", want: "user:secret@tcp(reader-1:3307)/app?parseTime=true"},
+		{name: "host keeps the port of the DSN", dsn: "user:secret@tcp(primary:3307)/app", host: "reader-1", want: "user:secret@tcp(reader-1:3307)/app"},
+		{name: "primary without port", dsn: "user:secret@tcp(primary)/app", host: "reader-1", want: "user:secret@tcp(reader-1:3306)/app"},
+		{name: "empty host", dsn: "user:secret@tcp(primary:3306)/app", wantErr: true},
+		{name: "invalid DSN", dsn: "not a dsn", host: "reader-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DeriveDSN(tt.dsn, tt.host)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReferencesTable(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		want       bool
+	}{
+		{name: "quoted", definition: "select `app`.`users`.`id` AS `id` from `app`.`users`", want: true},
+		{name: "unquoted", definition: "BEGIN DELETE FROM users WHERE id = OLD.id; END", want: true},
+		{name: "case insensitive", definition: "INSERT INTO Users (id) VALUES (NEW.id)", want: true},
+		{name: "prefix only", definition: "select * from `users_old`", want: false},
+		{name: "suffix only", definition: "select * from app_users", want: false},
+		{name: "later match", definition: "select * from app_users join users using (id)", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ReferencesTable(tt.definition, "users"))
+		})
+	}
+}
+
+func TestApplyPoolConfig(t *testing.T) {
+	t.Run("applies pool settings", func(t *testing.T) {
+		db, err := sqlx.Open("mysql", "user:pass@tcp(localhost:3306)/test")
+		require.NoError(t, err)
+		defer func() { _ = db.Close() }()
+
+		err = applyPoolConfig(db, config.DatabaseConfig{
+			MaxOpenConns:    4,
+			MaxIdleConns:    2,
+			ConnMaxLifetime: "5m",
+			ConnMaxIdleTime: "30s",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 4, db.Stats().MaxOpenConnections)
+	})
+
+	t.Run("invalid duration", func(t *testing.T) {
+		db, err := sqlx.Open("mysql", "user:pass@tcp(localhost:3306)/test")
+		require.NoError(t, err)
+		defer func() { _ = db.Close() }()
+
+		err = applyPoolConfig(db, config.DatabaseConfig{ConnMaxLifetime: "forever"})
+		assert.Error(t, err)
+	})
+}
+
+func TestApplySessionVars(t *testing.T) {
+	t.Run("no session vars keeps DSN", func(t *testing.T) {
+		dsn := "user:pass@tcp(localhost:3306)/app"
+		got, err := applySessionVars(dsn, nil)
+		require.NoError(t, err)
+		assert.Equal(t, dsn, got)
+	})
+
+	t.Run("session vars become connection parameters", func(t *testing.T) {
+		got, err := applySessionVars("user:pass@tcp(localhost:3306)/app?parseTime=true", map[string]string{
+			"innodb_lock_wait_timeout": "5",
+			"sql_mode":                 "STRICT_TRANS_TABLES,NO_ZERO_DATE",
+		})
+		require.NoError(t, err)
+
+		cfg, err := mysql.ParseDSN(got)
+		require.NoError(t, err)
+		assert.True(t, cfg.ParseTime)
+		assert.Equal(t, "5", cfg.Params["innodb_lock_wait_timeout"])
+		assert.Equal(t, "'STRICT_TRANS_TABLES,NO_ZERO_DATE'", cfg.Params["sql_mode"])
+	})
+}
+
+func TestDuplicateErrorCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantOK   bool
+	}{
+		{name: "duplicate entry", err: &mysql.MySQLError{Number: 1062}, wantCode: 1062, wantOK: true},
+		{name: "wrapped duplicate key name", err: fmt.Errorf("failed: %w", &mysql.MySQLError{Number: 1061}), wantCode: 1061, wantOK: true},
+		{name: "duplicate column", err: &mysql.MySQLError{Number: 1060}, wantCode: 1060, wantOK: true},
+		{name: "table exists", err: &mysql.MySQLError{Number: 1050}, wantCode: 1050, wantOK: true},
+		{name: "syntax error", err: &mysql.MySQLError{Number: 1064}},
+		{name: "plain error", err: fmt.Errorf("boom")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ok := DuplicateErrorCode(tt.err)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+
+	assert.False(t, IsDuplicateError(&mysql.MySQLError{Number: 1060}))
+	assert.True(t, IsDuplicateError(fmt.Errorf("failed: %w", &mysql.MySQLError{Number: 1050})))
+}
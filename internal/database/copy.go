@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CopyChunk は source の行を keyColumns の順に after より後から最大 chunkSize 行だけ target にコピーする。
+// pt-osc と同じく、先にチャンクの上限のキーを求めてから範囲指定で INSERT ... SELECT する。
+// 次のチャンクの開始位置(コピーした最後の行のキー)を返し、残りの行がなければ nil を返す。
+func (c *MySQLClient) CopyChunk(source, target string, keyColumns []string, after []any, chunkSize int) ([]any, int64, error) {
+	if len(keyColumns) == 0 {
+		return nil, 0, fmt.Errorf("key columns are required to copy %s in chunks", source)
+	}
+	if chunkSize <= 0 {
+		return nil, 0, fmt.Errorf("chunk size must be positive: %d", chunkSize)
+	}
+
+	quoted := make([]string, len(keyColumns))
+	for i, column := range keyColumns {
+		quoted[i] = fmt.Sprintf("`%s`", column)
+	}
+	keys := strings.Join(quoted, ", ")
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keyColumns)), ", ")
+
+	var conditions []string
+	var args []any
+	if after != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s) > (%s)", keys, placeholders))
+		args = append(args, after...)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	boundaryQuery := fmt.Sprintf("SELECT %s FROM `%s`%s ORDER BY %s LIMIT 1 OFFSET %d", keys, source, where, keys, chunkSize-1)
+	var upper []any
+	err := c.retry.do(c.logger, func() error {
+		c.pingIfNeeded()
+		var err error
+		upper, err = c.db.QueryRowx(boundaryQuery, args...).SliceScan()
+		if errors.Is(err, sql.ErrNoRows) {
+			upper = nil
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find chunk boundary of %s: %w", source, err)
+	}
+
+	// 上限が見つからなければ残りが chunkSize 行未満なので、すべてコピーして終わる
+	if upper != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s) <= (%s)", keys, placeholders))
+		args = append(args, upper...)
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	insertQuery := fmt.Sprintf("INSERT INTO `%s` SELECT * FROM `%s`%s", target, source, where)
+	result, err := c.exec(insertQuery, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to copy chunk of %s into %s: %w", source, target, err)
+	}
+	copied, err := result.RowsAffected()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get copied row count: %w", err)
+	}
+	return upper, copied, nil
+}
+
+// GetLastKey は keyColumns の順で最後の行のキーを文字列で返す。行がなければ nil を返す。
+// pt-osc はキーの順に行をコピーするため、_new テーブルに対して呼ぶとコピー済みのチャンクの境界が分かる
+func (c *MySQLClient) GetLastKey(tableName string, keyColumns []string) ([]string, error) {
+	if len(keyColumns) == 0 {
+		return nil, fmt.Errorf("key columns are required to find the last key of %s", tableName)
+	}
+
+	quoted := make([]string, len(keyColumns))
+	descending := make([]string, len(keyColumns))
+	for i, column := range keyColumns {
+		quoted[i] = fmt.Sprintf("`%s`", column)
+		descending[i] = quoted[i] + " DESC"
+	}
+	query := fmt.Sprintf("SELECT %s FROM `%s` ORDER BY %s LIMIT 1", strings.Join(quoted, ", "), tableName, strings.Join(descending, ", "))
+
+	var values []any
+	err := c.retry.do(c.logger, func() error {
+		c.pingIfNeeded()
+		var err error
+		values, err = c.db.QueryRowx(query).SliceScan()
+		if errors.Is(err, sql.ErrNoRows) {
+			values = nil
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the last key of %s: %w", tableName, err)
+	}
+	if values == nil {
+		return nil, nil
+	}
+
+	key := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case []byte:
+			key[i] = string(v)
+		case nil:
+			key[i] = "NULL"
+		default:
+			key[i] = fmt.Sprint(v)
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ScheduledEvent は有効な MySQL の EVENT。時刻はすべて現在時刻からの秒数で、時刻が変換できなかった場合は NULL になる
+type ScheduledEvent struct {
+	Name       string `db:"name"`
+	Definition string `db:"definition"`
+	// ONE TIME か RECURRING
+	Type          string         `db:"event_type"`
+	IntervalValue sql.NullString `db:"interval_value"`
+	IntervalField sql.NullString `db:"interval_field"`
+	ExecuteAtIn   sql.NullInt64  `db:"execute_at_in"`
+	StartsIn      sql.NullInt64  `db:"starts_in"`
+	EndsIn        sql.NullInt64  `db:"ends_in"`
+}
+
+// GetScheduledEvents は現在のスキーマの有効な EVENT のうち、定義にテーブル名が識別子として現れるものを返す。
+// EVENT の時刻はイベントのタイムゾーンで記録されているため、サーバー側でセッションのタイムゾーンに変換して現在時刻との差を求める。
+func (c *MySQLClient) GetScheduledEvents(tableName string) ([]ScheduledEvent, error) {
+	var candidates []ScheduledEvent
+	query := `
+		SELECT
+			EVENT_NAME AS name,
+			COALESCE(EVENT_DEFINITION, '') AS definition,
+			EVENT_TYPE AS event_type,
+			INTERVAL_VALUE AS interval_value,
+			INTERVAL_FIELD AS interval_field,
+			TIMESTAMPDIFF(SECOND, NOW(), CONVERT_TZ(EXECUTE_AT, TIME_ZONE, @@session.time_zone)) AS execute_at_in,
+			TIMESTAMPDIFF(SECOND, NOW(), CONVERT_TZ(STARTS, TIME_ZONE, @@session.time_zone)) AS starts_in,
+			TIMESTAMPDIFF(SECOND, NOW(), CONVERT_TZ(ENDS, TIME_ZONE, @@session.time_zone)) AS ends_in
+		FROM information_schema.EVENTS
+		WHERE EVENT_SCHEMA = DATABASE() AND STATUS = 'ENABLED' AND EVENT_DEFINITION LIKE CONCAT('%', ?, '%')
+		ORDER BY EVENT_NAME
+	`
+	if err := c.selectRows(&candidates, query, tableName); err != nil {
+		return nil, fmt.Errorf("failed to get events referencing %s: %w", tableName, err)
+	}
+
+	var events []ScheduledEvent
+	for _, event := range candidates {
+		if ReferencesTable(event.Definition, tableName) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// namedLockKeepAliveInterval は名前付きロックのコネクションを確認する間隔。
+// wait_timeout や NAT のアイドルタイムアウトでコネクションが切られないよう、これより短い間隔で使う
+const namedLockKeepAliveInterval = time.Minute
+
+// NamedLock は GET_LOCK で取得した名前付きロック
+type NamedLock interface {
+	// ConnectionID はロックを保持しているコネクションの CONNECTION_ID()。
+	// 同じユーザーのセッションを数えるチェックでは、このコネクションを除く
+	ConnectionID() int64
+	// Lost はコネクションが切れるなどしてロックを失ったときに閉じる
+	Lost() <-chan struct{}
+	Release() error
+}
+
+type mysqlNamedLock struct {
+	name         string
+	conn         *sql.Conn
+	connectionID int64
+	lost         chan struct{}
+	stop         chan struct{}
+	done         chan struct{}
+	releaseOnce  sync.Once
+	releaseErr   error
+}
+
+// AcquireNamedLock は GET_LOCK で名前付きロックを待たずに取得する。
+// ロックは取得したコネクションに紐づくため、Release を呼ぶまでそのコネクションをプールから借りたままにし、
+// 一定間隔で IS_USED_LOCK を確かめてコネクションを保つ。他のセッションが保持していれば acquired は false になる。
+func (c *MySQLClient) AcquireNamedLock(name string) (NamedLock, bool, error) {
+	ctx := context.Background()
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection for lock %s: %w", name, err)
+	}
+
+	var result sql.NullInt64
+	var connectionID int64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0), CONNECTION_ID()", name).Scan(&result, &connectionID); err != nil {
+		_ = conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if !result.Valid || result.Int64 != 1 {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+
+	lock := &mysqlNamedLock{
+		name:         name,
+		conn:         conn,
+		connectionID: connectionID,
+		lost:         make(chan struct{}),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go c.keepNamedLock(lock, namedLockKeepAliveInterval)
+	return lock, true, nil
+}
+
+// keepNamedLock は Release まで interval ごとにロックがまだこのコネクションのものかを確かめる。
+// 確かめられなければロックを失ったものとして Lost を閉じる
+func (c *MySQLClient) keepNamedLock(lock *mysqlNamedLock, interval time.Duration) {
+	defer close(lock.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-lock.stop:
+			return
+		case <-ticker.C:
+		}
+
+		var holder sql.NullInt64
+		err := lock.conn.QueryRowContext(context.Background(), "SELECT IS_USED_LOCK(?)", lock.name).Scan(&holder)
+		if err == nil && holder.Valid && holder.Int64 == lock.connectionID {
+			continue
+		}
+		if err != nil {
+			c.logger.Errorf("Lost lock %s: connection %d is gone: %v", lock.name, lock.connectionID, err)
+		} else {
+			c.logger.Errorf("Lost lock %s: it is no longer held by connection %d", lock.name, lock.connectionID)
+		}
+		close(lock.lost)
+		return
+	}
+}
+
+func (l *mysqlNamedLock) ConnectionID() int64 {
+	return l.connectionID
+}
+
+func (l *mysqlNamedLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release はロックを解放してコネクションをプールに返す。2回目以降は何もしない
+func (l *mysqlNamedLock) Release() error {
+	l.releaseOnce.Do(func() {
+		close(l.stop)
+		<-l.done
+		defer func() { _ = l.conn.Close() }()
+		if _, err := l.conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", l.name); err != nil {
+			l.releaseErr = fmt.Errorf("failed to release lock %s: %w", l.name, err)
+		}
+	})
+	return l.releaseErr
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// PtOscOperation はインスタンス上で実行中と思われる pt-osc (スキーマ・テーブル単位)。
+// タスクファイルに含まれないテーブルや、他のチームが起動した pt-osc も含む
+type PtOscOperation struct {
+	Schema string
+	Table  string
+	// テーブルに定義されている pt_osc_ で始まるトリガー
+	Triggers []string
+	// _table_new が存在するか
+	NewTableExists bool
+	// pt-osc のコメントが付いたクエリ、または _table_new を参照するクエリを実行中のセッション
+	Processes []PtOscProcess
+}
+
+// PtOscProcess は pt-osc のものと思われる PROCESSLIST の1行
+type PtOscProcess struct {
+	ID   int64  `db:"id"`
+	User string `db:"user"`
+	Host string `db:"host"`
+	DB   string `db:"db"`
+	Time int64  `db:"time"`
+	Info string `db:"info"`
+}
+
+type ptOscTrigger struct {
+	Schema string `db:"schema_name"`
+	Table  string `db:"table_name"`
+	Name   string `db:"trigger_name"`
+}
+
+type schemaTable struct {
+	Schema string `db:"schema_name"`
+	Table  string `db:"table_name"`
+}
+
+// `db`.`_table_new` または `_table_new` の形で書かれた pt-osc の新テーブル
+var ptOscNewTableRe = regexp.MustCompile("(?:`([^`]+)`\\.)?`_([^`]+)_new`")
+
+// GetRunningPtOscOperations はインスタンス全体から実行中の pt-osc を探す。
+// pt_osc_ で始まるトリガーと、pt-osc のコメント付きクエリや _table_new を参照するクエリを実行中のセッションを手がかりにする。
+// _table_new が残っているだけのテーブルは、実行中ではなく後始末の対象なので含めない
+func (c *MySQLClient) GetRunningPtOscOperations() ([]PtOscOperation, error) {
+	var triggers []ptOscTrigger
+	triggerQuery := `
+		SELECT EVENT_OBJECT_SCHEMA AS schema_name, EVENT_OBJECT_TABLE AS table_name, TRIGGER_NAME AS trigger_name
+		FROM information_schema.TRIGGERS
+		WHERE TRIGGER_NAME LIKE 'pt\_osc\_%'
+		ORDER BY EVENT_OBJECT_SCHEMA, EVENT_OBJECT_TABLE, TRIGGER_NAME
+	`
+	if err := c.selectRows(&triggers, triggerQuery); err != nil {
+		return nil, fmt.Errorf("failed to list pt-osc triggers: %w", err)
+	}
+
+	var newTables []schemaTable
+	newTableQuery := `
+		SELECT TABLE_SCHEMA AS schema_name, TABLE_NAME AS table_name
+		FROM information_schema.TABLES
+		WHERE TABLE_NAME LIKE '\_%\_new'
+	`
+	if err := c.selectRows(&newTables, newTableQuery); err != nil {
+		return nil, fmt.Errorf("failed to list _new tables: %w", err)
+	}
+
+	var processes []PtOscProcess
+	processQuery := `
+		SELECT ID AS id, USER AS user, HOST AS host, COALESCE(DB, '') AS db, TIME AS time, COALESCE(INFO, '') AS info
+		FROM information_schema.PROCESSLIST
+		WHERE ID <> CONNECTION_ID() AND (INFO LIKE '%pt-online-schema-change%' OR INFO LIKE ?)
+		ORDER BY ID
+	`
+	if err := c.selectRows(&processes, processQuery, "%\\_%\\_new`%"); err != nil {
+		return nil, fmt.Errorf("failed to check processlist for pt-osc: %w", err)
+	}
+
+	return mergePtOscOperations(triggers, newTables, processes), nil
+}
+
+// mergePtOscOperations はトリガー・_new テーブル・セッションをスキーマとテーブルごとにまとめる。
+// 対象のテーブルがクエリから分からないセッションは、テーブル名が空の1件にまとめる
+func mergePtOscOperations(triggers []ptOscTrigger, newTables []schemaTable, processes []PtOscProcess) []PtOscOperation {
+	operations := make(map[schemaTable]*PtOscOperation)
+	operation := func(key schemaTable) *PtOscOperation {
+		if op, ok := operations[key]; ok {
+			return op
+		}
+		op := &PtOscOperation{Schema: key.Schema, Table: key.Table}
+		operations[key] = op
+		return op
+	}
+
+	for _, trigger := range triggers {
+		op := operation(schemaTable{Schema: trigger.Schema, Table: trigger.Table})
+		op.Triggers = append(op.Triggers, trigger.Name)
+	}
+	for _, process := range processes {
+		key := schemaTable{Schema: process.DB}
+		if matches := ptOscNewTableRe.FindStringSubmatch(process.Info); matches != nil {
+			key.Table = matches[2]
+			if matches[1] != "" {
+				key.Schema = matches[1]
+			}
+		}
+		op := operation(key)
+		op.Processes = append(op.Processes, process)
+	}
+
+	newTableExists := make(map[schemaTable]bool, len(newTables))
+	for _, table := range newTables {
+		newTableExists[table] = true
+	}
+
+	result := make([]PtOscOperation, 0, len(operations))
+	for key, op := range operations {
+		op.NewTableExists = newTableExists[schemaTable{Schema: key.Schema, Table: fmt.Sprintf("_%s_new", key.Table)}]
+		result = append(result, *op)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Schema != result[j].Schema {
+			return result[i].Schema < result[j].Schema
+		}
+		return result[i].Table < result[j].Table
+	})
+	return result
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePtOscOperations(t *testing.T) {
+	triggers := []ptOscTrigger{
+		{Schema: "app", Table: "users", Name: "pt_osc_app_users_del"},
+		{Schema: "app", Table: "users", Name: "pt_osc_app_users_ins"},
+		{Schema: "billing", Table: "invoices", Name: "pt_osc_billing_invoices_ins"},
+	}
+	newTables := []schemaTable{
+		{Schema: "app", Table: "_users_new"},
+		{Schema: "app", Table: "_orders_new"},
+	}
+	processes := []PtOscProcess{
+		{ID: 10, DB: "app", Info: "INSERT LOW_PRIORITY IGNORE INTO `app`.`_users_new` (`id`) SELECT `id` FROM `app`.`users` /*pt-online-schema-change 1234 copy nibble*/"},
+		{ID: 11, DB: "", Info: "SELECT /*!40001 SQL_NO_CACHE */ `id` FROM `logs`.`events` /*pt-online-schema-change 99 next chunk boundary*/"},
+	}
+
+	operations := mergePtOscOperations(triggers, newTables, processes)
+
+	// _orders_new が残っているだけのテーブルは実行中とみなさない
+	require.Len(t, operations, 3)
+	assert.Equal(t, "", operations[0].Schema)
+	assert.Equal(t, "", operations[0].Table)
+	assert.Equal(t, int64(11), operations[0].Processes[0].ID)
+
+	assert.Equal(t, "app", operations[1].Schema)
+	assert.Equal(t, "users", operations[1].Table)
+	assert.Equal(t, []string{"pt_osc_app_users_del", "pt_osc_app_users_ins"}, operations[1].Triggers)
+	assert.True(t, operations[1].NewTableExists)
+	require.Len(t, operations[1].Processes, 1)
+	assert.Equal(t, int64(10), operations[1].Processes[0].ID)
+
+	assert.Equal(t, "billing", operations[2].Schema)
+	assert.Equal(t, "invoices", operations[2].Table)
+	assert.False(t, operations[2].NewTableExists)
+	assert.Empty(t, operations[2].Processes)
+}
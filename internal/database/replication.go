@@ -0,0 +1,103 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReplicationSettings は GTID とレプリケーションフィルタに関する接続先の設定
+type ReplicationSettings struct {
+	Version                string `db:"version"`
+	Schema                 string `db:"schema_name"`
+	GTIDMode               string `db:"gtid_mode"`
+	EnforceGTIDConsistency string `db:"enforce_gtid_consistency"`
+	// SHOW BINARY LOG STATUS の Binlog_Do_DB / Binlog_Ignore_DB
+	BinlogDoDB     []string
+	BinlogIgnoreDB []string
+	// performance_schema.replication_applier_global_filters (MySQL 8.0+) のレプリケーションフィルタ
+	Filters []ReplicationFilter
+}
+
+// ReplicationFilter は replicate-* オプション1つ分。Name は REPLICATE_DO_DB などで、Rule はカンマ区切りの値
+type ReplicationFilter struct {
+	Name string `db:"FILTER_NAME"`
+	Rule string `db:"FILTER_RULE"`
+}
+
+// BinlogSettings はバイナリログの形式に関する設定
+type BinlogSettings struct {
+	LogBin   bool   `db:"log_bin"`
+	Format   string `db:"binlog_format"`
+	RowImage string `db:"binlog_row_image"`
+}
+
+// GetBinlogSettings は log_bin / binlog_format / binlog_row_image を返す
+func (c *MySQLClient) GetBinlogSettings() (*BinlogSettings, error) {
+	var settings BinlogSettings
+	query := `SELECT @@log_bin AS log_bin, @@binlog_format AS binlog_format, @@binlog_row_image AS binlog_row_image`
+	if err := c.get(&settings, query); err != nil {
+		return nil, fmt.Errorf("failed to get binlog settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// GetReplicationSettings は GTID の設定とバイナリログ・レプリケーションのフィルタを返す。
+// バイナリログが無効な場合やフィルタのテーブルがない (MySQL 5.7) 場合、該当するフィルタは空になる。
+func (c *MySQLClient) GetReplicationSettings() (*ReplicationSettings, error) {
+	var settings ReplicationSettings
+	query := `SELECT @@version AS version, IFNULL(DATABASE(), '') AS schema_name,
+		@@gtid_mode AS gtid_mode, @@enforce_gtid_consistency AS enforce_gtid_consistency`
+	if err := c.get(&settings, query); err != nil {
+		return nil, fmt.Errorf("failed to get GTID settings: %w", err)
+	}
+
+	// 第一選択: SHOW BINARY LOG STATUS (MySQL 8.2+)
+	status, err := c.showReplicaStatus("SHOW BINARY LOG STATUS")
+	if err != nil {
+		// フォールバック: SHOW MASTER STATUS (8.4で削除)
+		c.logger.Debugf("SHOW BINARY LOG STATUS failed, trying SHOW MASTER STATUS: %v", err)
+		status, err = c.showReplicaStatus("SHOW MASTER STATUS")
+	}
+	if err != nil {
+		c.logger.Debugf("Failed to get binary log status, skipping binlog filters: %v", err)
+	}
+	settings.BinlogDoDB = splitFilterList(statusString(status, "Binlog_Do_DB"))
+	settings.BinlogIgnoreDB = splitFilterList(statusString(status, "Binlog_Ignore_DB"))
+
+	if err := c.selectRows(&settings.Filters, `
+		SELECT FILTER_NAME, FILTER_RULE
+		FROM performance_schema.replication_applier_global_filters
+		WHERE FILTER_RULE <> ''`); err != nil {
+		c.logger.Debugf("Failed to get replication filters, skipping them: %v", err)
+		settings.Filters = nil
+	}
+
+	return &settings, nil
+}
+
+func statusString(status map[string]any, column string) string {
+	switch value := status[column].(type) {
+	case []byte:
+		return string(value)
+	case string:
+		return value
+	default:
+		return ""
+	}
+}
+
+// splitFilterList はカンマ区切りのフィルタの値を分割する
+func splitFilterList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Values はフィルタの値 (データベース名や db.table のパターン) を返す
+func (f ReplicationFilter) Values() []string {
+	return splitFilterList(f.Rule)
+}
@@ -0,0 +1,19 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicationFilterValues(t *testing.T) {
+	assert.Equal(t, []string{"app.users", "app.orders"}, ReplicationFilter{Rule: "app.users, app.orders"}.Values())
+	assert.Nil(t, ReplicationFilter{Rule: ""}.Values())
+}
+
+func TestStatusString(t *testing.T) {
+	status := map[string]any{"Binlog_Do_DB": []byte("app,logs"), "Binlog_Ignore_DB": ""}
+	assert.Equal(t, "app,logs", statusString(status, "Binlog_Do_DB"))
+	assert.Equal(t, "", statusString(status, "Binlog_Ignore_DB"))
+	assert.Equal(t, "", statusString(nil, "Binlog_Do_DB"))
+}
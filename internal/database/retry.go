@@ -0,0 +1,84 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultRetryMaxAttempts    = 3
+	defaultRetryInitialBackoff = 1 * time.Second
+	defaultRetryMaxBackoff     = 30 * time.Second
+)
+
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func newRetryPolicy(cfg config.RetryConfig) (retryPolicy, error) {
+	policy := retryPolicy{
+		maxAttempts:    defaultRetryMaxAttempts,
+		initialBackoff: defaultRetryInitialBackoff,
+		maxBackoff:     defaultRetryMaxBackoff,
+	}
+
+	if cfg.MaxAttempts < 0 {
+		return policy, fmt.Errorf("invalid database.retry.max_attempts: must not be negative, got %d", cfg.MaxAttempts)
+	}
+	if cfg.MaxAttempts > 0 {
+		policy.maxAttempts = cfg.MaxAttempts
+	}
+
+	if cfg.InitialBackoff != "" {
+		d, err := time.ParseDuration(cfg.InitialBackoff)
+		if err != nil {
+			return policy, fmt.Errorf("invalid database.retry.initial_backoff: %w", err)
+		}
+		policy.initialBackoff = d
+	}
+
+	if cfg.MaxBackoff != "" {
+		d, err := time.ParseDuration(cfg.MaxBackoff)
+		if err != nil {
+			return policy, fmt.Errorf("invalid database.retry.max_backoff: %w", err)
+		}
+		policy.maxBackoff = d
+	}
+
+	return policy, nil
+}
+
+// do は一時的なエラーの間だけ指数バックオフでfnを再実行する。それ以外のエラーは即座に返す。
+func (p retryPolicy) do(logger *logrus.Logger, fn func() error) error {
+	maxAttempts := p.maxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	backoff := p.initialBackoff
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = fn()
+		if err == nil || !IsTransientError(err) {
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		logger.Warnf("Transient database error (attempt %d/%d), retrying in %s: %v", attempt, maxAttempts, backoff, err)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if p.maxBackoff > 0 && backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", maxAttempts, err)
+}
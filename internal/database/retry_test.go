@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "deadlock", err: &mysql.MySQLError{Number: 1213}, want: true},
+		{name: "lock wait timeout", err: &mysql.MySQLError{Number: 1205}, want: true},
+		{name: "wrapped lost connection", err: fmt.Errorf("failed: %w", &mysql.MySQLError{Number: 2013}), want: true},
+		{name: "server gone away", err: &mysql.MySQLError{Number: 2006}, want: true},
+		{name: "invalid connection", err: mysql.ErrInvalidConn, want: true},
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "syntax error", err: &mysql.MySQLError{Number: 1064}, want: false},
+		{name: "duplicate column", err: &mysql.MySQLError{Number: 1060}, want: false},
+		{name: "plain error", err: errors.New("boom"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsTransientError(tt.err))
+		})
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	policy := retryPolicy{maxAttempts: 3, initialBackoff: time.Millisecond, maxBackoff: 2 * time.Millisecond}
+
+	t.Run("succeeds after transient errors", func(t *testing.T) {
+		calls := 0
+		err := policy.do(logger, func() error {
+			calls++
+			if calls < 3 {
+				return &mysql.MySQLError{Number: 1213}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("fails fast on semantic errors", func(t *testing.T) {
+		calls := 0
+		semanticErr := &mysql.MySQLError{Number: 1064}
+		err := policy.do(logger, func() error {
+			calls++
+			return semanticErr
+		})
+		assert.Equal(t, semanticErr, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		calls := 0
+		err := policy.do(logger, func() error {
+			calls++
+			return &mysql.MySQLError{Number: 1205}
+		})
+		require.Error(t, err)
+		assert.Equal(t, 3, calls)
+		assert.True(t, IsTransientError(err))
+	})
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	policy, err := newRetryPolicy(config.RetryConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultRetryMaxAttempts, policy.maxAttempts)
+	assert.Equal(t, defaultRetryInitialBackoff, policy.initialBackoff)
+
+	policy, err = newRetryPolicy(config.RetryConfig{MaxAttempts: 5, InitialBackoff: "200ms", MaxBackoff: "5s"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, policy.maxAttempts)
+	assert.Equal(t, 200*time.Millisecond, policy.initialBackoff)
+	assert.Equal(t, 5*time.Second, policy.maxBackoff)
+
+	_, err = newRetryPolicy(config.RetryConfig{InitialBackoff: "later"})
+	assert.Error(t, err)
+
+	_, err = newRetryPolicy(config.RetryConfig{MaxAttempts: -1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pyama86/alterguard/internal/config"
+)
+
+const (
+	// RowCountStatsOnly は統計情報だけを使い、COUNT(*) は実行しない
+	RowCountStatsOnly = "stats-only"
+	// RowCountStatsThenCount は統計情報を使い、取得できないか0件のときだけ COUNT(*) で確認する
+	RowCountStatsThenCount = "stats-then-count"
+	// RowCountCountAlways は常に COUNT(*) で数える
+	RowCountCountAlways = "count-always"
+	// RowCountEstimate は EXPLAIN SELECT COUNT(*) の推定値を使う
+	RowCountEstimate = "estimate"
+)
+
+type rowCountRule struct {
+	strategy     string
+	countTimeout time.Duration
+}
+
+type rowCountPolicy struct {
+	rowCountRule
+	tables map[string]rowCountRule
+}
+
+func newRowCountPolicy(cfg config.RowCountConfig) (rowCountPolicy, error) {
+	policy := rowCountPolicy{}
+	base, err := parseRowCountRule("database.row_count", cfg.Strategy, cfg.CountTimeout, rowCountRule{strategy: RowCountStatsThenCount})
+	if err != nil {
+		return policy, err
+	}
+	policy.rowCountRule = base
+
+	for table, override := range cfg.Tables {
+		rule, err := parseRowCountRule(fmt.Sprintf("database.row_count.tables.%s", table), override.Strategy, override.CountTimeout, base)
+		if err != nil {
+			return policy, err
+		}
+		if policy.tables == nil {
+			policy.tables = make(map[string]rowCountRule)
+		}
+		policy.tables[table] = rule
+	}
+	return policy, nil
+}
+
+func parseRowCountRule(key, strategy, countTimeout string, base rowCountRule) (rowCountRule, error) {
+	rule := base
+	switch strategy {
+	case "":
+	case RowCountStatsOnly, RowCountStatsThenCount, RowCountCountAlways, RowCountEstimate:
+		rule.strategy = strategy
+	default:
+		return rule, fmt.Errorf("invalid %s.strategy %q (must be stats-only, stats-then-count, count-always or estimate)", key, strategy)
+	}
+
+	if countTimeout != "" {
+		d, err := time.ParseDuration(countTimeout)
+		if err != nil || d <= 0 {
+			return rule, fmt.Errorf("invalid %s.count_timeout %q", key, countTimeout)
+		}
+		rule.countTimeout = d
+	}
+	return rule, nil
+}
+
+// forTable はテーブルに適用する数え方を返す。ゼロ値のポリシーは stats-then-count で上限なし
+func (p rowCountPolicy) forTable(table string) rowCountRule {
+	if rule, ok := p.tables[table]; ok {
+		return rule
+	}
+	if p.strategy == "" {
+		return rowCountRule{strategy: RowCountStatsThenCount, countTimeout: p.countTimeout}
+	}
+	return p.rowCountRule
+}
+
+// countRowsQuery は COUNT(*) のクエリを返す。timeout が正なら MAX_EXECUTION_TIME ヒントを付け、
+// クライアント側で待つのをやめるだけでなくサーバー側でもクエリを止める
+func countRowsQuery(table string, timeout time.Duration) string {
+	if timeout > 0 {
+		return fmt.Sprintf("SELECT /*+ MAX_EXECUTION_TIME(%d) */ COUNT(*) FROM `%s`", timeout.Milliseconds(), table)
+	}
+	return fmt.Sprintf("SELECT COUNT(*) FROM `%s`", table)
+}
+
+// isMaxExecutionTimeExceeded は MAX_EXECUTION_TIME で止められたエラーかどうかを判定する
+func isMaxExecutionTimeExceeded(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 3024
+}
+
+// explainRow は EXPLAIN の1行。推定行数だけを使う
+type explainRow struct {
+	ID           sql.NullInt64   `db:"id"`
+	SelectType   sql.NullString  `db:"select_type"`
+	Table        sql.NullString  `db:"table"`
+	Partitions   sql.NullString  `db:"partitions"`
+	Type         sql.NullString  `db:"type"`
+	PossibleKeys sql.NullString  `db:"possible_keys"`
+	Key          sql.NullString  `db:"key"`
+	KeyLen       sql.NullString  `db:"key_len"`
+	Ref          sql.NullString  `db:"ref"`
+	Rows         sql.NullInt64   `db:"rows"`
+	Filtered     sql.NullFloat64 `db:"filtered"`
+	Extra        sql.NullString  `db:"Extra"`
+}
+
+// estimateRowCountWithDB は EXPLAIN SELECT COUNT(*) のオプティマイザの推定行数を返す。
+// 推定値が出ない場合 (Select tables optimized away など) は統計情報を使う
+func (c *MySQLClient) estimateRowCountWithDB(db DBExecutor, table string) (int64, error) {
+	var row explainRow
+	if err := db.Get(&row, fmt.Sprintf("EXPLAIN SELECT COUNT(*) FROM `%s`", table)); err != nil {
+		return 0, fmt.Errorf("failed to estimate row count for %s: %w", table, err)
+	}
+	if row.Rows.Valid {
+		c.logger.Infof("Used EXPLAIN estimate for table %s: %d rows", table, row.Rows.Int64)
+		return row.Rows.Int64, nil
+	}
+
+	c.logger.Infof("EXPLAIN gave no row estimate for table %s, using statistics", table)
+	count, _, err := c.getStatsRowCountWithDB(db, table)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get table row count for %s: %w", table, err)
+	}
+	return count, nil
+}
+
+// countRowsWithDB は COUNT(*) で数える。rule.countTimeout を超えた場合は推定値を返す
+func (c *MySQLClient) countRowsWithDB(db DBExecutor, table string, rule rowCountRule) (int64, error) {
+	var count int64
+	err := db.Get(&count, countRowsQuery(table, rule.countTimeout))
+	if err == nil {
+		return count, nil
+	}
+	if !isMaxExecutionTimeExceeded(err) {
+		return 0, err
+	}
+	c.logger.Warnf("COUNT(*) on %s exceeded count_timeout %s, using EXPLAIN estimate instead", table, rule.countTimeout)
+	return c.estimateRowCountWithDB(db, table)
+}
+
+// CountTableRows は database.row_count の設定に関わらず COUNT(*) で数える。
+// timeout が正で、それを超えた場合は推定値に頼らずエラーを返す
+func (c *MySQLClient) CountTableRows(table string, timeout time.Duration) (int64, error) {
+	var count int64
+	if err := c.get(&count, countRowsQuery(table, timeout)); err != nil {
+		return 0, fmt.Errorf("failed to count rows of %s: %w", table, err)
+	}
+	c.logger.Infof("Counted rows of table %s with COUNT(*): %d rows", table, count)
+	return count, nil
+}
+
+// retryingExecutor は MySQLClient の get(リトライ付き)と exec を DBExecutor として使うためのもの
+type retryingExecutor struct {
+	c *MySQLClient
+}
+
+func (e retryingExecutor) Get(dest any, query string, args ...any) error {
+	return e.c.get(dest, query, args...)
+}
+
+func (e retryingExecutor) Exec(query string, args ...any) (sql.Result, error) {
+	return e.c.exec(query, args...)
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRowCountPolicy(t *testing.T) {
+	policy, err := newRowCountPolicy(config.RowCountConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, rowCountRule{strategy: RowCountStatsThenCount}, policy.forTable("users"))
+
+	policy, err = newRowCountPolicy(config.RowCountConfig{
+		Strategy:     RowCountCountAlways,
+		CountTimeout: "30s",
+		Tables: map[string]config.RowCountTableConfig{
+			"huge_logs": {Strategy: RowCountEstimate},
+			"orders":    {CountTimeout: "5s"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rowCountRule{strategy: RowCountCountAlways, countTimeout: 30 * time.Second}, policy.forTable("users"))
+	assert.Equal(t, rowCountRule{strategy: RowCountEstimate, countTimeout: 30 * time.Second}, policy.forTable("huge_logs"))
+	assert.Equal(t, rowCountRule{strategy: RowCountCountAlways, countTimeout: 5 * time.Second}, policy.forTable("orders"))
+
+	_, err = newRowCountPolicy(config.RowCountConfig{Strategy: "guess"})
+	assert.ErrorContains(t, err, "invalid database.row_count.strategy")
+
+	_, err = newRowCountPolicy(config.RowCountConfig{Tables: map[string]config.RowCountTableConfig{"users": {CountTimeout: "soon"}}})
+	assert.ErrorContains(t, err, "invalid database.row_count.tables.users.count_timeout")
+}
+
+func TestCountRowsQuery(t *testing.T) {
+	assert.Equal(t, "SELECT COUNT(*) FROM `users`", countRowsQuery("users", 0))
+	assert.Equal(t, "SELECT /*+ MAX_EXECUTION_TIME(30000) */ COUNT(*) FROM `users`", countRowsQuery("users", 30*time.Second))
+}
+
+func isCountQuery(query string) bool {
+	return strings.Contains(query, "COUNT(*)") && !strings.HasPrefix(query, "EXPLAIN")
+}
+
+func isExplainQuery(query string) bool {
+	return strings.HasPrefix(query, "EXPLAIN")
+}
+
+func isStatsQuery(query string) bool {
+	return strings.Contains(query, "INNODB_SYS_TABLESTATS")
+}
+
+func TestGetTableRowCountWithDB_Strategies(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.RowCountConfig
+		setup  func(db *MockDB)
+		want   int64
+		noCall func(query string) bool
+	}{
+		{
+			name: "stats-only does not verify 0 rows",
+			cfg:  config.RowCountConfig{Strategy: RowCountStatsOnly},
+			setup: func(db *MockDB) {
+				db.On("Get", mock.Anything, mock.MatchedBy(isStatsQuery), "users").Run(func(args mock.Arguments) {
+					*args.Get(0).(*int64) = 0
+				}).Return(nil)
+			},
+			want:   0,
+			noCall: isCountQuery,
+		},
+		{
+			name: "count-always uses COUNT(*)",
+			cfg:  config.RowCountConfig{Strategy: RowCountCountAlways},
+			setup: func(db *MockDB) {
+				db.On("Get", mock.Anything, "SELECT COUNT(*) FROM `users`").Run(func(args mock.Arguments) {
+					*args.Get(0).(*int64) = 1234
+				}).Return(nil)
+			},
+			want:   1234,
+			noCall: isStatsQuery,
+		},
+		{
+			name: "count timeout falls back to EXPLAIN estimate",
+			cfg:  config.RowCountConfig{Strategy: RowCountCountAlways, CountTimeout: "2s"},
+			setup: func(db *MockDB) {
+				db.On("Get", mock.Anything, "SELECT /*+ MAX_EXECUTION_TIME(2000) */ COUNT(*) FROM `users`").Return(&mysql.MySQLError{Number: 3024})
+				db.On("Get", mock.Anything, mock.MatchedBy(isExplainQuery)).Run(func(args mock.Arguments) {
+					args.Get(0).(*explainRow).Rows = sql.NullInt64{Int64: 98765, Valid: true}
+				}).Return(nil)
+			},
+			want: 98765,
+		},
+		{
+			name: "estimate without row estimate uses statistics",
+			cfg:  config.RowCountConfig{Strategy: RowCountEstimate},
+			setup: func(db *MockDB) {
+				db.On("Get", mock.Anything, mock.MatchedBy(isExplainQuery)).Return(nil)
+				db.On("Get", mock.Anything, mock.MatchedBy(isStatsQuery), "users").Run(func(args mock.Arguments) {
+					*args.Get(0).(*int64) = 500
+				}).Return(nil)
+			},
+			want:   500,
+			noCall: isCountQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.PanicLevel)
+			policy, err := newRowCountPolicy(tt.cfg)
+			require.NoError(t, err)
+			client := &MySQLClient{logger: logger, rowCount: policy}
+
+			mockDB := &MockDB{}
+			tt.setup(mockDB)
+
+			count, err := client.getTableRowCountWithDB(mockDB, "users")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, count)
+			mockDB.AssertExpectations(t)
+			if tt.noCall != nil {
+				for _, call := range mockDB.Calls {
+					assert.False(t, tt.noCall(call.Arguments.String(1)), "unexpected query: %s", call.Arguments.String(1))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// SchemaExists はスキーマが存在するかを返す
+func (c *MySQLClient) SchemaExists(schema string) (bool, error) {
+	var count int
+	if err := c.get(&count, "SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE schema_name = ?", schema); err != nil {
+		return false, fmt.Errorf("failed to check schema existence for %s: %w", schema, err)
+	}
+	return count > 0, nil
+}
+
+// CreateShadowSchema は検証用のスキーマを作り、指定したテーブルの構造(データなし)を CREATE TABLE ... LIKE で複製する。
+// 既存のスキーマを誤って使わないよう、同名のスキーマがあればエラーにする。
+// テーブルの複製に失敗した場合は、自分で作ったスキーマを削除してからエラーを返す
+func (c *MySQLClient) CreateShadowSchema(schema string, tables []string) error {
+	if _, err := c.exec(fmt.Sprintf("CREATE DATABASE `%s`", schema)); err != nil {
+		return fmt.Errorf("failed to create shadow schema %s: %w", schema, err)
+	}
+	for _, table := range tables {
+		if _, err := c.exec(fmt.Sprintf("CREATE TABLE `%s`.`%s` LIKE `%s`", schema, table, table)); err != nil {
+			if dropErr := c.DropSchema(schema); dropErr != nil {
+				c.logger.Errorf("Failed to drop shadow schema %s: %v", schema, dropErr)
+			}
+			return fmt.Errorf("failed to copy structure of %s into %s: %w", table, schema, err)
+		}
+	}
+	return nil
+}
+
+// ExecuteInSchema は USE で指定したスキーマに切り替えた専用コネクション上でSQLを実行する。
+// 修飾されていないテーブル名がシャドウスキーマのテーブルを指すようにするためのもの。
+func (c *MySQLClient) ExecuteInSchema(schema, statement string) error {
+	ctx := context.Background()
+	conn, err := c.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var original string
+	if err := conn.GetContext(ctx, &original, "SELECT COALESCE(DATABASE(), '')"); err != nil {
+		return fmt.Errorf("failed to get current database: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("USE `%s`", schema)); err != nil {
+		return fmt.Errorf("failed to switch to %s: %w", schema, err)
+	}
+	// コネクションをプールに戻す前に元に戻す
+	defer func() {
+		if original == "" {
+			return
+		}
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("USE `%s`", original)); err != nil {
+			c.logger.Warnf("Failed to switch back to %s: %v", original, err)
+		}
+	}()
+
+	c.logger.Debugf("Executing SQL in %s: %s", schema, statement)
+	if _, err := conn.ExecContext(ctx, statement); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DropSchema はスキーマを削除する。存在しなければ何もしない。
+func (c *MySQLClient) DropSchema(schema string) error {
+	if _, err := c.exec(fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", schema)); err != nil {
+		return fmt.Errorf("failed to drop schema %s: %w", schema, err)
+	}
+	return nil
+}
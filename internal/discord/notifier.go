@@ -0,0 +1,97 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/slack"
+)
+
+const (
+	defaultUsername = "alterguard"
+	// Discord の embed の title と description の上限
+	maxTitleLength       = 256
+	maxDescriptionLength = 4096
+)
+
+// 重要度ごとの embed の色 (RGB の整数)
+var severityColors = map[string]int{
+	slack.SeverityInfo:    0x2eb886,
+	slack.SeverityWarning: 0xdaa038,
+	slack.SeverityError:   0xa30200,
+}
+
+// Notifier は Discord の Webhook に通知を送る slack.Sink
+type Notifier struct {
+	url      string
+	username string
+	http     *http.Client
+}
+
+var _ slack.Sink = (*Notifier)(nil)
+
+type embed struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Color       int    `json:"color"`
+	Timestamp   string `json:"timestamp"`
+}
+
+type payload struct {
+	Username string  `json:"username"`
+	Embeds   []embed `json:"embeds"`
+}
+
+// NewNotifier は Webhook URL に投稿する Notifier を返す。環境名があれば投稿者名の前に付ける。
+func NewNotifier(url, username, environment string) *Notifier {
+	if username == "" {
+		username = defaultUsername
+	}
+	if environment != "" {
+		username = fmt.Sprintf("[%s] %s", environment, username)
+	}
+	return &Notifier{url: url, username: username, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send は通知の1行目を title、残りを description にした embed を投稿する。色は重要度で決まる。
+func (n *Notifier) Send(severity, text string) error {
+	title, description, _ := strings.Cut(text, "\n")
+	body, err := json.Marshal(payload{
+		Username: n.username,
+		Embeds: []embed{{
+			Title:       truncate(title, maxTitleLength),
+			Description: truncate(description, maxDescriptionLength),
+			Color:       severityColors[severity],
+			Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.http.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send Discord notification: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("discord webhook returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// truncate は上限を超える文字列を末尾を省略して切り詰める
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
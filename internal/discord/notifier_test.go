@@ -0,0 +1,42 @@
+package discord
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend(t *testing.T) {
+	var received payload
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, "schema-bot", "")
+	require.NoError(t, notifier.Send(slack.SeverityWarning, "⚠️ Warning\nTable: users\nRow count mismatch"))
+
+	assert.Equal(t, "schema-bot", received.Username)
+	require.Len(t, received.Embeds, 1)
+	assert.Equal(t, "⚠️ Warning", received.Embeds[0].Title)
+	assert.Equal(t, "Table: users\nRow count mismatch", received.Embeds[0].Description)
+	assert.Equal(t, 0xdaa038, received.Embeds[0].Color)
+
+	status = http.StatusTooManyRequests
+	assert.ErrorContains(t, notifier.Send(slack.SeverityInfo, "hello"), "429")
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 3))
+	long := truncate(strings.Repeat("あ", 5000), maxDescriptionLength)
+	assert.Equal(t, maxDescriptionLength, len([]rune(long)))
+	assert.True(t, strings.HasSuffix(long, "…"))
+}
@@ -0,0 +1,62 @@
+// Package dsn は DATABASE_DSN (go-sql-driver/mysql の形式) を解釈する。
+// 接続に使うドライバと同じ解釈をするため、task・ptosc・ptarchiver などはここを通して DSN を読む。
+package dsn
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DSN は Percona Toolkit に渡すのに必要な接続先の情報
+type DSN struct {
+	Host     string
+	Port     string
+	Database string
+	User     string
+	Password string
+}
+
+// Parse は TCP 接続の DSN を解釈する。parseTime・loc・collation などのパラメータはドライバと同じように読み飛ばす。
+// パスワードに : や @ を含んでもよい。ポートがなければドライバと同じく 3306 とする
+func Parse(raw string) (*DSN, error) {
+	cfg, err := mysql.ParseDSN(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN format: %w", err)
+	}
+	if cfg.Net != "tcp" {
+		return nil, fmt.Errorf("only TCP connections are supported")
+	}
+	host, port, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host:port format: %w", err)
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return nil, fmt.Errorf("invalid port number: %s", port)
+	}
+	if cfg.DBName == "" {
+		return nil, fmt.Errorf("database name not found in DSN")
+	}
+
+	return &DSN{
+		Host:     host,
+		Port:     port,
+		Database: cfg.DBName,
+		User:     cfg.User,
+		Password: cfg.Passwd,
+	}, nil
+}
+
+// DatabaseName は DSN のデータベース名を返す。Unix ソケットの DSN でもよい
+func DatabaseName(raw string) (string, error) {
+	cfg, err := mysql.ParseDSN(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid DSN format: %w", err)
+	}
+	if cfg.DBName == "" {
+		return "", fmt.Errorf("database name not found in DSN")
+	}
+	return cfg.DBName, nil
+}
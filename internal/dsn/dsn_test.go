@@ -0,0 +1,89 @@
+package dsn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		want        *DSN
+		expectError string
+	}{
+		{
+			name: "basic",
+			raw:  "user:pass@tcp(localhost:3306)/testdb",
+			want: &DSN{Host: "localhost", Port: "3306", Database: "testdb", User: "user", Password: "pass"},
+		},
+		{
+			name: "parameters",
+			raw:  "user:pass@tcp(db.example.com:3307)/testdb?parseTime=true&loc=Asia%2FTokyo&collation=utf8mb4_bin&charset=utf8mb4",
+			want: &DSN{Host: "db.example.com", Port: "3307", Database: "testdb", User: "user", Password: "pass"},
+		},
+		{
+			name: "password with separators",
+			raw:  "user:p@ss:w/rd@tcp(localhost:3306)/testdb",
+			want: &DSN{Host: "localhost", Port: "3306", Database: "testdb", User: "user", Password: "p@ss:w/rd"},
+		},
+		{
+			name: "IPv6 address",
+			raw:  "user@tcp([::1]:3306)/testdb",
+			want: &DSN{Host: "::1", Port: "3306", Database: "testdb", User: "user"},
+		},
+		{
+			name: "default port",
+			raw:  "user@tcp(localhost)/testdb",
+			want: &DSN{Host: "localhost", Port: "3306", Database: "testdb", User: "user"},
+		},
+		{
+			name:        "unix socket",
+			raw:         "user@unix(/tmp/mysql.sock)/testdb",
+			expectError: "only TCP connections are supported",
+		},
+		{
+			name:        "invalid port",
+			raw:         "user@tcp(localhost:abc)/testdb",
+			expectError: "invalid port number",
+		},
+		{
+			name:        "no database",
+			raw:         "user@tcp(localhost:3306)/?parseTime=true",
+			expectError: "database name not found",
+		},
+		{
+			name:        "not a DSN",
+			raw:         "invalid_dsn",
+			expectError: "invalid DSN format",
+		},
+		{
+			name:        "invalid parameter",
+			raw:         "user@tcp(localhost:3306)/testdb?parseTime=maybe",
+			expectError: "invalid DSN format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.raw)
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDatabaseName(t *testing.T) {
+	name, err := DatabaseName("user@unix(/tmp/mysql.sock)/testdb?loc=Asia%2FTokyo")
+	require.NoError(t, err)
+	assert.Equal(t, "testdb", name)
+
+	_, err = DatabaseName("user@tcp(localhost:3306)/")
+	assert.ErrorContains(t, err, "database name not found")
+}
@@ -0,0 +1,230 @@
+package email
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/slack"
+)
+
+const (
+	TLSModeStartTLS = "starttls"
+	TLSModeTLS      = "tls"
+	TLSModeNone     = "none"
+
+	defaultSubjectTemplate = "[alterguard]{{if .Environment}}[{{.Environment}}]{{end}} {{.Title}}"
+	defaultBodyTemplate    = "{{.Message}}\n"
+
+	dialTimeout = 30 * time.Second
+)
+
+// TemplateData は件名・本文のテンプレートに渡す値
+type TemplateData struct {
+	Title       string
+	Message     string
+	Severity    string
+	Environment string
+	Timestamp   time.Time
+}
+
+// SMTPNotifier は通知をメールで送る slack.Sink
+type SMTPNotifier struct {
+	cfg         config.EmailConfig
+	environment string
+	password    string
+	subject     *template.Template
+	body        *template.Template
+	now         func() time.Time
+	// テストで差し替えるための送信処理
+	send func(addr string, from string, to []string, msg []byte) error
+}
+
+var _ slack.Sink = (*SMTPNotifier)(nil)
+
+// NewSMTPNotifier は設定から SMTPNotifier を作る。host が空なら nil を返す。
+func NewSMTPNotifier(cfg config.EmailConfig, environment string) (*SMTPNotifier, error) {
+	if cfg.Host == "" {
+		return nil, nil
+	}
+	if cfg.From == "" {
+		return nil, fmt.Errorf("email.from is required when email.host is set")
+	}
+	switch cfg.TLS {
+	case "":
+		cfg.TLS = TLSModeStartTLS
+	case TLSModeStartTLS, TLSModeTLS, TLSModeNone:
+	default:
+		return nil, fmt.Errorf("invalid email.tls %q (must be starttls, tls or none)", cfg.TLS)
+	}
+	if cfg.Port == 0 {
+		if cfg.TLS == TLSModeTLS {
+			cfg.Port = 465
+		} else {
+			cfg.Port = 587
+		}
+	}
+
+	subjectText := cfg.SubjectTemplate
+	if subjectText == "" {
+		subjectText = defaultSubjectTemplate
+	}
+	subject, err := template.New("subject").Parse(subjectText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email.subject_template: %w", err)
+	}
+	bodyText := cfg.BodyTemplate
+	if bodyText == "" {
+		bodyText = defaultBodyTemplate
+	}
+	body, err := template.New("body").Parse(bodyText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email.body_template: %w", err)
+	}
+
+	n := &SMTPNotifier{
+		cfg:         cfg,
+		environment: environment,
+		password:    os.Getenv("SMTP_PASSWORD"),
+		subject:     subject,
+		body:        body,
+		now:         time.Now,
+	}
+	n.send = n.sendMail
+	return n, nil
+}
+
+// recipients は重要度に対応する宛先を返す
+func (n *SMTPNotifier) recipients(severity string) []string {
+	switch severity {
+	case slack.SeverityError:
+		return n.cfg.Recipients.Error
+	case slack.SeverityWarning:
+		return n.cfg.Recipients.Warning
+	default:
+		return n.cfg.Recipients.Info
+	}
+}
+
+// Send は通知をメールにして、その重要度の宛先に送る。宛先がなければ何もしない。
+func (n *SMTPNotifier) Send(severity, text string) error {
+	to := n.recipients(severity)
+	if len(to) == 0 {
+		return nil
+	}
+	msg, err := n.buildMessage(severity, text, to)
+	if err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	if err := n.send(addr, n.cfg.From, to, msg); err != nil {
+		return fmt.Errorf("failed to send email notification: %w", err)
+	}
+	return nil
+}
+
+// buildMessage は件名と本文をテンプレートから作り、RFC 5322 形式のメッセージにする
+func (n *SMTPNotifier) buildMessage(severity, text string, to []string) ([]byte, error) {
+	title, _, _ := strings.Cut(text, "\n")
+	data := TemplateData{
+		Title:       title,
+		Message:     text,
+		Severity:    severity,
+		Environment: n.environment,
+		Timestamp:   n.now(),
+	}
+
+	var subject bytes.Buffer
+	if err := n.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("failed to render email subject: %w", err)
+	}
+	var body bytes.Buffer
+	if err := n.body.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render email body: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	// 件名は改行を含められないため1行にまとめる
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject.String()), " ")))
+	fmt.Fprintf(&msg, "Date: %s\r\n", data.Timestamp.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&msg)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(body.String(), "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+// sendMail は tls の設定に従って SMTP サーバーに接続し、メッセージを送る
+func (n *SMTPNotifier) sendMail(addr, from string, to []string, msg []byte) error {
+	tlsConfig := &tls.Config{ServerName: n.cfg.Host, MinVersion: tls.VersionTLS12}
+
+	var conn net.Conn
+	var err error
+	if n.cfg.TLS == TLSModeTLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, dialTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(dialTimeout * 2)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if n.cfg.TLS == TLSModeStartTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if n.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.cfg.Username, n.password, n.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
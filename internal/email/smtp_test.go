@@ -0,0 +1,103 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sentMail struct {
+	addr string
+	from string
+	to   []string
+	msg  string
+}
+
+func newTestNotifier(t *testing.T, cfg config.EmailConfig) (*SMTPNotifier, *[]sentMail) {
+	t.Helper()
+	n, err := NewSMTPNotifier(cfg, "production")
+	require.NoError(t, err)
+	require.NotNil(t, n)
+	n.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	var sent []sentMail
+	n.send = func(addr, from string, to []string, msg []byte) error {
+		sent = append(sent, sentMail{addr: addr, from: from, to: to, msg: string(msg)})
+		return nil
+	}
+	return n, &sent
+}
+
+func TestNewSMTPNotifier(t *testing.T) {
+	n, err := NewSMTPNotifier(config.EmailConfig{}, "")
+	assert.NoError(t, err)
+	assert.Nil(t, n)
+
+	_, err = NewSMTPNotifier(config.EmailConfig{Host: "smtp.example.com"}, "")
+	assert.ErrorContains(t, err, "email.from")
+
+	_, err = NewSMTPNotifier(config.EmailConfig{Host: "smtp.example.com", From: "a@example.com", TLS: "ssl"}, "")
+	assert.ErrorContains(t, err, "invalid email.tls")
+
+	_, err = NewSMTPNotifier(config.EmailConfig{Host: "smtp.example.com", From: "a@example.com", SubjectTemplate: "{{.Title"}, "")
+	assert.ErrorContains(t, err, "subject_template")
+
+	n, err = NewSMTPNotifier(config.EmailConfig{Host: "smtp.example.com", From: "a@example.com", TLS: TLSModeTLS}, "")
+	require.NoError(t, err)
+	assert.Equal(t, 465, n.cfg.Port)
+}
+
+func TestSendRoutesBySeverity(t *testing.T) {
+	n, sent := newTestNotifier(t, config.EmailConfig{
+		Host: "smtp.example.com",
+		From: "alterguard@example.com",
+		Recipients: config.EmailRecipientsConfig{
+			Warning: []string{"dba@example.com"},
+			Error:   []string{"dba@example.com", "oncall@example.com"},
+		},
+	})
+
+	require.NoError(t, n.Send(slack.SeverityInfo, "🚀 Schema change started\nTable: users"))
+	assert.Empty(t, *sent)
+
+	require.NoError(t, n.Send(slack.SeverityError, "❌ Schema change failed\nTable: users"))
+	require.Len(t, *sent, 1)
+	mail := (*sent)[0]
+	assert.Equal(t, "smtp.example.com:587", mail.addr)
+	assert.Equal(t, "alterguard@example.com", mail.from)
+	assert.Equal(t, []string{"dba@example.com", "oncall@example.com"}, mail.to)
+	assert.Contains(t, mail.msg, "To: dba@example.com, oncall@example.com\r\n")
+	assert.Contains(t, mail.msg, "Subject: =?utf-8?q?")
+	assert.Contains(t, mail.msg, "Table: users")
+}
+
+func TestBuildMessageTemplates(t *testing.T) {
+	n, sent := newTestNotifier(t, config.EmailConfig{
+		Host:            "smtp.example.com",
+		From:            "alterguard@example.com",
+		SubjectTemplate: "[{{.Severity}}] {{.Environment}} {{.Title}}",
+		BodyTemplate:    "At {{.Timestamp.Format \"2006-01-02\"}}:\n{{.Message}}",
+		Recipients:      config.EmailRecipientsConfig{Warning: []string{"dba@example.com"}},
+	})
+
+	require.NoError(t, n.Send(slack.SeverityWarning, "Row count mismatch\nTable: users"))
+	require.Len(t, *sent, 1)
+	assert.Contains(t, (*sent)[0].msg, "Subject: [warning] production Row count mismatch\r\n")
+	assert.True(t, strings.HasSuffix((*sent)[0].msg, "At 2024-01-02:\r\nRow count mismatch\r\nTable: users"))
+}
+
+func TestSendError(t *testing.T) {
+	n, _ := newTestNotifier(t, config.EmailConfig{
+		Host:       "smtp.example.com",
+		From:       "alterguard@example.com",
+		Recipients: config.EmailRecipientsConfig{Info: []string{"dba@example.com"}},
+	})
+	n.send = func(string, string, []string, []byte) error { return errors.New("connection refused") }
+
+	assert.ErrorContains(t, n.Send(slack.SeverityInfo, "hello"), "failed to send email notification")
+}
@@ -0,0 +1,249 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/pyama86/alterguard/internal/config"
+)
+
+const (
+	// EventBridge のイベントの source
+	eventBridgeSource = "alterguard"
+	snsAPIVersion     = "2010-03-31"
+	// SNS の Subject の上限
+	snsSubjectMaxLength = 100
+)
+
+// AWSPublisher は Event を SNS トピックまたは EventBridge バスに送る
+type AWSPublisher struct {
+	provider    string
+	arn         string
+	region      string
+	endpoint    string
+	tags        []string
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	http        *http.Client
+	now         func() time.Time
+}
+
+func newAWSPublisher(cfg config.EventsConfig, httpClient *http.Client) (*AWSPublisher, error) {
+	if cfg.ARN == "" {
+		return nil, fmt.Errorf("events.arn is required for events.provider %s", cfg.Provider)
+	}
+	region := cfg.Region
+	if region == "" {
+		region = regionFromARN(cfg.ARN)
+	}
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("events.region is required when events.arn does not contain a region")
+	}
+	credentials, err := loadAWSCredentials(region)
+	if err != nil {
+		return nil, err
+	}
+
+	service := "sns"
+	if cfg.Provider == ProviderEventBridge {
+		service = "events"
+	}
+	endpoint := cfg.URL
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com/", service, region)
+	}
+	return &AWSPublisher{
+		provider:    cfg.Provider,
+		arn:         cfg.ARN,
+		region:      region,
+		endpoint:    endpoint,
+		tags:        cfg.Tags,
+		credentials: credentials,
+		signer:      v4.NewSigner(),
+		http:        httpClient,
+		now:         time.Now,
+	}, nil
+}
+
+// loadAWSCredentials は AWS SDK の既定の順で認証情報の取得元を選ぶ。
+// 環境変数、共有の設定ファイル (~/.aws/config, ~/.aws/credentials と AWS_PROFILE)、Web Identity (EKS の IRSA)、
+// ECS/Fargate のコンテナの認証情報、EC2 インスタンスメタデータ (IMDSv2) の順で、一時的な認証情報は SDK がキャッシュして更新する
+func loadAWSCredentials(region string) (aws.CredentialsProvider, error) {
+	// SDK は片方だけの静的な認証情報を黙って無視して次の取得元に進むため、設定の誤りとして止める
+	if (os.Getenv("AWS_ACCESS_KEY_ID") == "") != (os.Getenv("AWS_SECRET_ACCESS_KEY") == "") {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables must be set together")
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return cfg.Credentials, nil
+}
+
+// regionFromARN は arn:partition:service:region:account:resource のリージョンを返す
+func regionFromARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[3]
+}
+
+func (p *AWSPublisher) Publish(ctx context.Context, event Event) error {
+	detail, err := json.Marshal(webhookEvent{Event: event, Tags: eventTags(event, p.tags)})
+	if err != nil {
+		return err
+	}
+	if p.provider == ProviderEventBridge {
+		return p.putEvents(ctx, event, detail)
+	}
+	return p.publishSNS(ctx, event, detail)
+}
+
+// publishSNS は SNS の Publish API (Query 形式) でイベントの JSON をメッセージとして送る
+func (p *AWSPublisher) publishSNS(ctx context.Context, event Event, detail []byte) error {
+	subject := truncateSubject(datadogTitle(event), snsSubjectMaxLength)
+	form := url.Values{
+		"Action":   {"Publish"},
+		"Version":  {snsAPIVersion},
+		"TopicArn": {p.arn},
+		"Subject":  {subject},
+		"Message":  {string(detail)},
+		// サブスクリプションのフィルタポリシーで絞り込めるようにする
+		"MessageAttributes.entry.1.Name":              {"phase"},
+		"MessageAttributes.entry.1.Value.DataType":    {"String"},
+		"MessageAttributes.entry.1.Value.StringValue": {event.Phase},
+		"MessageAttributes.entry.2.Name":              {"table"},
+		"MessageAttributes.entry.2.Value.DataType":    {"String"},
+		"MessageAttributes.entry.2.Value.StringValue": {event.Table},
+	}
+	_, err := p.post(ctx, "sns", "application/x-www-form-urlencoded; charset=utf-8", nil, []byte(form.Encode()))
+	return err
+}
+
+// truncateSubject は SNS の Subject の上限に収まるよう、文字の途中で切らずに max バイト以内に縮める
+func truncateSubject(subject string, max int) string {
+	if len(subject) <= max {
+		return subject
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(subject[cut]) {
+		cut--
+	}
+	return subject[:cut]
+}
+
+type putEventsEntry struct {
+	Source       string `json:"Source"`
+	DetailType   string `json:"DetailType"`
+	Detail       string `json:"Detail"`
+	EventBusName string `json:"EventBusName"`
+	Time         int64  `json:"Time"`
+}
+
+type putEventsResponse struct {
+	FailedEntryCount int `json:"FailedEntryCount"`
+	Entries          []struct {
+		ErrorCode    string `json:"ErrorCode"`
+		ErrorMessage string `json:"ErrorMessage"`
+	} `json:"Entries"`
+}
+
+// eventBridgeDetailType はルールで絞り込みやすいよう、開始・終了・行数の見積もり違いで detail-type を分ける
+func eventBridgeDetailType(event Event) string {
+	switch event.Phase {
+	case PhaseStart:
+		return "Schema Change Started"
+	case PhaseRowCountDivergence:
+		return "Schema Change Row Count Divergence"
+	}
+	return "Schema Change Finished"
+}
+
+// putEvents は EventBridge の PutEvents API でイベントを1件送る
+func (p *AWSPublisher) putEvents(ctx context.Context, event Event, detail []byte) error {
+	body, err := json.Marshal(map[string][]putEventsEntry{"Entries": {{
+		Source:       eventBridgeSource,
+		DetailType:   eventBridgeDetailType(event),
+		Detail:       string(detail),
+		EventBusName: p.arn,
+		Time:         event.Timestamp.Unix(),
+	}}})
+	if err != nil {
+		return err
+	}
+	respBody, err := p.post(ctx, "events", "application/x-amz-json-1.1", map[string]string{"X-Amz-Target": "AWSEvents.PutEvents"}, body)
+	if err != nil {
+		return err
+	}
+
+	// PutEvents はエントリ単位の失敗も 200 で返す
+	var resp putEventsResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("failed to parse PutEvents response: %w", err)
+	}
+	if resp.FailedEntryCount > 0 {
+		for _, entry := range resp.Entries {
+			if entry.ErrorCode != "" {
+				return fmt.Errorf("EventBridge rejected the event: %s: %s", entry.ErrorCode, entry.ErrorMessage)
+			}
+		}
+		return fmt.Errorf("EventBridge rejected %d events", resp.FailedEntryCount)
+	}
+	return nil
+}
+
+// post は SigV4 で署名したリクエストを送り、レスポンスの本文を返す
+func (p *AWSPublisher) post(ctx context.Context, service, contentType string, headers map[string]string, body []byte) ([]byte, error) {
+	creds, err := p.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := p.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(payloadHash[:]), service, p.region, p.now()); err != nil {
+		return nil, fmt.Errorf("failed to sign %s request: %w", service, err)
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send event: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(respBody) > 1024 {
+			respBody = respBody[:1024]
+		}
+		return nil, fmt.Errorf("%s API returned %s: %s", service, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
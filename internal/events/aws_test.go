@@ -0,0 +1,180 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearAWSEnvironment はテストがホストの AWS の設定や認証情報を読まないようにする
+func clearAWSEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_REGION", "AWS_PROFILE",
+		"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN", "AWS_CA_BUNDLE"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func setAWSCredentials(t *testing.T) {
+	clearAWSEnvironment(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+}
+
+// publishedAuthorization は SNS に1件送ったときの Authorization と X-Amz-Security-Token ヘッダを返す
+func publishedAuthorization(t *testing.T) (string, string) {
+	var authorization, token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		token = r.Header.Get("X-Amz-Security-Token")
+	}))
+	defer server.Close()
+
+	publisher, err := NewPublisher(config.EventsConfig{Provider: ProviderSNS, ARN: "arn:aws:sns:ap-northeast-1:123456789012:schema-changes", URL: server.URL})
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(context.Background(), Event{Phase: PhaseStart, Table: "users", Timestamp: time.Unix(1700000000, 0)}))
+	return authorization, token
+}
+
+func TestAWSCredentialsFromSharedConfig(t *testing.T) {
+	clearAWSEnvironment(t)
+	credentials := "[default]\naws_access_key_id = AKIDDEFAULT\naws_secret_access_key = secret\n\n" +
+		"[migrations]\naws_access_key_id = AKIDPROFILE\naws_secret_access_key = secret\naws_session_token = profile-session\n"
+	require.NoError(t, os.WriteFile(os.Getenv("AWS_SHARED_CREDENTIALS_FILE"), []byte(credentials), 0o600))
+
+	authorization, _ := publishedAuthorization(t)
+	assert.Contains(t, authorization, "Credential=AKIDDEFAULT/")
+
+	t.Setenv("AWS_PROFILE", "migrations")
+	authorization, token := publishedAuthorization(t)
+	assert.Contains(t, authorization, "Credential=AKIDPROFILE/")
+	assert.Equal(t, "profile-session", token)
+
+	t.Setenv("AWS_PROFILE", "missing")
+	_, err := NewPublisher(config.EventsConfig{Provider: ProviderSNS, ARN: "arn:aws:sns:ap-northeast-1:123456789012:schema-changes"})
+	assert.ErrorContains(t, err, "missing")
+}
+
+func TestAWSCredentialsFromContainer(t *testing.T) {
+	clearAWSEnvironment(t)
+	// ECS/Fargate のタスクロールの認証情報エンドポイント
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "container-token", r.Header.Get("Authorization"))
+		_, _ = fmt.Fprintf(w, `{"AccessKeyId":"ASIACONTAINER","SecretAccessKey":"secret","Token":"container-session","Expiration":%q}`,
+			time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	defer server.Close()
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", server.URL+"/v2/credentials/task")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "container-token")
+
+	authorization, token := publishedAuthorization(t)
+	assert.Contains(t, authorization, "Credential=ASIACONTAINER/")
+	assert.Equal(t, "container-session", token)
+}
+
+func TestTruncateSubject(t *testing.T) {
+	assert.Equal(t, "alterguard", truncateSubject("alterguard", 100))
+	// 3バイトの文字の途中では切らない
+	subject := truncateSubject(strings.Repeat("a", 98)+"テーブル", 100)
+	assert.True(t, utf8.ValidString(subject))
+	assert.Equal(t, strings.Repeat("a", 98), subject)
+	assert.Len(t, truncateSubject(strings.Repeat("あ", 40), 100), 99)
+}
+
+func TestNewAWSPublisher(t *testing.T) {
+	setAWSCredentials(t)
+
+	_, err := NewPublisher(config.EventsConfig{Provider: ProviderSNS})
+	assert.ErrorContains(t, err, "events.arn")
+
+	_, err = NewPublisher(config.EventsConfig{Provider: ProviderEventBridge, ARN: "migrations"})
+	assert.ErrorContains(t, err, "events.region")
+
+	publisher, err := NewPublisher(config.EventsConfig{Provider: ProviderSNS, ARN: "arn:aws:sns:ap-northeast-1:123456789012:schema-changes"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://sns.ap-northeast-1.amazonaws.com/", publisher.(*AWSPublisher).endpoint)
+
+	t.Setenv("AWS_REGION", "us-west-2")
+	publisher, err = NewPublisher(config.EventsConfig{Provider: ProviderEventBridge, ARN: "migrations"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://events.us-west-2.amazonaws.com/", publisher.(*AWSPublisher).endpoint)
+
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	_, err = NewPublisher(config.EventsConfig{Provider: ProviderSNS, ARN: "arn:aws:sns:ap-northeast-1:123456789012:schema-changes"})
+	assert.ErrorContains(t, err, "AWS_ACCESS_KEY_ID")
+}
+
+func TestSNSPublisher(t *testing.T) {
+	setAWSCredentials(t)
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=AKIDEXAMPLE/")
+		assert.Contains(t, r.Header.Get("Authorization"), "/ap-northeast-1/sns/aws4_request")
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+	}))
+	defer server.Close()
+
+	arn := "arn:aws:sns:ap-northeast-1:123456789012:schema-changes"
+	publisher, err := NewPublisher(config.EventsConfig{Provider: ProviderSNS, ARN: arn, URL: server.URL})
+	require.NoError(t, err)
+
+	err = publisher.Publish(context.Background(), Event{Phase: PhaseStart, Table: "users", Method: "pt-osc", Alter: "ADD COLUMN age INT", Timestamp: time.Unix(1700000000, 0)})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Publish", form.Get("Action"))
+	assert.Equal(t, arn, form.Get("TopicArn"))
+	assert.Equal(t, "alterguard: pt-osc started on users", form.Get("Subject"))
+	assert.Equal(t, "start", form.Get("MessageAttributes.entry.1.Value.StringValue"))
+	var message map[string]any
+	require.NoError(t, json.Unmarshal([]byte(form.Get("Message")), &message))
+	assert.Equal(t, "users", message["table"])
+}
+
+func TestEventBridgePublisher(t *testing.T) {
+	setAWSCredentials(t)
+	response := `{"FailedEntryCount":0,"Entries":[{"EventId":"1"}]}`
+	var entries map[string][]putEventsEntry
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "AWSEvents.PutEvents", r.Header.Get("X-Amz-Target"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/us-east-1/events/aws4_request")
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &entries))
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	arn := "arn:aws:events:us-east-1:123456789012:event-bus/migrations"
+	publisher, err := NewPublisher(config.EventsConfig{Provider: ProviderEventBridge, ARN: arn, URL: server.URL})
+	require.NoError(t, err)
+
+	event := Event{Phase: PhaseEnd, Table: "users", Method: "pt-osc", Success: true, Timestamp: time.Unix(1700000000, 0)}
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	require.Len(t, entries["Entries"], 1)
+	entry := entries["Entries"][0]
+	assert.Equal(t, "alterguard", entry.Source)
+	assert.Equal(t, "Schema Change Finished", entry.DetailType)
+	assert.Equal(t, arn, entry.EventBusName)
+	assert.Contains(t, entry.Detail, `"success":true`)
+
+	response = `{"FailedEntryCount":1,"Entries":[{"ErrorCode":"AccessDeniedException","ErrorMessage":"not authorized"}]}`
+	assert.ErrorContains(t, publisher.Publish(context.Background(), event), "AccessDeniedException")
+}
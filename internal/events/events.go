@@ -0,0 +1,220 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+)
+
+const (
+	ProviderDatadog     = "datadog"
+	ProviderWebhook     = "webhook"
+	ProviderSNS         = "sns"
+	ProviderEventBridge = "eventbridge"
+
+	PhaseStart = "start"
+	PhaseEnd   = "end"
+	// 方式の判定に使った統計情報の行数が COUNT(*) と大きく違った
+	PhaseRowCountDivergence = "row_count_divergence"
+
+	defaultDatadogSite = "datadoghq.com"
+)
+
+// Event はマイグレーションの開始または終了、または行数の見積もり違いを表す
+type Event struct {
+	Phase       string `json:"phase"`
+	Environment string `json:"environment,omitempty"`
+	Table       string `json:"table"`
+	Method      string `json:"method"`
+	Alter       string `json:"alter"`
+	RowCount    int64  `json:"row_count"`
+	// 以下は終了時のみ
+	DurationSeconds float64   `json:"duration_seconds,omitempty"`
+	Success         bool      `json:"success"`
+	Error           string    `json:"error,omitempty"`
+	RowsPerSecond   float64   `json:"rows_per_second,omitempty"`
+	LagWaits        int       `json:"lag_waits,omitempty"`
+	LoadPauses      int       `json:"load_pauses,omitempty"`
+	Throttled       string    `json:"throttled,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+	// 以下は row_count_divergence のみ。RowCount は COUNT(*) の行数
+	EstimatedRowCount int64   `json:"estimated_row_count,omitempty"`
+	DivergencePercent float64 `json:"divergence_percent,omitempty"`
+}
+
+// Publisher はイベントを外部に送る
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// NewPublisher は設定に応じた Publisher を返す。provider が空なら nil を返す。
+func NewPublisher(cfg config.EventsConfig) (Publisher, error) {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case ProviderDatadog:
+		apiKey := os.Getenv("DD_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("DD_API_KEY environment variable is required for events.provider datadog")
+		}
+		url := cfg.URL
+		if url == "" {
+			site := cfg.Site
+			if site == "" {
+				site = defaultDatadogSite
+			}
+			url = fmt.Sprintf("https://api.%s/api/v1/events", site)
+		}
+		return &DatadogPublisher{url: url, apiKey: apiKey, tags: cfg.Tags, http: httpClient}, nil
+	case ProviderWebhook:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("events.url is required for events.provider webhook")
+		}
+		return &WebhookPublisher{url: cfg.URL, tags: cfg.Tags, http: httpClient}, nil
+	case ProviderSNS, ProviderEventBridge:
+		return newAWSPublisher(cfg, httpClient)
+	default:
+		return nil, fmt.Errorf("unknown events.provider %q (expected datadog, webhook, sns or eventbridge)", cfg.Provider)
+	}
+}
+
+// DatadogPublisher は Datadog Events API にイベントを送る。
+// 開始と終了は同じ aggregation_key でまとめられ、ダッシュボード上のマーカーとして表示される。
+type DatadogPublisher struct {
+	url    string
+	apiKey string
+	tags   []string
+	http   *http.Client
+}
+
+type datadogEvent struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	Tags           []string `json:"tags"`
+	AlertType      string   `json:"alert_type"`
+	AggregationKey string   `json:"aggregation_key"`
+	SourceTypeName string   `json:"source_type_name"`
+	DateHappened   int64    `json:"date_happened"`
+}
+
+func (p *DatadogPublisher) Publish(ctx context.Context, event Event) error {
+	payload := datadogEvent{
+		Title:          datadogTitle(event),
+		Text:           fmt.Sprintf("%%%%%%\n```\nALTER TABLE %s %s\n```\nrows: %d\n%%%%%%", event.Table, event.Alter, event.RowCount),
+		Tags:           eventTags(event, p.tags),
+		AlertType:      "info",
+		AggregationKey: fmt.Sprintf("alterguard-%s-%s", event.Environment, event.Table),
+		SourceTypeName: "alterguard",
+		DateHappened:   event.Timestamp.Unix(),
+	}
+	if event.Phase == PhaseRowCountDivergence {
+		payload.AlertType = "warning"
+		payload.Text = fmt.Sprintf("%%%%%%\nestimated rows: %d\nCOUNT(*): %d\ndivergence: %.1f%%\n%%%%%%", event.EstimatedRowCount, event.RowCount, event.DivergencePercent)
+	}
+	if event.Phase == PhaseEnd {
+		if event.RowsPerSecond > 0 || event.Throttled != "" {
+			throttled := event.Throttled
+			if throttled == "" {
+				throttled = "none"
+			}
+			payload.Text = strings.TrimSuffix(payload.Text, "\n%%%") +
+				fmt.Sprintf("\ncopy rate: %.0f rows/sec\nthrottled by: %s\n%%%%%%", event.RowsPerSecond, throttled)
+		}
+		payload.AlertType = "success"
+		if !event.Success {
+			payload.AlertType = "error"
+			payload.Text = strings.TrimSuffix(payload.Text, "\n%%%") + "\nerror: " + event.Error + "\n%%%"
+		}
+	}
+
+	return postJSON(ctx, p.http, p.url, map[string]string{"DD-API-KEY": p.apiKey}, payload)
+}
+
+func datadogTitle(event Event) string {
+	if event.Phase == PhaseRowCountDivergence {
+		return fmt.Sprintf("alterguard: row count estimate of %s was off by %.0f%%", event.Table, event.DivergencePercent)
+	}
+	if event.Phase == PhaseStart {
+		return fmt.Sprintf("alterguard: %s started on %s", event.Method, event.Table)
+	}
+	duration := (time.Duration(event.DurationSeconds * float64(time.Second))).Round(time.Second)
+	if event.Success {
+		return fmt.Sprintf("alterguard: %s finished on %s in %s", event.Method, event.Table, duration)
+	}
+	return fmt.Sprintf("alterguard: %s failed on %s after %s", event.Method, event.Table, duration)
+}
+
+// WebhookPublisher は任意のイベント API に Event を JSON で POST する
+type WebhookPublisher struct {
+	url  string
+	tags []string
+	http *http.Client
+}
+
+type webhookEvent struct {
+	Event
+	Tags []string `json:"tags"`
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, event Event) error {
+	return postJSON(ctx, p.http, p.url, nil, webhookEvent{Event: event, Tags: eventTags(event, p.tags)})
+}
+
+// eventTags は設定のタグにテーブル・環境・方法・結果のタグを加える
+func eventTags(event Event, extra []string) []string {
+	tags := append([]string{}, extra...)
+	tags = append(tags, "source:alterguard", "table:"+event.Table, "method:"+event.Method, "phase:"+event.Phase)
+	if event.Environment != "" {
+		tags = append(tags, "env:"+event.Environment)
+	}
+	if event.Phase == PhaseEnd {
+		result := "success"
+		if !event.Success {
+			result = "failure"
+		}
+		tags = append(tags, "result:"+result, fmt.Sprintf("duration_seconds:%d", int64(event.DurationSeconds)))
+		tags = append(tags, fmt.Sprintf("throttled:%t", event.Throttled != ""))
+	}
+	if event.Phase == PhaseRowCountDivergence {
+		tags = append(tags, fmt.Sprintf("divergence_percent:%d", int64(event.DivergencePercent)))
+	}
+	return tags
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("event API returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(config.EventsConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, publisher)
+
+	t.Setenv("DD_API_KEY", "")
+	_, err = NewPublisher(config.EventsConfig{Provider: ProviderDatadog})
+	assert.ErrorContains(t, err, "DD_API_KEY")
+
+	t.Setenv("DD_API_KEY", "key")
+	publisher, err = NewPublisher(config.EventsConfig{Provider: ProviderDatadog, Site: "datadoghq.eu"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.datadoghq.eu/api/v1/events", publisher.(*DatadogPublisher).url)
+
+	_, err = NewPublisher(config.EventsConfig{Provider: ProviderWebhook})
+	assert.ErrorContains(t, err, "events.url")
+
+	_, err = NewPublisher(config.EventsConfig{Provider: "pagerduty"})
+	assert.ErrorContains(t, err, "unknown events.provider")
+}
+
+func TestDatadogPublisher(t *testing.T) {
+	var received datadogEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.Header.Get("DD-API-KEY"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	t.Setenv("DD_API_KEY", "key")
+	publisher, err := NewPublisher(config.EventsConfig{Provider: ProviderDatadog, URL: server.URL, Tags: []string{"service:users-db"}})
+	require.NoError(t, err)
+
+	err = publisher.Publish(context.Background(), Event{
+		Phase:           PhaseEnd,
+		Environment:     "prod",
+		Table:           "users",
+		Method:          "pt-osc",
+		Alter:           "ADD COLUMN age INT",
+		RowCount:        1000,
+		DurationSeconds: 125,
+		Error:           "exit status 1",
+		Timestamp:       time.Unix(1700000000, 0),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "alterguard: pt-osc failed on users after 2m5s", received.Title)
+	assert.Equal(t, "error", received.AlertType)
+	assert.Equal(t, "alterguard-prod-users", received.AggregationKey)
+	assert.Equal(t, int64(1700000000), received.DateHappened)
+	assert.Contains(t, received.Text, "ALTER TABLE users ADD COLUMN age INT")
+	assert.Contains(t, received.Text, "error: exit status 1")
+	assert.Equal(t, []string{
+		"service:users-db", "source:alterguard", "table:users", "method:pt-osc", "phase:end",
+		"env:prod", "result:failure", "duration_seconds:125", "throttled:false",
+	}, received.Tags)
+}
+
+func TestDatadogPublisherRowCountDivergence(t *testing.T) {
+	var received datadogEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	t.Setenv("DD_API_KEY", "key")
+	publisher, err := NewPublisher(config.EventsConfig{Provider: ProviderDatadog, URL: server.URL})
+	require.NoError(t, err)
+
+	err = publisher.Publish(context.Background(), Event{
+		Phase:             PhaseRowCountDivergence,
+		Environment:       "prod",
+		Table:             "users",
+		Method:            "alter-table",
+		RowCount:          5000,
+		EstimatedRowCount: 900,
+		DivergencePercent: 82,
+		Timestamp:         time.Unix(1700000000, 0),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "alterguard: row count estimate of users was off by 82%", received.Title)
+	assert.Equal(t, "warning", received.AlertType)
+	assert.Contains(t, received.Text, "estimated rows: 900\nCOUNT(*): 5000\ndivergence: 82.0%")
+	assert.Equal(t, []string{
+		"source:alterguard", "table:users", "method:alter-table", "phase:row_count_divergence",
+		"env:prod", "divergence_percent:82",
+	}, received.Tags)
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	publisher, err := NewPublisher(config.EventsConfig{Provider: ProviderWebhook, URL: server.URL})
+	require.NoError(t, err)
+
+	require.NoError(t, publisher.Publish(context.Background(), Event{Phase: PhaseStart, Table: "users", Method: "pt-osc"}))
+	assert.Equal(t, "start", received["phase"])
+	assert.Equal(t, "users", received["table"])
+	assert.Contains(t, received["tags"], "table:users")
+}
+
+func TestPublishErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	publisher, err := NewPublisher(config.EventsConfig{Provider: ProviderWebhook, URL: server.URL})
+	require.NoError(t, err)
+	assert.ErrorContains(t, publisher.Publish(context.Background(), Event{}), "403")
+}
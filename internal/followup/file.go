@@ -0,0 +1,108 @@
+package followup
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ActionSwap    = "swap"
+	ActionCleanup = "cleanup"
+)
+
+// Step は run の後に実行する swap / cleanup の1手順
+type Step struct {
+	Action       string `yaml:"action"`
+	Table        string `yaml:"table"`
+	DropTriggers bool   `yaml:"drop_triggers,omitempty"`
+	DropTable    bool   `yaml:"drop_table,omitempty"`
+	DropNewTable bool   `yaml:"drop_new_table,omitempty"`
+}
+
+// File は run が生成し、follow-up コマンドが読み込む後続作業の一覧
+type File struct {
+	GeneratedAt time.Time `yaml:"generated_at"`
+	Environment string    `yaml:"environment,omitempty"`
+	Steps       []Step    `yaml:"steps"`
+}
+
+// Command はこの手順と同じ操作を行う alterguard のコマンドラインを返す
+func (s Step) Command(prefix string) string {
+	if s.Action == ActionSwap {
+		return fmt.Sprintf("%s swap %s", prefix, s.Table)
+	}
+
+	command := fmt.Sprintf("%s cleanup %s", prefix, s.Table)
+	if s.DropTriggers {
+		command += " --drop-triggers"
+	}
+	if s.DropTable {
+		command += " --drop-table"
+	}
+	if s.DropNewTable {
+		command += " --drop-new-table"
+	}
+	return command
+}
+
+func (s Step) validate() error {
+	if s.Table == "" {
+		return fmt.Errorf("table is required")
+	}
+	switch s.Action {
+	case ActionSwap:
+		return nil
+	case ActionCleanup:
+		if !s.DropTriggers && !s.DropTable && !s.DropNewTable {
+			return fmt.Errorf("cleanup of %s must enable at least one of drop_triggers, drop_table or drop_new_table", s.Table)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q for %s", s.Action, s.Table)
+	}
+}
+
+// Write は後続作業の一覧をレビューしやすいYAMLとして書き出す
+func Write(path string, file *File) error {
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal follow-up tasks: %w", err)
+	}
+
+	header := []string{
+		"# Generated by alterguard run. Review before executing with:",
+		"#   alterguard follow-up " + path,
+		"",
+	}
+	content := strings.Join(header, "\n") + string(data)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write follow-up tasks file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Load は follow-up ファイルを読み込み、各手順を検証する
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read follow-up tasks file %s: %w", path, err)
+	}
+
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse follow-up tasks file %s: %w", path, err)
+	}
+	if len(file.Steps) == 0 {
+		return nil, fmt.Errorf("follow-up tasks file %s has no steps", path)
+	}
+	for i, step := range file.Steps {
+		if err := step.validate(); err != nil {
+			return nil, fmt.Errorf("invalid step %d in %s: %w", i+1, path, err)
+		}
+	}
+	return &file, nil
+}
@@ -0,0 +1,65 @@
+package followup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepCommand(t *testing.T) {
+	assert.Equal(t, "alterguard swap users", Step{Action: ActionSwap, Table: "users"}.Command("alterguard"))
+	assert.Equal(t, "alterguard -e prod cleanup users --drop-triggers --drop-table",
+		Step{Action: ActionCleanup, Table: "users", DropTriggers: true, DropTable: true}.Command("alterguard -e prod"))
+}
+
+func TestWriteAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "follow-up.yaml")
+	file := &File{
+		GeneratedAt: time.Date(2024, 5, 10, 2, 0, 0, 0, time.UTC),
+		Environment: "prod",
+		Steps: []Step{
+			{Action: ActionSwap, Table: "users"},
+			{Action: ActionCleanup, Table: "users", DropTable: true},
+		},
+	}
+
+	require.NoError(t, Write(path, file))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# Generated by alterguard run.")
+	assert.Contains(t, string(data), "action: swap")
+	assert.NotContains(t, string(data), "drop_triggers")
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, file, loaded)
+}
+
+func TestLoadValidatesSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "no steps", content: "environment: prod\n", expected: "has no steps"},
+		{name: "missing table", content: "steps:\n  - action: swap\n", expected: "table is required"},
+		{name: "unknown action", content: "steps:\n  - action: drop\n    table: users\n", expected: `unknown action "drop"`},
+		{name: "empty cleanup", content: "steps:\n  - action: cleanup\n    table: users\n", expected: "must enable at least one"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "follow-up.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			_, err := Load(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package ghactions
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/artifacts"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/sirupsen/logrus"
+)
+
+// SummaryPathEnv は GitHub Actions がジョブサマリの書き込み先として設定する環境変数
+const SummaryPathEnv = "GITHUB_STEP_SUMMARY"
+
+// Reporter は GitHub Actions 上での実行結果をジョブサマリとアノテーションとして出力する。
+// logrus のフックとして登録すると、警告ログを ::warning アノテーションとして出力しサマリにも載せる。
+type Reporter struct {
+	summaryPath string
+	out         io.Writer
+
+	mu       sync.Mutex
+	warnings []string
+}
+
+// NewReporterFromEnv は GITHUB_STEP_SUMMARY が設定されていれば Reporter を返す。設定されていなければ nil を返す。
+func NewReporterFromEnv(out io.Writer) *Reporter {
+	path := os.Getenv(SummaryPathEnv)
+	if path == "" {
+		return nil
+	}
+	return NewReporter(path, out)
+}
+
+func NewReporter(summaryPath string, out io.Writer) *Reporter {
+	return &Reporter{summaryPath: summaryPath, out: out}
+}
+
+func (r *Reporter) Levels() []logrus.Level {
+	return []logrus.Level{logrus.WarnLevel}
+}
+
+func (r *Reporter) Fire(entry *logrus.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.warnings = append(r.warnings, entry.Message)
+	_, err := fmt.Fprintf(r.out, "::warning title=alterguard::%s\n", escapeData(entry.Message))
+	return err
+}
+
+// Finish は失敗したタスクを ::error アノテーションとして出力し、ジョブサマリに Markdown を追記する
+func (r *Reporter) Finish(report artifacts.Report, plan any) error {
+	for _, result := range report.Tasks {
+		if result.Success {
+			continue
+		}
+		title := "alterguard " + result.Task
+		if result.TableName != "" {
+			title = fmt.Sprintf("alterguard %s: %s", result.Task, result.TableName)
+		}
+		if _, err := fmt.Fprintf(r.out, "::error title=%s::%s\n", escapeProperty(title), escapeData(result.Error)); err != nil {
+			return err
+		}
+	}
+	if report.Error != "" && !hasFailedTask(report) {
+		if _, err := fmt.Fprintf(r.out, "::error title=%s::%s\n", escapeProperty("alterguard "+report.Command), escapeData(report.Error)); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	warnings := append([]string{}, r.warnings...)
+	r.mu.Unlock()
+
+	// 同じジョブの別ステップの出力を消さないよう追記する
+	f, err := os.OpenFile(r.summaryPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644) // #nosec G302 G304 -- GitHub Actions が用意するファイル
+	if err != nil {
+		return fmt.Errorf("failed to open job summary: %w", err)
+	}
+	if _, err := io.WriteString(f, renderSummary(report, plan, warnings)); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write job summary: %w", err)
+	}
+	return f.Close()
+}
+
+func hasFailedTask(report artifacts.Report) bool {
+	for _, result := range report.Tasks {
+		if !result.Success {
+			return true
+		}
+	}
+	return false
+}
+
+func renderSummary(report artifacts.Report, plan any, warnings []string) string {
+	var b strings.Builder
+
+	status := "✅ Succeeded"
+	if !report.Success {
+		status = "❌ Failed"
+	}
+	fmt.Fprintf(&b, "## alterguard %s: %s\n\n", report.Command, status)
+
+	fmt.Fprintf(&b, "| Environment | Operator | Dry run | Duration |\n")
+	fmt.Fprintf(&b, "| --- | --- | --- | --- |\n")
+	environment := report.Environment
+	if environment == "" {
+		environment = "-"
+	}
+	fmt.Fprintf(&b, "| %s | %s | %t | %s |\n\n",
+		escapeCell(environment), escapeCell(report.Operator.Summary()), report.DryRun, formatDuration(report.DurationSeconds))
+
+	if report.Error != "" {
+		fmt.Fprintf(&b, "**Error:** %s\n\n", escapeCell(report.Error))
+	}
+
+	if p, ok := plan.(task.Plan); ok && len(p.Tables) > 0 {
+		fmt.Fprintf(&b, "### Plan\n\n")
+		fmt.Fprintf(&b, "| Table | Rows | Planned method | Changes |\n")
+		fmt.Fprintf(&b, "| --- | ---: | --- | --- |\n")
+		for _, table := range p.Tables {
+			rows := "-"
+			if table.RowCount != nil {
+				rows = fmt.Sprintf("%d", *table.RowCount)
+			}
+			changes := append(append([]string{}, table.AlterParts...), table.OtherQueries...)
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+				escapeCell(table.TableName), rows, escapeCell(table.PlannedMethod), codeList(changes))
+		}
+		if len(p.NonTableQueries) > 0 {
+			fmt.Fprintf(&b, "\nQueries without a table: %s\n", codeList(p.NonTableQueries))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(report.Tasks) > 0 {
+		fmt.Fprintf(&b, "### Results\n\n")
+		fmt.Fprintf(&b, "| Table | Method | Rows | Duration | Result |\n")
+		fmt.Fprintf(&b, "| --- | --- | ---: | ---: | --- |\n")
+		for _, result := range report.Tasks {
+			table := result.TableName
+			if table == "" {
+				table = "-"
+			}
+			outcome := "✅"
+			if !result.Success {
+				outcome = "❌ " + escapeCell(result.Error)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %d | %s | %s |\n",
+				escapeCell(table), escapeCell(result.Method), result.RowCount, formatDuration(result.DurationSeconds), outcome)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(warnings) > 0 {
+		fmt.Fprintf(&b, "### Warnings\n\n")
+		for _, warning := range warnings {
+			fmt.Fprintf(&b, "- %s\n", escapeCell(warning))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func formatDuration(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second).String()
+}
+
+func codeList(items []string) string {
+	quoted := make([]string, 0, len(items))
+	for _, item := range items {
+		quoted = append(quoted, "`"+escapeCell(strings.ReplaceAll(item, "`", ""))+"`")
+	}
+	return strings.Join(quoted, "<br>")
+}
+
+// escapeCell は Markdown の表を崩さないよう | と改行を置き換える
+func escapeCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", " "), "\n", " ")
+}
+
+// escapeData はワークフローコマンドのメッセージ部分をエスケープする
+func escapeData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeProperty はワークフローコマンドのプロパティ値をエスケープする
+func escapeProperty(s string) string {
+	s = escapeData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
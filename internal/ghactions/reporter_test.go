@@ -0,0 +1,93 @@
+package ghactions
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/artifacts"
+	"github.com/pyama86/alterguard/internal/audit"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReporterFinish(t *testing.T) {
+	summaryPath := filepath.Join(t.TempDir(), "summary.md")
+	require.NoError(t, os.WriteFile(summaryPath, []byte("previous step\n"), 0o600))
+
+	var out bytes.Buffer
+	reporter := NewReporter(summaryPath, &out)
+
+	logger := logrus.New()
+	logger.SetOutput(&bytes.Buffer{})
+	logger.AddHook(reporter)
+	logger.Info("not annotated")
+	logger.Warn("users has 3 dependent views\nsecond line")
+
+	rowCount := int64(1500000)
+	plan := task.Plan{
+		Environment: "prod",
+		Tables: []task.PlanTable{
+			{TableName: "users", AlterParts: []string{"ADD COLUMN age INT"}, RowCount: &rowCount, PlannedMethod: "pt-osc"},
+			{TableName: "orders", OtherQueries: []string{"DROP TABLE orders_tmp"}, PlannedMethod: "small-query"},
+		},
+	}
+	report := artifacts.Report{
+		Command:         "run",
+		Operator:        audit.Identity{Operator: "alice", OSUser: "runner", Host: "gha"},
+		Environment:     "prod",
+		DurationSeconds: 125,
+		Error:           "failed to execute queries for table users: pt-osc failed",
+		Tasks: []artifacts.TaskResult{
+			{Task: "pt-osc", TableName: "users", Method: "pt-osc", RowCount: rowCount, DurationSeconds: 120, Error: "pt-osc failed: exit status 1, 50% done"},
+		},
+	}
+
+	require.NoError(t, reporter.Finish(report, plan))
+
+	assert.Equal(t,
+		"::warning title=alterguard::users has 3 dependent views%0Asecond line\n"+
+			"::error title=alterguard pt-osc%3A users::pt-osc failed: exit status 1, 50%25 done\n",
+		out.String())
+
+	data, err := os.ReadFile(summaryPath)
+	require.NoError(t, err)
+	summary := string(data)
+	assert.Contains(t, summary, "previous step\n## alterguard run: ❌ Failed")
+	assert.Contains(t, summary, "| prod | alice (runner@gha) | false | 2m5s |")
+	assert.Contains(t, summary, "| users | 1500000 | pt-osc | `ADD COLUMN age INT` |")
+	assert.Contains(t, summary, "| orders | - | small-query | `DROP TABLE orders_tmp` |")
+	assert.Contains(t, summary, "| users | pt-osc | 1500000 | 2m0s | ❌ pt-osc failed: exit status 1, 50% done |")
+	assert.Contains(t, summary, "- users has 3 dependent views second line")
+}
+
+func TestReporterFinishRunErrorWithoutTask(t *testing.T) {
+	summaryPath := filepath.Join(t.TempDir(), "summary.md")
+	var out bytes.Buffer
+	reporter := NewReporter(summaryPath, &out)
+
+	require.NoError(t, reporter.Finish(artifacts.Report{Command: "cleanup", Success: false, Error: "lock wait timeout"}, nil))
+	assert.Equal(t, "::error title=alterguard cleanup::lock wait timeout\n", out.String())
+
+	data, err := os.ReadFile(summaryPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "**Error:** lock wait timeout")
+	assert.NotContains(t, string(data), "### Plan")
+}
+
+func TestNewReporterFromEnv(t *testing.T) {
+	t.Setenv(SummaryPathEnv, "")
+	assert.Nil(t, NewReporterFromEnv(&bytes.Buffer{}))
+
+	t.Setenv(SummaryPathEnv, filepath.Join(t.TempDir(), "summary.md"))
+	assert.NotNil(t, NewReporterFromEnv(&bytes.Buffer{}))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "0s", formatDuration(0.2))
+	assert.Equal(t, "1h0m1s", formatDuration(float64(time.Hour/time.Second)+1))
+}
@@ -0,0 +1,234 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/artifacts"
+)
+
+// AppliedStatement はある環境で適用済みの文。同じ文が何度も実行されていれば最初の時刻を持つ
+type AppliedStatement struct {
+	Hash      string
+	Query     string
+	AppliedAt time.Time
+}
+
+// MissingStatement は他の環境で適用済みなのに、比較対象の環境には適用されていない文
+type MissingStatement struct {
+	AppliedStatement
+	AppliedIn []string
+}
+
+// EnvironmentDrift は環境ごとの適用漏れ
+type EnvironmentDrift struct {
+	Environment string
+	Missing     []MissingStatement
+}
+
+// LoadReports は --artifacts-dir の下にある実行ごとのディレクトリから report.json を読み込み、実行履歴として返す。
+// report.json のないディレクトリ(実行中や異常終了したもの)は読み飛ばす
+func LoadReports(baseDir string) ([]artifacts.Report, error) {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifacts directory %s: %w", baseDir, err)
+	}
+
+	var reports []artifacts.Report
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(baseDir, entry.Name(), "report.json")
+		data, err := os.ReadFile(path) // #nosec G304
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var report artifacts.Report
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// LatestProgress は --artifacts-dir の下の実行ごとのディレクトリから、テーブルの pt-osc の進み具合のうち
+// 最後に書き出されたものと、その実行のディレクトリを返す。見つからなければ nil を返す
+func LatestProgress(baseDir, tableName string) (*artifacts.CopyProgress, string, error) {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read artifacts directory %s: %w", baseDir, err)
+	}
+
+	var latest *artifacts.CopyProgress
+	var latestDir string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		runDir := filepath.Join(baseDir, entry.Name())
+		path := artifacts.ProgressPath(runDir, tableName)
+		data, err := os.ReadFile(path) // #nosec G304
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var progress artifacts.CopyProgress
+		if err := json.Unmarshal(data, &progress); err != nil {
+			return nil, "", fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if latest == nil || progress.UpdatedAt.After(latest.UpdatedAt) {
+			latest = &progress
+			latestDir = runDir
+		}
+	}
+	return latest, latestDir, nil
+}
+
+// QueryHash は空白の違いと末尾のセミコロンを無視した文のハッシュを返す
+func QueryHash(query string) string {
+	normalized := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(query), ";"))
+	normalized = strings.Join(strings.Fields(normalized), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// BatchHash はクエリの並び全体のハッシュを返す。各クエリは QueryHash と同じく空白の違いと末尾のセミコロンを無視する
+func BatchHash(queries []string) string {
+	hashes := make([]string, 0, len(queries))
+	for _, query := range queries {
+		hashes = append(hashes, QueryHash(query))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(hashes, "\n")))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// FindRecentRuns は environment で batchHash と同じバッチを実行し、since 以降に開始して成功した run を新しい順に返す。
+// dry run と失敗した実行は含めない
+func FindRecentRuns(reports []artifacts.Report, environment, batchHash string, since time.Time) []artifacts.Report {
+	var runs []artifacts.Report
+	for _, report := range reports {
+		if report.Command != "run" || report.DryRun || !report.Success {
+			continue
+		}
+		if report.Environment != environment || report.BatchHash != batchHash || report.StartedAt.Before(since) {
+			continue
+		}
+		runs = append(runs, report)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs
+}
+
+// AppliedStatements は dry run 以外の実行で成功したタスクの文を環境ごとにまとめる
+func AppliedStatements(reports []artifacts.Report) map[string]map[string]AppliedStatement {
+	applied := make(map[string]map[string]AppliedStatement)
+	for _, report := range reports {
+		if report.DryRun {
+			continue
+		}
+		for _, task := range report.Tasks {
+			if !task.Success {
+				continue
+			}
+			statements := applied[report.Environment]
+			if statements == nil {
+				statements = make(map[string]AppliedStatement)
+				applied[report.Environment] = statements
+			}
+			for i, query := range task.Queries {
+				// redaction.mode で伏せたクエリは、伏せる前のハッシュが残っていればそれで比べる
+				hash := QueryHash(query)
+				if len(task.QueryHashes) == len(task.Queries) {
+					hash = task.QueryHashes[i]
+				}
+				if existing, ok := statements[hash]; ok && !task.FinishedAt.Before(existing.AppliedAt) {
+					continue
+				}
+				statements[hash] = AppliedStatement{Hash: hash, Query: query, AppliedAt: task.FinishedAt}
+			}
+		}
+	}
+	return applied
+}
+
+// DiffEnvironments は環境ごとに、他の環境のいずれかで適用済みなのに適用されていない文を返す
+func DiffEnvironments(reports []artifacts.Report, environments []string) []EnvironmentDrift {
+	applied := AppliedStatements(reports)
+
+	drifts := make([]EnvironmentDrift, 0, len(environments))
+	for _, environment := range environments {
+		missing := make(map[string]*MissingStatement)
+		for _, other := range environments {
+			if other == environment {
+				continue
+			}
+			for hash, statement := range applied[other] {
+				if _, ok := applied[environment][hash]; ok {
+					continue
+				}
+				if entry, ok := missing[hash]; ok {
+					entry.AppliedIn = append(entry.AppliedIn, other)
+					if statement.AppliedAt.Before(entry.AppliedAt) {
+						entry.AppliedAt = statement.AppliedAt
+					}
+					continue
+				}
+				missing[hash] = &MissingStatement{AppliedStatement: statement, AppliedIn: []string{other}}
+			}
+		}
+
+		drift := EnvironmentDrift{Environment: environment}
+		for _, entry := range missing {
+			drift.Missing = append(drift.Missing, *entry)
+		}
+		sort.Slice(drift.Missing, func(i, j int) bool {
+			if !drift.Missing[i].AppliedAt.Equal(drift.Missing[j].AppliedAt) {
+				return drift.Missing[i].AppliedAt.Before(drift.Missing[j].AppliedAt)
+			}
+			return drift.Missing[i].Hash < drift.Missing[j].Hash
+		})
+		drifts = append(drifts, drift)
+	}
+	return drifts
+}
+
+// CountMissing は全環境の適用漏れの件数を返す
+func CountMissing(drifts []EnvironmentDrift) int {
+	count := 0
+	for _, drift := range drifts {
+		count += len(drift.Missing)
+	}
+	return count
+}
+
+// FormatDrift は環境ごとの適用漏れを人が読む形にまとめる
+func FormatDrift(drifts []EnvironmentDrift) string {
+	var b strings.Builder
+	for _, drift := range drifts {
+		if len(drift.Missing) == 0 {
+			fmt.Fprintf(&b, "%s: up to date\n", drift.Environment)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %d statements missing\n", drift.Environment, len(drift.Missing))
+		for _, statement := range drift.Missing {
+			fmt.Fprintf(&b, "  %s %s\n", statement.Hash, statement.Query)
+			fmt.Fprintf(&b, "      applied in %s (first at %s)\n", strings.Join(statement.AppliedIn, ", "), statement.AppliedAt.Format(time.RFC3339))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
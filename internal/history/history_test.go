@@ -0,0 +1,133 @@
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/artifacts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func report(environment string, dryRun bool, at time.Time, success bool, queries ...string) artifacts.Report {
+	return artifacts.Report{
+		Command:     "run",
+		Environment: environment,
+		DryRun:      dryRun,
+		Tasks: []artifacts.TaskResult{{
+			Task:       "alter-table",
+			Queries:    queries,
+			FinishedAt: at,
+			Success:    success,
+		}},
+	}
+}
+
+func TestQueryHash(t *testing.T) {
+	assert.Equal(t, QueryHash("ALTER TABLE users ADD COLUMN age INT"), QueryHash("  ALTER TABLE users\n  ADD COLUMN age INT;"))
+	assert.NotEqual(t, QueryHash("ALTER TABLE users ADD COLUMN age INT"), QueryHash("ALTER TABLE users ADD COLUMN age BIGINT"))
+}
+
+func TestDiffEnvironments(t *testing.T) {
+	day1 := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+
+	reports := []artifacts.Report{
+		report("dev", false, day1, true, "ALTER TABLE users ADD COLUMN age INT"),
+		report("qa", false, day2, true, "ALTER TABLE users ADD COLUMN age INT"),
+		report("prod", false, day2, true, "ALTER TABLE users ADD COLUMN age INT;"),
+		// prod にだけ当てたホットフィックス
+		report("prod", false, day2, true, "ALTER TABLE orders ADD INDEX idx_status (status)"),
+		// dry run と失敗したタスクは適用済みとみなさない
+		report("dev", true, day2, true, "ALTER TABLE items DROP COLUMN legacy"),
+		report("qa", false, day2, false, "ALTER TABLE items DROP COLUMN legacy"),
+	}
+
+	drifts := DiffEnvironments(reports, []string{"dev", "qa", "prod"})
+	require.Len(t, drifts, 3)
+
+	for _, drift := range drifts[:2] {
+		require.Len(t, drift.Missing, 1, drift.Environment)
+		assert.Equal(t, "ALTER TABLE orders ADD INDEX idx_status (status)", drift.Missing[0].Query)
+		assert.Equal(t, []string{"prod"}, drift.Missing[0].AppliedIn)
+	}
+	assert.Empty(t, drifts[2].Missing)
+	assert.Equal(t, 2, CountMissing(drifts))
+	assert.Contains(t, FormatDrift(drifts), "prod: up to date")
+}
+
+func TestLoadReports(t *testing.T) {
+	dir := t.TempDir()
+	want := report("prod", false, time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC), true, "ALTER TABLE users ADD COLUMN age INT")
+	data, err := json.Marshal(want)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "20261001-100000-run"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "20261001-100000-run", "report.json"), data, 0o600))
+	// 実行中で report.json がまだないディレクトリ
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "20261002-100000-run", "tasks"), 0o750))
+
+	reports, err := LoadReports(dir)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, "prod", reports[0].Environment)
+	assert.Equal(t, want.Tasks[0].Queries, reports[0].Tasks[0].Queries)
+}
+
+func TestBatchHash(t *testing.T) {
+	batch := []string{"ALTER TABLE users ADD COLUMN age INT", "ALTER TABLE orders ADD INDEX idx_status (status)"}
+	assert.Equal(t, BatchHash(batch), BatchHash([]string{"ALTER TABLE users  ADD COLUMN age INT;", "ALTER TABLE orders ADD INDEX idx_status (status)"}))
+	assert.NotEqual(t, BatchHash(batch), BatchHash([]string{batch[1], batch[0]}))
+	assert.NotEqual(t, BatchHash(batch), BatchHash(batch[:1]))
+}
+
+func TestFindRecentRuns(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	run := func(environment, hash string, dryRun, success bool, startedAt time.Time) artifacts.Report {
+		return artifacts.Report{Command: "run", Environment: environment, BatchHash: hash, DryRun: dryRun, Success: success, StartedAt: startedAt}
+	}
+	reports := []artifacts.Report{
+		run("prod", "abc", false, true, now.Add(-30*time.Hour)),
+		run("prod", "abc", false, true, now.Add(-2*time.Hour)),
+		run("prod", "abc", false, true, now.Add(-10*time.Minute)),
+		run("prod", "abc", true, true, now.Add(-5*time.Minute)),
+		run("prod", "abc", false, false, now.Add(-4*time.Minute)),
+		run("qa", "abc", false, true, now.Add(-3*time.Minute)),
+		run("prod", "def", false, true, now.Add(-2*time.Minute)),
+		{Command: "swap", Environment: "prod", BatchHash: "abc", Success: true, StartedAt: now.Add(-time.Minute)},
+	}
+
+	runs := FindRecentRuns(reports, "prod", "abc", now.Add(-24*time.Hour))
+	require.Len(t, runs, 2)
+	assert.Equal(t, now.Add(-10*time.Minute), runs[0].StartedAt)
+	assert.Equal(t, now.Add(-2*time.Hour), runs[1].StartedAt)
+
+	assert.Empty(t, FindRecentRuns(reports, "staging", "abc", now.Add(-24*time.Hour)))
+}
+
+func TestLatestProgress(t *testing.T) {
+	baseDir := t.TempDir()
+	at := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	for i, percent := range []int{80, 30} {
+		recorder, err := artifacts.NewRecorder(baseDir, "run", at.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+		require.NoError(t, recorder.WriteProgress(artifacts.CopyProgress{
+			TableName: "users",
+			Percent:   percent,
+			UpdatedAt: at.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	progress, runDir, err := LatestProgress(baseDir, "users")
+	require.NoError(t, err)
+	require.NotNil(t, progress)
+	assert.Equal(t, 30, progress.Percent)
+	assert.Equal(t, filepath.Join(baseDir, "20261001-110000-run"), runDir)
+
+	progress, _, err = LatestProgress(baseDir, "orders")
+	require.NoError(t, err)
+	assert.Nil(t, progress)
+}
@@ -0,0 +1,92 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RunMarker は実行中の run を表す印。run の開始時に --artifacts-dir に書き、終了時に消す
+type RunMarker struct {
+	Environment string    `json:"environment"`
+	BatchHash   string    `json:"batch_hash"`
+	StartedAt   time.Time `json:"started_at"`
+	Host        string    `json:"host,omitempty"`
+	PID         int       `json:"pid"`
+}
+
+// MarkerPath は environment と batchHash の組に対する印のパスを返す
+func MarkerPath(baseDir, environment, batchHash string) string {
+	sum := sha256.Sum256([]byte(environment))
+	return filepath.Join(baseDir, fmt.Sprintf(".running-%s-%s.json", hex.EncodeToString(sum[:])[:12], batchHash))
+}
+
+// AcquireRunMarker は同じ環境で同じバッチの run が実行中でないことを確かめ、実行中の印を書く。
+// since 以降に書かれた印があれば、終わっていない run として書かずにその印を返す。
+// それより古い印は異常終了した run が残したものとみなし、置き換える。
+// takeOver が true なら、終わっていない run の印も置き換える
+func AcquireRunMarker(baseDir string, marker RunMarker, since time.Time, takeOver bool) (release func() error, existing *RunMarker, err error) {
+	if err := os.MkdirAll(baseDir, 0o750); err != nil {
+		return nil, nil, fmt.Errorf("failed to create artifacts directory %s: %w", baseDir, err)
+	}
+	path := MarkerPath(baseDir, marker.Environment, marker.BatchHash)
+	data, err := json.MarshalIndent(marker, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600) // #nosec G304
+		if errors.Is(err, os.ErrExist) {
+			current, readErr := readRunMarker(path)
+			if readErr != nil {
+				return nil, nil, readErr
+			}
+			if current.StartedAt.After(since) && !takeOver {
+				return nil, current, nil
+			}
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, nil, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+			existing = current
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		_, writeErr := file.Write(data)
+		if closeErr := file.Close(); writeErr == nil {
+			writeErr = closeErr
+		}
+		if writeErr != nil {
+			_ = os.Remove(path)
+			return nil, nil, fmt.Errorf("failed to write %s: %w", path, writeErr)
+		}
+		release = func() error {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+			return nil
+		}
+		// 置き換えた印は呼び出し側で警告できるよう返す
+		return release, existing, nil
+	}
+	return nil, nil, fmt.Errorf("another run created %s at the same time", path)
+}
+
+func readRunMarker(path string) (*RunMarker, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var marker RunMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &marker, nil
+}
@@ -0,0 +1,60 @@
+package history
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireRunMarker(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	since := now.Add(-time.Hour)
+	marker := RunMarker{Environment: "prod", BatchHash: "abc123", StartedAt: now, PID: 1}
+
+	release, existing, err := AcquireRunMarker(dir, marker, since, false)
+	require.NoError(t, err)
+	require.NotNil(t, release)
+	assert.Nil(t, existing)
+
+	// 終わっていない同じバッチの run があれば拒否する
+	second, existing, err := AcquireRunMarker(dir, RunMarker{Environment: "prod", BatchHash: "abc123", StartedAt: now, PID: 2}, since, false)
+	require.NoError(t, err)
+	assert.Nil(t, second)
+	require.NotNil(t, existing)
+	assert.Equal(t, 1, existing.PID)
+
+	// 環境が違えば別の印になる
+	other, _, err := AcquireRunMarker(dir, RunMarker{Environment: "stg", BatchHash: "abc123", StartedAt: now}, since, false)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+	require.NoError(t, other())
+
+	require.NoError(t, release())
+	_, err = os.Stat(MarkerPath(dir, "prod", "abc123"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestAcquireRunMarkerReplacesStaleMarker(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	_, _, err := AcquireRunMarker(dir, RunMarker{Environment: "prod", BatchHash: "abc123", StartedAt: now.Add(-2 * time.Hour), PID: 1}, now.Add(-3*time.Hour), false)
+	require.NoError(t, err)
+
+	// window より古い印は異常終了した run のものとして置き換える
+	release, existing, err := AcquireRunMarker(dir, RunMarker{Environment: "prod", BatchHash: "abc123", StartedAt: now, PID: 2}, now.Add(-time.Hour), false)
+	require.NoError(t, err)
+	require.NotNil(t, release)
+	require.NotNil(t, existing)
+	assert.Equal(t, 1, existing.PID)
+
+	// --force では終わっていない run の印も置き換える
+	release, existing, err = AcquireRunMarker(dir, RunMarker{Environment: "prod", BatchHash: "abc123", StartedAt: now, PID: 3}, now.Add(-time.Hour), true)
+	require.NoError(t, err)
+	require.NotNil(t, release)
+	assert.Equal(t, 2, existing.PID)
+}
@@ -0,0 +1,79 @@
+package mattermost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/slack"
+)
+
+const defaultUsername = "alterguard"
+
+// 重要度ごとのアタッチメントの色
+var severityColors = map[string]string{
+	slack.SeverityInfo:    "#2eb886",
+	slack.SeverityWarning: "#daa038",
+	slack.SeverityError:   "#a30200",
+}
+
+// Notifier は Mattermost の Incoming Webhook に通知を送る slack.Sink
+type Notifier struct {
+	url      string
+	username string
+	http     *http.Client
+}
+
+var _ slack.Sink = (*Notifier)(nil)
+
+type attachment struct {
+	Color    string `json:"color"`
+	Text     string `json:"text"`
+	Fallback string `json:"fallback"`
+}
+
+type payload struct {
+	Username    string       `json:"username"`
+	IconEmoji   string       `json:"icon_emoji"`
+	Attachments []attachment `json:"attachments"`
+}
+
+// NewNotifier は Webhook URL に投稿する Notifier を返す。環境名があれば投稿者名の前に付ける。
+func NewNotifier(url, username, environment string) *Notifier {
+	if username == "" {
+		username = defaultUsername
+	}
+	if environment != "" {
+		username = fmt.Sprintf("[%s] %s", environment, username)
+	}
+	return &Notifier{url: url, username: username, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send は通知をアタッチメントにして投稿する。色は重要度で決まる。
+func (n *Notifier) Send(severity, text string) error {
+	title, _, _ := strings.Cut(text, "\n")
+	body, err := json.Marshal(payload{
+		Username:    n.username,
+		IconEmoji:   ":gear:",
+		Attachments: []attachment{{Color: severityColors[severity], Text: text, Fallback: title}},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.http.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send Mattermost notification: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mattermost webhook returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
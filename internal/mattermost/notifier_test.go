@@ -0,0 +1,34 @@
+package mattermost
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend(t *testing.T) {
+	var received payload
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, "", "production")
+	require.NoError(t, notifier.Send(slack.SeverityError, "❌ Schema change failed\nTable: users"))
+
+	assert.Equal(t, "[production] alterguard", received.Username)
+	require.Len(t, received.Attachments, 1)
+	assert.Equal(t, "#a30200", received.Attachments[0].Color)
+	assert.Equal(t, "❌ Schema change failed\nTable: users", received.Attachments[0].Text)
+	assert.Equal(t, "❌ Schema change failed", received.Attachments[0].Fallback)
+
+	status = http.StatusBadRequest
+	assert.ErrorContains(t, notifier.Send(slack.SeverityInfo, "hello"), "400")
+}
@@ -0,0 +1,148 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// API は operator が使う Kubernetes API の操作
+type API interface {
+	List(ctx context.Context) ([]SchemaChange, error)
+	ListApprovals(ctx context.Context) ([]SchemaChangeApproval, error)
+	UpdateStatus(ctx context.Context, name string, status SchemaChangeStatus) error
+}
+
+// Client は Pod のサービスアカウントで Kubernetes API を呼び出す
+type Client struct {
+	baseURL   string
+	namespace string
+	tokenPath string
+	http      *http.Client
+}
+
+// InClusterNamespace は Pod が動いている namespace を返す
+func InClusterNamespace() (string, error) {
+	data, err := os.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil {
+		return "", fmt.Errorf("failed to read namespace: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// NewInClusterClient は KUBERNETES_SERVICE_HOST とサービスアカウントのトークン・CA証明書を使うクライアントを作る
+func NewInClusterClient(namespace string) (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set (not running in a cluster?)")
+	}
+
+	caData, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("failed to parse cluster CA")
+	}
+
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+	return newClient("https://"+net.JoinHostPort(host, port), namespace, serviceAccountDir+"/token", httpClient), nil
+}
+
+func newClient(baseURL, namespace, tokenPath string, httpClient *http.Client) *Client {
+	return &Client{baseURL: baseURL, namespace: namespace, tokenPath: tokenPath, http: httpClient}
+}
+
+func (c *Client) resourceURL(resource, name string) string {
+	u := fmt.Sprintf("%s/apis/%s/%s/namespaces/%s/%s",
+		c.baseURL, Group, Version, url.PathEscape(c.namespace), resource)
+	if name != "" {
+		u += "/" + url.PathEscape(name)
+	}
+	return u
+}
+
+func (c *Client) List(ctx context.Context) ([]SchemaChange, error) {
+	body, err := c.do(ctx, http.MethodGet, c.resourceURL(Resource, ""), "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemachanges: %w", err)
+	}
+	var list schemaChangeList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode schemachanges: %w", err)
+	}
+	return list.Items, nil
+}
+
+func (c *Client) ListApprovals(ctx context.Context) ([]SchemaChangeApproval, error) {
+	body, err := c.do(ctx, http.MethodGet, c.resourceURL(ApprovalResource, ""), "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemachangeapprovals: %w", err)
+	}
+	var list schemaChangeApprovalList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode schemachangeapprovals: %w", err)
+	}
+	return list.Items, nil
+}
+
+func (c *Client) UpdateStatus(ctx context.Context, name string, status SchemaChangeStatus) error {
+	patch, err := json.Marshal(map[string]any{"status": status})
+	if err != nil {
+		return err
+	}
+	if _, err := c.do(ctx, http.MethodPatch, c.resourceURL(Resource, name)+"/status", "application/merge-patch+json", patch); err != nil {
+		return fmt.Errorf("failed to update status of schemachange %s: %w", name, err)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, u, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	// projected service account token はローテーションされるため毎回読み直す
+	token, err := os.ReadFile(c.tokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, u, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
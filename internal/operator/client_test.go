@@ -0,0 +1,59 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("secret-token\n"), 0o600))
+
+	var patchBody map[string]SchemaChangeStatus
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret-token", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/alterguard.pyama86.github.io/v1alpha1/namespaces/db/schemachanges":
+			_, _ = io.WriteString(w, `{"items":[{"metadata":{"name":"add-age","uid":"6f1c2a4e-uid","generation":2},"spec":{"queries":["ALTER TABLE users ADD COLUMN age INT"]}}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/alterguard.pyama86.github.io/v1alpha1/namespaces/db/schemachangeapprovals":
+			_, _ = io.WriteString(w, `{"items":[{"metadata":{"name":"add-age-by-dba"},"spec":{"schemaChange":"add-age","uid":"6f1c2a4e-uid","generation":2,"queriesSHA256":"abc123","approvedBy":"dba"}}]}`)
+		case r.Method == http.MethodPatch && r.URL.Path == "/apis/alterguard.pyama86.github.io/v1alpha1/namespaces/db/schemachanges/add-age/status":
+			assert.Equal(t, "application/merge-patch+json", r.Header.Get("Content-Type"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&patchBody))
+			_, _ = io.WriteString(w, `{}`)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newClient(server.URL, "db", tokenPath, server.Client())
+
+	changes, err := client.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "add-age", changes[0].Metadata.Name)
+	assert.Equal(t, "6f1c2a4e-uid", changes[0].Metadata.UID)
+	assert.Equal(t, int64(2), changes[0].Metadata.Generation)
+
+	approvals, err := client.ListApprovals(context.Background())
+	require.NoError(t, err)
+	require.Len(t, approvals, 1)
+	assert.Equal(t, SchemaChangeApprovalSpec{SchemaChange: "add-age", UID: "6f1c2a4e-uid", Generation: 2, QueriesSHA256: "abc123", ApprovedBy: "dba"}, approvals[0].Spec)
+
+	require.NoError(t, client.UpdateStatus(context.Background(), "add-age", SchemaChangeStatus{Phase: PhaseRunning, TotalTables: 1}))
+	assert.Equal(t, PhaseRunning, patchBody["status"].Phase)
+	assert.Equal(t, 1, patchBody["status"].TotalTables)
+
+	err = client.UpdateStatus(context.Background(), "missing", SchemaChangeStatus{})
+	assert.ErrorContains(t, err, "404")
+}
@@ -0,0 +1,209 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/sirupsen/logrus"
+)
+
+// RunFunc は承認された SchemaChange の queries を実行する。進捗は progress で通知する。
+type RunFunc func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error
+
+// Reconciler は SchemaChange を1件ずつ alterguard の実行に変換し、結果を status に書き戻す
+type Reconciler struct {
+	api         API
+	environment string
+	run         RunFunc
+	logger      *logrus.Logger
+
+	now func() time.Time
+}
+
+func NewReconciler(api API, environment string, run RunFunc, logger *logrus.Logger) *Reconciler {
+	return &Reconciler{
+		api:         api,
+		environment: environment,
+		run:         run,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Run は ctx がキャンセルされるまで interval ごとに ReconcileOnce を繰り返す
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.ReconcileOnce(ctx); err != nil {
+			r.logger.Errorf("Reconcile failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReconcileOnce は SchemaChange を名前順に処理する。
+// スキーマ変更は同時に実行しないため、承認済みのものは1件ずつ完了まで実行する。
+func (r *Reconciler) ReconcileOnce(ctx context.Context) error {
+	changes, err := r.api.List(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Metadata.Name < changes[j].Metadata.Name })
+
+	approvalList, err := r.api.ListApprovals(ctx)
+	if err != nil {
+		return err
+	}
+	approvals := make(map[string][]SchemaChangeApproval)
+	for _, approval := range approvalList {
+		approvals[approval.Spec.SchemaChange] = append(approvals[approval.Spec.SchemaChange], approval)
+	}
+
+	for _, change := range changes {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := r.reconcile(ctx, change, approvals[change.Metadata.Name]); err != nil {
+			r.logger.Errorf("Failed to reconcile schemachange %s: %v", change.Metadata.Name, err)
+		}
+	}
+	return nil
+}
+
+// findApproval は SchemaChange の現在の世代に対する承認を探す。
+// 承認は uid・世代・クエリのハッシュがすべて一致するものだけを有効とし、
+// 見つからなければ、待っている理由を status.message 用に返す
+func findApproval(change SchemaChange, approvals []SchemaChangeApproval) (*SchemaChangeApproval, string) {
+	generation := change.Metadata.Generation
+	digest := QueriesSHA256(change.Spec.Queries)
+	var stale []string
+	for i, approval := range approvals {
+		switch {
+		case approval.Spec.UID != change.Metadata.UID:
+			stale = append(stale, fmt.Sprintf("%s (uid %s, another SchemaChange with the same name)", approval.Metadata.Name, approval.Spec.UID))
+		case approval.Spec.Generation != generation:
+			stale = append(stale, fmt.Sprintf("%s (generation %d)", approval.Metadata.Name, approval.Spec.Generation))
+		case approval.Spec.QueriesSHA256 != digest:
+			stale = append(stale, fmt.Sprintf("%s (queriesSHA256 %s)", approval.Metadata.Name, approval.Spec.QueriesSHA256))
+		default:
+			return &approvals[i], ""
+		}
+	}
+	message := fmt.Sprintf("create a SchemaChangeApproval with spec.schemaChange %s, spec.uid %s, spec.generation %d and spec.queriesSHA256 %s to run this schema change",
+		change.Metadata.Name, change.Metadata.UID, generation, digest)
+	if len(stale) > 0 {
+		message = fmt.Sprintf("the approval does not match this schema change: %s; %s", strings.Join(stale, ", "), message)
+	}
+	return nil, message
+}
+
+// QueriesSHA256 は承認の spec.queriesSHA256 に書く、spec.queries のハッシュ。
+// 各クエリの末尾に改行を付けて連結した文字列の SHA-256 を16進数で返す
+func QueriesSHA256(queries []string) string {
+	hash := sha256.New()
+	for _, query := range queries {
+		hash.Write([]byte(query + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, change SchemaChange, approvals []SchemaChangeApproval) error {
+	name := change.Metadata.Name
+	status := change.Status
+
+	if change.Spec.Environment != r.environment {
+		return nil
+	}
+
+	switch status.Phase {
+	case PhaseSucceeded, PhaseFailed:
+		// 完了したものは spec が変更されても再実行しない。やり直す場合は新しいリソースを作る
+		return nil
+	case PhaseRunning:
+		// 実行は同期的に行うため、一覧の時点で Running のものは前の operator が途中で止まったもの
+		status.Phase = PhaseFailed
+		status.Message = "operator stopped while the schema change was running; inspect the table and create a new SchemaChange to retry"
+		status.FinishedAt = r.timestamp()
+		r.logger.Warnf("Schemachange %s was left running by a previous operator; marking it as failed", name)
+		return r.api.UpdateStatus(ctx, name, status)
+	}
+
+	// 承認は SchemaChange 自身ではなく、現在の世代を指す SchemaChangeApproval で行う
+	approval, waiting := findApproval(change, approvals)
+	if approval == nil {
+		if status.Phase == PhaseAwaitingApproval && status.ObservedGeneration == change.Metadata.Generation && status.Message == waiting {
+			return nil
+		}
+		status.Phase = PhaseAwaitingApproval
+		status.Message = waiting
+		status.ObservedGeneration = change.Metadata.Generation
+		r.logger.Infof("Schemachange %s is awaiting approval", name)
+		return r.api.UpdateStatus(ctx, name, status)
+	}
+
+	status.ObservedGeneration = change.Metadata.Generation
+	status.Approval = approval.Metadata.Name
+	if len(change.Spec.Queries) == 0 {
+		status.Phase = PhaseFailed
+		status.Message = "spec.queries is empty"
+		status.FinishedAt = r.timestamp()
+		return r.api.UpdateStatus(ctx, name, status)
+	}
+
+	status.Phase = PhaseRunning
+	status.Message = ""
+	status.StartedAt = r.timestamp()
+	if err := r.api.UpdateStatus(ctx, name, status); err != nil {
+		// Running を記録できないまま実行すると、再起動後に二重実行されるおそれがあるため実行しない
+		return err
+	}
+
+	r.logger.Infof("Running schemachange %s (%d queries), approved by %s", name, len(change.Spec.Queries), DescribeApproval(*approval))
+	runErr := r.run(ctx, change, *approval, func(progress task.RunProgress) {
+		status.CompletedTables = progress.CompletedTables
+		status.TotalTables = progress.TotalTables
+		status.RowCounts = progress.RowCounts
+		status.Methods = progress.Methods
+		if err := r.api.UpdateStatus(ctx, name, status); err != nil {
+			r.logger.Warnf("Failed to update progress of schemachange %s: %v", name, err)
+		}
+	})
+
+	status.FinishedAt = r.timestamp()
+	if runErr != nil {
+		status.Phase = PhaseFailed
+		status.Message = runErr.Error()
+	} else {
+		status.Phase = PhaseSucceeded
+		status.Message = fmt.Sprintf("completed %d tables", status.CompletedTables)
+	}
+	// 実行中に ctx がキャンセルされても結果は書き戻す
+	if err := r.api.UpdateStatus(context.WithoutCancel(ctx), name, status); err != nil {
+		return err
+	}
+	return runErr
+}
+
+// DescribeApproval は承認のリソース名と、書かれていれば承認者を返す
+func DescribeApproval(approval SchemaChangeApproval) string {
+	if approval.Spec.ApprovedBy == "" {
+		return "SchemaChangeApproval " + approval.Metadata.Name
+	}
+	return fmt.Sprintf("SchemaChangeApproval %s (%s)", approval.Metadata.Name, approval.Spec.ApprovedBy)
+}
+
+func (r *Reconciler) timestamp() string {
+	return r.now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,266 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAPI struct {
+	changes   []SchemaChange
+	approvals []SchemaChangeApproval
+	updates   []statusUpdate
+}
+
+type statusUpdate struct {
+	name   string
+	status SchemaChangeStatus
+}
+
+func (f *fakeAPI) List(ctx context.Context) ([]SchemaChange, error) {
+	return f.changes, nil
+}
+
+func (f *fakeAPI) ListApprovals(ctx context.Context) ([]SchemaChangeApproval, error) {
+	return f.approvals, nil
+}
+
+func (f *fakeAPI) UpdateStatus(ctx context.Context, name string, status SchemaChangeStatus) error {
+	f.updates = append(f.updates, statusUpdate{name: name, status: status})
+	return nil
+}
+
+func (f *fakeAPI) phases(name string) []string {
+	var phases []string
+	for _, update := range f.updates {
+		if update.name == name {
+			phases = append(phases, update.status.Phase)
+		}
+	}
+	return phases
+}
+
+func (f *fakeAPI) last(name string) SchemaChangeStatus {
+	var status SchemaChangeStatus
+	for _, update := range f.updates {
+		if update.name == name {
+			status = update.status
+		}
+	}
+	return status
+}
+
+func newTestReconciler(api API, run RunFunc) *Reconciler {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	r := NewReconciler(api, "production", run, logger)
+	r.now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return r
+}
+
+func schemaChange(name string) SchemaChange {
+	return SchemaChange{
+		Metadata: ObjectMeta{Name: name, UID: name + "-uid-1", Generation: 1},
+		Spec: SchemaChangeSpec{
+			Queries:     []string{"ALTER TABLE users ADD COLUMN age INT"},
+			Environment: "production",
+		},
+	}
+}
+
+// approval は change の現在の内容を承認する
+func approval(change SchemaChange) SchemaChangeApproval {
+	return SchemaChangeApproval{
+		Metadata: ObjectMeta{Name: change.Metadata.Name + "-approval"},
+		Spec: SchemaChangeApprovalSpec{
+			SchemaChange:  change.Metadata.Name,
+			UID:           change.Metadata.UID,
+			Generation:    change.Metadata.Generation,
+			QueriesSHA256: QueriesSHA256(change.Spec.Queries),
+			ApprovedBy:    "dba",
+		},
+	}
+}
+
+func TestReconcileOnceRunsApprovedChange(t *testing.T) {
+	api := &fakeAPI{changes: []SchemaChange{schemaChange("add-age")}, approvals: []SchemaChangeApproval{approval(schemaChange("add-age"))}}
+	var queries []string
+	r := newTestReconciler(api, func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error {
+		queries = change.Spec.Queries
+		progress(task.RunProgress{TotalTables: 1, RowCounts: map[string]int64{"users": 1000}})
+		progress(task.RunProgress{CompletedTables: 1, TotalTables: 1, RowCounts: map[string]int64{"users": 1000}, Methods: map[string]string{"users": "pt-osc"}})
+		return nil
+	})
+
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+
+	assert.Equal(t, []string{"ALTER TABLE users ADD COLUMN age INT"}, queries)
+	assert.Equal(t, []string{PhaseRunning, PhaseRunning, PhaseRunning, PhaseSucceeded}, api.phases("add-age"))
+	status := api.last("add-age")
+	assert.Equal(t, 1, status.CompletedTables)
+	assert.Equal(t, int64(1000), status.RowCounts["users"])
+	assert.Equal(t, "pt-osc", status.Methods["users"])
+	assert.Equal(t, int64(1), status.ObservedGeneration)
+	assert.Equal(t, "2025-01-02T03:04:05Z", status.StartedAt)
+	assert.Equal(t, "2025-01-02T03:04:05Z", status.FinishedAt)
+	assert.Equal(t, "add-age-approval", status.Approval)
+}
+
+func TestReconcileOnceRecordsFailure(t *testing.T) {
+	api := &fakeAPI{changes: []SchemaChange{schemaChange("add-age")}, approvals: []SchemaChangeApproval{approval(schemaChange("add-age"))}}
+	r := newTestReconciler(api, func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error {
+		return errors.New("pt-osc failed")
+	})
+
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+
+	status := api.last("add-age")
+	assert.Equal(t, PhaseFailed, status.Phase)
+	assert.Equal(t, "pt-osc failed", status.Message)
+}
+
+func TestReconcileOnceWaitsForApproval(t *testing.T) {
+	api := &fakeAPI{changes: []SchemaChange{schemaChange("add-age")}, approvals: []SchemaChangeApproval{approval(schemaChange("other"))}}
+	r := newTestReconciler(api, func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error {
+		t.Fatal("unapproved schema change must not run")
+		return nil
+	})
+
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+	assert.Equal(t, []string{PhaseAwaitingApproval}, api.phases("add-age"))
+	assert.Contains(t, api.last("add-age").Message, "SchemaChangeApproval")
+
+	// 既に AwaitingApproval を記録済みなら更新しない
+	api.changes[0].Status = api.last("add-age")
+	api.updates = nil
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+	assert.Empty(t, api.updates)
+}
+
+func TestReconcileOnceSkips(t *testing.T) {
+	otherEnvironment := schemaChange("other-env")
+	otherEnvironment.Spec.Environment = "staging"
+	succeeded := schemaChange("succeeded")
+	succeeded.Status.Phase = PhaseSucceeded
+	failed := schemaChange("failed")
+	failed.Status.Phase = PhaseFailed
+
+	api := &fakeAPI{
+		changes:   []SchemaChange{otherEnvironment, succeeded, failed},
+		approvals: []SchemaChangeApproval{approval(schemaChange("other-env")), approval(schemaChange("succeeded")), approval(schemaChange("failed"))},
+	}
+	r := newTestReconciler(api, func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error {
+		t.Fatalf("schemachange %s must not run", change.Metadata.Name)
+		return nil
+	})
+
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+	assert.Empty(t, api.updates)
+}
+
+func TestReconcileOnceFailsInterruptedRun(t *testing.T) {
+	interrupted := schemaChange("interrupted")
+	interrupted.Status.Phase = PhaseRunning
+
+	api := &fakeAPI{changes: []SchemaChange{interrupted}, approvals: []SchemaChangeApproval{approval(schemaChange("interrupted"))}}
+	r := newTestReconciler(api, func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error {
+		t.Fatal("interrupted schema change must not be re-run")
+		return nil
+	})
+
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+	assert.Equal(t, []string{PhaseFailed}, api.phases("interrupted"))
+	assert.Contains(t, api.last("interrupted").Message, "operator stopped")
+}
+
+func TestReconcileOnceRejectsEmptyQueries(t *testing.T) {
+	empty := schemaChange("empty")
+	empty.Spec.Queries = nil
+
+	api := &fakeAPI{changes: []SchemaChange{empty}, approvals: []SchemaChangeApproval{approval(empty)}}
+	r := newTestReconciler(api, func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error {
+		t.Fatal("empty schema change must not run")
+		return nil
+	})
+
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+	assert.Equal(t, PhaseFailed, api.last("empty").Phase)
+}
+
+func TestReconcileOnceIgnoresApprovalOfEarlierGeneration(t *testing.T) {
+	// 承認の後に spec を書き換えると generation が上がり、承認は無効になる
+	edited := schemaChange("add-age")
+	edited.Metadata.Generation = 2
+	api := &fakeAPI{changes: []SchemaChange{edited}, approvals: []SchemaChangeApproval{approval(schemaChange("add-age"))}}
+	r := newTestReconciler(api, func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error {
+		t.Fatal("schema change edited after approval must not run")
+		return nil
+	})
+
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+	status := api.last("add-age")
+	assert.Equal(t, PhaseAwaitingApproval, status.Phase)
+	assert.Contains(t, status.Message, "add-age-approval (generation 1)")
+	assert.Contains(t, status.Message, "spec.generation 2")
+
+	api.approvals = append(api.approvals, approval(edited))
+	api.approvals[1].Metadata.Name = "add-age-approval-2"
+	var approvedBy string
+	r.run = func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error {
+		approvedBy = approval.Metadata.Name
+		return nil
+	}
+	api.changes[0].Status = status
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+	assert.Equal(t, "add-age-approval-2", approvedBy)
+	assert.Equal(t, PhaseSucceeded, api.last("add-age").Phase)
+}
+
+func TestReconcileOnceIgnoresApprovalOfRecreatedChange(t *testing.T) {
+	// 削除して同じ名前で作り直すと generation は1に戻るが、uid が変わるので前の承認は使えない
+	recreated := schemaChange("add-age")
+	recreated.Metadata.UID = "add-age-uid-2"
+	recreated.Spec.Queries = []string{"ALTER TABLE users DROP COLUMN email"}
+	api := &fakeAPI{changes: []SchemaChange{recreated}, approvals: []SchemaChangeApproval{approval(schemaChange("add-age"))}}
+	r := newTestReconciler(api, func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error {
+		t.Fatal("recreated schema change must not run with the earlier approval")
+		return nil
+	})
+
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+	status := api.last("add-age")
+	assert.Equal(t, PhaseAwaitingApproval, status.Phase)
+	assert.Contains(t, status.Message, "another SchemaChange with the same name")
+	assert.Contains(t, status.Message, "spec.uid add-age-uid-2")
+	assert.Contains(t, status.Message, QueriesSHA256(recreated.Spec.Queries))
+}
+
+func TestReconcileOnceIgnoresApprovalOfOtherQueries(t *testing.T) {
+	change := schemaChange("add-age")
+	approved := approval(change)
+	approved.Spec.QueriesSHA256 = QueriesSHA256([]string{"ALTER TABLE users ADD COLUMN age BIGINT"})
+	api := &fakeAPI{changes: []SchemaChange{change}, approvals: []SchemaChangeApproval{approved}}
+	r := newTestReconciler(api, func(ctx context.Context, change SchemaChange, approval SchemaChangeApproval, progress func(task.RunProgress)) error {
+		t.Fatal("schema change must not run when the approved queries differ")
+		return nil
+	})
+
+	require.NoError(t, r.ReconcileOnce(context.Background()))
+	assert.Equal(t, PhaseAwaitingApproval, api.last("add-age").Phase)
+	assert.Contains(t, api.last("add-age").Message, "add-age-approval (queriesSHA256 ")
+}
+
+func TestQueriesSHA256(t *testing.T) {
+	// echo 'ALTER TABLE users ADD COLUMN age INT' | sha256sum と同じ値
+	sum := sha256.Sum256([]byte("ALTER TABLE users ADD COLUMN age INT\n"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), QueriesSHA256([]string{"ALTER TABLE users ADD COLUMN age INT"}))
+	assert.NotEqual(t, QueriesSHA256([]string{"a", "b"}), QueriesSHA256([]string{"ab"}))
+}
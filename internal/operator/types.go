@@ -0,0 +1,82 @@
+package operator
+
+const (
+	Group    = "alterguard.pyama86.github.io"
+	Version  = "v1alpha1"
+	Resource = "schemachanges"
+	// SchemaChange の承認のリソース
+	ApprovalResource = "schemachangeapprovals"
+)
+
+// SchemaChange のフェーズ
+const (
+	PhaseAwaitingApproval = "AwaitingApproval"
+	PhaseRunning          = "Running"
+	PhaseSucceeded        = "Succeeded"
+	PhaseFailed           = "Failed"
+)
+
+// SchemaChange は alterguard run 1回分に相当するカスタムリソース
+type SchemaChange struct {
+	Metadata ObjectMeta         `json:"metadata"`
+	Spec     SchemaChangeSpec   `json:"spec"`
+	Status   SchemaChangeStatus `json:"status,omitempty"`
+}
+
+type ObjectMeta struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	UID        string `json:"uid,omitempty"`
+	Generation int64  `json:"generation,omitempty"`
+}
+
+type SchemaChangeSpec struct {
+	Queries []string `json:"queries"`
+	// 一覧表示用。実行対象は queries で決まる
+	Table string `json:"table,omitempty"`
+	// operator の --environment と一致するものだけを処理する
+	Environment string `json:"environment,omitempty"`
+	DryRun      bool   `json:"dryRun,omitempty"`
+}
+
+// SchemaChangeApproval は SchemaChange の承認。SchemaChange を書ける人が自分で承認できないよう、
+// 承認は SchemaChange とは別のリソースにして、作成できる人を RBAC で分ける
+type SchemaChangeApproval struct {
+	Metadata ObjectMeta               `json:"metadata"`
+	Spec     SchemaChangeApprovalSpec `json:"spec"`
+}
+
+type SchemaChangeApprovalSpec struct {
+	// 承認する SchemaChange の名前
+	SchemaChange string `json:"schemaChange"`
+	// 承認した SchemaChange の metadata.uid。同じ名前で作り直したものには承認が引き継がれない
+	UID string `json:"uid"`
+	// 承認した SchemaChange の metadata.generation。承認後に spec が変わると承認は無効になる
+	Generation int64 `json:"generation"`
+	// 承認した spec.queries の QueriesSHA256
+	QueriesSHA256 string `json:"queriesSHA256"`
+	// 記録用の承認者
+	ApprovedBy string `json:"approvedBy,omitempty"`
+}
+
+type SchemaChangeStatus struct {
+	Phase              string            `json:"phase,omitempty"`
+	Message            string            `json:"message,omitempty"`
+	ObservedGeneration int64             `json:"observedGeneration,omitempty"`
+	CompletedTables    int               `json:"completedTables"`
+	TotalTables        int               `json:"totalTables"`
+	RowCounts          map[string]int64  `json:"rowCounts,omitempty"`
+	Methods            map[string]string `json:"methods,omitempty"`
+	StartedAt          string            `json:"startedAt,omitempty"`
+	FinishedAt         string            `json:"finishedAt,omitempty"`
+	// 実行を許可した SchemaChangeApproval の名前
+	Approval string `json:"approval,omitempty"`
+}
+
+type schemaChangeList struct {
+	Items []SchemaChange `json:"items"`
+}
+
+type schemaChangeApprovalList struct {
+	Items []SchemaChangeApproval `json:"items"`
+}
@@ -0,0 +1,158 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHeadLines はサマリに残す先頭の行数
+	DefaultHeadLines = 20
+	// DefaultTailLines はサマリに残す末尾の行数
+	DefaultTailLines = 50
+	// KeepTempFiles は同じパターンの一時ファイルを残す数。古いものから削除する
+	KeepTempFiles = 10
+)
+
+// Buffer は外部コマンドの出力を先頭N行と末尾N行だけメモリに保持する。
+// 長時間のpt-oscでも使用メモリとSlackに送るサイズが一定になるよう、全量は一時ファイルに書き出す。
+type Buffer struct {
+	mu        sync.Mutex
+	headLimit int
+	tailLimit int
+	head      []string
+	tail      []string
+	tailStart int
+	total     int
+	file      *os.File
+	filePath  string
+}
+
+func NewBuffer(headLimit, tailLimit int) *Buffer {
+	return &Buffer{
+		headLimit: headLimit,
+		tailLimit: tailLimit,
+	}
+}
+
+// SpillToTempFile は全出力の書き出し先となる一時ファイルを作成する。
+// ファイルは失敗の調査や実行結果へのコピーのために実行後も残すが、溜まり続けないよう、
+// 同じパターンのファイルは新しいものから KeepTempFiles 個だけを残して削除する
+func (b *Buffer) SpillToTempFile(pattern string) error {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	pruneTempFiles(filepath.Join(os.TempDir(), pattern), file.Name(), KeepTempFiles)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.file = file
+	b.filePath = file.Name()
+	return nil
+}
+
+func (b *Buffer) AddLine(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.total++
+	if b.file != nil {
+		// 書き込みに失敗してもサマリは残るため、ファイルへの出力をやめるだけにする
+		if _, err := b.file.WriteString(line + "\n"); err != nil {
+			_ = b.file.Close()
+			b.file = nil
+		}
+	}
+
+	if len(b.head) < b.headLimit {
+		b.head = append(b.head, line)
+		return
+	}
+	if b.tailLimit <= 0 {
+		return
+	}
+	if len(b.tail) < b.tailLimit {
+		b.tail = append(b.tail, line)
+		return
+	}
+	b.tail[b.tailStart] = line
+	b.tailStart = (b.tailStart + 1) % b.tailLimit
+}
+
+// String は先頭と末尾の行を返す。省略した行がある場合はその件数と全出力のファイルパスを挟む。
+func (b *Buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := make([]string, 0, len(b.head)+len(b.tail)+1)
+	lines = append(lines, b.head...)
+
+	omitted := b.total - len(b.head) - len(b.tail)
+	if omitted > 0 {
+		marker := fmt.Sprintf("... %d lines omitted ...", omitted)
+		if b.filePath != "" {
+			marker = fmt.Sprintf("... %d lines omitted (full output: %s) ...", omitted, b.filePath)
+		}
+		lines = append(lines, marker)
+	}
+
+	lines = append(lines, b.tail[b.tailStart:]...)
+	lines = append(lines, b.tail[:b.tailStart]...)
+	return strings.Join(lines, "\n")
+}
+
+// FilePath は全出力を書き出した一時ファイルのパスを返す。書き出していない場合は空文字。
+func (b *Buffer) FilePath() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.filePath
+}
+
+// Close は一時ファイルを閉じる。ファイル自体は調査用に残す (次の SpillToTempFile で古いものから削除する)。
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.file == nil {
+		return nil
+	}
+	err := b.file.Close()
+	b.file = nil
+	return err
+}
+
+// pruneTempFiles は glob に一致するファイルのうち、current を含めて新しいものから keep 個を残して削除する。
+// 削除に失敗しても出力の記録は続けられるため、エラーは無視する
+func pruneTempFiles(glob, current string, keep int) {
+	paths, err := filepath.Glob(glob)
+	if err != nil || len(paths) <= keep {
+		return
+	}
+
+	type tempFile struct {
+		path    string
+		modTime time.Time
+	}
+	files := make([]tempFile, 0, len(paths))
+	for _, path := range paths {
+		if path == current {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, tempFile{path: path, modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+
+	// current の分を1つ引いた数だけ残す
+	for i := keep - 1; i < len(files); i++ {
+		_ = os.Remove(files[i].path)
+	}
+}
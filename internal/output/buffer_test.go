@@ -0,0 +1,101 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuffer(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines int
+		want  string
+	}{
+		{
+			name:  "empty",
+			lines: 0,
+			want:  "",
+		},
+		{
+			name:  "fits without omission",
+			lines: 5,
+			want:  "line1\nline2\nline3\nline4\nline5",
+		},
+		{
+			name:  "keeps head and tail",
+			lines: 10,
+			want:  "line1\nline2\n... 5 lines omitted ...\nline8\nline9\nline10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuffer(2, 3)
+			for i := 1; i <= tt.lines; i++ {
+				b.AddLine(fmt.Sprintf("line%d", i))
+			}
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
+
+func TestBufferSpillToTempFile(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	b := NewBuffer(1, 1)
+	require.NoError(t, b.SpillToTempFile("alterguard-test-*.log"))
+	for i := 1; i <= 4; i++ {
+		b.AddLine(fmt.Sprintf("line%d", i))
+	}
+	require.NoError(t, b.Close())
+
+	path := b.FilePath()
+	require.NotEmpty(t, path)
+	assert.Equal(t, fmt.Sprintf("line1\n... 2 lines omitted (full output: %s) ...\nline4", path), b.String())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "line1\nline2\nline3\nline4", strings.TrimSpace(string(content)))
+}
+
+func TestSpillToTempFilePrunesOldFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	base := time.Now().Add(-time.Hour)
+	var old []string
+	for i := 0; i < KeepTempFiles+2; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("alterguard-pt-osc-%d.log", i))
+		require.NoError(t, os.WriteFile(path, []byte("output\n"), 0o600))
+		require.NoError(t, os.Chtimes(path, base.Add(time.Duration(i)*time.Minute), base.Add(time.Duration(i)*time.Minute)))
+		old = append(old, path)
+	}
+	other := filepath.Join(dir, "alterguard-pt-archiver-0.log")
+	require.NoError(t, os.WriteFile(other, []byte("output\n"), 0o600))
+	require.NoError(t, os.Chtimes(other, base, base))
+
+	buf := NewBuffer(DefaultHeadLines, DefaultTailLines)
+	require.NoError(t, buf.SpillToTempFile("alterguard-pt-osc-*.log"))
+	require.NoError(t, buf.Close())
+
+	remaining, err := filepath.Glob(filepath.Join(dir, "alterguard-pt-osc-*.log"))
+	require.NoError(t, err)
+	assert.Len(t, remaining, KeepTempFiles)
+	assert.Contains(t, remaining, buf.FilePath())
+	// 古いものから削除され、新しいものは残る
+	for _, path := range old[:3] {
+		assert.NoFileExists(t, path)
+	}
+	for _, path := range old[3:] {
+		assert.FileExists(t, path)
+	}
+	// 別のパターンのファイルには触れない
+	assert.FileExists(t, other)
+}
@@ -2,25 +2,81 @@ package ptarchiver
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os/exec"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/dsn"
+	"github.com/pyama86/alterguard/internal/output"
 	"github.com/sirupsen/logrus"
 )
 
 type Executor interface {
-	ExecutePurge(tableName string, ptArchiverConfig config.PtArchiverConfig, dsn string, dryRun bool) error
+	ExecutePurge(ctx context.Context, tableName string, ptArchiverConfig config.PtArchiverConfig, dsn string, dryRun bool) error
+}
+
+// ctxがキャンセルされた時にSIGTERMを送ってから待つ猶予
+const processWaitDelay = 30 * time.Second
+
+func newCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...) // #nosec G204
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = processWaitDelay
+	return cmd
+}
+
+// outputLine は stdout か stderr から読んだ1行
+type outputLine struct {
+	text    string
+	isError bool
+}
+
+// readOutputs は stdout と stderr を並行して読み、届いた順に1行ずつ handle に渡す。
+// handle は呼び出し元の goroutine だけで呼ぶので、stdout と stderr の行が混ざっても出力の順序が保たれる。
+// 両方を読み切るまで戻らない
+func readOutputs(stdout, stderr io.Reader, logger *logrus.Logger, handle func(line string, isError bool)) {
+	lines := make(chan outputLine)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go scanOutput(stdout, false, lines, &wg, logger)
+	go scanOutput(stderr, true, lines, &wg, logger)
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+	for line := range lines {
+		handle(line.text, line.isError)
+	}
+}
+
+// scanOutput は r を1行ずつ lines に送る。読み取りエラー後も残りを読み捨て、パイプが詰まってプロセスが止まらないようにする
+func scanOutput(r io.Reader, isError bool, lines chan<- outputLine, wg *sync.WaitGroup, logger *logrus.Logger) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines <- outputLine{text: scanner.Text(), isError: isError}
+	}
+	if err := scanner.Err(); err != nil {
+		logger.Warnf("Failed to read pt-archiver output: %v", err)
+		_, _ = io.Copy(io.Discard, r)
+	}
 }
 
 type PtArchiverExecutor struct {
 	logger        *logrus.Logger
 	hasError      bool
 	errorMessages []string
-	outputSummary string
+	outputBuffer  *output.Buffer
+	deletedRows   int64
+	result        *PurgeResult
 	mutex         sync.Mutex
 }
 
@@ -30,12 +86,20 @@ func NewPtArchiverExecutor(logger *logrus.Logger) *PtArchiverExecutor {
 	}
 }
 
-func (e *PtArchiverExecutor) ExecutePurge(tableName string, ptArchiverConfig config.PtArchiverConfig, dsn string, dryRun bool) error {
+func (e *PtArchiverExecutor) ExecutePurge(ctx context.Context, tableName string, ptArchiverConfig config.PtArchiverConfig, dsn string, dryRun bool) error {
 	e.mutex.Lock()
 	e.hasError = false
 	e.errorMessages = []string{}
-	e.outputSummary = ""
+	e.deletedRows = 0
+	e.result = &PurgeResult{}
+	outputBuffer := e.newOutputBuffer()
+	e.outputBuffer = outputBuffer
 	e.mutex.Unlock()
+	defer func() {
+		if err := outputBuffer.Close(); err != nil {
+			e.logger.Warnf("Failed to close pt-archiver output file: %v", err)
+		}
+	}()
 
 	args, password, err := e.BuildArgsWithPassword(tableName, ptArchiverConfig, dsn, dryRun)
 	if err != nil {
@@ -51,7 +115,7 @@ func (e *PtArchiverExecutor) ExecutePurge(tableName string, ptArchiverConfig con
 	}
 	e.logger.Infof("Executing pt-archiver command: pt-archiver %s", strings.Join(maskedArgs, " "))
 
-	cmd := exec.Command("pt-archiver", args...) // #nosec G204
+	cmd := newCommand(ctx, "pt-archiver", args...)
 
 	if password != "" {
 		e.logger.Debugf("Using password for pt-archiver")
@@ -70,14 +134,26 @@ func (e *PtArchiverExecutor) ExecutePurge(tableName string, ptArchiverConfig con
 		return fmt.Errorf("failed to start command: %w", err)
 	}
 
-	go e.logOutputWithSummary(stdoutPipe, false)
-	go e.logOutputWithSummary(stderrPipe, true)
+	// 出力を読み切ってから Wait する。先に Wait すると末尾の出力が失われる
+	readOutputs(stdoutPipe, stderrPipe, e.logger, func(line string, isError bool) {
+		e.logOutputWithSummary(line, isError, outputBuffer)
+	})
 
 	cmdErr := cmd.Wait()
 
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("pt-archiver for table %s was terminated: %w", tableName, ctxErr)
+	}
+
+	// --retries を超えて終了した場合は、終了コードが 0 でも削除を諦めたので失敗とする
+	if cmdErr == nil && !e.hasError && e.result.QuitReason == QuitRetriesExceeded {
+		e.hasError = true
+		e.errorMessages = append(e.errorMessages, e.result.QuitMessage)
+	}
+
 	if cmdErr != nil || e.hasError {
 		var errorMsg string
 		if cmdErr != nil && e.hasError {
@@ -96,41 +172,83 @@ func (e *PtArchiverExecutor) ExecutePurge(tableName string, ptArchiverConfig con
 	return nil
 }
 
-func (e *PtArchiverExecutor) logOutputWithSummary(r io.Reader, isError bool) {
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
+// newOutputBuffer は出力の先頭と末尾だけを保持するバッファを作る。全量は一時ファイルに書き出す。
+func (e *PtArchiverExecutor) newOutputBuffer() *output.Buffer {
+	buf := output.NewBuffer(output.DefaultHeadLines, output.DefaultTailLines)
+	if err := buf.SpillToTempFile("alterguard-pt-archiver-*.log"); err != nil {
+		e.logger.Warnf("Full pt-archiver output will not be kept: %v", err)
+	}
+	return buf
+}
+
+func (e *PtArchiverExecutor) logOutputWithSummary(line string, isError bool, outputBuffer *output.Buffer) {
+	if isError {
+		outputBuffer.AddLine("[STDERR] " + line)
+	} else {
+		outputBuffer.AddLine("[STDOUT] " + line)
+	}
 
+	if e.containsErrorPattern(line) {
 		e.mutex.Lock()
-		if e.outputSummary != "" {
-			e.outputSummary += "\n"
-		}
-		if isError {
-			e.outputSummary += "[STDERR] " + line
-		} else {
-			e.outputSummary += "[STDOUT] " + line
-		}
+		e.hasError = true
+		e.errorMessages = append(e.errorMessages, line)
 		e.mutex.Unlock()
+	}
 
-		if e.containsErrorPattern(line) {
-			e.mutex.Lock()
-			e.hasError = true
-			e.errorMessages = append(e.errorMessages, line)
-			e.mutex.Unlock()
+	if !isError {
+		e.mutex.Lock()
+		if deleted, ok := parseDeletedRows(line); ok {
+			e.deletedRows = deleted
 		}
-
-		if isError {
-			e.logger.Errorf("[pt-archiver] %s", line)
-		} else {
-			e.logger.Infof("[pt-archiver] %s", line)
+		if e.result != nil {
+			e.result.parseLine(line)
 		}
+		e.mutex.Unlock()
+	}
+
+	if isError {
+		e.logger.Errorf("[pt-archiver] %s", line)
+	} else {
+		e.logger.Infof("[pt-archiver] %s", line)
 	}
 }
 
 func (e *PtArchiverExecutor) GetOutputSummary() string {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
-	return e.outputSummary
+	if e.outputBuffer == nil {
+		return ""
+	}
+	return e.outputBuffer.String()
+}
+
+// GetDeletedRows は実行中または直近の実行で削除した行数を返す。
+// --progress または --statistics の出力から読み取るため、どちらも指定していなければ0のまま。
+func (e *PtArchiverExecutor) GetDeletedRows() int64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.deletedRows
+}
+
+// GetPurgeResult は直近の実行の統計情報と終了理由を返す。実行していなければ nil
+func (e *PtArchiverExecutor) GetPurgeResult() *PurgeResult {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.result == nil {
+		return nil
+	}
+	result := *e.result
+	return &result
+}
+
+// GetOutputFilePath は直近の実行の全出力を書き出したファイルのパスを返す
+func (e *PtArchiverExecutor) GetOutputFilePath() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.outputBuffer == nil {
+		return ""
+	}
+	return e.outputBuffer.FilePath()
 }
 
 func (e *PtArchiverExecutor) containsErrorPattern(line string) bool {
@@ -203,11 +321,28 @@ func (e *PtArchiverExecutor) BuildArgsWithPassword(
 		args = append(args, fmt.Sprintf("--limit=%d", ptArchiverConfig.Limit))
 	}
 
+	if ptArchiverConfig.CommitEach && ptArchiverConfig.TxnSize > 0 {
+		return nil, "", fmt.Errorf("pt_archiver.commit_each and pt_archiver.txn_size cannot be used together")
+	}
+
 	if ptArchiverConfig.CommitEach {
 		args = append(args, "--commit-each")
 	}
 
-	args = append(args, "--purge")
+	if ptArchiverConfig.TxnSize > 0 {
+		args = append(args, fmt.Sprintf("--txn-size=%d", ptArchiverConfig.TxnSize))
+	}
+
+	if ptArchiverConfig.Sleep > 0 {
+		args = append(args, fmt.Sprintf("--sleep=%d", ptArchiverConfig.Sleep))
+	}
+
+	if ptArchiverConfig.Retries > 0 {
+		args = append(args, fmt.Sprintf("--retries=%d", ptArchiverConfig.Retries))
+	}
+
+	// 終了理由を出力させ、--run-time で止まったのか行がなくなったのかを区別する
+	args = append(args, "--purge", "--why-quit")
 
 	if ptArchiverConfig.Progress > 0 {
 		args = append(args, fmt.Sprintf("--progress=%d", ptArchiverConfig.Progress))
@@ -217,6 +352,25 @@ func (e *PtArchiverExecutor) BuildArgsWithPassword(
 		args = append(args, fmt.Sprintf("--max-lag=%f", ptArchiverConfig.MaxLag))
 	}
 
+	if ptArchiverConfig.CheckInterval != "" {
+		arg, err := CheckIntervalArg(ptArchiverConfig.CheckInterval)
+		if err != nil {
+			return nil, "", err
+		}
+		if ptArchiverConfig.MaxLag <= 0 {
+			e.logger.Warnf("pt_archiver.check_interval has no effect without pt_archiver.max_lag")
+		}
+		args = append(args, arg)
+	}
+
+	if ptArchiverConfig.RunTime != "" {
+		arg, err := RunTimeArg(ptArchiverConfig.RunTime)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, arg)
+	}
+
 	if ptArchiverConfig.NoCheckCharset {
 		args = append(args, "--no-check-charset")
 	}
@@ -233,6 +387,14 @@ func (e *PtArchiverExecutor) BuildArgsWithPassword(
 		args = append(args, "--statistics")
 	}
 
+	setVars, skipped := config.SetVarsArgument(ptArchiverConfig.SessionVars)
+	if len(skipped) > 0 {
+		e.logger.Warnf("session_vars containing commas cannot be passed to pt-archiver: %v", skipped)
+	}
+	if setVars != "" {
+		args = append(args, fmt.Sprintf("--set-vars=%s", setVars))
+	}
+
 	if dryRun {
 		args = append(args, "--dry-run")
 	}
@@ -240,50 +402,30 @@ func (e *PtArchiverExecutor) BuildArgsWithPassword(
 	return args, password, nil
 }
 
-func (e *PtArchiverExecutor) ParseDSN(dsn string) (host, port, database, user, password string, err error) {
-	parts := strings.Split(dsn, "@tcp")
-	if len(parts) != 2 {
-		return "", "", "", "", "", fmt.Errorf("invalid DSN format")
-	}
-
-	userPart := parts[0]
-	hostPart := parts[1]
-
-	userParts := strings.Split(userPart, ":")
-	if len(userParts) == 1 {
-		user = userParts[0]
-		password = ""
-	} else if len(userParts) == 2 {
-		user = userParts[0]
-		password = userParts[1]
-	} else {
-		return "", "", "", "", "", fmt.Errorf("invalid user:password format")
-	}
-
-	if !strings.HasPrefix(hostPart, "(") {
-		return "", "", "", "", "", fmt.Errorf("only TCP connections are supported")
-	}
-
-	hostPart = strings.TrimPrefix(hostPart, "(")
-	parts = strings.Split(hostPart, ")/")
-	if len(parts) != 2 {
-		return "", "", "", "", "", fmt.Errorf("invalid DSN format")
+// ParseDSN は DSN から接続先を取り出す。解釈は internal/dsn に合わせる
+func (e *PtArchiverExecutor) ParseDSN(rawDSN string) (host, port, database, user, password string, err error) {
+	parsed, err := dsn.Parse(rawDSN)
+	if err != nil {
+		return "", "", "", "", "", err
 	}
+	return parsed.Host, parsed.Port, parsed.Database, parsed.User, parsed.Password, nil
+}
 
-	hostPort := parts[0]
-	database = parts[1]
-
-	if strings.Contains(database, "?") {
-		database = strings.Split(database, "?")[0]
+// CheckIntervalArg は pt_archiver.check_interval を pt-archiver に渡す --check-interval にする。
+// pt-archiver は秒の整数しか受け付けないので、1秒未満は切り捨てる
+func CheckIntervalArg(raw string) (string, error) {
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval < time.Second {
+		return "", fmt.Errorf("invalid pt_archiver.check_interval %q: must be a duration of at least 1s", raw)
 	}
+	return fmt.Sprintf("--check-interval=%d", int(interval.Seconds())), nil
+}
 
-	hostPortParts := strings.Split(hostPort, ":")
-	if len(hostPortParts) != 2 {
-		return "", "", "", "", "", fmt.Errorf("invalid host:port format")
+// RunTimeArg は pt_archiver.run_time を pt-archiver に渡す --run-time にする
+func RunTimeArg(raw string) (string, error) {
+	runTime, err := time.ParseDuration(raw)
+	if err != nil || runTime < time.Second {
+		return "", fmt.Errorf("invalid pt_archiver.run_time %q: must be a duration of at least 1s", raw)
 	}
-
-	host = hostPortParts[0]
-	port = hostPortParts[1]
-
-	return host, port, database, user, password, nil
+	return fmt.Sprintf("--run-time=%ds", int(runTime.Seconds())), nil
 }
@@ -56,6 +56,40 @@ func TestBuildArgsWithPassword(t *testing.T) {
 			},
 			expectedPassword: "pass",
 		},
+		{
+			name:      "load shaping options",
+			tableName: "users_old",
+			ptArchiverConfig: config.PtArchiverConfig{
+				Limit:         1000,
+				TxnSize:       500,
+				Sleep:         2,
+				Retries:       5,
+				MaxLag:        1.0,
+				CheckInterval: "3s",
+			},
+			dsn: "user:pass@tcp(localhost:3306)/testdb",
+			expectedArgsContains: []string{
+				"--limit=1000",
+				"--txn-size=500",
+				"--sleep=2",
+				"--retries=5",
+				"--max-lag=1.000000",
+				"--check-interval=3",
+			},
+			expectedPassword: "pass",
+		},
+		{
+			name:      "session vars",
+			tableName: "users_old",
+			ptArchiverConfig: config.PtArchiverConfig{
+				SessionVars: map[string]string{"innodb_lock_wait_timeout": "5"},
+			},
+			dsn: "user:pass@tcp(localhost:3306)/testdb",
+			expectedArgsContains: []string{
+				"--set-vars=innodb_lock_wait_timeout=5",
+			},
+			expectedPassword: "pass",
+		},
 		{
 			name:      "dry run mode",
 			tableName: "orders_old",
@@ -131,6 +165,40 @@ func TestBuildArgsWithPassword(t *testing.T) {
 	}
 }
 
+func TestBuildArgsRejectsInvalidOptions(t *testing.T) {
+	executor := NewPtArchiverExecutor(logrus.New())
+
+	tests := []struct {
+		name             string
+		ptArchiverConfig config.PtArchiverConfig
+		expectError      string
+	}{
+		{
+			name:             "commit_each with txn_size",
+			ptArchiverConfig: config.PtArchiverConfig{CommitEach: true, TxnSize: 100},
+			expectError:      "cannot be used together",
+		},
+		{
+			name:             "invalid check_interval",
+			ptArchiverConfig: config.PtArchiverConfig{MaxLag: 1, CheckInterval: "soon"},
+			expectError:      "invalid pt_archiver.check_interval",
+		},
+		{
+			name:             "check_interval below one second",
+			ptArchiverConfig: config.PtArchiverConfig{MaxLag: 1, CheckInterval: "500ms"},
+			expectError:      "invalid pt_archiver.check_interval",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := executor.BuildArgsWithPassword("users_old", tt.ptArchiverConfig, "user:pass@tcp(localhost:3306)/testdb", false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectError)
+		})
+	}
+}
+
 func TestParseDSN(t *testing.T) {
 	logger := logrus.New()
 	executor := NewPtArchiverExecutor(logger)
@@ -0,0 +1,31 @@
+package ptarchiver
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// --progress の出力行。例: 2024-01-01T00:00:10      10     5000
+	progressLinePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\s+\d+\s+(\d+)$`)
+	// --statistics の出力行。例: DELETE 5000
+	statisticsDeletePattern = regexp.MustCompile(`^DELETE\s+(\d+)$`)
+)
+
+// parseDeletedRows は pt-archiver の出力行から、それまでに削除した行数を読み取る
+func parseDeletedRows(line string) (int64, bool) {
+	line = strings.TrimSpace(line)
+	matches := progressLinePattern.FindStringSubmatch(line)
+	if matches == nil {
+		matches = statisticsDeletePattern.FindStringSubmatch(line)
+	}
+	if matches == nil {
+		return 0, false
+	}
+	count, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return count, true
+}
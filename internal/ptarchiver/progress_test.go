@@ -0,0 +1,30 @@
+package ptarchiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDeletedRows(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected int64
+		ok       bool
+	}{
+		{name: "progress header", line: "TIME                ELAPSED   COUNT", ok: false},
+		{name: "progress line", line: "2024-01-01T00:00:10      10     5000", expected: 5000, ok: true},
+		{name: "statistics delete", line: "DELETE 12345", expected: 12345, ok: true},
+		{name: "statistics select", line: "SELECT 12345", ok: false},
+		{name: "other output", line: "Started at 2024-01-01T00:00:00, ended at 2024-01-01T00:01:00", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, ok := parseDeletedRows(tt.line)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, count)
+		})
+	}
+}
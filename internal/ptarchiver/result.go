@@ -0,0 +1,124 @@
+package ptarchiver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuitReason は pt-archiver が終了した理由。--why-quit の出力から読み取る
+type QuitReason string
+
+const (
+	// 出力に終了理由がない (--why-quit の出力前にエラーで止まった場合など)
+	QuitUnknown QuitReason = ""
+	// 対象の行がなくなった
+	QuitNoMoreRows QuitReason = "no more rows"
+	// --run-time に達した。行が残っている可能性がある
+	QuitRunTime QuitReason = "run time reached"
+	// --sentinel のファイルが置かれた
+	QuitSentinel QuitReason = "sentinel file"
+	// ロック待ちやデッドロックのリトライ回数 (--retries) を超えた
+	QuitRetriesExceeded QuitReason = "retries exceeded"
+	// 上記以外の理由
+	QuitOther QuitReason = "other"
+)
+
+// PurgeResult は1回の pt-archiver の実行結果。--statistics と --why-quit の出力から組み立てる
+type PurgeResult struct {
+	Selected int64
+	Inserted int64
+	Deleted  int64
+	// --statistics の Started at / ended at から求めた実行時間。出力がなければ 0
+	Duration   time.Duration
+	QuitReason QuitReason
+	// pt-archiver が出力した終了理由の行
+	QuitMessage string
+}
+
+var (
+	// --why-quit の出力。例: Exiting because there are no more rows
+	whyQuitPattern = regexp.MustCompile(`^Exiting because (.+?)\.?$`)
+	// --statistics の出力。例: Started at 2024-01-01T00:00:00, ended at 2024-01-01T00:01:00
+	statisticsTimePattern = regexp.MustCompile(`^Started at (\S+), ended at (\S+)$`)
+	// --statistics の出力。例: SELECT 5000
+	statisticsCountPattern = regexp.MustCompile(`^(SELECT|INSERT|DELETE)\s+(\d+)$`)
+)
+
+const statisticsTimeLayout = "2006-01-02T15:04:05"
+
+// parseLine は pt-archiver の出力行から統計情報と終了理由を読み取る
+func (r *PurgeResult) parseLine(line string) {
+	line = strings.TrimSpace(line)
+
+	if matches := whyQuitPattern.FindStringSubmatch(line); matches != nil {
+		r.QuitMessage = line
+		r.QuitReason = classifyQuitReason(matches[1])
+		return
+	}
+
+	if matches := statisticsTimePattern.FindStringSubmatch(line); matches != nil {
+		started, err := time.Parse(statisticsTimeLayout, matches[1])
+		if err != nil {
+			return
+		}
+		ended, err := time.Parse(statisticsTimeLayout, matches[2])
+		if err != nil {
+			return
+		}
+		r.Duration = ended.Sub(started)
+		return
+	}
+
+	if matches := statisticsCountPattern.FindStringSubmatch(line); matches != nil {
+		count, err := strconv.ParseInt(matches[2], 10, 64)
+		if err != nil {
+			return
+		}
+		switch matches[1] {
+		case "SELECT":
+			r.Selected = count
+		case "INSERT":
+			r.Inserted = count
+		case "DELETE":
+			r.Deleted = count
+		}
+	}
+}
+
+func classifyQuitReason(reason string) QuitReason {
+	reason = strings.ToLower(reason)
+	switch {
+	case strings.Contains(reason, "no more rows"):
+		return QuitNoMoreRows
+	case strings.Contains(reason, "retries"):
+		return QuitRetriesExceeded
+	case strings.Contains(reason, "sentinel"):
+		return QuitSentinel
+	case strings.Contains(reason, "time"):
+		return QuitRunTime
+	}
+	return QuitOther
+}
+
+// RowsMayRemain は pt-archiver が対象の行を削除し終える前に、エラーではない理由 (--run-time) で止まった場合に true を返す
+func (r *PurgeResult) RowsMayRemain() bool {
+	return r.QuitReason == QuitRunTime
+}
+
+func (r *PurgeResult) String() string {
+	description := fmt.Sprintf("deleted %d rows", r.Deleted)
+	if r.Duration > 0 {
+		description += fmt.Sprintf(" in %s", r.Duration)
+	}
+	switch r.QuitReason {
+	case QuitUnknown:
+	case QuitOther:
+		description += fmt.Sprintf(", %s", r.QuitMessage)
+	default:
+		description += fmt.Sprintf(", stopped because of %s", r.QuitReason)
+	}
+	return description
+}
@@ -0,0 +1,61 @@
+package ptarchiver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeResultParseLine(t *testing.T) {
+	output := []string{
+		"Started at 2024-01-01T00:00:00, ended at 2024-01-01T00:01:30",
+		"Source: D=testdb,t=users_old",
+		"SELECT 5000",
+		"INSERT 0",
+		"DELETE 5000",
+		"Action         Count       Time        Pct",
+		"deleting        5000    12.3456      13.72",
+		"Exiting because time exceeded",
+	}
+
+	result := &PurgeResult{}
+	for _, line := range output {
+		result.parseLine(line)
+	}
+
+	assert.Equal(t, int64(5000), result.Selected)
+	assert.Equal(t, int64(0), result.Inserted)
+	assert.Equal(t, int64(5000), result.Deleted)
+	assert.Equal(t, 90*time.Second, result.Duration)
+	assert.Equal(t, QuitRunTime, result.QuitReason)
+	assert.Equal(t, "Exiting because time exceeded", result.QuitMessage)
+	assert.True(t, result.RowsMayRemain())
+	assert.Equal(t, "deleted 5000 rows in 1m30s, stopped because of run time reached", result.String())
+}
+
+func TestClassifyQuitReason(t *testing.T) {
+	tests := []struct {
+		line string
+		want QuitReason
+	}{
+		{line: "Exiting because there are no more rows", want: QuitNoMoreRows},
+		{line: "Exiting because time exceeded", want: QuitRunTime},
+		{line: "Exiting because sentinel file /tmp/pt-archiver-sentinel exists", want: QuitSentinel},
+		{line: "Exiting because retries exceeded", want: QuitRetriesExceeded},
+		{line: "Exiting because of a strange reason.", want: QuitOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			result := &PurgeResult{}
+			result.parseLine(tt.line)
+			assert.Equal(t, tt.want, result.QuitReason)
+			assert.Equal(t, tt.want == QuitRunTime, result.RowsMayRemain())
+		})
+	}
+
+	result := &PurgeResult{Deleted: 3}
+	result.parseLine("Exiting because of a strange reason.")
+	assert.Equal(t, "deleted 3 rows, Exiting because of a strange reason.", result.String())
+}
@@ -0,0 +1,152 @@
+package ptosc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	copyStartRe      = regexp.MustCompile(`Copying approximately (\d+) rows`)
+	lagWaitRe        = regexp.MustCompile(`Replica lag is ([\d.]+|\?) seconds? on .*Waiting`)
+	loadPauseRe      = regexp.MustCompile(`^Pausing because `)
+	pauseFileSleepRe = regexp.MustCompile(`Sleeping ([\d.]+) seconds? because .* exists`)
+	chunkReducedRe   = regexp.MustCompile(`--chunk-size has been automatically reduced`)
+	copyProgressRe   = regexp.MustCompile(`^Copying \S+:\s+(\d+)% (\S+) remain`)
+)
+
+// CopyStats は pt-osc の出力から読み取った行コピーの性能と、スロットリングの回数
+type CopyStats struct {
+	// "Copying approximately N rows..." の N
+	ApproximateRows int64
+	// 行コピーの開始から "Copied rows OK." までの時間
+	CopyDuration time.Duration
+	// レプリカ遅延 (--max-lag) で待った回数と、その時に報告された最大の遅延
+	LagWaits      int
+	MaxLagSeconds float64
+	// --max-load による一時停止の回数
+	LoadPauses int
+	// --pause-file (Aurora のレプリカ遅延監視) による待ち時間
+	PauseFileSleep time.Duration
+	// コピーが遅いため pt-osc が --chunk-size を自動的に縮めた回数
+	ChunkSizeReductions int
+	// 行コピーを始めた時刻。まだ始まっていなければゼロ値
+	CopyStartedAt time.Time
+	// --progress で直近に報告されたコピーの進み具合(%)と残り時間の見込み (例: 03:21)
+	ProgressPercent   int
+	ProgressRemaining string
+	// プラグイン (pt_osc.plugin) から受け取ったチャンクの数と、コピーした行数の合計。プラグインを使わなければ0
+	PluginChunks     int64
+	PluginRowsCopied int64
+	// プラグインから受け取った直近のチャンクのコピー時間(秒)
+	LastChunkSeconds float64
+}
+
+// RowsPerSecond は行コピーの平均速度を返す。コピー時間が分からない場合は0を返す。
+func (s CopyStats) RowsPerSecond() float64 {
+	if s.ApproximateRows <= 0 || s.CopyDuration <= 0 {
+		return 0
+	}
+	return float64(s.ApproximateRows) / s.CopyDuration.Seconds()
+}
+
+// Throttled はコピーの遅れの主な原因を返す。スロットリングが見つからなければ空を返す。
+func (s CopyStats) Throttled() string {
+	var causes []string
+	if s.LagWaits > 0 {
+		causes = append(causes, fmt.Sprintf("replica lag (%d waits, max %.1fs)", s.LagWaits, s.MaxLagSeconds))
+	}
+	if s.PauseFileSleep > 0 {
+		causes = append(causes, fmt.Sprintf("pause file (%s)", s.PauseFileSleep))
+	}
+	if s.LoadPauses > 0 {
+		causes = append(causes, fmt.Sprintf("server load (%d pauses)", s.LoadPauses))
+	}
+	if s.ChunkSizeReductions > 0 {
+		causes = append(causes, fmt.Sprintf("slow chunks (chunk size reduced %d times)", s.ChunkSizeReductions))
+	}
+	return strings.Join(causes, ", ")
+}
+
+// copyStatsCollector は pt-osc の出力を1行ずつ受け取り CopyStats を組み立てる
+type copyStatsCollector struct {
+	stats       CopyStats
+	copyStarted time.Time
+	now         func() time.Time
+}
+
+func newCopyStatsCollector() *copyStatsCollector {
+	return &copyStatsCollector{now: time.Now}
+}
+
+func (c *copyStatsCollector) observe(line string) {
+	line = strings.TrimSpace(line)
+
+	switch {
+	case copyStartRe.MatchString(line):
+		rows, err := strconv.ParseInt(copyStartRe.FindStringSubmatch(line)[1], 10, 64)
+		if err == nil {
+			c.stats.ApproximateRows = rows
+		}
+		c.copyStarted = c.now()
+		c.stats.CopyStartedAt = c.copyStarted
+	case strings.HasPrefix(line, "Copied rows OK"):
+		if !c.copyStarted.IsZero() {
+			c.stats.CopyDuration = c.now().Sub(c.copyStarted)
+		}
+		c.stats.ProgressPercent = 100
+		c.stats.ProgressRemaining = ""
+	case copyProgressRe.MatchString(line):
+		match := copyProgressRe.FindStringSubmatch(line)
+		if percent, err := strconv.Atoi(match[1]); err == nil {
+			c.stats.ProgressPercent = percent
+		}
+		c.stats.ProgressRemaining = match[2]
+	case lagWaitRe.MatchString(line):
+		c.stats.LagWaits++
+		if lag, err := strconv.ParseFloat(lagWaitRe.FindStringSubmatch(line)[1], 64); err == nil && lag > c.stats.MaxLagSeconds {
+			c.stats.MaxLagSeconds = lag
+		}
+	case loadPauseRe.MatchString(line):
+		c.stats.LoadPauses++
+	case pauseFileSleepRe.MatchString(line):
+		if seconds, err := strconv.ParseFloat(pauseFileSleepRe.FindStringSubmatch(line)[1], 64); err == nil {
+			c.stats.PauseFileSleep += time.Duration(seconds * float64(time.Second))
+		}
+	case chunkReducedRe.MatchString(line):
+		c.stats.ChunkSizeReductions++
+	}
+}
+
+// observePlugin はプラグインから届いたイベントを反映する。出力から読み取れる値より正確なので、そちらを上書きする
+func (c *copyStatsCollector) observePlugin(event PluginEvent) {
+	switch event.Event {
+	case "copy_start":
+		if c.copyStarted.IsZero() {
+			c.copyStarted = c.now()
+			c.stats.CopyStartedAt = c.copyStarted
+		}
+	case "chunk":
+		c.stats.PluginChunks++
+		if event.Chunk > c.stats.PluginChunks {
+			c.stats.PluginChunks = event.Chunk
+		}
+		c.stats.PluginRowsCopied += event.Rows
+		c.stats.LastChunkSeconds = event.Seconds
+		if c.stats.ApproximateRows > 0 && c.stats.ProgressPercent < 100 {
+			percent := int(c.stats.PluginRowsCopied * 100 / c.stats.ApproximateRows)
+			if percent > 99 {
+				percent = 99
+			}
+			c.stats.ProgressPercent = percent
+		}
+	case "copy_end":
+		if !c.copyStarted.IsZero() && c.stats.CopyDuration == 0 {
+			c.stats.CopyDuration = c.now().Sub(c.copyStarted)
+		}
+		c.stats.ProgressPercent = 100
+		c.stats.ProgressRemaining = ""
+	}
+}
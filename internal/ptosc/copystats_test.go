@@ -0,0 +1,69 @@
+package ptosc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyStatsCollector(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	collector := newCopyStatsCollector()
+	collector.now = func() time.Time { return now }
+
+	lines := []string{
+		"Creating triggers...",
+		"Created triggers OK.",
+		"Copying approximately 120000 rows...",
+		"Replica lag is 3 seconds on replica1.  Waiting.",
+		"Replica lag is 7.5 seconds on replica1.  Waiting.",
+		"Replica lag is ? seconds on replica2.  Waiting.",
+		"Pausing because Threads_running=60.",
+		"Sleeping 60 seconds because /tmp/alterguard-ptosc-pause exists",
+		"Rows are copying very slowly.  --chunk-size has been automatically reduced to 1.  Check that the server is not being overloaded, or increase --chunk-time.",
+		"Copying `db`.`users`:  45% 01:23 remain",
+	}
+	for _, line := range lines {
+		collector.observe(line)
+	}
+	now = now.Add(2 * time.Minute)
+	collector.observe("Copied rows OK.")
+
+	stats := collector.stats
+	assert.Equal(t, int64(120000), stats.ApproximateRows)
+	assert.Equal(t, 2*time.Minute, stats.CopyDuration)
+	assert.Equal(t, float64(1000), stats.RowsPerSecond())
+	assert.Equal(t, 3, stats.LagWaits)
+	assert.Equal(t, 7.5, stats.MaxLagSeconds)
+	assert.Equal(t, 1, stats.LoadPauses)
+	assert.Equal(t, time.Minute, stats.PauseFileSleep)
+	assert.Equal(t, 1, stats.ChunkSizeReductions)
+	assert.Equal(t,
+		"replica lag (3 waits, max 7.5s), pause file (1m0s), server load (1 pauses), slow chunks (chunk size reduced 1 times)",
+		stats.Throttled())
+}
+
+func TestCopyStatsCollectorProgress(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	collector := newCopyStatsCollector()
+	collector.now = func() time.Time { return now }
+
+	collector.observe("Copying approximately 120000 rows...")
+	assert.Equal(t, now, collector.stats.CopyStartedAt)
+	assert.Equal(t, 0, collector.stats.ProgressPercent)
+
+	collector.observe("Copying `db`.`users`:  45% 01:23 remain")
+	assert.Equal(t, 45, collector.stats.ProgressPercent)
+	assert.Equal(t, "01:23", collector.stats.ProgressRemaining)
+
+	collector.observe("Copied rows OK.")
+	assert.Equal(t, 100, collector.stats.ProgressPercent)
+	assert.Empty(t, collector.stats.ProgressRemaining)
+}
+
+func TestCopyStatsWithoutThrottling(t *testing.T) {
+	stats := CopyStats{}
+	assert.Equal(t, float64(0), stats.RowsPerSecond())
+	assert.Empty(t, stats.Throttled())
+}
@@ -5,12 +5,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
-	"strconv"
+	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/dsn"
+	"github.com/pyama86/alterguard/internal/output"
 	"github.com/sirupsen/logrus"
 )
 
@@ -24,8 +29,57 @@ type DryRunResult struct {
 }
 
 type Executor interface {
-	ExecuteAlter(tableName, alterStatement string, ptOscConfig config.PtOscConfig, dsn string, forceDryRun bool) error
-	ExecuteAlterWithDryRunResult(tableName, alterStatement string, ptOscConfig config.PtOscConfig, dsn string, forceDryRun bool) (*DryRunResult, error)
+	ExecuteAlter(ctx context.Context, tableName, alterStatement string, ptOscConfig config.PtOscConfig, dsn string, forceDryRun bool) error
+	ExecuteAlterWithDryRunResult(ctx context.Context, tableName, alterStatement string, ptOscConfig config.PtOscConfig, dsn string, forceDryRun bool) (*DryRunResult, error)
+}
+
+// ctxがキャンセルされた時にSIGTERMを送ってから待つ猶予。pt-oscにトリガー等の後始末をさせるため。
+const processWaitDelay = 30 * time.Second
+
+func newCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...) // #nosec G204
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = processWaitDelay
+	return cmd
+}
+
+// outputLine は stdout か stderr から読んだ1行
+type outputLine struct {
+	text    string
+	isError bool
+}
+
+// readOutputs は stdout と stderr を並行して読み、届いた順に1行ずつ handle に渡す。
+// handle は呼び出し元の goroutine だけで呼ぶので、stdout と stderr の行が混ざっても出力の順序が保たれる。
+// 両方を読み切るまで戻らない
+func readOutputs(stdout, stderr io.Reader, logger *logrus.Logger, handle func(line string, isError bool)) {
+	lines := make(chan outputLine)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go scanOutput(stdout, false, lines, &wg, logger)
+	go scanOutput(stderr, true, lines, &wg, logger)
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+	for line := range lines {
+		handle(line.text, line.isError)
+	}
+}
+
+// scanOutput は r を1行ずつ lines に送る。読み取りエラー後も残りを読み捨て、パイプが詰まってプロセスが止まらないようにする
+func scanOutput(r io.Reader, isError bool, lines chan<- outputLine, wg *sync.WaitGroup, logger *logrus.Logger) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines <- outputLine{text: scanner.Text(), isError: isError}
+	}
+	if err := scanner.Err(); err != nil {
+		logger.Warnf("Failed to read pt-osc output: %v", err)
+		_, _ = io.Copy(io.Discard, r)
+	}
 }
 
 type PtOscExecutor struct {
@@ -34,7 +88,8 @@ type PtOscExecutor struct {
 	hasError          bool
 	errorMessages     []string
 	outputLines       []string
-	outputSummary     string
+	outputBuffer      *output.Buffer
+	copyStats         *copyStatsCollector
 	mutex             sync.Mutex
 }
 
@@ -45,13 +100,20 @@ func NewPtOscExecutor(logger *logrus.Logger, replicaLagFetcher ReplicaLagFetcher
 	}
 }
 
-func (e *PtOscExecutor) ExecuteAlter(tableName, alterStatement string, ptOscConfig config.PtOscConfig, dsn string, forceDryRun bool) error {
+func (e *PtOscExecutor) ExecuteAlter(ctx context.Context, tableName, alterStatement string, ptOscConfig config.PtOscConfig, dsn string, forceDryRun bool) error {
 	e.mutex.Lock()
 	e.hasError = false
 	e.errorMessages = []string{}
 	e.outputLines = []string{}
-	e.outputSummary = ""
+	outputBuffer := e.newOutputBuffer()
+	e.outputBuffer = outputBuffer
+	e.copyStats = newCopyStatsCollector()
 	e.mutex.Unlock()
+	defer func() {
+		if err := outputBuffer.Close(); err != nil {
+			e.logger.Warnf("Failed to close pt-osc output file: %v", err)
+		}
+	}()
 
 	monitor, monitorCancel, err := e.startAuroraMonitorIfEnabled(ptOscConfig, forceDryRun)
 	if err != nil {
@@ -61,6 +123,14 @@ func (e *PtOscExecutor) ExecuteAlter(tableName, alterStatement string, ptOscConf
 		defer monitorCancel()
 	}
 
+	plugin, err := e.startPluginListenerIfEnabled(ptOscConfig, forceDryRun)
+	if err != nil {
+		return err
+	}
+	if plugin != nil {
+		defer plugin.Close()
+	}
+
 	args, password, err := e.buildArgsWithMonitor(tableName, alterStatement, ptOscConfig, dsn, forceDryRun, monitor)
 	if err != nil {
 		return fmt.Errorf("failed to build pt-osc arguments: %w", err)
@@ -76,7 +146,10 @@ func (e *PtOscExecutor) ExecuteAlter(tableName, alterStatement string, ptOscConf
 	}
 	e.logger.Infof("Executing pt-online-schema-change command: pt-online-schema-change %s", strings.Join(maskedArgs, " "))
 
-	cmd := exec.Command("pt-online-schema-change", args...) // #nosec G204
+	cmd := newCommand(ctx, "pt-online-schema-change", args...)
+	if plugin != nil {
+		cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", PluginSocketEnv, plugin.Path()))
+	}
 
 	if password != "" {
 		e.logger.Debugf("Using password for pt-online-schema-change")
@@ -96,14 +169,20 @@ func (e *PtOscExecutor) ExecuteAlter(tableName, alterStatement string, ptOscConf
 		return fmt.Errorf("failed to start command: %w", err)
 	}
 
-	go e.logOutputWithSummary(stdoutPipe, false)
-	go e.logOutputWithSummary(stderrPipe, true)
+	// 出力を読み切ってから Wait する。先に Wait すると末尾の出力が失われる
+	readOutputs(stdoutPipe, stderrPipe, e.logger, func(line string, isError bool) {
+		e.logOutputWithSummary(line, isError, outputBuffer)
+	})
 
 	cmdErr := cmd.Wait()
 
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("pt-online-schema-change for table %s was terminated: %w", tableName, ctxErr)
+	}
+
 	// コマンドが異常終了した場合、またはエラーパターンが検出された場合はエラーとする
 	if cmdErr != nil || e.hasError {
 		var errorMsg string
@@ -123,61 +202,81 @@ func (e *PtOscExecutor) ExecuteAlter(tableName, alterStatement string, ptOscConf
 	return nil
 }
 
-func (e *PtOscExecutor) logOutput(r io.Reader, isError bool) {
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
+func (e *PtOscExecutor) logOutput(line string, isError bool) {
+	if e.containsErrorPattern(line) {
+		e.mutex.Lock()
+		e.hasError = true
+		e.errorMessages = append(e.errorMessages, line)
+		e.mutex.Unlock()
+	}
 
-		if e.containsErrorPattern(line) {
-			e.mutex.Lock()
-			e.hasError = true
-			e.errorMessages = append(e.errorMessages, line)
-			e.mutex.Unlock()
-		}
+	if isError {
+		e.logger.Errorf("[pt-osc] %s", line)
+	} else {
+		e.logger.Infof("[pt-osc] %s", line)
+	}
+}
 
-		if isError {
-			e.logger.Errorf("[pt-osc] %s", line)
-		} else {
-			e.logger.Infof("[pt-osc] %s", line)
-		}
+// newOutputBuffer は出力の先頭と末尾だけを保持するバッファを作る。全量は一時ファイルに書き出す。
+func (e *PtOscExecutor) newOutputBuffer() *output.Buffer {
+	buf := output.NewBuffer(output.DefaultHeadLines, output.DefaultTailLines)
+	if err := buf.SpillToTempFile("alterguard-pt-osc-*.log"); err != nil {
+		e.logger.Warnf("Full pt-osc output will not be kept: %v", err)
 	}
+	return buf
 }
 
-func (e *PtOscExecutor) logOutputWithSummary(r io.Reader, isError bool) {
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
+func (e *PtOscExecutor) logOutputWithSummary(line string, isError bool, outputBuffer *output.Buffer) {
+	if isError {
+		outputBuffer.AddLine("[STDERR] " + line)
+	} else {
+		outputBuffer.AddLine("[STDOUT] " + line)
+	}
 
-		e.mutex.Lock()
-		if e.outputSummary != "" {
-			e.outputSummary += "\n"
-		}
-		if isError {
-			e.outputSummary += "[STDERR] " + line
-		} else {
-			e.outputSummary += "[STDOUT] " + line
-		}
-		e.mutex.Unlock()
+	e.mutex.Lock()
+	if e.containsErrorPattern(line) {
+		e.hasError = true
+		e.errorMessages = append(e.errorMessages, line)
+	}
+	if e.copyStats != nil {
+		e.copyStats.observe(line)
+	}
+	e.mutex.Unlock()
 
-		if e.containsErrorPattern(line) {
-			e.mutex.Lock()
-			e.hasError = true
-			e.errorMessages = append(e.errorMessages, line)
-			e.mutex.Unlock()
-		}
+	if isError {
+		e.logger.Errorf("[pt-osc] %s", line)
+	} else {
+		e.logger.Infof("[pt-osc] %s", line)
+	}
+}
 
-		if isError {
-			e.logger.Errorf("[pt-osc] %s", line)
-		} else {
-			e.logger.Infof("[pt-osc] %s", line)
-		}
+// GetCopyStats は直近の実行の出力から読み取った行コピーの性能を返す
+func (e *PtOscExecutor) GetCopyStats() CopyStats {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.copyStats == nil {
+		return CopyStats{}
 	}
+	return e.copyStats.stats
 }
 
 func (e *PtOscExecutor) GetOutputSummary() string {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
-	return e.outputSummary
+	if e.outputBuffer == nil {
+		return ""
+	}
+	return e.outputBuffer.String()
+}
+
+// GetOutputFilePath は直近の実行の全出力を書き出したファイルのパスを返す
+func (e *PtOscExecutor) GetOutputFilePath() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.outputBuffer == nil {
+		return ""
+	}
+	return e.outputBuffer.FilePath()
 }
 
 func (e *PtOscExecutor) containsErrorPattern(line string) bool {
@@ -230,6 +329,28 @@ func (e *PtOscExecutor) containsErrorPattern(line string) bool {
 	return false
 }
 
+// containsWarningPattern は dry-run の出力のうち、本番実行前に確認が必要な警告かどうかを返す
+func (e *PtOscExecutor) containsWarningPattern(line string) bool {
+	line = strings.ToLower(strings.TrimSpace(line))
+
+	warningPatterns := []string{
+		"warning",
+		"can be dangerous",
+		"data loss",
+		"you are trying to add an unique key",
+		"will be renamed",
+		"child tables:",
+	}
+
+	for _, pattern := range warningPatterns {
+		if strings.Contains(line, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (e *PtOscExecutor) BuildArgsWithPassword(
 	tableName, alterStatement string,
 	ptOscConfig config.PtOscConfig,
@@ -246,6 +367,8 @@ func (e *PtOscExecutor) buildArgsWithMonitor(
 	forceDryRun bool,
 	monitor *AuroraMonitor,
 ) ([]string, string, error) {
+	ptOscConfig = ptOscConfig.ForTable(tableName)
+
 	host, port, database, user, password, err := e.ParseDSN(rawDSN)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to parse DSN: %w", err)
@@ -267,9 +390,18 @@ func (e *PtOscExecutor) buildArgsWithMonitor(
 	if ptOscConfig.RecursionMethod != "" {
 		method := strings.ReplaceAll(ptOscConfig.RecursionMethod, "<db>", database)
 		method = strings.ReplaceAll(method, "<table>", tableName)
+		// none ではレプリカ遅延を一切確認しなくなるため、明示的な了承がなければ実行しない
+		if method == "none" && !ptOscConfig.AcknowledgeNoReplicaCheck {
+			return nil, "", fmt.Errorf("recursion_method none disables replica lag checks for %s; set acknowledge_no_replica_check to confirm", tableName)
+		}
 		args = append(args, fmt.Sprintf("--recursion-method=%s", method))
 		if method == "dsn" {
-			args = append(args, fmt.Sprintf("--recursion-dsn=%s", ptOscDSN))
+			recursionDSN := ptOscDSN
+			if ptOscConfig.RecursionDSN != "" {
+				recursionDSN = strings.ReplaceAll(ptOscConfig.RecursionDSN, "<db>", database)
+				recursionDSN = strings.ReplaceAll(recursionDSN, "<table>", tableName)
+			}
+			args = append(args, fmt.Sprintf("--recursion-dsn=%s", recursionDSN))
 		}
 	}
 
@@ -289,6 +421,13 @@ func (e *PtOscExecutor) buildArgsWithMonitor(
 	if ptOscConfig.Statistics {
 		args = append(args, "--statistics")
 	}
+	setVars, skipped := config.SetVarsArgument(ptOscConfig.SessionVars)
+	if len(skipped) > 0 {
+		e.logger.Warnf("session_vars containing commas cannot be passed to pt-online-schema-change: %v", skipped)
+	}
+	if setVars != "" {
+		args = append(args, fmt.Sprintf("--set-vars=%s", setVars))
+	}
 
 	// dry-runでない場合のみ、--no-drop-triggers と --no-drop-new-table を追加
 	if !forceDryRun && !ptOscConfig.DryRun {
@@ -313,10 +452,36 @@ func (e *PtOscExecutor) buildArgsWithMonitor(
 		args = append(args, "--no-check-alter")
 	}
 
+	if ptOscConfig.DataDir != "" {
+		if !filepath.IsAbs(ptOscConfig.DataDir) {
+			return nil, "", fmt.Errorf("pt_osc.data_dir must be an absolute path: %s", ptOscConfig.DataDir)
+		}
+		// --remove-data-dir と同時に指定すると --data-dir は無視される
+		if ptOscConfig.RemoveDataDir != nil && *ptOscConfig.RemoveDataDir {
+			return nil, "", fmt.Errorf("pt_osc.data_dir cannot be combined with remove_data_dir: true")
+		}
+		args = append(args, fmt.Sprintf("--data-dir=%s", ptOscConfig.DataDir))
+	}
+
+	if ptOscConfig.RemoveDataDir != nil {
+		if *ptOscConfig.RemoveDataDir {
+			args = append(args, "--remove-data-dir")
+		} else {
+			args = append(args, "--no-remove-data-dir")
+		}
+	}
+
 	if monitor != nil {
 		args = append(args, fmt.Sprintf("--pause-file=%s", monitor.PauseFilePath()))
 	}
 
+	if ptOscConfig.Plugin.Path != "" {
+		if _, err := os.Stat(ptOscConfig.Plugin.Path); err != nil {
+			return nil, "", fmt.Errorf("pt_osc.plugin.path is not readable: %w", err)
+		}
+		args = append(args, fmt.Sprintf("--plugin=%s", ptOscConfig.Plugin.Path))
+	}
+
 	if forceDryRun || ptOscConfig.DryRun {
 		args = append(args, "--dry-run")
 	} else {
@@ -356,57 +521,18 @@ func (e *PtOscExecutor) startAuroraMonitorIfEnabled(
 	return monitor, cancel, nil
 }
 
-func (e *PtOscExecutor) ParseDSN(dsn string) (host, port, database, user, password string, err error) {
-	parts := strings.Split(dsn, "@tcp")
-	if len(parts) != 2 {
-		return "", "", "", "", "", fmt.Errorf("invalid DSN format")
-	}
-
-	userPart := parts[0]
-	hostPart := parts[1]
-
-	userParts := strings.Split(userPart, ":")
-	if len(userParts) == 1 {
-		user = userParts[0]
-		password = ""
-	} else if len(userParts) == 2 {
-		user = userParts[0]
-		password = userParts[1]
-	} else {
-		return "", "", "", "", "", fmt.Errorf("invalid user:password format")
-	}
-
-	if !strings.HasPrefix(hostPart, "(") {
-		return "", "", "", "", "", fmt.Errorf("only TCP connections are supported")
-	}
-
-	hostPart = strings.TrimPrefix(hostPart, "(")
-	parts = strings.Split(hostPart, ")/")
-	if len(parts) != 2 {
-		return "", "", "", "", "", fmt.Errorf("invalid DSN format")
-	}
-
-	hostPort := parts[0]
-	database = parts[1]
-
-	hostPortParts := strings.Split(hostPort, ":")
-	if len(hostPortParts) != 2 {
-		return "", "", "", "", "", fmt.Errorf("invalid host:port format")
-	}
-
-	host = hostPortParts[0]
-	port = hostPortParts[1]
-
-	if _, err := strconv.Atoi(port); err != nil {
-		return "", "", "", "", "", fmt.Errorf("invalid port number: %s", port)
+// ParseDSN は DSN から接続先を取り出す。解釈は internal/dsn に合わせる
+func (e *PtOscExecutor) ParseDSN(rawDSN string) (host, port, database, user, password string, err error) {
+	parsed, err := dsn.Parse(rawDSN)
+	if err != nil {
+		return "", "", "", "", "", err
 	}
-
-	return host, port, database, user, password, nil
+	return parsed.Host, parsed.Port, parsed.Database, parsed.User, parsed.Password, nil
 }
 
-func (e *PtOscExecutor) ExecuteAlterWithDryRunResult(tableName, alterStatement string, ptOscConfig config.PtOscConfig, dsn string, forceDryRun bool) (*DryRunResult, error) {
+func (e *PtOscExecutor) ExecuteAlterWithDryRunResult(ctx context.Context, tableName, alterStatement string, ptOscConfig config.PtOscConfig, dsn string, forceDryRun bool) (*DryRunResult, error) {
 	if !forceDryRun && !ptOscConfig.DryRun {
-		_, err := e.executeAlterInternal(tableName, alterStatement, ptOscConfig, dsn, forceDryRun, nil)
+		_, err := e.executeAlterInternal(ctx, tableName, alterStatement, ptOscConfig, dsn, forceDryRun, nil)
 		return nil, err
 	}
 
@@ -414,11 +540,11 @@ func (e *PtOscExecutor) ExecuteAlterWithDryRunResult(tableName, alterStatement s
 		Warnings: []string{},
 	}
 
-	_, err := e.executeAlterInternal(tableName, alterStatement, ptOscConfig, dsn, forceDryRun, result)
+	_, err := e.executeAlterInternal(ctx, tableName, alterStatement, ptOscConfig, dsn, forceDryRun, result)
 	return result, err
 }
 
-func (e *PtOscExecutor) executeAlterInternal(tableName, alterStatement string, ptOscConfig config.PtOscConfig, dsn string, forceDryRun bool, dryRunResult *DryRunResult) (bool, error) {
+func (e *PtOscExecutor) executeAlterInternal(ctx context.Context, tableName, alterStatement string, ptOscConfig config.PtOscConfig, dsn string, forceDryRun bool, dryRunResult *DryRunResult) (bool, error) {
 	e.mutex.Lock()
 	e.hasError = false
 	e.errorMessages = []string{}
@@ -439,7 +565,7 @@ func (e *PtOscExecutor) executeAlterInternal(tableName, alterStatement string, p
 	}
 	e.logger.Infof("Executing pt-online-schema-change command: pt-online-schema-change %s", strings.Join(maskedArgs, " "))
 
-	cmd := exec.Command("pt-online-schema-change", args...) // #nosec G204
+	cmd := newCommand(ctx, "pt-online-schema-change", args...)
 
 	if password != "" {
 		e.logger.Debugf("Using password for pt-online-schema-change")
@@ -459,19 +585,33 @@ func (e *PtOscExecutor) executeAlterInternal(tableName, alterStatement string, p
 		return false, fmt.Errorf("failed to start command: %w", err)
 	}
 
+	// 出力を読み切ってから Wait する。先に Wait すると末尾の出力が失われる
+	var outputBuffer *output.Buffer
 	if dryRunResult != nil {
-		go e.logOutputWithDryRunAnalysis(stdoutPipe, false, dryRunResult)
-		go e.logOutputWithDryRunAnalysis(stderrPipe, true, dryRunResult)
+		outputBuffer = e.newOutputBuffer()
+		readOutputs(stdoutPipe, stderrPipe, e.logger, func(line string, isError bool) {
+			e.logOutputWithDryRunAnalysis(line, isError, dryRunResult, outputBuffer)
+		})
 	} else {
-		go e.logOutput(stdoutPipe, false)
-		go e.logOutput(stderrPipe, true)
+		readOutputs(stdoutPipe, stderrPipe, e.logger, e.logOutput)
 	}
 
 	cmdErr := cmd.Wait()
 
+	if outputBuffer != nil {
+		if err := outputBuffer.Close(); err != nil {
+			e.logger.Warnf("Failed to close pt-osc output file: %v", err)
+		}
+		dryRunResult.Summary = outputBuffer.String()
+	}
+
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return false, fmt.Errorf("pt-online-schema-change for table %s was terminated: %w", tableName, ctxErr)
+	}
+
 	// コマンドが異常終了した場合、またはエラーパターンが検出された場合はエラーとする
 	if cmdErr != nil || e.hasError {
 		var errorMsg string
@@ -491,41 +631,36 @@ func (e *PtOscExecutor) executeAlterInternal(tableName, alterStatement string, p
 	return true, nil
 }
 
-func (e *PtOscExecutor) logOutputWithDryRunAnalysis(r io.Reader, isError bool, result *DryRunResult) {
-	scanner := bufio.NewScanner(r)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// 全ての出力をSummaryに追加
-		if result.Summary != "" {
-			result.Summary += "\n"
-		}
-		if isError {
-			result.Summary += "[STDERR] " + line
-		} else {
-			result.Summary += "[STDOUT] " + line
-		}
+func (e *PtOscExecutor) logOutputWithDryRunAnalysis(line string, isError bool, result *DryRunResult, outputBuffer *output.Buffer) {
+	// 全ての出力をSummaryに追加
+	if isError {
+		outputBuffer.AddLine("[STDERR] " + line)
+	} else {
+		outputBuffer.AddLine("[STDOUT] " + line)
+	}
 
-		if e.containsErrorPattern(line) {
-			e.mutex.Lock()
-			e.hasError = true
-			e.errorMessages = append(e.errorMessages, line)
-			e.mutex.Unlock()
-		}
+	if e.containsErrorPattern(line) {
+		e.mutex.Lock()
+		e.hasError = true
+		e.errorMessages = append(e.errorMessages, line)
+		e.mutex.Unlock()
+	} else if e.containsWarningPattern(line) {
+		e.mutex.Lock()
+		result.Warnings = append(result.Warnings, strings.TrimSpace(line))
+		e.mutex.Unlock()
+	}
 
-		// 簡単な検証結果の設定
-		if strings.Contains(line, "Dry run complete") {
-			result.ValidationResult = "Dry run completed successfully"
-		} else if strings.Contains(line, "Starting a dry run") {
-			result.ValidationResult = "Dry run started"
-		}
+	// 簡単な検証結果の設定
+	if strings.Contains(line, "Dry run complete") {
+		result.ValidationResult = "Dry run completed successfully"
+	} else if strings.Contains(line, "Starting a dry run") {
+		result.ValidationResult = "Dry run started"
+	}
 
-		if isError {
-			e.logger.Errorf("[pt-osc] %s", line)
-		} else {
-			e.logger.Infof("[pt-osc] %s", line)
-		}
+	if isError {
+		e.logger.Errorf("[pt-osc] %s", line)
+	} else {
+		e.logger.Infof("[pt-osc] %s", line)
 	}
 }
 
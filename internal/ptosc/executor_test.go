@@ -1,9 +1,12 @@
 package ptosc
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/output"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -236,6 +239,84 @@ func TestBuildArgsWithPassword(t *testing.T) {
 			},
 			expectedPassword: "pass",
 		},
+		{
+			name:           "data dir",
+			tableName:      "users",
+			alterStatement: "ADD COLUMN foo INT",
+			ptOscConfig: config.PtOscConfig{
+				DataDir:       "/mnt/large-volume/mysql",
+				RemoveDataDir: boolPtr(false),
+			},
+			dsn:         "user@tcp(localhost:3306)/testdb",
+			forceDryRun: false,
+			expectedArgs: []string{
+				"--alter=ADD COLUMN foo INT",
+				"--data-dir=/mnt/large-volume/mysql",
+				"--no-remove-data-dir",
+				"--execute",
+				"h=localhost,P=3306,D=testdb,t=users,u=user",
+			},
+			expectedPassword: "",
+		},
+		{
+			name:           "remove data dir",
+			tableName:      "users",
+			alterStatement: "ADD COLUMN foo INT",
+			ptOscConfig: config.PtOscConfig{
+				RemoveDataDir: boolPtr(true),
+			},
+			dsn:         "user@tcp(localhost:3306)/testdb",
+			forceDryRun: false,
+			expectedArgs: []string{
+				"--alter=ADD COLUMN foo INT",
+				"--remove-data-dir",
+				"--execute",
+				"h=localhost,P=3306,D=testdb,t=users,u=user",
+			},
+			expectedPassword: "",
+		},
+		{
+			name:           "per-table recursion override",
+			tableName:      "users",
+			alterStatement: "ADD COLUMN foo INT",
+			ptOscConfig: config.PtOscConfig{
+				RecursionMethod: "processlist",
+				Tables: map[string]config.PtOscTableConfig{
+					"users":  {RecursionMethod: "dsn", RecursionDSN: "h=replica-admin,D=percona,t=dsns_<db>"},
+					"orders": {RecursionMethod: "none", AcknowledgeNoReplicaCheck: true},
+				},
+			},
+			dsn:         "user@tcp(localhost:3306)/testdb",
+			forceDryRun: false,
+			expectedArgs: []string{
+				"--alter=ADD COLUMN foo INT",
+				"--recursion-method=dsn",
+				"--recursion-dsn=h=replica-admin,D=percona,t=dsns_testdb",
+				"--execute",
+				"h=localhost,P=3306,D=testdb,t=users,u=user",
+			},
+			expectedPassword: "",
+		},
+		{
+			name:           "acknowledged recursion method none",
+			tableName:      "orders",
+			alterStatement: "ADD COLUMN foo INT",
+			ptOscConfig: config.PtOscConfig{
+				RecursionMethod: "processlist",
+				Tables: map[string]config.PtOscTableConfig{
+					"orders": {RecursionMethod: "none", AcknowledgeNoReplicaCheck: true},
+				},
+			},
+			dsn:         "user@tcp(localhost:3306)/testdb",
+			forceDryRun: false,
+			expectedArgs: []string{
+				"--alter=ADD COLUMN foo INT",
+				"--recursion-method=none",
+				"--execute",
+				"h=localhost,P=3306,D=testdb,t=orders,u=user",
+			},
+			expectedPassword: "",
+		},
 	}
 
 	for _, tt := range tests {
@@ -248,6 +329,80 @@ func TestBuildArgsWithPassword(t *testing.T) {
 	}
 }
 
+func TestBuildArgsRejectsUnacknowledgedRecursionNone(t *testing.T) {
+	executor := NewPtOscExecutor(logrus.New(), nil)
+
+	tests := []struct {
+		name        string
+		ptOscConfig config.PtOscConfig
+	}{
+		{name: "global", ptOscConfig: config.PtOscConfig{RecursionMethod: "none"}},
+		{
+			name: "per table",
+			ptOscConfig: config.PtOscConfig{
+				RecursionMethod: "processlist",
+				Tables:          map[string]config.PtOscTableConfig{"users": {RecursionMethod: "none"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := executor.BuildArgsWithPassword("users", "ADD COLUMN foo INT", tt.ptOscConfig, "user@tcp(localhost:3306)/testdb", false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "acknowledge_no_replica_check")
+		})
+	}
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestBuildArgsRejectsInvalidDataDir(t *testing.T) {
+	executor := NewPtOscExecutor(logrus.New(), nil)
+
+	tests := []struct {
+		name        string
+		ptOscConfig config.PtOscConfig
+		expectError string
+	}{
+		{
+			name:        "relative path",
+			ptOscConfig: config.PtOscConfig{DataDir: "mysql-data"},
+			expectError: "must be an absolute path",
+		},
+		{
+			name:        "combined with remove_data_dir",
+			ptOscConfig: config.PtOscConfig{DataDir: "/mnt/mysql", RemoveDataDir: boolPtr(true)},
+			expectError: "cannot be combined with remove_data_dir",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := executor.BuildArgsWithPassword("users", "ADD COLUMN foo INT", tt.ptOscConfig, "user@tcp(localhost:3306)/testdb", false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectError)
+		})
+	}
+}
+
+func TestBuildArgsWithPlugin(t *testing.T) {
+	executor := NewPtOscExecutor(logrus.New(), nil)
+	pluginPath := "../../examples/pt-osc-plugin/progress_plugin.pl"
+
+	args, _, err := executor.BuildArgsWithPassword("users", "ADD COLUMN foo INT",
+		config.PtOscConfig{Plugin: config.PtOscPluginConfig{Path: pluginPath}}, "user@tcp(localhost:3306)/testdb", false)
+	require.NoError(t, err)
+	assert.Contains(t, args, "--plugin="+pluginPath)
+
+	_, _, err = executor.BuildArgsWithPassword("users", "ADD COLUMN foo INT",
+		config.PtOscConfig{Plugin: config.PtOscPluginConfig{Path: "/nonexistent/plugin.pl"}}, "user@tcp(localhost:3306)/testdb", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pt_osc.plugin.path is not readable")
+}
+
 func TestBuildArgsWithAuroraMonitor(t *testing.T) {
 	logger := logrus.New()
 	executor := NewPtOscExecutor(logger, nil)
@@ -413,6 +568,49 @@ func TestContainsErrorPattern(t *testing.T) {
 	}
 }
 
+func TestContainsWarningPattern(t *testing.T) {
+	logger := logrus.New()
+	executor := NewPtOscExecutor(logger, nil)
+
+	tests := []struct {
+		name     string
+		line     string
+		expected bool
+	}{
+		{
+			name:     "Dropping primary key",
+			line:     "--alter contains 'DROP PRIMARY KEY'.  Dropping and altering the primary key can be dangerous, especially if the original table does not have other unique indexes.",
+			expected: true,
+		},
+		{
+			name:     "Adding unique key",
+			line:     "You are trying to add an unique key. This can result in data loss if the data is not unique.",
+			expected: true,
+		},
+		{
+			name:     "Foreign keys referencing the table",
+			line:     "Child tables:",
+			expected: true,
+		},
+		{
+			name:     "Dry run progress",
+			line:     "Starting a dry run.  `test`.`users` will not be altered.  Specify --execute instead of --dry-run to alter the table.",
+			expected: false,
+		},
+		{
+			name:     "No foreign keys",
+			line:     "No foreign keys reference `test`.`users`; ignoring --alter-foreign-keys-method.",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, executor.containsWarningPattern(tt.line))
+		})
+	}
+}
+
 func TestParseDSN(t *testing.T) {
 	logger := logrus.New()
 	executor := NewPtOscExecutor(logger, nil)
@@ -468,9 +666,22 @@ func TestParseDSN(t *testing.T) {
 			expectError: true,
 		},
 		{
-			name:        "invalid DSN - malformed host:port",
-			dsn:         "user:pass@tcp(localhost)/testdb",
-			expectError: true,
+			name:             "DSN without port uses the driver default",
+			dsn:              "user:pass@tcp(localhost)/testdb",
+			expectedHost:     "localhost",
+			expectedPort:     "3306",
+			expectedDatabase: "testdb",
+			expectedUser:     "user",
+			expectedPassword: "pass",
+		},
+		{
+			name:             "DSN with parameters",
+			dsn:              "user:pass@tcp(localhost:3306)/testdb?parseTime=true&loc=Asia%2FTokyo&collation=utf8mb4_bin",
+			expectedHost:     "localhost",
+			expectedPort:     "3306",
+			expectedDatabase: "testdb",
+			expectedUser:     "user",
+			expectedPassword: "pass",
 		},
 		{
 			name:        "invalid DSN - invalid port",
@@ -501,3 +712,54 @@ func TestParseDSN(t *testing.T) {
 		})
 	}
 }
+
+func TestNewCommandTerminatesOnContextTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := newCommand(ctx, "sleep", "10").Run()
+
+	assert.Error(t, err)
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestBuildArgsWithSessionVars(t *testing.T) {
+	logger := logrus.New()
+	executor := NewPtOscExecutor(logger, nil)
+
+	args, _, err := executor.BuildArgsWithPassword(
+		"users",
+		"ADD COLUMN foo INT",
+		config.PtOscConfig{SessionVars: map[string]string{
+			"wait_timeout":             "28800",
+			"innodb_lock_wait_timeout": "5",
+		}},
+		"user:pass@tcp(localhost:3306)/testdb",
+		false,
+	)
+	require.NoError(t, err)
+	assert.Contains(t, args, "--set-vars=innodb_lock_wait_timeout=5,wait_timeout=28800")
+}
+
+func TestReadOutputsCapturesTailBeforeWait(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	executor := NewPtOscExecutor(logger, nil)
+
+	cmd := newCommand(context.Background(), "sh", "-c", "for i in 1 2 3; do echo out$i; done; sleep 0.1; echo err1 >&2; sleep 0.1; echo last")
+	stdoutPipe, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	stderrPipe, err := cmd.StderrPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	outputBuffer := output.NewBuffer(output.DefaultHeadLines, output.DefaultTailLines)
+	readOutputs(stdoutPipe, stderrPipe, logger, func(line string, isError bool) {
+		executor.logOutputWithSummary(line, isError, outputBuffer)
+	})
+	require.NoError(t, cmd.Wait())
+
+	assert.Contains(t, outputBuffer.String(), "[STDOUT] out1\n[STDOUT] out2\n[STDOUT] out3\n[STDERR] err1\n[STDOUT] last")
+}
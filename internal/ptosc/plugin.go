@@ -0,0 +1,135 @@
+package ptosc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// PluginSocketEnv はプラグインに進み具合の送り先のソケットを伝える環境変数
+const PluginSocketEnv = "ALTERGUARD_PROGRESS_SOCKET"
+
+// PluginEvent はプラグインから1行の JSON で送られるイベント
+type PluginEvent struct {
+	// copy_start, chunk, copy_end, swap_start, swap_end のいずれか
+	Event string `json:"event"`
+	// chunk のとき、何番目のチャンクか
+	Chunk int64 `json:"chunk"`
+	// chunk のとき、そのチャンクでコピーした行数
+	Rows int64 `json:"rows"`
+	// chunk のとき、そのチャンクのコピーにかかった秒数
+	Seconds float64 `json:"seconds"`
+}
+
+// pluginListener はプラグインからの接続を Unix ドメインソケットで受け付け、届いたイベントを handle に渡す
+type pluginListener struct {
+	listener net.Listener
+	path     string
+	handle   func(PluginEvent)
+	logger   *logrus.Logger
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+func startPluginListener(path string, handle func(PluginEvent), logger *logrus.Logger) (*pluginListener, error) {
+	// 前回の実行が残したソケットがあると listen できない
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale plugin socket %s: %w", path, err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on plugin socket %s: %w", path, err)
+	}
+
+	l := &pluginListener{
+		listener: listener,
+		path:     path,
+		handle:   handle,
+		logger:   logger,
+		conns:    map[net.Conn]struct{}{},
+	}
+	l.wg.Add(1)
+	go l.accept()
+	return l, nil
+}
+
+func (l *pluginListener) Path() string {
+	return l.path
+}
+
+func (l *pluginListener) accept() {
+	defer l.wg.Done()
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		l.conns[conn] = struct{}{}
+		l.mu.Unlock()
+
+		l.wg.Add(1)
+		go l.serve(conn)
+	}
+}
+
+func (l *pluginListener) serve(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var event PluginEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			l.logger.Debugf("Ignoring invalid pt-osc plugin message %q: %v", scanner.Text(), err)
+			continue
+		}
+		l.handle(event)
+	}
+}
+
+// Close は待ち受けをやめ、残っている接続を閉じてから戻る
+func (l *pluginListener) Close() {
+	_ = l.listener.Close()
+	l.mu.Lock()
+	for conn := range l.conns {
+		_ = conn.Close()
+	}
+	l.mu.Unlock()
+	l.wg.Wait()
+	_ = os.Remove(l.path)
+}
+
+// startPluginListenerIfEnabled は plugin.path が指定されていれば、プラグインからの進み具合を受け取るソケットを開く
+func (e *PtOscExecutor) startPluginListenerIfEnabled(ptOscConfig config.PtOscConfig, forceDryRun bool) (*pluginListener, error) {
+	if ptOscConfig.Plugin.Path == "" || forceDryRun || ptOscConfig.DryRun {
+		return nil, nil
+	}
+
+	path := ptOscConfig.Plugin.SocketPath
+	if path == "" {
+		path = filepath.Join(os.TempDir(), fmt.Sprintf("alterguard-ptosc-%d.sock", os.Getpid()))
+	}
+	return startPluginListener(path, func(event PluginEvent) {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		if e.copyStats != nil {
+			e.copyStats.observePlugin(event)
+		}
+	}, e.logger)
+}
@@ -0,0 +1,85 @@
+package ptosc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginListenerReceivesEvents(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	executor := NewPtOscExecutor(logger, nil)
+	executor.copyStats = newCopyStatsCollector()
+	executor.copyStats.now = func() time.Time { return now }
+	executor.copyStats.stats.ApproximateRows = 4000
+
+	// Unix ドメインソケットのパスは長さに上限があるため短いディレクトリに作る
+	dir, err := os.MkdirTemp("", "agp")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "p.sock")
+
+	plugin, err := executor.startPluginListenerIfEnabled(config.PtOscConfig{
+		Plugin: config.PtOscPluginConfig{Path: "/opt/plugin.pl", SocketPath: socketPath},
+	}, false)
+	require.NoError(t, err)
+	require.NotNil(t, plugin)
+	assert.Equal(t, socketPath, plugin.Path())
+
+	conn, err := net.Dial("unix", socketPath)
+	require.NoError(t, err)
+	fmt.Fprintln(conn, `{"event":"copy_start"}`)
+	fmt.Fprintln(conn, `{"event":"chunk","chunk":1,"rows":1000,"seconds":0.4}`)
+	fmt.Fprintln(conn, `not json`)
+	fmt.Fprintln(conn, `{"event":"chunk","chunk":2,"rows":1000,"seconds":0.6}`)
+	require.NoError(t, conn.Close())
+
+	assert.Eventually(t, func() bool {
+		return executor.GetCopyStats().PluginChunks == 2
+	}, time.Second, 10*time.Millisecond)
+	plugin.Close()
+
+	stats := executor.GetCopyStats()
+	assert.Equal(t, int64(2000), stats.PluginRowsCopied)
+	assert.Equal(t, 0.6, stats.LastChunkSeconds)
+	assert.Equal(t, 50, stats.ProgressPercent)
+	assert.Equal(t, now, stats.CopyStartedAt)
+	_, err = os.Stat(socketPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPluginListenerDisabled(t *testing.T) {
+	executor := NewPtOscExecutor(logrus.New(), nil)
+
+	plugin, err := executor.startPluginListenerIfEnabled(config.PtOscConfig{}, false)
+	assert.NoError(t, err)
+	assert.Nil(t, plugin)
+
+	plugin, err = executor.startPluginListenerIfEnabled(config.PtOscConfig{Plugin: config.PtOscPluginConfig{Path: "/opt/plugin.pl"}}, true)
+	assert.NoError(t, err)
+	assert.Nil(t, plugin)
+}
+
+func TestCopyStatsCollectorPluginCopyEnd(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	collector := newCopyStatsCollector()
+	collector.now = func() time.Time { return now }
+
+	collector.observePlugin(PluginEvent{Event: "copy_start"})
+	now = now.Add(time.Minute)
+	collector.observePlugin(PluginEvent{Event: "copy_end"})
+
+	assert.Equal(t, time.Minute, collector.stats.CopyDuration)
+	assert.Equal(t, 100, collector.stats.ProgressPercent)
+}
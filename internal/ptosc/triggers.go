@@ -0,0 +1,21 @@
+package ptosc
+
+import (
+	"regexp"
+)
+
+// maxTriggerPrefixLength は pt-osc がトリガー名の接頭辞を切り詰める長さ。
+// MySQL のトリガー名の上限 64 文字から "_del" などの接尾辞の分を引いたもの
+const maxTriggerPrefixLength = 60
+
+var nonWordRe = regexp.MustCompile(`[^0-9A-Za-z_]`)
+
+// TriggerNames は pt-osc が database.table に作るトリガーの名前を、pt-osc と同じ規則で返す。
+// 接頭辞 "pt_osc_<db>_<table>" の英数字と _ 以外の文字を _ に置き換え、60 文字を超えたら切り詰める
+func TriggerNames(database, table string) []string {
+	prefix := nonWordRe.ReplaceAllString("pt_osc_"+database+"_"+table, "_")
+	if len(prefix) > maxTriggerPrefixLength {
+		prefix = prefix[:maxTriggerPrefixLength]
+	}
+	return []string{prefix + "_del", prefix + "_upd", prefix + "_ins"}
+}
@@ -0,0 +1,51 @@
+package ptosc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriggerNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		database string
+		table    string
+		want     []string
+	}{
+		{
+			name:     "short names",
+			database: "app",
+			table:    "users",
+			want:     []string{"pt_osc_app_users_del", "pt_osc_app_users_upd", "pt_osc_app_users_ins"},
+		},
+		{
+			name:     "non-word characters are replaced",
+			database: "app-prod",
+			table:    "user$events",
+			want:     []string{"pt_osc_app_prod_user_events_del", "pt_osc_app_prod_user_events_upd", "pt_osc_app_prod_user_events_ins"},
+		},
+		{
+			name:     "long names are truncated to 60 characters before the suffix",
+			database: "analytics_warehouse_production",
+			table:    "customer_subscription_billing_events",
+			want: []string{
+				"pt_osc_analytics_warehouse_production_customer_subscription__del",
+				"pt_osc_analytics_warehouse_production_customer_subscription__upd",
+				"pt_osc_analytics_warehouse_production_customer_subscription__ins",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TriggerNames(tt.database, tt.table)
+			assert.Equal(t, tt.want, got)
+			for _, name := range got {
+				assert.LessOrEqual(t, len(name), 64)
+				assert.True(t, strings.HasPrefix(name, "pt_osc_"))
+			}
+		})
+	}
+}
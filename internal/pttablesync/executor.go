@@ -0,0 +1,141 @@
+package pttablesync
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/dsn"
+	"github.com/sirupsen/logrus"
+)
+
+// Executor は source テーブルの内容に合わせて destination テーブルを pt-table-sync で修復する
+type Executor interface {
+	Sync(ctx context.Context, source, destination string, columns []string, syncConfig config.SwapRemediationConfig, dsn string) error
+}
+
+// ctxがキャンセルされた時にSIGTERMを送ってから待つ猶予
+const processWaitDelay = 30 * time.Second
+
+type PtTableSyncExecutor struct {
+	logger *logrus.Logger
+}
+
+func NewPtTableSyncExecutor(logger *logrus.Logger) *PtTableSyncExecutor {
+	return &PtTableSyncExecutor{logger: logger}
+}
+
+func (e *PtTableSyncExecutor) Sync(ctx context.Context, source, destination string, columns []string, syncConfig config.SwapRemediationConfig, dsn string) error {
+	args, err := BuildArgs(source, destination, columns, syncConfig, dsn)
+	if err != nil {
+		return fmt.Errorf("failed to build pt-table-sync arguments: %w", err)
+	}
+
+	maskedArgs := make([]string, len(args))
+	copy(maskedArgs, args)
+	for i, arg := range maskedArgs {
+		if strings.HasPrefix(arg, "--password=") {
+			maskedArgs[i] = "--password=[masked]"
+		}
+	}
+	e.logger.Infof("Executing pt-table-sync command: pt-table-sync %s", strings.Join(maskedArgs, " "))
+
+	cmd := exec.CommandContext(ctx, "pt-table-sync", args...) // #nosec G204
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = processWaitDelay
+
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start command: %w", err)
+	}
+
+	// 出力を読み切ってから Wait する。先に Wait すると末尾の出力が失われる
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		e.logOutput(stdoutPipe, false)
+	}()
+	go func() {
+		defer wg.Done()
+		e.logOutput(stderrPipe, true)
+	}()
+	wg.Wait()
+
+	cmdErr := cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("pt-table-sync for table %s was terminated: %w", destination, ctxErr)
+	}
+	if cmdErr != nil && !differencesSynced(cmdErr) {
+		return fmt.Errorf("pt-table-sync failed for table %s: %w", destination, cmdErr)
+	}
+
+	e.logger.Infof("pt-table-sync completed successfully for table %s", destination)
+	return nil
+}
+
+// differencesSynced は終了コード 2 (差分があり、--execute で修復した) かどうかを判定する
+func differencesSynced(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == 2
+}
+
+func (e *PtTableSyncExecutor) logOutput(r io.Reader, isError bool) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if isError {
+			e.logger.Errorf("[pt-table-sync] %s", scanner.Text())
+		} else {
+			e.logger.Infof("[pt-table-sync] %s", scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		e.logger.Warnf("Failed to read pt-table-sync output: %v", err)
+		_, _ = io.Copy(io.Discard, r)
+	}
+}
+
+// BuildArgs は source から destination への同期の引数を組み立てる。
+// destination の DSN は t= 以外を source から引き継ぐ。columns は ALTER の前後で共通のカラムに限って比較するために渡す
+func BuildArgs(source, destination string, columns []string, syncConfig config.SwapRemediationConfig, rawDSN string) ([]string, error) {
+	parsed, err := dsn.Parse(rawDSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DSN: %w", err)
+	}
+
+	args := []string{
+		"--execute",
+		fmt.Sprintf("--user=%s", parsed.User),
+	}
+	if parsed.Password != "" {
+		args = append(args, fmt.Sprintf("--password=%s", parsed.Password))
+	}
+	if len(columns) > 0 {
+		args = append(args, fmt.Sprintf("--columns=%s", strings.Join(columns, ",")))
+	}
+	if syncConfig.ChunkSize > 0 {
+		args = append(args, fmt.Sprintf("--chunk-size=%d", syncConfig.ChunkSize))
+	}
+	args = append(args,
+		fmt.Sprintf("h=%s,P=%s,D=%s,t=%s", parsed.Host, parsed.Port, parsed.Database, source),
+		fmt.Sprintf("t=%s", destination),
+	)
+	return args, nil
+}
@@ -0,0 +1,26 @@
+package pttablesync
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildArgs(t *testing.T) {
+	args, err := BuildArgs("users", "_users_new", []string{"id", "name"}, config.SwapRemediationConfig{ChunkSize: 500}, "user:pass@tcp(db.example.com:3306)/app?parseTime=true")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"--execute",
+		"--user=user",
+		"--password=pass",
+		"--columns=id,name",
+		"--chunk-size=500",
+		"h=db.example.com,P=3306,D=app,t=users",
+		"t=_users_new",
+	}, args)
+
+	_, err = BuildArgs("users", "_users_new", nil, config.SwapRemediationConfig{}, "user@unix(/tmp/mysql.sock)/app")
+	assert.Error(t, err)
+}
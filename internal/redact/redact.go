@@ -0,0 +1,158 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ModeElide はリテラルを ? に置き換える
+	ModeElide = "elide"
+	// ModeHash はリテラルを HashKey を鍵にした HMAC-SHA256 に置き換える。同じ値は同じハッシュになるため、値を出さずに比較できる
+	ModeHash = "hash"
+)
+
+// Policy は値の伏せ方。Mode が空なら伏せない
+type Policy struct {
+	Mode string
+	// ModeHash で使う秘密の鍵。鍵がなければ、ありそうな ID やメールアドレスからハッシュを作って照合できてしまう
+	HashKey string
+}
+
+// 以降のリテラルを伏せる対象にするキーワード
+var scopeKeywords = map[string]bool{
+	"WHERE":  true,
+	"HAVING": true,
+	"VALUES": true,
+	"SET":    true,
+}
+
+// Validate は redaction の設定を確認する。mode が空なら伏せないことを表す
+func Validate(policy Policy) error {
+	switch policy.Mode {
+	case "", ModeElide:
+		return nil
+	case ModeHash:
+		if policy.HashKey == "" {
+			return fmt.Errorf("redaction.hash_key is required when redaction.mode is hash")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid redaction.mode %q (must be elide or hash)", policy.Mode)
+	}
+}
+
+// Query はクエリやコマンドのうち、WHERE・HAVING・VALUES・SET (pt-archiver の --where= を含む) より後にある
+// 文字列と数値のリテラルを伏せる。ALTER の列定義 (型の長さや DEFAULT) は読めるように残す。
+// policy.Mode が空ならそのまま返す。
+func Query(query string, policy Policy) string {
+	if policy.Mode == "" {
+		return query
+	}
+
+	var b strings.Builder
+	inScope := false
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := quotedEnd(query, i)
+			if inScope {
+				b.WriteString(replacement(query[i:end], policy))
+			} else {
+				b.WriteString(query[i:end])
+			}
+			i = end
+		case c == '`':
+			end := skipPast(query, i+1, "`")
+			b.WriteString(query[i:end])
+			i = end
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := skipPast(query, i+2, "*/")
+			b.WriteString(query[i:end])
+			i = end
+		case c == '-' && i+2 < len(query) && query[i+1] == '-' && isWordStart(query[i+2]):
+			// pt-archiver などのコマンドのオプション。次のオプションで対象から外れ、--where= なら再び対象になる
+			inScope = false
+			b.WriteString("--")
+			i += 2
+		case isWordStart(c):
+			start := i
+			for i < len(query) && isWordChar(query[i]) {
+				i++
+			}
+			word := query[start:i]
+			if scopeKeywords[strings.ToUpper(word)] {
+				inScope = true
+			}
+			b.WriteString(word)
+		case isDigit(c):
+			start := i
+			for i < len(query) && (isWordChar(query[i]) || query[i] == '.') {
+				i++
+			}
+			if inScope {
+				b.WriteString(replacement(query[start:i], policy))
+			} else {
+				b.WriteString(query[start:i])
+			}
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// skipPast は from 以降で最初の terminator の直後の位置を返す。閉じられていなければ末尾を返す
+func skipPast(s string, from int, terminator string) int {
+	end := strings.Index(s[from:], terminator)
+	if end < 0 {
+		return len(s)
+	}
+	return from + end + len(terminator)
+}
+
+// quotedEnd は start の引用符で始まる文字列リテラルの終わりの次の位置を返す。
+// バックスラッシュのエスケープと、引用符を2つ重ねたエスケープを扱う。
+func quotedEnd(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func replacement(literal string, policy Policy) string {
+	if policy.Mode == ModeHash {
+		mac := hmac.New(sha256.New, []byte(policy.HashKey))
+		mac.Write([]byte(literal))
+		return "<redacted:" + hex.EncodeToString(mac.Sum(nil))[:8] + ">"
+	}
+	return "?"
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWordChar(c byte) bool {
+	return isWordStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,98 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		mode     string
+		expected string
+	}{
+		{
+			name:     "disabled",
+			query:    "DELETE FROM users WHERE email = 'a@example.com'",
+			expected: "DELETE FROM users WHERE email = 'a@example.com'",
+		},
+		{
+			name:     "where clause",
+			query:    "DELETE FROM users WHERE email = 'a@example.com' AND id IN (1, 22) AND t1.x = \"it's\"",
+			mode:     ModeElide,
+			expected: "DELETE FROM users WHERE email = ? AND id IN (?, ?) AND t1.x = ?",
+		},
+		{
+			name:     "alter definitions are kept",
+			query:    "ALTER TABLE users ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'full name'",
+			mode:     ModeElide,
+			expected: "ALTER TABLE users ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'full name'",
+		},
+		{
+			name:     "insert and update",
+			query:    "INSERT INTO t (a, b) VALUES ('x', 10); UPDATE t SET a = 'it''s' WHERE b = 0x1F",
+			mode:     ModeElide,
+			expected: "INSERT INTO t (a, b) VALUES (?, ?); UPDATE t SET a = ? WHERE b = ?",
+		},
+		{
+			name:     "pt-archiver command",
+			query:    "pt-archiver --source=h=HOST,P=PORT,D=DATABASE,t=_users_old --where=customer_id=12345 --limit=1000 --purge",
+			mode:     ModeElide,
+			expected: "pt-archiver --source=h=HOST,P=PORT,D=DATABASE,t=_users_old --where=customer_id=? --limit=1000 --purge",
+		},
+		{
+			name:     "identifiers and comments are kept",
+			query:    "SELECT * FROM `t 'x'` WHERE /* 'note' */ `col1` = 'v'",
+			mode:     ModeElide,
+			expected: "SELECT * FROM `t 'x'` WHERE /* 'note' */ `col1` = ?",
+		},
+		{
+			name:     "unterminated literal",
+			query:    "DELETE FROM t WHERE a = 'abc",
+			mode:     ModeElide,
+			expected: "DELETE FROM t WHERE a = ?",
+		},
+		{
+			name:     "hash",
+			query:    "DELETE FROM t WHERE a = 'x' OR a = 'x'",
+			mode:     ModeHash,
+			expected: "DELETE FROM t WHERE a = <redacted:" + hashOf("'x'") + "> OR a = <redacted:" + hashOf("'x'") + ">",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Query(tt.query, Policy{Mode: tt.mode, HashKey: "secret"}))
+		})
+	}
+}
+
+func hashOf(literal string) string {
+	r := replacement(literal, Policy{Mode: ModeHash, HashKey: "secret"})
+	return r[len("<redacted:") : len(r)-1]
+}
+
+func TestHashUsesKey(t *testing.T) {
+	// 鍵のない SHA-256 では、候補の値からハッシュを作って照合できてしまう
+	unkeyed := sha256.Sum256([]byte("'alice@example.com'"))
+	hashed := replacement("'alice@example.com'", Policy{Mode: ModeHash, HashKey: "secret"})
+	assert.NotEqual(t, "<redacted:"+hex.EncodeToString(unkeyed[:])[:8]+">", hashed)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("'alice@example.com'"))
+	assert.Equal(t, "<redacted:"+hex.EncodeToString(mac.Sum(nil))[:8]+">", hashed)
+	assert.NotEqual(t, hashed, replacement("'alice@example.com'", Policy{Mode: ModeHash, HashKey: "other"}))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(Policy{}))
+	assert.NoError(t, Validate(Policy{Mode: ModeElide}))
+	assert.NoError(t, Validate(Policy{Mode: ModeHash, HashKey: "secret"}))
+	assert.ErrorContains(t, Validate(Policy{Mode: ModeHash}), "redaction.hash_key is required")
+	assert.Error(t, Validate(Policy{Mode: "mask"}))
+}
@@ -0,0 +1,168 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expression は5フィールド(分 時 日 月 曜日)の cron 式。
+// *, 範囲(1-5), リスト(1,15), ステップ(*/10, 0-30/5) と @hourly 等のマクロに対応する。
+type Expression struct {
+	minutes, hours, days, months, weekdays uint64
+	// 日と曜日の両方が指定されている場合は、cron と同じくどちらかに一致すれば実行する
+	daysRestricted, weekdaysRestricted bool
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Parse は cron 式を解釈する
+func Parse(spec string) (*Expression, error) {
+	spec = strings.TrimSpace(spec)
+	if macro, ok := macros[spec]; ok {
+		spec = macro
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", spec, len(parts))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, part := range parts {
+		b, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+		}
+		bits[i] = b
+	}
+
+	// 曜日の7は日曜日(0)として扱う
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &Expression{
+		minutes:            bits[0],
+		hours:              bits[1],
+		days:               bits[2],
+		months:             bits[3],
+		weekdays:           bits[4],
+		daysRestricted:     parts[2] != "*",
+		weekdaysRestricted: parts[4] != "*",
+	}, nil
+}
+
+func parseField(part string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(part, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			rangePart = item[:i]
+			var err error
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", f.name, item)
+			}
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = parseValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(bounds[1], f); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range in %s field: %q", f.name, item)
+			}
+		default:
+			value, err := parseValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			start = value
+			if !strings.Contains(item, "/") {
+				end = value
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, f field) (int, error) {
+	value, err := strconv.Atoi(s)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("%s must be between %d and %d: %q", f.name, f.min, f.max, s)
+	}
+	return value, nil
+}
+
+// Next は after より後で式に一致する最初の時刻(分単位)を返す。
+// 該当する時刻がない場合(2月31日など)はゼロ値を返す。
+func (e *Expression) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// 閏年の2月29日を含め、5年先まで探せば必ず見つかる
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if e.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !e.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (e *Expression) matchDay(t time.Time) bool {
+	dayMatch := e.days&(1<<uint(t.Day())) != 0
+	weekdayMatch := e.weekdays&(1<<uint(t.Weekday())) != 0
+	if e.daysRestricted && e.weekdaysRestricted {
+		return dayMatch || weekdayMatch
+	}
+	return dayMatch && weekdayMatch
+}
@@ -0,0 +1,61 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	}
+
+	for _, spec := range tests {
+		t.Run(spec, func(t *testing.T) {
+			_, err := Parse(spec)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2024-01-10 は水曜日
+	base := time.Date(2024, 1, 10, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{name: "every minute", spec: "* * * * *", from: base, expected: time.Date(2024, 1, 10, 10, 31, 0, 0, time.UTC)},
+		{name: "daily macro", spec: "@daily", from: base, expected: time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{name: "step", spec: "*/20 * * * *", from: base, expected: time.Date(2024, 1, 10, 10, 40, 0, 0, time.UTC)},
+		{name: "list and range", spec: "0 2,14 * * 1-5", from: base, expected: time.Date(2024, 1, 10, 14, 0, 0, 0, time.UTC)},
+		{name: "weekly on sunday as 7", spec: "30 3 * * 7", from: base, expected: time.Date(2024, 1, 14, 3, 30, 0, 0, time.UTC)},
+		{name: "day of month or weekday", spec: "0 0 15 * 5", from: base, expected: time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{name: "next month", spec: "0 0 1 * *", from: base, expected: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", spec: "0 0 29 2 *", from: base, expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "exactly on schedule moves forward", spec: "0 * * * *", from: time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC), expected: time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)},
+		{name: "impossible date", spec: "0 0 31 2 *", from: base, expected: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := Parse(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, expression.Next(tt.from))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package schedule
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Job はスケジューラが定期的に実行するジョブ
+type Job struct {
+	Name       string
+	Expression *Expression
+	// 予定時刻から最大でこの時間だけランダムに遅らせる。複数のジョブや複数環境の実行が重ならないようにする
+	Jitter time.Duration
+}
+
+// RunFunc はジョブを1回実行する
+type RunFunc func(ctx context.Context, job Job) error
+
+// Scheduler はジョブを予定時刻に1つずつ順番に実行する。
+// 同時に実行しないため、前のジョブが長引いた場合は後続のジョブが遅れて実行される。
+type Scheduler struct {
+	jobs     []Job
+	location *time.Location
+	run      RunFunc
+	logger   *logrus.Logger
+
+	now    func() time.Time
+	after  func(time.Duration) <-chan time.Time
+	jitter func(time.Duration) time.Duration
+}
+
+func NewScheduler(jobs []Job, location *time.Location, run RunFunc, logger *logrus.Logger) *Scheduler {
+	if location == nil {
+		location = time.Local
+	}
+	return &Scheduler{
+		jobs:     jobs,
+		location: location,
+		run:      run,
+		logger:   logger,
+		now:      time.Now,
+		after:    time.After,
+		jitter: func(max time.Duration) time.Duration {
+			if max <= 0 {
+				return 0
+			}
+			return rand.N(max) // #nosec G404 -- 実行時刻をずらすだけで暗号用途ではない
+		},
+	}
+}
+
+// Run は ctx がキャンセルされるまでジョブを実行し続ける。ジョブの失敗では止まらない。
+func (s *Scheduler) Run(ctx context.Context) error {
+	next := make([]time.Time, len(s.jobs))
+	now := s.now().In(s.location)
+	for i, job := range s.jobs {
+		next[i] = job.Expression.Next(now)
+		s.logger.Infof("Scheduled job %s: next run at %s", job.Name, next[i].Format(time.RFC3339))
+	}
+
+	for {
+		index := s.earliest(next)
+		if index < 0 {
+			s.logger.Info("No scheduled jobs have an upcoming run")
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		job := s.jobs[index]
+
+		wait := next[index].Sub(s.now()) + s.jitter(job.Jitter)
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.after(wait):
+			}
+		}
+
+		s.logger.Infof("Running scheduled job %s", job.Name)
+		if err := s.run(ctx, job); err != nil {
+			s.logger.Errorf("Scheduled job %s failed: %v", job.Name, err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// 長時間のジョブの間に過ぎた予定はまとめて1回分とし、現在時刻以降の次回予定を計算する
+		from := next[index]
+		if now := s.now().In(s.location); now.After(from) {
+			from = now
+		}
+		next[index] = job.Expression.Next(from)
+		if !next[index].IsZero() {
+			s.logger.Infof("Scheduled job %s: next run at %s", job.Name, next[index].Format(time.RFC3339))
+		}
+	}
+}
+
+func (s *Scheduler) earliest(next []time.Time) int {
+	index := -1
+	for i, t := range next {
+		if t.IsZero() {
+			continue
+		}
+		if index < 0 || t.Before(next[index]) {
+			index = i
+		}
+	}
+	return index
+}
@@ -0,0 +1,102 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock は待ち時間の分だけ即座に時計を進める
+type fakeClock struct {
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestSchedulerRunsJobsInOrder(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	hourly, err := Parse("0 * * * *")
+	require.NoError(t, err)
+	halfPast, err := Parse("30 * * * *")
+	require.NoError(t, err)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 10, 10, 15, 0, 0, time.UTC)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ran []string
+	var ranAt []time.Time
+	scheduler := NewScheduler([]Job{
+		{Name: "hourly", Expression: hourly, Jitter: time.Minute},
+		{Name: "half-past", Expression: halfPast},
+	}, time.UTC, func(ctx context.Context, job Job) error {
+		ran = append(ran, job.Name)
+		ranAt = append(ranAt, clock.now)
+		if len(ran) == 3 {
+			cancel()
+		}
+		return errors.New("job failures do not stop the scheduler")
+	}, logger)
+	scheduler.now = clock.Now
+	scheduler.after = clock.After
+	scheduler.jitter = func(max time.Duration) time.Duration { return max / 2 }
+
+	err = scheduler.Run(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"half-past", "hourly", "half-past"}, ran)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 1, 10, 10, 30, 0, 0, time.UTC),
+		time.Date(2024, 1, 10, 11, 0, 30, 0, time.UTC),
+		time.Date(2024, 1, 10, 11, 30, 0, 0, time.UTC),
+	}, ranAt)
+}
+
+func TestSchedulerSkipsRunsMissedDuringLongJob(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	everyTen, err := Parse("*/10 * * * *")
+	require.NoError(t, err)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 10, 10, 5, 0, 0, time.UTC)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ranAt []time.Time
+	scheduler := NewScheduler([]Job{{Name: "purge", Expression: everyTen}}, time.UTC, func(ctx context.Context, job Job) error {
+		ranAt = append(ranAt, clock.now)
+		if len(ranAt) == 1 {
+			// 35分かかったことにする
+			clock.now = clock.now.Add(35 * time.Minute)
+		} else {
+			cancel()
+		}
+		return nil
+	}, logger)
+	scheduler.now = clock.Now
+	scheduler.after = clock.After
+
+	_ = scheduler.Run(ctx)
+
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 1, 10, 10, 10, 0, 0, time.UTC),
+		time.Date(2024, 1, 10, 10, 50, 0, 0, time.UTC),
+	}, ranAt)
+}
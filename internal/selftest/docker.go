@@ -0,0 +1,81 @@
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	containerPassword = "alterguard-selftest"
+	containerDatabase = "alterguard_selftest"
+)
+
+// Container は selftest のために起動した MySQL コンテナ
+type Container struct {
+	ID  string
+	DSN string
+}
+
+// Docker は docker CLI で使い捨ての MySQL コンテナを起動・削除する
+type Docker struct {
+	run func(ctx context.Context, args ...string) (string, error)
+}
+
+// NewDocker は PATH 上の docker コマンドを使う Docker を返す
+func NewDocker() (*Docker, error) {
+	path, err := exec.LookPath("docker")
+	if err != nil {
+		return nil, fmt.Errorf("docker command is required for selftest: %w", err)
+	}
+	return &Docker{
+		run: func(ctx context.Context, args ...string) (string, error) {
+			var stdout, stderr bytes.Buffer
+			cmd := exec.CommandContext(ctx, path, args...) // #nosec G204
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); err != nil {
+				return "", fmt.Errorf("docker %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+			}
+			return strings.TrimSpace(stdout.String()), nil
+		},
+	}, nil
+}
+
+// StartMySQL はイメージからコンテナを起動し、ホストの空きポートに公開された MySQL の DSN を返す。
+// 起動直後は初期化中のため、接続できるまで待つのは呼び出し側の責任。
+func (d *Docker) StartMySQL(ctx context.Context, image string) (*Container, error) {
+	id, err := d.run(ctx, "run", "-d", "--rm",
+		"-e", "MYSQL_ROOT_PASSWORD="+containerPassword,
+		"-e", "MYSQL_DATABASE="+containerDatabase,
+		"-p", "127.0.0.1::3306",
+		image)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := d.run(ctx, "port", id, "3306/tcp")
+	if err != nil {
+		_ = d.Remove(context.WithoutCancel(ctx), id)
+		return nil, err
+	}
+	// IPv4 と IPv6 の両方が返ることがあるため先頭の行を使う
+	address = strings.TrimSpace(strings.SplitN(address, "\n", 2)[0])
+	if address == "" {
+		_ = d.Remove(context.WithoutCancel(ctx), id)
+		return nil, fmt.Errorf("could not determine published port of container %s", id)
+	}
+
+	return &Container{
+		ID:  id,
+		DSN: fmt.Sprintf("root:%s@tcp(%s)/%s", containerPassword, address, containerDatabase),
+	}, nil
+}
+
+// Remove はコンテナを停止して削除する
+func (d *Docker) Remove(ctx context.Context, id string) error {
+	_, err := d.run(ctx, "rm", "-f", id)
+	return err
+}
@@ -0,0 +1,76 @@
+package selftest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Step は selftest の1手順
+type Step struct {
+	Name string
+	Run  func() error
+}
+
+// Result は手順の実行結果。前の手順が失敗した場合は実行せず Skipped になる。
+type Result struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+	Skipped  bool
+}
+
+// RunSteps は手順を順番に実行する。手順は前の手順の結果に依存するため、失敗したらそこで止める。
+func RunSteps(steps []Step, logger *logrus.Logger) []Result {
+	results := make([]Result, 0, len(steps))
+	failed := false
+	for _, step := range steps {
+		if failed {
+			results = append(results, Result{Name: step.Name, Skipped: true})
+			continue
+		}
+
+		logger.Infof("[selftest] %s", step.Name)
+		start := time.Now()
+		err := step.Run()
+		results = append(results, Result{Name: step.Name, Duration: time.Since(start), Err: err})
+		if err != nil {
+			logger.Errorf("[selftest] %s failed: %v", step.Name, err)
+			failed = true
+		}
+	}
+	return results
+}
+
+// Passed はすべての手順が成功したかを返す
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if result.Err != nil || result.Skipped {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatResults は手順ごとの結果を表形式のテキストにする
+func FormatResults(results []Result) string {
+	var b strings.Builder
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			fmt.Fprintf(&b, "SKIP  %s\n", result.Name)
+		case result.Err != nil:
+			fmt.Fprintf(&b, "FAIL  %s (%s): %v\n", result.Name, result.Duration.Round(time.Millisecond), result.Err)
+		default:
+			fmt.Fprintf(&b, "PASS  %s (%s)\n", result.Name, result.Duration.Round(time.Millisecond))
+		}
+	}
+	if Passed(results) {
+		b.WriteString("selftest passed")
+	} else {
+		b.WriteString("selftest failed")
+	}
+	return b.String()
+}
@@ -0,0 +1,174 @@
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/task"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	TableName = "selftest_users"
+	// 変更を確認するため照合順序を持つ文字列型のカラムを追加する
+	addedColumn = "nickname"
+
+	insertBatchSize = 500
+)
+
+// ManagerFunc は queries を実行する task manager を作る
+type ManagerFunc func(queries []string, dryRun bool) *task.Manager
+
+// PrepareConfig はユーザーの共通設定を selftest のコンテナで実行できるように上書きする。
+// pt-osc のオプションなどはそのまま使い、イメージと toolkit の組み合わせで通ることを確認する。
+func PrepareConfig(common config.CommonConfig) config.CommonConfig {
+	// 行数の推定値に関わらず必ず pt-osc を使う
+	common.PtOscThreshold = -1
+
+	// コンテナにはレプリカがない
+	common.PtOsc.RecursionMethod = "none"
+	common.PtOsc.RecursionDSN = ""
+	common.PtOsc.AcknowledgeNoReplicaCheck = true
+	common.PtOsc.Tables = nil
+	common.PtOsc.AuroraReplicaCheck = config.AuroraReplicaCheckConfig{}
+	common.PtOsc.DataDir = ""
+	common.PtOsc.RemoveDataDir = nil
+
+	// swap と cleanup を alterguard 側で行う
+	common.PtOsc.DryRun = false
+	common.PtOsc.NoSwapTables = true
+	common.PtOsc.NoDropTriggers = true
+	common.PtOsc.NoDropNewTable = true
+	common.PtOsc.NoDropOldTable = true
+	common.PtOsc.AutoSwap = config.AutoSwapConfig{}
+
+	common.ConnectionCheck.Enabled = false
+	common.PtArchiver.Enabled = false
+	common.SwapSoak = config.SwapSoakConfig{}
+	common.SwapFreshness = config.SwapFreshnessConfig{Disabled: true}
+	common.Events = config.EventsConfig{}
+	return common
+}
+
+// WaitForMySQL はコンテナの MySQL が接続を受け付けるようになるまで待つ
+func WaitForMySQL(ctx context.Context, dsn string, dbConfig config.DatabaseConfig, logger *logrus.Logger, timeout time.Duration) (*database.MySQLClient, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		client, err := database.NewMySQLClientWithConfig(dsn, logger, dbConfig)
+		if err == nil {
+			return client, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("MySQL did not become ready within %s: %w", timeout, err)
+		}
+		logger.Debugf("Waiting for MySQL to become ready: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// Scenario はテーブルの作成から pt-osc の dry run・実行、swap、cleanup までの手順を返す
+func Scenario(db database.Client, newManager ManagerFunc, rows int) []Step {
+	alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s VARCHAR(32) NULL", TableName, addedColumn)
+
+	return []Step{
+		{
+			Name: fmt.Sprintf("create %s with %d rows", TableName, rows),
+			Run:  func() error { return seedTable(db, rows) },
+		},
+		{
+			Name: "pt-osc dry run",
+			Run:  func() error { return newManager([]string{alter}, true).ExecuteAllTasks() },
+		},
+		{
+			Name: "pt-osc",
+			Run:  func() error { return newManager([]string{alter}, false).ExecuteAllTasks() },
+		},
+		{
+			Name: "swap",
+			Run:  func() error { return newManager(nil, false).SwapTable(TableName) },
+		},
+		{
+			Name: "cleanup triggers and old table",
+			Run: func() error {
+				manager := newManager(nil, false)
+				if err := manager.CleanupTriggers(TableName); err != nil {
+					return err
+				}
+				return manager.CleanupOldTable(TableName)
+			},
+		},
+		{
+			Name: "verify",
+			Run:  func() error { return verify(db, rows) },
+		},
+	}
+}
+
+func seedTable(db database.Client, rows int) error {
+	createSQL := fmt.Sprintf(`CREATE TABLE %s (
+		id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(64) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`, TableName)
+	if err := db.ExecuteAlter(createSQL); err != nil {
+		return err
+	}
+
+	for start := 0; start < rows; start += insertBatchSize {
+		end := min(start+insertBatchSize, rows)
+		values := make([]string, 0, end-start)
+		for i := start; i < end; i++ {
+			values = append(values, fmt.Sprintf("('user-%d')", i+1))
+		}
+		if err := db.ExecuteAlter(fmt.Sprintf("INSERT INTO %s (name) VALUES %s", TableName, strings.Join(values, ", "))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func verify(db database.Client, rows int) error {
+	info, err := db.GetTableCharsetInfo(TableName)
+	if err != nil {
+		return err
+	}
+	if _, ok := info.Columns[addedColumn]; !ok {
+		return fmt.Errorf("column %s was not added to %s", addedColumn, TableName)
+	}
+
+	count, err := db.GetTableRowCountForSwap(TableName)
+	if err != nil {
+		return err
+	}
+	if count != int64(rows) {
+		return fmt.Errorf("%s has %d rows after the migration, expected %d", TableName, count, rows)
+	}
+
+	for _, leftover := range []string{TableName + "_old", fmt.Sprintf("_%s_new", TableName)} {
+		exists, err := db.TableExists(leftover)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%s was not cleaned up", leftover)
+		}
+	}
+
+	triggers, err := db.GetTriggerNames(TableName)
+	if err != nil {
+		return err
+	}
+	if len(triggers) > 0 {
+		return fmt.Errorf("triggers were not cleaned up: %s", strings.Join(triggers, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package selftest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartMySQL(t *testing.T) {
+	var calls []string
+	docker := &Docker{run: func(ctx context.Context, args ...string) (string, error) {
+		calls = append(calls, strings.Join(args, " "))
+		switch args[0] {
+		case "run":
+			return "abc123", nil
+		case "port":
+			return "127.0.0.1:49153\n[::1]:49153", nil
+		}
+		return "", nil
+	}}
+
+	container, err := docker.StartMySQL(context.Background(), "mysql:8.0")
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc123", container.ID)
+	assert.Equal(t, "root:alterguard-selftest@tcp(127.0.0.1:49153)/alterguard_selftest", container.DSN)
+	assert.Equal(t, []string{
+		"run -d --rm -e MYSQL_ROOT_PASSWORD=alterguard-selftest -e MYSQL_DATABASE=alterguard_selftest -p 127.0.0.1::3306 mysql:8.0",
+		"port abc123 3306/tcp",
+	}, calls)
+}
+
+func TestStartMySQLRemovesContainerWhenPortIsUnknown(t *testing.T) {
+	var removed bool
+	docker := &Docker{run: func(ctx context.Context, args ...string) (string, error) {
+		switch args[0] {
+		case "run":
+			return "abc123", nil
+		case "port":
+			return "", errors.New("no public port")
+		case "rm":
+			removed = true
+		}
+		return "", nil
+	}}
+
+	_, err := docker.StartMySQL(context.Background(), "mysql:8.0")
+	assert.ErrorContains(t, err, "no public port")
+	assert.True(t, removed)
+}
+
+func TestRunSteps(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var ran []string
+	step := func(name string, err error) Step {
+		return Step{Name: name, Run: func() error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+
+	results := RunSteps([]Step{step("seed", nil), step("pt-osc", errors.New("pt-online-schema-change: not found")), step("swap", nil)}, logger)
+
+	assert.Equal(t, []string{"seed", "pt-osc"}, ran)
+	assert.False(t, Passed(results))
+	assert.True(t, results[2].Skipped)
+
+	output := FormatResults(results)
+	assert.Contains(t, output, "PASS  seed")
+	assert.Contains(t, output, "FAIL  pt-osc")
+	assert.Contains(t, output, "pt-online-schema-change: not found")
+	assert.Contains(t, output, "SKIP  swap")
+	assert.True(t, strings.HasSuffix(output, "selftest failed"))
+
+	passed := RunSteps([]Step{step("seed", nil)}, logger)
+	assert.True(t, Passed(passed))
+	assert.True(t, strings.HasSuffix(FormatResults(passed), "selftest passed"))
+}
+
+func TestPrepareConfig(t *testing.T) {
+	common := config.CommonConfig{
+		PtOsc: config.PtOscConfig{
+			Charset:         "utf8mb4",
+			ChunkSize:       500,
+			RecursionMethod: "dsn=D=percona,t=dsns",
+			NoSwapTables:    false,
+			Tables:          map[string]config.PtOscTableConfig{"users": {RecursionMethod: "processlist"}},
+		},
+		PtOscThreshold:  1000000,
+		ConnectionCheck: config.ConnectionCheckConfig{Enabled: true},
+		SwapSoak:        config.SwapSoakConfig{Duration: "10m"},
+	}
+
+	prepared := PrepareConfig(common)
+
+	assert.Equal(t, int64(-1), prepared.PtOscThreshold)
+	assert.Equal(t, "none", prepared.PtOsc.RecursionMethod)
+	assert.True(t, prepared.PtOsc.AcknowledgeNoReplicaCheck)
+	assert.Nil(t, prepared.PtOsc.Tables)
+	assert.True(t, prepared.PtOsc.NoSwapTables)
+	assert.True(t, prepared.PtOsc.NoDropTriggers)
+	assert.False(t, prepared.ConnectionCheck.Enabled)
+	assert.Empty(t, prepared.SwapSoak.Duration)
+	assert.True(t, prepared.SwapFreshness.Disabled)
+	// pt-osc の調整用の設定はそのまま使う
+	assert.Equal(t, "utf8mb4", prepared.PtOsc.Charset)
+	assert.Equal(t, 500, prepared.PtOsc.ChunkSize)
+}
@@ -1,10 +1,16 @@
 package slack
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/pyama86/alterguard/internal/redact"
+	"github.com/pyama86/alterguard/internal/sqlfmt"
 	"github.com/sirupsen/logrus"
 	"github.com/slack-go/slack"
 )
@@ -15,11 +21,13 @@ type Notifier interface {
 	NotifyFailure(taskName, tableName string, rowCount int64, err error) error
 	NotifyWarning(taskName, tableName string, message string) error
 	NotifyStartWithQuery(taskName, tableName, query string, rowCount int64) error
+	NotifyStartWithQueryAndStorage(taskName, tableName, query string, rowCount int64, storage string) error
 	NotifySuccessWithQuery(taskName, tableName, query string, rowCount int64, duration time.Duration) error
 	NotifyFailureWithQuery(taskName, tableName, query string, rowCount int64, err error) error
+	NotifySuccessWithQueryAndAlgorithm(taskName, tableName, query string, rowCount int64, duration time.Duration, algorithm string) error
 	NotifySuccessWithQueryAndLog(taskName, tableName, query string, rowCount int64, duration time.Duration, ptOscLog string) error
 	NotifyFailureWithQueryAndLog(taskName, tableName, query string, rowCount int64, err error, ptOscLog string) error
-	NotifyPtOscCompletionWithNewTableCount(taskName, tableName string, originalRowCount, newRowCount int64, duration time.Duration, ptOscLog string) error
+	NotifyPtOscCompletionWithNewTableCount(taskName, tableName string, originalRowCount, newRowCount int64, duration time.Duration, ptOscLog string, performance *CopyPerformance) error
 	NotifyDryRunResult(taskName, tableName string, result *DryRunResult, duration time.Duration) error
 	NotifyConnectionCheckFailure(taskName, tableName, username string) error
 	NotifyTriggerCleanupStart(taskName, tableName string, triggers []string) error
@@ -27,8 +35,27 @@ type Notifier interface {
 	NotifyTriggerCleanupFailure(taskName, tableName string, triggers []string, err error) error
 	NotifyPtOscPreCheckFailure(taskName, tableName string) error
 	NotifyAllTasksStart(totalQueries int) error
+	NotifyAllTasksStartWithFindings(totalQueries int, findings []string) error
 	NotifyAllTasksSuccess(totalQueries int, duration time.Duration) error
 	NotifyAllTasksFailure(totalQueries int, err error) error
+	NotifyShardSummary(pattern string, tableCount int, methodCounts map[string]int, duration time.Duration) error
+	NotifyTimeout(taskName, tableName string, timeout time.Duration) error
+	NotifyExecutionTimePage(taskName, tableName, message string) error
+	NotifyWatchProgress(tableName string, copiedRows, totalRows int64, newTableSizeMB float64, elapsed time.Duration) error
+	NotifyArchiverProgress(tableName string, deletedRows, totalRows int64, elapsed time.Duration) error
+	NotifyPurgeSuccess(taskName, tableName, command string, deletedRows int64, duration time.Duration, archiverLog string) error
+	NotifyWarmupProgress(tableName, indexName string, done, total int, rows int64, elapsed time.Duration) error
+	NotifyKillBlockersSummary(tableName string, killed, protected, failed []string) error
+	NotifyFollowUpCommands(commands []string) error
+	NotifySwapReverted(tableName, reason string, revertErr error) error
+	NotifyBenchmarkResult(tableName, summary string, duration time.Duration) error
+	NotifyImpactEstimate(summary string) error
+	NotifyShadowValidation(schema, summary string, failed bool, duration time.Duration) error
+	NotifyDryRunPreChecks(operation, tableName, summary string, failed bool) error
+	NotifyPlanForApproval(path string, commands []string) error
+	NotifyReplicaLag(taskName, tableName, phase string, lags []ReplicaLag) error
+	NotifyConfigSummary(summary string) error
+	NotifyCrash(runID, reason, stack string) error
 }
 
 type DryRunResult struct {
@@ -40,10 +67,41 @@ type DryRunResult struct {
 	Summary          string
 }
 
+// CopyPerformance は pt-osc の行コピーの速度とスロットリングの状況
+type CopyPerformance struct {
+	RowsPerSecond float64
+	CopyDuration  time.Duration
+	// 遅れの原因 (replica lag, server load など)。スロットリングがなければ空
+	Throttled string
+}
+
+// ReplicaLag はフェーズの開始時と終了時に測ったレプリカ1台の遅延(秒)。測れなかった場合は nil
+type ReplicaLag struct {
+	Name  string
+	Start *float64
+	End   *float64
+}
+
+// 通知の重要度。Slack の色 (good/warning/danger) に対応する
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Sink は Slack に送るのと同じ通知を別の経路にも送る通知先
+type Sink interface {
+	Send(severity, text string) error
+}
+
 type SlackNotifier struct {
 	client      *slack.Client
 	logger      *logrus.Logger
 	environment string
+	operator    string
+	sinks       []Sink
+	// 通知に載せるクエリのリテラルを伏せる方式。Mode が空なら伏せない
+	redaction redact.Policy
 }
 
 func NewSlackNotifier(logger *logrus.Logger) (*SlackNotifier, error) {
@@ -66,6 +124,11 @@ func NewSlackNotifierWithEnvironment(logger *logrus.Logger, environment string)
 	}, nil
 }
 
+// NewDisabledNotifier は SLACK_WEBHOOK_URL に関わらず何も送らない Notifier を返す。selftest のように通知が不要な実行に使う。
+func NewDisabledNotifier(logger *logrus.Logger) *SlackNotifier {
+	return &SlackNotifier{logger: logger}
+}
+
 func (n *SlackNotifier) formatTitle(title string) string {
 	if n.environment != "" {
 		return fmt.Sprintf("%s [%s]", title, n.environment)
@@ -77,12 +140,78 @@ func (n *SlackNotifier) FormatTitle(title string) string {
 	return n.formatTitle(title)
 }
 
+// SetOperator は開始通知に載せる実行者を設定する
+func (n *SlackNotifier) SetOperator(operator string) {
+	n.operator = operator
+}
+
+// AddSink は Slack と同じ通知を送る通知先を追加する
+func (n *SlackNotifier) AddSink(sink Sink) {
+	n.sinks = append(n.sinks, sink)
+}
+
+// SetQueryRedaction は通知に載せるクエリやコマンドの WHERE 句などの値を伏せる方式を設定する。ログには元のまま出す
+func (n *SlackNotifier) SetQueryRedaction(policy redact.Policy) {
+	n.redaction = policy
+}
+
+// redactQuery は Slack の書式のためにクエリを囲んだバッククォートを外してから値を伏せ、元の囲みに戻す
+func (n *SlackNotifier) redactQuery(query string) string {
+	if n.redaction.Mode == "" {
+		return query
+	}
+	inner := strings.TrimLeft(query, "`")
+	prefix := query[:len(query)-len(inner)]
+	body := strings.TrimRight(inner, "`")
+	suffix := inner[len(body):]
+	return prefix + redact.Query(body, n.redaction) + suffix
+}
+
+var quotedSegment = regexp.MustCompile("`{1,3}([^`]+)`{1,3}")
+
+// formatQuery は通知に載せるクエリを整形する。呼び出し側がバッククォートで囲んだ部分のうち、SQL はキーワードを大文字にして
+// 句ごとに改行したコードブロックにし、pt-osc のコマンドなどはそのままインラインで載せる。どちらも redaction.mode に従って値を伏せる
+func (n *SlackNotifier) formatQuery(query string) string {
+	format := func(body string) string {
+		if n.redaction.Mode != "" {
+			body = redact.Query(body, n.redaction)
+		}
+		if sqlfmt.IsSQL(body) {
+			return "```\n" + sqlfmt.Format(body) + "\n```"
+		}
+		return "`" + body + "`"
+	}
+
+	if !strings.Contains(query, "`") {
+		return format(query)
+	}
+	return quotedSegment.ReplaceAllStringFunc(query, func(segment string) string {
+		return format(strings.Trim(segment, "`"))
+	})
+}
+
+func (n *SlackNotifier) redactQueries(queries []string) []string {
+	redacted := make([]string, len(queries))
+	for i, query := range queries {
+		redacted[i] = n.redactQuery(query)
+	}
+	return redacted
+}
+
+// withOperator は開始通知の末尾に実行者を付け加える
+func (n *SlackNotifier) withOperator(message string) string {
+	if n.operator == "" {
+		return message
+	}
+	return fmt.Sprintf("%s\nOperator: %s", message, n.operator)
+}
+
 func (n *SlackNotifier) NotifyStart(taskName, tableName string, rowCount int64) error {
 	title := n.formatTitle("🚀 Schema change started")
 	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nRow count: %d",
 		title, taskName, tableName, rowCount)
 
-	return n.sendMessage(message, "good")
+	return n.sendMessage(n.withOperator(message), "good")
 }
 
 func (n *SlackNotifier) NotifySuccess(taskName, tableName string, rowCount int64, duration time.Duration) error {
@@ -112,15 +241,50 @@ func (n *SlackNotifier) NotifyWarning(taskName, tableName string, message string
 func (n *SlackNotifier) NotifyStartWithQuery(taskName, tableName, query string, rowCount int64) error {
 	title := n.formatTitle("🚀 Schema change started")
 	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nRow count: %d\nQuery: %s",
-		title, taskName, tableName, rowCount, query)
+		title, taskName, tableName, rowCount, n.formatQuery(query))
+
+	return n.sendMessage(n.withOperator(message), "good")
+}
+
+// NotifyStartWithQueryAndStorage は開始の通知に、承認者が確認できるようテーブルのストレージエンジンと行フォーマットを含める
+func (n *SlackNotifier) NotifyStartWithQueryAndStorage(taskName, tableName, query string, rowCount int64, storage string) error {
+	title := n.formatTitle("🚀 Schema change started")
+	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nRow count: %d\nStorage: %s\nQuery: %s",
+		title, taskName, tableName, rowCount, storage, n.formatQuery(query))
+
+	return n.sendMessage(n.withOperator(message), "good")
+}
+
+// NotifyReplicaLag は重いフェーズの前後のレプリカ遅延を通知する
+func (n *SlackNotifier) NotifyReplicaLag(taskName, tableName, phase string, lags []ReplicaLag) error {
+	title := n.formatTitle("🐢 Replica lag")
+	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nPhase: %s", title, taskName, tableName, phase)
+	for _, lag := range lags {
+		message += fmt.Sprintf("\n• %s: %s → %s", lag.Name, formatLagSeconds(lag.Start), formatLagSeconds(lag.End))
+	}
 
 	return n.sendMessage(message, "good")
 }
 
+func formatLagSeconds(seconds *float64) string {
+	if seconds == nil {
+		return "unavailable"
+	}
+	return fmt.Sprintf("%.0fs", *seconds)
+}
+
 func (n *SlackNotifier) NotifySuccessWithQuery(taskName, tableName, query string, rowCount int64, duration time.Duration) error {
 	title := n.formatTitle("✅ Schema change completed successfully")
 	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nRow count: %d\nDuration: %s\nQuery: %s",
-		title, taskName, tableName, rowCount, duration.String(), query)
+		title, taskName, tableName, rowCount, duration.String(), n.formatQuery(query))
+
+	return n.sendMessage(message, "good")
+}
+
+func (n *SlackNotifier) NotifySuccessWithQueryAndAlgorithm(taskName, tableName, query string, rowCount int64, duration time.Duration, algorithm string) error {
+	title := n.formatTitle("✅ Schema change completed successfully")
+	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nRow count: %d\nDuration: %s\nAlgorithm: %s\nQuery: %s",
+		title, taskName, tableName, rowCount, duration.String(), algorithm, n.formatQuery(query))
 
 	return n.sendMessage(message, "good")
 }
@@ -128,7 +292,7 @@ func (n *SlackNotifier) NotifySuccessWithQuery(taskName, tableName, query string
 func (n *SlackNotifier) NotifyFailureWithQuery(taskName, tableName, query string, rowCount int64, err error) error {
 	title := n.formatTitle("❌ Schema change failed")
 	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nRow count: %d\nError: %s\nQuery: %s",
-		title, taskName, tableName, rowCount, err.Error(), query)
+		title, taskName, tableName, rowCount, err.Error(), n.formatQuery(query))
 
 	return n.sendMessage(message, "danger")
 }
@@ -136,7 +300,7 @@ func (n *SlackNotifier) NotifyFailureWithQuery(taskName, tableName, query string
 func (n *SlackNotifier) NotifySuccessWithQueryAndLog(taskName, tableName, query string, rowCount int64, duration time.Duration, ptOscLog string) error {
 	title := n.formatTitle("✅ Schema change completed successfully")
 	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nRow count: %d\nDuration: %s\nQuery: %s",
-		title, taskName, tableName, rowCount, duration.String(), query)
+		title, taskName, tableName, rowCount, duration.String(), n.formatQuery(query))
 
 	if ptOscLog != "" {
 		message += "\n\n📋 pt-osc Output:\n```\n" + ptOscLog + "\n```"
@@ -148,7 +312,7 @@ func (n *SlackNotifier) NotifySuccessWithQueryAndLog(taskName, tableName, query
 func (n *SlackNotifier) NotifyFailureWithQueryAndLog(taskName, tableName, query string, rowCount int64, err error, ptOscLog string) error {
 	title := n.formatTitle("❌ Schema change failed")
 	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nRow count: %d\nError: %s\nQuery: %s",
-		title, taskName, tableName, rowCount, err.Error(), query)
+		title, taskName, tableName, rowCount, err.Error(), n.formatQuery(query))
 
 	if ptOscLog != "" {
 		message += "\n\n📋 pt-osc Output:\n```\n" + ptOscLog + "\n```"
@@ -157,11 +321,23 @@ func (n *SlackNotifier) NotifyFailureWithQueryAndLog(taskName, tableName, query
 	return n.sendMessage(message, "danger")
 }
 
-func (n *SlackNotifier) NotifyPtOscCompletionWithNewTableCount(taskName, tableName string, originalRowCount, newRowCount int64, duration time.Duration, ptOscLog string) error {
+func (n *SlackNotifier) NotifyPtOscCompletionWithNewTableCount(taskName, tableName string, originalRowCount, newRowCount int64, duration time.Duration, ptOscLog string, performance *CopyPerformance) error {
 	title := n.formatTitle("✅ pt-osc completed successfully")
 	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nOriginal row count: %d\nNew table row count: %d\nDuration: %s",
 		title, taskName, tableName, originalRowCount, newRowCount, duration.String())
 
+	if performance != nil {
+		message += "\n\n📈 Performance:"
+		if performance.RowsPerSecond > 0 {
+			message += fmt.Sprintf("\nCopy rate: %.0f rows/sec (copy took %s)", performance.RowsPerSecond, performance.CopyDuration.Round(time.Second))
+		}
+		throttled := performance.Throttled
+		if throttled == "" {
+			throttled = "none"
+		}
+		message += "\nThrottled by: " + throttled
+	}
+
 	if ptOscLog != "" {
 		message += "\n\n📋 pt-osc Output:\n```\n" + ptOscLog + "\n```"
 	}
@@ -181,6 +357,10 @@ func (n *SlackNotifier) NotifyDryRunResult(taskName, tableName string, result *D
 			title, taskName, tableName, duration.String())
 	}
 
+	if len(result.Warnings) > 0 {
+		message += "\n\n⚠️ Warnings:\n• " + strings.Join(result.Warnings, "\n• ")
+	}
+
 	if result.Summary != "" {
 		message += "\n\n📋 pt-osc Output:\n```\n" + result.Summary + "\n```"
 	}
@@ -206,7 +386,7 @@ func (n *SlackNotifier) NotifyTriggerCleanupStart(taskName, tableName string, tr
 	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nTriggers: %v",
 		title, taskName, tableName, triggers)
 
-	return n.sendMessage(message, "good")
+	return n.sendMessage(n.withOperator(message), "good")
 }
 
 func (n *SlackNotifier) NotifyTriggerCleanupSuccess(taskName, tableName string, triggers []string, duration time.Duration) error {
@@ -237,7 +417,15 @@ func (n *SlackNotifier) NotifyAllTasksStart(totalQueries int) error {
 	title := n.formatTitle("🚀 All tasks started")
 	message := fmt.Sprintf("%s\nTotal queries: %d", title, totalQueries)
 
-	return n.sendMessage(message, "good")
+	return n.sendMessage(n.withOperator(message), "good")
+}
+
+// NotifyAllTasksStartWithFindings は実行前の確認で見つかった注意点を添えて全体の開始を通知する
+func (n *SlackNotifier) NotifyAllTasksStartWithFindings(totalQueries int, findings []string) error {
+	title := n.formatTitle("🚀 All tasks started")
+	message := fmt.Sprintf("%s\nTotal queries: %d\n⚠️ Findings:\n• %s", title, totalQueries, strings.Join(findings, "\n• "))
+
+	return n.sendMessage(n.withOperator(message), "warning")
 }
 
 func (n *SlackNotifier) NotifyAllTasksSuccess(totalQueries int, duration time.Duration) error {
@@ -254,7 +442,245 @@ func (n *SlackNotifier) NotifyAllTasksFailure(totalQueries int, err error) error
 	return n.sendMessage(message, "danger")
 }
 
+func (n *SlackNotifier) NotifyShardSummary(pattern string, tableCount int, methodCounts map[string]int, duration time.Duration) error {
+	title := n.formatTitle("📊 Sharded tables completed")
+	message := fmt.Sprintf("%s\nPattern: %s\nTables: %d\nTotal duration: %s",
+		title, pattern, tableCount, duration.String())
+
+	methods := make([]string, 0, len(methodCounts))
+	for method := range methodCounts {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		message += fmt.Sprintf("\n%s: %d", method, methodCounts[method])
+	}
+
+	return n.sendMessage(message, "good")
+}
+
+func (n *SlackNotifier) NotifyBenchmarkResult(tableName, summary string, duration time.Duration) error {
+	title := n.formatTitle("⏱️ Copy benchmark completed")
+	message := fmt.Sprintf("%s\nTable: %s\nDuration: %s\n```\n%s\n```",
+		title, tableName, duration.String(), summary)
+
+	return n.sendMessage(message, "good")
+}
+
+func (n *SlackNotifier) NotifyImpactEstimate(summary string) error {
+	title := n.formatTitle("📊 Estimated impact of this run")
+	message := fmt.Sprintf("%s\n```\n%s\n```", title, summary)
+
+	return n.sendMessage(message, "good")
+}
+
+func (n *SlackNotifier) NotifyConfigSummary(summary string) error {
+	title := n.formatTitle("⚙️ Configuration of this run")
+	message := fmt.Sprintf("%s\n```\n%s\n```", title, summary)
+
+	return n.sendMessage(message, "good")
+}
+
+// maxCrashStackLength は異常終了の通知に載せるスタックトレースの長さの上限。先頭 (panic した箇所に近い方) を残す
+const maxCrashStackLength = 3000
+
+// NotifyCrash は panic による異常終了を、理由とスタックトレースを付けて通知する
+func (n *SlackNotifier) NotifyCrash(runID, reason, stack string) error {
+	title := n.formatTitle("💥 alterguard crashed")
+	if len(stack) > maxCrashStackLength {
+		stack = stack[:maxCrashStackLength] + "\n... (truncated, see the log for the full stack trace)"
+	}
+	message := n.withOperator(fmt.Sprintf("%s\nRun ID: %s\nPanic: %s", title, runID, reason))
+	message = fmt.Sprintf("%s\nTables may be left mid-migration; check them with `alterguard status <table>`.\n```\n%s\n```", message, stack)
+
+	return n.sendMessage(message, "danger")
+}
+
+func (n *SlackNotifier) NotifyShadowValidation(schema, summary string, failed bool, duration time.Duration) error {
+	title := n.formatTitle("🧪 Shadow schema validation passed")
+	color := "good"
+	if failed {
+		title = n.formatTitle("🚨 Shadow schema validation failed")
+		color = "danger"
+	}
+	message := fmt.Sprintf("%s\nShadow schema: %s\nDuration: %s\n```\n%s\n```",
+		title, schema, duration.String(), summary)
+
+	return n.sendMessage(message, color)
+}
+
+func (n *SlackNotifier) NotifyDryRunPreChecks(operation, tableName, summary string, failed bool) error {
+	title := n.formatTitle(fmt.Sprintf("🧪 Dry run: %s pre-checks passed", operation))
+	color := "good"
+	if failed {
+		title = n.formatTitle(fmt.Sprintf("🚨 Dry run: %s would fail its pre-checks", operation))
+		color = "danger"
+	}
+	message := fmt.Sprintf("%s\nTable: %s\n```\n%s\n```", title, tableName, summary)
+
+	return n.sendMessage(message, color)
+}
+
+func (n *SlackNotifier) NotifyTimeout(taskName, tableName string, timeout time.Duration) error {
+	title := n.formatTitle("⏰ Schema change timed out")
+	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nTimeout: %s\nThe running process was terminated and cleanup was attempted.",
+		title, taskName, tableName, timeout.String())
+
+	return n.sendMessage(message, "danger")
+}
+
+// NotifyExecutionTimePage は閾値の4倍を超えて実行が続いていることを、チャンネル全体への呼び出し付きで通知する
+func (n *SlackNotifier) NotifyExecutionTimePage(taskName, tableName, message string) error {
+	title := n.formatTitle("🚨 Operation is taking far longer than expected")
+	text := fmt.Sprintf("<!channel> %s\nTask: %s\nTable: %s\n%s", title, taskName, tableName, message)
+
+	return n.sendMessage(text, "danger")
+}
+
+func (n *SlackNotifier) NotifyWatchProgress(tableName string, copiedRows, totalRows int64, newTableSizeMB float64, elapsed time.Duration) error {
+	title := n.formatTitle("👀 Schema change in progress")
+	progress := "unknown"
+	if totalRows > 0 {
+		progress = fmt.Sprintf("%.1f%%", float64(copiedRows)*100/float64(totalRows))
+	}
+	message := fmt.Sprintf("%s\nTable: %s\nCopied rows: %d / %d (%s)\nNew table size: %.1f MB\nElapsed: %s",
+		title, tableName, copiedRows, totalRows, progress, newTableSizeMB, elapsed.Round(time.Second).String())
+
+	return n.sendMessage(message, "good")
+}
+
+func (n *SlackNotifier) NotifyArchiverProgress(tableName string, deletedRows, totalRows int64, elapsed time.Duration) error {
+	title := n.formatTitle("🧹 Purge in progress")
+	progress := "unknown"
+	eta := "unknown"
+	if totalRows > 0 {
+		progress = fmt.Sprintf("%.1f%%", float64(deletedRows)*100/float64(totalRows))
+	}
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(deletedRows) / elapsed.Seconds()
+	}
+	if totalRows > 0 && rate > 0 {
+		remaining := totalRows - deletedRows
+		if remaining < 0 {
+			remaining = 0
+		}
+		eta = (time.Duration(float64(remaining)/rate) * time.Second).Round(time.Second).String()
+	}
+	message := fmt.Sprintf("%s\nTable: %s\nDeleted rows: %d / %d (%s)\nRate: %.0f rows/s\nElapsed: %s\nETA: %s",
+		title, tableName, deletedRows, totalRows, progress, rate, elapsed.Round(time.Second).String(), eta)
+
+	return n.sendMessage(message, "good")
+}
+
+// NotifyPurgeSuccess は pt-archiver の完了を通知する。削除した行数はテーブルの行数と区別して載せる
+func (n *SlackNotifier) NotifyPurgeSuccess(taskName, tableName, command string, deletedRows int64, duration time.Duration, archiverLog string) error {
+	title := n.formatTitle("✅ Purge completed successfully")
+	message := fmt.Sprintf("%s\nTask: %s\nTable: %s\nDeleted rows: %d\nDuration: %s\nCommand: %s",
+		title, taskName, tableName, deletedRows, duration.String(), n.formatQuery(command))
+
+	if archiverLog != "" {
+		message += "\n\n📋 pt-archiver Output:\n```\n" + archiverLog + "\n```"
+	}
+
+	return n.sendMessage(message, "good")
+}
+
+func (n *SlackNotifier) NotifyWarmupProgress(tableName, indexName string, done, total int, rows int64, elapsed time.Duration) error {
+	title := n.formatTitle("🔥 Warming up new table before swap")
+	message := fmt.Sprintf("%s\nTable: %s\nIndex: %s (%d rows)\nProgress: %d / %d indexes\nElapsed: %s",
+		title, tableName, indexName, rows, done, total, elapsed.Round(time.Second).String())
+
+	return n.sendMessage(message, "good")
+}
+
+func (n *SlackNotifier) NotifyKillBlockersSummary(tableName string, killed, protected, failed []string) error {
+	title := n.formatTitle("🔪 Blocking sessions killed")
+	color := "warning"
+	if len(failed) > 0 {
+		title = n.formatTitle("🔪 Failed to kill some blocking sessions")
+		color = "danger"
+	}
+
+	message := fmt.Sprintf("%s\nTable: %s\nKilled: %d", title, tableName, len(killed))
+	message += formatSessionList(killed)
+	if len(protected) > 0 {
+		message += fmt.Sprintf("\nSkipped (protected users): %d", len(protected))
+		message += formatSessionList(protected)
+	}
+	if len(failed) > 0 {
+		message += fmt.Sprintf("\nFailed: %d", len(failed))
+		message += formatSessionList(failed)
+	}
+
+	return n.sendMessage(n.withOperator(message), color)
+}
+
+func (n *SlackNotifier) NotifyFollowUpCommands(commands []string) error {
+	title := n.formatTitle("📝 Follow-up commands")
+	message := fmt.Sprintf("%s\nRun the following after verifying the new tables:\n```\n%s\n```",
+		title, strings.Join(n.redactQueries(commands), "\n"))
+
+	return n.sendMessage(message, "good")
+}
+
+func (n *SlackNotifier) NotifyPlanForApproval(path string, commands []string) error {
+	title := n.formatTitle("📋 Plan ready for approval")
+	message := fmt.Sprintf("%s\nPlan file: %s\nThe following will be executed by `run --from-plan %s`:\n```\n%s\n```",
+		title, path, path, strings.Join(n.redactQueries(commands), "\n"))
+
+	return n.sendMessage(message, "good")
+}
+
+func (n *SlackNotifier) NotifySwapReverted(tableName, reason string, revertErr error) error {
+	if revertErr != nil {
+		title := n.formatTitle("🚨 Swap health check failed and revert FAILED")
+		message := fmt.Sprintf("%s\nTable: %s\nReason: %s\nRevert error: %s\nManual intervention is required.",
+			title, tableName, reason, revertErr.Error())
+		return n.sendMessage(message, "danger")
+	}
+
+	title := n.formatTitle("↩️ Swap reverted after failed health check")
+	message := fmt.Sprintf("%s\nTable: %s\nReason: %s\nThe original table is back in place; the new table is _%s_new again. Writes made during the soak period remain only in _%s_new.",
+		title, tableName, reason, tableName, tableName)
+	return n.sendMessage(message, "danger")
+}
+
+func formatSessionList(sessions []string) string {
+	var result string
+	for _, session := range sessions {
+		result += "\n• " + session
+	}
+	return result
+}
+
+// severityFromColor は Slack の色を通知の重要度に変換する
+func severityFromColor(color string) string {
+	switch color {
+	case "danger":
+		return SeverityError
+	case "warning":
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
 func (n *SlackNotifier) sendMessage(text, color string) error {
+	var errs []error
+	for _, sink := range n.sinks {
+		if err := sink.Send(severityFromColor(color), text); err != nil {
+			n.logger.Errorf("Failed to send notification: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	if err := n.postWebhook(text, color); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (n *SlackNotifier) postWebhook(text, color string) error {
 	if n.client == nil {
 		return nil
 	}
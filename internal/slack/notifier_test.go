@@ -2,9 +2,11 @@ package slack
 
 import (
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/pyama86/alterguard/internal/redact"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -87,10 +89,41 @@ func TestNotificationMessages(t *testing.T) {
 				return notifier.NotifyWarning("test_task", "test_table", "test warning message")
 			},
 		},
+		{
+			name: "notify start with query and storage",
+			testFunc: func() error {
+				return notifier.NotifyStartWithQueryAndStorage("pt-osc", "test_table", "ALTER TABLE test_table ADD COLUMN c INT", 1000, "InnoDB, ROW_FORMAT=Dynamic")
+			},
+		},
+		{
+			name: "notify replica lag",
+			testFunc: func() error {
+				lag := 3.0
+				return notifier.NotifyReplicaLag("pt-osc", "test_table", "pt-osc", []ReplicaLag{{Name: "reader-1", Start: &lag, End: &lag}, {Name: "reader-2"}})
+			},
+		},
+		{
+			name: "notify crash",
+			testFunc: func() error {
+				return notifier.NotifyCrash("20250101-000000-1a2b3c4d", "runtime error: invalid memory address or nil pointer dereference", strings.Repeat("goroutine 1 [running]:\n", 200))
+			},
+		},
+		{
+			name: "notify config summary",
+			testFunc: func() error {
+				return notifier.NotifyConfigSummary("Environment: production\nDry run: false")
+			},
+		},
+		{
+			name: "notify purge success",
+			testFunc: func() error {
+				return notifier.NotifyPurgeSuccess("pt-archiver", "test_table_old", "pt-archiver --where=1=1", 1000, 5*time.Minute, "pt-archiver output log")
+			},
+		},
 		{
 			name: "notify pt-osc completion with new table count",
 			testFunc: func() error {
-				return notifier.NotifyPtOscCompletionWithNewTableCount("pt-osc", "test_table", 1000, 1000, 5*time.Minute, "pt-osc output log")
+				return notifier.NotifyPtOscCompletionWithNewTableCount("pt-osc", "test_table", 1000, 1000, 5*time.Minute, "pt-osc output log", &CopyPerformance{RowsPerSecond: 3.3, CopyDuration: 5 * time.Minute})
 			},
 		},
 	}
@@ -105,3 +138,121 @@ func TestNotificationMessages(t *testing.T) {
 		})
 	}
 }
+
+func TestWithOperator(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	t.Setenv("SLACK_WEBHOOK_URL", "")
+	notifier, err := NewSlackNotifier(logger)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "started", notifier.withOperator("started"))
+
+	notifier.SetOperator("alice (deploy@job-1)")
+	assert.Equal(t, "started\nOperator: alice (deploy@job-1)", notifier.withOperator("started"))
+}
+
+type recordingSink struct {
+	severities []string
+	texts      []string
+	err        error
+}
+
+func (s *recordingSink) Send(severity, text string) error {
+	s.severities = append(s.severities, severity)
+	s.texts = append(s.texts, text)
+	return s.err
+}
+
+func TestSinksReceiveNotificationsWithoutWebhook(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	notifier := NewDisabledNotifier(logger)
+	sink := &recordingSink{}
+	notifier.AddSink(sink)
+
+	assert.NoError(t, notifier.NotifyStart("task", "users", 10))
+	assert.NoError(t, notifier.NotifyWarning("task", "users", "careful"))
+	assert.NoError(t, notifier.NotifyFailure("task", "users", 10, errors.New("boom")))
+
+	assert.Equal(t, []string{SeverityInfo, SeverityWarning, SeverityError}, sink.severities)
+	assert.Contains(t, sink.texts[1], "careful")
+
+	sink.err = errors.New("smtp down")
+	assert.Error(t, notifier.NotifyWarning("task", "users", "careful"))
+}
+
+func TestQueryRedaction(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	notifier := NewDisabledNotifier(logger)
+	sink := &recordingSink{}
+	notifier.AddSink(sink)
+	notifier.SetQueryRedaction(redact.Policy{Mode: redact.ModeElide})
+
+	assert.NoError(t, notifier.NotifyStartWithQuery("pt-archiver", "users", "`DELETE FROM users WHERE email = 'a@example.com'`", 0))
+	assert.NoError(t, notifier.NotifyFollowUpCommands([]string{"pt-archiver --where=id=5"}))
+	assert.NoError(t, notifier.NotifySuccessWithQuery("alter-table", "users", "```UPDATE users SET plan = 'pro'```", 0, time.Second))
+
+	assert.Contains(t, sink.texts[0], "WHERE email = ?")
+	assert.NotContains(t, sink.texts[0], "a@example.com")
+	assert.Contains(t, sink.texts[1], "--where=id=?")
+	assert.Contains(t, sink.texts[2], "```\nUPDATE users\nSET plan = ?\n```")
+}
+
+func TestFormatQuery(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	notifier := NewDisabledNotifier(logger)
+
+	assert.Equal(t, "```\nALTER TABLE users\n  ADD COLUMN age INT,\n  ADD INDEX idx_age (age)\n```",
+		notifier.formatQuery("`alter table users add column age int, add index idx_age (age)`"))
+	assert.Equal(t, "```\nDROP TABLE users_old\n```", notifier.formatQuery("DROP TABLE users_old"))
+	assert.Equal(t, "ALTER: ```\nALTER TABLE users\n  ADD COLUMN age INT\n```\npt-osc: `pt-online-schema-change --alter 'ADD COLUMN age INT' D=app,t=users`",
+		notifier.formatQuery("ALTER: `ALTER TABLE users ADD COLUMN age INT`\npt-osc: `pt-online-schema-change --alter 'ADD COLUMN age INT' D=app,t=users`"))
+	assert.Equal(t, "`pt-archiver --source D=app,t=logs --where=created_at < '2024-01-01'`",
+		notifier.formatQuery("`pt-archiver --source D=app,t=logs --where=created_at < '2024-01-01'`"))
+}
+
+func TestNotifyAllTasksStartWithFindings(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	notifier := NewDisabledNotifier(logger)
+	sink := &recordingSink{}
+	notifier.AddSink(sink)
+
+	assert.NoError(t, notifier.NotifyAllTasksStartWithFindings(4, []string{"binlog_format=MIXED", "binlog_row_image=MINIMAL"}))
+
+	assert.Equal(t, []string{SeverityWarning}, sink.severities)
+	assert.Contains(t, sink.texts[0], "Total queries: 4")
+	assert.Contains(t, sink.texts[0], "• binlog_format=MIXED\n• binlog_row_image=MINIMAL")
+}
+
+func TestNotifyCrashTruncatesStack(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	notifier := NewDisabledNotifier(logger)
+	notifier.SetOperator("alice")
+	sink := &recordingSink{}
+	notifier.AddSink(sink)
+
+	stack := "main.panicSite()\n" + strings.Repeat("x", maxCrashStackLength*2)
+	assert.NoError(t, notifier.NotifyCrash("run-1", "boom", stack))
+
+	if assert.Len(t, sink.texts, 1) {
+		text := sink.texts[0]
+		assert.Contains(t, text, "alterguard crashed")
+		assert.Contains(t, text, "Run ID: run-1")
+		assert.Contains(t, text, "Panic: boom")
+		assert.Contains(t, text, "Operator: alice")
+		assert.Contains(t, text, "main.panicSite()")
+		assert.Contains(t, text, "truncated")
+		assert.Less(t, len(text), maxCrashStackLength+500)
+	}
+}
@@ -0,0 +1,214 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSONFileName はディレクトリに書き出す場合のスナップショット全体のファイル名
+const JSONFileName = "snapshot.json"
+
+var autoIncrementRe = regexp.MustCompile(`\s*AUTO_INCREMENT=\d+`)
+
+// Source はスナップショットを取るためにデータベースから読む情報
+type Source interface {
+	ListTables() ([]string, error)
+	GetCreateTable(tableName string) (string, error)
+}
+
+// Snapshot はある時点の各テーブルの SHOW CREATE TABLE
+type Snapshot struct {
+	TakenAt     time.Time `json:"taken_at"`
+	Environment string    `json:"environment,omitempty"`
+	// パスワードを含まない接続先 (host:port/db)
+	Database string            `json:"database"`
+	Tables   map[string]string `json:"tables"`
+}
+
+// TableDiff は2つのスナップショットの間での1テーブル分の違い
+type TableDiff struct {
+	TableName string
+	// added, removed, changed のいずれか
+	Change string
+	// changed の場合の行単位の差分。"- " が変更前、"+ " が変更後の行
+	Lines []string
+}
+
+// Take は tables (空なら全テーブル) の SHOW CREATE TABLE を取得する
+func Take(source Source, tables []string, now time.Time) (*Snapshot, error) {
+	if len(tables) == 0 {
+		var err error
+		tables, err = source.ListTables()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot := &Snapshot{TakenAt: now, Tables: make(map[string]string, len(tables))}
+	for _, table := range tables {
+		createTable, err := source.GetCreateTable(table)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Tables[table] = createTable
+	}
+	return snapshot, nil
+}
+
+// Write はスナップショットを書き出す。path が .json で終わればその JSON ファイルに、
+// それ以外はディレクトリとみなし、snapshot.json とテーブルごとの <table>.sql を書き出す
+func Write(path string, snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	data = append(data, '\n')
+
+	if strings.HasSuffix(path, ".json") {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write snapshot %s: %w", path, err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(path, 0o750); err != nil {
+		return fmt.Errorf("failed to create snapshot directory %s: %w", path, err)
+	}
+	if err := os.WriteFile(filepath.Join(path, JSONFileName), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot %s: %w", path, err)
+	}
+	for table, createTable := range snapshot.Tables {
+		file := filepath.Join(path, table+".sql")
+		if err := os.WriteFile(file, []byte(createTable+";\n"), 0o644); err != nil {
+			return fmt.Errorf("failed to write snapshot %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// Load は Write で書き出したスナップショットを JSON ファイルかディレクトリから読み込む
+func Load(path string) (*Snapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+	if info.IsDir() {
+		path = filepath.Join(path, JSONFileName)
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	if snapshot.Tables == nil {
+		return nil, fmt.Errorf("snapshot %s has no tables", path)
+	}
+	return &snapshot, nil
+}
+
+// Diff は before から after への変更をテーブル名順に返す。AUTO_INCREMENT の値は書き込みのたびに変わるため比較しない
+func Diff(before, after *Snapshot) []TableDiff {
+	names := make(map[string]bool)
+	for table := range before.Tables {
+		names[table] = true
+	}
+	for table := range after.Tables {
+		names[table] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for table := range names {
+		sorted = append(sorted, table)
+	}
+	sort.Strings(sorted)
+
+	var diffs []TableDiff
+	for _, table := range sorted {
+		oldDefinition, inBefore := before.Tables[table]
+		newDefinition, inAfter := after.Tables[table]
+		switch {
+		case !inBefore:
+			diffs = append(diffs, TableDiff{TableName: table, Change: "added"})
+		case !inAfter:
+			diffs = append(diffs, TableDiff{TableName: table, Change: "removed"})
+		default:
+			oldDefinition = autoIncrementRe.ReplaceAllString(oldDefinition, "")
+			newDefinition = autoIncrementRe.ReplaceAllString(newDefinition, "")
+			if oldDefinition != newDefinition {
+				diffs = append(diffs, TableDiff{
+					TableName: table,
+					Change:    "changed",
+					Lines:     diffLines(strings.Split(oldDefinition, "\n"), strings.Split(newDefinition, "\n")),
+				})
+			}
+		}
+	}
+	return diffs
+}
+
+// diffLines は最長共通部分列から、変更された行だけを "- " / "+ " 付きで返す
+func diffLines(before, after []string) []string {
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch {
+		case before[i] == after[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+strings.TrimSpace(before[i]))
+			i++
+		default:
+			lines = append(lines, "+ "+strings.TrimSpace(after[j]))
+			j++
+		}
+	}
+	for ; i < len(before); i++ {
+		lines = append(lines, "- "+strings.TrimSpace(before[i]))
+	}
+	for ; j < len(after); j++ {
+		lines = append(lines, "+ "+strings.TrimSpace(after[j]))
+	}
+	return lines
+}
+
+// FormatDiff は差分を人が読む形にまとめる
+func FormatDiff(before, after *Snapshot, diffs []TableDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "before: %s %s\nafter:  %s %s\n",
+		before.Database, before.TakenAt.Format(time.RFC3339), after.Database, after.TakenAt.Format(time.RFC3339))
+	if len(diffs) == 0 {
+		b.WriteString("no schema changes")
+		return b.String()
+	}
+	for _, diff := range diffs {
+		fmt.Fprintf(&b, "%s: %s\n", diff.TableName, diff.Change)
+		for _, line := range diff.Lines {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,95 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	tables map[string]string
+}
+
+func (f fakeSource) ListTables() ([]string, error) {
+	var tables []string
+	for table := range f.tables {
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func (f fakeSource) GetCreateTable(tableName string) (string, error) {
+	return f.tables[tableName], nil
+}
+
+const usersBefore = "CREATE TABLE `users` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  `name` varchar(255) NOT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=10 DEFAULT CHARSET=utf8mb4"
+
+const usersAfter = "CREATE TABLE `users` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  `name` varchar(255) NOT NULL,\n  `age` int DEFAULT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=25 DEFAULT CHARSET=utf8mb4"
+
+func TestTake(t *testing.T) {
+	source := fakeSource{tables: map[string]string{"users": usersBefore, "orders": "CREATE TABLE `orders` (`id` int)"}}
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	snapshot, err := Take(source, []string{"users"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"users": usersBefore}, snapshot.Tables)
+
+	snapshot, err = Take(source, nil, now)
+	require.NoError(t, err)
+	assert.Len(t, snapshot.Tables, 2)
+}
+
+func TestWriteAndLoad(t *testing.T) {
+	snapshot := &Snapshot{
+		TakenAt:  time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+		Database: "db:3306/app",
+		Tables:   map[string]string{"users": usersBefore},
+	}
+
+	t.Run("json file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "before.json")
+		require.NoError(t, Write(path, snapshot))
+		loaded, err := Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, snapshot, loaded)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "before")
+		require.NoError(t, Write(dir, snapshot))
+		sql, err := os.ReadFile(filepath.Join(dir, "users.sql"))
+		require.NoError(t, err)
+		assert.Equal(t, usersBefore+";\n", string(sql))
+
+		loaded, err := Load(dir)
+		require.NoError(t, err)
+		assert.Equal(t, snapshot, loaded)
+	})
+}
+
+func TestDiff(t *testing.T) {
+	before := &Snapshot{Tables: map[string]string{
+		"users":    usersBefore,
+		"legacy":   "CREATE TABLE `legacy` (`id` int)",
+		"counters": "CREATE TABLE `counters` (`id` int) AUTO_INCREMENT=5",
+	}}
+	after := &Snapshot{Tables: map[string]string{
+		"users":    usersAfter,
+		"orders":   "CREATE TABLE `orders` (`id` int)",
+		"counters": "CREATE TABLE `counters` (`id` int) AUTO_INCREMENT=500",
+	}}
+
+	diffs := Diff(before, after)
+	assert.Equal(t, []TableDiff{
+		{TableName: "legacy", Change: "removed"},
+		{TableName: "orders", Change: "added"},
+		{TableName: "users", Change: "changed", Lines: []string{"+ `age` int DEFAULT NULL,"}},
+	}, diffs)
+
+	assert.Contains(t, FormatDiff(before, after, diffs), "users: changed\n  + `age` int DEFAULT NULL,")
+	assert.Contains(t, FormatDiff(before, before, Diff(before, before)), "no schema changes")
+}
@@ -0,0 +1,328 @@
+// Package sqlfmt は通知に載せるクエリを読みやすく整形する。
+// 構文解析はせず、字句ごとにキーワードの大文字化・句ごとの改行・バッククォートの統一だけを行う。
+package sqlfmt
+
+import (
+	"regexp"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenQuotedIdentifier
+	tokenString
+	tokenComment
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// 元のクエリで直前に空白があったか。関数名と括弧の間など、空白の有無に意味がある箇所を保つ
+	spaceBefore bool
+}
+
+// sqlStatements は整形の対象とみなす文の先頭のキーワード
+var sqlStatements = map[string]bool{
+	"ALTER": true, "ANALYZE": true, "CREATE": true, "DELETE": true, "DROP": true, "INSERT": true,
+	"OPTIMIZE": true, "RENAME": true, "REPLACE": true, "SELECT": true, "SET": true, "TRUNCATE": true,
+	"UPDATE": true, "WITH": true,
+}
+
+var simpleIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// IsSQL は text が SQL 文で始まるかどうかを返す。pt-osc のコマンドなどは整形しない
+func IsSQL(text string) bool {
+	fields := strings.Fields(text)
+	return len(fields) > 0 && sqlStatements[strings.ToUpper(fields[0])]
+}
+
+// Format はクエリを整形する。キーワードを大文字にし、ALTER TABLE の変更内容・CREATE TABLE の列定義・
+// SELECT などの句をそれぞれ1行にする。バッククォートは予約語や記号を含む識別子にだけ付ける。
+func Format(query string) string {
+	var out []string
+	for _, statement := range splitStatements(tokenize(query)) {
+		if formatted := formatStatement(statement); formatted != "" {
+			out = append(out, formatted)
+		}
+	}
+	return strings.Join(out, ";\n")
+}
+
+func tokenize(query string) []token {
+	var tokens []token
+	space := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+			continue
+		case c == '-' && strings.HasPrefix(query[i:], "-- "), c == '#':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			tokens = append(tokens, token{kind: tokenComment, text: strings.TrimRight(query[i:i+end], " \t\r"), spaceBefore: space})
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			tokens = append(tokens, token{kind: tokenComment, text: query[i : i+end], spaceBefore: space})
+			i += end
+		case c == '\'' || c == '"' || c == '`':
+			end := quotedEnd(query, i)
+			kind := tokenString
+			if c == '`' {
+				kind = tokenQuotedIdentifier
+			}
+			tokens = append(tokens, token{kind: kind, text: query[i:end], spaceBefore: space})
+			i = end
+		case isWordByte(c):
+			end := i
+			for end < len(query) && isWordByte(query[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: query[i:end], spaceBefore: space})
+			i = end
+		default:
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(c), spaceBefore: space})
+			i++
+		}
+		space = false
+	}
+	return tokens
+}
+
+// quotedEnd は start の引用符に対応する閉じ引用符の直後の位置を返す。バックスラッシュと重ねた引用符によるエスケープに対応する
+func quotedEnd(query string, start int) int {
+	quote := query[start]
+	for i := start + 1; i < len(query); i++ {
+		switch {
+		case query[i] == '\\' && quote != '`':
+			i++
+		case query[i] == quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c == '@' ||
+		'0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= 0x80
+}
+
+func splitStatements(tokens []token) [][]token {
+	var statements [][]token
+	var current []token
+	for _, t := range tokens {
+		if t.kind == tokenSymbol && t.text == ";" {
+			statements = append(statements, current)
+			current = nil
+			continue
+		}
+		current = append(current, t)
+	}
+	return append(statements, current)
+}
+
+func formatStatement(tokens []token) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	for i := range tokens {
+		tokens[i].text = normalizeToken(tokens, i)
+	}
+
+	var b strings.Builder
+	mode := statementMode(tokens)
+	depth := 0
+	// ALTER TABLE の変更内容の始まり、CREATE TABLE の列定義の括弧の位置
+	bodyStart := -1
+	switch mode {
+	case modeAlter:
+		bodyStart = alterBodyStart(tokens)
+	case modeCreate:
+		bodyStart = createBodyStart(tokens)
+	}
+
+	for i, t := range tokens {
+		separator := ""
+		if i > 0 && t.spaceBefore {
+			separator = " "
+		}
+		// 行末までのコメントの後ろは改行しないとコメントに飲み込まれる
+		if i > 0 && tokens[i-1].kind == tokenComment && !strings.HasPrefix(tokens[i-1].text, "/*") {
+			separator = "\n"
+		}
+
+		switch {
+		case mode == modeAlter && i == bodyStart:
+			separator = "\n  "
+		case mode == modeAlter && depth == 0 && i > bodyStart && bodyStart >= 0 && i > 0 && isSymbol(tokens[i-1], ","):
+			separator = "\n  "
+		case mode == modeCreate && depth == 1 && i > bodyStart && bodyStart >= 0 && (i == bodyStart+1 || isSymbol(tokens[i-1], ",")):
+			separator = "\n  "
+		case mode == modeCreate && depth == 1 && bodyStart >= 0 && i > bodyStart && isSymbol(t, ")"):
+			separator = "\n"
+		case mode == modeDML && depth == 0 && i > 1 && startsClause(tokens, i):
+			separator = "\n"
+		}
+		b.WriteString(separator)
+		b.WriteString(t.text)
+
+		if t.kind == tokenSymbol {
+			switch t.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+		}
+	}
+	return b.String()
+}
+
+type stmtMode int
+
+const (
+	modeOther stmtMode = iota
+	modeAlter
+	modeCreate
+	modeDML
+)
+
+func statementMode(tokens []token) stmtMode {
+	first := strings.ToUpper(tokens[0].text)
+	switch first {
+	case "ALTER":
+		if len(tokens) > 1 && strings.EqualFold(tokens[1].text, "TABLE") {
+			return modeAlter
+		}
+	case "CREATE":
+		for _, t := range tokens[1:] {
+			if t.kind != tokenWord {
+				break
+			}
+			if strings.EqualFold(t.text, "TABLE") {
+				return modeCreate
+			}
+		}
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "REPLACE", "WITH":
+		return modeDML
+	}
+	return modeOther
+}
+
+// alterBodyStart は ALTER TABLE [db.]name の直後の位置を返す
+func alterBodyStart(tokens []token) int {
+	i := 2
+	if i >= len(tokens) {
+		return -1
+	}
+	i++
+	for i+1 < len(tokens) && isSymbol(tokens[i], ".") {
+		i += 2
+	}
+	if i >= len(tokens) {
+		return -1
+	}
+	return i
+}
+
+// createBodyStart は CREATE TABLE の列定義を囲む括弧の位置を返す
+func createBodyStart(tokens []token) int {
+	for i, t := range tokens {
+		if isSymbol(t, "(") {
+			return i
+		}
+		if t.kind == tokenWord && (strings.EqualFold(t.text, "SELECT") || strings.EqualFold(t.text, "LIKE") || strings.EqualFold(t.text, "AS")) {
+			return -1
+		}
+	}
+	return -1
+}
+
+// startsClause は i の語が DML の句の始まりかどうかを返す
+func startsClause(tokens []token, i int) bool {
+	if tokens[i].kind != tokenWord || i > 0 && isSymbol(tokens[i-1], ".") {
+		return false
+	}
+	next := ""
+	if i+1 < len(tokens) && tokens[i+1].kind == tokenWord {
+		next = strings.ToUpper(tokens[i+1].text)
+	}
+	previous := ""
+	if i > 0 && tokens[i-1].kind == tokenWord {
+		previous = strings.ToUpper(tokens[i-1].text)
+	}
+	switch strings.ToUpper(tokens[i].text) {
+	case "FROM", "WHERE", "HAVING", "LIMIT", "UNION", "STRAIGHT_JOIN":
+		return true
+	case "JOIN":
+		return previous != "LEFT" && previous != "RIGHT" && previous != "INNER" && previous != "CROSS" && previous != "NATURAL" && previous != "OUTER"
+	case "VALUES":
+		// ON DUPLICATE KEY UPDATE b = VALUES(b) の VALUES は関数
+		return !isSymbol(tokens[i-1], "=") && !isSymbol(tokens[i-1], ",") && !isSymbol(tokens[i-1], "(")
+	case "SET":
+		return strings.EqualFold(tokens[0].text, "UPDATE") || strings.EqualFold(tokens[0].text, "INSERT")
+	case "GROUP", "ORDER":
+		return next == "BY"
+	case "LEFT", "RIGHT", "INNER", "CROSS", "NATURAL":
+		return next == "JOIN" || next == "OUTER"
+	case "ON":
+		return next == "DUPLICATE"
+	}
+	return false
+}
+
+func isSymbol(t token, symbol string) bool {
+	return t.kind == tokenSymbol && t.text == symbol
+}
+
+// normalizeToken はキーワードを大文字にし、不要なバッククォートを外す
+func normalizeToken(tokens []token, i int) string {
+	t := tokens[i]
+	qualified := i > 0 && isSymbol(tokens[i-1], ".") || i+1 < len(tokens) && isSymbol(tokens[i+1], ".")
+	switch t.kind {
+	case tokenWord:
+		upper := strings.ToUpper(t.text)
+		if qualified {
+			return t.text
+		}
+		if reservedWords[upper] || keywords[upper] && !followsTableKeyword(tokens, i) {
+			return upper
+		}
+	case tokenQuotedIdentifier:
+		name := strings.Trim(t.text, "`")
+		upper := strings.ToUpper(name)
+		if simpleIdentifier.MatchString(name) && !reservedWords[upper] && !keywords[upper] {
+			return name
+		}
+	}
+	return t.text
+}
+
+// followsTableKeyword は i の語がテーブル名の位置 (TABLE users, FROM users など) にあるかどうかを返す
+func followsTableKeyword(tokens []token, i int) bool {
+	if i == 0 || tokens[i-1].kind != tokenWord {
+		return false
+	}
+	switch strings.ToUpper(tokens[i-1].text) {
+	case "TABLE", "INTO", "FROM", "UPDATE", "JOIN", "REFERENCES", "TO", "EXISTS", "LIKE":
+		return true
+	}
+	return false
+}
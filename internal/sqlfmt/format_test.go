@@ -0,0 +1,77 @@
+package sqlfmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "alter table with several changes",
+			query: "alter table `users` add column `age` int not null default 0 after `name`, add index idx_age (age), algorithm=instant",
+			want: "ALTER TABLE users\n" +
+				"  ADD COLUMN age INT NOT NULL DEFAULT 0 AFTER name,\n" +
+				"  ADD INDEX idx_age (age),\n" +
+				"  ALGORITHM=INSTANT",
+		},
+		{
+			name:  "reserved identifiers keep their backticks and strings are untouched",
+			query: "ALTER TABLE app.users ADD COLUMN `order` varchar(255) comment 'add, drop; it''s fine'",
+			want:  "ALTER TABLE app.users\n  ADD COLUMN `order` VARCHAR(255) COMMENT 'add, drop; it''s fine'",
+		},
+		{
+			name:  "create table puts one definition per line",
+			query: "create table if not exists posts (id bigint unsigned not null auto_increment, `user_id` int, primary key (id), key idx_user (user_id, created_at)) engine=InnoDB",
+			want: "CREATE TABLE IF NOT EXISTS posts (\n" +
+				"  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,\n" +
+				"  user_id INT,\n" +
+				"  PRIMARY KEY (id),\n" +
+				"  KEY idx_user (user_id, created_at)\n" +
+				") ENGINE=InnoDB",
+		},
+		{
+			name:  "update with join and subquery",
+			query: "update users u left join plans p on p.id = u.plan_id set u.plan = 'pro' where u.id in (select user_id from vip) order by u.id limit 10",
+			want: "UPDATE users u\n" +
+				"LEFT JOIN plans p ON p.id = u.plan_id\n" +
+				"SET u.plan = 'pro'\n" +
+				"WHERE u.id IN (SELECT user_id FROM vip)\n" +
+				"ORDER BY u.id\n" +
+				"LIMIT 10",
+		},
+		{
+			name:  "insert on duplicate key update",
+			query: "insert into logs (a, b) values (1, 'x') on duplicate key update b = values(b)",
+			want:  "INSERT INTO logs (a, b)\nVALUES (1, 'x')\nON DUPLICATE KEY UPDATE b = VALUES(b)",
+		},
+		{
+			name:  "line comments and several statements",
+			query: "DELETE FROM sessions WHERE expires_at < now() -- expired\n AND id > 5; rename table a to b;",
+			want:  "DELETE FROM sessions\nWHERE expires_at < NOW() -- expired\nAND id > 5;\nRENAME TABLE a TO b",
+		},
+		{
+			name:  "keywords used as table names are left alone",
+			query: "alter table comment add column body text",
+			want:  "ALTER TABLE comment\n  ADD COLUMN body TEXT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Format(tt.query))
+		})
+	}
+}
+
+func TestIsSQL(t *testing.T) {
+	assert.True(t, IsSQL("  alter table users add column age int"))
+	assert.True(t, IsSQL("RENAME TABLE a TO b"))
+	assert.False(t, IsSQL("pt-online-schema-change --alter 'ADD COLUMN age INT' D=app,t=users"))
+	assert.False(t, IsSQL(""))
+}
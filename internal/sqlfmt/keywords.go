@@ -0,0 +1,34 @@
+package sqlfmt
+
+// reservedWords は MySQL の予約語のうち、スキーマ変更やバッチで使うもの。識別子として使うにはバッククォートが必要なので、常にキーワードとして大文字にする
+var reservedWords = toSet(`ADD ALL ALTER ANALYZE AND AS ASC BEFORE BETWEEN BIGINT BINARY BLOB BOTH BY CASCADE CASE
+CHANGE CHAR CHARACTER CHECK COLLATE COLUMN CONSTRAINT CONVERT CREATE CROSS CURRENT_DATE CURRENT_TIME
+CURRENT_TIMESTAMP DATABASE DECIMAL DEFAULT DELETE DESC DISTINCT DIV DOUBLE DROP ELSE ELSEIF EXISTS
+FALSE FLOAT FOR FORCE FOREIGN FROM FULLTEXT GENERATED GROUP HAVING IF IGNORE IN INDEX INNER INSERT INT
+INTEGER INTERVAL INTO IS JOIN KEY KEYS KILL LEADING LEFT LIKE LIMIT LINES LOCK LONGBLOB LONGTEXT
+MEDIUMBLOB MEDIUMINT MEDIUMTEXT MOD NATURAL NOT NULL NUMERIC ON OPTIMIZE OR ORDER OUTER PARTITION
+PRIMARY RANGE REFERENCES REGEXP RENAME REPLACE RESTRICT RIGHT SELECT SET SMALLINT SPATIAL STORED
+STRAIGHT_JOIN TABLE THEN TINYBLOB TINYINT TINYTEXT TO TRAILING TRIGGER TRUE UNION UNIQUE UNSIGNED
+UPDATE USING VALUES VARBINARY VARCHAR VIRTUAL WHEN WHERE WITH XOR ZEROFILL`)
+
+// keywords は予約語ではないがキーワードとして大文字にする語。テーブル名として現れうる位置では変えない
+var keywords = toSet(`ACTION AFTER ALGORITHM AUTO_INCREMENT AVG BIT BOOL BOOLEAN CHARSET COMMENT COMPRESSED COPY
+COUNT DATE DATETIME DISABLE DUPLICATE DYNAMIC ENABLE ENGINE ENUM EXCLUSIVE FIRST INPLACE INSTANT
+INVISIBLE JSON MAX MIN MODIFY NO NONE NOW OFFSET ROW_FORMAT SHARED SUM TEMPORARY TEXT TIME TIMESTAMP
+TRUNCATE VIEW VISIBLE YEAR`)
+
+func toSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	word := ""
+	for _, c := range words + " " {
+		if c == ' ' || c == '\n' {
+			if word != "" {
+				set[word] = true
+			}
+			word = ""
+			continue
+		}
+		word += string(c)
+	}
+	return set
+}
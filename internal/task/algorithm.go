@@ -0,0 +1,53 @@
+package task
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/database"
+)
+
+// executeAlterWithAlgorithm はダイレクトALTERを実行し、サーバーが使ったアルゴリズムを返す。
+// DRY RUN や重複エラーで実行されなかった場合は nil を返す。
+func (m *Manager) executeAlterWithAlgorithm(ctx context.Context, queryInfo *QueryInfo, taskName string) (*database.AlterAlgorithm, error) {
+	if m.dryRun {
+		m.logger.Infof("[DRY RUN] Would execute SQL: %s", queryInfo.Query)
+		return nil, nil
+	}
+
+	defer m.invalidateRowCount(queryInfo.TableName)
+	algorithm, err := m.db.ExecuteAlterWithAlgorithm(ctx, queryInfo.TableName, queryInfo.Query)
+	if err != nil {
+		return nil, m.handleQueryError(taskName, queryInfo, err)
+	}
+	return algorithm, nil
+}
+
+// summarizeAlgorithms は通知用にアルゴリズムをまとめる。
+// テーブルが作り直された場合は pt_osc_threshold の見直しを促す警告を出す。
+func (m *Manager) summarizeAlgorithms(tableName string, rowCount int64, algorithms []*database.AlterAlgorithm) string {
+	if len(algorithms) == 0 {
+		return "not executed"
+	}
+
+	seen := make(map[string]bool)
+	var descriptions []string
+	rebuilt := false
+	for _, algorithm := range algorithms {
+		description := algorithm.String()
+		if !seen[description] {
+			seen[description] = true
+			descriptions = append(descriptions, description)
+		}
+		if algorithm.Rebuilt {
+			rebuilt = true
+		}
+	}
+
+	if rebuilt {
+		m.logger.Warnf("Direct ALTER rebuilt table %s (%d rows, pt_osc_threshold: %d); consider lowering the threshold if this took too long",
+			tableName, rowCount, m.config.Common.PtOscThreshold)
+	}
+
+	return strings.Join(descriptions, " / ")
+}
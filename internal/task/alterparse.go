@@ -0,0 +1,133 @@
+package task
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// partition_options は ALTER の最後にカンマなしで続くため、句とは分けて扱う
+	partitionOptionsRe = regexp.MustCompile(`(?i)(?:^|\s)(PARTITION\s+BY|REMOVE\s+PARTITIONING)\b`)
+	// パーティションの管理操作は他の変更と1つの ALTER にまとめられない
+	partitionManagementRe = regexp.MustCompile(`(?i)^(?:ADD|DROP|DISCARD|IMPORT|TRUNCATE|COALESCE|REORGANIZE|EXCHANGE|ANALYZE|CHECK|OPTIMIZE|REBUILD|REPAIR)\s+PARTITION\b`)
+)
+
+// alterSpec は ALTER TABLE のテーブル名より後ろを句に分解したもの
+type alterSpec struct {
+	// カンマで区切られた変更 (ADD COLUMN ... など)
+	Clauses []string
+	// 末尾の PARTITION BY / REMOVE PARTITIONING、またはパーティションの管理操作
+	Partition string
+	// パーティションの管理操作で、他の変更とまとめられない
+	Exclusive bool
+}
+
+// parseAlterSpec は ALTER の句を、引用符と括弧の中を無視して分解する。
+// 複数行にわたる句や CHECK 制約・COMMENT の中のカンマがあっても句の区切りを誤らない。
+func parseAlterSpec(alter string) alterSpec {
+	alter = strings.TrimSpace(alter)
+	masked := maskAlterNesting(alter)
+	if partitionManagementRe.MatchString(masked) {
+		return alterSpec{Partition: alter, Exclusive: true}
+	}
+
+	var spec alterSpec
+	body := alter
+	if loc := partitionOptionsRe.FindStringSubmatchIndex(masked); loc != nil {
+		spec.Partition = strings.TrimSpace(alter[loc[2]:])
+		body = alter[:loc[2]]
+	}
+	spec.Clauses = splitAlterClauses(body)
+	return spec
+}
+
+// String は pt-osc の --alter や ALTER TABLE にそのまま渡せる形に組み立てる
+func (s alterSpec) String() string {
+	text := strings.Join(s.Clauses, ", ")
+	switch {
+	case s.Partition == "":
+		return text
+	case text == "":
+		return s.Partition
+	}
+	return text + " " + s.Partition
+}
+
+// combineAlterParts は同じテーブルの ALTER を1つにまとめる。
+// 句はカンマでつなぎ、partition_options は最後に置く (1つのグループに partition_options は1つまで)。
+func combineAlterParts(alterParts []string) string {
+	var combined alterSpec
+	for _, part := range alterParts {
+		spec := parseAlterSpec(part)
+		combined.Clauses = append(combined.Clauses, spec.Clauses...)
+		if spec.Partition != "" {
+			combined.Partition = spec.Partition
+		}
+	}
+	return combined.String()
+}
+
+// splitAlterClauses は ALTER TABLE の句をトップレベルのカンマで分割する。
+// DECIMAL(10,2) や ENUM('a,b') のように括弧や引用符の中にあるカンマでは分割しない。
+func splitAlterClauses(alter string) []string {
+	masked := maskAlterNesting(alter)
+	var clauses []string
+	start := 0
+	for i := 0; i < len(masked); i++ {
+		if masked[i] != ',' {
+			continue
+		}
+		if clause := strings.TrimSpace(alter[start:i]); clause != "" {
+			clauses = append(clauses, clause)
+		}
+		start = i + 1
+	}
+	if clause := strings.TrimSpace(alter[start:]); clause != "" {
+		clauses = append(clauses, clause)
+	}
+	return clauses
+}
+
+// maskAlterNesting は引用符で囲まれた部分と括弧の中を '_' に置き換える。
+// バイト位置は元の文字列と同じなので、トップレベルの区切りやキーワードの位置をそのまま元の文字列に使える。
+func maskAlterNesting(alter string) string {
+	masked := []byte(alter)
+	depth := 0
+	var quote byte
+	for i := 0; i < len(alter); i++ {
+		c := alter[i]
+		switch {
+		case quote != 0:
+			masked[i] = '_'
+			switch {
+			case c == '\\' && quote != '`' && i+1 < len(alter):
+				i++
+				masked[i] = '_'
+			case c == quote && i+1 < len(alter) && alter[i+1] == quote:
+				// '' のように重ねた引用符はエスケープ
+				i++
+				masked[i] = '_'
+			case c == quote:
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			masked[i] = '_'
+		case c == '(':
+			if depth > 0 {
+				masked[i] = '_'
+			}
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+			if depth > 0 {
+				masked[i] = '_'
+			}
+		case depth > 0:
+			masked[i] = '_'
+		}
+	}
+	return string(masked)
+}
@@ -0,0 +1,95 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAlterSpec(t *testing.T) {
+	tests := []struct {
+		name  string
+		alter string
+		want  alterSpec
+	}{
+		{
+			name:  "clauses across lines",
+			alter: "ADD COLUMN price DECIMAL(10,2) NOT NULL,\n  ADD INDEX idx_price (price, id)",
+			want:  alterSpec{Clauses: []string{"ADD COLUMN price DECIMAL(10,2) NOT NULL", "ADD INDEX idx_price (price, id)"}},
+		},
+		{
+			name:  "commas in comments and check constraints",
+			alter: `ADD COLUMN note VARCHAR(10) COMMENT 'a, b\', c', ADD CONSTRAINT chk_price CHECK (price > 0 AND price IN (1, 2))`,
+			want: alterSpec{Clauses: []string{
+				`ADD COLUMN note VARCHAR(10) COMMENT 'a, b\', c'`,
+				"ADD CONSTRAINT chk_price CHECK (price > 0 AND price IN (1, 2))",
+			}},
+		},
+		{
+			name:  "partition options after clauses",
+			alter: "ADD COLUMN created DATE NOT NULL\nPARTITION BY RANGE (YEAR(created)) (PARTITION p0 VALUES LESS THAN (2020), PARTITION p1 VALUES LESS THAN MAXVALUE)",
+			want: alterSpec{
+				Clauses:   []string{"ADD COLUMN created DATE NOT NULL"},
+				Partition: "PARTITION BY RANGE (YEAR(created)) (PARTITION p0 VALUES LESS THAN (2020), PARTITION p1 VALUES LESS THAN MAXVALUE)",
+			},
+		},
+		{
+			name:  "partition options only",
+			alter: "PARTITION BY HASH(id) PARTITIONS 4",
+			want:  alterSpec{Partition: "PARTITION BY HASH(id) PARTITIONS 4"},
+		},
+		{
+			name:  "partition management",
+			alter: "REORGANIZE PARTITION p0, p1 INTO (PARTITION p01 VALUES LESS THAN (2021))",
+			want:  alterSpec{Partition: "REORGANIZE PARTITION p0, p1 INTO (PARTITION p01 VALUES LESS THAN (2021))", Exclusive: true},
+		},
+		{
+			name:  "partition keyword inside a quoted identifier",
+			alter: "ADD COLUMN `partition by` INT",
+			want:  alterSpec{Clauses: []string{"ADD COLUMN `partition by` INT"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseAlterSpec(tt.alter))
+		})
+	}
+}
+
+func TestCombineAlterParts(t *testing.T) {
+	got := combineAlterParts([]string{
+		"PARTITION BY HASH(id) PARTITIONS 4",
+		"ADD COLUMN age INT",
+		"ADD INDEX idx_age (age), ADD COLUMN note VARCHAR(10) COMMENT 'x, y'",
+	})
+	assert.Equal(t, "ADD COLUMN age INT, ADD INDEX idx_age (age), ADD COLUMN note VARCHAR(10) COMMENT 'x, y' PARTITION BY HASH(id) PARTITIONS 4", got)
+}
+
+func TestGroupQueriesByTable_Partitions(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{Queries: []string{
+		"ALTER TABLE events\n  ADD COLUMN created DATE NOT NULL\n  PARTITION BY HASH(id) PARTITIONS 4",
+		"ALTER TABLE events ADD INDEX idx_created (created)",
+		"ALTER TABLE events PARTITION BY KEY(id) PARTITIONS 8",
+		"ALTER TABLE events TRUNCATE PARTITION p0",
+		"ALTER TABLE events ADD COLUMN note TEXT",
+	}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	queries, err := manager.parseConfiguredQueries()
+	require.NoError(t, err)
+	groups := manager.groupQueriesByTable(queries)
+
+	require.Len(t, groups, 4)
+	assert.Equal(t, "ADD COLUMN created DATE NOT NULL, ADD INDEX idx_created (created) PARTITION BY HASH(id) PARTITIONS 4",
+		combineAlterParts(groups[0].AlterParts))
+	assert.Equal(t, "PARTITION BY KEY(id) PARTITIONS 8", combineAlterParts(groups[1].AlterParts))
+	assert.Equal(t, "TRUNCATE PARTITION p0", combineAlterParts(groups[2].AlterParts))
+	assert.Equal(t, "ADD COLUMN note TEXT", combineAlterParts(groups[3].AlterParts))
+}
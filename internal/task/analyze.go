@@ -0,0 +1,76 @@
+package task
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	analyzeOnFailureWarn = "warn"
+	analyzeOnFailureFail = "fail"
+)
+
+type analyzeSettings struct {
+	beforeSwap bool
+	afterSwap  bool
+	timeout    time.Duration
+	fail       bool
+}
+
+// resolveAnalyzeSettings は analyze_table と analyze_table.tables の上書きからテーブルの設定を決める
+func (m *Manager) resolveAnalyzeSettings(tableName string) (*analyzeSettings, error) {
+	cfg := m.config.Common.AnalyzeTable
+	settings := &analyzeSettings{beforeSwap: !m.config.Common.DisableAnalyzeTable}
+	if cfg.BeforeSwap != nil {
+		settings.beforeSwap = *cfg.BeforeSwap
+	}
+	if cfg.AfterSwap != nil {
+		settings.afterSwap = *cfg.AfterSwap
+	}
+	if override, ok := cfg.Tables[tableName]; ok {
+		if override.BeforeSwap != nil {
+			settings.beforeSwap = *override.BeforeSwap
+		}
+		if override.AfterSwap != nil {
+			settings.afterSwap = *override.AfterSwap
+		}
+	}
+
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid analyze_table.timeout %q", cfg.Timeout)
+		}
+		settings.timeout = timeout
+	}
+
+	switch cfg.OnFailure {
+	case "", analyzeOnFailureWarn:
+	case analyzeOnFailureFail:
+		settings.fail = true
+	default:
+		return nil, fmt.Errorf("invalid analyze_table.on_failure %q (must be warn or fail)", cfg.OnFailure)
+	}
+	return settings, nil
+}
+
+// analyzeTable は ANALYZE TABLE を実行し、失敗した場合は on_failure に従ってエラーを返すか警告にとどめる
+func (m *Manager) analyzeTable(phase, tableName string, settings *analyzeSettings) error {
+	if m.dryRun {
+		m.logger.Infof("[DRY RUN] Would execute ANALYZE TABLE for %s %s", tableName, phase)
+		return nil
+	}
+
+	m.logger.Infof("Executing ANALYZE TABLE for %s %s", tableName, phase)
+	stopMonitor := m.startPhaseMonitor(PhaseAnalyze, "analyze-table", tableName, "`ANALYZE TABLE "+tableName+"`")
+	err := m.db.AnalyzeTable(tableName, settings.timeout)
+	stopMonitor()
+	if err == nil {
+		return nil
+	}
+	if settings.fail {
+		return fmt.Errorf("ANALYZE TABLE %s %s failed: %w", tableName, phase, err)
+	}
+	m.logger.Warnf("ANALYZE TABLE failed for %s: %v", tableName, err)
+	return nil
+}
@@ -0,0 +1,123 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveAnalyzeSettings(t *testing.T) {
+	enabled, disabled := true, false
+
+	tests := []struct {
+		name        string
+		disable     bool
+		cfg         config.AnalyzeTableConfig
+		want        *analyzeSettings
+		errContains string
+	}{
+		{name: "default", want: &analyzeSettings{beforeSwap: true}},
+		{name: "legacy disable", disable: true, want: &analyzeSettings{}},
+		{
+			name: "after swap with timeout and fail",
+			cfg:  config.AnalyzeTableConfig{AfterSwap: &enabled, Timeout: "5m", OnFailure: "fail"},
+			want: &analyzeSettings{beforeSwap: true, afterSwap: true, timeout: 5 * time.Minute, fail: true},
+		},
+		{
+			name: "per-table override",
+			cfg: config.AnalyzeTableConfig{
+				AfterSwap: &enabled,
+				Tables:    map[string]config.AnalyzeTableOverride{"users": {BeforeSwap: &disabled, AfterSwap: &disabled}},
+			},
+			want: &analyzeSettings{},
+		},
+		{name: "invalid timeout", cfg: config.AnalyzeTableConfig{Timeout: "soon"}, errContains: "invalid analyze_table.timeout"},
+		{name: "invalid on_failure", cfg: config.AnalyzeTableConfig{OnFailure: "ignore"}, errContains: "invalid analyze_table.on_failure"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			cfg := &config.Config{Common: config.CommonConfig{DisableAnalyzeTable: tt.disable, AnalyzeTable: tt.cfg}}
+			manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+			settings, err := manager.resolveAnalyzeSettings("users")
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, settings)
+		})
+	}
+}
+
+func newAnalyzeSwapMocks() (*MockDBClient, *MockSlackNotifier) {
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "users").Return(true, nil)
+	mockDB.On("TableExists", "_users_new").Return(true, nil)
+	mockDB.On("TableExists", "users_old").Return(false, nil)
+	mockDB.On("GetTableRowCountForSwap", "users").Return(int64(1000), nil)
+	mockDB.On("GetNewTableRowCountForSwap", "users").Return(int64(1000), nil)
+	mockDB.On("GetTableDependencies", "users").Return(nil, nil)
+	mockDB.On("GetTableCharsetInfo", mock.Anything).Return(&database.TableCharsetInfo{}, nil)
+	mockDB.On("SetSessionConfig", 0, 0).Return(nil)
+	mockDB.On("GetAutoIncrementColumn", "users").Return("", nil)
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQuery", "swap", "users", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifySuccessWithQuery", "swap", "users", mock.Anything, int64(0), mock.Anything).Return(nil)
+	mockSlack.On("NotifyFailure", "swap", "users", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return mockDB, mockSlack
+}
+
+func TestSwapTable_AnalyzeFailureBeforeSwapAborts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB, mockSlack := newAnalyzeSwapMocks()
+	mockDB.On("AnalyzeTable", "_users_new", time.Minute).Return(errors.New("Lock wait timeout exceeded"))
+
+	cfg := &config.Config{Common: config.CommonConfig{
+		AnalyzeTable: config.AnalyzeTableConfig{Timeout: "1m", OnFailure: "fail"},
+	}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	err := manager.SwapTable("users")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ANALYZE TABLE _users_new before swap failed")
+	mockDB.AssertNotCalled(t, "ExecuteAlter", mock.Anything)
+}
+
+func TestSwapTable_AnalyzeAfterSwap(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	enabled, disabled := true, false
+	mockDB, mockSlack := newAnalyzeSwapMocks()
+	mockDB.On("ExecuteAlter", "RENAME TABLE users TO users_old, _users_new TO users").Return(nil)
+	mockDB.On("AnalyzeTable", "users", time.Duration(0)).Return(errors.New("timeout"))
+
+	cfg := &config.Config{Common: config.CommonConfig{
+		AnalyzeTable: config.AnalyzeTableConfig{
+			OnFailure: "fail",
+			Tables:    map[string]config.AnalyzeTableOverride{"users": {BeforeSwap: &disabled, AfterSwap: &enabled}},
+		},
+	}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	err := manager.SwapTable("users")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "swap completed but ANALYZE TABLE users after swap failed")
+	mockDB.AssertCalled(t, "ExecuteAlter", "RENAME TABLE users TO users_old, _users_new TO users")
+	mockDB.AssertNotCalled(t, "AnalyzeTable", "_users_new", mock.Anything)
+}
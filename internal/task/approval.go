@@ -0,0 +1,169 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/approval"
+	"github.com/pyama86/alterguard/internal/ptosc"
+)
+
+// DefaultPlanRowTolerance は承認済みの計画と比べて許容する行数の変化(%)のデフォルト。
+// 行数は統計情報の推定値のため、書き込みがなくても多少は変動する。
+const DefaultPlanRowTolerance = 10.0
+
+var createTableAutoIncrementRe = regexp.MustCompile(`\s*AUTO_INCREMENT=\d+`)
+
+// SetPlanFile を設定すると、dry run で決まった実行方式と pt-osc の引数を承認用の計画として書き出す
+func (m *Manager) SetPlanFile(path string) {
+	m.planFile = path
+}
+
+// SetApprovedPlan を設定すると、各テーブルの変更・実行方式・pt-osc の引数・テーブル定義が計画と同じで、
+// 行数の変化が tolerance(%) 以内であることを確認してから実行する。違っていれば実行しない。
+func (m *Manager) SetApprovedPlan(plan *approval.Plan, tolerance float64) {
+	m.approvedPlan = plan
+	m.planRowTolerance = tolerance
+	m.executedPlanTables = make(map[*approval.Table]bool)
+}
+
+// checkPlan は dry run では計画にテーブルを追加し、承認済みの計画があれば今の状態と照合する
+func (m *Manager) checkPlan(tableName, method string, rowCount int64, alterParts []string) error {
+	recording := m.planFile != "" && m.dryRun
+	if !recording && m.approvedPlan == nil {
+		return nil
+	}
+
+	current, err := m.describePlannedTable(tableName, method, rowCount, alterParts)
+	if err != nil {
+		return err
+	}
+
+	if recording {
+		m.plannedTables = append(m.plannedTables, *current)
+		return nil
+	}
+
+	approved, ok := m.approvedPlan.FindTable(tableName)
+	// 依存関係で同じテーブルが複数回に分けて変更される場合は、同じ変更の計画と照合する
+	for i := range m.approvedPlan.Tables {
+		if planned := &m.approvedPlan.Tables[i]; planned.TableName == tableName && reflect.DeepEqual(planned.AlterParts, current.AlterParts) {
+			approved = planned
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("refusing to run %s: the table is not in the approved plan", tableName)
+	}
+	if !reflect.DeepEqual(approved.AlterParts, current.AlterParts) {
+		return fmt.Errorf("refusing to run %s: the ALTER changed since the plan was generated (planned: %s, now: %s)",
+			tableName, strings.Join(approved.AlterParts, ", "), strings.Join(current.AlterParts, ", "))
+	}
+	if approved.Method != current.Method {
+		return fmt.Errorf("refusing to run %s: the method changed from %s to %s since the plan was generated",
+			tableName, approved.Method, current.Method)
+	}
+	if approved.SchemaFingerprint != current.SchemaFingerprint {
+		return fmt.Errorf("refusing to run %s: the table definition changed since the plan was generated", tableName)
+	}
+	allowed := float64(approved.RowCount) * m.planRowTolerance / 100
+	if diff := math.Abs(float64(current.RowCount - approved.RowCount)); diff > allowed {
+		return fmt.Errorf("refusing to run %s: the row count changed from %d to %d since the plan was generated (tolerance: %.1f%%)",
+			tableName, approved.RowCount, current.RowCount, m.planRowTolerance)
+	}
+	if !reflect.DeepEqual(approved.PtOscArgs, current.PtOscArgs) {
+		return fmt.Errorf("refusing to run %s: the pt-osc arguments changed since the plan was generated (planned: %s, now: %s)",
+			tableName, strings.Join(approved.PtOscArgs, " "), strings.Join(current.PtOscArgs, " "))
+	}
+
+	m.executedPlanTables[approved] = true
+	m.logger.Infof("Table %s matches the approved plan", tableName)
+	return nil
+}
+
+// checkApprovedPlanExecuted は承認済みの計画のうち実行されなかったテーブルがあればエラーを返す。
+// 承認した変更の一部が適用されないまま、実行が成功したと扱わないようにする
+func (m *Manager) checkApprovedPlanExecuted() error {
+	if m.approvedPlan == nil {
+		return nil
+	}
+	var missing []string
+	for i := range m.approvedPlan.Tables {
+		if table := &m.approvedPlan.Tables[i]; !m.executedPlanTables[table] {
+			missing = append(missing, fmt.Sprintf("%s (%s)", table.TableName, strings.Join(table.AlterParts, ", ")))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	m.logger.Errorf("Tables in the approved plan did not run: %s", strings.Join(missing, "; "))
+	return fmt.Errorf("%d table(s) in the approved plan did not run: %s", len(missing), strings.Join(missing, "; "))
+}
+
+func (m *Manager) describePlannedTable(tableName, method string, rowCount int64, alterParts []string) (*approval.Table, error) {
+	createTable, err := m.db.GetCreateTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	// AUTO_INCREMENT は書き込みのたびに変わるため比較しない
+	sum := sha256.Sum256([]byte(createTableAutoIncrementRe.ReplaceAllString(createTable, "")))
+
+	table := &approval.Table{
+		TableName:         tableName,
+		Method:            method,
+		AlterParts:        alterParts,
+		RowCount:          rowCount,
+		SchemaFingerprint: hex.EncodeToString(sum[:]),
+	}
+	if method == "pt-osc" {
+		if ptOscExecutor, ok := m.ptosc.(*ptosc.PtOscExecutor); ok {
+			// 承認後に実行するコマンドを記録するため、dry run でも --execute の引数を組み立てる
+			args, _, err := ptOscExecutor.BuildArgsWithPassword(tableName, combineAlterParts(alterParts), m.config.Common.PtOsc, m.config.DSN, false)
+			if err != nil {
+				return nil, fmt.Errorf("failed to build pt-osc args for %s: %w", tableName, err)
+			}
+			table.PtOscArgs = args
+		}
+	}
+	return table, nil
+}
+
+// writePlanFile は dry run の最後に承認用の計画を書き出し、実行されるコマンドを通知する
+func (m *Manager) writePlanFile() error {
+	if m.planFile == "" || !m.dryRun {
+		return nil
+	}
+
+	plan := &approval.Plan{
+		GeneratedAt: time.Now(),
+		Environment: m.config.Environment,
+		Queries:     m.config.Queries,
+		Tasks:       m.config.Tasks,
+		Tables:      m.plannedTables,
+	}
+	if err := approval.Write(m.planFile, plan); err != nil {
+		return err
+	}
+	m.logger.Infof("Plan for approval written to %s", m.planFile)
+
+	var commands []string
+	for _, table := range plan.Tables {
+		if table.PtOscArgs != nil {
+			commands = append(commands, "pt-online-schema-change "+strings.Join(table.PtOscArgs, " "))
+			continue
+		}
+		for _, part := range table.AlterParts {
+			commands = append(commands, fmt.Sprintf("ALTER TABLE %s %s", table.TableName, part))
+		}
+	}
+	if err := m.slack.NotifyPlanForApproval(m.planFile, commands); err != nil {
+		m.logger.Errorf("Failed to send plan notification: %v", err)
+	}
+	return nil
+}
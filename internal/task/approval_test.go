@@ -0,0 +1,158 @@
+package task
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/approval"
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanFileRoundTrip(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	path := filepath.Join(t.TempDir(), "plan.json")
+	cfg := &config.Config{Environment: "prod", Queries: []string{"ALTER TABLE users ADD COLUMN age INT"}}
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetCreateTable", "users").Return("CREATE TABLE `users` (`id` int) ENGINE=InnoDB AUTO_INCREMENT=100", nil).Once()
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyPlanForApproval", path, []string{"ALTER TABLE users ADD COLUMN age INT"}).Return(nil)
+
+	planner := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, true)
+	planner.SetPlanFile(path)
+	require.NoError(t, planner.checkPlan("users", "alter-table", 100, []string{"ADD COLUMN age INT"}))
+	require.NoError(t, planner.writePlanFile())
+	mockSlack.AssertExpectations(t)
+
+	plan, err := approval.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", plan.Environment)
+	assert.Equal(t, cfg.Queries, plan.Queries)
+	require.Len(t, plan.Tables, 1)
+	assert.Equal(t, int64(100), plan.Tables[0].RowCount)
+
+	tests := []struct {
+		name        string
+		createTable string
+		method      string
+		rowCount    int64
+		alterParts  []string
+		wantErr     string
+	}{
+		{
+			name:        "matches despite auto increment and small row count change",
+			createTable: "CREATE TABLE `users` (`id` int) ENGINE=InnoDB AUTO_INCREMENT=250",
+			method:      "alter-table",
+			rowCount:    105,
+			alterParts:  []string{"ADD COLUMN age INT"},
+		},
+		{
+			name:        "definition changed",
+			createTable: "CREATE TABLE `users` (`id` bigint) ENGINE=InnoDB AUTO_INCREMENT=100",
+			method:      "alter-table",
+			rowCount:    100,
+			alterParts:  []string{"ADD COLUMN age INT"},
+			wantErr:     "table definition changed",
+		},
+		{
+			name:        "method changed",
+			createTable: "CREATE TABLE `users` (`id` int) ENGINE=InnoDB AUTO_INCREMENT=100",
+			method:      "pt-osc",
+			rowCount:    100,
+			alterParts:  []string{"ADD COLUMN age INT"},
+			wantErr:     "method changed from alter-table to pt-osc",
+		},
+		{
+			name:        "row count changed",
+			createTable: "CREATE TABLE `users` (`id` int) ENGINE=InnoDB AUTO_INCREMENT=100",
+			method:      "alter-table",
+			rowCount:    150,
+			alterParts:  []string{"ADD COLUMN age INT"},
+			wantErr:     "row count changed from 100 to 150",
+		},
+		{
+			name:        "alter changed",
+			createTable: "CREATE TABLE `users` (`id` int) ENGINE=InnoDB AUTO_INCREMENT=100",
+			method:      "alter-table",
+			rowCount:    100,
+			alterParts:  []string{"ADD COLUMN age BIGINT"},
+			wantErr:     "ALTER changed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockDBClient{}
+			mockDB.On("GetCreateTable", "users").Return(tt.createTable, nil)
+
+			runner := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+			runner.SetApprovedPlan(plan, DefaultPlanRowTolerance)
+
+			err := runner.checkPlan("users", tt.method, tt.rowCount, tt.alterParts)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCheckPlan_RejectsTableOutsidePlan(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetCreateTable", "orders").Return("CREATE TABLE `orders` (`id` int)", nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	manager.SetApprovedPlan(&approval.Plan{Queries: []string{"ALTER TABLE users ADD COLUMN age INT"}}, DefaultPlanRowTolerance)
+
+	err := manager.checkPlan("orders", "alter-table", 10, []string{"ADD COLUMN note TEXT"})
+	assert.ErrorContains(t, err, "not in the approved plan")
+}
+
+func TestCheckApprovedPlanExecuted_ReportsTablesThatDidNotRun(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetCreateTable", "users").Return("CREATE TABLE `users` (`id` int)", nil)
+	mockDB.On("GetCreateTable", "orders").Return("CREATE TABLE `orders` (`id` int)", nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	plan := &approval.Plan{}
+	for _, tableName := range []string{"users", "orders"} {
+		table, err := manager.describePlannedTable(tableName, "alter-table", 10, []string{"ADD COLUMN note TEXT"})
+		require.NoError(t, err)
+		plan.Tables = append(plan.Tables, *table)
+	}
+	manager.SetApprovedPlan(plan, DefaultPlanRowTolerance)
+
+	require.NoError(t, manager.checkPlan("users", "alter-table", 10, []string{"ADD COLUMN note TEXT"}))
+	err := manager.checkApprovedPlanExecuted()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 table(s) in the approved plan did not run: orders (ADD COLUMN note TEXT)")
+	assert.NotContains(t, err.Error(), "users")
+
+	require.NoError(t, manager.checkPlan("orders", "alter-table", 10, []string{"ADD COLUMN note TEXT"}))
+	assert.NoError(t, manager.checkApprovedPlanExecuted())
+}
+
+func TestCheckPlan_DoesNothingWithoutPlan(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, true)
+
+	require.NoError(t, manager.checkPlan("users", "alter-table", 10, []string{"ADD COLUMN age INT"}))
+	require.NoError(t, manager.writePlanFile())
+	require.NoError(t, manager.checkApprovedPlanExecuted())
+	mockDB.AssertNotCalled(t, "GetCreateTable", mock.Anything)
+}
@@ -0,0 +1,295 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+)
+
+// deletedRowsReporter は pt-archiver の出力から削除済みの行数を返せる Executor
+type deletedRowsReporter interface {
+	GetDeletedRows() int64
+}
+
+// purgeResultReporter は pt-archiver の統計情報と終了理由 (--why-quit) を返せる Executor
+type purgeResultReporter interface {
+	GetPurgeResult() *ptarchiver.PurgeResult
+}
+
+func (m *Manager) lastPurgeResult() *ptarchiver.PurgeResult {
+	if reporter, ok := m.ptarchiver.(purgeResultReporter); ok {
+		return reporter.GetPurgeResult()
+	}
+	return nil
+}
+
+// executePurge は pt-archiver を実行する。--run-time で止まったなど、エラーではなく行を残して終了した場合は、
+// 行がなくなるまで繰り返す。pt_archiver.adaptive が有効なら pass_duration ごとに区切って実行し、
+// 合間に測ったレプリカ遅延で --limit と --sleep を変える。pt_archiver.until_empty が有効なら、終了理由ではなく
+// 残りの行数で繰り返すかを決める。速度を変えた判断のまとめを返す (adaptive でなければ空)
+func (m *Manager) executePurge(ctx context.Context, tableName string) (string, error) {
+	cfg := m.config.Common.PtArchiver
+	m.purgedRowsBefore.Store(0)
+
+	var pacing *archiverPacing
+	pace := archiverPace{limit: cfg.Limit, sleep: cfg.Sleep}
+	if cfg.Adaptive.Enabled {
+		var err error
+		pacing, err = resolveArchiverPacing(cfg)
+		if err != nil {
+			return "", err
+		}
+		if len(m.lagReplicas) == 0 {
+			return "", fmt.Errorf("pt_archiver.adaptive requires replica_lag.replicas to measure replica lag")
+		}
+		pace = pacing.initialPace(cfg)
+	}
+
+	untilEmpty, err := m.resolvePurgeUntilEmpty(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	var decisions []string
+	var total int64
+	passes := 0
+	start := time.Now()
+	for {
+		passes++
+		passCfg := cfg
+		if pacing != nil {
+			passCfg.Limit = pace.limit
+			passCfg.Sleep = pace.sleep
+			passCfg.RunTime = fmt.Sprintf("%ds", int(pacing.passDuration.Seconds()))
+			// --why-quit の出力がない場合も、パスで削除した行数から終わりを判断できるようにする
+			passCfg.Statistics = true
+			m.logger.Infof("pt-archiver pass %d on %s: limit %d, sleep %ds, run time %s", passes, tableName, pace.limit, pace.sleep, passCfg.RunTime)
+		}
+
+		m.purgedRowsBefore.Store(total)
+		err := m.ptarchiver.ExecutePurge(ctx, tableName, passCfg, m.config.DSN, m.dryRun)
+		result := m.lastPurgeResult()
+		if result != nil {
+			m.logger.Infof("pt-archiver pass %d on %s: %s", passes, tableName, result)
+		}
+		if err != nil {
+			if pacing != nil {
+				return summarizeArchiverPacing(passes, pace, decisions), err
+			}
+			return "", err
+		}
+		if m.dryRun {
+			break
+		}
+
+		deleted := m.archiverDeletedRows() - total
+		total += deleted
+		if untilEmpty != nil {
+			more, err := m.purgeUntilEmptyNext(tableName, untilEmpty, result, passes, deleted, total, start)
+			if err != nil {
+				if pacing != nil {
+					return summarizeArchiverPacing(passes, pace, decisions), err
+				}
+				return "", err
+			}
+			if !more {
+				break
+			}
+		} else if !m.purgeRowsRemain(result, deleted, pacing != nil) {
+			break
+		}
+
+		if pacing == nil {
+			if untilEmpty != nil {
+				continue
+			}
+			m.logger.Infof("pt-archiver stopped on %s before the rows ran out (%s); starting pass %d", tableName, result.QuitMessage, passes+1)
+			continue
+		}
+		decision := fmt.Sprintf("replica lag unavailable, keeping limit %d and sleep %ds", pace.limit, pace.sleep)
+		if lag, ok := m.maxReplicaLag(); ok {
+			pace, decision = pacing.next(pace, lag)
+		}
+		m.logger.Infof("Adaptive pacing on %s after pass %d (%d rows deleted): %s", tableName, passes, deleted, decision)
+		decisions = append(decisions, fmt.Sprintf("pass %d (%d rows): %s", passes, deleted, decision))
+	}
+
+	if pacing == nil {
+		return "", nil
+	}
+	summary := summarizeArchiverPacing(passes, pace, decisions)
+	m.logger.Infof("Adaptive pacing summary for %s:\n%s", tableName, summary)
+	return summary, nil
+}
+
+// purgeRowsRemain は pt-archiver を続けて実行すべきかを返す。
+// 終了理由が分かればそれに従い、--run-time で止まった場合だけ続ける。分からなければ、adaptive のときだけ削除した行数で判断する
+func (m *Manager) purgeRowsRemain(result *ptarchiver.PurgeResult, deleted int64, adaptive bool) bool {
+	if result != nil && result.QuitReason != ptarchiver.QuitUnknown {
+		if result.QuitReason == ptarchiver.QuitSentinel {
+			m.logger.Warnf("pt-archiver stopped because of its sentinel file; rows may remain")
+		}
+		return result.RowsMayRemain()
+	}
+	if !adaptive {
+		return false
+	}
+	if _, ok := m.ptarchiver.(deletedRowsReporter); !ok {
+		m.logger.Warnf("Deleted rows of pt-archiver cannot be read; adaptive pacing runs a single pass")
+		return false
+	}
+	return deleted > 0
+}
+
+// purgeUntilEmpty は pt_archiver.until_empty の上限を解決したもの
+type purgeUntilEmpty struct {
+	maxIterations int
+	maxDuration   time.Duration
+	countTimeout  time.Duration
+}
+
+// resolvePurgeUntilEmpty は pt_archiver.until_empty が有効なら上限を返す。無効なら nil を返す
+func (m *Manager) resolvePurgeUntilEmpty(cfg config.PtArchiverConfig) (*purgeUntilEmpty, error) {
+	if !cfg.UntilEmpty.Enabled {
+		return nil, nil
+	}
+	// where に合わない行は消えないので、テーブルが空になることはない
+	if cfg.Where != "" {
+		return nil, fmt.Errorf("pt_archiver.until_empty cannot be used with pt_archiver.where, because rows outside the condition are never purged")
+	}
+	if cfg.UntilEmpty.MaxIterations < 0 {
+		return nil, fmt.Errorf("pt_archiver.until_empty.max_iterations must not be negative")
+	}
+	maxDuration, err := resolveTimeout("pt_archiver.until_empty.max_duration", cfg.UntilEmpty.MaxDuration)
+	if err != nil {
+		return nil, err
+	}
+	countTimeout, err := resolveTimeout("pt_archiver.until_empty.count_timeout", cfg.UntilEmpty.CountTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &purgeUntilEmpty{maxIterations: cfg.UntilEmpty.MaxIterations, maxDuration: maxDuration, countTimeout: countTimeout}, nil
+}
+
+// purgeUntilEmptyNext は pt_archiver.until_empty で次のパスを実行するかを返す。
+// 残りの行数を見積もって進捗を通知し、0 なら終わる。行が残ったまま上限に達した場合や、
+// パスで1行も削除できなかった場合は、DROP の前に大量の行を残さないようにエラーにする
+func (m *Manager) purgeUntilEmptyNext(tableName string, bounds *purgeUntilEmpty, result *ptarchiver.PurgeResult, passes int, deleted, total int64, start time.Time) (bool, error) {
+	remaining, err := m.remainingPurgeRows(tableName, bounds, result, deleted)
+	if err != nil {
+		return false, err
+	}
+	if remaining == 0 {
+		m.logger.Infof("%s is empty after %d pt-archiver passes", tableName, passes)
+		return false, nil
+	}
+
+	elapsed := time.Since(start)
+	m.logger.Infof("Purge progress on %s after pass %d: %d rows deleted, %d rows remain, elapsed %s",
+		tableName, passes, total, remaining, elapsed.Round(time.Second))
+	if err := m.slack.NotifyArchiverProgress(tableName, total, total+remaining, elapsed); err != nil {
+		m.logger.Errorf("Failed to send purge progress notification: %v", err)
+	}
+
+	if result != nil && result.QuitReason == ptarchiver.QuitSentinel {
+		return false, fmt.Errorf("pt-archiver was stopped by its sentinel file with %d rows remaining in %s", remaining, tableName)
+	}
+	if _, ok := m.ptarchiver.(deletedRowsReporter); ok && deleted == 0 {
+		return false, fmt.Errorf("pt-archiver deleted no rows from %s in pass %d but %d rows remain", tableName, passes, remaining)
+	}
+	if bounds.maxIterations > 0 && passes >= bounds.maxIterations {
+		return false, fmt.Errorf("%d rows remain in %s after %d pt-archiver passes (pt_archiver.until_empty.max_iterations: %d)",
+			remaining, tableName, passes, bounds.maxIterations)
+	}
+	if bounds.maxDuration > 0 && elapsed >= bounds.maxDuration {
+		return false, fmt.Errorf("%d rows remain in %s after purging for %s (pt_archiver.until_empty.max_duration: %s)",
+			remaining, tableName, elapsed.Round(time.Second), bounds.maxDuration)
+	}
+
+	m.logger.Infof("Starting pt-archiver pass %d on %s to purge the remaining %d rows", passes+1, tableName, remaining)
+	return true, nil
+}
+
+// remainingPurgeRows はパスの後に残っている行数を返す。大きなテーブルで毎回 COUNT(*) を実行しないよう統計情報で見積もり、
+// 見積もりが 0 になったときや、pt-archiver が行が尽きたと報告したときだけ COUNT(*) で確かめる。
+// 削除の直後は統計情報が古いことがあるため、見積もりだけで空とも空でないとも決めない
+func (m *Manager) remainingPurgeRows(tableName string, bounds *purgeUntilEmpty, result *ptarchiver.PurgeResult, deleted int64) (int64, error) {
+	estimate, err := m.db.GetTableRowCount(tableName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate remaining rows of %s: %w", tableName, err)
+	}
+	exhausted := result != nil && result.QuitReason == ptarchiver.QuitNoMoreRows
+	if _, ok := m.ptarchiver.(deletedRowsReporter); ok && deleted == 0 {
+		exhausted = true
+	}
+	if estimate > 0 && !exhausted {
+		return estimate, nil
+	}
+
+	remaining, err := m.db.CountTableRows(tableName, bounds.countTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count remaining rows of %s: %w", tableName, err)
+	}
+	return remaining, nil
+}
+
+// archiverDeletedRows は今回のパージで削除した行数を返す。pt_archiver.adaptive で繰り返した場合は前のパスの分も含める
+func (m *Manager) archiverDeletedRows() int64 {
+	if reporter, ok := m.ptarchiver.(deletedRowsReporter); ok {
+		return m.purgedRowsBefore.Load() + reporter.GetDeletedRows()
+	}
+	return 0
+}
+
+// startArchiverProgress は pt_archiver.notify_interval ごとに削除の進捗を通知する goroutine を起動し、
+// 停止するための関数を返す。ETA は開始時点の _old テーブルの行数(統計情報)から計算する。
+func (m *Manager) startArchiverProgress(tableName string) (func(), error) {
+	cfg := m.config.Common.PtArchiver
+	interval, err := resolveRollingDuration(cfg.NotifyInterval, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pt_archiver.notify_interval: %w", err)
+	}
+	_, ok := m.ptarchiver.(deletedRowsReporter)
+	if interval <= 0 || !ok || m.dryRun {
+		return func() {}, nil
+	}
+	if cfg.Progress <= 0 && !cfg.Statistics && !cfg.Adaptive.Enabled {
+		m.logger.Warnf("pt_archiver.notify_interval is set but progress is not; deleted rows will not be reported until pt-archiver finishes")
+	}
+
+	totalRows, err := m.db.GetTableRowCount(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get row count for %s: %v", tableName, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			deletedRows := m.archiverDeletedRows()
+			elapsed := time.Since(start)
+			m.logger.Infof("Purge progress on %s: %d/%d rows deleted, elapsed %s",
+				tableName, deletedRows, totalRows, elapsed.Round(time.Second))
+			if err := m.slack.NotifyArchiverProgress(tableName, deletedRows, totalRows, elapsed); err != nil {
+				m.logger.Errorf("Failed to send purge progress notification: %v", err)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
@@ -0,0 +1,116 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+)
+
+const (
+	defaultAdaptivePassDuration = time.Minute
+	defaultAdaptiveMinLimit     = 10
+	defaultAdaptiveMaxSleep     = 10
+	// limit を指定していない場合の最初の --limit。pt-archiver の既定の 1 では遅すぎる
+	defaultAdaptiveLimit = 100
+)
+
+// archiverPace は1回の pt-archiver の実行で使う --limit と --sleep(秒)
+type archiverPace struct {
+	limit int
+	sleep int
+}
+
+// archiverPacing は pt_archiver.adaptive に既定値を補ったもの
+type archiverPacing struct {
+	passDuration time.Duration
+	targetLag    float64
+	minLimit     int
+	maxLimit     int
+	maxSleep     int
+}
+
+func resolveArchiverPacing(cfg config.PtArchiverConfig) (*archiverPacing, error) {
+	adaptive := cfg.Adaptive
+	if adaptive.TargetLagSeconds <= 0 {
+		return nil, fmt.Errorf("pt_archiver.adaptive.target_lag_seconds must be positive")
+	}
+	passDuration, err := resolveRollingDuration(adaptive.PassDuration, defaultAdaptivePassDuration)
+	if err != nil || passDuration < time.Second {
+		return nil, fmt.Errorf("invalid pt_archiver.adaptive.pass_duration %q: must be a duration of at least 1s", adaptive.PassDuration)
+	}
+
+	pacing := &archiverPacing{
+		passDuration: passDuration,
+		targetLag:    adaptive.TargetLagSeconds,
+		minLimit:     adaptive.MinLimit,
+		maxLimit:     adaptive.MaxLimit,
+		maxSleep:     adaptive.MaxSleep,
+	}
+	if pacing.minLimit <= 0 {
+		pacing.minLimit = defaultAdaptiveMinLimit
+	}
+	if pacing.maxLimit <= 0 {
+		pacing.maxLimit = max(initialArchiverLimit(cfg)*10, 1000)
+	}
+	if pacing.maxSleep <= 0 {
+		pacing.maxSleep = defaultAdaptiveMaxSleep
+	}
+	if pacing.minLimit > pacing.maxLimit {
+		return nil, fmt.Errorf("pt_archiver.adaptive.min_limit (%d) must not exceed max_limit (%d)", pacing.minLimit, pacing.maxLimit)
+	}
+	return pacing, nil
+}
+
+func initialArchiverLimit(cfg config.PtArchiverConfig) int {
+	if cfg.Limit > 0 {
+		return cfg.Limit
+	}
+	return defaultAdaptiveLimit
+}
+
+// initialPace は pt_archiver.limit と sleep を上限・下限に収めて最初のパスのペースにする
+func (p *archiverPacing) initialPace(cfg config.PtArchiverConfig) archiverPace {
+	return archiverPace{
+		limit: min(max(initialArchiverLimit(cfg), p.minLimit), p.maxLimit),
+		sleep: min(cfg.Sleep, p.maxSleep),
+	}
+}
+
+// next は直前のパスの後に測ったレプリカ遅延から次のパスのペースを決め、判断の内容を返す。
+// 目標を超えたら --limit を半分にして --sleep を倍にする。目標の半分を下回ったら、先に --sleep を半分にし、
+// --sleep が 0 になってから --limit を倍にする
+func (p *archiverPacing) next(pace archiverPace, lag float64) (archiverPace, string) {
+	next := pace
+	var action string
+	switch {
+	case lag > p.targetLag:
+		next.limit = max(p.minLimit, pace.limit/2)
+		next.sleep = min(p.maxSleep, max(1, pace.sleep*2))
+		action = "slowing down"
+	case lag < p.targetLag/2:
+		if pace.sleep > 0 {
+			next.sleep = pace.sleep / 2
+		} else {
+			next.limit = min(p.maxLimit, pace.limit*2)
+		}
+		action = "speeding up"
+	default:
+		action = "keeping the pace"
+	}
+	if next == pace && action != "keeping the pace" {
+		action = "already at the bound, keeping the pace"
+	}
+	return next, fmt.Sprintf("lag %.1fs (target %.1fs): %s, limit %d -> %d, sleep %ds -> %ds",
+		lag, p.targetLag, action, pace.limit, next.limit, pace.sleep, next.sleep)
+}
+
+func summarizeArchiverPacing(passes int, pace archiverPace, decisions []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Adaptive pacing: %d passes, final limit %d, sleep %ds", passes, pace.limit, pace.sleep)
+	for _, decision := range decisions {
+		fmt.Fprintf(&b, "\n%s", decision)
+	}
+	return b.String()
+}
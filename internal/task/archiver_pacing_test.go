@@ -0,0 +1,129 @@
+package task
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiverPacingNext(t *testing.T) {
+	pacing := &archiverPacing{targetLag: 2, minLimit: 10, maxLimit: 1000, maxSleep: 8}
+
+	tests := []struct {
+		name string
+		pace archiverPace
+		lag  float64
+		want archiverPace
+	}{
+		{name: "lag above target", pace: archiverPace{limit: 400, sleep: 0}, lag: 5, want: archiverPace{limit: 200, sleep: 1}},
+		{name: "lag above target with sleep", pace: archiverPace{limit: 200, sleep: 1}, lag: 3, want: archiverPace{limit: 100, sleep: 2}},
+		{name: "slowing down stops at the bounds", pace: archiverPace{limit: 15, sleep: 8}, lag: 3, want: archiverPace{limit: 10, sleep: 8}},
+		{name: "lag well below target reduces sleep first", pace: archiverPace{limit: 100, sleep: 4}, lag: 0.2, want: archiverPace{limit: 100, sleep: 2}},
+		{name: "lag well below target without sleep raises limit", pace: archiverPace{limit: 600, sleep: 0}, lag: 0, want: archiverPace{limit: 1000, sleep: 0}},
+		{name: "lag near target", pace: archiverPace{limit: 100, sleep: 1}, lag: 1.5, want: archiverPace{limit: 100, sleep: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, decision := pacing.next(tt.pace, tt.lag)
+			assert.Equal(t, tt.want, got)
+			assert.NotEmpty(t, decision)
+		})
+	}
+}
+
+func TestResolveArchiverPacing(t *testing.T) {
+	pacing, err := resolveArchiverPacing(config.PtArchiverConfig{Limit: 500, Adaptive: config.PtArchiverAdaptiveConfig{Enabled: true, TargetLagSeconds: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, &archiverPacing{passDuration: defaultAdaptivePassDuration, targetLag: 1, minLimit: 10, maxLimit: 5000, maxSleep: 10}, pacing)
+
+	_, err = resolveArchiverPacing(config.PtArchiverConfig{Adaptive: config.PtArchiverAdaptiveConfig{Enabled: true}})
+	assert.ErrorContains(t, err, "target_lag_seconds")
+
+	_, err = resolveArchiverPacing(config.PtArchiverConfig{Adaptive: config.PtArchiverAdaptiveConfig{Enabled: true, TargetLagSeconds: 1, PassDuration: "500ms"}})
+	assert.ErrorContains(t, err, "pass_duration")
+
+	_, err = resolveArchiverPacing(config.PtArchiverConfig{Adaptive: config.PtArchiverAdaptiveConfig{Enabled: true, TargetLagSeconds: 1, MinLimit: 100, MaxLimit: 50}})
+	assert.ErrorContains(t, err, "min_limit")
+}
+
+func TestPurgeOldTableAdaptive(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	archiverConfig := config.PtArchiverConfig{
+		Limit:    400,
+		Adaptive: config.PtArchiverAdaptiveConfig{Enabled: true, TargetLagSeconds: 2, PassDuration: "30s"},
+	}
+	cfg := &config.Config{
+		DSN:    "user:password@tcp(localhost:3306)/testdb",
+		Common: config.CommonConfig{PtArchiver: archiverConfig},
+	}
+
+	// フェーズの開始時、パス1とパス2の後、フェーズの終了時に測る
+	replicaDB := &MockDBClient{}
+	replicaDB.On("GetReplicaLagSeconds").Return(0.0, nil).Once()
+	replicaDB.On("GetReplicaLagSeconds").Return(5.0, nil).Once()
+	replicaDB.On("GetReplicaLagSeconds").Return(0.5, nil).Once()
+	replicaDB.On("GetReplicaLagSeconds").Return(0.0, nil).Once()
+
+	archiver := &progressPtArchiverExecutor{}
+	pass := func(limit, sleep int) any {
+		return mock.MatchedBy(func(c config.PtArchiverConfig) bool {
+			return c.Limit == limit && c.Sleep == sleep && c.RunTime == "30s" && c.Statistics
+		})
+	}
+	archiver.On("ExecutePurge", "users_old", pass(400, 0), cfg.DSN, false).Run(func(args mock.Arguments) {
+		archiver.deletedRows.Store(1000)
+	}).Return(nil).Once()
+	archiver.On("ExecutePurge", "users_old", pass(200, 1), cfg.DSN, false).Run(func(args mock.Arguments) {
+		archiver.deletedRows.Store(500)
+	}).Return(nil).Once()
+	archiver.On("ExecutePurge", "users_old", pass(200, 0), cfg.DSN, false).Run(func(args mock.Arguments) {
+		archiver.deletedRows.Store(0)
+	}).Return(nil).Once()
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifyReplicaLag", "pt-archiver", "users_old", PhasePtArchiver, mock.Anything).Return(nil)
+	mockSlack.On("NotifyPurgeSuccess", "pt-archiver", "users_old", mock.Anything, int64(1500), mock.Anything, mock.MatchedBy(func(log string) bool {
+		return strings.Contains(log, "Adaptive pacing: 3 passes, final limit 200, sleep 0s") &&
+			strings.Contains(log, "pass 1 (1000 rows): lag 5.0s (target 2.0s): slowing down, limit 400 -> 200, sleep 0s -> 1s") &&
+			strings.Contains(log, "pass 2 (500 rows): lag 0.5s (target 2.0s): speeding up, limit 200 -> 200, sleep 1s -> 0s")
+	})).Return(nil)
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, archiver, mockSlack, logger, cfg, false)
+	manager.SetLagReplicas([]RollingHost{{Name: "replica1", DB: replicaDB}})
+
+	require.NoError(t, manager.PurgeOldTable("users_old"))
+
+	archiver.AssertExpectations(t)
+	replicaDB.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestPurgeOldTableAdaptiveRequiresReplicas(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{
+		DSN: "user:password@tcp(localhost:3306)/testdb",
+		Common: config.CommonConfig{PtArchiver: config.PtArchiverConfig{
+			Adaptive: config.PtArchiverAdaptiveConfig{Enabled: true, TargetLagSeconds: 2},
+		}},
+	}
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifyFailureWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0), mock.Anything).Return(nil)
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &progressPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	err := manager.PurgeOldTable("users_old")
+	assert.ErrorContains(t, err, "replica_lag.replicas")
+}
@@ -0,0 +1,247 @@
+package task
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type progressPtArchiverExecutor struct {
+	MockPtArchiverExecutor
+	deletedRows atomic.Int64
+}
+
+func (e *progressPtArchiverExecutor) GetDeletedRows() int64 {
+	return e.deletedRows.Load()
+}
+
+// resultPtArchiverExecutor は --why-quit の終了理由を返す pt-archiver
+type resultPtArchiverExecutor struct {
+	progressPtArchiverExecutor
+	result atomic.Pointer[ptarchiver.PurgeResult]
+}
+
+func (e *resultPtArchiverExecutor) GetPurgeResult() *ptarchiver.PurgeResult {
+	return e.result.Load()
+}
+
+func TestPurgeOldTableRepeatsUntilNoMoreRows(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	archiverConfig := config.PtArchiverConfig{Statistics: true, RunTime: "10m"}
+	cfg := &config.Config{
+		DSN:    "user:password@tcp(localhost:3306)/testdb",
+		Common: config.CommonConfig{PtArchiver: archiverConfig},
+	}
+
+	archiver := &resultPtArchiverExecutor{}
+	archiver.On("ExecutePurge", "users_old", archiverConfig, cfg.DSN, false).Run(func(args mock.Arguments) {
+		archiver.deletedRows.Store(7000)
+		archiver.result.Store(&ptarchiver.PurgeResult{Deleted: 7000, QuitReason: ptarchiver.QuitRunTime, QuitMessage: "Exiting because time exceeded"})
+	}).Return(nil).Once()
+	archiver.On("ExecutePurge", "users_old", archiverConfig, cfg.DSN, false).Run(func(args mock.Arguments) {
+		archiver.deletedRows.Store(3000)
+		archiver.result.Store(&ptarchiver.PurgeResult{Deleted: 3000, QuitReason: ptarchiver.QuitNoMoreRows})
+	}).Return(nil).Once()
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifyPurgeSuccess", "pt-archiver", "users_old", mock.Anything, int64(10000), mock.Anything, mock.Anything).Return(nil)
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, archiver, mockSlack, logger, cfg, false)
+
+	require.NoError(t, manager.PurgeOldTable("users_old"))
+
+	archiver.AssertNumberOfCalls(t, "ExecutePurge", 2)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestPurgeOldTableUntilEmpty(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	archiverConfig := config.PtArchiverConfig{Statistics: true, RunTime: "10m", UntilEmpty: config.PtArchiverUntilEmptyConfig{Enabled: true, CountTimeout: "1m"}}
+	cfg := &config.Config{
+		DSN:    "user:password@tcp(localhost:3306)/testdb",
+		Common: config.CommonConfig{PtArchiver: archiverConfig},
+	}
+
+	// 統計情報の見積もりは古くても、行が尽きたと報告されたパスの後は COUNT(*) で確かめる
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableRowCount", "users_old").Return(int64(900), nil).Twice()
+	mockDB.On("CountTableRows", "users_old", time.Minute).Return(int64(300), nil).Once()
+	mockDB.On("CountTableRows", "users_old", time.Minute).Return(int64(0), nil).Once()
+
+	// 1回目は行がなくなったと報告しても、残りの行数で繰り返す
+	archiver := &resultPtArchiverExecutor{}
+	archiver.On("ExecutePurge", "users_old", archiverConfig, cfg.DSN, false).Run(func(args mock.Arguments) {
+		archiver.deletedRows.Store(700)
+		archiver.result.Store(&ptarchiver.PurgeResult{Deleted: 700, QuitReason: ptarchiver.QuitNoMoreRows})
+	}).Return(nil).Once()
+	archiver.On("ExecutePurge", "users_old", archiverConfig, cfg.DSN, false).Run(func(args mock.Arguments) {
+		archiver.deletedRows.Store(300)
+		archiver.result.Store(&ptarchiver.PurgeResult{Deleted: 300, QuitReason: ptarchiver.QuitNoMoreRows})
+	}).Return(nil).Once()
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifyArchiverProgress", "users_old", int64(700), int64(1000), mock.Anything).Return(nil).Once()
+	mockSlack.On("NotifyPurgeSuccess", "pt-archiver", "users_old", mock.Anything, int64(1000), mock.Anything, mock.Anything).Return(nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, archiver, mockSlack, logger, cfg, false)
+
+	require.NoError(t, manager.PurgeOldTable("users_old"))
+
+	archiver.AssertNumberOfCalls(t, "ExecutePurge", 2)
+	mockDB.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestPurgeOldTableUntilEmptyBounds(t *testing.T) {
+	tests := []struct {
+		name       string
+		untilEmpty config.PtArchiverUntilEmptyConfig
+		deleted    []int64
+		wantErr    string
+	}{
+		{
+			name:       "max iterations",
+			untilEmpty: config.PtArchiverUntilEmptyConfig{Enabled: true, MaxIterations: 2},
+			deleted:    []int64{100, 100},
+			wantErr:    "after 2 pt-archiver passes",
+		},
+		{
+			name:       "pass without progress",
+			untilEmpty: config.PtArchiverUntilEmptyConfig{Enabled: true},
+			deleted:    []int64{100, 0},
+			wantErr:    "deleted no rows from users_old in pass 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			archiverConfig := config.PtArchiverConfig{UntilEmpty: tt.untilEmpty}
+			cfg := &config.Config{
+				DSN:    "user:password@tcp(localhost:3306)/testdb",
+				Common: config.CommonConfig{PtArchiver: archiverConfig},
+			}
+
+			mockDB := &MockDBClient{}
+			mockDB.On("GetTableRowCount", "users_old").Return(int64(500), nil)
+			mockDB.On("CountTableRows", "users_old", time.Duration(0)).Return(int64(500), nil)
+
+			archiver := &resultPtArchiverExecutor{}
+			for _, deleted := range tt.deleted {
+				archiver.On("ExecutePurge", "users_old", archiverConfig, cfg.DSN, false).Run(func(args mock.Arguments) {
+					archiver.deletedRows.Store(deleted)
+				}).Return(nil).Once()
+			}
+
+			mockSlack := &MockSlackNotifier{}
+			mockSlack.On("NotifyStartWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0)).Return(nil)
+			mockSlack.On("NotifyArchiverProgress", "users_old", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			mockSlack.On("NotifyFailureWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0), mock.Anything).Return(nil)
+
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, archiver, mockSlack, logger, cfg, false)
+
+			err := manager.PurgeOldTable("users_old")
+			assert.ErrorContains(t, err, tt.wantErr)
+			archiver.AssertNumberOfCalls(t, "ExecutePurge", len(tt.deleted))
+		})
+	}
+}
+
+func TestRemainingPurgeRowsUsesEstimateBetweenPasses(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableRowCount", "users_old").Return(int64(250000), nil).Once()
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &resultPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+
+	remaining, err := manager.remainingPurgeRows("users_old", &purgeUntilEmpty{}, &ptarchiver.PurgeResult{QuitReason: ptarchiver.QuitRunTime}, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, int64(250000), remaining)
+	mockDB.AssertNotCalled(t, "CountTableRows", mock.Anything, mock.Anything)
+}
+
+func TestResolvePurgeUntilEmptyRejectsWhere(t *testing.T) {
+	cfg := &config.Config{Common: config.CommonConfig{PtArchiver: config.PtArchiverConfig{
+		Where:      "created_at < NOW()",
+		UntilEmpty: config.PtArchiverUntilEmptyConfig{Enabled: true},
+	}}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &resultPtArchiverExecutor{}, &MockSlackNotifier{}, logrus.New(), cfg, false)
+
+	_, err := manager.resolvePurgeUntilEmpty(cfg.Common.PtArchiver)
+	assert.ErrorContains(t, err, "pt_archiver.where")
+}
+
+func TestPurgeOldTableReportsProgress(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	archiverConfig := config.PtArchiverConfig{Progress: 1000, NotifyInterval: "10ms"}
+	cfg := &config.Config{
+		DSN:    "user:password@tcp(localhost:3306)/testdb",
+		Common: config.CommonConfig{PtArchiver: archiverConfig},
+	}
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableRowCount", "users_old").Return(int64(10000), nil)
+
+	archiver := &progressPtArchiverExecutor{}
+	archiver.On("ExecutePurge", "users_old", archiverConfig, cfg.DSN, false).Run(func(args mock.Arguments) {
+		archiver.deletedRows.Store(4000)
+		time.Sleep(50 * time.Millisecond)
+		archiver.deletedRows.Store(10000)
+	}).Return(nil)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifyArchiverProgress", "users_old", mock.Anything, int64(10000), mock.Anything).Return(nil)
+	mockSlack.On("NotifyPurgeSuccess", "pt-archiver", "users_old", mock.Anything, int64(10000), mock.Anything, mock.Anything).Return(nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, archiver, mockSlack, logger, cfg, false)
+
+	require.NoError(t, manager.PurgeOldTable("users_old"))
+
+	mockSlack.AssertCalled(t, "NotifyArchiverProgress", "users_old", int64(4000), int64(10000), mock.Anything)
+	mockSlack.AssertCalled(t, "NotifyPurgeSuccess", "pt-archiver", "users_old", mock.Anything, int64(10000), mock.Anything, mock.Anything)
+	mockDB.AssertExpectations(t)
+}
+
+func TestStartArchiverProgressInvalidInterval(t *testing.T) {
+	cfg := &config.Config{Common: config.CommonConfig{PtArchiver: config.PtArchiverConfig{NotifyInterval: "often"}}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &progressPtArchiverExecutor{}, &MockSlackNotifier{}, logrus.New(), cfg, false)
+
+	_, err := manager.startArchiverProgress("users_old")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid pt_archiver.notify_interval")
+}
+
+func TestBuildPtArchiverCommandMatchesExecutorArgs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	cfg := &config.Config{Common: config.CommonConfig{PtArchiver: config.PtArchiverConfig{
+		MaxLag:        1,
+		CheckInterval: "1500ms",
+		RunTime:       "2m",
+	}}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	command := manager.buildPtArchiverCommand("users")
+	assert.Contains(t, command, "--check-interval=1")
+	assert.NotContains(t, command, "--check-interval=1500ms")
+	assert.Contains(t, command, "--run-time=120s")
+}
@@ -0,0 +1,209 @@
+package task
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/artifacts"
+	"github.com/pyama86/alterguard/internal/history"
+	"github.com/pyama86/alterguard/internal/ptarchiver"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/redact"
+)
+
+// Plan は実行前に解決したテーブルごとの実行計画。--artifacts-dir の plan.json に書き出す。
+type Plan struct {
+	Environment          string      `json:"environment,omitempty"`
+	DryRun               bool        `json:"dry_run"`
+	PtOscThreshold       int64       `json:"pt_osc_threshold"`
+	PtOscSizeThresholdMB float64     `json:"pt_osc_size_threshold_mb,omitempty"`
+	Tables               []PlanTable `json:"tables"`
+	NonTableQueries      []string    `json:"non_table_queries,omitempty"`
+}
+
+type PlanTable struct {
+	TableName    string   `json:"table_name"`
+	ShardPattern string   `json:"shard_pattern,omitempty"`
+	AlterParts   []string `json:"alter_parts,omitempty"`
+	OtherQueries []string `json:"other_queries,omitempty"`
+	// 行数が事前に取得できなかった場合は実行時に決まるため空になる
+	RowCount      *int64          `json:"row_count,omitempty"`
+	PlannedMethod string          `json:"planned_method,omitempty"`
+	Impact        *ImpactEstimate `json:"impact,omitempty"`
+}
+
+func (m *Manager) SetArtifactsRecorder(recorder *artifacts.Recorder) {
+	m.artifacts = recorder
+}
+
+func (m *Manager) writePlan(groups []*TableGroup, queries []QueryInfo, impacts map[string]*ImpactEstimate) {
+	if m.artifacts == nil {
+		return
+	}
+
+	plan := Plan{
+		Environment:          m.config.Environment,
+		DryRun:               m.dryRun,
+		PtOscThreshold:       m.config.Common.PtOscThreshold,
+		PtOscSizeThresholdMB: m.config.Common.PtOscSizeThresholdMB,
+	}
+	for _, group := range groups {
+		entry := PlanTable{
+			TableName:    group.TableName,
+			ShardPattern: group.ShardPattern,
+			Impact:       impacts[group.TableName],
+		}
+		for _, part := range group.AlterParts {
+			entry.AlterParts = append(entry.AlterParts, m.redactQuery(part))
+		}
+		for _, query := range group.OtherQueries {
+			entry.OtherQueries = append(entry.OtherQueries, m.redactQuery(query.Query))
+		}
+		if count, ok := m.rowCounts[group.TableName]; ok {
+			entry.RowCount = &count
+			entry.PlannedMethod = m.plannedMethod(group, count)
+		} else if len(group.AlterParts) == 0 {
+			entry.PlannedMethod = "small-query"
+		} else if forced, _ := m.resolveForcedMethod(); forced != "" {
+			entry.PlannedMethod = forced
+		}
+		plan.Tables = append(plan.Tables, entry)
+	}
+	for _, query := range queries {
+		if query.TableName == "" {
+			plan.NonTableQueries = append(plan.NonTableQueries, m.redactQuery(query.Query))
+		}
+	}
+
+	if err := m.artifacts.WritePlan(plan); err != nil {
+		m.logger.Errorf("Failed to write plan artifact: %v", err)
+	}
+}
+
+func (m *Manager) plannedMethod(group *TableGroup, rowCount int64) string {
+	if len(group.AlterParts) == 0 {
+		return "small-query"
+	}
+	return m.chooseAlterMethod(group.TableName, rowCount)
+}
+
+// recordGroupResult はテーブルごとの実行結果を成果物として残す。pt-osc を使った場合はその全出力も保存する。
+func (m *Manager) recordGroupResult(group *TableGroup, start time.Time, err error) {
+	if m.artifacts == nil {
+		return
+	}
+
+	queries := make([]string, 0, len(group.OtherQueries)+len(group.AlterParts))
+	for _, query := range group.OtherQueries {
+		queries = append(queries, query.Query)
+	}
+	for _, part := range group.AlterParts {
+		queries = append(queries, fmt.Sprintf("ALTER TABLE %s %s", group.TableName, part))
+	}
+
+	result := newTaskResult(group.Method, start, err)
+	result.TableName = group.TableName
+	result.ShardPattern = group.ShardPattern
+	result.Method = group.Method
+	result.Queries = queries
+	result.RowCount = group.RowCount
+	if group.Method == "pt-osc" {
+		if ptOscExecutor, ok := m.ptosc.(*ptosc.PtOscExecutor); ok {
+			result.LogFile = m.copyToolLog(group.TableName+".pt-osc", ptOscExecutor.GetOutputFilePath())
+		}
+	}
+	m.recordTask(result)
+}
+
+func (m *Manager) recordQueryResult(query QueryInfo, start time.Time, err error) {
+	if m.artifacts == nil {
+		return
+	}
+
+	result := newTaskResult("non-table-query", start, err)
+	result.Method = "non-table-query"
+	result.Queries = []string{query.Query}
+	m.recordTask(result)
+}
+
+func (m *Manager) recordPurgeResult(tableName, command string, start time.Time, err error) {
+	if m.artifacts == nil {
+		return
+	}
+
+	result := newTaskResult("pt-archiver", start, err)
+	result.TableName = tableName
+	result.Method = "pt-archiver"
+	result.Queries = []string{command}
+	if ptArchiverExecutor, ok := m.ptarchiver.(*ptarchiver.PtArchiverExecutor); ok {
+		result.LogFile = m.copyToolLog(tableName+".pt-archiver", ptArchiverExecutor.GetOutputFilePath())
+	}
+	m.recordTask(result)
+}
+
+func (m *Manager) recordSwapResult(tableName, swapSQL string, start time.Time, err error) {
+	if m.artifacts == nil {
+		return
+	}
+
+	result := newTaskResult("swap", start, err)
+	result.TableName = tableName
+	result.Method = "swap"
+	result.Queries = []string{swapSQL}
+	m.recordTask(result)
+}
+
+func newTaskResult(task string, start time.Time, err error) artifacts.TaskResult {
+	finished := time.Now()
+	result := artifacts.TaskResult{
+		Task:            task,
+		StartedAt:       start,
+		FinishedAt:      finished,
+		DurationSeconds: finished.Sub(start).Seconds(),
+		Success:         err == nil,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// copyToolLog は pt-osc / pt-archiver の出力を成果物として残す。--where などの値を含むため、クエリと同じく伏せる
+func (m *Manager) copyToolLog(name, path string) string {
+	var transform func(string) string
+	if m.config.Common.Redaction.Mode != "" {
+		transform = m.redactQuery
+	}
+	rel, err := m.artifacts.CopyLogWith(name, path, transform)
+	if err != nil {
+		m.logger.Errorf("Failed to save %s log artifact: %v", name, err)
+		return ""
+	}
+	return rel
+}
+
+func (m *Manager) recordTask(result artifacts.TaskResult) {
+	// 環境間の比較に使うハッシュは伏せる前のクエリから計算する
+	result.QueryHashes = make([]string, len(result.Queries))
+	for i, query := range result.Queries {
+		result.QueryHashes[i] = history.QueryHash(query)
+	}
+
+	// 実行結果は履歴として残るため、通知と同じくクエリの値を伏せる。エラーにもクエリが含まれる
+	if m.config.Common.Redaction.Mode != "" {
+		queries := make([]string, len(result.Queries))
+		for i, query := range result.Queries {
+			queries[i] = m.redactQuery(query)
+		}
+		result.Queries = queries
+		result.Error = m.redactQuery(result.Error)
+	}
+	if err := m.artifacts.RecordTask(result); err != nil {
+		m.logger.Errorf("Failed to write task result artifact: %v", err)
+	}
+}
+
+// redactQuery は redaction.mode に従ってクエリの値を伏せる
+func (m *Manager) redactQuery(query string) string {
+	return redact.Query(query, m.config.Common.Redaction.Policy())
+}
@@ -0,0 +1,123 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/artifacts"
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/history"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteAllTasks_WritesArtifacts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableStorage", mock.Anything).Return(&database.TableStorage{Engine: "InnoDB"}, nil).Maybe()
+	mockDB.On("GetTableStructure", mock.Anything).Return(&database.TableStructure{}, nil).Maybe()
+	mockSlack := &MockSlackNotifier{}
+
+	queries := []string{"ALTER TABLE users ADD COLUMN age INT"}
+
+	mockDB.On("GetTableRowCounts", []string{"users"}).Return(map[string]int64{"users": 100}, nil)
+	mockDB.On("ExecuteAlterWithAlgorithm", "users", queries[0]).Return(&database.AlterAlgorithm{Algorithm: database.AlterAlgorithmInstant}, nil)
+
+	mockSlack.On("NotifyAllTasksStart", 1).Return(nil)
+	mockSlack.On("NotifyStartWithQueryAndStorage", "alter-table", "users", mock.Anything, int64(100), mock.Anything).Return(nil)
+	mockSlack.On("NotifySuccessWithQueryAndAlgorithm", "alter-table", "users", mock.Anything, int64(100), mock.Anything, "instant").Return(nil)
+	mockSlack.On("NotifyAllTasksSuccess", 1, mock.Anything).Return(nil)
+
+	cfg := &config.Config{
+		Queries: queries,
+		Common: config.CommonConfig{
+			PtOscThreshold: 1000,
+		},
+		DSN: "test-dsn",
+	}
+
+	recorder, err := artifacts.NewRecorder(t.TempDir(), "run", time.Now())
+	require.NoError(t, err)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	manager.SetArtifactsRecorder(recorder)
+	require.NoError(t, manager.ExecuteAllTasks())
+
+	var plan Plan
+	readJSON(t, filepath.Join(recorder.Dir(), "plan.json"), &plan)
+	require.Len(t, plan.Tables, 1)
+	assert.Equal(t, "users", plan.Tables[0].TableName)
+	assert.Equal(t, "alter-table", plan.Tables[0].PlannedMethod)
+	require.NotNil(t, plan.Tables[0].RowCount)
+	assert.Equal(t, int64(100), *plan.Tables[0].RowCount)
+
+	var result artifacts.TaskResult
+	readJSON(t, filepath.Join(recorder.Dir(), "tasks", "001-users.json"), &result)
+	assert.True(t, result.Success)
+	assert.Equal(t, "alter-table", result.Method)
+	assert.Equal(t, []string{"ALTER TABLE users ADD COLUMN age INT"}, result.Queries)
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, v))
+}
+
+func TestRecordTask_RedactsQueries(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{Common: config.CommonConfig{Redaction: config.RedactionConfig{Mode: "elide"}}}
+	recorder, err := artifacts.NewRecorder(t.TempDir(), "cleanup", time.Now())
+	require.NoError(t, err)
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+	manager.SetArtifactsRecorder(recorder)
+	command := "pt-archiver --where=customer_id=12345 --limit=1000"
+	manager.recordPurgeResult("_users_old", command, time.Now(), fmt.Errorf("failed to execute query: %s", command))
+
+	files, err := filepath.Glob(filepath.Join(recorder.Dir(), "tasks", "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	var result artifacts.TaskResult
+	readJSON(t, files[0], &result)
+	assert.Equal(t, []string{"pt-archiver --where=customer_id=? --limit=1000"}, result.Queries)
+	assert.Equal(t, "failed to execute query: pt-archiver --where=customer_id=? --limit=1000", result.Error)
+	// 伏せる前のクエリのハッシュを残す
+	assert.Equal(t, []string{history.QueryHash(command)}, result.QueryHashes)
+}
+
+func TestWritePlan_RedactsQueries(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{Common: config.CommonConfig{Redaction: config.RedactionConfig{Mode: "elide"}}}
+	recorder, err := artifacts.NewRecorder(t.TempDir(), "run", time.Now())
+	require.NoError(t, err)
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+	manager.SetArtifactsRecorder(recorder)
+	groups := []*TableGroup{{
+		TableName:    "users",
+		OtherQueries: []QueryInfo{{Query: "UPDATE users SET plan = 'pro' WHERE id = 42", TableName: "users"}},
+	}}
+	queries := []QueryInfo{{Query: "INSERT INTO audit_settings VALUES ('secret')"}}
+	manager.writePlan(groups, queries, nil)
+
+	var plan Plan
+	readJSON(t, filepath.Join(recorder.Dir(), "plan.json"), &plan)
+	require.Len(t, plan.Tables, 1)
+	assert.Equal(t, []string{"UPDATE users SET plan = ? WHERE id = ?"}, plan.Tables[0].OtherQueries)
+	assert.Equal(t, []string{"INSERT INTO audit_settings VALUES (?)"}, plan.NonTableQueries)
+}
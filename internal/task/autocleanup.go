@@ -0,0 +1,62 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cleanupAfterPtOscFailure は auto_cleanup_on_failure が有効なとき、失敗した pt-osc が残したトリガーと
+// _new テーブルを削除し、何を削除したかを通知する。pt-osc が強制終了されると自身では後始末できないため。
+// 実行前に _new テーブルが無いことは確認済みなので、残っている _new テーブルは今回の pt-osc が作ったもの。
+func (m *Manager) cleanupAfterPtOscFailure(tableName string, cause error) {
+	if !m.config.Common.AutoCleanupOnFailure {
+		return
+	}
+
+	var debris []string
+	listed := true
+	triggers, err := m.db.GetTriggerNames(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to list triggers of %s: %v", tableName, err)
+		listed = false
+	}
+	for _, trigger := range triggers {
+		if strings.HasPrefix(trigger, ptOscTriggerPrefix) {
+			debris = append(debris, "trigger "+trigger)
+		}
+	}
+	newTableExists, err := m.db.CheckNewTableExists(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to check new table existence for %s: %v", tableName, err)
+		listed = false
+	}
+	if newTableExists {
+		debris = append(debris, fmt.Sprintf("table _%s_new", tableName))
+	}
+	// 一覧が取れなかった場合も、DROP ... IF EXISTS で後始末を試みる
+	if listed && len(debris) == 0 {
+		m.logger.Infof("pt-osc left nothing to clean up for %s", tableName)
+		return
+	}
+
+	m.logger.Warnf("Cleaning up pt-osc artifacts for %s after failure: %s", tableName, strings.Join(debris, ", "))
+	var failures []string
+	if err := m.CleanupTriggers(tableName); err != nil {
+		failures = append(failures, fmt.Sprintf("triggers: %v", err))
+	}
+	if err := m.CleanupNewTable(tableName); err != nil {
+		failures = append(failures, fmt.Sprintf("_%s_new: %v", tableName, err))
+	}
+
+	message := fmt.Sprintf("pt-osc failed on %s (%v). Automatic cleanup removed: %s", tableName, cause, strings.Join(debris, ", "))
+	if len(debris) == 0 {
+		message = fmt.Sprintf("pt-osc failed on %s (%v). Automatic cleanup dropped any remaining pt-osc triggers and _%s_new", tableName, cause, tableName)
+	}
+	if len(failures) > 0 {
+		message += fmt.Sprintf(". Some artifacts could not be removed: %s", strings.Join(failures, "; "))
+	}
+	m.logger.Warn(message)
+	if err := m.slack.NotifyWarning("pt-osc-auto-cleanup", tableName, message); err != nil {
+		m.logger.Errorf("Failed to send auto cleanup notification: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package task
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCleanupAfterPtOscFailure(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	cause := errors.New("signal: killed")
+
+	t.Run("drops triggers and the new table left by pt-osc", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetTriggerNames", "users").Return([]string{"pt_osc_app_users_ins", "pt_osc_app_users_upd", "audit_users"}, nil)
+		mockDB.On("CheckNewTableExists", "users").Return(true, nil)
+		mockDB.On("ExecuteAlter", mock.Anything).Return(nil)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyTriggerCleanupStart", "trigger-cleanup", "users", mock.Anything).Return(nil)
+		mockSlack.On("NotifyTriggerCleanupSuccess", "trigger-cleanup", "users", mock.Anything, mock.Anything).Return(nil)
+		mockSlack.On("NotifyWarning", "trigger-cleanup", "users", mock.MatchedBy(func(message string) bool {
+			return strings.Contains(message, "audit_users")
+		})).Return(nil)
+		mockSlack.On("NotifyStartWithQuery", "new-table-cleanup", "users", mock.Anything, int64(0)).Return(nil)
+		mockSlack.On("NotifySuccessWithQuery", "new-table-cleanup", "users", mock.Anything, int64(0), mock.Anything).Return(nil)
+		mockSlack.On("NotifyWarning", "pt-osc-auto-cleanup", "users", mock.MatchedBy(func(message string) bool {
+			return strings.Contains(message, "trigger pt_osc_app_users_ins, trigger pt_osc_app_users_upd, table _users_new") &&
+				!strings.Contains(message, "audit_users") && strings.Contains(message, "signal: killed")
+		})).Return(nil)
+
+		cfg := &config.Config{DSN: "user:pass@tcp(localhost:3306)/app", Common: config.CommonConfig{AutoCleanupOnFailure: true}}
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+		manager.cleanupAfterPtOscFailure("users", cause)
+
+		mockDB.AssertCalled(t, "ExecuteAlter", "DROP TRIGGER IF EXISTS pt_osc_app_users_ins")
+		mockDB.AssertCalled(t, "ExecuteAlter", "DROP TABLE IF EXISTS _users_new")
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("nothing left behind", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetTriggerNames", "users").Return([]string{"audit_users"}, nil)
+		mockDB.On("CheckNewTableExists", "users").Return(false, nil)
+
+		cfg := &config.Config{DSN: "user:pass@tcp(localhost:3306)/app", Common: config.CommonConfig{AutoCleanupOnFailure: true}}
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+		manager.cleanupAfterPtOscFailure("users", cause)
+
+		mockDB.AssertNotCalled(t, "ExecuteAlter", mock.Anything)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+		manager.cleanupAfterPtOscFailure("users", cause)
+
+		mockDB.AssertNotCalled(t, "GetTriggerNames", mock.Anything)
+	})
+}
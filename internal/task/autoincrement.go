@@ -0,0 +1,60 @@
+package task
+
+import (
+	"fmt"
+)
+
+// checkAutoIncrementContinuity は swap 後のテーブルの AUTO_INCREMENT が _old テーブルの最大値 + headroom を
+// 超えているかを確認する。不足していれば警告し、bump が有効であれば引き上げる。
+// swap 自体は完了しているため、確認の失敗は警告にとどめる。
+func (m *Manager) checkAutoIncrementContinuity(tableName, oldTableName string) {
+	settings := m.config.Common.SwapAutoIncrement
+	if settings.Disabled {
+		return
+	}
+
+	column, err := m.db.GetAutoIncrementColumn(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get auto increment column of %s: %v", tableName, err)
+		return
+	}
+	if column == "" {
+		return
+	}
+
+	oldMax, err := m.db.GetMaxIntValue(oldTableName, column)
+	if err != nil {
+		m.logger.Warnf("Failed to get max(%s) of %s: %v", column, oldTableName, err)
+		return
+	}
+	current, err := m.db.GetAutoIncrementValue(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get auto increment value of %s: %v", tableName, err)
+		return
+	}
+
+	required := oldMax + settings.Headroom + 1
+	m.logger.Infof("AUTO_INCREMENT of %s is %d (max(%s) of %s: %d, headroom: %d)",
+		tableName, current, column, oldTableName, oldMax, settings.Headroom)
+	if current >= required {
+		return
+	}
+
+	message := fmt.Sprintf("AUTO_INCREMENT of %s is %d but max(%s) of %s is %d (headroom: %d)",
+		tableName, current, column, oldTableName, oldMax, settings.Headroom)
+	if settings.Bump {
+		bumpSQL := fmt.Sprintf("ALTER TABLE `%s` AUTO_INCREMENT = %d", tableName, required)
+		if err := m.db.ExecuteAlter(bumpSQL); err != nil {
+			message += fmt.Sprintf(". Failed to bump it to %d: %v", required, err)
+		} else {
+			message += fmt.Sprintf(". Bumped it to %d", required)
+		}
+	} else {
+		message += fmt.Sprintf(". Set swap_auto_increment.bump or run `ALTER TABLE %s AUTO_INCREMENT = %d`", tableName, required)
+	}
+
+	m.logger.Warn(message)
+	if err := m.slack.NotifyWarning("swap-auto-increment-check", tableName, message); err != nil {
+		m.logger.Errorf("Failed to send auto increment warning notification: %v", err)
+	}
+}
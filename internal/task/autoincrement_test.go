@@ -0,0 +1,94 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckAutoIncrementContinuity(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	tests := []struct {
+		name          string
+		settings      config.SwapAutoIncrementConfig
+		initMock      func(*MockDBClient)
+		expectWarning string
+	}{
+		{
+			name:     "disabled",
+			settings: config.SwapAutoIncrementConfig{Disabled: true},
+			initMock: func(d *MockDBClient) {},
+		},
+		{
+			name:     "no auto increment column",
+			initMock: func(d *MockDBClient) { d.On("GetAutoIncrementColumn", "users").Return("", nil) },
+		},
+		{
+			name:     "enough headroom",
+			settings: config.SwapAutoIncrementConfig{Headroom: 100},
+			initMock: func(d *MockDBClient) {
+				d.On("GetAutoIncrementColumn", "users").Return("id", nil)
+				d.On("GetMaxIntValue", "users_old", "id").Return(int64(1000), nil)
+				d.On("GetAutoIncrementValue", "users").Return(int64(1101), nil)
+			},
+		},
+		{
+			name:     "behind without bump",
+			settings: config.SwapAutoIncrementConfig{Headroom: 100},
+			initMock: func(d *MockDBClient) {
+				d.On("GetAutoIncrementColumn", "users").Return("id", nil)
+				d.On("GetMaxIntValue", "users_old", "id").Return(int64(1000), nil)
+				d.On("GetAutoIncrementValue", "users").Return(int64(1001), nil)
+			},
+			expectWarning: "AUTO_INCREMENT of users is 1001 but max(id) of users_old is 1000 (headroom: 100). Set swap_auto_increment.bump or run `ALTER TABLE users AUTO_INCREMENT = 1101`",
+		},
+		{
+			name:     "behind with bump",
+			settings: config.SwapAutoIncrementConfig{Headroom: 100, Bump: true},
+			initMock: func(d *MockDBClient) {
+				d.On("GetAutoIncrementColumn", "users").Return("id", nil)
+				d.On("GetMaxIntValue", "users_old", "id").Return(int64(1000), nil)
+				d.On("GetAutoIncrementValue", "users").Return(int64(1001), nil)
+				d.On("ExecuteAlter", "ALTER TABLE `users` AUTO_INCREMENT = 1101").Return(nil)
+			},
+			expectWarning: "AUTO_INCREMENT of users is 1001 but max(id) of users_old is 1000 (headroom: 100). Bumped it to 1101",
+		},
+		{
+			name:     "bump failed",
+			settings: config.SwapAutoIncrementConfig{Bump: true},
+			initMock: func(d *MockDBClient) {
+				d.On("GetAutoIncrementColumn", "users").Return("id", nil)
+				d.On("GetMaxIntValue", "users_old", "id").Return(int64(1000), nil)
+				d.On("GetAutoIncrementValue", "users").Return(int64(900), nil)
+				d.On("ExecuteAlter", "ALTER TABLE `users` AUTO_INCREMENT = 1001").Return(errors.New("lock wait timeout"))
+			},
+			expectWarning: "AUTO_INCREMENT of users is 900 but max(id) of users_old is 1000 (headroom: 0). Failed to bump it to 1001: lock wait timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockDBClient{}
+			tt.initMock(mockDB)
+			mockSlack := &MockSlackNotifier{}
+			mockSlack.On("NotifyWarning", "swap-auto-increment-check", "users", mock.Anything).Return(nil)
+
+			cfg := &config.Config{Common: config.CommonConfig{SwapAutoIncrement: tt.settings}}
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+			manager.checkAutoIncrementContinuity("users", "users_old")
+
+			if tt.expectWarning != "" {
+				mockSlack.AssertCalled(t, "NotifyWarning", "swap-auto-increment-check", "users", tt.expectWarning)
+			} else {
+				mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package task
+
+import (
+	"regexp"
+	"strings"
+)
+
+// noBatchDirectiveRe はクエリ中の /* alterguard:no-batch */ 指定。
+// 指定された ALTER は同じテーブルの他の ALTER とまとめず、単独で実行する。
+var noBatchDirectiveRe = regexp.MustCompile(`(?i)/\*\s*alterguard:\s*no-batch\s*\*/`)
+
+// stripNoBatchDirective はクエリから no-batch 指定を取り除き、指定があったかどうかを返す
+func stripNoBatchDirective(query string) (string, bool) {
+	if !noBatchDirectiveRe.MatchString(query) {
+		return query, false
+	}
+	return strings.TrimSpace(noBatchDirectiveRe.ReplaceAllString(query, " ")), true
+}
@@ -0,0 +1,54 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupQueriesByTable_NoBatch(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{Queries: []string{
+		"ALTER TABLE users ADD COLUMN email VARCHAR(255)",
+		"/* alterguard:no-batch */ ALTER TABLE users ADD UNIQUE INDEX uq_email (email)",
+		"ALTER TABLE users ADD COLUMN age INT",
+		"ALTER TABLE users ADD INDEX idx_age (age)",
+	}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	queries, err := manager.parseConfiguredQueries()
+	require.NoError(t, err)
+	assert.Equal(t, "ALTER TABLE users ADD UNIQUE INDEX uq_email (email)", queries[1].Query)
+	assert.Equal(t, "users", queries[1].TableName)
+
+	groups := manager.groupQueriesByTable(queries)
+	require.Len(t, groups, 3)
+	assert.Equal(t, []string{"ADD COLUMN email VARCHAR(255)"}, groups[0].AlterParts)
+	assert.Equal(t, []string{"ADD UNIQUE INDEX uq_email (email)"}, groups[1].AlterParts)
+	assert.Equal(t, []string{"ADD COLUMN age INT", "ADD INDEX idx_age (age)"}, groups[2].AlterParts)
+}
+
+func TestGroupQueriesByTable_BatchFalseTask(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	batch := false
+	cfg := &config.Config{Tasks: []config.TaskDefinition{
+		{Name: "columns", Query: "ALTER TABLE users ADD COLUMN email VARCHAR(255); ALTER TABLE users ADD COLUMN age INT"},
+		{Name: "unique-email", Query: "ALTER TABLE users ADD UNIQUE INDEX uq_email (email)", Batch: &batch},
+	}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	queries, err := manager.parseConfiguredQueries()
+	require.NoError(t, err)
+	groups := manager.groupQueriesByTable(queries)
+
+	require.Len(t, groups, 2)
+	assert.Equal(t, []string{"ADD COLUMN email VARCHAR(255)", "ADD COLUMN age INT"}, groups[0].AlterParts)
+	assert.Equal(t, []string{"ADD UNIQUE INDEX uq_email (email)"}, groups[1].AlterParts)
+}
@@ -0,0 +1,239 @@
+package task
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBenchmarkSampleRows = 1000000
+
+	defaultBenchmarkChunkSize = 1000
+	defaultBenchmarkMaxLag    = 1.0
+	// pt-osc の --chunk-time のデフォルト。推奨チャンクサイズは1チャンクがこの時間で終わる行数とする
+	benchmarkTargetChunkTime = 500 * time.Millisecond
+	benchmarkLagWaitTimeout  = 10 * time.Minute
+)
+
+// pt-osc の --check-interval のデフォルトに合わせる。テストでは短くする
+var benchmarkLagCheckInterval = time.Second
+
+// BenchmarkResult は benchmark で計測した行コピーの性能
+type BenchmarkResult struct {
+	TableName    string
+	ChunkSize    int
+	MaxLag       float64
+	Chunks       int
+	CopiedRows   int64
+	Duration     time.Duration
+	MaxChunkTime time.Duration
+	// レプリカ遅延の取得元。取得できない場合は空
+	LagSource          string
+	BaselineLagSeconds float64
+	MaxLagSeconds      float64
+	LagWaits           int
+	LagWaitTime        time.Duration
+}
+
+// RowsPerSecond はレプリカ遅延の待ち時間を除いた平均コピー速度を返す
+func (r *BenchmarkResult) RowsPerSecond() float64 {
+	copyTime := r.Duration - r.LagWaitTime
+	if copyTime <= 0 {
+		return 0
+	}
+	return float64(r.CopiedRows) / copyTime.Seconds()
+}
+
+// SuggestedChunkSize は1チャンクが pt-osc の --chunk-time (0.5秒) で終わる行数を100行単位で返す
+func (r *BenchmarkResult) SuggestedChunkSize() int {
+	size := int(math.Round(r.RowsPerSecond()*benchmarkTargetChunkTime.Seconds()/100)) * 100
+	if size < 100 {
+		return 100
+	}
+	return size
+}
+
+// SuggestedMaxLag は計測したコピー速度で発生した遅延を許容できる max_lag を返す。遅延が取得できなければ0を返す。
+func (r *BenchmarkResult) SuggestedMaxLag() float64 {
+	if r.LagSource == "" {
+		return 0
+	}
+	return math.Max(1, math.Ceil(r.MaxLagSeconds)+1)
+}
+
+// Summary は計測結果と推奨設定をテキストで返す
+func (r *BenchmarkResult) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Copied rows:      %d in %d chunks of %d\n", r.CopiedRows, r.Chunks, r.ChunkSize)
+	fmt.Fprintf(&b, "Duration:         %s (waiting for replica lag: %s)\n", r.Duration.Round(time.Millisecond), r.LagWaitTime.Round(time.Millisecond))
+	fmt.Fprintf(&b, "Copy rate:        %.0f rows/sec\n", r.RowsPerSecond())
+	fmt.Fprintf(&b, "Slowest chunk:    %s\n", r.MaxChunkTime.Round(time.Millisecond))
+	if r.LagSource == "" {
+		fmt.Fprintf(&b, "Replica lag:      not measured (set REPLICA_DSNS or enable pt_osc.aurora_replica_check)\n")
+	} else {
+		fmt.Fprintf(&b, "Replica lag:      baseline %.1fs, max %.1fs, %d waits over max_lag %.1fs (%s)\n",
+			r.BaselineLagSeconds, r.MaxLagSeconds, r.LagWaits, r.MaxLag, r.LagSource)
+	}
+	fmt.Fprintf(&b, "Suggested:        pt_osc.chunk_size: %d", r.SuggestedChunkSize())
+	if maxLag := r.SuggestedMaxLag(); maxLag > 0 {
+		fmt.Fprintf(&b, ", pt_osc.max_lag: %.0f", maxLag)
+	}
+	return b.String()
+}
+
+// BenchmarkTable は最大 sampleRows 行を pt_osc の chunk_size で一時テーブル _<table>_bench にコピーし、
+// コピー速度とレプリカ遅延への影響を計測する。一時テーブルは終了時に必ず削除する。
+// replicas が指定されていればその遅延を、なければ Aurora のレプリカ遅延(aurora_replica_check 有効時)を計測する。
+func (m *Manager) BenchmarkTable(tableName string, sampleRows int64, replicas []RollingHost) (*BenchmarkResult, error) {
+	result, err := m.benchmarkTable(tableName, sampleRows, replicas)
+	if err != nil {
+		if slackErr := m.slack.NotifyFailure("benchmark", tableName, 0, err); slackErr != nil {
+			m.logger.Errorf("Failed to send failure notification: %v", slackErr)
+		}
+	}
+	return result, err
+}
+
+func (m *Manager) benchmarkTable(tableName string, sampleRows int64, replicas []RollingHost) (*BenchmarkResult, error) {
+	if sampleRows <= 0 {
+		sampleRows = DefaultBenchmarkSampleRows
+	}
+	chunkSize := m.config.Common.PtOsc.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultBenchmarkChunkSize
+	}
+	maxLag := m.config.Common.PtOsc.MaxLag
+	if maxLag <= 0 {
+		maxLag = defaultBenchmarkMaxLag
+	}
+
+	keyColumns, err := m.db.GetPrimaryKeyColumns(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyColumns) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key; benchmark copies rows in primary key order like pt-osc", tableName)
+	}
+
+	scratchTable := fmt.Sprintf("_%s_bench", tableName)
+	exists, err := m.db.TableExists(scratchTable)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("scratch table %s already exists; drop it before running benchmark", scratchTable)
+	}
+
+	if m.dryRun {
+		m.logger.Infof("[DRY RUN] Would copy up to %d rows of %s into %s in chunks of %d ordered by %s",
+			sampleRows, tableName, scratchTable, chunkSize, strings.Join(keyColumns, ", "))
+		return nil, nil
+	}
+
+	result := &BenchmarkResult{TableName: tableName, ChunkSize: chunkSize, MaxLag: maxLag}
+	result.LagSource, result.BaselineLagSeconds, err = m.measureBenchmarkLag(replicas)
+	if err != nil {
+		return nil, err
+	}
+	result.MaxLagSeconds = result.BaselineLagSeconds
+
+	if err := m.db.ExecuteAlter(fmt.Sprintf("CREATE TABLE `%s` LIKE `%s`", scratchTable, tableName)); err != nil {
+		return nil, fmt.Errorf("failed to create scratch table: %w", err)
+	}
+	defer func() {
+		if err := m.db.ExecuteAlter(fmt.Sprintf("DROP TABLE IF EXISTS `%s`", scratchTable)); err != nil {
+			m.logger.Errorf("Failed to drop scratch table %s; drop it manually: %v", scratchTable, err)
+		}
+	}()
+
+	m.logger.Infof("Benchmarking copy of up to %d rows of %s in chunks of %d", sampleRows, tableName, chunkSize)
+	start := time.Now()
+	var after []any
+	for result.CopiedRows < sampleRows {
+		size := chunkSize
+		if remaining := sampleRows - result.CopiedRows; remaining < int64(size) {
+			size = int(remaining)
+		}
+
+		chunkStart := time.Now()
+		next, copied, err := m.db.CopyChunk(tableName, scratchTable, keyColumns, after, size)
+		if err != nil {
+			return nil, err
+		}
+		if chunkTime := time.Since(chunkStart); chunkTime > result.MaxChunkTime {
+			result.MaxChunkTime = chunkTime
+		}
+		result.Chunks++
+		result.CopiedRows += copied
+
+		if err := m.waitForBenchmarkLag(replicas, result); err != nil {
+			return nil, err
+		}
+		if next == nil {
+			break
+		}
+		after = next
+	}
+	result.Duration = time.Since(start)
+
+	summary := result.Summary()
+	m.logger.Infof("Benchmark of %s completed:\n%s", tableName, summary)
+	if err := m.slack.NotifyBenchmarkResult(tableName, summary, result.Duration); err != nil {
+		m.logger.Errorf("Failed to send benchmark result notification: %v", err)
+	}
+	return result, nil
+}
+
+// measureBenchmarkLag は現在のレプリカ遅延(秒)を返す。取得元がなければ空の source を返す。
+func (m *Manager) measureBenchmarkLag(replicas []RollingHost) (source string, lag float64, err error) {
+	if len(replicas) > 0 {
+		var names []string
+		for _, replica := range replicas {
+			replicaLag, err := replica.DB.GetReplicaLagSeconds()
+			if err != nil {
+				return "", 0, fmt.Errorf("failed to check replica lag on %s: %w", replica.Name, err)
+			}
+			lag = math.Max(lag, replicaLag)
+			names = append(names, replica.Name)
+		}
+		return strings.Join(names, ", "), lag, nil
+	}
+
+	if m.config.Common.PtOsc.AuroraReplicaCheck.Enabled {
+		lagMs, err := m.db.GetMaxAuroraReplicaLagMs()
+		if err != nil {
+			return "", 0, err
+		}
+		return "aurora replicas", lagMs / 1000, nil
+	}
+	return "", 0, nil
+}
+
+// waitForBenchmarkLag は pt-osc と同じく遅延が max_lag を超えている間は次のチャンクのコピーを待つ
+func (m *Manager) waitForBenchmarkLag(replicas []RollingHost, result *BenchmarkResult) error {
+	if result.LagSource == "" {
+		return nil
+	}
+
+	waitStart := time.Now()
+	for {
+		_, lag, err := m.measureBenchmarkLag(replicas)
+		if err != nil {
+			return err
+		}
+		result.MaxLagSeconds = math.Max(result.MaxLagSeconds, lag)
+		if lag <= result.MaxLag {
+			return nil
+		}
+		if waited := time.Since(waitStart); waited > benchmarkLagWaitTimeout {
+			return fmt.Errorf("replica lag %.1fs did not recover below %.1fs within %s", lag, result.MaxLag, benchmarkLagWaitTimeout)
+		}
+
+		result.LagWaits++
+		m.logger.Infof("Replica lag %.1fs exceeds max_lag %.1fs, waiting %s", lag, result.MaxLag, benchmarkLagCheckInterval)
+		time.Sleep(benchmarkLagCheckInterval)
+		result.LagWaitTime += benchmarkLagCheckInterval
+	}
+}
@@ -0,0 +1,146 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newBenchmarkTestManager(mockDB *MockDBClient, mockSlack *MockSlackNotifier, dryRun bool) *Manager {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	cfg := &config.Config{
+		Common: config.CommonConfig{
+			PtOsc: config.PtOscConfig{ChunkSize: 2, MaxLag: 1},
+		},
+	}
+	return NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, dryRun)
+}
+
+func TestBenchmarkTable(t *testing.T) {
+	original := benchmarkLagCheckInterval
+	benchmarkLagCheckInterval = time.Millisecond
+	defer func() { benchmarkLagCheckInterval = original }()
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetPrimaryKeyColumns", "users").Return([]string{"id"}, nil)
+	mockDB.On("TableExists", "_users_bench").Return(false, nil)
+	mockDB.On("ExecuteAlter", "CREATE TABLE `_users_bench` LIKE `users`").Return(nil)
+	mockDB.On("ExecuteAlter", "DROP TABLE IF EXISTS `_users_bench`").Return(nil)
+	mockDB.On("CopyChunk", "users", "_users_bench", []string{"id"}, []any(nil), 2).Return([]any{int64(2)}, int64(2), nil)
+	mockDB.On("CopyChunk", "users", "_users_bench", []string{"id"}, []any{int64(2)}, 2).Return([]any{int64(4)}, int64(2), nil)
+	// sampleRows の残りが1行なのでチャンクを縮める
+	mockDB.On("CopyChunk", "users", "_users_bench", []string{"id"}, []any{int64(4)}, 1).Return([]any{int64(5)}, int64(1), nil)
+
+	replicaDB := &MockDBClient{}
+	replicaDB.On("GetReplicaLagSeconds").Return(0.0, nil).Once()
+	replicaDB.On("GetReplicaLagSeconds").Return(3.0, nil).Once()
+	replicaDB.On("GetReplicaLagSeconds").Return(0.5, nil)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyBenchmarkResult", "users", mock.Anything, mock.Anything).Return(nil)
+
+	manager := newBenchmarkTestManager(mockDB, mockSlack, false)
+	result, err := manager.BenchmarkTable("users", 5, []RollingHost{{Name: "replica1", DB: replicaDB}})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(5), result.CopiedRows)
+	assert.Equal(t, 3, result.Chunks)
+	assert.Equal(t, "replica1", result.LagSource)
+	assert.Equal(t, 3.0, result.MaxLagSeconds)
+	assert.Equal(t, 1, result.LagWaits)
+	assert.Equal(t, 4.0, result.SuggestedMaxLag())
+	assert.Contains(t, result.Summary(), "pt_osc.max_lag: 4")
+	mockDB.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestBenchmarkTableStopsWhenTableIsExhausted(t *testing.T) {
+	mockDB := &MockDBClient{}
+	mockDB.On("GetPrimaryKeyColumns", "users").Return([]string{"tenant_id", "id"}, nil)
+	mockDB.On("TableExists", "_users_bench").Return(false, nil)
+	mockDB.On("ExecuteAlter", "CREATE TABLE `_users_bench` LIKE `users`").Return(nil)
+	mockDB.On("ExecuteAlter", "DROP TABLE IF EXISTS `_users_bench`").Return(nil)
+	mockDB.On("CopyChunk", "users", "_users_bench", []string{"tenant_id", "id"}, []any(nil), 2).Return(nil, int64(1), nil)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyBenchmarkResult", "users", mock.Anything, mock.Anything).Return(nil)
+
+	manager := newBenchmarkTestManager(mockDB, mockSlack, false)
+	result, err := manager.BenchmarkTable("users", 100, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), result.CopiedRows)
+	assert.Empty(t, result.LagSource)
+	assert.Equal(t, float64(0), result.SuggestedMaxLag())
+	assert.Contains(t, result.Summary(), "not measured")
+	mockDB.AssertExpectations(t)
+}
+
+func TestBenchmarkTableDropsScratchTableOnError(t *testing.T) {
+	copyErr := errors.New("lock wait timeout")
+	mockDB := &MockDBClient{}
+	mockDB.On("GetPrimaryKeyColumns", "users").Return([]string{"id"}, nil)
+	mockDB.On("TableExists", "_users_bench").Return(false, nil)
+	mockDB.On("ExecuteAlter", "CREATE TABLE `_users_bench` LIKE `users`").Return(nil)
+	mockDB.On("ExecuteAlter", "DROP TABLE IF EXISTS `_users_bench`").Return(nil)
+	mockDB.On("CopyChunk", "users", "_users_bench", []string{"id"}, []any(nil), 2).Return(nil, int64(0), copyErr)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyFailure", "benchmark", "users", int64(0), copyErr).Return(nil)
+
+	manager := newBenchmarkTestManager(mockDB, mockSlack, false)
+	_, err := manager.BenchmarkTable("users", 100, nil)
+	require.ErrorIs(t, err, copyErr)
+	mockDB.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestBenchmarkTableRequirements(t *testing.T) {
+	t.Run("no primary key", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetPrimaryKeyColumns", "logs").Return([]string{}, nil)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyFailure", "benchmark", "logs", int64(0), mock.Anything).Return(nil)
+
+		_, err := newBenchmarkTestManager(mockDB, mockSlack, false).BenchmarkTable("logs", 0, nil)
+		assert.ErrorContains(t, err, "no primary key")
+	})
+
+	t.Run("scratch table exists", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetPrimaryKeyColumns", "users").Return([]string{"id"}, nil)
+		mockDB.On("TableExists", "_users_bench").Return(true, nil)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyFailure", "benchmark", "users", int64(0), mock.Anything).Return(nil)
+
+		_, err := newBenchmarkTestManager(mockDB, mockSlack, false).BenchmarkTable("users", 0, nil)
+		assert.ErrorContains(t, err, "already exists")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetPrimaryKeyColumns", "users").Return([]string{"id"}, nil)
+		mockDB.On("TableExists", "_users_bench").Return(false, nil)
+
+		result, err := newBenchmarkTestManager(mockDB, &MockSlackNotifier{}, true).BenchmarkTable("users", 0, nil)
+		require.NoError(t, err)
+		assert.Nil(t, result)
+		mockDB.AssertNotCalled(t, "ExecuteAlter", mock.Anything)
+	})
+}
+
+func TestBenchmarkResultSuggestions(t *testing.T) {
+	result := &BenchmarkResult{CopiedRows: 100000, Duration: 12 * time.Second, LagWaitTime: 2 * time.Second}
+	assert.Equal(t, float64(10000), result.RowsPerSecond())
+	assert.Equal(t, 5000, result.SuggestedChunkSize())
+
+	slow := &BenchmarkResult{CopiedRows: 10, Duration: time.Second}
+	assert.Equal(t, 100, slow.SuggestedChunkSize())
+}
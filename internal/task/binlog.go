@@ -0,0 +1,78 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	binlogPolicyWarn   = "warn"
+	binlogPolicyBlock  = "block"
+	binlogPolicyIgnore = "ignore"
+)
+
+// checkBinlogSettings は binlog_check.policy に従って binlog_format / binlog_row_image を確認し、
+// 開始通知に載せる注意点を返す。block のときは注意点があればエラーにする。
+func (m *Manager) checkBinlogSettings() ([]string, error) {
+	policy := m.config.Common.BinlogCheck.Policy
+	switch policy {
+	case "", binlogPolicyIgnore:
+		return nil, nil
+	case binlogPolicyWarn, binlogPolicyBlock:
+	default:
+		return nil, fmt.Errorf("invalid binlog_check.policy %q (must be warn, block or ignore)", policy)
+	}
+
+	settings, err := m.db.GetBinlogSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check binlog settings: %w", err)
+	}
+	if !settings.LogBin {
+		m.logger.Infof("Binary logging is disabled, skipping binlog check")
+		return nil, nil
+	}
+
+	var findings []string
+	switch strings.ToUpper(settings.Format) {
+	case "STATEMENT":
+		findings = append(findings, "binlog_format=STATEMENT: pt-online-schema-change's chunked INSERT ... SELECT copy and its triggers are not deterministic under statement-based replication, and replicas can diverge")
+	case "MIXED":
+		findings = append(findings, "binlog_format=MIXED: statements the server considers safe are still logged as statements, so replicas and binlog consumers may see a different copy than the source")
+	}
+	if strings.ToUpper(settings.Format) != "STATEMENT" {
+		switch strings.ToUpper(settings.RowImage) {
+		case "MINIMAL", "NOBLOB":
+			findings = append(findings, fmt.Sprintf("binlog_row_image=%s: row events for copied and updated rows do not carry every column, which breaks CDC consumers that expect full rows", strings.ToUpper(settings.RowImage)))
+		}
+	}
+
+	if len(findings) == 0 {
+		m.logger.Infof("Binlog check passed (binlog_format=%s, binlog_row_image=%s)", settings.Format, settings.RowImage)
+		return nil, nil
+	}
+	for _, finding := range findings {
+		m.logger.Warn(finding)
+	}
+
+	if policy == binlogPolicyBlock {
+		errMsg := "binlog check failed: " + strings.Join(findings, "; ")
+		if slackErr := m.slack.NotifyWarning("binlog-check", "", errMsg); slackErr != nil {
+			m.logger.Errorf("Failed to send binlog check warning notification: %v", slackErr)
+		}
+		return nil, fmt.Errorf("%s", errMsg)
+	}
+	return findings, nil
+}
+
+// notifyAllTasksStart は全体の開始を通知する。実行前の確認で注意点があれば一緒に載せる
+func (m *Manager) notifyAllTasksStart(totalQueries int, findings []string) {
+	var err error
+	if len(findings) > 0 {
+		err = m.slack.NotifyAllTasksStartWithFindings(totalQueries, findings)
+	} else {
+		err = m.slack.NotifyAllTasksStart(totalQueries)
+	}
+	if err != nil {
+		m.logger.Errorf("Failed to send all tasks start notification: %v", err)
+	}
+}
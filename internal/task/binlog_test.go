@@ -0,0 +1,85 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBinlogSettings(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	newManager := func(policy string, settings *database.BinlogSettings, mockSlack *MockSlackNotifier) (*Manager, *MockDBClient) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetBinlogSettings").Return(settings, nil)
+		cfg := &config.Config{Common: config.CommonConfig{BinlogCheck: config.BinlogCheckConfig{Policy: policy}}}
+		return NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false), mockDB
+	}
+
+	t.Run("not configured", func(t *testing.T) {
+		manager, mockDB := newManager("", nil, &MockSlackNotifier{})
+		findings, err := manager.checkBinlogSettings()
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+		mockDB.AssertNotCalled(t, "GetBinlogSettings")
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		manager, _ := newManager("strict", nil, &MockSlackNotifier{})
+		_, err := manager.checkBinlogSettings()
+		assert.ErrorContains(t, err, "invalid binlog_check.policy")
+	})
+
+	t.Run("row format with full images", func(t *testing.T) {
+		manager, _ := newManager("block", &database.BinlogSettings{LogBin: true, Format: "ROW", RowImage: "FULL"}, &MockSlackNotifier{})
+		findings, err := manager.checkBinlogSettings()
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("binary log disabled", func(t *testing.T) {
+		manager, _ := newManager("block", &database.BinlogSettings{LogBin: false, Format: "STATEMENT", RowImage: "FULL"}, &MockSlackNotifier{})
+		findings, err := manager.checkBinlogSettings()
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("warn reports findings", func(t *testing.T) {
+		manager, _ := newManager("warn", &database.BinlogSettings{LogBin: true, Format: "MIXED", RowImage: "MINIMAL"}, &MockSlackNotifier{})
+		findings, err := manager.checkBinlogSettings()
+		require.NoError(t, err)
+		require.Len(t, findings, 2)
+		assert.Contains(t, findings[0], "binlog_format=MIXED")
+		assert.Contains(t, findings[1], "binlog_row_image=MINIMAL")
+	})
+
+	t.Run("block stops on statement-based replication", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "binlog-check", "", mock.AnythingOfType("string")).Return(nil)
+		manager, _ := newManager("block", &database.BinlogSettings{LogBin: true, Format: "STATEMENT", RowImage: "MINIMAL"}, mockSlack)
+		_, err := manager.checkBinlogSettings()
+		assert.ErrorContains(t, err, "binlog_format=STATEMENT")
+		assert.NotContains(t, err.Error(), "binlog_row_image")
+		mockSlack.AssertExpectations(t)
+	})
+}
+
+func TestNotifyAllTasksStartWithFindings(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyAllTasksStart", 3).Return(nil).Once()
+	mockSlack.On("NotifyAllTasksStartWithFindings", 3, []string{"binlog_format=MIXED"}).Return(nil).Once()
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+	manager.notifyAllTasksStart(3, nil)
+	manager.notifyAllTasksStart(3, []string{"binlog_format=MIXED"})
+	mockSlack.AssertExpectations(t)
+}
@@ -0,0 +1,80 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/database"
+)
+
+// checkCollationDrift は swap 前に元テーブルと _new テーブルの照合順序とテーブルオプションを比較する。
+// pt-osc の --charset やサーバーのデフォルト設定によって、ALTER で意図していない照合順序の変更が
+// 入っていることがあるため、差分があれば警告する。意図した変更もありうるため swap は止めない。
+func (m *Manager) checkCollationDrift(tableName string) {
+	newTableName := fmt.Sprintf("_%s_new", tableName)
+
+	original, err := m.db.GetTableCharsetInfo(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get charset info of %s: %v", tableName, err)
+		return
+	}
+	shadow, err := m.db.GetTableCharsetInfo(newTableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get charset info of %s: %v", newTableName, err)
+		return
+	}
+
+	differences := diffCharsetInfo(original, shadow)
+	if len(differences) == 0 {
+		m.logger.Infof("Collations and table options of %s match %s", newTableName, tableName)
+		return
+	}
+
+	message := fmt.Sprintf("%s differs from %s: %s", newTableName, tableName, strings.Join(differences, "; "))
+	if charset := m.config.Common.PtOsc.Charset; charset != "" {
+		message += fmt.Sprintf(" (pt_osc.charset is %s)", charset)
+	}
+	m.logger.Warn(message)
+
+	taskName := "swap-collation-check"
+	if m.dryRun {
+		taskName = "swap-collation-check (DRY RUN)"
+	}
+	if err := m.slack.NotifyWarning(taskName, tableName, message); err != nil {
+		m.logger.Errorf("Failed to send collation check warning notification: %v", err)
+	}
+}
+
+// diffCharsetInfo は両方のテーブルに存在するカラムとテーブルオプションの差分を返す。
+// 追加・削除されたカラムは ALTER による意図的な変更なので対象にしない。
+func diffCharsetInfo(original, shadow *database.TableCharsetInfo) []string {
+	var differences []string
+	options := []struct {
+		name          string
+		before, after string
+	}{
+		{"table collation", original.Collation, shadow.Collation},
+		{"engine", original.Engine, shadow.Engine},
+		{"row_format", original.RowFormat, shadow.RowFormat},
+		{"create_options", original.CreateOptions, shadow.CreateOptions},
+	}
+	for _, option := range options {
+		if option.before != option.after {
+			differences = append(differences, fmt.Sprintf("%s %s -> %s", option.name, option.before, option.after))
+		}
+	}
+
+	columns := make([]string, 0, len(original.Columns))
+	for column := range original.Columns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		after, ok := shadow.Columns[column]
+		if ok && original.Columns[column] != after {
+			differences = append(differences, fmt.Sprintf("column %s %s -> %s", column, original.Columns[column], after))
+		}
+	}
+	return differences
+}
@@ -0,0 +1,70 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDiffCharsetInfo(t *testing.T) {
+	original := &database.TableCharsetInfo{
+		Engine:    "InnoDB",
+		Collation: "utf8mb4_0900_ai_ci",
+		RowFormat: "Dynamic",
+		Columns:   map[string]string{"name": "utf8mb4_0900_ai_ci", "email": "utf8mb4_bin", "dropped": "utf8mb4_bin"},
+	}
+
+	t.Run("identical", func(t *testing.T) {
+		shadow := &database.TableCharsetInfo{
+			Engine:    "InnoDB",
+			Collation: "utf8mb4_0900_ai_ci",
+			RowFormat: "Dynamic",
+			Columns:   map[string]string{"name": "utf8mb4_0900_ai_ci", "email": "utf8mb4_bin", "added": "utf8mb4_general_ci"},
+		}
+		assert.Empty(t, diffCharsetInfo(original, shadow))
+	})
+
+	t.Run("drifted", func(t *testing.T) {
+		shadow := &database.TableCharsetInfo{
+			Engine:    "InnoDB",
+			Collation: "utf8mb4_general_ci",
+			RowFormat: "Compact",
+			Columns:   map[string]string{"name": "utf8mb4_general_ci", "email": "utf8mb4_bin"},
+		}
+		assert.Equal(t, []string{
+			"table collation utf8mb4_0900_ai_ci -> utf8mb4_general_ci",
+			"row_format Dynamic -> Compact",
+			"column name utf8mb4_0900_ai_ci -> utf8mb4_general_ci",
+		}, diffCharsetInfo(original, shadow))
+	})
+}
+
+func TestCheckCollationDrift(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableCharsetInfo", "users").Return(&database.TableCharsetInfo{
+		Collation: "utf8mb4_0900_ai_ci",
+		Columns:   map[string]string{"name": "utf8mb4_0900_ai_ci"},
+	}, nil)
+	mockDB.On("GetTableCharsetInfo", "_users_new").Return(&database.TableCharsetInfo{
+		Collation: "utf8mb4_general_ci",
+		Columns:   map[string]string{"name": "utf8mb4_general_ci"},
+	}, nil)
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyWarning", "swap-collation-check", "users", mock.Anything).Return(nil)
+
+	cfg := &config.Config{Common: config.CommonConfig{PtOsc: config.PtOscConfig{Charset: "utf8mb4"}}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	manager.checkCollationDrift("users")
+
+	mockSlack.AssertCalled(t, "NotifyWarning", "swap-collation-check", "users",
+		"_users_new differs from users: table collation utf8mb4_0900_ai_ci -> utf8mb4_general_ci; column name utf8mb4_0900_ai_ci -> utf8mb4_general_ci (pt_osc.charset is utf8mb4)")
+	mockDB.AssertExpectations(t)
+}
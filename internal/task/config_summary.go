@@ -0,0 +1,132 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/config"
+)
+
+// FormatConfigSummary は実行時に有効な設定 (環境変数・フラグ・profile を反映した後のもの) を人が読む形にまとめる。
+// 事後の振り返りで、git 上の設定ファイルではなく実際に使われた値を確かめられるようにする。パスワードや URL は含めない
+func FormatConfigSummary(cfg *config.Config, dryRun bool) string {
+	common := cfg.Common
+	environment := cfg.Environment
+	if environment == "" {
+		environment = "(none)"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Environment: %s\n", environment)
+	fmt.Fprintf(&b, "Dry run: %t\n", dryRun)
+
+	fmt.Fprintf(&b, "\nThresholds:\n")
+	fmt.Fprintf(&b, "  pt_osc_threshold: %d rows\n", common.PtOscThreshold)
+	fmt.Fprintf(&b, "  pt_osc_size_threshold_mb: %s\n", disabledIfZero(common.PtOscSizeThresholdMB, "%g MB"))
+	fmt.Fprintf(&b, "  force_method: %s\n", orDefault(common.ForceMethod, "(none)"))
+
+	ptOsc := common.PtOsc
+	fmt.Fprintf(&b, "\npt-osc:\n")
+	fmt.Fprintf(&b, "  chunk_size: %s\n", orDefault(intOrEmpty(ptOsc.ChunkSize), "(pt-osc default)"))
+	fmt.Fprintf(&b, "  max_lag: %s\n", orDefault(floatOrEmpty(ptOsc.MaxLag), "(pt-osc default)"))
+	fmt.Fprintf(&b, "  recursion_method: %s\n", orDefault(ptOsc.RecursionMethod, "(pt-osc default)"))
+	fmt.Fprintf(&b, "  charset: %s\n", orDefault(ptOsc.Charset, "(pt-osc default)"))
+	fmt.Fprintf(&b, "  flags: %s\n", orDefault(strings.Join(ptOscBoolFlags(ptOsc), " "), "(none)"))
+	fmt.Fprintf(&b, "  aurora_replica_check: %s\n", enabledIf(ptOsc.AuroraReplicaCheck.Enabled))
+	fmt.Fprintf(&b, "  auto_swap: %s\n", enabledIf(ptOsc.AutoSwap.Enabled))
+	if len(ptOsc.Tables) > 0 {
+		fmt.Fprintf(&b, "  per-table overrides: %d tables\n", len(ptOsc.Tables))
+	}
+
+	fmt.Fprintf(&b, "\nSafety checks:\n")
+	fmt.Fprintf(&b, "  connection_check: %s\n", enabledIf(common.ConnectionCheck.Enabled))
+	fmt.Fprintf(&b, "  replication_check: %s\n", enabledIf(common.ReplicationCheck.Enabled))
+	fmt.Fprintf(&b, "  binlog_check: %s\n", orDefault(common.BinlogCheck.Policy, "disabled"))
+	fmt.Fprintf(&b, "  conflict_check: %s\n", orDefault(common.ConflictCheck.Policy, "disabled"))
+	fmt.Fprintf(&b, "  dependency_check: %s\n", orDefault(common.DependencyCheck.Policy, "disabled"))
+	fmt.Fprintf(&b, "  min_connection_headroom_percent: %s\n", disabledIfZero(common.MinConnectionHeadroomPercent, "%g%%"))
+	fmt.Fprintf(&b, "  row_count_verify: %s\n", disabledIfZero(common.RowCountVerify.MaxDivergencePercent, "%g%%"))
+	fmt.Fprintf(&b, "  swap_freshness_check: %s\n", enabledIf(!common.SwapFreshness.Disabled))
+	fmt.Fprintf(&b, "  duplicate_run_window: %s\n", orDefault(common.DuplicateRunWindow, "disabled"))
+	fmt.Fprintf(&b, "  require_execute_flag: %s\n", enabledIf(common.RequireExecuteFlag))
+
+	fmt.Fprintf(&b, "\nLimits:\n")
+	fmt.Fprintf(&b, "  lock_wait_timeout: %s\n", orDefault(intOrEmpty(common.SessionConfig.LockWaitTimeout), "(server default)"))
+	fmt.Fprintf(&b, "  innodb_lock_wait_timeout: %s\n", orDefault(intOrEmpty(common.SessionConfig.InnodbLockWaitTimeout), "(server default)"))
+	fmt.Fprintf(&b, "  task_timeout: %s\n", orDefault(common.TaskTimeout, "(none)"))
+	fmt.Fprintf(&b, "  run_timeout: %s", orDefault(common.RunTimeout, "(none)"))
+	return b.String()
+}
+
+// ptOscBoolFlags は有効になっている pt-osc の真偽値の設定を返す
+func ptOscBoolFlags(ptOsc config.PtOscConfig) []string {
+	var flags []string
+	for _, flag := range []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "no_swap_tables", enabled: ptOsc.NoSwapTables},
+		{name: "no_drop_triggers", enabled: ptOsc.NoDropTriggers},
+		{name: "no_drop_new_table", enabled: ptOsc.NoDropNewTable},
+		{name: "no_drop_old_table", enabled: ptOsc.NoDropOldTable},
+		{name: "no_check_unique_key_change", enabled: ptOsc.NoCheckUniqueKeyChange},
+		{name: "no_check_alter", enabled: ptOsc.NoCheckAlter},
+		{name: "statistics", enabled: ptOsc.Statistics},
+		{name: "dry_run", enabled: ptOsc.DryRun},
+	} {
+		if flag.enabled {
+			flags = append(flags, flag.name)
+		}
+	}
+	return flags
+}
+
+func enabledIf(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+func disabledIfZero(value float64, format string) string {
+	if value == 0 {
+		return "disabled"
+	}
+	return fmt.Sprintf(format, value)
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func intOrEmpty(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func floatOrEmpty(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// notifyConfigSummary は実行時の設定をログに出し、notify_config_summary が有効なら通知する
+func (m *Manager) notifyConfigSummary() {
+	summary := FormatConfigSummary(m.config, m.dryRun)
+	if len(m.thresholdOverrides) > 0 {
+		summary += "\n\nOverrides: " + strings.Join(m.thresholdOverrides, ", ")
+	}
+	m.logger.Infof("Resolved configuration:\n%s", summary)
+	if !m.config.Common.NotifyConfigSummary {
+		return
+	}
+	if err := m.slack.NotifyConfigSummary(summary); err != nil {
+		m.logger.Errorf("Failed to send configuration summary notification: %v", err)
+	}
+}
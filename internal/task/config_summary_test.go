@@ -0,0 +1,80 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFormatConfigSummary(t *testing.T) {
+	cfg := &config.Config{
+		Environment: "production",
+		Common: config.CommonConfig{
+			PtOscThreshold: 1000000,
+			PtOsc: config.PtOscConfig{
+				ChunkSize:       500,
+				RecursionMethod: "dsn=D=percona,t=dsns",
+				RecursionDSN:    "h=secret-host,u=admin,p=secret",
+				NoDropOldTable:  true,
+				Statistics:      true,
+			},
+			ConnectionCheck:    config.ConnectionCheckConfig{Enabled: true},
+			BinlogCheck:        config.BinlogCheckConfig{Policy: "block"},
+			SwapFreshness:      config.SwapFreshnessConfig{Disabled: true},
+			SessionConfig:      config.SessionConfig{LockWaitTimeout: 5},
+			RequireExecuteFlag: true,
+		},
+	}
+
+	summary := FormatConfigSummary(cfg, true)
+
+	assert.Contains(t, summary, "Environment: production")
+	assert.Contains(t, summary, "Dry run: true")
+	assert.Contains(t, summary, "pt_osc_threshold: 1000000 rows")
+	assert.Contains(t, summary, "pt_osc_size_threshold_mb: disabled")
+	assert.Contains(t, summary, "chunk_size: 500")
+	assert.Contains(t, summary, "max_lag: (pt-osc default)")
+	assert.Contains(t, summary, "flags: no_drop_old_table statistics")
+	assert.Contains(t, summary, "connection_check: enabled")
+	assert.Contains(t, summary, "replication_check: disabled")
+	assert.Contains(t, summary, "binlog_check: block")
+	assert.Contains(t, summary, "swap_freshness_check: disabled")
+	assert.Contains(t, summary, "require_execute_flag: enabled")
+	assert.Contains(t, summary, "lock_wait_timeout: 5")
+	assert.NotContains(t, summary, "secret")
+}
+
+func TestNotifyConfigSummary(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	t.Run("notifies when enabled", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		var got string
+		mockSlack.On("NotifyConfigSummary", mock.Anything).Run(func(args mock.Arguments) {
+			got = args.String(0)
+		}).Return(nil)
+
+		cfg := &config.Config{Common: config.CommonConfig{NotifyConfigSummary: true, PtOscThreshold: 100}}
+		manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+		manager.SetThresholdOverrides([]string{"pt_osc_threshold=100"})
+
+		manager.notifyConfigSummary()
+
+		mockSlack.AssertExpectations(t)
+		assert.Contains(t, got, "pt_osc_threshold: 100 rows")
+		assert.Contains(t, got, "Overrides: pt_osc_threshold=100")
+	})
+
+	t.Run("only logs when disabled", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+		manager.notifyConfigSummary()
+
+		mockSlack.AssertNotCalled(t, "NotifyConfigSummary", mock.Anything)
+	})
+}
@@ -0,0 +1,193 @@
+package task
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/schedule"
+)
+
+const defaultConflictWindow = time.Hour
+
+// conflictTimeLayout は衝突する予定の時刻を通知に載せるときの書式
+const conflictTimeLayout = "2006-01-02 15:04 MST"
+
+// checkScheduleConflicts は操作を始める前に、同じテーブルに触れる MySQL の EVENT と conflict_check.jobs の cron ジョブが
+// conflict_check.window の間に動く予定かを調べる。バッチのメタデータロックと swap がぶつかって長時間止まるのを防ぐため。
+func (m *Manager) checkScheduleConflicts(operation, tableName string) error {
+	check := m.config.Common.ConflictCheck
+	switch check.Policy {
+	case "", dependencyPolicyIgnore:
+		return nil
+	case dependencyPolicyWarn, dependencyPolicyBlock:
+	default:
+		return fmt.Errorf("invalid conflict_check.policy %q (must be warn, block or ignore)", check.Policy)
+	}
+
+	window, err := resolveRollingDuration(check.Window, defaultConflictWindow)
+	if err != nil {
+		return fmt.Errorf("invalid conflict_check.window: %w", err)
+	}
+	now := time.Now()
+
+	conflicts, err := m.findJobConflicts(tableName, now, window)
+	if err != nil {
+		return err
+	}
+
+	events, err := m.db.GetScheduledEvents(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to check events of %s: %w", tableName, err)
+	}
+	for _, event := range events {
+		next, runs := eventRunsWithin(event, now, window)
+		if !runs {
+			continue
+		}
+		if next.IsZero() {
+			conflicts = append(conflicts, fmt.Sprintf("event %s (schedule could not be determined)", event.Name))
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("event %s (next run %s)", event.Name, next.Format(conflictTimeLayout)))
+	}
+
+	if len(conflicts) == 0 {
+		m.logger.Infof("No events or known jobs touch %s in the next %s", tableName, window)
+		return nil
+	}
+
+	message := fmt.Sprintf("%s %s may collide with scheduled jobs in the next %s: %s",
+		operation, tableName, window, strings.Join(conflicts, ", "))
+	m.logger.Warn(message)
+
+	taskName := operation + "-conflict-check"
+	if m.dryRun {
+		taskName += " (DRY RUN)"
+	}
+	if err := m.slack.NotifyWarning(taskName, tableName, message); err != nil {
+		m.logger.Errorf("Failed to send conflict check warning notification: %v", err)
+	}
+
+	if check.Policy == dependencyPolicyBlock {
+		return fmt.Errorf("conflict check failed: %s", message)
+	}
+	return nil
+}
+
+// findJobConflicts は conflict_check.jobs のうち、テーブルに触れて window の間に動いている・動き出すものを返す
+func (m *Manager) findJobConflicts(tableName string, now time.Time, window time.Duration) ([]string, error) {
+	check := m.config.Common.ConflictCheck
+	location := time.Local
+	if check.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(check.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid conflict_check.timezone %q: %w", check.Timezone, err)
+		}
+	}
+
+	var conflicts []string
+	for i, job := range check.Jobs {
+		if !slices.ContainsFunc(job.Tables, func(table string) bool { return strings.EqualFold(table, tableName) }) {
+			continue
+		}
+		expression, err := schedule.Parse(job.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid conflict_check.jobs[%d].cron: %w", i, err)
+		}
+		var duration time.Duration
+		if job.Duration != "" {
+			duration, err = time.ParseDuration(job.Duration)
+			if err != nil {
+				return nil, fmt.Errorf("invalid conflict_check.jobs[%d].duration: %w", i, err)
+			}
+		}
+
+		// 開始時刻が now - duration より後なら、今動いているか window の間に動き出す
+		start := expression.Next(now.In(location).Add(-duration))
+		if start.IsZero() || start.After(now.Add(window)) {
+			continue
+		}
+		name := job.Name
+		if name == "" {
+			name = job.Cron
+		}
+		if start.Before(now) {
+			conflicts = append(conflicts, fmt.Sprintf("job %s (running since %s)", name, start.Format(conflictTimeLayout)))
+		} else {
+			conflicts = append(conflicts, fmt.Sprintf("job %s (starts %s)", name, start.Format(conflictTimeLayout)))
+		}
+	}
+	return conflicts, nil
+}
+
+// eventRunsWithin は EVENT が now から window の間に実行される予定かと、その時刻を返す。
+// 実行時刻が分からない場合 (タイムゾーンを変換できない、複合の間隔など) は安全側に倒してゼロ値と true を返す。
+func eventRunsWithin(event database.ScheduledEvent, now time.Time, window time.Duration) (time.Time, bool) {
+	end := now.Add(window)
+	if event.EndsIn.Valid && event.EndsIn.Int64 < 0 {
+		return time.Time{}, false
+	}
+	if event.EndsIn.Valid {
+		if ends := now.Add(time.Duration(event.EndsIn.Int64) * time.Second); ends.Before(end) {
+			end = ends
+		}
+	}
+
+	if strings.EqualFold(event.Type, "ONE TIME") {
+		if !event.ExecuteAtIn.Valid {
+			return time.Time{}, true
+		}
+		at := now.Add(time.Duration(event.ExecuteAtIn.Int64) * time.Second)
+		return at, !at.Before(now) && !at.After(end)
+	}
+
+	if !event.StartsIn.Valid || !event.IntervalValue.Valid || !event.IntervalField.Valid {
+		return time.Time{}, true
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(event.IntervalValue.String))
+	if err != nil || value <= 0 {
+		return time.Time{}, true
+	}
+	field := strings.ToUpper(event.IntervalField.String)
+	next := now.Add(time.Duration(event.StartsIn.Int64) * time.Second)
+	if unit, ok := fixedEventIntervals[field]; ok {
+		step := time.Duration(value) * unit
+		if next.Before(now) {
+			next = next.Add((now.Sub(next) + step - 1) / step * step)
+		}
+		return next, !next.After(end)
+	}
+
+	months, ok := calendarEventIntervals[field]
+	if !ok {
+		return time.Time{}, true
+	}
+	// 月単位の間隔でも 100 年分回せば十分
+	for i := 0; next.Before(now) && i < 1200; i++ {
+		next = next.AddDate(0, months*value, 0)
+	}
+	if next.Before(now) {
+		return time.Time{}, true
+	}
+	return next, !next.After(end)
+}
+
+// fixedEventIntervals と calendarEventIntervals は EVENT の EVERY 句の単位。複合の単位 (DAY_HOUR など) には対応しない
+var fixedEventIntervals = map[string]time.Duration{
+	"SECOND": time.Second,
+	"MINUTE": time.Minute,
+	"HOUR":   time.Hour,
+	"DAY":    24 * time.Hour,
+	"WEEK":   7 * 24 * time.Hour,
+}
+
+var calendarEventIntervals = map[string]int{
+	"MONTH":   1,
+	"QUARTER": 3,
+	"YEAR":    12,
+}
@@ -0,0 +1,149 @@
+package task
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventRunsWithin(t *testing.T) {
+	now := time.Date(2025, 1, 10, 1, 30, 0, 0, time.UTC)
+	seconds := func(v int64) sql.NullInt64 { return sql.NullInt64{Int64: v, Valid: true} }
+	text := func(v string) sql.NullString { return sql.NullString{String: v, Valid: true} }
+
+	tests := []struct {
+		name     string
+		event    database.ScheduledEvent
+		wantRuns bool
+		wantNext time.Time
+	}{
+		{
+			name:     "one time event inside the window",
+			event:    database.ScheduledEvent{Type: "ONE TIME", ExecuteAtIn: seconds(1800)},
+			wantRuns: true,
+			wantNext: now.Add(30 * time.Minute),
+		},
+		{
+			name:  "one time event after the window",
+			event: database.ScheduledEvent{Type: "ONE TIME", ExecuteAtIn: seconds(7200)},
+		},
+		{
+			name:     "daily event started long ago",
+			event:    database.ScheduledEvent{Type: "RECURRING", IntervalValue: text("1"), IntervalField: text("DAY"), StartsIn: seconds(-100*86400 + 1800)},
+			wantRuns: true,
+			wantNext: now.Add(30 * time.Minute),
+		},
+		{
+			name:  "daily event running later in the day",
+			event: database.ScheduledEvent{Type: "RECURRING", IntervalValue: text("1"), IntervalField: text("DAY"), StartsIn: seconds(-86400 + 5*3600)},
+		},
+		{
+			name:  "recurring event that has ended",
+			event: database.ScheduledEvent{Type: "RECURRING", IntervalValue: text("1"), IntervalField: text("MINUTE"), StartsIn: seconds(-3600), EndsIn: seconds(-60)},
+		},
+		{
+			name:     "monthly event",
+			event:    database.ScheduledEvent{Type: "RECURRING", IntervalValue: text("1"), IntervalField: text("MONTH"), StartsIn: seconds(-(31*86400 - 600))},
+			wantRuns: true,
+			wantNext: now.Add(-(31*86400-600)*time.Second).AddDate(0, 1, 0),
+		},
+		{
+			name:     "compound interval is treated as a conflict",
+			event:    database.ScheduledEvent{Type: "RECURRING", IntervalValue: text("1 12"), IntervalField: text("DAY_HOUR"), StartsIn: seconds(-60)},
+			wantRuns: true,
+		},
+		{
+			name:     "unconvertible time zone is treated as a conflict",
+			event:    database.ScheduledEvent{Type: "RECURRING", IntervalValue: text("1"), IntervalField: text("DAY")},
+			wantRuns: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, runs := eventRunsWithin(tt.event, now, time.Hour)
+			assert.Equal(t, tt.wantRuns, runs)
+			if tt.wantRuns {
+				assert.True(t, tt.wantNext.Equal(next), "next run %s, want %s", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestFindJobConflicts(t *testing.T) {
+	now := time.Date(2025, 1, 10, 1, 30, 0, 0, time.UTC)
+	cfg := &config.Config{Common: config.CommonConfig{ConflictCheck: config.ConflictCheckConfig{
+		Timezone: "UTC",
+		Jobs: []config.KnownJobConfig{
+			{Name: "nightly-batch", Cron: "0 2 * * *", Tables: []string{"orders", "users"}},
+			{Name: "report", Cron: "0 1 * * *", Tables: []string{"users"}, Duration: "45m"},
+			{Name: "morning", Cron: "0 9 * * *", Tables: []string{"users"}},
+			{Name: "other-table", Cron: "* * * * *", Tables: []string{"logs"}},
+		},
+	}}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logrus.New(), cfg, false)
+
+	conflicts, err := manager.findJobConflicts("users", now, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"job nightly-batch (starts 2025-01-10 02:00 UTC)",
+		"job report (running since 2025-01-10 01:00 UTC)",
+	}, conflicts)
+
+	cfg.Common.ConflictCheck.Jobs[0].Cron = "every night"
+	_, err = manager.findJobConflicts("users", now, time.Hour)
+	assert.ErrorContains(t, err, "invalid conflict_check.jobs[0].cron")
+}
+
+func TestCheckScheduleConflicts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	events := []database.ScheduledEvent{{
+		Name:        "purge_sessions",
+		Type:        "ONE TIME",
+		ExecuteAtIn: sql.NullInt64{Int64: 60, Valid: true},
+	}}
+	newManager := func(policy string, mockSlack *MockSlackNotifier) (*Manager, *MockDBClient) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetScheduledEvents", "users").Return(events, nil)
+		cfg := &config.Config{Common: config.CommonConfig{ConflictCheck: config.ConflictCheckConfig{Policy: policy}}}
+		return NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false), mockDB
+	}
+
+	t.Run("not configured", func(t *testing.T) {
+		manager, mockDB := newManager("", &MockSlackNotifier{})
+		assert.NoError(t, manager.checkScheduleConflicts("swap", "users"))
+		mockDB.AssertNotCalled(t, "GetScheduledEvents", mock.Anything)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		manager, _ := newManager("deny", &MockSlackNotifier{})
+		assert.ErrorContains(t, manager.checkScheduleConflicts("swap", "users"), "invalid conflict_check.policy")
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "swap-conflict-check", "users", mock.MatchedBy(func(msg string) bool {
+			return strings.Contains(msg, "swap users may collide with scheduled jobs in the next 1h0m0s: event purge_sessions (next run")
+		})).Return(nil)
+		manager, _ := newManager("warn", mockSlack)
+		assert.NoError(t, manager.checkScheduleConflicts("swap", "users"))
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "pt-osc-conflict-check", "users", mock.AnythingOfType("string")).Return(nil)
+		manager, _ := newManager("block", mockSlack)
+		assert.ErrorContains(t, manager.checkScheduleConflicts("pt-osc", "users"), "conflict check failed")
+	})
+}
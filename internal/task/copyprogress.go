@@ -0,0 +1,109 @@
+package task
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/artifacts"
+	"github.com/pyama86/alterguard/internal/ptosc"
+)
+
+// copyProgressInterval は pt-osc の進み具合を書き出す間隔
+var copyProgressInterval = 30 * time.Second
+
+// startCopyProgressRecorder は pt-osc の実行中、copyProgressInterval ごとに進み具合を --artifacts-dir に書き出す。
+// 返り値の関数で pt-osc の終了を伝えると、最後の状態を書き出して止まる。成果物を書き出さない場合は何もしない
+func (m *Manager) startCopyProgressRecorder(tableName string) func(completed bool) {
+	if m.artifacts.Dir() == "" {
+		return func(bool) {}
+	}
+	if _, ok := m.ptosc.(*ptosc.PtOscExecutor); !ok {
+		return func(bool) {}
+	}
+
+	keyColumns, err := m.db.GetPrimaryKeyColumns(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get primary key of %s, the chunk boundary will not be recorded: %v", tableName, err)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(copyProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.recordCopyProgress(tableName, keyColumns, false)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func(completed bool) {
+		close(done)
+		<-stopped
+		m.recordCopyProgress(tableName, keyColumns, completed)
+	}
+}
+
+func (m *Manager) recordCopyProgress(tableName string, keyColumns []string, completed bool) {
+	if stats := m.ptOscCopyStats(); stats != nil {
+		m.writeCopyProgress(tableName, keyColumns, *stats, completed)
+	}
+}
+
+// writeCopyProgress は pt-osc の出力から読み取った進み具合と、_new テーブルの最後の行のキーを書き出す
+func (m *Manager) writeCopyProgress(tableName string, keyColumns []string, stats ptosc.CopyStats, completed bool) {
+	progress := artifacts.CopyProgress{
+		TableName:       tableName,
+		Percent:         stats.ProgressPercent,
+		Remaining:       stats.ProgressRemaining,
+		ApproximateRows: stats.ApproximateRows,
+		CopiedRows:      stats.ApproximateRows * int64(stats.ProgressPercent) / 100,
+		CopyStartedAt:   stats.CopyStartedAt,
+		UpdatedAt:       time.Now(),
+		Completed:       completed,
+	}
+	if stats.PluginChunks > 0 {
+		progress.CopiedRows = stats.PluginRowsCopied
+		progress.Chunks = stats.PluginChunks
+		progress.LastChunkSeconds = stats.LastChunkSeconds
+	}
+	if len(keyColumns) > 0 && !stats.CopyStartedAt.IsZero() && !completed {
+		boundary, err := m.db.GetLastKey(fmt.Sprintf("_%s_new", tableName), keyColumns)
+		if err != nil {
+			m.logger.Warnf("Failed to get the last copied key of %s: %v", tableName, err)
+		} else {
+			progress.KeyColumns = keyColumns
+			progress.LastChunkBoundary = boundary
+		}
+	}
+
+	if err := m.artifacts.WriteProgress(progress); err != nil {
+		m.logger.Warnf("Failed to record copy progress of %s: %v", tableName, err)
+	}
+}
+
+// FormatCopyProgress は記録された pt-osc の進み具合と、最初から実行し直した場合の時間の見込みを人が読む形にまとめる
+func FormatCopyProgress(progress *artifacts.CopyProgress) string {
+	if progress.Completed {
+		return fmt.Sprintf("pt-osc finished copying at %s", progress.UpdatedAt.Format(time.RFC3339))
+	}
+
+	message := fmt.Sprintf("Last recorded copy progress (%s): %d%% (~%d of %d rows)",
+		progress.UpdatedAt.Format(time.RFC3339), progress.Percent, progress.CopiedRows, progress.ApproximateRows)
+	if progress.Chunks > 0 {
+		message += fmt.Sprintf("\nChunks copied: %d (last chunk took %.2fs)", progress.Chunks, progress.LastChunkSeconds)
+	}
+	if len(progress.LastChunkBoundary) > 0 {
+		message += fmt.Sprintf("\nLast chunk boundary: %v = %v", progress.KeyColumns, progress.LastChunkBoundary)
+	}
+	if rate := progress.RowsPerSecond(); rate > 0 {
+		message += fmt.Sprintf("\nCopy rate: %.0f rows/sec", rate)
+		message += fmt.Sprintf("\nA re-run starts the copy from the beginning and would take about %s", progress.EstimatedCopyDuration().Round(time.Second))
+	}
+	return message
+}
@@ -0,0 +1,99 @@
+package task
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/artifacts"
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCopyProgress(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetLastKey", "_users_new", []string{"id"}).Return([]string{"250000"}, nil)
+
+	recorder, err := artifacts.NewRecorder(t.TempDir(), "run", time.Now())
+	require.NoError(t, err)
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	manager.SetArtifactsRecorder(recorder)
+
+	started := time.Now().Add(-time.Minute)
+	manager.writeCopyProgress("users", []string{"id"}, ptosc.CopyStats{
+		ApproximateRows:   1000000,
+		CopyStartedAt:     started,
+		ProgressPercent:   25,
+		ProgressRemaining: "03:00",
+	}, false)
+	mockDB.AssertExpectations(t)
+
+	data, err := os.ReadFile(artifacts.ProgressPath(recorder.Dir(), "users"))
+	require.NoError(t, err)
+	var progress artifacts.CopyProgress
+	require.NoError(t, json.Unmarshal(data, &progress))
+	assert.Equal(t, 25, progress.Percent)
+	assert.Equal(t, "03:00", progress.Remaining)
+	assert.Equal(t, int64(250000), progress.CopiedRows)
+	assert.Equal(t, []string{"id"}, progress.KeyColumns)
+	assert.Equal(t, []string{"250000"}, progress.LastChunkBoundary)
+	assert.False(t, progress.Completed)
+
+	formatted := FormatCopyProgress(&progress)
+	assert.Contains(t, formatted, "25% (~250000 of 1000000 rows)")
+	assert.Contains(t, formatted, "Last chunk boundary: [id] = [250000]")
+	assert.Contains(t, formatted, "A re-run starts the copy from the beginning and would take about")
+}
+
+func TestWriteCopyProgressFromPlugin(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	recorder, err := artifacts.NewRecorder(t.TempDir(), "run", time.Now())
+	require.NoError(t, err)
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	manager.SetArtifactsRecorder(recorder)
+
+	manager.writeCopyProgress("users", nil, ptosc.CopyStats{
+		ApproximateRows:  1000000,
+		CopyStartedAt:    time.Now().Add(-time.Minute),
+		ProgressPercent:  30,
+		PluginChunks:     312,
+		PluginRowsCopied: 312000,
+		LastChunkSeconds: 0.5,
+	}, false)
+
+	data, err := os.ReadFile(artifacts.ProgressPath(recorder.Dir(), "users"))
+	require.NoError(t, err)
+	var progress artifacts.CopyProgress
+	require.NoError(t, json.Unmarshal(data, &progress))
+	assert.Equal(t, int64(312000), progress.CopiedRows)
+	assert.Equal(t, int64(312), progress.Chunks)
+	assert.Contains(t, FormatCopyProgress(&progress), "Chunks copied: 312 (last chunk took 0.50s)")
+}
+
+func TestWriteCopyProgressCompleted(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	recorder, err := artifacts.NewRecorder(t.TempDir(), "run", time.Now())
+	require.NoError(t, err)
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	manager.SetArtifactsRecorder(recorder)
+
+	manager.writeCopyProgress("users", []string{"id"}, ptosc.CopyStats{ApproximateRows: 100, CopyStartedAt: time.Now(), ProgressPercent: 100}, true)
+
+	data, err := os.ReadFile(artifacts.ProgressPath(recorder.Dir(), "users"))
+	require.NoError(t, err)
+	var progress artifacts.CopyProgress
+	require.NoError(t, json.Unmarshal(data, &progress))
+	assert.True(t, progress.Completed)
+	assert.Contains(t, FormatCopyProgress(&progress), "pt-osc finished copying")
+}
@@ -0,0 +1,77 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/database"
+)
+
+const (
+	dependencyPolicyWarn   = "warn"
+	dependencyPolicyBlock  = "block"
+	dependencyPolicyIgnore = "ignore"
+)
+
+func (m *Manager) dependencyPolicy() (string, error) {
+	policy := m.config.Common.DependencyCheck.Policy
+	switch policy {
+	case "":
+		return dependencyPolicyWarn, nil
+	case dependencyPolicyWarn, dependencyPolicyBlock, dependencyPolicyIgnore:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid dependency_check.policy %q (must be warn, block or ignore)", policy)
+	}
+}
+
+// checkTableDependencies は RENAME や DROP で壊れうるビュー・トリガー・ストアドルーチンを調べて通知する。
+// ビューやルーチンは名前で参照しているため、swap後は新しいテーブルを、cleanup後は存在しないテーブルを指すことになる。
+// 元テーブルのトリガーは RENAME で _old テーブルに付いていく。
+func (m *Manager) checkTableDependencies(operation, tableName string) error {
+	policy, err := m.dependencyPolicy()
+	if err != nil {
+		return err
+	}
+	if policy == dependencyPolicyIgnore {
+		return nil
+	}
+
+	dependencies, err := m.db.GetTableDependencies(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to check dependencies of %s: %w", tableName, err)
+	}
+	if len(dependencies) == 0 {
+		m.logger.Infof("No views, triggers or routines reference %s", tableName)
+		return nil
+	}
+
+	message := fmt.Sprintf("%s %s may affect objects referencing it: %s",
+		operation, tableName, describeDependencies(dependencies))
+	m.logger.Warn(message)
+
+	taskName := operation + "-dependency-check"
+	if m.dryRun {
+		taskName += " (DRY RUN)"
+	}
+	if err := m.slack.NotifyWarning(taskName, tableName, message); err != nil {
+		m.logger.Errorf("Failed to send dependency check warning notification: %v", err)
+	}
+
+	if policy == dependencyPolicyBlock {
+		return fmt.Errorf("dependency check failed: %s", message)
+	}
+	return nil
+}
+
+func describeDependencies(dependencies []database.TableDependency) string {
+	descriptions := make([]string, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		description := fmt.Sprintf("%s %s", strings.ToLower(dependency.Type), dependency.Name)
+		if dependency.OnTable != "" {
+			description += fmt.Sprintf(" (on %s)", dependency.OnTable)
+		}
+		descriptions = append(descriptions, description)
+	}
+	return strings.Join(descriptions, ", ")
+}
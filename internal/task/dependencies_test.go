@@ -0,0 +1,70 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTableDependencies(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dependencies := []database.TableDependency{
+		{Type: "VIEW", Name: "active_users"},
+		{Type: "TRIGGER", Name: "users_audit", OnTable: "users"},
+		{Type: "PROCEDURE", Name: "purge_users"},
+	}
+
+	tests := []struct {
+		name         string
+		policy       string
+		dependencies []database.TableDependency
+		expectQuery  bool
+		expectNotify bool
+		expectError  string
+	}{
+		{name: "no dependencies", expectQuery: true},
+		{name: "warn by default", dependencies: dependencies, expectQuery: true, expectNotify: true},
+		{name: "block", policy: "block", dependencies: dependencies, expectQuery: true, expectNotify: true, expectError: "dependency check failed"},
+		{name: "ignore", policy: "ignore"},
+		{name: "invalid policy", policy: "stop", expectError: "invalid dependency_check.policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockDBClient{}
+			if tt.expectQuery {
+				mockDB.On("GetTableDependencies", "users").Return(tt.dependencies, nil)
+			}
+			mockSlack := &MockSlackNotifier{}
+			mockSlack.On("NotifyWarning", "swap-dependency-check", "users", mock.Anything).Return(nil)
+
+			cfg := &config.Config{Common: config.CommonConfig{
+				DependencyCheck: config.DependencyCheckConfig{Policy: tt.policy},
+			}}
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+			err := manager.checkTableDependencies("swap", "users")
+
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				require.NoError(t, err)
+			}
+			if tt.expectNotify {
+				mockSlack.AssertCalled(t, "NotifyWarning", "swap-dependency-check", "users",
+					"swap users may affect objects referencing it: view active_users, trigger users_audit (on users), procedure purge_users")
+			} else {
+				mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
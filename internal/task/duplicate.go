@@ -0,0 +1,136 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/database"
+)
+
+const (
+	duplicatePolicyWarn = "warn"
+	duplicatePolicyFail = "fail"
+)
+
+// duplicate_errors で指定がない場合の扱い。1060 は ADD COLUMN の指定ミスを見逃さないよう失敗にする
+var defaultDuplicatePolicies = map[int]string{
+	1050: duplicatePolicyWarn,
+	1060: duplicatePolicyFail,
+	1061: duplicatePolicyWarn,
+	1062: duplicatePolicyWarn,
+}
+
+// duplicatePolicy は環境ごとの設定、共通の設定、既定値の順に重複エラーの扱いを決め、どの設定によるものかと合わせて返す
+func (m *Manager) duplicatePolicy(code int) (string, string) {
+	policies := m.config.Common.DuplicateErrors
+	if policy, ok := policies.Environments[m.config.Environment][code]; ok {
+		return policy, fmt.Sprintf("duplicate_errors.environments.%s", m.config.Environment)
+	}
+	if policy, ok := policies.Codes[code]; ok {
+		return policy, "duplicate_errors.codes"
+	}
+	return defaultDuplicatePolicies[code], "default"
+}
+
+// handleDuplicateError は重複系のエラーを duplicate_errors の設定に従って扱う。
+// warn なら判断の根拠を含めた警告を通知して nil を返し、fail なら根拠を付けたエラーを返す。
+// 重複系以外のエラーはそのまま返す。
+func (m *Manager) handleDuplicateError(taskName string, queryInfo *QueryInfo, err error) error {
+	code, ok := database.DuplicateErrorCode(err)
+	if !ok {
+		return err
+	}
+
+	policy, source := m.duplicatePolicy(code)
+	switch policy {
+	case duplicatePolicyWarn:
+		warning := fmt.Sprintf("Duplicate detected in %s: %s (query: %s, policy for %d: warn by %s)", taskName, err.Error(), queryInfo.Query, code, source)
+		m.logger.Warn(warning)
+
+		if m.duplicateWarnings != nil {
+			m.duplicateWarnings[queryInfo.TableName] = append(m.duplicateWarnings[queryInfo.TableName], duplicateWarning{
+				taskName: taskName,
+				code:     code,
+				message:  warning,
+			})
+			return nil
+		}
+		if slackErr := m.slack.NotifyWarning(taskName, queryInfo.TableName, warning); slackErr != nil {
+			m.logger.Errorf("Failed to send warning notification: %v", slackErr)
+		}
+
+		return nil
+	case duplicatePolicyFail:
+		return fmt.Errorf("%w (policy for %d: fail by %s)", err, code, source)
+	default:
+		return fmt.Errorf("%w (invalid policy %q for %d in %s: must be warn or fail)", err, policy, code, source)
+	}
+}
+
+// duplicateWarningExamples は集約した警告の通知に載せる例の数
+const duplicateWarningExamples = 3
+
+// duplicateWarning は集約中の重複の警告1件
+type duplicateWarning struct {
+	taskName string
+	code     int
+	message  string
+}
+
+// startDuplicateWarningAggregation は以降の重複の警告を通知せずにためる。
+// IF NOT EXISTS 相当の再実行でクエリごとに警告が飛ばないよう、テーブルのまとまりの終わりに flushDuplicateWarnings でまとめて通知する
+func (m *Manager) startDuplicateWarningAggregation() {
+	m.duplicateWarnings = map[string][]duplicateWarning{}
+}
+
+// flushDuplicateWarnings はためた重複の警告をテーブルごとに1件の通知にまとめて送り、集約をやめる
+func (m *Manager) flushDuplicateWarnings() {
+	warnings := m.duplicateWarnings
+	m.duplicateWarnings = nil
+
+	tables := make([]string, 0, len(warnings))
+	for table := range warnings {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		tableWarnings := warnings[table]
+		message := tableWarnings[0].message
+		if len(tableWarnings) > 1 {
+			message = summarizeDuplicateWarnings(table, tableWarnings)
+		}
+		if err := m.slack.NotifyWarning(tableWarnings[0].taskName, table, message); err != nil {
+			m.logger.Errorf("Failed to send warning notification: %v", err)
+		}
+	}
+}
+
+// summarizeDuplicateWarnings はエラー番号ごとの件数と、先頭のいくつかの警告をまとめる
+func summarizeDuplicateWarnings(table string, warnings []duplicateWarning) string {
+	counts := map[int]int{}
+	for _, warning := range warnings {
+		counts[warning.code]++
+	}
+	codes := make([]int, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	countParts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		countParts = append(countParts, fmt.Sprintf("%d: %d", code, counts[code]))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d duplicate warnings for %s (%s)\nExamples:", len(warnings), table, strings.Join(countParts, ", "))
+	for i, warning := range warnings {
+		if i == duplicateWarningExamples {
+			fmt.Fprintf(&b, "\n... and %d more (see the log)", len(warnings)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s", warning.message)
+	}
+	return b.String()
+}
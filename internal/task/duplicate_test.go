@@ -0,0 +1,136 @@
+package task
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleDuplicateError(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	policies := config.DuplicateErrorsConfig{
+		Codes: map[int]string{1060: "warn"},
+		Environments: map[string]map[int]string{
+			"prod": {1050: "fail"},
+		},
+	}
+	queryInfo := &QueryInfo{Query: "CREATE TABLE users (id INT)", TableName: "users"}
+
+	tests := []struct {
+		name        string
+		environment string
+		err         error
+		wantErr     string
+		wantWarning string
+	}{
+		{
+			name:        "default warns for table exists",
+			environment: "stg",
+			err:         &mysql.MySQLError{Number: 1050, Message: "Table 'users' already exists"},
+			wantWarning: "policy for 1050: warn by default",
+		},
+		{
+			name:        "environment override fails",
+			environment: "prod",
+			err:         &mysql.MySQLError{Number: 1050, Message: "Table 'users' already exists"},
+			wantErr:     "policy for 1050: fail by duplicate_errors.environments.prod",
+		},
+		{
+			name:        "codes override warns for duplicate column",
+			environment: "prod",
+			err:         &mysql.MySQLError{Number: 1060, Message: "Duplicate column name 'age'"},
+			wantWarning: "policy for 1060: warn by duplicate_errors.codes",
+		},
+		{
+			name:        "other errors are returned as is",
+			environment: "prod",
+			err:         errors.New("boom"),
+			wantErr:     "boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSlack := &MockSlackNotifier{}
+			mockSlack.On("NotifyWarning", "small-query", "users", mock.Anything).Return(nil)
+
+			cfg := &config.Config{Environment: tt.environment, Common: config.CommonConfig{DuplicateErrors: policies}}
+			manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+			err := manager.handleDuplicateError("small-query", queryInfo, tt.err)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, tt.err)
+				mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			mockSlack.AssertCalled(t, "NotifyWarning", "small-query", "users", mock.MatchedBy(func(message string) bool {
+				return assert.Contains(t, message, tt.wantWarning)
+			}))
+		})
+	}
+}
+
+func TestHandleDuplicateError_DefaultFailsForDuplicateColumn(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+
+	err := manager.handleDuplicateError("alter-table", &QueryInfo{Query: "ALTER TABLE users ADD COLUMN age INT"}, &mysql.MySQLError{Number: 1060})
+	assert.ErrorContains(t, err, "policy for 1060: fail by default")
+}
+
+func TestDuplicateWarningAggregation(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var messages []string
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyWarning", "small-query", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		messages = append(messages, args.String(2))
+	}).Return(nil)
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+	manager.startDuplicateWarningAggregation()
+
+	for i := 0; i < 5; i++ {
+		err := manager.handleDuplicateError("small-query", &QueryInfo{Query: "CREATE INDEX idx ON users (name)", TableName: "users"},
+			&mysql.MySQLError{Number: 1061, Message: "Duplicate key name 'idx'"})
+		assert.NoError(t, err)
+	}
+	err := manager.handleDuplicateError("small-query", &QueryInfo{Query: "CREATE TABLE users (id INT)", TableName: "users"},
+		&mysql.MySQLError{Number: 1050, Message: "Table 'users' already exists"})
+	assert.NoError(t, err)
+	err = manager.handleDuplicateError("small-query", &QueryInfo{Query: "CREATE TABLE orders (id INT)", TableName: "orders"},
+		&mysql.MySQLError{Number: 1050, Message: "Table 'orders' already exists"})
+	assert.NoError(t, err)
+	mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+
+	manager.flushDuplicateWarnings()
+
+	mockSlack.AssertNumberOfCalls(t, "NotifyWarning", 2)
+	if assert.Len(t, messages, 2) {
+		// 1件だけのテーブルはそのままの警告を送る
+		assert.Contains(t, messages[0], "Duplicate detected in small-query")
+		assert.Contains(t, messages[0], "orders")
+		assert.Contains(t, messages[1], "6 duplicate warnings for users (1050: 1, 1061: 5)")
+		assert.Equal(t, 3, strings.Count(messages[1], "\n- "))
+		assert.Contains(t, messages[1], "... and 3 more")
+	}
+
+	// 集約を終えた後はすぐに通知する
+	err = manager.handleDuplicateError("small-query", &QueryInfo{Query: "CREATE TABLE users (id INT)", TableName: "users"},
+		&mysql.MySQLError{Number: 1050, Message: "Table 'users' already exists"})
+	assert.NoError(t, err)
+	mockSlack.AssertNumberOfCalls(t, "NotifyWarning", 3)
+}
@@ -0,0 +1,115 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/database"
+)
+
+// unsupportedPtOscEngines は pt-osc で正しくコピーできないストレージエンジンと、その理由
+var unsupportedPtOscEngines = map[string]string{
+	"MEMORY":    "rows are kept only in memory and the table is locked as a whole, so the copy is neither online nor crash safe",
+	"FEDERATED": "rows live on a remote server, so the copy and the triggers would act on the remote table",
+	"BLACKHOLE": "writes are discarded, so the new table would be empty",
+	"ARCHIVE":   "UPDATE and DELETE are not supported, so the pt-osc triggers fail",
+}
+
+// temporaryTableRe は TEMPORARY テーブルを作る・消す文
+var temporaryTableRe = regexp.MustCompile(`(?i)^\s*(CREATE|DROP)\s+TEMPORARY\s+TABLE\b`)
+
+// checkTemporaryTable は TEMPORARY テーブルの文を拒否する。
+// TEMPORARY テーブルは作ったセッションにしか見えず、alterguard は文ごとに別の接続を使いうるため扱えない
+func checkTemporaryTable(query string) error {
+	if temporaryTableRe.MatchString(query) {
+		return fmt.Errorf("temporary tables are not supported, because they are only visible to the session that created them: %s", strings.TrimSpace(query))
+	}
+	return nil
+}
+
+// getTableStorage はテーブルのストレージエンジンと行フォーマットを取得してキャッシュする。
+// 取得できない場合 (dry-run で CREATE TABLE がまだ実行されていない等) は警告のログだけ出して nil を返す
+func (m *Manager) getTableStorage(tableName string) *database.TableStorage {
+	if storage, ok := m.tableStorages[tableName]; ok {
+		return storage
+	}
+
+	storage, err := m.db.GetTableStorage(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get storage engine of %s, skipping the engine checks: %v", tableName, err)
+		return nil
+	}
+	m.tableStorages[tableName] = storage
+	return storage
+}
+
+// checkStorageEngine は pt-osc で変更するテーブルのストレージエンジンを確認し、pt-osc が正しく動かないエンジンなら中止する。
+// MyISAM は force_method で pt-osc を指定した場合だけ警告して続ける
+func (m *Manager) checkStorageEngine(tableName, method string, storage *database.TableStorage) error {
+	if method != "pt-osc" || storage == nil {
+		return nil
+	}
+	engine := strings.ToUpper(storage.Engine)
+
+	if reason, ok := unsupportedPtOscEngines[engine]; ok {
+		return fmt.Errorf("table %s uses the %s storage engine, which pt-online-schema-change does not support (%s); change it with a direct ALTER (--force-method=direct) or convert it to InnoDB first", tableName, engine, reason)
+	}
+
+	if engine != "MYISAM" {
+		return nil
+	}
+	reason := "MyISAM is not transactional, so writes during the copy can leave the new table inconsistent with the original"
+	if forced, _ := m.resolveForcedMethod(); forced != "pt-osc" {
+		return fmt.Errorf("table %s uses the MyISAM storage engine and pt-online-schema-change was chosen by row count (%s); run with --force-method=ptosc to use pt-osc anyway, --force-method=direct for a direct ALTER, or convert it to InnoDB first", tableName, reason)
+	}
+	message := fmt.Sprintf("Table %s uses the MyISAM storage engine, running pt-online-schema-change because of force_method: %s", tableName, reason)
+	m.logger.Warn(message)
+	if err := m.slack.NotifyWarning("storage-engine", tableName, message); err != nil {
+		m.logger.Errorf("Failed to send warning notification: %v", err)
+	}
+	return nil
+}
+
+// rowFormatRe は ALTER の中の ROW_FORMAT 指定
+var rowFormatRe = regexp.MustCompile(`(?i)\bROW_FORMAT\b`)
+
+// warnImplicitRowFormatChange は ROW_FORMAT を明示していない InnoDB テーブルの行フォーマットが
+// innodb_default_row_format と違う場合に、作り直すと行フォーマットが変わることを警告する
+func (m *Manager) warnImplicitRowFormatChange(tableName, method string, storage *database.TableStorage, alterParts []string) {
+	if storage == nil || !strings.EqualFold(storage.Engine, "InnoDB") || storage.ExplicitRowFormat {
+		return
+	}
+	if storage.RowFormat == "" || storage.DefaultRowFormat == "" || strings.EqualFold(storage.RowFormat, storage.DefaultRowFormat) {
+		return
+	}
+	for _, part := range alterParts {
+		if rowFormatRe.MatchString(part) {
+			return
+		}
+	}
+
+	rebuild := "if the ALTER rebuilds the table"
+	if method == "pt-osc" {
+		rebuild = "because pt-online-schema-change rebuilds the table"
+	}
+	message := fmt.Sprintf("Table %s has ROW_FORMAT=%s without an explicit ROW_FORMAT, so %s it will become ROW_FORMAT=%s (innodb_default_row_format). Add ROW_FORMAT=%s to the ALTER to keep it",
+		tableName, storage.RowFormat, rebuild, strings.ToUpper(storage.DefaultRowFormat), strings.ToUpper(storage.RowFormat))
+	m.logger.Warn(message)
+	if err := m.slack.NotifyWarning("row-format", tableName, message); err != nil {
+		m.logger.Errorf("Failed to send warning notification: %v", err)
+	}
+}
+
+// notifyAlterStart は ALTER の開始を通知する。ストレージエンジンが分かっていれば承認者が確認できるよう通知に含める
+func (m *Manager) notifyAlterStart(taskName, tableName, query string, rowCount int64) {
+	var err error
+	if storage := m.tableStorages[tableName]; storage != nil {
+		err = m.slack.NotifyStartWithQueryAndStorage(taskName, tableName, query, rowCount, storage.String())
+	} else {
+		err = m.slack.NotifyStartWithQuery(taskName, tableName, query, rowCount)
+	}
+	if err != nil {
+		m.logger.Errorf("Failed to send start notification: %v", err)
+	}
+}
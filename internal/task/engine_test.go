@@ -0,0 +1,144 @@
+package task
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newEngineTestManager(slack *MockSlackNotifier, forceMethod string) *Manager {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	cfg := &config.Config{Common: config.CommonConfig{ForceMethod: forceMethod}}
+	return NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, slack, logger, cfg, false)
+}
+
+func TestCheckStorageEngine(t *testing.T) {
+	tests := []struct {
+		engine  string
+		wantErr string
+	}{
+		{engine: "InnoDB"},
+		{engine: ""},
+		{engine: "MEMORY", wantErr: "table users uses the MEMORY storage engine"},
+		{engine: "FEDERATED", wantErr: "rows live on a remote server"},
+		{engine: "BLACKHOLE", wantErr: "the new table would be empty"},
+		{engine: "ARCHIVE", wantErr: "UPDATE and DELETE are not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			manager := newEngineTestManager(&MockSlackNotifier{}, "")
+
+			err := manager.checkStorageEngine("users", "pt-osc", &database.TableStorage{Engine: tt.engine})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.ErrorContains(t, err, "--force-method=direct")
+
+			assert.NoError(t, manager.checkStorageEngine("users", "alter-table", &database.TableStorage{Engine: tt.engine}))
+		})
+	}
+
+	t.Run("unknown storage", func(t *testing.T) {
+		manager := newEngineTestManager(&MockSlackNotifier{}, "")
+		assert.NoError(t, manager.checkStorageEngine("users", "pt-osc", nil))
+	})
+}
+
+func TestCheckStorageEngineMyISAM(t *testing.T) {
+	t.Run("chosen by row count", func(t *testing.T) {
+		manager := newEngineTestManager(&MockSlackNotifier{}, "")
+
+		err := manager.checkStorageEngine("users", "pt-osc", &database.TableStorage{Engine: "MyISAM"})
+		assert.ErrorContains(t, err, "table users uses the MyISAM storage engine")
+		assert.ErrorContains(t, err, "--force-method=ptosc")
+	})
+
+	t.Run("forced", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "storage-engine", "users", mock.MatchedBy(func(msg string) bool {
+			return strings.Contains(msg, "MyISAM")
+		})).Return(nil)
+		manager := newEngineTestManager(mockSlack, "ptosc")
+
+		assert.NoError(t, manager.checkStorageEngine("users", "pt-osc", &database.TableStorage{Engine: "MyISAM"}))
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("direct alter", func(t *testing.T) {
+		manager := newEngineTestManager(&MockSlackNotifier{}, "")
+		assert.NoError(t, manager.checkStorageEngine("users", "alter-table", &database.TableStorage{Engine: "MyISAM"}))
+	})
+}
+
+func TestWarnImplicitRowFormatChange(t *testing.T) {
+	compact := &database.TableStorage{Engine: "InnoDB", RowFormat: "Compact", DefaultRowFormat: "dynamic"}
+
+	tests := []struct {
+		name       string
+		storage    *database.TableStorage
+		alterParts []string
+		wantWarn   bool
+	}{
+		{name: "implicit row format differs", storage: compact, alterParts: []string{"ADD COLUMN c INT"}, wantWarn: true},
+		{name: "alter sets row format", storage: compact, alterParts: []string{"ADD COLUMN c INT", "ROW_FORMAT=COMPACT"}},
+		{name: "explicit row format", storage: &database.TableStorage{Engine: "InnoDB", RowFormat: "Compact", ExplicitRowFormat: true, DefaultRowFormat: "dynamic"}, alterParts: []string{"ADD COLUMN c INT"}},
+		{name: "same as default", storage: &database.TableStorage{Engine: "InnoDB", RowFormat: "Dynamic", DefaultRowFormat: "dynamic"}, alterParts: []string{"ADD COLUMN c INT"}},
+		{name: "not innodb", storage: &database.TableStorage{Engine: "MyISAM", RowFormat: "Fixed", DefaultRowFormat: "dynamic"}, alterParts: []string{"ADD COLUMN c INT"}},
+		{name: "unknown storage", alterParts: []string{"ADD COLUMN c INT"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSlack := &MockSlackNotifier{}
+			if tt.wantWarn {
+				mockSlack.On("NotifyWarning", "row-format", "users", mock.MatchedBy(func(msg string) bool {
+					return strings.Contains(msg, "ROW_FORMAT=DYNAMIC") && strings.Contains(msg, "Add ROW_FORMAT=COMPACT")
+				})).Return(nil)
+			}
+			manager := newEngineTestManager(mockSlack, "")
+
+			manager.warnImplicitRowFormatChange("users", "pt-osc", tt.storage, tt.alterParts)
+			mockSlack.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNotifyAlterStartIncludesStorage(t *testing.T) {
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQueryAndStorage", "pt-osc", "users", "ADD COLUMN c INT", int64(10), "InnoDB, ROW_FORMAT=Dynamic").Return(nil)
+	mockSlack.On("NotifyStartWithQuery", "pt-osc", "orders", "ADD COLUMN c INT", int64(10)).Return(nil)
+	manager := newEngineTestManager(mockSlack, "")
+	manager.tableStorages["users"] = &database.TableStorage{Engine: "InnoDB", RowFormat: "Dynamic"}
+
+	manager.notifyAlterStart("pt-osc", "users", "ADD COLUMN c INT", 10)
+	manager.notifyAlterStart("pt-osc", "orders", "ADD COLUMN c INT", 10)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestParseQueriesRejectsTemporaryTables(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+
+	for _, query := range []string{
+		"CREATE TEMPORARY TABLE tmp_users (id INT)",
+		"drop temporary table tmp_users",
+	} {
+		_, err := manager.parseQueries([]string{query})
+		assert.ErrorContains(t, err, "temporary tables are not supported", query)
+	}
+
+	queries, err := manager.parseQueries([]string{"CREATE TABLE temporary_users (id INT)"})
+	require.NoError(t, err)
+	assert.Equal(t, "temporary_users", queries[0].TableName)
+}
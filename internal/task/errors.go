@@ -0,0 +1,15 @@
+package task
+
+import "errors"
+
+// 失敗の種類。task が返すエラーはこれらを包んでいるため、呼び出し側は errors.Is で種類を判別できる
+var (
+	// swap 前の行数の確認で、元テーブルと _new テーブルの行数の差が閾値を超えた
+	ErrRowCountMismatch = errors.New("row count check failed")
+	// 前回の pt-osc が残した _new テーブルがあるため pt-osc を始められない
+	ErrNewTableExists = errors.New("previous pt-osc execution failed, new table already exists")
+	// connection_check で同じユーザーの他の接続が見つかった (または待っても終わらなかった)
+	ErrConnectionCheckFailed = errors.New("connection check failed")
+	// pt-online-schema-change が失敗した。dry run の失敗も含む
+	ErrPtOscFailed = errors.New("pt-online-schema-change failed")
+)
@@ -0,0 +1,57 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKinds(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	t.Run("pt-osc failure keeps the cause", func(t *testing.T) {
+		cause := errors.New("exit status 1")
+		manager, _ := newPtOscEventTestManager(cause)
+
+		err := manager.ExecuteAllTasks()
+
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrPtOscFailed)
+		assert.ErrorIs(t, err, cause)
+		assert.Contains(t, err.Error(), "pt-online-schema-change failed: exit status 1")
+	})
+
+	t.Run("new table exists", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("CheckNewTableExists", "users").Return(true, nil)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyPtOscPreCheckFailure", "pt-osc", "users").Return(nil)
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+		err := manager.checkNewTableExists("pt-osc", "users")
+
+		assert.ErrorIs(t, err, ErrNewTableExists)
+		assert.Contains(t, err.Error(), "_users_new")
+	})
+
+	t.Run("other active connections", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("HasOtherActiveConnections", mock.Anything).Return(true, "migrator", nil)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyConnectionCheckFailure", "pt-osc", "users", "migrator").Return(nil)
+		cfg := &config.Config{Common: config.CommonConfig{ConnectionCheck: config.ConnectionCheckConfig{Enabled: true}}}
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+		err := manager.checkOtherActiveConnections(context.Background(), "pt-osc", "users")
+
+		assert.ErrorIs(t, err, ErrConnectionCheckFailed)
+		assert.NotErrorIs(t, err, ErrPtOscFailed)
+	})
+}
@@ -0,0 +1,67 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/events"
+)
+
+// SetEventPublisher を設定すると、pt-osc と pt-archiver の開始と終了、swap の結果をイベントとして送る
+func (m *Manager) SetEventPublisher(publisher events.Publisher) {
+	m.events = publisher
+}
+
+// publishMigrationEvent は pt-osc のイベントを送る。終了時は duration と err を渡す。
+func (m *Manager) publishMigrationEvent(phase, tableName, alter string, rowCount int64, duration time.Duration, err error) {
+	event := m.newEvent(phase, "pt-osc", tableName, alter, rowCount, duration, err)
+	if phase == events.PhaseEnd {
+		if stats := m.ptOscCopyStats(); stats != nil {
+			event.RowsPerSecond = stats.RowsPerSecond()
+			event.LagWaits = stats.LagWaits
+			event.LoadPauses = stats.LoadPauses
+			event.Throttled = stats.Throttled()
+		}
+	}
+	m.publishEvent(event)
+}
+
+// publishOperationEvent は swap や pt-archiver のように pt-osc 以外の操作のイベントを送る。
+// statement には実行した SQL やコマンドを、rowCount には終了時なら処理した行数を渡す
+func (m *Manager) publishOperationEvent(phase, method, tableName, statement string, rowCount int64, duration time.Duration, err error) {
+	m.publishEvent(m.newEvent(phase, method, tableName, statement, rowCount, duration, err))
+}
+
+// newEvent はイベントを組み立てる。statement とエラーのメッセージは通知と同じく redaction.mode で値を伏せる
+func (m *Manager) newEvent(phase, method, tableName, statement string, rowCount int64, duration time.Duration, err error) events.Event {
+	event := events.Event{
+		Phase:       phase,
+		Environment: m.config.Environment,
+		Table:       tableName,
+		Method:      method,
+		Alter:       m.redactQuery(statement),
+		RowCount:    rowCount,
+		Timestamp:   time.Now(),
+	}
+	if phase == events.PhaseEnd {
+		event.DurationSeconds = duration.Seconds()
+		event.Success = err == nil
+		if err != nil {
+			event.Error = m.redactQuery(err.Error())
+		}
+	}
+	return event
+}
+
+// publishEvent はイベントを送る。送信の失敗で処理は止めない
+func (m *Manager) publishEvent(event events.Event) {
+	if m.events == nil || m.dryRun {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := m.events.Publish(ctx, event); err != nil {
+		m.logger.Warnf("Failed to publish %s event for %s: %v", event.Phase, event.Table, err)
+	}
+}
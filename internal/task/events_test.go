@@ -0,0 +1,176 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/events"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func newPtOscEventTestManager(ptOscErr error) (*Manager, *recordingPublisher) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableRowCounts", mock.Anything).Return(map[string]int64{}, nil).Maybe()
+	mockDB.On("GetTableStructure", mock.Anything).Return(&database.TableStructure{}, nil).Maybe()
+	mockDB.On("GetTableRowCount", "large_table").Return(int64(5000), nil)
+	mockDB.On("GetTableStorage", "large_table").Return(&database.TableStorage{Engine: "InnoDB"}, nil)
+	mockDB.On("CheckNewTableExists", "large_table").Return(false, nil)
+	mockDB.On("GetNewTableRowCount", "large_table").Return(int64(5000), nil).Maybe()
+
+	mockPtOsc := &MockPtOscExecutor{}
+	mockPtOsc.On("ExecuteAlter", "large_table", "ADD COLUMN new_col INT", config.PtOscConfig{}, "test-dsn", false).Return(ptOscErr)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyAllTasksStart", 1).Return(nil)
+	mockSlack.On("NotifyStartWithQueryAndStorage", "pt-osc", "large_table", mock.Anything, int64(5000), mock.Anything).Return(nil)
+	mockSlack.On("NotifyPtOscCompletionWithNewTableCount", "pt-osc", "large_table", int64(5000), int64(5000), mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSlack.On("NotifyFailureWithQueryAndLog", "pt-osc", "large_table", mock.Anything, int64(5000), ptOscErr, mock.Anything).Return(nil).Maybe()
+	mockSlack.On("NotifyAllTasksSuccess", 1, mock.Anything).Return(nil).Maybe()
+	mockSlack.On("NotifyAllTasksFailure", 1, mock.Anything).Return(nil).Maybe()
+
+	cfg := &config.Config{
+		Queries:     []string{"ALTER TABLE large_table ADD COLUMN new_col INT"},
+		Common:      config.CommonConfig{PtOscThreshold: 1000},
+		DSN:         "test-dsn",
+		Environment: "prod",
+	}
+
+	publisher := &recordingPublisher{}
+	manager := NewManager(mockDB, mockPtOsc, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	manager.SetEventPublisher(publisher)
+	return manager, publisher
+}
+
+func TestPtOscPublishesEvents(t *testing.T) {
+	manager, publisher := newPtOscEventTestManager(nil)
+
+	require.NoError(t, manager.ExecuteAllTasks())
+
+	require.Len(t, publisher.events, 2)
+	start, end := publisher.events[0], publisher.events[1]
+	assert.Equal(t, events.PhaseStart, start.Phase)
+	assert.Equal(t, "large_table", start.Table)
+	assert.Equal(t, "prod", start.Environment)
+	assert.Equal(t, "ADD COLUMN new_col INT", start.Alter)
+	assert.Equal(t, int64(5000), start.RowCount)
+	assert.Equal(t, events.PhaseEnd, end.Phase)
+	assert.True(t, end.Success)
+}
+
+func TestPtOscPublishesFailureEvent(t *testing.T) {
+	manager, publisher := newPtOscEventTestManager(errors.New("exit status 1"))
+
+	require.Error(t, manager.ExecuteAllTasks())
+
+	require.Len(t, publisher.events, 2)
+	end := publisher.events[1]
+	assert.Equal(t, events.PhaseEnd, end.Phase)
+	assert.False(t, end.Success)
+	assert.Equal(t, "exit status 1", end.Error)
+}
+
+func TestSwapTablePublishesEvent(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB, mockSlack := newAnalyzeSwapMocks()
+	mockDB.On("ExecuteAlter", "RENAME TABLE users TO users_old, _users_new TO users").Return(nil)
+
+	cfg := &config.Config{Environment: "prod", Common: config.CommonConfig{DisableAnalyzeTable: true}}
+	publisher := &recordingPublisher{}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	manager.SetEventPublisher(publisher)
+
+	require.NoError(t, manager.SwapTable("users"))
+
+	require.Len(t, publisher.events, 1)
+	event := publisher.events[0]
+	assert.Equal(t, events.PhaseEnd, event.Phase)
+	assert.Equal(t, "swap", event.Method)
+	assert.Equal(t, "users", event.Table)
+	assert.Equal(t, "RENAME TABLE users TO users_old, _users_new TO users", event.Alter)
+	assert.True(t, event.Success)
+}
+
+func TestPurgeOldTablePublishesEvents(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{DSN: "user:password@tcp(localhost:3306)/testdb"}
+	archiver := &progressPtArchiverExecutor{}
+	archiver.On("ExecutePurge", "users_old", config.PtArchiverConfig{}, cfg.DSN, false).Run(func(args mock.Arguments) {
+		archiver.deletedRows.Store(500)
+	}).Return(nil)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifyPurgeSuccess", "pt-archiver", "users_old", mock.Anything, int64(500), mock.Anything, mock.Anything).Return(nil)
+
+	publisher := &recordingPublisher{}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, archiver, mockSlack, logger, cfg, false)
+	manager.SetEventPublisher(publisher)
+
+	require.NoError(t, manager.PurgeOldTable("users_old"))
+
+	require.Len(t, publisher.events, 2)
+	start, end := publisher.events[0], publisher.events[1]
+	assert.Equal(t, events.PhaseStart, start.Phase)
+	assert.Equal(t, "pt-archiver", start.Method)
+	assert.Equal(t, events.PhaseEnd, end.Phase)
+	assert.Equal(t, int64(500), end.RowCount)
+	assert.True(t, end.Success)
+}
+
+func TestPurgeEventsAreRedacted(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{
+		DSN: "user:password@tcp(localhost:3306)/testdb",
+		Common: config.CommonConfig{
+			PtArchiver: config.PtArchiverConfig{Where: "email = 'alice@example.com'"},
+			Redaction:  config.RedactionConfig{Mode: "elide"},
+		},
+	}
+	purgeErr := errors.New("pt-archiver --where=email = 'alice@example.com' failed: exit status 1")
+	archiver := &MockPtArchiverExecutor{}
+	archiver.On("ExecutePurge", "users_old", cfg.Common.PtArchiver, cfg.DSN, false).Return(purgeErr)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifyFailureWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0), mock.Anything).Return(nil)
+
+	publisher := &recordingPublisher{}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, archiver, mockSlack, logger, cfg, false)
+	manager.SetEventPublisher(publisher)
+
+	require.Error(t, manager.PurgeOldTable("users_old"))
+
+	require.Len(t, publisher.events, 2)
+	for _, event := range publisher.events {
+		assert.NotContains(t, event.Alter, "alice@example.com")
+		assert.Contains(t, event.Alter, "--where")
+	}
+	end := publisher.events[1]
+	assert.False(t, end.Success)
+	assert.NotContains(t, end.Error, "alice@example.com")
+	assert.Contains(t, end.Error, "failed")
+}
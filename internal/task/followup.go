@@ -0,0 +1,254 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/followup"
+)
+
+const defaultCommandPrefix = "alterguard"
+
+// SetCommandPrefix は後続作業として案内するコマンドの先頭部分(設定ファイル指定など)を設定する
+func (m *Manager) SetCommandPrefix(prefix string) {
+	m.commandPrefix = prefix
+}
+
+// SetFollowUpPath を設定すると、run の最後に後続作業の手順をYAMLとして書き出す
+func (m *Manager) SetFollowUpPath(path string) {
+	m.followUpPath = path
+}
+
+// swapWindow は自動swapを行ってよい時間帯。start > end の場合は日付をまたぐ。
+type swapWindow struct {
+	startHour, startMinute int
+	endHour, endMinute     int
+	location               *time.Location
+}
+
+// resolveSwapWindow は auto_swap の時間帯を解釈する。時間帯の指定がなければ nil を返す。
+func resolveSwapWindow(cfg config.AutoSwapConfig) (*swapWindow, error) {
+	if cfg.WindowStart == "" && cfg.WindowEnd == "" {
+		return nil, nil
+	}
+	if cfg.WindowStart == "" || cfg.WindowEnd == "" {
+		return nil, fmt.Errorf("auto_swap.window_start and auto_swap.window_end must be set together")
+	}
+
+	start, err := time.Parse("15:04", cfg.WindowStart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auto_swap.window_start %q: %w", cfg.WindowStart, err)
+	}
+	end, err := time.Parse("15:04", cfg.WindowEnd)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auto_swap.window_end %q: %w", cfg.WindowEnd, err)
+	}
+	if start.Equal(end) {
+		return nil, fmt.Errorf("auto_swap.window_start and auto_swap.window_end must differ")
+	}
+
+	location := time.Local
+	if cfg.Timezone != "" {
+		location, err = time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auto_swap.timezone %q: %w", cfg.Timezone, err)
+		}
+	}
+
+	return &swapWindow{
+		startHour:   start.Hour(),
+		startMinute: start.Minute(),
+		endHour:     end.Hour(),
+		endMinute:   end.Minute(),
+		location:    location,
+	}, nil
+}
+
+// nextStart は now が時間帯内であれば now を、そうでなければ次に時間帯が始まる時刻を返す
+func (w *swapWindow) nextStart(now time.Time) time.Time {
+	local := now.In(w.location)
+	year, month, day := local.Date()
+	start := time.Date(year, month, day, w.startHour, w.startMinute, 0, 0, w.location)
+	end := time.Date(year, month, day, w.endHour, w.endMinute, 0, 0, w.location)
+
+	if start.Before(end) {
+		if !local.Before(start) && local.Before(end) {
+			return now
+		}
+		if local.Before(start) {
+			return start
+		}
+		return start.AddDate(0, 0, 1)
+	}
+
+	// 日付をまたぐ時間帯(例: 22:00-02:00)
+	if !local.Before(start) || local.Before(end) {
+		return now
+	}
+	return start
+}
+
+func (m *Manager) waitForSwapWindow(ctx context.Context, window *swapWindow, tableName string) error {
+	if window == nil {
+		return nil
+	}
+
+	now := time.Now()
+	next := window.nextStart(now)
+	if !next.After(now) {
+		return nil
+	}
+
+	m.logger.Infof("Waiting until %s to swap %s", next.Format(time.RFC3339), tableName)
+	timer := time.NewTimer(next.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("stopped waiting for swap window of %s: %w", tableName, ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// runAutoSwaps は no_swap_tables で pt-osc を実行したテーブルを、設定された時間帯にswapする。
+// swapしたテーブル名を返す。
+func (m *Manager) runAutoSwaps(ctx context.Context, groups []*TableGroup) (map[string]bool, error) {
+	swapped := make(map[string]bool)
+	ptOscConfig := m.config.Common.PtOsc
+	if !ptOscConfig.NoSwapTables || !ptOscConfig.AutoSwap.Enabled {
+		return swapped, nil
+	}
+	if m.dryRun || ptOscConfig.DryRun {
+		m.logger.Info("[DRY RUN] Skipping automatic swap")
+		return swapped, nil
+	}
+
+	window, err := resolveSwapWindow(ptOscConfig.AutoSwap)
+	if err != nil {
+		return swapped, err
+	}
+
+	for _, group := range groups {
+		if group.Method != "pt-osc" {
+			continue
+		}
+		if err := m.waitForSwapWindow(ctx, window, group.TableName); err != nil {
+			return swapped, err
+		}
+		if err := m.SwapTableContext(ctx, group.TableName); err != nil {
+			return swapped, fmt.Errorf("automatic swap failed for %s: %w", group.TableName, err)
+		}
+		swapped[group.TableName] = true
+	}
+	return swapped, nil
+}
+
+// followUpSteps は pt-osc が残したテーブルやトリガーを片付けるための手順を、実行順に返す
+func (m *Manager) followUpSteps(groups []*TableGroup, swapped map[string]bool) []followup.Step {
+	ptOscConfig := m.config.Common.PtOsc
+
+	var steps []followup.Step
+	for _, group := range groups {
+		if group.Method != "pt-osc" {
+			continue
+		}
+		if ptOscConfig.NoSwapTables && !swapped[group.TableName] {
+			steps = append(steps, followup.Step{Action: followup.ActionSwap, Table: group.TableName})
+		}
+
+		cleanup := followup.Step{
+			Action:       followup.ActionCleanup,
+			Table:        group.TableName,
+			DropTriggers: ptOscConfig.NoDropTriggers,
+			DropTable:    ptOscConfig.NoSwapTables || ptOscConfig.NoDropOldTable,
+		}
+		if cleanup.DropTriggers || cleanup.DropTable {
+			steps = append(steps, cleanup)
+		}
+	}
+	return steps
+}
+
+func (m *Manager) followUpCommands(steps []followup.Step) []string {
+	prefix := m.commandPrefix
+	if prefix == "" {
+		prefix = defaultCommandPrefix
+	}
+
+	commands := make([]string, 0, len(steps))
+	for _, step := range steps {
+		commands = append(commands, step.Command(prefix))
+	}
+	return commands
+}
+
+func (m *Manager) reportFollowUpCommands(groups []*TableGroup, swapped map[string]bool) {
+	steps := m.followUpSteps(groups, swapped)
+	if len(steps) == 0 {
+		return
+	}
+
+	if m.followUpPath != "" {
+		file := &followup.File{
+			GeneratedAt: time.Now(),
+			Environment: m.config.Environment,
+			Steps:       steps,
+		}
+		if err := followup.Write(m.followUpPath, file); err != nil {
+			m.logger.Errorf("Failed to write follow-up tasks file: %v", err)
+		} else {
+			m.logger.Infof("Follow-up tasks written to %s", m.followUpPath)
+		}
+	}
+
+	commands := m.followUpCommands(steps)
+	m.logger.Infof("Follow-up commands:\n%s", strings.Join(commands, "\n"))
+	if err := m.slack.NotifyFollowUpCommands(commands); err != nil {
+		m.logger.Errorf("Failed to send follow-up commands notification: %v", err)
+	}
+}
+
+// ExecuteFollowUp は run が生成した follow-up ファイルの手順を順番に実行する。
+// 1つでも失敗したらそこで止める。
+func (m *Manager) ExecuteFollowUp(file *followup.File) error {
+	for i, step := range file.Steps {
+		m.logger.Infof("Follow-up step %d/%d: %s %s", i+1, len(file.Steps), step.Action, step.Table)
+
+		var err error
+		switch step.Action {
+		case followup.ActionSwap:
+			err = m.SwapTable(step.Table)
+		case followup.ActionCleanup:
+			err = m.executeCleanupStep(step)
+		default:
+			err = fmt.Errorf("unknown action %q", step.Action)
+		}
+		if err != nil {
+			return fmt.Errorf("follow-up step %d (%s %s) failed: %w", i+1, step.Action, step.Table, err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) executeCleanupStep(step followup.Step) error {
+	if step.DropTriggers {
+		if err := m.CleanupTriggers(step.Table); err != nil {
+			return err
+		}
+	}
+	if step.DropTable {
+		if err := m.CleanupOldTable(step.Table); err != nil {
+			return err
+		}
+	}
+	if step.DropNewTable {
+		if err := m.CleanupNewTable(step.Table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/followup"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSwapWindow(t *testing.T) {
+	window, err := resolveSwapWindow(config.AutoSwapConfig{Enabled: true})
+	require.NoError(t, err)
+	assert.Nil(t, window)
+
+	_, err = resolveSwapWindow(config.AutoSwapConfig{WindowStart: "02:00"})
+	assert.Error(t, err)
+
+	_, err = resolveSwapWindow(config.AutoSwapConfig{WindowStart: "25:00", WindowEnd: "03:00"})
+	assert.Error(t, err)
+
+	_, err = resolveSwapWindow(config.AutoSwapConfig{WindowStart: "02:00", WindowEnd: "04:00", Timezone: "Nowhere/Invalid"})
+	assert.Error(t, err)
+
+	window, err = resolveSwapWindow(config.AutoSwapConfig{WindowStart: "02:00", WindowEnd: "04:30", Timezone: "Asia/Tokyo"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, window.startHour)
+	assert.Equal(t, 30, window.endMinute)
+	assert.Equal(t, "Asia/Tokyo", window.location.String())
+}
+
+func TestSwapWindowNextStart(t *testing.T) {
+	day := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 10, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		start    string
+		end      string
+		now      time.Time
+		expected time.Time
+	}{
+		{name: "before window", start: "02:00", end: "04:00", now: day(1, 0), expected: day(2, 0)},
+		{name: "inside window", start: "02:00", end: "04:00", now: day(3, 0), expected: day(3, 0)},
+		{name: "after window", start: "02:00", end: "04:00", now: day(5, 0), expected: day(26, 0)},
+		{name: "end is exclusive", start: "02:00", end: "04:00", now: day(4, 0), expected: day(26, 0)},
+		{name: "overnight before midnight", start: "22:00", end: "02:00", now: day(23, 0), expected: day(23, 0)},
+		{name: "overnight after midnight", start: "22:00", end: "02:00", now: day(1, 0), expected: day(1, 0)},
+		{name: "overnight outside", start: "22:00", end: "02:00", now: day(12, 0), expected: day(22, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := resolveSwapWindow(config.AutoSwapConfig{WindowStart: tt.start, WindowEnd: tt.end, Timezone: "UTC"})
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(window.nextStart(tt.now)), "expected %s, got %s", tt.expected, window.nextStart(tt.now))
+		})
+	}
+}
+
+func TestWaitForSwapWindowStopsOnContextCancel(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	now := time.Now().UTC()
+	start := now.Add(2 * time.Hour)
+	window := &swapWindow{
+		startHour:   start.Hour(),
+		startMinute: start.Minute(),
+		endHour:     start.Add(time.Hour).Hour(),
+		endMinute:   start.Minute(),
+		location:    time.UTC,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	err := manager.waitForSwapWindow(ctx, window, "users")
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFollowUpCommands(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	groups := []*TableGroup{
+		{TableName: "users", Method: "pt-osc"},
+		{TableName: "orders", Method: "pt-osc"},
+		{TableName: "tags", Method: "alter-table"},
+	}
+
+	t.Run("no swap tables", func(t *testing.T) {
+		cfg := &config.Config{Common: config.CommonConfig{PtOsc: config.PtOscConfig{NoSwapTables: true, NoDropTriggers: true}}}
+		manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+		manager.SetCommandPrefix("alterguard --common-config config.yaml")
+
+		commands := manager.followUpCommands(manager.followUpSteps(groups, map[string]bool{"orders": true}))
+
+		assert.Equal(t, []string{
+			"alterguard --common-config config.yaml swap users",
+			"alterguard --common-config config.yaml cleanup users --drop-triggers --drop-table",
+			"alterguard --common-config config.yaml cleanup orders --drop-triggers --drop-table",
+		}, commands)
+	})
+
+	t.Run("swapped by pt-osc", func(t *testing.T) {
+		cfg := &config.Config{}
+		manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+		assert.Empty(t, manager.followUpSteps(groups, nil))
+	})
+
+	t.Run("old table kept", func(t *testing.T) {
+		cfg := &config.Config{Common: config.CommonConfig{PtOsc: config.PtOscConfig{NoDropOldTable: true}}}
+		manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+		assert.Equal(t, []string{
+			"alterguard cleanup users --drop-table",
+			"alterguard cleanup orders --drop-table",
+		}, manager.followUpCommands(manager.followUpSteps(groups, nil)))
+	})
+}
+
+func TestExecuteFollowUpStopsAtFirstFailure(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "users").Return(false, nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	err := manager.ExecuteFollowUp(&followup.File{Steps: []followup.Step{
+		{Action: followup.ActionSwap, Table: "users"},
+		{Action: followup.ActionCleanup, Table: "users", DropTable: true},
+	}})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "follow-up step 1 (swap users) failed")
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNumberOfCalls(t, "TableExists", 1)
+}
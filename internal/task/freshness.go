@@ -0,0 +1,98 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pt-osc が元テーブルに作成するトリガーの種類
+var ptOscTriggerSuffixes = []string{"_ins", "_upd", "_del"}
+
+// checkShadowTableFreshness は no_swap_tables で残した _new テーブルが今も元テーブルに追従しているかを確認する。
+// トリガーが消えていたり、AUTO_INCREMENT 列やタイムスタンプ列の最大値が元テーブルより古ければ swap を中止する。
+func (m *Manager) checkShadowTableFreshness(tableName string) error {
+	if !m.config.Common.PtOsc.NoSwapTables || m.config.Common.SwapFreshness.Disabled {
+		return nil
+	}
+
+	newTableName := fmt.Sprintf("_%s_new", tableName)
+	var problems []string
+
+	triggers, err := m.db.GetTriggerNames(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to check pt-osc triggers: %w", err)
+	}
+	if missing := missingPtOscTriggers(triggers); len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("pt-osc triggers missing: %s (found: %v)", strings.Join(missing, ", "), triggers))
+	}
+
+	column, err := m.db.GetAutoIncrementColumn(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to get auto increment column: %w", err)
+	}
+	if column != "" {
+		// 元テーブルを先に読むことで、並行する INSERT があっても _new 側が小さくなることはない
+		originalMax, err := m.db.GetMaxIntValue(tableName, column)
+		if err != nil {
+			return err
+		}
+		newMax, err := m.db.GetMaxIntValue(newTableName, column)
+		if err != nil {
+			return err
+		}
+		m.logger.Infof("Freshness check for %s: max(%s) original=%d, new=%d", tableName, column, originalMax, newMax)
+		if newMax < originalMax {
+			problems = append(problems, fmt.Sprintf("max(%s) of %s is %d but %s is %d", column, newTableName, newMax, tableName, originalMax))
+		}
+	}
+
+	if timestampColumn := m.config.Common.SwapFreshness.TimestampColumn; timestampColumn != "" {
+		originalLatest, err := m.db.GetMaxUnixTime(tableName, timestampColumn)
+		if err != nil {
+			return err
+		}
+		newLatest, err := m.db.GetMaxUnixTime(newTableName, timestampColumn)
+		if err != nil {
+			return err
+		}
+		m.logger.Infof("Freshness check for %s: max(%s) original=%d, new=%d", tableName, timestampColumn, originalLatest, newLatest)
+		if newLatest < originalLatest {
+			problems = append(problems, fmt.Sprintf("max(%s) of %s is %d seconds behind %s", timestampColumn, newTableName, originalLatest-newLatest, tableName))
+		}
+	}
+
+	if len(problems) == 0 {
+		m.logger.Infof("Freshness check passed for %s", newTableName)
+		return nil
+	}
+
+	errMsg := fmt.Sprintf("%s looks stale: %s", newTableName, strings.Join(problems, "; "))
+	m.logger.Errorf("Freshness check failed for table %s: %s", tableName, errMsg)
+
+	taskName := "swap-freshness-check"
+	if m.dryRun {
+		taskName = "swap-freshness-check (DRY RUN)"
+	}
+	if slackErr := m.slack.NotifyWarning(taskName, tableName, errMsg); slackErr != nil {
+		m.logger.Errorf("Failed to send freshness check warning notification: %v", slackErr)
+	}
+
+	return fmt.Errorf("freshness check failed: %s", errMsg)
+}
+
+func missingPtOscTriggers(triggers []string) []string {
+	var missing []string
+	for _, suffix := range ptOscTriggerSuffixes {
+		found := false
+		for _, trigger := range triggers {
+			if strings.HasPrefix(trigger, ptOscTriggerPrefix) && strings.HasSuffix(trigger, suffix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, ptOscTriggerPrefix+"*"+suffix)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,101 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckShadowTableFreshness(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	allTriggers := []string{"pt_osc_app_users_del", "pt_osc_app_users_ins", "pt_osc_app_users_upd"}
+
+	tests := []struct {
+		name        string
+		freshness   config.SwapFreshnessConfig
+		noSwap      bool
+		initMock    func(*MockDBClient)
+		expectError string
+	}{
+		{
+			name:     "skipped without no_swap_tables",
+			noSwap:   false,
+			initMock: func(d *MockDBClient) {},
+		},
+		{
+			name:      "skipped when disabled",
+			noSwap:    true,
+			freshness: config.SwapFreshnessConfig{Disabled: true},
+			initMock:  func(d *MockDBClient) {},
+		},
+		{
+			name:      "fresh",
+			noSwap:    true,
+			freshness: config.SwapFreshnessConfig{TimestampColumn: "updated_at"},
+			initMock: func(d *MockDBClient) {
+				d.On("GetTriggerNames", "users").Return(allTriggers, nil)
+				d.On("GetAutoIncrementColumn", "users").Return("id", nil)
+				d.On("GetMaxIntValue", "users", "id").Return(int64(1050), nil)
+				d.On("GetMaxIntValue", "_users_new", "id").Return(int64(1051), nil)
+				d.On("GetMaxUnixTime", "users", "updated_at").Return(int64(1700000000), nil)
+				d.On("GetMaxUnixTime", "_users_new", "updated_at").Return(int64(1700000000), nil)
+			},
+		},
+		{
+			name:   "triggers dropped and new table behind",
+			noSwap: true,
+			initMock: func(d *MockDBClient) {
+				d.On("GetTriggerNames", "users").Return([]string{"pt_osc_app_users_ins"}, nil)
+				d.On("GetAutoIncrementColumn", "users").Return("id", nil)
+				d.On("GetMaxIntValue", "users", "id").Return(int64(1050), nil)
+				d.On("GetMaxIntValue", "_users_new", "id").Return(int64(1000), nil)
+			},
+			expectError: "pt-osc triggers missing: pt_osc_*_upd, pt_osc_*_del",
+		},
+		{
+			name:      "timestamp behind",
+			noSwap:    true,
+			freshness: config.SwapFreshnessConfig{TimestampColumn: "updated_at"},
+			initMock: func(d *MockDBClient) {
+				d.On("GetTriggerNames", "users").Return(allTriggers, nil)
+				d.On("GetAutoIncrementColumn", "users").Return("", nil)
+				d.On("GetMaxUnixTime", "users", "updated_at").Return(int64(1700000600), nil)
+				d.On("GetMaxUnixTime", "_users_new", "updated_at").Return(int64(1700000000), nil)
+			},
+			expectError: "max(updated_at) of _users_new is 600 seconds behind users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockDBClient{}
+			tt.initMock(mockDB)
+			mockSlack := &MockSlackNotifier{}
+			mockSlack.On("NotifyWarning", "swap-freshness-check", "users", mock.Anything).Return(nil)
+
+			cfg := &config.Config{Common: config.CommonConfig{
+				PtOsc:         config.PtOscConfig{NoSwapTables: tt.noSwap},
+				SwapFreshness: tt.freshness,
+			}}
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+			err := manager.checkShadowTableFreshness("users")
+
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				mockSlack.AssertCalled(t, "NotifyWarning", "swap-freshness-check", "users", mock.Anything)
+			} else {
+				require.NoError(t, err)
+				mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
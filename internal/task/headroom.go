@@ -0,0 +1,34 @@
+package task
+
+import "fmt"
+
+// checkConnectionHeadroom は max_connections までの空きが min_connection_headroom_percent 未満なら開始を拒否する。
+// pt-osc のコピーやトリガーは接続と負荷を増やすため、途中で "Too many connections" になるのを避ける。
+func (m *Manager) checkConnectionHeadroom(taskName, tableName string) error {
+	minPercent := m.config.Common.MinConnectionHeadroomPercent
+	if minPercent <= 0 {
+		return nil
+	}
+
+	connected, maxConnections, err := m.db.GetConnectionUsage()
+	if err != nil {
+		return fmt.Errorf("failed to check connection headroom: %w", err)
+	}
+	if maxConnections <= 0 {
+		return nil
+	}
+
+	headroom := float64(maxConnections-connected) / float64(maxConnections) * 100
+	m.logger.Infof("Connection headroom: %d of %d connections in use (%.1f%% free)", connected, maxConnections, headroom)
+	if headroom >= minPercent {
+		return nil
+	}
+
+	errMsg := fmt.Sprintf("connection headroom too low: %d of %d connections in use (%.1f%% free, need %.1f%%)",
+		connected, maxConnections, headroom, minPercent)
+	m.logger.Warn(errMsg)
+	if slackErr := m.slack.NotifyWarning(taskName, tableName, errMsg); slackErr != nil {
+		m.logger.Errorf("Failed to send connection headroom warning notification: %v", slackErr)
+	}
+	return fmt.Errorf("%s", errMsg)
+}
@@ -0,0 +1,53 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckConnectionHeadroom(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	newManager := func(minPercent float64, mockDB *MockDBClient, mockSlack *MockSlackNotifier) *Manager {
+		cfg := &config.Config{Common: config.CommonConfig{MinConnectionHeadroomPercent: minPercent}}
+		return NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		assert.NoError(t, newManager(0, mockDB, &MockSlackNotifier{}).checkConnectionHeadroom("pt-osc", "users"))
+		mockDB.AssertNotCalled(t, "GetConnectionUsage")
+	})
+
+	t.Run("enough headroom", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetConnectionUsage").Return(int64(500), int64(1000), nil)
+		assert.NoError(t, newManager(20, mockDB, &MockSlackNotifier{}).checkConnectionHeadroom("pt-osc", "users"))
+	})
+
+	t.Run("too little headroom", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetConnectionUsage").Return(int64(900), int64(1000), nil)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "pt-osc", "users", mock.MatchedBy(func(msg string) bool {
+			return msg == "connection headroom too low: 900 of 1000 connections in use (10.0% free, need 20.0%)"
+		})).Return(nil)
+
+		err := newManager(20, mockDB, mockSlack).checkConnectionHeadroom("pt-osc", "users")
+		assert.ErrorContains(t, err, "connection headroom too low")
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("query failure", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetConnectionUsage").Return(int64(0), int64(0), errors.New("denied"))
+		err := newManager(20, mockDB, &MockSlackNotifier{}).checkConnectionHeadroom("pt-osc", "users")
+		assert.ErrorContains(t, err, "failed to check connection headroom")
+	})
+}
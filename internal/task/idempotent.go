@@ -0,0 +1,164 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type alterClauseKind int
+
+const (
+	alterClauseOther alterClauseKind = iota
+	alterClauseAddColumn
+	alterClauseDropColumn
+	alterClauseAddIndex
+	alterClauseDropIndex
+)
+
+type alterClause struct {
+	text string
+	kind alterClauseKind
+	name string
+}
+
+const alterIdentifier = "`?([^`\\s(),]+)`?"
+
+var (
+	addIndexClauseRe   = regexp.MustCompile(`(?is)^ADD\s+(?:UNIQUE\s+(?:INDEX\s+|KEY\s+)?|(?:FULLTEXT|SPATIAL)\s+(?:INDEX\s+|KEY\s+)?|INDEX\s+|KEY\s+)(?:IF\s+NOT\s+EXISTS\s+)?` + alterIdentifier)
+	addColumnClauseRe  = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?` + alterIdentifier + `\s`)
+	dropIndexClauseRe  = regexp.MustCompile(`(?is)^DROP\s+(?:INDEX|KEY)\s+(?:IF\s+EXISTS\s+)?` + alterIdentifier + `$`)
+	dropColumnClauseRe = regexp.MustCompile(`(?is)^DROP\s+(?:COLUMN\s+)?(?:IF\s+EXISTS\s+)?` + alterIdentifier + `$`)
+
+	// カラム名の位置に来るが、カラムではなく別の句を表すキーワード
+	alterClauseKeywords = map[string]bool{
+		"COLUMN": true, "INDEX": true, "KEY": true, "UNIQUE": true, "FULLTEXT": true, "SPATIAL": true,
+		"PRIMARY": true, "CONSTRAINT": true, "FOREIGN": true, "PARTITION": true, "CHECK": true,
+	}
+)
+
+// classifyAlterClause は適用済みかどうかを information_schema で判定できる句を見分ける。
+// 名前のないインデックスや複数カラムをまとめて追加する句などは判定できないため alterClauseOther になる。
+func classifyAlterClause(text string) alterClause {
+	clause := alterClause{text: text, kind: alterClauseOther}
+	if matches := addIndexClauseRe.FindStringSubmatch(text); matches != nil {
+		if !alterClauseKeywords[strings.ToUpper(matches[1])] {
+			clause.kind, clause.name = alterClauseAddIndex, matches[1]
+		}
+		return clause
+	}
+	if matches := addColumnClauseRe.FindStringSubmatch(text); matches != nil {
+		if !alterClauseKeywords[strings.ToUpper(matches[1])] {
+			clause.kind, clause.name = alterClauseAddColumn, matches[1]
+		}
+		return clause
+	}
+	if matches := dropIndexClauseRe.FindStringSubmatch(text); matches != nil {
+		clause.kind, clause.name = alterClauseDropIndex, matches[1]
+		return clause
+	}
+	if matches := dropColumnClauseRe.FindStringSubmatch(text); matches != nil {
+		if !alterClauseKeywords[strings.ToUpper(matches[1])] {
+			clause.kind, clause.name = alterClauseDropColumn, matches[1]
+		}
+	}
+	return clause
+}
+
+// skipAppliedAlterParts は既に適用済みの ADD COLUMN / ADD INDEX / DROP COLUMN / DROP INDEX を
+// information_schema で確認して取り除いた ALTER 句を返す。途中まで適用されたタスクファイルを
+// 再実行しても、重複エラーに頼らず安全に続きから実行できるようにするためのもの。
+// テーブル構造が取得できない場合はそのまま実行し、従来どおりエラー時に判定する。
+// CHANGE や RENAME COLUMN など判定できない句はカラムやインデックスの名前を変えうるため、
+// その句以降は実行後の構造を追えなくなったものとして、何も取り除かない。
+func (m *Manager) skipAppliedAlterParts(tableName string, alterParts []string) []string {
+	specs := make([]alterSpec, len(alterParts))
+	parsed := make([][]alterClause, len(alterParts))
+	checkable := false
+	for i, part := range alterParts {
+		specs[i] = parseAlterSpec(part)
+		for _, text := range specs[i].Clauses {
+			clause := classifyAlterClause(text)
+			if clause.kind != alterClauseOther {
+				checkable = true
+			}
+			parsed[i] = append(parsed[i], clause)
+		}
+	}
+	if !checkable {
+		return alterParts
+	}
+
+	structure, err := m.db.GetTableStructure(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get structure of %s, executing all ALTER clauses: %v", tableName, err)
+		return alterParts
+	}
+	columns := make(map[string]bool, len(structure.Columns))
+	for _, column := range structure.Columns {
+		columns[strings.ToLower(column)] = true
+	}
+	indexes := make(map[string]bool, len(structure.Indexes))
+	for _, index := range structure.Indexes {
+		indexes[strings.ToLower(index)] = true
+	}
+
+	var result []string
+	var skipped []string
+	untracked := false
+	for i, clauses := range parsed {
+		var kept []string
+		for _, clause := range clauses {
+			if clause.kind == alterClauseOther {
+				untracked = true
+			}
+			if untracked {
+				kept = append(kept, clause.text)
+				continue
+			}
+			name := strings.ToLower(clause.name)
+			applied := false
+			// 同じタスクファイル内で後続の句が前の句を前提にできるよう、実行予定の変更も反映していく
+			switch clause.kind {
+			case alterClauseAddColumn:
+				applied = columns[name]
+				columns[name] = true
+			case alterClauseDropColumn:
+				applied = !columns[name]
+				delete(columns, name)
+			case alterClauseAddIndex:
+				applied = indexes[name]
+				indexes[name] = true
+			case alterClauseDropIndex:
+				applied = !indexes[name]
+				delete(indexes, name)
+			}
+			if applied {
+				skipped = append(skipped, clause.text)
+				continue
+			}
+			kept = append(kept, clause.text)
+		}
+		if len(kept) == len(clauses) {
+			result = append(result, alterParts[i])
+		} else if rebuilt := (alterSpec{Clauses: kept, Partition: specs[i].Partition}).String(); rebuilt != "" {
+			result = append(result, rebuilt)
+		}
+	}
+
+	if len(skipped) == 0 {
+		return alterParts
+	}
+
+	message := fmt.Sprintf("Skipping ALTER clauses already applied to %s: %s", tableName, strings.Join(skipped, "; "))
+	m.logger.Warn(message)
+
+	taskName := "idempotency-check"
+	if m.dryRun {
+		taskName = "idempotency-check (DRY RUN)"
+	}
+	if err := m.slack.NotifyWarning(taskName, tableName, message); err != nil {
+		m.logger.Errorf("Failed to send warning notification: %v", err)
+	}
+	return result
+}
@@ -0,0 +1,154 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAlterClauses(t *testing.T) {
+	assert.Equal(t, []string{
+		"ADD COLUMN price DECIMAL(10,2)",
+		"ADD COLUMN kind ENUM('a,b', 'c')",
+		"ADD INDEX idx_price (price, kind)",
+	}, splitAlterClauses("ADD COLUMN price DECIMAL(10,2), ADD COLUMN kind ENUM('a,b', 'c'),  ADD INDEX idx_price (price, kind)"))
+}
+
+func TestClassifyAlterClause(t *testing.T) {
+	tests := []struct {
+		clause string
+		kind   alterClauseKind
+		name   string
+	}{
+		{"ADD COLUMN age INT", alterClauseAddColumn, "age"},
+		{"add `age` int", alterClauseAddColumn, "age"},
+		{"ADD COLUMN IF NOT EXISTS age INT", alterClauseAddColumn, "age"},
+		{"ADD INDEX idx_age (age)", alterClauseAddIndex, "idx_age"},
+		{"ADD UNIQUE KEY `uniq_email` (email)", alterClauseAddIndex, "uniq_email"},
+		{"ADD FULLTEXT INDEX ft_body (body)", alterClauseAddIndex, "ft_body"},
+		{"DROP COLUMN age", alterClauseDropColumn, "age"},
+		{"DROP `age`", alterClauseDropColumn, "age"},
+		{"DROP INDEX idx_age", alterClauseDropIndex, "idx_age"},
+		{"ADD INDEX (age)", alterClauseOther, ""},
+		{"ADD UNIQUE KEY (email)", alterClauseOther, ""},
+		{"ADD COLUMN (a INT, b INT)", alterClauseOther, ""},
+		{"ADD PRIMARY KEY (id)", alterClauseOther, ""},
+		{"ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id)", alterClauseOther, ""},
+		{"DROP PRIMARY KEY", alterClauseOther, ""},
+		{"DROP FOREIGN KEY fk_user", alterClauseOther, ""},
+		{"MODIFY COLUMN age BIGINT", alterClauseOther, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.clause, func(t *testing.T) {
+			clause := classifyAlterClause(tt.clause)
+			assert.Equal(t, tt.kind, clause.kind)
+			assert.Equal(t, tt.name, clause.name)
+		})
+	}
+}
+
+func TestSkipAppliedAlterParts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	t.Run("removes applied clauses and notifies", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetTableStructure", "users").Return(&database.TableStructure{
+			Columns: []string{"id", "Age", "legacy"},
+			Indexes: []string{"PRIMARY", "idx_age"},
+		}, nil)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "idempotency-check", "users", mock.Anything).Return(nil)
+
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+		result := manager.skipAppliedAlterParts("users", []string{
+			"ADD COLUMN age INT",
+			"ADD COLUMN email VARCHAR(255), ADD INDEX idx_age (age)",
+			"DROP COLUMN nickname",
+			"DROP COLUMN legacy",
+			"MODIFY COLUMN id BIGINT",
+		})
+
+		assert.Equal(t, []string{"ADD COLUMN email VARCHAR(255)", "DROP COLUMN legacy", "MODIFY COLUMN id BIGINT"}, result)
+		mockSlack.AssertCalled(t, "NotifyWarning", "idempotency-check", "users",
+			"Skipping ALTER clauses already applied to users: ADD COLUMN age INT; ADD INDEX idx_age (age); DROP COLUMN nickname")
+	})
+
+	t.Run("considers earlier clauses in the same run", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetTableStructure", "users").Return(&database.TableStructure{Columns: []string{"id"}}, nil)
+		mockSlack := &MockSlackNotifier{}
+
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+		parts := []string{"ADD COLUMN age INT", "DROP COLUMN age"}
+		assert.Equal(t, parts, manager.skipAppliedAlterParts("users", parts))
+		mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("stops skipping after a clause it cannot track", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetTableStructure", "users").Return(&database.TableStructure{
+			Columns: []string{"id", "a"},
+			Indexes: []string{"PRIMARY", "idx_a"},
+		}, nil)
+		mockSlack := &MockSlackNotifier{}
+
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+		parts := []string{
+			"RENAME COLUMN a TO b, ADD COLUMN a INT",
+			"RENAME INDEX idx_a TO idx_b",
+			"ADD INDEX idx_a (a)",
+			"CHANGE COLUMN b c INT",
+		}
+		assert.Equal(t, parts, manager.skipAppliedAlterParts("users", parts))
+		mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("does not query when nothing can be checked", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+
+		parts := []string{"MODIFY COLUMN age BIGINT"}
+		assert.Equal(t, parts, manager.skipAppliedAlterParts("users", parts))
+		mockDB.AssertNotCalled(t, "GetTableStructure", mock.Anything)
+	})
+
+	t.Run("keeps all clauses when the structure is unavailable", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetTableStructure", "users").Return(nil, errors.New("access denied"))
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+
+		parts := []string{"ADD COLUMN age INT"}
+		assert.Equal(t, parts, manager.skipAppliedAlterParts("users", parts))
+	})
+}
+
+func TestExecuteTableGroup_SkipsAlreadyAppliedAlter(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableStructure", "users").Return(&database.TableStructure{Columns: []string{"id", "age"}}, nil)
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyWarning", "idempotency-check", "users", mock.Anything).Return(nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{DSN: "test-dsn"}, false)
+
+	group := &TableGroup{TableName: "users", AlterParts: []string{"ADD COLUMN age INT"}}
+	require.NoError(t, manager.executeTableGroup(context.Background(), "users", group))
+
+	assert.Equal(t, "already-applied", group.Method)
+	mockDB.AssertNotCalled(t, "GetTableRowCount", mock.Anything)
+	mockDB.AssertNotCalled(t, "ExecuteAlterWithAlgorithm", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,67 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ignoreErrorsDirectiveRe はクエリ中の /* alterguard:ignore-errors=1091,1051 */ 指定。
+// 指定したエラー番号で失敗した場合は警告にとどめて続行する。
+var ignoreErrorsDirectiveRe = regexp.MustCompile(`(?i)/\*\s*alterguard:\s*ignore-errors\s*=\s*([^*]*?)\s*\*/`)
+
+// stripIgnoreErrorsDirective はクエリから ignore-errors 指定を取り除き、指定されたエラー番号を返す
+func stripIgnoreErrorsDirective(query string) (string, []int, error) {
+	matches := ignoreErrorsDirectiveRe.FindAllStringSubmatch(query, -1)
+	if len(matches) == 0 {
+		return query, nil, nil
+	}
+
+	var codes []int
+	for _, match := range matches {
+		for _, field := range strings.Split(match[1], ",") {
+			field = strings.TrimSpace(field)
+			code, err := strconv.Atoi(field)
+			if err != nil || code <= 0 {
+				return "", nil, fmt.Errorf("invalid error code %q in alterguard:ignore-errors", field)
+			}
+			codes = append(codes, code)
+		}
+	}
+	return strings.TrimSpace(ignoreErrorsDirectiveRe.ReplaceAllString(query, " ")), codes, nil
+}
+
+// ignoredErrorCode は err が queryInfo で許容されたエラー番号の MySQL エラーなら、その番号を返す
+func ignoredErrorCode(queryInfo *QueryInfo, err error) (int, bool) {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return 0, false
+	}
+	for _, code := range queryInfo.IgnoredErrors {
+		if int(mysqlErr.Number) == code {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// handleQueryError はクエリの実行エラーを扱う。ignore_errors で許容したエラーは警告を通知して nil を返し、
+// それ以外は duplicate_errors の設定に従う。
+func (m *Manager) handleQueryError(taskName string, queryInfo *QueryInfo, err error) error {
+	code, ok := ignoredErrorCode(queryInfo, err)
+	if !ok {
+		return m.handleDuplicateError(taskName, queryInfo, err)
+	}
+
+	warning := fmt.Sprintf("Ignored error in %s: %s (query: %s, %d is tolerated by ignore_errors)", taskName, err.Error(), queryInfo.Query, code)
+	m.logger.Warn(warning)
+
+	if slackErr := m.slack.NotifyWarning(taskName, queryInfo.TableName, warning); slackErr != nil {
+		m.logger.Errorf("Failed to send warning notification: %v", slackErr)
+	}
+	return nil
+}
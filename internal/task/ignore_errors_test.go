@@ -0,0 +1,114 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripIgnoreErrorsDirective(t *testing.T) {
+	query, codes, err := stripIgnoreErrorsDirective("/* alterguard:ignore-errors=1091, 1051 */ ALTER TABLE users DROP COLUMN legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "ALTER TABLE users DROP COLUMN legacy", query)
+	assert.Equal(t, []int{1091, 1051}, codes)
+
+	query, codes, err = stripIgnoreErrorsDirective("ALTER TABLE users DROP COLUMN legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "ALTER TABLE users DROP COLUMN legacy", query)
+	assert.Nil(t, codes)
+
+	_, _, err = stripIgnoreErrorsDirective("/* alterguard:ignore-errors=abc */ ALTER TABLE users DROP COLUMN legacy")
+	assert.ErrorContains(t, err, `invalid error code "abc"`)
+}
+
+func TestParseConfiguredQueries_IgnoreErrors(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{Tasks: []config.TaskDefinition{
+		{
+			Name:         "cleanup",
+			Query:        "ALTER TABLE users DROP COLUMN legacy; /* alterguard:ignore-errors=1051 */ DROP TABLE old_users",
+			IgnoreErrors: []int{1091},
+		},
+		{Name: "add", Query: "ALTER TABLE users ADD COLUMN age INT"},
+	}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	queries, err := manager.parseConfiguredQueries()
+	require.NoError(t, err)
+	require.Len(t, queries, 3)
+	assert.Equal(t, []int{1091}, queries[0].IgnoredErrors)
+	assert.Equal(t, "DROP TABLE old_users", queries[1].Query)
+	assert.Equal(t, []int{1091, 1051}, queries[1].IgnoredErrors)
+	assert.Nil(t, queries[2].IgnoredErrors)
+
+	// 許容するエラーのある ALTER は他の ALTER とまとめない
+	groups := manager.groupQueriesByTable(queries)
+	require.Len(t, groups, 3)
+	assert.Equal(t, []string{"DROP COLUMN legacy"}, groups[0].AlterParts)
+	assert.Equal(t, []int{1091}, groups[0].ignoredErrors)
+	assert.Equal(t, []string{"ADD COLUMN age INT"}, groups[2].AlterParts)
+	assert.Nil(t, groups[2].ignoredErrors)
+}
+
+func TestHandleQueryError(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	queryInfo := &QueryInfo{Query: "ALTER TABLE users DROP COLUMN legacy", TableName: "users", IgnoredErrors: []int{1091}}
+
+	tests := []struct {
+		name        string
+		err         error
+		wantErr     string
+		wantWarning string
+	}{
+		{
+			name:        "tolerated error warns",
+			err:         &mysql.MySQLError{Number: 1091, Message: "Can't DROP 'legacy'; check that column/key exists"},
+			wantWarning: "1091 is tolerated by ignore_errors",
+		},
+		{
+			name:    "other mysql errors abort",
+			err:     &mysql.MySQLError{Number: 1146, Message: "Table 'users' doesn't exist"},
+			wantErr: "doesn't exist",
+		},
+		{
+			name:        "duplicate errors still follow duplicate_errors",
+			err:         &mysql.MySQLError{Number: 1061, Message: "Duplicate key name 'idx'"},
+			wantWarning: "policy for 1061: warn by default",
+		},
+		{
+			name:    "non mysql errors abort",
+			err:     errors.New("boom"),
+			wantErr: "boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSlack := &MockSlackNotifier{}
+			mockSlack.On("NotifyWarning", "small-query", "users", mock.Anything).Return(nil)
+
+			manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+			err := manager.handleQueryError("small-query", queryInfo, tt.err)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			mockSlack.AssertCalled(t, "NotifyWarning", "small-query", "users", mock.MatchedBy(func(message string) bool {
+				return assert.Contains(t, message, tt.wantWarning)
+			}))
+		})
+	}
+}
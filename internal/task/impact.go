@@ -0,0 +1,119 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pt-osc の --max-lag の既定値(秒)
+const defaultPtOscMaxLagSeconds = 1.0
+
+// ImpactEstimate はテーブルの統計情報から見積もった1テーブル分の影響。
+// 統計情報のサイズ (データ+インデックス) を基にした概算で、上限の目安として扱う。
+type ImpactEstimate struct {
+	Method      string  `json:"method"`
+	TableSizeMB float64 `json:"table_size_mb"`
+	// 行イメージとして binlog に書かれる量。ALTER TABLE は文だけが記録されるため 0
+	BinlogMB float64 `json:"binlog_mb"`
+	// _new テーブルや再構築の一時テーブルが使うディスク
+	TempDiskMB float64 `json:"temp_disk_mb"`
+	// レプリカ遅延の見込み (説明文)
+	ReplicaLag string `json:"replica_lag"`
+	// 元テーブルの読み込みと新しいテーブルの書き込みがバッファプールに占める割合(%)。バッファプールのサイズが不明なら 0
+	BufferPoolChurnPct float64 `json:"buffer_pool_churn_pct,omitempty"`
+}
+
+// estimateImpact は ALTER を含むテーブルごとに影響を見積もる。行数が事前に取得できなかったテーブルは対象外にする。
+// 見積もりに必要な情報が取得できなくても実行は止めず、警告ログを残して見積もりを省く。
+func (m *Manager) estimateImpact(groups []*TableGroup) map[string]*ImpactEstimate {
+	if !m.config.Common.ImpactEstimate.Enabled {
+		return nil
+	}
+
+	bufferPoolMB, err := m.db.GetBufferPoolSizeMB()
+	if err != nil {
+		m.logger.Warnf("Failed to get buffer pool size for the impact estimate: %v", err)
+		bufferPoolMB = 0
+	}
+
+	estimates := make(map[string]*ImpactEstimate)
+	for _, group := range groups {
+		if len(group.AlterParts) == 0 {
+			continue
+		}
+		rowCount, ok := m.rowCounts[group.TableName]
+		if !ok {
+			continue
+		}
+		sizeMB, err := m.db.GetTableSizeMB(group.TableName)
+		if err != nil {
+			m.logger.Warnf("Failed to get table size of %s for the impact estimate: %v", group.TableName, err)
+			continue
+		}
+		estimates[group.TableName] = m.impactFor(group.TableName, m.plannedMethod(group, rowCount), sizeMB, bufferPoolMB)
+	}
+	return estimates
+}
+
+// impactFor は方式とテーブルサイズから影響を見積もる
+func (m *Manager) impactFor(tableName, method string, sizeMB, bufferPoolMB float64) *ImpactEstimate {
+	estimate := &ImpactEstimate{Method: method, TableSizeMB: sizeMB, TempDiskMB: sizeMB}
+	if method == "pt-osc" {
+		// 全行を INSERT ... SELECT でコピーするため、行イメージがそのまま binlog に載る
+		estimate.BinlogMB = sizeMB
+		maxLag := m.config.Common.PtOsc.ForTable(tableName).MaxLag
+		if maxLag <= 0 {
+			maxLag = defaultPtOscMaxLagSeconds
+		}
+		estimate.ReplicaLag = fmt.Sprintf("<= %gs (pt-osc pauses at max_lag)", maxLag)
+	} else {
+		estimate.ReplicaLag = "up to the ALTER duration (replicas apply it after the primary finishes)"
+	}
+	if bufferPoolMB > 0 {
+		// 元テーブルを読み、同じ大きさの新しいテーブルを書く
+		estimate.BufferPoolChurnPct = 2 * sizeMB / bufferPoolMB * 100
+	}
+	return estimate
+}
+
+// FormatImpactEstimate は見積もりをテーブルごとの行と合計にまとめる
+func FormatImpactEstimate(groups []*TableGroup, rowCounts map[string]int64, estimates map[string]*ImpactEstimate) string {
+	var b strings.Builder
+	var totalBinlog, peakTempDisk float64
+	for _, group := range groups {
+		estimate, ok := estimates[group.TableName]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "%s (%s, %d rows, %.1f MB)\n", group.TableName, estimate.Method, rowCounts[group.TableName], estimate.TableSizeMB)
+		fmt.Fprintf(&b, "  binlog: ~%.1f MB\n", estimate.BinlogMB)
+		if estimate.Method == "pt-osc" {
+			fmt.Fprintf(&b, "  temp disk: ~%.1f MB (_new table)\n", estimate.TempDiskMB)
+		} else {
+			fmt.Fprintf(&b, "  temp disk: up to %.1f MB (0 if the ALTER runs INSTANT or in place without a rebuild)\n", estimate.TempDiskMB)
+		}
+		fmt.Fprintf(&b, "  replica lag: %s\n", estimate.ReplicaLag)
+		if estimate.BufferPoolChurnPct > 0 {
+			fmt.Fprintf(&b, "  buffer pool churn: ~%.0f%% of the buffer pool\n", estimate.BufferPoolChurnPct)
+		}
+		totalBinlog += estimate.BinlogMB
+		if estimate.TempDiskMB > peakTempDisk {
+			peakTempDisk = estimate.TempDiskMB
+		}
+	}
+	// テーブルは1つずつ変更するため、一時ディスクは最大のテーブルの分だけ同時に使う
+	fmt.Fprintf(&b, "Total: binlog ~%.1f MB, peak temp disk ~%.1f MB", totalBinlog, peakTempDisk)
+	return b.String()
+}
+
+// notifyImpactEstimate は見積もりがあれば実行前に通知する
+func (m *Manager) notifyImpactEstimate(groups []*TableGroup, estimates map[string]*ImpactEstimate) {
+	if len(estimates) == 0 {
+		return
+	}
+	summary := FormatImpactEstimate(groups, m.rowCounts, estimates)
+	m.logger.Infof("Estimated impact:\n%s", summary)
+	if err := m.slack.NotifyImpactEstimate(summary); err != nil {
+		m.logger.Errorf("Failed to send impact estimate notification: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateImpact(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetBufferPoolSizeMB").Return(4096.0, nil)
+	mockDB.On("GetTableSizeMB", "events").Return(1024.0, nil)
+	mockDB.On("GetTableSizeMB", "users").Return(10.0, nil)
+	mockDB.On("GetTableSizeMB", "logs").Return(0.0, errors.New("boom"))
+
+	cfg := &config.Config{Common: config.CommonConfig{
+		PtOscThreshold: 1000,
+		PtOsc:          config.PtOscConfig{MaxLag: 3},
+		ImpactEstimate: config.ImpactEstimateConfig{Enabled: true},
+	}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+	manager.rowCounts = map[string]int64{"events": 5000000, "users": 100, "logs": 10}
+
+	groups := []*TableGroup{
+		{TableName: "events", AlterParts: []string{"ADD COLUMN region VARCHAR(16)"}},
+		{TableName: "users", AlterParts: []string{"ADD INDEX idx_name (name)"}},
+		{TableName: "logs", AlterParts: []string{"ADD COLUMN x INT"}},
+		{TableName: "settings", OtherQueries: []QueryInfo{{Query: "UPDATE settings SET v = 1"}}},
+		{TableName: "unknown", AlterParts: []string{"ADD COLUMN x INT"}},
+	}
+	estimates := manager.estimateImpact(groups)
+	require.Len(t, estimates, 2)
+
+	events := estimates["events"]
+	assert.Equal(t, "pt-osc", events.Method)
+	assert.Equal(t, 1024.0, events.BinlogMB)
+	assert.Equal(t, 1024.0, events.TempDiskMB)
+	assert.Equal(t, "<= 3s (pt-osc pauses at max_lag)", events.ReplicaLag)
+	assert.InDelta(t, 50.0, events.BufferPoolChurnPct, 0.001)
+
+	users := estimates["users"]
+	assert.Equal(t, "alter-table", users.Method)
+	assert.Equal(t, 0.0, users.BinlogMB)
+	assert.Equal(t, 10.0, users.TempDiskMB)
+
+	summary := FormatImpactEstimate(groups, manager.rowCounts, estimates)
+	assert.Contains(t, summary, "events (pt-osc, 5000000 rows, 1024.0 MB)")
+	assert.Contains(t, summary, "buffer pool churn: ~50% of the buffer pool")
+	assert.Contains(t, summary, "Total: binlog ~1024.0 MB, peak temp disk ~1024.0 MB")
+}
+
+func TestEstimateImpact_Disabled(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+
+	assert.Nil(t, manager.estimateImpact([]*TableGroup{{TableName: "users", AlterParts: []string{"ADD COLUMN x INT"}}}))
+	mockDB.AssertNotCalled(t, "GetBufferPoolSizeMB")
+	mockDB.AssertNotCalled(t, "GetTableSizeMB", mock.Anything)
+}
@@ -0,0 +1,80 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/pyama86/alterguard/internal/database"
+)
+
+// MySQL内部のスレッドは設定に関わらずKILLしない
+var defaultProtectedUsers = []string{"system user", "event_scheduler", "rdsadmin"}
+
+// BlockerPlan は kill-blockers で KILL するセッションと保護されたセッションの一覧
+type BlockerPlan struct {
+	TableName string
+	Killable  []database.BlockingSession
+	Protected []database.BlockingSession
+}
+
+// FindBlockers はテーブルのメタデータロックを保持しているセッションを調べ、
+// kill_blockers.protected_users に該当するものを除外した計画を返す
+func (m *Manager) FindBlockers(tableName string) (*BlockerPlan, error) {
+	sessions, err := m.db.GetBlockingSessions(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	protectedUsers := make(map[string]bool)
+	for _, user := range defaultProtectedUsers {
+		protectedUsers[user] = true
+	}
+	for _, user := range m.config.Common.KillBlockers.ProtectedUsers {
+		protectedUsers[user] = true
+	}
+
+	plan := &BlockerPlan{TableName: tableName}
+	for _, session := range sessions {
+		if protectedUsers[session.User] {
+			plan.Protected = append(plan.Protected, session)
+		} else {
+			plan.Killable = append(plan.Killable, session)
+		}
+	}
+	return plan, nil
+}
+
+// KillBlockers は計画に含まれるセッションを KILL し、結果をまとめて通知する
+func (m *Manager) KillBlockers(plan *BlockerPlan) error {
+	var killed, protected, failed []string
+	for _, session := range plan.Protected {
+		protected = append(protected, describeSession(session))
+	}
+
+	for _, session := range plan.Killable {
+		if err := m.db.KillSession(session.ID); err != nil {
+			m.logger.Errorf("Failed to kill session %d: %v", session.ID, err)
+			failed = append(failed, fmt.Sprintf("%s (%v)", describeSession(session), err))
+			continue
+		}
+		m.logger.Infof("Killed blocking session on %s: %s", plan.TableName, describeSession(session))
+		killed = append(killed, describeSession(session))
+	}
+
+	if err := m.slack.NotifyKillBlockersSummary(plan.TableName, killed, protected, failed); err != nil {
+		m.logger.Errorf("Failed to send kill-blockers summary notification: %v", err)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to kill %d of %d blocking sessions on %s", len(failed), len(plan.Killable), plan.TableName)
+	}
+	return nil
+}
+
+func describeSession(session database.BlockingSession) string {
+	description := fmt.Sprintf("id=%d user=%s host=%s command=%s time=%ds locks=%s",
+		session.ID, session.User, session.Host, session.Command, session.Time, session.LockTypes)
+	if session.Info != "" {
+		description += fmt.Sprintf(" query=%q", session.Info)
+	}
+	return description
+}
@@ -0,0 +1,86 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindBlockersSeparatesProtectedUsers(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	sessions := []database.BlockingSession{
+		{ID: 10, User: "app", Host: "10.0.0.1:5000", Command: "Sleep", Time: 600, LockTypes: "SHARED_READ"},
+		{ID: 11, User: "replicator", Host: "10.0.0.2:5000", Command: "Query", Time: 30, LockTypes: "SHARED_READ"},
+		{ID: 12, User: "system user", Command: "Connect", LockTypes: "SHARED_WRITE"},
+	}
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetBlockingSessions", "users").Return(sessions, nil)
+
+	cfg := &config.Config{Common: config.CommonConfig{
+		KillBlockers: config.KillBlockersConfig{ProtectedUsers: []string{"replicator"}},
+	}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	plan, err := manager.FindBlockers("users")
+
+	require.NoError(t, err)
+	assert.Equal(t, []database.BlockingSession{sessions[0]}, plan.Killable)
+	assert.Equal(t, []database.BlockingSession{sessions[1], sessions[2]}, plan.Protected)
+}
+
+func TestKillBlockers(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	t.Run("kills sessions and sends summary", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("KillSession", int64(10)).Return(nil)
+
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyKillBlockersSummary", "users",
+			[]string{"id=10 user=app host=10.0.0.1:5000 command=Sleep time=600s locks=SHARED_READ"},
+			[]string{"id=12 user=system user host= command=Connect time=0s locks=SHARED_WRITE"},
+			[]string(nil)).Return(nil)
+
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+		err := manager.KillBlockers(&BlockerPlan{
+			TableName: "users",
+			Killable:  []database.BlockingSession{{ID: 10, User: "app", Host: "10.0.0.1:5000", Command: "Sleep", Time: 600, LockTypes: "SHARED_READ"}},
+			Protected: []database.BlockingSession{{ID: 12, User: "system user", Command: "Connect", LockTypes: "SHARED_WRITE"}},
+		})
+
+		require.NoError(t, err)
+		mockDB.AssertExpectations(t)
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("continues after a failed kill and returns an error", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("KillSession", int64(10)).Return(assert.AnError)
+		mockDB.On("KillSession", int64(11)).Return(nil)
+
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyKillBlockersSummary", "users", mock.Anything, []string(nil), mock.MatchedBy(func(failed []string) bool {
+			return len(failed) == 1
+		})).Return(nil)
+
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+		err := manager.KillBlockers(&BlockerPlan{
+			TableName: "users",
+			Killable:  []database.BlockingSession{{ID: 10, User: "app"}, {ID: 11, User: "app", Info: "SELECT 1"}},
+		})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to kill 1 of 2 blocking sessions on users")
+		mockDB.AssertExpectations(t)
+		mockSlack.AssertExpectations(t)
+	})
+}
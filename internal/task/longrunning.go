@@ -0,0 +1,76 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// 実行時間を監視するフェーズ。alert.phases のキーに使う
+const (
+	PhaseSwap       = "swap"
+	PhasePtOsc      = "pt-osc"
+	PhasePtArchiver = "pt-archiver"
+	PhaseAnalyze    = "analyze"
+	PhaseCleanup    = "cleanup"
+)
+
+// phaseThreshold はフェーズの実行時間の閾値を返す。alert.phases にあればそれを、なければ
+// execution_time_threshold_seconds を使う。0 なら監視しない
+func (m *Manager) phaseThreshold(phase string) time.Duration {
+	seconds := m.config.Common.Alert.ExecutionTimeThresholdSeconds
+	if override, ok := m.config.Common.Alert.Phases[phase]; ok {
+		seconds = override
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// startPhaseMonitor はフェーズの実行時間を監視し、閾値 T を超えたら警告、2T で再度警告、4T で呼び出し(ページ)を送る。
+// 返り値の関数でフェーズの終了を伝えると監視をやめる。replica_lag.replicas があれば、開始時と終了時のレプリカ遅延も通知する
+func (m *Manager) startPhaseMonitor(phase, taskName, tableName, query string) func() {
+	stopLagSampling := m.startReplicaLagSampling(phase, taskName, tableName)
+	stopWatch := m.watchExecutionTime(m.phaseThreshold(phase), taskName, tableName, query)
+	return func() {
+		stopWatch()
+		stopLagSampling()
+	}
+}
+
+func (m *Manager) watchExecutionTime(threshold time.Duration, taskName, tableName, query string) func() {
+	if threshold <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	start := time.Now()
+	go func() {
+		for _, multiple := range []time.Duration{1, 2, 4} {
+			timer := time.NewTimer(threshold*multiple - time.Since(start))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+
+			message := fmt.Sprintf("Long execution time detected in %s: operation is taking longer than %d seconds for query: %s",
+				taskName, int(threshold.Seconds()), query)
+			if multiple > 1 {
+				message += fmt.Sprintf(" (still running after %s)", time.Since(start).Round(time.Second))
+			}
+
+			if multiple < 4 {
+				m.logger.Warn(message)
+				if err := m.slack.NotifyWarning(taskName, tableName, message); err != nil {
+					m.logger.Errorf("Failed to send execution time warning notification: %v", err)
+				}
+				continue
+			}
+			m.logger.Error(message)
+			if err := m.slack.NotifyExecutionTimePage(taskName, tableName, message); err != nil {
+				m.logger.Errorf("Failed to send execution time page notification: %v", err)
+			}
+		}
+	}()
+	return cancel
+}
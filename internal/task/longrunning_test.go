@@ -0,0 +1,68 @@
+package task
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPhaseThreshold(t *testing.T) {
+	cfg := &config.Config{Common: config.CommonConfig{Alert: config.AlertConfig{
+		ExecutionTimeThresholdSeconds: 60,
+		Phases:                        map[string]int{PhasePtOsc: 3600, PhaseAnalyze: 0},
+	}}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logrus.New(), cfg, false)
+
+	assert.Equal(t, time.Hour, manager.phaseThreshold(PhasePtOsc))
+	assert.Equal(t, time.Duration(0), manager.phaseThreshold(PhaseAnalyze))
+	assert.Equal(t, time.Minute, manager.phaseThreshold(PhaseSwap))
+	assert.Equal(t, time.Minute, manager.phaseThreshold(PhaseCleanup))
+}
+
+func TestWatchExecutionTime(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	t.Run("escalates at 1x, 2x and 4x the threshold", func(t *testing.T) {
+		var calls atomic.Int32
+		count := func(mock.Arguments) { calls.Add(1) }
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "task", "users", mock.MatchedBy(func(msg string) bool {
+			return !strings.Contains(msg, "still running")
+		})).Return(nil).Run(count).Once()
+		mockSlack.On("NotifyWarning", "task", "users", mock.MatchedBy(func(msg string) bool {
+			return strings.Contains(msg, "still running")
+		})).Return(nil).Run(count).Once()
+		mockSlack.On("NotifyExecutionTimePage", "task", "users", mock.MatchedBy(func(msg string) bool {
+			return strings.Contains(msg, "Long execution time detected in task")
+		})).Return(nil).Run(count).Once()
+
+		manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+		stop := manager.watchExecutionTime(20*time.Millisecond, "task", "users", "`ALTER TABLE users`")
+		assert.Eventually(t, func() bool { return calls.Load() == 3 }, time.Second, 5*time.Millisecond)
+		stop()
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("stops before the first warning", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+		stop := manager.watchExecutionTime(50*time.Millisecond, "task", "users", "`ALTER TABLE users`")
+		stop()
+		time.Sleep(100 * time.Millisecond)
+		mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("disabled threshold", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+		manager.watchExecutionTime(0, "task", "users", "`ALTER TABLE users`")()
+		mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
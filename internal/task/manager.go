@@ -5,12 +5,18 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/pyama86/alterguard/internal/approval"
+	"github.com/pyama86/alterguard/internal/artifacts"
 	"github.com/pyama86/alterguard/internal/config"
 	"github.com/pyama86/alterguard/internal/database"
+	"github.com/pyama86/alterguard/internal/dsn"
+	"github.com/pyama86/alterguard/internal/events"
 	"github.com/pyama86/alterguard/internal/ptarchiver"
 	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/pttablesync"
 	"github.com/pyama86/alterguard/internal/slack"
 	"github.com/sirupsen/logrus"
 )
@@ -23,19 +29,67 @@ type Manager struct {
 	logger     *logrus.Logger
 	config     *config.Config
 	dryRun     bool
+	// 実行中に同じテーブルの統計情報を何度も引かないためのキャッシュ
+	rowCounts map[string]int64
+	artifacts *artifacts.Recorder
+	// 後続作業のコマンド案内に使う (例: "alterguard --common-config config.yaml")
+	commandPrefix string
+	followUpPath  string
+	progress      func(RunProgress)
+	events        events.Publisher
+	// run --plan-file / --from-plan で使う承認用の計画
+	planFile         string
+	plannedTables    []approval.Table
+	approvedPlan     *approval.Plan
+	planRowTolerance float64
+	// 承認済みの計画のうち、照合して実行したテーブル
+	executedPlanTables map[*approval.Table]bool
+	// v2 形式のタスク名 -> 推移的に依存するタスク名の集合
+	taskDependencies map[string]map[string]bool
+	// --pt-osc-threshold などで今回の実行だけ変えた閾値の説明
+	thresholdOverrides []string
+	// swap_remediation で使う pt-table-sync。設定されていなければ修復しない
+	tableSync pttablesync.Executor
+	// dry-run で pt-osc が出した警告。RunResult.Warnings に載せる
+	dryRunWarnings []string
+	// 実行中に同じテーブルのストレージエンジンを何度も引かないためのキャッシュ
+	tableStorages map[string]*database.TableStorage
+	// 重いフェーズの前後で遅延を測るレプリカ
+	lagReplicas []RollingHost
+	// テーブルのまとまりを実行している間にためる重複の警告 (テーブル名 -> 警告)。nil ならすぐに通知する
+	duplicateWarnings map[string][]duplicateWarning
+	// pt_archiver.adaptive で pt-archiver を繰り返すとき、実行中のパスより前のパスで削除した行数
+	purgedRowsBefore atomic.Int64
+	// RegisterSwapValidator で追加した swap 前の検証
+	swapValidators []SwapValidator
+	// schedule のジョブが保持している名前付きロックのコネクション。同じユーザーのセッションを数えるときに除く
+	lockConnectionIDs []int64
 }
 
+// QueryResult はタスクファイルの1クエリの実行結果。
+// 同じテーブルの ALTER はまとめて実行するため、Method・RowCount・Duration はテーブル単位の値になる。
 type QueryResult struct {
-	Query    string
-	Duration time.Duration
-	Success  bool
-	Error    error
+	Query     string
+	TableName string
+	Method    string
+	Status    QueryStatus
+	RowCount  int64
+	Duration  time.Duration
+	Success   bool
+	Error     error
 }
 
 type QueryInfo struct {
-	Query     string
-	QueryType string
-	TableName string
+	Query        string
+	QueryType    string
+	TableName    string
+	ShardPattern string
+	// v2 形式のタスクファイルで、このクエリが書かれたタスクの名前
+	TaskName string
+	// true なら同じテーブルの他の ALTER とまとめずに単独で実行する (batch: false または no-batch 指定)
+	Isolated bool
+	// 失敗しても警告にとどめる MySQL のエラー番号 (ignore_errors または ignore-errors 指定)
+	IgnoredErrors []int
 }
 
 type TableGroup struct {
@@ -43,70 +97,136 @@ type TableGroup struct {
 	AlterParts   []string
 	OtherQueries []QueryInfo
 	RowCount     int64
+	ShardPattern string
+	Method       string
+	// このグループにまとめたクエリの、解析後のクエリ一覧での位置
+	queryIndexes []int
+	// 単独で実行する ALTER のためのグループで、他のクエリを追加しない
+	isolated bool
+	// PARTITION BY などの partition_options を含む ALTER が既にある
+	hasPartitionOptions bool
+	// 単独で実行する ALTER で、失敗しても警告にとどめる MySQL のエラー番号
+	ignoredErrors []int
 }
 
 func NewManager(db database.Client, ptoscExec ptosc.Executor, ptarchiverExec ptarchiver.Executor, slackNotifier slack.Notifier, logger *logrus.Logger, cfg *config.Config, dryRun bool) *Manager {
 	return &Manager{
-		db:         db,
-		ptosc:      ptoscExec,
-		ptarchiver: ptarchiverExec,
-		slack:      slackNotifier,
-		logger:     logger,
-		config:     cfg,
-		dryRun:     dryRun,
+		db:            db,
+		ptosc:         ptoscExec,
+		ptarchiver:    ptarchiverExec,
+		slack:         slackNotifier,
+		logger:        logger,
+		config:        cfg,
+		dryRun:        dryRun,
+		rowCounts:     make(map[string]int64),
+		tableStorages: make(map[string]*database.TableStorage),
 	}
 }
 
 func (m *Manager) extractDatabaseNameFromDSN() (string, error) {
-	dsn := m.config.DSN
-	parts := strings.Split(dsn, "/")
-	if len(parts) < 2 {
-		return "", fmt.Errorf("invalid DSN format: %s", dsn)
-	}
-
-	dbPart := parts[len(parts)-1]
+	return dsn.DatabaseName(m.config.DSN)
+}
 
-	if strings.Contains(dbPart, "?") {
-		dbPart = strings.Split(dbPart, "?")[0]
-	}
+func (m *Manager) ExecuteAllTasks() error {
+	return m.ExecuteAllTasksContext(context.Background())
+}
 
-	if dbPart == "" {
-		return "", fmt.Errorf("database name not found in DSN: %s", dsn)
-	}
+// ExecuteAllTasksContext は ctx がキャンセルされたら実行中のタスクを中断する ExecuteAllTasks
+func (m *Manager) ExecuteAllTasksContext(ctx context.Context) error {
+	_, err := m.ExecuteAllTasksWithResultContext(ctx)
+	return err
+}
 
-	return dbPart, nil
+// ExecuteAllTasksWithResult は ExecuteAllTasks と同じく全タスクを実行し、クエリごとの結果を返す。
+// 途中で失敗した場合も、そこまでの結果と実行されなかったクエリを含めて返す。
+func (m *Manager) ExecuteAllTasksWithResult() (*RunResult, error) {
+	return m.ExecuteAllTasksWithResultContext(context.Background())
 }
 
-func (m *Manager) ExecuteAllTasks() error {
+// ExecuteAllTasksWithResultContext は ctx がキャンセルされたら実行中のタスクを中断する ExecuteAllTasksWithResult
+func (m *Manager) ExecuteAllTasksWithResultContext(parent context.Context) (*RunResult, error) {
 	m.logger.Infof("Starting execution of %d queries", len(m.config.Queries))
+	m.resetRowCounts()
 
-	queries, err := m.parseQueries(m.config.Queries)
+	queries, err := m.parseConfiguredQueries()
 	if err != nil {
-		return fmt.Errorf("failed to parse queries: %w", err)
+		return nil, fmt.Errorf("failed to parse queries: %w", err)
 	}
+	result := newRunResult(queries, m.dryRun)
 
-	// 全体の開始を通知
-	if err := m.slack.NotifyAllTasksStart(len(queries)); err != nil {
-		m.logger.Errorf("Failed to send all tasks start notification: %v", err)
+	runTimeout, err := resolveTimeout("run_timeout", m.config.Common.RunTimeout)
+	if err != nil {
+		return result, err
 	}
+	forcedMethod, err := m.resolveForcedMethod()
+	if err != nil {
+		return result, err
+	}
+	if err := m.checkStdinOverlap(); err != nil {
+		return result, err
+	}
+	if err := m.checkReplicationCompatibility(queries); err != nil {
+		return result, err
+	}
+	binlogFindings, err := m.checkBinlogSettings()
+	if err != nil {
+		return result, err
+	}
+	ctx, cancel := withOptionalTimeout(parent, runTimeout)
+	defer cancel()
+
+	// 全体の開始を通知
+	m.notifyAllTasksStart(len(queries), binlogFindings)
+	m.notifyThresholdOverrides()
+	m.notifyForcedMethod(forcedMethod)
+	m.notifyConfigSummary()
 
 	start := time.Now()
+	m.dryRunWarnings = nil
+	defer func() {
+		result.Duration = time.Since(start)
+		result.Warnings = m.dryRunWarnings
+	}()
 
 	tableGroups := m.groupQueriesByTable(queries)
-
-	for _, group := range tableGroups {
-		if err := m.executeTableGroup(group.TableName, group); err != nil {
+	m.prefetchRowCounts(tableGroups)
+	impacts := m.estimateImpact(tableGroups)
+	m.writePlan(tableGroups, queries, impacts)
+	m.notifyImpactEstimate(tableGroups, impacts)
+	m.reportProgress(tableGroups, 0)
+
+	for i, group := range tableGroups {
+		if err := m.checkRunDeadline(ctx); err != nil {
+			if slackErr := m.slack.NotifyAllTasksFailure(len(queries), err); slackErr != nil {
+				m.logger.Errorf("Failed to send all tasks failure notification: %v", slackErr)
+			}
+			return result, err
+		}
+		groupStart := time.Now()
+		m.startDuplicateWarningAggregation()
+		err := m.executeTableGroup(ctx, group.TableName, group)
+		m.flushDuplicateWarnings()
+		m.recordGroupResult(group, groupStart, err)
+		result.recordGroup(group, time.Since(groupStart), err)
+		if err != nil {
 			// 失敗時の通知
 			if slackErr := m.slack.NotifyAllTasksFailure(len(queries), err); slackErr != nil {
 				m.logger.Errorf("Failed to send all tasks failure notification: %v", slackErr)
 			}
-			return fmt.Errorf("failed to execute queries for table %s: %w", group.TableName, err)
+			return result, fmt.Errorf("failed to execute queries for table %s: %w", group.TableName, err)
 		}
+		m.reportProgress(tableGroups, i+1)
 	}
 
 	// テーブル指定がないクエリを実行する
-	for _, query := range queries {
+	for i, query := range queries {
 		if query.TableName == "" {
+			if err := m.checkRunDeadline(ctx); err != nil {
+				if slackErr := m.slack.NotifyAllTasksFailure(len(queries), err); slackErr != nil {
+					m.logger.Errorf("Failed to send all tasks failure notification: %v", slackErr)
+				}
+				return result, err
+			}
 			cleanedQuery := strings.ReplaceAll(query.Query, "`", "")
 			quotedQuery := fmt.Sprintf("`%s`", cleanedQuery)
 			taskName := "non-table-query"
@@ -118,7 +238,10 @@ func (m *Manager) ExecuteAllTasks() error {
 			}
 
 			queryStart := time.Now()
-			if err := m.executeQuery(&query, "non-table-query"); err != nil {
+			err := m.executeQuery(ctx, &query, "non-table-query")
+			m.recordQueryResult(query, queryStart, err)
+			result.recordQuery(i, "non-table-query", time.Since(queryStart), err)
+			if err != nil {
 				if slackErr := m.slack.NotifyFailureWithQuery(taskName, query.TableName, quotedQuery, 0, err); slackErr != nil {
 					m.logger.Errorf("Failed to send failure notification: %v", slackErr)
 				}
@@ -126,7 +249,7 @@ func (m *Manager) ExecuteAllTasks() error {
 				if slackErr := m.slack.NotifyAllTasksFailure(len(queries), err); slackErr != nil {
 					m.logger.Errorf("Failed to send all tasks failure notification: %v", slackErr)
 				}
-				return fmt.Errorf("failed to execute query: %w", err)
+				return result, fmt.Errorf("failed to execute query: %w", err)
 			}
 
 			duration := time.Since(queryStart)
@@ -136,62 +259,115 @@ func (m *Manager) ExecuteAllTasks() error {
 		}
 	}
 
+	// no_swap_tables で残したテーブルを時間帯指定でswapする
+	swapped, err := m.runAutoSwaps(ctx, tableGroups)
+	if err != nil {
+		if slackErr := m.slack.NotifyAllTasksFailure(len(queries), err); slackErr != nil {
+			m.logger.Errorf("Failed to send all tasks failure notification: %v", slackErr)
+		}
+		return result, err
+	}
+
+	if err := m.checkApprovedPlanExecuted(); err != nil {
+		if slackErr := m.slack.NotifyAllTasksFailure(len(queries), err); slackErr != nil {
+			m.logger.Errorf("Failed to send all tasks failure notification: %v", slackErr)
+		}
+		return result, err
+	}
+
 	totalDuration := time.Since(start)
 
+	m.notifyShardSummaries(tableGroups, totalDuration)
+
 	// 全体の完了を通知
 	if err := m.slack.NotifyAllTasksSuccess(len(queries), totalDuration); err != nil {
 		m.logger.Errorf("Failed to send all tasks success notification: %v", err)
 	}
 
+	m.reportFollowUpCommands(tableGroups, swapped)
+
+	if err := m.writePlanFile(); err != nil {
+		return result, err
+	}
+
 	m.logger.Info("All queries completed successfully")
-	return nil
+	return result, nil
 }
 
 func (m *Manager) groupQueriesByTable(queries []QueryInfo) []*TableGroup {
 	groupMap := make(map[string]*TableGroup)
+	groupIndexes := make(map[*TableGroup]int)
+	// タスク名 -> そのタスクのクエリを含む最後のグループの位置
+	taskGroups := make(map[string]int)
+	var result []*TableGroup
 
-	for _, query := range queries {
+	for i, query := range queries {
 		if query.TableName == "" {
 			continue
 		}
 
+		var alterPart string
+		var spec alterSpec
+		if query.QueryType == "ALTER" {
+			alterPart = m.extractAlterStatement(query.Query)
+			spec = parseAlterSpec(alterPart)
+		}
+
 		group, exists := groupMap[query.TableName]
-		if !exists {
+		// 許容するエラーは他の ALTER と同じ文で失敗すると他の変更まで失われるため、単独で実行する
+		isolated := ((query.Isolated || len(query.IgnoredErrors) > 0) && query.QueryType == "ALTER") || spec.Exclusive
+		// partition_options は1つの ALTER に1つしか書けない
+		partitionConflict := exists && spec.Partition != "" && group.hasPartitionOptions
+		if !exists || group.isolated || isolated || partitionConflict || m.mustStartNewGroup(query, groupIndexes[group], taskGroups) {
 			group = &TableGroup{
 				TableName:    query.TableName,
 				AlterParts:   []string{},
 				OtherQueries: []QueryInfo{},
+				ShardPattern: query.ShardPattern,
+				isolated:     isolated,
+			}
+			if query.QueryType == "ALTER" {
+				group.ignoredErrors = query.IgnoredErrors
 			}
 			groupMap[query.TableName] = group
+			groupIndexes[group] = len(result)
+			result = append(result, group)
+		}
+		group.queryIndexes = append(group.queryIndexes, i)
+		if query.TaskName != "" {
+			if last, ok := taskGroups[query.TaskName]; !ok || groupIndexes[group] > last {
+				taskGroups[query.TaskName] = groupIndexes[group]
+			}
 		}
 
 		if query.QueryType == "ALTER" {
-			alterPart := m.extractAlterStatement(query.Query)
 			if alterPart != "" {
 				group.AlterParts = append(group.AlterParts, alterPart)
+				if spec.Partition != "" {
+					group.hasPartitionOptions = true
+				}
 			}
 		} else {
 			group.OtherQueries = append(group.OtherQueries, query)
 		}
 	}
 
-	seen := make(map[string]bool)
-	var result []*TableGroup
-	for _, query := range queries {
-		if query.TableName == "" || seen[query.TableName] {
-			continue
-		}
-		seen[query.TableName] = true
-		result = append(result, groupMap[query.TableName])
-	}
-
 	return result
 }
 
-func (m *Manager) executeTableGroup(tableName string, group *TableGroup) error {
+func (m *Manager) executeTableGroup(ctx context.Context, tableName string, group *TableGroup) error {
 	m.logger.Infof("Processing table: %s", tableName)
 
-	if err := m.executeSmallQueries(group.OtherQueries); err != nil {
+	// 直接実行する ALTER や小さなクエリも run_timeout / task_timeout で中断する
+	taskCtx, cancel, err := m.taskContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	group.Method = "small-query"
+	if err := m.executeSmallQueries(taskCtx, group.OtherQueries); err != nil {
+		m.handleTimeout(ctx, "small-query", tableName, err, nil)
 		return err
 	}
 
@@ -199,76 +375,124 @@ func (m *Manager) executeTableGroup(tableName string, group *TableGroup) error {
 		return nil
 	}
 
-	rowCount, err := m.db.GetTableRowCount(tableName)
-	if err != nil {
+	alterParts := m.skipAppliedAlterParts(tableName, group.AlterParts)
+	if len(alterParts) == 0 {
+		group.Method = "already-applied"
+		return nil
+	}
+
+	group.Method = "alter-table"
+	var rowCount int64
+	if forced, _ := m.resolveForcedMethod(); forced != "" {
+		// force_method は統計が当てにならないときのためのものなので行数は数えない。先読みした行数があれば表示にだけ使う
+		m.logger.Warnf("Using %s for table %s because of force_method, without counting rows", forced, tableName)
+		group.Method = forced
+		rowCount = m.rowCounts[tableName]
+		group.RowCount = rowCount
+	} else if rowCount, err = m.getTableRowCount(tableName); err != nil {
 		m.logger.Warnf("Failed to get row count for table %s, treating as small query: %v", tableName, err)
-		return m.executeAlterPartsAsSmallQueries(tableName, group.AlterParts)
+	} else {
+		group.RowCount = rowCount
+		group.Method = m.chooseAlterMethod(tableName, rowCount)
+		group.Method, group.RowCount, err = m.verifyRowCount(tableName, group.Method, rowCount)
+		if err != nil {
+			return err
+		}
+		rowCount = group.RowCount
 	}
 
-	threshold := m.config.Common.PtOscThreshold
-	m.logger.Infof("Table %s has %d rows (threshold: %d)", tableName, rowCount, threshold)
+	storage := m.getTableStorage(tableName)
+	if err := m.checkStorageEngine(tableName, group.Method, storage); err != nil {
+		return err
+	}
+	m.warnImplicitRowFormatChange(tableName, group.Method, storage, alterParts)
 
-	if rowCount <= threshold {
-		return m.executeAlterPartsAsSmallQueries(tableName, group.AlterParts)
-	} else {
-		return m.executeLargeAlterQuery(tableName, group.AlterParts, rowCount)
+	if err := m.checkPlan(tableName, group.Method, group.RowCount, alterParts); err != nil {
+		return err
 	}
+
+	if group.Method == "pt-osc" {
+		// swap するとテーブルが _new に入れ替わるため、行数のキャッシュを捨てる
+		defer m.invalidateRowCount(tableName)
+		return m.executeLargeAlterQuery(ctx, tableName, alterParts, rowCount)
+	}
+	err = m.executeAlterPartsAsSmallQueries(taskCtx, tableName, alterParts, group.ignoredErrors)
+	m.handleTimeout(ctx, "alter-table", tableName, err, nil)
+	return err
 }
 
-func (m *Manager) executeAlterPartsAsSmallQueries(tableName string, alterParts []string) error {
+func (m *Manager) executeAlterPartsAsSmallQueries(ctx context.Context, tableName string, alterParts []string, ignoredErrors []int) error {
 	taskName := "alter-table"
 	if m.dryRun {
 		taskName = "alter-table (DRY RUN)"
 	}
 
-	if err := m.checkOtherActiveConnections(taskName, tableName); err != nil {
+	if err := m.checkOtherActiveConnections(ctx, taskName, tableName); err != nil {
 		return err
 	}
 
-	rowCount, err := m.db.GetTableRowCount(tableName)
-	if err != nil {
-		m.logger.Warnf("Failed to get row count for table %s: %v", tableName, err)
-		rowCount = 0
+	// force_method のときは通知のためだけに数えない
+	rowCount := m.rowCounts[tableName]
+	if forced, _ := m.resolveForcedMethod(); forced == "" {
+		var err error
+		rowCount, err = m.getTableRowCount(tableName)
+		if err != nil {
+			m.logger.Warnf("Failed to get row count for table %s: %v", tableName, err)
+			rowCount = 0
+		}
 	}
 
-	cleanedQuery := strings.ReplaceAll(fmt.Sprintf("ALTER TABLE %s %s", tableName, strings.Join(alterParts, ", ")), "`", "")
+	cleanedQuery := strings.ReplaceAll(fmt.Sprintf("ALTER TABLE %s %s", tableName, combineAlterParts(alterParts)), "`", "")
 	combinedQuery := fmt.Sprintf("`%s`", cleanedQuery)
 
-	if err := m.slack.NotifyStartWithQuery(taskName, tableName, combinedQuery, rowCount); err != nil {
-		m.logger.Errorf("Failed to send start notification: %v", err)
-	}
+	m.notifyAlterStart(taskName, tableName, combinedQuery, rowCount)
 
 	start := time.Now()
+	var algorithms []*database.AlterAlgorithm
 	for _, alterPart := range alterParts {
 		query := fmt.Sprintf("ALTER TABLE %s %s", tableName, alterPart)
 		queryInfo := QueryInfo{
-			Query:     query,
-			QueryType: "ALTER",
-			TableName: tableName,
+			Query:         query,
+			QueryType:     "ALTER",
+			TableName:     tableName,
+			IgnoredErrors: ignoredErrors,
 		}
-		if err := m.executeQuery(&queryInfo, "alter-table"); err != nil {
+		algorithm, err := m.executeAlterWithAlgorithm(ctx, &queryInfo, "alter-table")
+		if err != nil {
 			if slackErr := m.slack.NotifyFailureWithQuery(taskName, tableName, combinedQuery, rowCount, err); slackErr != nil {
 				m.logger.Errorf("Failed to send failure notification: %v", slackErr)
 			}
 			return err
 		}
+		if algorithm != nil {
+			algorithms = append(algorithms, algorithm)
+		}
 	}
 
 	duration := time.Since(start)
-	if err := m.slack.NotifySuccessWithQuery(taskName, tableName, combinedQuery, rowCount, duration); err != nil {
+	algorithmSummary := m.summarizeAlgorithms(tableName, rowCount, algorithms)
+	if err := m.slack.NotifySuccessWithQueryAndAlgorithm(taskName, tableName, combinedQuery, rowCount, duration, algorithmSummary); err != nil {
 		m.logger.Errorf("Failed to send success notification: %v", err)
 	}
 
 	return nil
 }
 
-func (m *Manager) executeLargeAlterQuery(tableName string, alterParts []string, rowCount int64) error {
+func (m *Manager) executeLargeAlterQuery(ctx context.Context, tableName string, alterParts []string, rowCount int64) error {
 	taskName := "pt-osc"
 	if m.dryRun {
 		taskName = "pt-osc (DRY RUN)"
 	}
 
-	if err := m.checkOtherActiveConnections(taskName, tableName); err != nil {
+	if err := m.checkOtherActiveConnections(ctx, taskName, tableName); err != nil {
+		return err
+	}
+
+	if err := m.checkConnectionHeadroom(taskName, tableName); err != nil {
+		return err
+	}
+
+	if err := m.checkScheduleConflicts("pt-osc", tableName); err != nil {
 		return err
 	}
 
@@ -276,7 +500,7 @@ func (m *Manager) executeLargeAlterQuery(tableName string, alterParts []string,
 		return err
 	}
 
-	combinedAlter := strings.Join(alterParts, ", ")
+	combinedAlter := combineAlterParts(alterParts)
 	cleanedAlterQuery := strings.ReplaceAll(fmt.Sprintf("ALTER TABLE %s %s", tableName, combinedAlter), "`", "")
 	alterQuery := fmt.Sprintf("`%s`", cleanedAlterQuery)
 
@@ -302,23 +526,33 @@ func (m *Manager) executeLargeAlterQuery(tableName string, alterParts []string,
 
 	m.logger.Infof("Executing pt-online-schema-change for table %s (rows: %d)", tableName, rowCount)
 
-	if err := m.slack.NotifyStartWithQuery(taskName, tableName, queryInfo, rowCount); err != nil {
-		m.logger.Errorf("Failed to send start notification: %v", err)
+	m.notifyAlterStart(taskName, tableName, queryInfo, rowCount)
+
+	taskCtx, cancel, err := m.taskContext(ctx)
+	if err != nil {
+		return err
 	}
+	defer cancel()
 
 	start := time.Now()
 
 	if m.dryRun {
-		dryRunResult, err := m.ptosc.ExecuteAlterWithDryRunResult(tableName, combinedAlter, m.config.Common.PtOsc, m.config.DSN, m.dryRun)
+		dryRunResult, err := m.ptosc.ExecuteAlterWithDryRunResult(taskCtx, tableName, combinedAlter, m.config.Common.PtOsc, m.config.DSN, m.dryRun)
 		if err != nil {
+			if m.handleTimeout(ctx, taskName, tableName, err, nil) {
+				return fmt.Errorf("pt-online-schema-change dry run timed out: %w", err)
+			}
 			if slackErr := m.slack.NotifyFailureWithQuery(taskName, tableName, queryInfo, rowCount, err); slackErr != nil {
 				m.logger.Errorf("Failed to send failure notification: %v", slackErr)
 			}
-			return fmt.Errorf("pt-online-schema-change dry run failed: %w", err)
+			return fmt.Errorf("%w (dry run): %w", ErrPtOscFailed, err)
 		}
 
 		duration := time.Since(start)
 		if dryRunResult != nil {
+			for _, warning := range dryRunResult.Warnings {
+				m.dryRunWarnings = append(m.dryRunWarnings, fmt.Sprintf("%s: %s", tableName, warning))
+			}
 			slackDryRunResult := &slack.DryRunResult{
 				EstimatedTime:    dryRunResult.EstimatedTime,
 				AffectedRows:     dryRunResult.AffectedRows,
@@ -336,18 +570,33 @@ func (m *Manager) executeLargeAlterQuery(tableName string, alterParts []string,
 			}
 		}
 	} else {
-		if err := m.ptosc.ExecuteAlter(tableName, combinedAlter, m.config.Common.PtOsc, m.config.DSN, m.dryRun); err != nil {
+		m.publishMigrationEvent(events.PhaseStart, tableName, combinedAlter, rowCount, 0, nil)
+		stopMonitor := m.startPhaseMonitor(PhasePtOsc, taskName, tableName, ptOscCommand)
+		stopProgress := m.startCopyProgressRecorder(tableName)
+		err := m.ptosc.ExecuteAlter(taskCtx, tableName, combinedAlter, m.config.Common.PtOsc, m.config.DSN, m.dryRun)
+		stopProgress(err == nil)
+		stopMonitor()
+		if err != nil {
+			m.publishMigrationEvent(events.PhaseEnd, tableName, combinedAlter, rowCount, time.Since(start), err)
+			if m.handleTimeout(ctx, taskName, tableName, err, func() { m.cleanupAfterPtOscTimeout(tableName) }) {
+				return fmt.Errorf("pt-online-schema-change timed out: %w", err)
+			}
 			var ptOscLog string
 			if ptOscExecutor, ok := m.ptosc.(*ptosc.PtOscExecutor); ok {
 				ptOscLog = ptOscExecutor.GetOutputSummary()
+				if path := ptOscExecutor.GetOutputFilePath(); path != "" {
+					m.logger.Errorf("Full pt-online-schema-change output for %s: %s", tableName, path)
+				}
 			}
 			if slackErr := m.slack.NotifyFailureWithQueryAndLog(taskName, tableName, queryInfo, rowCount, err, ptOscLog); slackErr != nil {
 				m.logger.Errorf("Failed to send failure notification: %v", slackErr)
 			}
-			return fmt.Errorf("pt-online-schema-change failed: %w", err)
+			m.cleanupAfterPtOscFailure(tableName, err)
+			return fmt.Errorf("%w: %w", ErrPtOscFailed, err)
 		}
 
 		duration := time.Since(start)
+		m.publishMigrationEvent(events.PhaseEnd, tableName, combinedAlter, rowCount, duration, nil)
 		var ptOscLog string
 		if ptOscExecutor, ok := m.ptosc.(*ptosc.PtOscExecutor); ok {
 			ptOscLog = ptOscExecutor.GetOutputSummary()
@@ -361,7 +610,8 @@ func (m *Manager) executeLargeAlterQuery(tableName string, alterParts []string,
 			}
 		} else {
 			m.logger.Infof("pt-osc completed for table %s: original=%d, new=%d", tableName, rowCount, newRowCount)
-			if err := m.slack.NotifyPtOscCompletionWithNewTableCount(taskName, tableName, rowCount, newRowCount, duration, ptOscLog); err != nil {
+			performance := m.logCopyPerformance(tableName, m.ptOscCopyStats())
+			if err := m.slack.NotifyPtOscCompletionWithNewTableCount(taskName, tableName, rowCount, newRowCount, duration, ptOscLog, performance); err != nil {
 				m.logger.Errorf("Failed to send completion notification: %v", err)
 			}
 		}
@@ -370,13 +620,13 @@ func (m *Manager) executeLargeAlterQuery(tableName string, alterParts []string,
 	return nil
 }
 
-func (m *Manager) executeSmallQueries(queries []QueryInfo) error {
+func (m *Manager) executeSmallQueries(ctx context.Context, queries []QueryInfo) error {
 	for _, queryInfo := range queries {
 		m.logger.Infof("Executing query: %s", queryInfo.Query)
 
 		var rowCount int64 = 0
 		if queryInfo.TableName != "" {
-			if count, err := m.db.GetTableRowCount(queryInfo.TableName); err == nil {
+			if count, err := m.getTableRowCount(queryInfo.TableName); err == nil {
 				rowCount = count
 			}
 		}
@@ -392,7 +642,7 @@ func (m *Manager) executeSmallQueries(queries []QueryInfo) error {
 		}
 
 		start := time.Now()
-		if err := m.executeQuery(&queryInfo, "small-query"); err != nil {
+		if err := m.executeQuery(ctx, &queryInfo, "small-query"); err != nil {
 			if slackErr := m.slack.NotifyFailureWithQuery(taskName, queryInfo.TableName, quotedQuery, rowCount, err); slackErr != nil {
 				m.logger.Errorf("Failed to send failure notification: %v", slackErr)
 			}
@@ -407,24 +657,16 @@ func (m *Manager) executeSmallQueries(queries []QueryInfo) error {
 	return nil
 }
 
-func (m *Manager) executeQuery(queryInfo *QueryInfo, taskName string) error {
+func (m *Manager) executeQuery(ctx context.Context, queryInfo *QueryInfo, taskName string) error {
 	if m.dryRun {
 		m.logger.Infof("[DRY RUN] Would execute SQL: %s", queryInfo.Query)
 		return nil
 	}
 
-	if err := m.db.ExecuteAlter(queryInfo.Query); err != nil {
-		if database.IsDuplicateError(err) {
-			warning := fmt.Sprintf("Duplicate detected in %s: %s (query: %s)", taskName, err.Error(), queryInfo.Query)
-			m.logger.Warn(warning)
-
-			if slackErr := m.slack.NotifyWarning(taskName, queryInfo.TableName, warning); slackErr != nil {
-				m.logger.Errorf("Failed to send warning notification: %v", slackErr)
-			}
-
-			return nil
-		}
-		return err
+	// 失敗や中断でも途中まで書き込んでいることがあるため、結果にかかわらずキャッシュを捨てる
+	defer m.invalidateRowCount(queryInfo.TableName)
+	if err := m.db.ExecuteAlterContext(ctx, queryInfo.Query); err != nil {
+		return m.handleQueryError(taskName, queryInfo, err)
 	}
 	return nil
 }
@@ -432,17 +674,32 @@ func (m *Manager) executeQuery(queryInfo *QueryInfo, taskName string) error {
 func (m *Manager) parseQueries(queries []string) ([]QueryInfo, error) {
 	var result []QueryInfo
 	for _, query := range queries {
+		query, isolated := stripNoBatchDirective(query)
+		query, ignoredErrors, err := stripIgnoreErrorsDirective(query)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkTemporaryTable(query); err != nil {
+			return nil, err
+		}
 		queryType, err := m.getQueryType(query)
 		if err != nil {
 			return nil, err
 		}
 
 		queryInfo := QueryInfo{
-			Query:     strings.TrimSpace(query),
-			TableName: m.extractTableName(query),
-			QueryType: queryType,
+			Query:         strings.TrimSpace(query),
+			TableName:     m.extractTableName(query),
+			QueryType:     queryType,
+			Isolated:      isolated,
+			IgnoredErrors: ignoredErrors,
+		}
+
+		expanded, err := m.expandShardQuery(queryInfo)
+		if err != nil {
+			return nil, err
 		}
-		result = append(result, queryInfo)
+		result = append(result, expanded...)
 	}
 
 	return result, nil
@@ -482,7 +739,7 @@ func (m *Manager) extractTableName(query string) string {
 }
 
 func (m *Manager) extractAlterStatement(query string) string {
-	alterTableRe := regexp.MustCompile(`(?i)ALTER\s+TABLE\s+` + "`" + `?[^` + "`" + `\s]+` + "`" + `?\s+(.+)`)
+	alterTableRe := regexp.MustCompile(`(?is)ALTER\s+TABLE\s+` + "`" + `?[^` + "`" + `\s]+` + "`" + `?\s+(.+)`)
 	if matches := alterTableRe.FindStringSubmatch(query); len(matches) > 1 {
 		return matches[1]
 	}
@@ -490,58 +747,104 @@ func (m *Manager) extractAlterStatement(query string) string {
 }
 
 func (m *Manager) SwapTable(tableName string) error {
+	return m.SwapTableContext(context.Background(), tableName)
+}
+
+// SwapTableContext は ctx が終わったら RENAME TABLE の前で中断する SwapTable。
+// 接続チェックの待機や swap_validators も ctx で止まる
+func (m *Manager) SwapTableContext(ctx context.Context, tableName string) error {
 	m.logger.Infof("Starting table swap for %s", tableName)
+	defer m.invalidateRowCount(tableName)
 
 	taskName := "swap"
 	if m.dryRun {
 		taskName = "swap (DRY RUN)"
 	}
 
-	if err := m.checkOtherActiveConnections(taskName, tableName); err != nil {
+	soak, err := m.resolveSwapSoak()
+	if err != nil {
 		return err
 	}
-
-	originalTableExists, err := m.db.TableExists(tableName)
+	analyze, err := m.resolveAnalyzeSettings(tableName)
 	if err != nil {
-		m.logger.Errorf("Failed to check original table existence: %v", err)
-		return fmt.Errorf("failed to check original table existence: %w", err)
-	}
-	if !originalTableExists {
-		return fmt.Errorf("original table %s does not exist", tableName)
+		return err
 	}
 
-	newTableName := fmt.Sprintf("_%s_new", tableName)
-	newTableExists, err := m.db.TableExists(newTableName)
+	validatorChecks, err := m.swapValidatorChecks(ctx, tableName)
 	if err != nil {
-		m.logger.Errorf("Failed to check new table existence: %v", err)
-		return fmt.Errorf("failed to check new table existence: %w", err)
-	}
-	if !newTableExists {
-		return fmt.Errorf("new table %s does not exist", newTableName)
+		return err
 	}
 
-	m.logger.Infof("Both tables exist: %s and %s", tableName, newTableName)
-
-	// レコード件数チェック（5%の閾値でハードコーディング）
-	if err := m.checkRowCountDifference(tableName); err != nil {
+	newTableName := fmt.Sprintf("_%s_new", tableName)
+	var oldTable *oldTableSwapPlan
+	checks := []preCheck{
+		{name: "active connections", run: func() error { return m.checkOtherActiveConnections(ctx, taskName, tableName) }},
+		{name: "original table exists", prerequisite: true, run: func() error {
+			originalTableExists, err := m.db.TableExists(tableName)
+			if err != nil {
+				m.logger.Errorf("Failed to check original table existence: %v", err)
+				return fmt.Errorf("failed to check original table existence: %w", err)
+			}
+			if !originalTableExists {
+				return fmt.Errorf("original table %s does not exist", tableName)
+			}
+			return nil
+		}},
+		{name: "new table exists", prerequisite: true, run: func() error {
+			newTableExists, err := m.db.TableExists(newTableName)
+			if err != nil {
+				m.logger.Errorf("Failed to check new table existence: %v", err)
+				return fmt.Errorf("failed to check new table existence: %w", err)
+			}
+			if !newTableExists {
+				return fmt.Errorf("new table %s does not exist", newTableName)
+			}
+			m.logger.Infof("Both tables exist: %s and %s", tableName, newTableName)
+			return nil
+		}},
+		{name: "old table name", run: func() error {
+			var err error
+			oldTable, err = m.planOldTable(tableName, time.Now())
+			return err
+		}},
+	}
+	// 行数チェック（5%の閾値）と swap_validators の検証
+	checks = append(checks, validatorChecks...)
+	checks = append(checks,
+		preCheck{name: "freshness", run: func() error { return m.checkShadowTableFreshness(tableName) }},
+		preCheck{name: "dependencies", run: func() error { return m.checkTableDependencies("swap", tableName) }},
+		preCheck{name: "scheduled jobs", run: func() error { return m.checkScheduleConflicts("swap", tableName) }},
+	)
+	checks = append(checks, m.swapEnvironmentChecks(tableName)...)
+	if err := m.runPreChecks("swap", tableName, checks); err != nil {
 		return err
 	}
 
+	if oldTable.dropExisting {
+		if err := m.CleanupOldTable(tableName); err != nil {
+			return fmt.Errorf("failed to drop existing %s_old before swap: %w", tableName, err)
+		}
+	}
+
+	m.checkCollationDrift(tableName)
+
 	// swap前にnewテーブルに対してANALYZE TABLEを実行
-	if !m.config.Common.DisableAnalyzeTable {
-		newTableName := fmt.Sprintf("_%s_new", tableName)
-		if m.dryRun {
-			m.logger.Infof("[DRY RUN] Would execute ANALYZE TABLE for %s before swap", newTableName)
-		} else {
-			m.logger.Infof("Executing ANALYZE TABLE for %s before swap", newTableName)
-			if err := m.db.AnalyzeTable(newTableName); err != nil {
-				m.logger.Warnf("ANALYZE TABLE failed for %s: %v", newTableName, err)
-			}
+	if analyze.beforeSwap {
+		if err := m.analyzeTable("before swap", newTableName, analyze); err != nil {
+			return err
 		}
 	}
 
-	swapSQL := fmt.Sprintf("RENAME TABLE %s TO %s_old, _%s_new TO %s",
-		tableName, tableName, tableName, tableName)
+	if m.config.Common.SwapWarmup.Enabled {
+		m.warmupNewTable(tableName, newTableName)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("swap of %s canceled before RENAME TABLE: %w", tableName, err)
+	}
+
+	swapSQL := fmt.Sprintf("RENAME TABLE %s TO %s, _%s_new TO %s",
+		tableName, oldTable.name, tableName, tableName)
 	cleanedQuery := strings.ReplaceAll(swapSQL, "`", "")
 	quotedQuery := fmt.Sprintf("`%s`", cleanedQuery)
 
@@ -561,6 +864,7 @@ func (m *Manager) SwapTable(tableName string) error {
 
 	if m.dryRun {
 		m.logger.Infof("[DRY RUN] Would execute SQL: %s", swapSQL)
+		m.recordSwapResult(tableName, swapSQL, start, nil)
 		duration := time.Since(start)
 		if err := m.slack.NotifySuccessWithQuery(taskName, tableName, quotedQuery, 0, duration); err != nil {
 			m.logger.Errorf("Failed to send success notification: %v", err)
@@ -568,30 +872,13 @@ func (m *Manager) SwapTable(tableName string) error {
 		return nil
 	}
 
-	// Start concurrent execution time monitoring
-	thresholdSeconds := m.config.Common.Alert.ExecutionTimeThresholdSeconds
-	if thresholdSeconds > 0 {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
-		go func() {
-			timer := time.NewTimer(time.Duration(thresholdSeconds) * time.Second)
-			defer timer.Stop()
-			select {
-			case <-timer.C:
-				warning := fmt.Sprintf("Long execution time detected in %s: operation is taking longer than %d seconds for query: %s",
-					taskName, thresholdSeconds, quotedQuery)
-				m.logger.Warn(warning)
-				if slackErr := m.slack.NotifyWarning(taskName, tableName, warning); slackErr != nil {
-					m.logger.Errorf("Failed to send execution time warning notification: %v", slackErr)
-				}
-			case <-ctx.Done():
-				return
-			}
-		}()
-	}
-
-	if err := m.db.ExecuteAlter(swapSQL); err != nil {
+	stopMonitor := m.startPhaseMonitor(PhaseSwap, taskName, tableName, quotedQuery)
+	err = m.db.ExecuteAlter(swapSQL)
+	stopMonitor()
+	m.recordSwapResult(tableName, swapSQL, start, err)
+	// RENAME は一瞬で終わるため、終了のイベントだけを送る
+	m.publishOperationEvent(events.PhaseEnd, "swap", tableName, swapSQL, 0, time.Since(start), err)
+	if err != nil {
 		if slackErr := m.slack.NotifyFailureWithQuery(taskName, tableName, quotedQuery, 0, err); slackErr != nil {
 			m.logger.Errorf("Failed to send failure notification: %v", slackErr)
 		}
@@ -604,6 +891,19 @@ func (m *Manager) SwapTable(tableName string) error {
 		m.logger.Errorf("Failed to send success notification: %v", err)
 	}
 
+	m.checkAutoIncrementContinuity(tableName, oldTable.name)
+
+	// swap 後のテーブルの統計情報を更新する
+	if analyze.afterSwap {
+		if err := m.analyzeTable("after swap", tableName, analyze); err != nil {
+			return fmt.Errorf("swap completed but %w", err)
+		}
+	}
+
+	if err := m.soakSwap(ctx, tableName, oldTable.name, soak); err != nil {
+		return err
+	}
+
 	m.logger.Infof("Table swap completed for %s", tableName)
 	return nil
 }
@@ -611,37 +911,26 @@ func (m *Manager) SwapTable(tableName string) error {
 func (m *Manager) CleanupOldTable(tableName string) error {
 	m.logger.Infof("Starting cleanup for table %s", tableName)
 
-	// pt-archiverが有効な場合、DROP前にデータを削除
+	oldTableName := fmt.Sprintf("%s_old", tableName)
+	checks := []preCheck{
+		{name: "dependencies", run: func() error { return m.checkTableDependencies("cleanup", oldTableName) }},
+	}
+	// pt-archiverが有効な場合、DROP前にデータを削除 (dry run では pt-archiver --dry-run)
 	if m.config.Common.PtArchiver.Enabled {
-		oldTableName := fmt.Sprintf("%s_old", tableName)
-		if err := m.PurgeOldTable(oldTableName); err != nil {
-			return fmt.Errorf("failed to purge old table before cleanup: %w", err)
-		}
+		checks = append(checks, preCheck{name: "pt-archiver purge", run: func() error {
+			if err := m.PurgeOldTable(oldTableName); err != nil {
+				return fmt.Errorf("failed to purge old table before cleanup: %w", err)
+			}
+			return nil
+		}})
 	}
-
 	// バッファプールサイズチェック（閾値が設定されている場合）
 	if m.config.Common.BufferPoolSizeThresholdMB > 0 {
-		dbName, err := m.extractDatabaseNameFromDSN()
-		if err != nil {
-			return fmt.Errorf("failed to extract database name from DSN: %w", err)
-		}
-
-		oldTableName := fmt.Sprintf("%s_old", tableName)
-		bufferPoolSizeMB, err := m.db.GetTableBufferPoolSizeMB(dbName, oldTableName)
-		if err != nil {
-			m.logger.Warnf("Failed to get buffer pool size for table %s: %v", oldTableName, err)
-		} else {
-			m.logger.Infof("Buffer pool size for table %s: %.2f MB (threshold: %.2f MB)",
-				oldTableName, bufferPoolSizeMB, m.config.Common.BufferPoolSizeThresholdMB)
-
-			if bufferPoolSizeMB > m.config.Common.BufferPoolSizeThresholdMB {
-				errMsg := fmt.Sprintf(
-					"buffer pool size (%.2f MB) exceeds threshold (%.2f MB) for table %s",
-					bufferPoolSizeMB, m.config.Common.BufferPoolSizeThresholdMB, oldTableName)
-				m.logger.Errorf("Buffer pool size check failed: %s", errMsg)
-				return fmt.Errorf("buffer pool size check failed: %s", errMsg)
-			}
-		}
+		checks = append(checks, preCheck{name: "buffer pool size", run: func() error { return m.checkBufferPoolSize(oldTableName) }})
+	}
+	checks = append(checks, m.dryRunEnvironmentChecks(oldTableName)...)
+	if err := m.runPreChecks("cleanup", tableName, checks); err != nil {
+		return err
 	}
 
 	dropSQL := fmt.Sprintf("DROP TABLE IF EXISTS %s_old", tableName)
@@ -668,7 +957,10 @@ func (m *Manager) CleanupOldTable(tableName string) error {
 		return nil
 	}
 
-	if err := m.db.ExecuteAlter(dropSQL); err != nil {
+	stopMonitor := m.startPhaseMonitor(PhaseCleanup, taskName, tableName, quotedQuery)
+	err := m.db.ExecuteAlter(dropSQL)
+	stopMonitor()
+	if err != nil {
 		if slackErr := m.slack.NotifyFailureWithQuery(taskName, tableName, quotedQuery, 0, err); slackErr != nil {
 			m.logger.Errorf("Failed to send failure notification: %v", slackErr)
 		}
@@ -684,7 +976,36 @@ func (m *Manager) CleanupOldTable(tableName string) error {
 	return nil
 }
 
+func (m *Manager) checkBufferPoolSize(oldTableName string) error {
+	dbName, err := m.extractDatabaseNameFromDSN()
+	if err != nil {
+		return fmt.Errorf("failed to extract database name from DSN: %w", err)
+	}
+
+	bufferPoolSizeMB, err := m.db.GetTableBufferPoolSizeMB(dbName, oldTableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get buffer pool size for table %s: %v", oldTableName, err)
+		return nil
+	}
+	m.logger.Infof("Buffer pool size for table %s: %.2f MB (threshold: %.2f MB)",
+		oldTableName, bufferPoolSizeMB, m.config.Common.BufferPoolSizeThresholdMB)
+
+	if bufferPoolSizeMB > m.config.Common.BufferPoolSizeThresholdMB {
+		errMsg := fmt.Sprintf(
+			"buffer pool size (%.2f MB) exceeds threshold (%.2f MB) for table %s",
+			bufferPoolSizeMB, m.config.Common.BufferPoolSizeThresholdMB, oldTableName)
+		m.logger.Errorf("Buffer pool size check failed: %s", errMsg)
+		return fmt.Errorf("buffer pool size check failed: %s", errMsg)
+	}
+	return nil
+}
+
 func (m *Manager) PurgeOldTable(tableName string) error {
+	return m.PurgeOldTableContext(context.Background(), tableName)
+}
+
+// PurgeOldTableContext は PurgeOldTable と同じく行を削除する。ctx が終わると実行中の pt-archiver も止める
+func (m *Manager) PurgeOldTableContext(ctx context.Context, tableName string) error {
 	m.logger.Infof("Starting purge for table %s using pt-archiver", tableName)
 
 	taskName := "pt-archiver"
@@ -692,6 +1013,14 @@ func (m *Manager) PurgeOldTable(tableName string) error {
 		taskName = "pt-archiver (DRY RUN)"
 	}
 
+	if err := m.checkConnectionHeadroom(taskName, tableName); err != nil {
+		return err
+	}
+
+	if err := m.checkScheduleConflicts("pt-archiver", tableName); err != nil {
+		return err
+	}
+
 	ptArchiverCommand := m.buildPtArchiverCommand(tableName)
 	cleanedCommand := strings.ReplaceAll(ptArchiverCommand, "`", "")
 	quotedCommand := fmt.Sprintf("`%s`", cleanedCommand)
@@ -700,9 +1029,34 @@ func (m *Manager) PurgeOldTable(tableName string) error {
 		m.logger.Errorf("Failed to send start notification: %v", err)
 	}
 
+	runCtx := ctx
+	taskCtx, cancel, err := m.taskContext(runCtx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	stopProgress, err := m.startArchiverProgress(tableName)
+	if err != nil {
+		return err
+	}
+
 	start := time.Now()
 
-	if err := m.ptarchiver.ExecutePurge(tableName, m.config.Common.PtArchiver, m.config.DSN, m.dryRun); err != nil {
+	stopMonitor := func() {}
+	if !m.dryRun {
+		stopMonitor = m.startPhaseMonitor(PhasePtArchiver, taskName, tableName, quotedCommand)
+	}
+	m.publishOperationEvent(events.PhaseStart, "pt-archiver", tableName, ptArchiverCommand, 0, 0, nil)
+	pacingSummary, err := m.executePurge(taskCtx, tableName)
+	stopMonitor()
+	stopProgress()
+	m.recordPurgeResult(tableName, ptArchiverCommand, start, err)
+	m.publishOperationEvent(events.PhaseEnd, "pt-archiver", tableName, ptArchiverCommand, m.archiverDeletedRows(), time.Since(start), err)
+	if err != nil {
+		if m.handleTimeout(runCtx, taskName, tableName, err, nil) {
+			return fmt.Errorf("pt-archiver timed out: %w", err)
+		}
 		if slackErr := m.slack.NotifyFailureWithQuery(taskName, tableName, quotedCommand, 0, err); slackErr != nil {
 			m.logger.Errorf("Failed to send failure notification: %v", slackErr)
 		}
@@ -710,23 +1064,22 @@ func (m *Manager) PurgeOldTable(tableName string) error {
 	}
 
 	duration := time.Since(start)
+	deletedRows := m.archiverDeletedRows()
 
 	var ptArchiverLog string
 	if ptArchiverExecutor, ok := m.ptarchiver.(*ptarchiver.PtArchiverExecutor); ok {
 		ptArchiverLog = ptArchiverExecutor.GetOutputSummary()
 	}
 
-	if ptArchiverLog != "" {
-		if err := m.slack.NotifySuccessWithQueryAndLog(taskName, tableName, quotedCommand, 0, duration, ptArchiverLog); err != nil {
-			m.logger.Errorf("Failed to send success notification: %v", err)
-		}
-	} else {
-		if err := m.slack.NotifySuccessWithQuery(taskName, tableName, quotedCommand, 0, duration); err != nil {
-			m.logger.Errorf("Failed to send success notification: %v", err)
-		}
+	if pacingSummary != "" {
+		ptArchiverLog = strings.TrimSpace(pacingSummary + "\n\n" + ptArchiverLog)
 	}
 
-	m.logger.Infof("Purge completed for table %s", tableName)
+	if err := m.slack.NotifyPurgeSuccess(taskName, tableName, quotedCommand, deletedRows, duration, ptArchiverLog); err != nil {
+		m.logger.Errorf("Failed to send success notification: %v", err)
+	}
+
+	m.logger.Infof("Purge completed for table %s (%d rows deleted)", tableName, deletedRows)
 	return nil
 }
 
@@ -751,7 +1104,19 @@ func (m *Manager) buildPtArchiverCommand(tableName string) string {
 		args = append(args, "--commit-each")
 	}
 
-	args = append(args, "--purge")
+	if cfg.TxnSize > 0 {
+		args = append(args, fmt.Sprintf("--txn-size=%d", cfg.TxnSize))
+	}
+
+	if cfg.Sleep > 0 {
+		args = append(args, fmt.Sprintf("--sleep=%d", cfg.Sleep))
+	}
+
+	if cfg.Retries > 0 {
+		args = append(args, fmt.Sprintf("--retries=%d", cfg.Retries))
+	}
+
+	args = append(args, "--purge", "--why-quit")
 
 	if cfg.Progress > 0 {
 		args = append(args, fmt.Sprintf("--progress=%d", cfg.Progress))
@@ -761,6 +1126,23 @@ func (m *Manager) buildPtArchiverCommand(tableName string) string {
 		args = append(args, fmt.Sprintf("--max-lag=%f", cfg.MaxLag))
 	}
 
+	// 実行時と同じ変換を使い、通知するコマンドと実際に渡す値をそろえる。不正な値は実行時にエラーになる
+	if cfg.CheckInterval != "" {
+		if arg, err := ptarchiver.CheckIntervalArg(cfg.CheckInterval); err == nil {
+			args = append(args, arg)
+		} else {
+			args = append(args, fmt.Sprintf("--check-interval=%s", cfg.CheckInterval))
+		}
+	}
+
+	if cfg.RunTime != "" {
+		if arg, err := ptarchiver.RunTimeArg(cfg.RunTime); err == nil {
+			args = append(args, arg)
+		} else {
+			args = append(args, fmt.Sprintf("--run-time=%s", cfg.RunTime))
+		}
+	}
+
 	if cfg.NoCheckCharset {
 		args = append(args, "--no-check-charset")
 	}
@@ -811,7 +1193,10 @@ func (m *Manager) CleanupNewTable(tableName string) error {
 		return nil
 	}
 
-	if err := m.db.ExecuteAlter(dropSQL); err != nil {
+	stopMonitor := m.startPhaseMonitor(PhaseCleanup, taskName, tableName, quotedQuery)
+	err := m.db.ExecuteAlter(dropSQL)
+	stopMonitor()
+	if err != nil {
 		if slackErr := m.slack.NotifyFailureWithQuery(taskName, tableName, quotedQuery, 0, err); slackErr != nil {
 			m.logger.Errorf("Failed to send failure notification: %v", slackErr)
 		}
@@ -827,18 +1212,18 @@ func (m *Manager) CleanupNewTable(tableName string) error {
 	return nil
 }
 
+// CleanupTriggers はテーブルに実際に定義されている pt-osc のトリガー (pt_osc_ で始まるもの) を削除する。
+// pt-osc 以外のトリガーは削除せず、見つかったことを通知する
 func (m *Manager) CleanupTriggers(tableName string) error {
 	m.logger.Infof("Starting trigger cleanup for table %s", tableName)
 
-	dbName, err := m.extractDatabaseNameFromDSN()
+	triggers, err := m.findPtOscTriggers(tableName)
 	if err != nil {
-		return fmt.Errorf("failed to extract database name from DSN: %w", err)
+		return err
 	}
-
-	triggers := []string{
-		fmt.Sprintf("pt_osc_%s_%s_del", dbName, tableName),
-		fmt.Sprintf("pt_osc_%s_%s_upd", dbName, tableName),
-		fmt.Sprintf("pt_osc_%s_%s_ins", dbName, tableName),
+	if len(triggers) == 0 {
+		m.logger.Infof("No pt-osc triggers found on table %s", tableName)
+		return nil
 	}
 
 	taskName := "trigger-cleanup"
@@ -885,12 +1270,47 @@ func (m *Manager) CleanupTriggers(tableName string) error {
 	return nil
 }
 
-func (m *Manager) checkOtherActiveConnections(taskName, tableName string) error {
+// findPtOscTriggers は information_schema.TRIGGERS からテーブルの pt-osc のトリガーを探す。
+// 一覧を取れなかった場合は pt-osc と同じ規則で計算した名前を返し、DROP TRIGGER IF EXISTS で削除を試みる
+func (m *Manager) findPtOscTriggers(tableName string) ([]string, error) {
+	names, err := m.db.GetTriggerNames(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to list triggers of %s, dropping the trigger names pt-osc would use: %v", tableName, err)
+		dbName, dsnErr := m.extractDatabaseNameFromDSN()
+		if dsnErr != nil {
+			return nil, fmt.Errorf("failed to extract database name from DSN: %w", dsnErr)
+		}
+		return ptosc.TriggerNames(dbName, tableName), nil
+	}
+
+	var triggers, others []string
+	for _, name := range names {
+		if strings.HasPrefix(name, ptOscTriggerPrefix) {
+			triggers = append(triggers, name)
+		} else {
+			others = append(others, name)
+		}
+	}
+
+	if len(others) > 0 {
+		message := fmt.Sprintf("Table %s has triggers not created by pt-osc, which were left in place: %s", tableName, strings.Join(others, ", "))
+		m.logger.Warn(message)
+		if err := m.slack.NotifyWarning("trigger-cleanup", tableName, message); err != nil {
+			m.logger.Errorf("Failed to send warning notification: %v", err)
+		}
+	}
+	return triggers, nil
+}
+
+func (m *Manager) checkOtherActiveConnections(ctx context.Context, taskName, tableName string) error {
 	if !m.config.Common.ConnectionCheck.Enabled {
 		return nil
 	}
+	if m.config.Common.ConnectionCheck.WaitTimeout != "" {
+		return m.waitForQuiesce(ctx, taskName, tableName)
+	}
 
-	hasOthers, username, err := m.db.HasOtherActiveConnections()
+	hasOthers, username, err := m.db.HasOtherActiveConnections(m.lockConnectionIDs)
 	if err != nil {
 		return fmt.Errorf("failed to check active connections: %w", err)
 	}
@@ -903,7 +1323,7 @@ func (m *Manager) checkOtherActiveConnections(taskName, tableName string) error
 			m.logger.Errorf("Failed to send connection check failure notification: %v", slackErr)
 		}
 
-		return fmt.Errorf("%s", errMsg)
+		return fmt.Errorf("%w: %s", ErrConnectionCheckFailed, errMsg)
 	}
 
 	return nil
@@ -923,7 +1343,7 @@ func (m *Manager) checkNewTableExists(taskName, tableName string) error {
 			m.logger.Errorf("Failed to send pt-osc pre-check failure notification: %v", slackErr)
 		}
 
-		return fmt.Errorf("%s", errMsg)
+		return fmt.Errorf("%w: _%s_new", ErrNewTableExists, tableName)
 	}
 
 	return nil
@@ -942,6 +1362,10 @@ func (m *Manager) checkRowCountDifference(tableName string) error {
 
 	m.logger.Infof("Row count comparison for %s: original=%d, new=%d", tableName, originalCount, newCount)
 
+	if expected, ok := m.expectedRowDelta(tableName); ok {
+		return m.checkExpectedRowDelta(tableName, originalCount, newCount, expected)
+	}
+
 	var diffPercent float64
 
 	if originalCount >= newCount {
@@ -952,7 +1376,7 @@ func (m *Manager) checkRowCountDifference(tableName string) error {
 		diffPercent = float64(newCount-originalCount) / float64(newCount) * 100
 	}
 
-	threshold := 5.0 // 5%の閾値をハードコーディング
+	threshold := rowCountDiffThresholdPercent
 	if diffPercent > threshold {
 		errMsg := fmt.Sprintf("row count difference exceeds threshold: %.2f%% (threshold: %.2f%%), original=%d, new=%d",
 			diffPercent, threshold, originalCount, newCount)
@@ -968,7 +1392,7 @@ func (m *Manager) checkRowCountDifference(tableName string) error {
 			m.logger.Errorf("Failed to send row count check warning notification: %v", slackErr)
 		}
 
-		return fmt.Errorf("row count check failed: %s", errMsg)
+		return fmt.Errorf("%w: %s", ErrRowCountMismatch, errMsg)
 	}
 
 	m.logger.Infof("Row count check passed for table %s: difference=%.2f%% (threshold: %.2f%%)",
@@ -0,0 +1,85 @@
+package task
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	oldTablePolicyAbort   = "abort"
+	oldTablePolicySuffix  = "suffix"
+	oldTablePolicyCleanup = "cleanup"
+
+	// MySQL のテーブル名の最大長
+	maxTableNameLength = 64
+)
+
+func (m *Manager) oldTablePolicy() (string, error) {
+	policy := m.config.Common.SwapOldTable.Policy
+	switch policy {
+	case "":
+		return oldTablePolicyAbort, nil
+	case oldTablePolicyAbort, oldTablePolicySuffix, oldTablePolicyCleanup:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid swap_old_table.policy %q (must be abort, suffix or cleanup)", policy)
+	}
+}
+
+// oldTableSwapPlan は swap で元のテーブルを RENAME する先の名前と、その前に既存の _old テーブルを削除するかどうか
+type oldTableSwapPlan struct {
+	name         string
+	dropExisting bool
+}
+
+// planOldTable は前回の移行の _old テーブルが残っていないかを確認し、swap_old_table.policy に従って
+// 元のテーブルの RENAME 先を決める。残ったまま RENAME すると文の途中で失敗するため、事前に検出する。
+func (m *Manager) planOldTable(tableName string, now time.Time) (*oldTableSwapPlan, error) {
+	policy, err := m.oldTablePolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &oldTableSwapPlan{name: fmt.Sprintf("%s_old", tableName)}
+	exists, err := m.db.TableExists(plan.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check old table existence: %w", err)
+	}
+	if !exists {
+		return plan, nil
+	}
+
+	var message string
+	switch policy {
+	case oldTablePolicySuffix:
+		suffixed := fmt.Sprintf("%s_%s", plan.name, now.Format("20060102150405"))
+		if len(suffixed) > maxTableNameLength {
+			return nil, fmt.Errorf("%s already exists and %s is longer than %d characters; drop it with cleanup --drop-table",
+				plan.name, suffixed, maxTableNameLength)
+		}
+		suffixedExists, err := m.db.TableExists(suffixed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check old table existence: %w", err)
+		}
+		if suffixedExists {
+			return nil, fmt.Errorf("both %s and %s already exist", plan.name, suffixed)
+		}
+		message = fmt.Sprintf("%s already exists from a previous migration; %s will be renamed to %s instead", plan.name, tableName, suffixed)
+		plan.name = suffixed
+	case oldTablePolicyCleanup:
+		message = fmt.Sprintf("%s already exists from a previous migration and will be dropped before the swap", plan.name)
+		plan.dropExisting = true
+	default:
+		return nil, fmt.Errorf("%s already exists from a previous migration; drop it with cleanup --drop-table or set swap_old_table.policy", plan.name)
+	}
+
+	m.logger.Warn(message)
+	taskName := "swap-old-table-check"
+	if m.dryRun {
+		taskName = "swap-old-table-check (DRY RUN)"
+	}
+	if err := m.slack.NotifyWarning(taskName, tableName, message); err != nil {
+		m.logger.Errorf("Failed to send old table check warning notification: %v", err)
+	}
+	return plan, nil
+}
@@ -0,0 +1,88 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanOldTable(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		policy      string
+		oldExists   bool
+		want        *oldTableSwapPlan
+		errContains string
+	}{
+		{name: "no old table", oldExists: false, want: &oldTableSwapPlan{name: "users_old"}},
+		{name: "abort by default", oldExists: true, errContains: "users_old already exists"},
+		{name: "suffix", policy: "suffix", oldExists: true, want: &oldTableSwapPlan{name: "users_old_20261018093000"}},
+		{name: "cleanup", policy: "cleanup", oldExists: true, want: &oldTableSwapPlan{name: "users_old", dropExisting: true}},
+		{name: "invalid policy", policy: "rename", oldExists: true, errContains: "invalid swap_old_table.policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			mockDB := &MockDBClient{}
+			mockDB.On("TableExists", "users_old").Return(tt.oldExists, nil).Maybe()
+			mockDB.On("TableExists", "users_old_20261018093000").Return(false, nil).Maybe()
+			mockSlack := &MockSlackNotifier{}
+			mockSlack.On("NotifyWarning", "swap-old-table-check", "users", mock.Anything).Return(nil).Maybe()
+
+			cfg := &config.Config{Common: config.CommonConfig{SwapOldTable: config.SwapOldTableConfig{Policy: tt.policy}}}
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+			plan, err := manager.planOldTable("users", now)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, plan)
+		})
+	}
+}
+
+func TestSwapTable_SuffixesExistingOldTable(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "users").Return(true, nil)
+	mockDB.On("TableExists", "_users_new").Return(true, nil)
+	mockDB.On("TableExists", "users_old").Return(true, nil)
+	mockDB.On("TableExists", mock.MatchedBy(func(name string) bool { return len(name) == len("users_old_20261018093000") })).Return(false, nil)
+	mockDB.On("GetTableRowCountForSwap", "users").Return(int64(1000), nil)
+	mockDB.On("GetNewTableRowCountForSwap", "users").Return(int64(1000), nil)
+	mockDB.On("GetTableDependencies", "users").Return(nil, nil)
+	mockDB.On("GetTableCharsetInfo", mock.Anything).Return(&database.TableCharsetInfo{}, nil)
+	mockDB.On("SetSessionConfig", 0, 0).Return(nil)
+	var swapSQL string
+	mockDB.On("ExecuteAlter", mock.Anything).Run(func(args mock.Arguments) { swapSQL = args.String(0) }).Return(nil)
+	mockDB.On("GetAutoIncrementColumn", "users").Return("", nil)
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyWarning", "swap-old-table-check", "users", mock.Anything).Return(nil)
+	mockSlack.On("NotifyStartWithQuery", "swap", "users", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifySuccessWithQuery", "swap", "users", mock.Anything, int64(0), mock.Anything).Return(nil)
+
+	cfg := &config.Config{Common: config.CommonConfig{
+		DisableAnalyzeTable: true,
+		SwapOldTable:        config.SwapOldTableConfig{Policy: "suffix"},
+	}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	require.NoError(t, manager.SwapTable("users"))
+	assert.Regexp(t, `^RENAME TABLE users TO users_old_\d{14}, _users_new TO users$`, swapSQL)
+}
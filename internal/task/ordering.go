@@ -0,0 +1,188 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/config"
+)
+
+// orderTasks は depends_on に従ってタスクをトポロジカル順に並べる。
+// 依存関係で順序が決まらないタスク同士はファイルに書かれた順を保つ。
+// 名前の重複、存在しないタスクへの依存、循環があればエラーにする。
+func orderTasks(tasks []config.TaskDefinition) ([]config.TaskDefinition, error) {
+	index := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		if _, exists := index[task.Name]; exists {
+			return nil, fmt.Errorf("duplicate task name %q", task.Name)
+		}
+		index[task.Name] = i
+	}
+	for _, task := range tasks {
+		for _, dependency := range task.DependsOn {
+			if _, exists := index[dependency]; !exists {
+				return nil, fmt.Errorf("task %s depends on unknown task %q", task.Name, dependency)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(tasks))
+	ordered := make([]config.TaskDefinition, 0, len(tasks))
+	var path []string
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for j, name := range path {
+				if name == tasks[i].Name {
+					start = j
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), tasks[i].Name)
+			return fmt.Errorf("dependency cycle between tasks: %s", strings.Join(cycle, " -> "))
+		}
+		state[i] = visiting
+		path = append(path, tasks[i].Name)
+		for _, dependency := range tasks[i].DependsOn {
+			if err := visit(index[dependency]); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		ordered = append(ordered, tasks[i])
+		return nil
+	}
+
+	// ファイル順に深さ優先で辿り、依存先を先に並べる
+	for i := range tasks {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// parseConfiguredQueries は設定のクエリを解析する。v2 形式のタスクがあれば依存関係の順に並べ、
+// 各クエリにタスク名を付けて、テーブルごとのまとめ方が依存関係を崩さないようにする。
+func (m *Manager) parseConfiguredQueries() ([]QueryInfo, error) {
+	if len(m.config.Tasks) == 0 {
+		return m.parseQueries(m.config.Queries)
+	}
+
+	ordered, err := orderTasks(m.config.Tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []QueryInfo
+	hasTableStatement := make(map[string]bool)
+	for _, task := range ordered {
+		statements, err := config.SplitStatements(task.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse query of task %s: %w", task.Name, err)
+		}
+		queries, err := m.parseQueries(statements)
+		if err != nil {
+			return nil, fmt.Errorf("task %s: %w", task.Name, err)
+		}
+		for i := range queries {
+			queries[i].TaskName = task.Name
+			if task.Batch != nil && !*task.Batch {
+				queries[i].Isolated = true
+			}
+			if len(task.IgnoreErrors) > 0 {
+				queries[i].IgnoredErrors = append(append([]int{}, task.IgnoreErrors...), queries[i].IgnoredErrors...)
+			}
+			if queries[i].TableName != "" {
+				hasTableStatement[task.Name] = true
+			}
+		}
+		result = append(result, queries...)
+	}
+
+	m.taskDependencies = make(map[string]map[string]bool, len(ordered))
+	for _, task := range ordered {
+		dependencies := make(map[string]bool)
+		for _, dependency := range task.DependsOn {
+			// テーブル指定のないクエリは全テーブルの後に実行するため、それを前提にする順序は守れない
+			if !hasTableStatement[dependency] {
+				return nil, fmt.Errorf("task %s depends on %s, which has no table statement; statements without a table run after all table changes", task.Name, dependency)
+			}
+			dependencies[dependency] = true
+			// ordered は依存先が先に並んでいるため、依存先の推移的な依存関係は計算済み
+			for transitive := range m.taskDependencies[dependency] {
+				dependencies[transitive] = true
+			}
+		}
+		m.taskDependencies[task.Name] = dependencies
+	}
+	return result, nil
+}
+
+// mustStartNewGroup は既存のテーブルのグループにクエリをまとめると依存関係の順序が崩れる場合に true を返す。
+// 依存先がそのグループより後のグループで実行される場合と、ALTER 以外の文が同じグループ内の依存先に依存する場合が該当する。
+// (グループ内では ALTER 以外の文が ALTER より先に実行されるため)
+func (m *Manager) mustStartNewGroup(query QueryInfo, groupIndex int, taskGroups map[string]int) bool {
+	for dependency := range m.taskDependencies[query.TaskName] {
+		dependencyGroup, ok := taskGroups[dependency]
+		if !ok {
+			continue
+		}
+		if dependencyGroup > groupIndex || (dependencyGroup == groupIndex && query.QueryType != "ALTER") {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfiguredTables はタスクファイルの ALTER 対象のテーブルを実行順に重複なく返す。
+// cleanup / swap --from-tasks が、リリースで pt-osc の対象になり得たテーブルを一度に扱うために使う
+func (m *Manager) ConfiguredTables() ([]string, error) {
+	queries, err := m.parseConfiguredQueries()
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []string
+	seen := make(map[string]bool)
+	for _, query := range queries {
+		if query.QueryType != "ALTER" || query.TableName == "" || seen[query.TableName] {
+			continue
+		}
+		seen[query.TableName] = true
+		tables = append(tables, query.TableName)
+	}
+	return tables, nil
+}
+
+// ConfiguredTablesWithTable は ConfiguredTables のうち、pt-osc が作る _<table><suffix> テーブルが存在するものだけを返す。
+// 直接 ALTER したテーブルや、まだ pt-osc を実行していないテーブルは skipped として返す。
+// swap --from-tasks は _new、cleanup --from-tasks は _old があるテーブルだけを扱うために使う
+func (m *Manager) ConfiguredTablesWithTable(suffix string) (tables, skipped []string, err error) {
+	configured, err := m.ConfiguredTables()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, table := range configured {
+		exists, err := m.db.TableExists(fmt.Sprintf("_%s%s", table, suffix))
+		if err != nil {
+			return nil, nil, err
+		}
+		if exists {
+			tables = append(tables, table)
+		} else {
+			skipped = append(skipped, table)
+		}
+	}
+	return tables, skipped, nil
+}
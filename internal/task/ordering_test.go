@@ -0,0 +1,146 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func taskNames(tasks []config.TaskDefinition) []string {
+	names := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		names = append(names, task.Name)
+	}
+	return names
+}
+
+func TestOrderTasks(t *testing.T) {
+	ordered, err := orderTasks([]config.TaskDefinition{
+		{Name: "add-fk", DependsOn: []string{"create-users", "create-orders"}},
+		{Name: "unrelated"},
+		{Name: "create-orders"},
+		{Name: "create-users"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"create-users", "create-orders", "add-fk", "unrelated"}, taskNames(ordered))
+
+	_, err = orderTasks([]config.TaskDefinition{
+		{Name: "a", DependsOn: []string{"b"}},
+		{Name: "b", DependsOn: []string{"c"}},
+		{Name: "c", DependsOn: []string{"b"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "b -> c -> b")
+
+	_, err = orderTasks([]config.TaskDefinition{{Name: "a", DependsOn: []string{"missing"}}})
+	assert.ErrorContains(t, err, "unknown task")
+
+	_, err = orderTasks([]config.TaskDefinition{{Name: "a"}, {Name: "a"}})
+	assert.ErrorContains(t, err, "duplicate task name")
+}
+
+func TestGroupQueriesByTable_Dependencies(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{
+		Tasks: []config.TaskDefinition{
+			{Name: "orders-column", Query: "ALTER TABLE orders ADD COLUMN user_id INT"},
+			{Name: "create-users", Query: "CREATE TABLE users (id INT PRIMARY KEY)"},
+			{Name: "orders-fk", Query: "ALTER TABLE orders ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id)", DependsOn: []string{"create-users", "orders-column"}},
+		},
+	}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	queries, err := manager.parseConfiguredQueries()
+	require.NoError(t, err)
+	groups := manager.groupQueriesByTable(queries)
+
+	// 外部キーの ALTER は users の作成より後に実行するため、orders のグループが分かれる
+	require.Len(t, groups, 3)
+	assert.Equal(t, "orders", groups[0].TableName)
+	assert.Equal(t, []string{"ADD COLUMN user_id INT"}, groups[0].AlterParts)
+	assert.Equal(t, "users", groups[1].TableName)
+	assert.Equal(t, "orders", groups[2].TableName)
+	assert.Equal(t, []int{2}, groups[2].queryIndexes)
+
+	cfg.Tasks[0].DependsOn = []string{"orders-fk"}
+	_, err = manager.parseConfiguredQueries()
+	assert.ErrorContains(t, err, "dependency cycle")
+}
+
+func TestGroupQueriesByTable_DependenciesWithinGroup(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{
+		Tasks: []config.TaskDefinition{
+			{Name: "add-column", Query: "ALTER TABLE users ADD COLUMN age INT"},
+			{Name: "add-index", Query: "ALTER TABLE users ADD INDEX idx_age (age)", DependsOn: []string{"add-column"}},
+		},
+	}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	queries, err := manager.parseConfiguredQueries()
+	require.NoError(t, err)
+	groups := manager.groupQueriesByTable(queries)
+
+	// 同じテーブルの ALTER 同士は1回の ALTER にまとめても順序が保たれる
+	require.Len(t, groups, 1)
+	assert.Equal(t, []string{"ADD COLUMN age INT", "ADD INDEX idx_age (age)"}, groups[0].AlterParts)
+}
+
+func TestConfiguredTables(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{
+		Queries: []string{
+			"ALTER TABLE orders ADD COLUMN user_id INT",
+			"CREATE TABLE users (id INT PRIMARY KEY)",
+			"ALTER TABLE events_[0-1] ADD INDEX idx_created_at (created_at)",
+			"ALTER TABLE orders ADD INDEX idx_user_id (user_id)",
+		},
+	}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	tables, err := manager.ConfiguredTables()
+	require.NoError(t, err)
+	// CREATE TABLE の対象は pt-osc を使わないため含めない
+	assert.Equal(t, []string{"orders", "events_0", "events_1"}, tables)
+}
+
+func TestConfiguredTablesWithTable(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// pt-osc を使ったテーブルと直接 ALTER したテーブルが混在するタスクファイル
+	cfg := &config.Config{
+		Queries: []string{
+			"ALTER TABLE users ADD COLUMN age INT",
+			"ALTER TABLE orders ADD INDEX idx_user_id (user_id)",
+			"ALTER TABLE events ADD COLUMN note TEXT",
+		},
+	}
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "_users_new").Return(true, nil)
+	mockDB.On("TableExists", "_orders_new").Return(false, nil)
+	mockDB.On("TableExists", "_events_new").Return(false, nil)
+	mockDB.On("TableExists", "_users_old").Return(false, nil)
+	mockDB.On("TableExists", "_orders_old").Return(false, nil)
+	mockDB.On("TableExists", "_events_old").Return(true, nil)
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	tables, skipped, err := manager.ConfiguredTablesWithTable("_new")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users"}, tables)
+	assert.Equal(t, []string{"orders", "events"}, skipped)
+
+	tables, skipped, err = manager.ConfiguredTablesWithTable("_old")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"events"}, tables)
+	assert.Equal(t, []string{"users", "orders"}, skipped)
+}
@@ -0,0 +1,36 @@
+package task
+
+import (
+	"github.com/pyama86/alterguard/internal/ptosc"
+	"github.com/pyama86/alterguard/internal/slack"
+)
+
+// ptOscCopyStats は直近の pt-osc の出力から読み取った行コピーの性能を返す。取得できない場合は nil を返す。
+func (m *Manager) ptOscCopyStats() *ptosc.CopyStats {
+	ptOscExecutor, ok := m.ptosc.(*ptosc.PtOscExecutor)
+	if !ok {
+		return nil
+	}
+	stats := ptOscExecutor.GetCopyStats()
+	return &stats
+}
+
+// logCopyPerformance は行コピーの速度とスロットリングの状況をログに出し、完了通知用に変換する
+func (m *Manager) logCopyPerformance(tableName string, stats *ptosc.CopyStats) *slack.CopyPerformance {
+	if stats == nil {
+		return nil
+	}
+
+	throttled := stats.Throttled()
+	if throttled == "" {
+		m.logger.Infof("pt-osc copy rate for %s: %.0f rows/sec, not throttled", tableName, stats.RowsPerSecond())
+	} else {
+		m.logger.Infof("pt-osc copy rate for %s: %.0f rows/sec, throttled by %s", tableName, stats.RowsPerSecond(), throttled)
+	}
+
+	return &slack.CopyPerformance{
+		RowsPerSecond: stats.RowsPerSecond(),
+		CopyDuration:  stats.CopyDuration,
+		Throttled:     throttled,
+	}
+}
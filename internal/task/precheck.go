@@ -0,0 +1,141 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// preCheck は swap / cleanup の前に行う読み取りだけの確認
+type preCheck struct {
+	name string
+	run  func() error
+	// 失敗すると後続の確認が意味をなさない (テーブルが存在しないなど)
+	prerequisite bool
+	// dry run でだけ行う確認。本番の実行を止めはしないが、ロック待ちなどで失敗する原因になる
+	dryRunOnly bool
+}
+
+type preCheckResult struct {
+	name    string
+	err     error
+	skipped bool
+}
+
+// runPreChecks は事前チェックを順に実行する。本番では最初に失敗したチェックのエラーを返す。
+// dry run では失敗しても残りのチェックを続け、本番で通るかどうかをまとめて報告する。
+func (m *Manager) runPreChecks(operation, tableName string, checks []preCheck) error {
+	if !m.dryRun {
+		for _, check := range checks {
+			if check.dryRunOnly {
+				continue
+			}
+			if err := check.run(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	results := make([]preCheckResult, 0, len(checks))
+	var failures []string
+	var failureErrs []error
+	blocked := false
+	for _, check := range checks {
+		result := preCheckResult{name: check.name, skipped: blocked}
+		if !blocked {
+			result.err = check.run()
+		}
+		if result.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", check.name, result.err))
+			failureErrs = append(failureErrs, result.err)
+			blocked = check.prerequisite
+		}
+		results = append(results, result)
+	}
+
+	summary := formatPreChecks(results)
+	m.logger.Infof("[DRY RUN] Pre-checks for %s on %s:\n%s", operation, tableName, summary)
+	if err := m.slack.NotifyDryRunPreChecks(operation, tableName, summary, len(failures) > 0); err != nil {
+		m.logger.Errorf("Failed to send dry run pre-check notification: %v", err)
+	}
+
+	if len(failures) > 0 {
+		return &preCheckFailures{
+			message: fmt.Sprintf("dry run: %d of %d %s pre-checks on %s would fail: %s",
+				len(failures), len(checks), operation, tableName, strings.Join(failures, "; ")),
+			errs: failureErrs,
+		}
+	}
+	return nil
+}
+
+// preCheckFailures は dry run で失敗した事前確認をまとめたエラー。errors.Is で個々の失敗の種類を判別できる
+type preCheckFailures struct {
+	message string
+	errs    []error
+}
+
+func (e *preCheckFailures) Error() string {
+	return e.message
+}
+
+func (e *preCheckFailures) Unwrap() []error {
+	return e.errs
+}
+
+func formatPreChecks(results []preCheckResult) string {
+	var b strings.Builder
+	for _, result := range results {
+		switch {
+		case result.skipped:
+			fmt.Fprintf(&b, "SKIP %s (an earlier check failed)\n", result.name)
+		case result.err != nil:
+			fmt.Fprintf(&b, "FAIL %s\n     %v\n", result.name, result.err)
+		default:
+			fmt.Fprintf(&b, "OK   %s\n", result.name)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// checkMetadataLockBlockers はテーブルのメタデータロックを保持しているセッションがあれば失敗する。
+// RENAME や DROP はそれらのセッションが終わるか lock_wait_timeout まで待たされる。
+func (m *Manager) checkMetadataLockBlockers(tableName string) error {
+	sessions, err := m.db.GetBlockingSessions(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to check metadata locks on %s: %w", tableName, err)
+	}
+	if len(sessions) == 0 {
+		return nil
+	}
+	descriptions := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		descriptions = append(descriptions, describeSession(session))
+	}
+	return fmt.Errorf("%d sessions hold metadata locks on %s and would block it until lock_wait_timeout: %s",
+		len(sessions), tableName, strings.Join(descriptions, "; "))
+}
+
+// checkAuroraReplicaLag は aurora_replica_check の max_lag_ms をレプリカの遅延が超えていれば失敗する
+func (m *Manager) checkAuroraReplicaLag() error {
+	lagMs, err := m.db.GetMaxAuroraReplicaLagMs()
+	if err != nil {
+		return fmt.Errorf("failed to get Aurora replica lag: %w", err)
+	}
+	maxLagMs := m.config.Common.PtOsc.AuroraReplicaCheck.MaxLagMs
+	if maxLagMs > 0 && lagMs > maxLagMs {
+		return fmt.Errorf("Aurora replica lag is %.0f ms (max_lag_ms: %.0f)", lagMs, maxLagMs)
+	}
+	return nil
+}
+
+// dryRunEnvironmentChecks は swap / cleanup の dry run で追加する、サーバーの状態の確認
+func (m *Manager) dryRunEnvironmentChecks(tableName string) []preCheck {
+	checks := []preCheck{
+		{name: metadataLockBlockersCheck, dryRunOnly: true, run: func() error { return m.checkMetadataLockBlockers(tableName) }},
+	}
+	if m.config.Common.PtOsc.AuroraReplicaCheck.Enabled {
+		checks = append(checks, preCheck{name: "replica lag", dryRunOnly: true, run: m.checkAuroraReplicaLag})
+	}
+	return checks
+}
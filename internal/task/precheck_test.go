@@ -0,0 +1,106 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapTable_DryRunReportsEveryPreCheck(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "users").Return(true, nil)
+	mockDB.On("TableExists", "_users_new").Return(true, nil)
+	mockDB.On("TableExists", "users_old").Return(false, nil)
+	mockDB.On("GetTableRowCountForSwap", "users").Return(int64(1000), nil)
+	mockDB.On("GetNewTableRowCountForSwap", "users").Return(int64(500), nil)
+	mockDB.On("GetTableDependencies", "users").Return(nil, nil)
+	mockDB.On("GetBlockingSessions", "users").Return([]database.BlockingSession{
+		{ID: 10, User: "app", Command: "Sleep", Time: 600, LockTypes: "SHARED_READ"},
+	}, nil)
+	mockDB.On("GetMaxAuroraReplicaLagMs").Return(50.0, nil)
+
+	var summary string
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyWarning", "swap-row-count-check (DRY RUN)", "users", mock.Anything).Return(nil)
+	mockSlack.On("NotifyDryRunPreChecks", "swap", "users", mock.Anything, true).Run(func(args mock.Arguments) {
+		summary = args.String(2)
+	}).Return(nil)
+
+	cfg := &config.Config{Common: config.CommonConfig{PtOsc: config.PtOscConfig{
+		AuroraReplicaCheck: config.AuroraReplicaCheckConfig{Enabled: true, MaxLagMs: 1000},
+	}}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, true)
+
+	err := manager.SwapTable("users")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 of 10 swap pre-checks on users would fail")
+	assert.Contains(t, err.Error(), "row count check failed")
+	assert.ErrorIs(t, err, ErrRowCountMismatch)
+	assert.Contains(t, summary, "OK   new table exists")
+	assert.Contains(t, summary, "FAIL row count")
+	assert.Contains(t, summary, "FAIL metadata lock blockers")
+	assert.Contains(t, summary, "OK   replica lag")
+	assert.Contains(t, summary, "OK   scheduled jobs")
+	mockDB.AssertNotCalled(t, "ExecuteAlter", mock.Anything)
+	mockDB.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestSwapTable_DryRunSkipsChecksAfterMissingTable(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "users").Return(true, nil)
+	mockDB.On("TableExists", "_users_new").Return(false, nil)
+
+	var summary string
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyDryRunPreChecks", "swap", "users", mock.Anything, true).Run(func(args mock.Arguments) {
+		summary = args.String(2)
+	}).Return(nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, true)
+
+	err := manager.SwapTable("users")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "new table _users_new does not exist")
+	assert.Contains(t, summary, "SKIP row count")
+	assert.Contains(t, summary, "SKIP metadata lock blockers")
+	mockDB.AssertNotCalled(t, "GetTableRowCountForSwap", mock.Anything)
+}
+
+func TestCleanupOldTable_DryRunReportsBufferPoolFailure(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableDependencies", "users_old").Return(nil, nil)
+	mockDB.On("GetTableBufferPoolSizeMB", "app", "users_old").Return(500.0, nil)
+	mockDB.On("GetBlockingSessions", "users_old").Return(nil, nil)
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyDryRunPreChecks", "cleanup", "users", mock.Anything, true).Return(nil)
+
+	cfg := &config.Config{
+		DSN:    "user:pass@tcp(localhost:3306)/app",
+		Common: config.CommonConfig{BufferPoolSizeThresholdMB: 100},
+	}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, true)
+
+	err := manager.CleanupOldTable("users")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "buffer pool size check failed")
+	mockDB.AssertExpectations(t)
+	mockSlack.AssertNotCalled(t, "NotifyStartWithQuery", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
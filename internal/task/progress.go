@@ -0,0 +1,41 @@
+package task
+
+// RunProgress は ExecuteAllTasks の進捗。テーブル単位で更新される。
+type RunProgress struct {
+	CompletedTables int
+	TotalTables     int
+	// 事前に取得できたテーブルごとの行数
+	RowCounts map[string]int64
+	// 完了したテーブルごとの実行方法 (alter-table, pt-osc など)
+	Methods map[string]string
+}
+
+// SetProgressFunc を設定すると、ExecuteAllTasks の開始時とテーブルの変更が終わるたびに呼ばれる
+func (m *Manager) SetProgressFunc(progress func(RunProgress)) {
+	m.progress = progress
+}
+
+func (m *Manager) reportProgress(groups []*TableGroup, completed int) {
+	if m.progress == nil {
+		return
+	}
+
+	progress := RunProgress{
+		CompletedTables: completed,
+		TotalTables:     len(groups),
+		RowCounts:       make(map[string]int64),
+		Methods:         make(map[string]string),
+	}
+	for i, group := range groups {
+		// 実行済みのテーブルは書き込みでキャッシュが捨てられているため、実行時の行数を使う
+		if i < completed && group.RowCount > 0 {
+			progress.RowCounts[group.TableName] = group.RowCount
+		} else if count, ok := m.rowCounts[group.TableName]; ok {
+			progress.RowCounts[group.TableName] = count
+		}
+		if i < completed && group.Method != "" {
+			progress.Methods[group.TableName] = group.Method
+		}
+	}
+	m.progress(progress)
+}
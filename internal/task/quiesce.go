@@ -0,0 +1,122 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/database"
+)
+
+const (
+	defaultQuiescePollInterval   = 10 * time.Second
+	defaultQuiesceNotifyInterval = time.Minute
+)
+
+type quiesceSettings struct {
+	timeout        time.Duration
+	pollInterval   time.Duration
+	notifyInterval time.Duration
+}
+
+func (m *Manager) resolveQuiesceSettings() (*quiesceSettings, error) {
+	check := m.config.Common.ConnectionCheck
+	settings := &quiesceSettings{pollInterval: defaultQuiescePollInterval, notifyInterval: defaultQuiesceNotifyInterval}
+
+	timeout, err := time.ParseDuration(check.WaitTimeout)
+	if err != nil || timeout <= 0 {
+		return nil, fmt.Errorf("invalid connection_check.wait_timeout %q", check.WaitTimeout)
+	}
+	settings.timeout = timeout
+	if check.PollInterval != "" {
+		if settings.pollInterval, err = time.ParseDuration(check.PollInterval); err != nil || settings.pollInterval <= 0 {
+			return nil, fmt.Errorf("invalid connection_check.poll_interval %q", check.PollInterval)
+		}
+	}
+	if check.NotifyInterval != "" {
+		if settings.notifyInterval, err = time.ParseDuration(check.NotifyInterval); err != nil || settings.notifyInterval <= 0 {
+			return nil, fmt.Errorf("invalid connection_check.notify_interval %q", check.NotifyInterval)
+		}
+	}
+	return settings, nil
+}
+
+// waitForQuiesce は同じユーザーの他のセッションがいなくなるまで connection_check.wait_timeout まで待つ。
+// 待っている間は notify_interval ごとに残っているセッションを通知し、期限を過ぎるか ctx が終わったら失敗する。
+// dry run では待たずに、本番なら待つことになるセッションを警告として報告する。
+func (m *Manager) waitForQuiesce(ctx context.Context, taskName, tableName string) error {
+	settings, err := m.resolveQuiesceSettings()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	deadline := start.Add(settings.timeout)
+	var lastNotified time.Time
+	for {
+		connections, username, err := m.db.GetOtherActiveConnections(m.lockConnectionIDs)
+		if err != nil {
+			return fmt.Errorf("failed to check active connections: %w", err)
+		}
+		if len(connections) == 0 {
+			if time.Since(start) >= settings.pollInterval {
+				m.logger.Infof("Other connections for user '%s' are gone after %s, proceeding", username, time.Since(start).Round(time.Second))
+			}
+			return nil
+		}
+
+		remaining := m.describeConnections(connections)
+		if m.dryRun {
+			message := fmt.Sprintf("[DRY RUN] Would wait up to %s for %d other connections for user '%s' to finish, and fail if they are still there: %s",
+				settings.timeout, len(connections), username, remaining)
+			m.logger.Warn(message)
+			m.dryRunWarnings = append(m.dryRunWarnings, fmt.Sprintf("%s: %s", tableName, message))
+			if slackErr := m.slack.NotifyWarning(taskName+"-connection-wait", tableName, message); slackErr != nil {
+				m.logger.Errorf("Failed to send connection wait notification: %v", slackErr)
+			}
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			errMsg := fmt.Sprintf("other active connections for user '%s' did not finish within %s: %s",
+				username, settings.timeout, remaining)
+			m.logger.Warn(errMsg)
+			if slackErr := m.slack.NotifyConnectionCheckFailure(taskName, tableName, username); slackErr != nil {
+				m.logger.Errorf("Failed to send connection check failure notification: %v", slackErr)
+			}
+			return fmt.Errorf("%w: %s", ErrConnectionCheckFailed, errMsg)
+		}
+
+		if lastNotified.IsZero() || time.Since(lastNotified) >= settings.notifyInterval {
+			message := fmt.Sprintf("Waiting for %d other connections for user '%s' to finish (%s of %s): %s",
+				len(connections), username, time.Since(start).Round(time.Second), settings.timeout, remaining)
+			m.logger.Warn(message)
+			if slackErr := m.slack.NotifyWarning(taskName+"-connection-wait", tableName, message); slackErr != nil {
+				m.logger.Errorf("Failed to send connection wait notification: %v", slackErr)
+			}
+			lastNotified = time.Now()
+		}
+
+		timer := time.NewTimer(min(settings.pollInterval, time.Until(deadline)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("stopped waiting for other connections for user '%s' to finish: %w", username, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// describeConnections は残っているセッションを通知用に並べる。実行中のクエリは redaction.mode に従って値を伏せる
+func (m *Manager) describeConnections(connections []database.ActiveConnection) string {
+	descriptions := make([]string, 0, len(connections))
+	for _, connection := range connections {
+		description := fmt.Sprintf("id=%d host=%s command=%s time=%ds", connection.ID, connection.Host, connection.Command, connection.Time)
+		if connection.Info != "" {
+			description += fmt.Sprintf(" query=%q", m.redactQuery(connection.Info))
+		}
+		descriptions = append(descriptions, description)
+	}
+	return strings.Join(descriptions, "; ")
+}
@@ -0,0 +1,100 @@
+package task
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForQuiesce(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	busy := []database.ActiveConnection{{ID: 42, Host: "10.0.0.1:5000", Command: "Query", Time: 12, Info: "SELECT 1"}}
+
+	newManager := func(mockDB *MockDBClient, mockSlack *MockSlackNotifier, waitTimeout string) *Manager {
+		cfg := &config.Config{Common: config.CommonConfig{ConnectionCheck: config.ConnectionCheckConfig{
+			Enabled:        true,
+			WaitTimeout:    waitTimeout,
+			PollInterval:   "1ms",
+			NotifyInterval: "1h",
+		}}}
+		return NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	}
+
+	t.Run("proceeds once other sessions are gone", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetOtherActiveConnections", mock.Anything).Return(busy, "migrator", nil).Twice()
+		mockDB.On("GetOtherActiveConnections", mock.Anything).Return(nil, "migrator", nil).Once()
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "pt-osc-connection-wait", "users", mock.MatchedBy(func(message string) bool {
+			return strings.Contains(message, "1 other connections") && strings.Contains(message, `id=42 host=10.0.0.1:5000 command=Query time=12s query="SELECT 1"`)
+		})).Return(nil).Once()
+
+		manager := newManager(mockDB, mockSlack, "1m")
+		require.NoError(t, manager.checkOtherActiveConnections(context.Background(), "pt-osc", "users"))
+		mockDB.AssertExpectations(t)
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("fails after wait_timeout", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetOtherActiveConnections", mock.Anything).Return(busy, "migrator", nil)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "pt-osc-connection-wait", "users", mock.Anything).Return(nil)
+		mockSlack.On("NotifyConnectionCheckFailure", "pt-osc", "users", "migrator").Return(nil)
+
+		manager := newManager(mockDB, mockSlack, "5ms")
+		err := manager.checkOtherActiveConnections(context.Background(), "pt-osc", "users")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "did not finish within 5ms")
+		assert.ErrorIs(t, err, ErrConnectionCheckFailed)
+		mockSlack.AssertCalled(t, "NotifyConnectionCheckFailure", "pt-osc", "users", "migrator")
+	})
+
+	t.Run("invalid wait_timeout", func(t *testing.T) {
+		manager := newManager(&MockDBClient{}, &MockSlackNotifier{}, "soon")
+		assert.Error(t, manager.checkOtherActiveConnections(context.Background(), "pt-osc", "users"))
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetOtherActiveConnections", mock.Anything).Return(busy, "migrator", nil)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "pt-osc-connection-wait", "users", mock.Anything).Return(nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		manager := newManager(mockDB, mockSlack, "1h")
+		err := manager.checkOtherActiveConnections(ctx, "pt-osc", "users")
+		assert.ErrorIs(t, err, context.Canceled)
+		mockSlack.AssertNotCalled(t, "NotifyConnectionCheckFailure", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("dry run warns with redacted queries", func(t *testing.T) {
+		secret := []database.ActiveConnection{{ID: 7, Host: "10.0.0.2:5000", Command: "Query", Time: 3, Info: "UPDATE users SET email = 'alice@example.com' WHERE id = 1"}}
+		mockDB := &MockDBClient{}
+		mockDB.On("GetOtherActiveConnections", mock.Anything).Return(secret, "migrator", nil).Once()
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "pt-osc-connection-wait", "users", mock.MatchedBy(func(message string) bool {
+			return strings.Contains(message, "[DRY RUN] Would wait up to 1h0m0s") && !strings.Contains(message, "alice@example.com")
+		})).Return(nil).Once()
+
+		cfg := &config.Config{Common: config.CommonConfig{
+			ConnectionCheck: config.ConnectionCheckConfig{Enabled: true, WaitTimeout: "1h"},
+			Redaction:       config.RedactionConfig{Mode: "elide"},
+		}}
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, true)
+		require.NoError(t, manager.checkOtherActiveConnections(context.Background(), "pt-osc", "users"))
+		require.Len(t, manager.dryRunWarnings, 1)
+		assert.Contains(t, manager.dryRunWarnings[0], "users: [DRY RUN] Would wait")
+		mockDB.AssertExpectations(t)
+		mockSlack.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,112 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/pttablesync"
+)
+
+// swap_remediation.timeout を指定しなかった場合の pt-table-sync の実行時間の上限
+const defaultRemediationTimeout = 30 * time.Minute
+
+// SetTableSyncExecutor を設定すると、swap_remediation が有効なときに swap 前の行数チェックの失敗を pt-table-sync で修復する
+func (m *Manager) SetTableSyncExecutor(executor pttablesync.Executor) {
+	m.tableSync = executor
+}
+
+// checkRowCountWithRemediation は行数チェックが失敗したら、pt-table-sync で _new テーブルを元テーブルに合わせてからもう一度確認する。
+// 修復に失敗した場合や、修復後も差分が残る場合は swap しない
+func (m *Manager) checkRowCountWithRemediation(tableName string) error {
+	checkErr := m.checkRowCountDifference(tableName)
+	remediation := m.config.Common.SwapRemediation
+	if checkErr == nil || !remediation.Enabled {
+		return checkErr
+	}
+	// 意図して行数を変えたテーブルを pt-table-sync で元テーブルに合わせると、変更を打ち消してしまう
+	if _, ok := m.expectedRowDelta(tableName); ok {
+		m.logger.Warnf("Skipping pt-table-sync remediation for %s because pt_osc.tables.%s.expected_row_delta_percent is set", tableName, tableName)
+		return checkErr
+	}
+	if m.tableSync == nil {
+		m.logger.Warnf("swap_remediation is enabled but pt-table-sync is not available for this command")
+		return checkErr
+	}
+
+	timeout := defaultRemediationTimeout
+	if remediation.Timeout != "" {
+		parsed, err := time.ParseDuration(remediation.Timeout)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid swap_remediation.timeout %q", remediation.Timeout)
+		}
+		timeout = parsed
+	}
+
+	newTableName := fmt.Sprintf("_%s_new", tableName)
+	if m.dryRun {
+		m.logger.Infof("[DRY RUN] Would run pt-table-sync from %s to %s (timeout: %s) and check the row count again", tableName, newTableName, timeout)
+		return checkErr
+	}
+
+	columns, err := m.commonColumns(tableName, newTableName)
+	if err != nil {
+		return fmt.Errorf("%w (pt-table-sync remediation could not start: %v)", checkErr, err)
+	}
+
+	m.notifyRemediation(tableName, fmt.Sprintf("Running pt-table-sync from %s to %s (timeout: %s) before swapping. Compared columns: %s",
+		tableName, newTableName, timeout, strings.Join(columns, ", ")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	if err := m.tableSync.Sync(ctx, tableName, newTableName, columns, remediation, m.config.DSN); err != nil {
+		m.notifyRemediation(tableName, fmt.Sprintf("pt-table-sync failed after %s: %v. The swap was not performed", time.Since(start).Round(time.Second), err))
+		return fmt.Errorf("%w and pt-table-sync remediation failed: %w", ErrRowCountMismatch, err)
+	}
+
+	if err := m.checkRowCountDifference(tableName); err != nil {
+		m.notifyRemediation(tableName, "The row count check still fails after pt-table-sync. The swap was not performed")
+		return fmt.Errorf("row count check still fails after pt-table-sync: %w", err)
+	}
+
+	m.notifyRemediation(tableName, fmt.Sprintf("pt-table-sync repaired %s in %s and the row count check passed. Proceeding with the swap",
+		newTableName, time.Since(start).Round(time.Second)))
+	return nil
+}
+
+// commonColumns は ALTER の前後の両方にあるカラムを元テーブルの順で返す。
+// 追加・削除したカラムまで比較すると全行が差分になるため、共通のカラムだけを pt-table-sync で比較する
+func (m *Manager) commonColumns(tableName, newTableName string) ([]string, error) {
+	original, err := m.db.GetTableStructure(tableName)
+	if err != nil {
+		return nil, err
+	}
+	altered, err := m.db.GetTableStructure(newTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[string]bool, len(altered.Columns))
+	for _, column := range altered.Columns {
+		exists[strings.ToLower(column)] = true
+	}
+	var columns []string
+	for _, column := range original.Columns {
+		if exists[strings.ToLower(column)] {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%s and %s have no columns in common", tableName, newTableName)
+	}
+	return columns, nil
+}
+
+func (m *Manager) notifyRemediation(tableName, message string) {
+	m.logger.Warn(message)
+	if err := m.slack.NotifyWarning("swap-remediation", tableName, message); err != nil {
+		m.logger.Errorf("Failed to send warning notification: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newRemediationMocks(newCountAfterSync int64) (*MockDBClient, *MockSlackNotifier) {
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableRowCountForSwap", "users").Return(int64(1000), nil)
+	mockDB.On("GetNewTableRowCountForSwap", "users").Return(int64(800), nil).Once()
+	mockDB.On("GetNewTableRowCountForSwap", "users").Return(newCountAfterSync, nil)
+	mockDB.On("GetTableStructure", "users").Return(&database.TableStructure{Columns: []string{"id", "name", "legacy"}}, nil)
+	mockDB.On("GetTableStructure", "_users_new").Return(&database.TableStructure{Columns: []string{"id", "name", "age"}}, nil)
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyWarning", "swap-row-count-check", "users", mock.Anything).Return(nil)
+	mockSlack.On("NotifyWarning", "swap-remediation", "users", mock.Anything).Return(nil)
+	return mockDB, mockSlack
+}
+
+func TestCheckRowCountWithRemediation(t *testing.T) {
+	remediation := config.SwapRemediationConfig{Enabled: true, ChunkSize: 1000}
+	dsn := "user:pass@tcp(localhost:3306)/app"
+
+	tests := []struct {
+		name              string
+		newCountAfterSync int64
+		syncErr           error
+		errContains       string
+	}{
+		{name: "repaired", newCountAfterSync: 1000},
+		{name: "still differs", newCountAfterSync: 800, errContains: "still fails after pt-table-sync"},
+		{name: "sync fails", syncErr: errors.New("exit status 1"), errContains: "pt-table-sync remediation failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			mockDB, mockSlack := newRemediationMocks(tt.newCountAfterSync)
+			mockSync := &MockTableSyncExecutor{}
+			mockSync.On("Sync", "users", "_users_new", []string{"id", "name"}, remediation, dsn).Return(tt.syncErr)
+
+			cfg := &config.Config{DSN: dsn, Common: config.CommonConfig{SwapRemediation: remediation}}
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+			manager.SetTableSyncExecutor(mockSync)
+
+			err := manager.checkRowCountWithRemediation("users")
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+			mockSync.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCheckRowCountWithRemediation_Disabled(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB, mockSlack := newRemediationMocks(1000)
+	mockSync := &MockTableSyncExecutor{}
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+	manager.SetTableSyncExecutor(mockSync)
+
+	err := manager.checkRowCountWithRemediation("users")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "row count check failed")
+	mockSync.AssertNotCalled(t, "Sync", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCheckRowCountWithRemediation_SkipsExpectedRowDelta(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// 20% 減る想定で 20% 減っていれば、pt-table-sync を使わずに通す
+	mockDB, mockSlack := newRemediationMocks(1000)
+	mockSync := &MockTableSyncExecutor{}
+	expected := -20.0
+	cfg := &config.Config{Common: config.CommonConfig{
+		SwapRemediation: config.SwapRemediationConfig{Enabled: true},
+		PtOsc: config.PtOscConfig{Tables: map[string]config.PtOscTableConfig{
+			"users": {ExpectedRowDeltaPercent: &expected},
+		}},
+	}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	manager.SetTableSyncExecutor(mockSync)
+
+	require.NoError(t, manager.checkRowCountWithRemediation("users"))
+
+	// 想定と違えば、行数を元テーブルに戻してしまう pt-table-sync は使わずに失敗する
+	expected = 0
+	mockDB, mockSlack = newRemediationMocks(1000)
+	manager = NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	manager.SetTableSyncExecutor(mockSync)
+
+	assert.ErrorIs(t, manager.checkRowCountWithRemediation("users"), ErrRowCountMismatch)
+	mockSync.AssertNotCalled(t, "Sync", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
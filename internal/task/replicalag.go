@@ -0,0 +1,67 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/pyama86/alterguard/internal/slack"
+)
+
+// SetLagReplicas は重いフェーズの前後で遅延を測るレプリカを設定する
+func (m *Manager) SetLagReplicas(replicas []RollingHost) {
+	m.lagReplicas = replicas
+}
+
+// sampleReplicaLags は全レプリカの遅延(秒)を測る。測れなかったレプリカは nil になる
+func (m *Manager) sampleReplicaLags() []*float64 {
+	lags := make([]*float64, len(m.lagReplicas))
+	for i, replica := range m.lagReplicas {
+		lag, err := replica.DB.GetReplicaLagSeconds()
+		if err != nil {
+			m.logger.Warnf("Failed to get replica lag of %s: %v", replica.Name, err)
+			continue
+		}
+		lags[i] = &lag
+	}
+	return lags
+}
+
+// maxReplicaLag は全レプリカのうち最も大きい遅延(秒)を返す。どのレプリカも測れなければ false を返す
+func (m *Manager) maxReplicaLag() (float64, bool) {
+	var maxLag float64
+	found := false
+	for _, lag := range m.sampleReplicaLags() {
+		if lag != nil && (!found || *lag > maxLag) {
+			maxLag = *lag
+			found = true
+		}
+	}
+	return maxLag, found
+}
+
+// startReplicaLagSampling はフェーズの開始時にレプリカ遅延を測り、返り値の関数が呼ばれた終了時にもう一度測って
+// 前後の遅延を通知する。レプリカが設定されていなければ何もしない
+func (m *Manager) startReplicaLagSampling(phase, taskName, tableName string) func() {
+	if len(m.lagReplicas) == 0 {
+		return func() {}
+	}
+
+	startLags := m.sampleReplicaLags()
+	return func() {
+		endLags := m.sampleReplicaLags()
+		lags := make([]slack.ReplicaLag, len(m.lagReplicas))
+		for i, replica := range m.lagReplicas {
+			lags[i] = slack.ReplicaLag{Name: replica.Name, Start: startLags[i], End: endLags[i]}
+			m.logger.Infof("Replica lag of %s during %s on %s: %s -> %s", replica.Name, phase, tableName, formatLag(startLags[i]), formatLag(endLags[i]))
+		}
+		if err := m.slack.NotifyReplicaLag(taskName, tableName, phase, lags); err != nil {
+			m.logger.Errorf("Failed to send replica lag notification: %v", err)
+		}
+	}
+}
+
+func formatLag(seconds *float64) string {
+	if seconds == nil {
+		return "unavailable"
+	}
+	return fmt.Sprintf("%.0fs", *seconds)
+}
@@ -0,0 +1,61 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/slack"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStartPhaseMonitorReportsReplicaLag(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	reader1 := &MockDBClient{}
+	reader1.On("GetReplicaLagSeconds").Return(float64(1), nil).Once()
+	reader1.On("GetReplicaLagSeconds").Return(float64(12), nil).Once()
+	reader2 := &MockDBClient{}
+	reader2.On("GetReplicaLagSeconds").Return(float64(0), errors.New("replication is not running")).Once()
+	reader2.On("GetReplicaLagSeconds").Return(float64(0), nil).Once()
+
+	var got []slack.ReplicaLag
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyReplicaLag", "pt-osc", "users", PhasePtOsc, mock.Anything).Run(func(args mock.Arguments) {
+		got = args.Get(3).([]slack.ReplicaLag)
+	}).Return(nil)
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+	manager.SetLagReplicas([]RollingHost{{Name: "reader-1", DB: reader1}, {Name: "reader-2", DB: reader2}})
+
+	stop := manager.startPhaseMonitor(PhasePtOsc, "pt-osc", "users", "ALTER TABLE users ADD COLUMN c INT")
+	stop()
+
+	mockSlack.AssertExpectations(t)
+	reader1.AssertExpectations(t)
+	reader2.AssertExpectations(t)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "reader-1", got[0].Name)
+		assert.Equal(t, 1.0, *got[0].Start)
+		assert.Equal(t, 12.0, *got[0].End)
+		assert.Equal(t, "reader-2", got[1].Name)
+		assert.Nil(t, got[1].Start)
+		assert.Equal(t, 0.0, *got[1].End)
+	}
+}
+
+func TestStartPhaseMonitorWithoutLagReplicas(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockSlack := &MockSlackNotifier{}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+	stop := manager.startPhaseMonitor(PhaseSwap, "swap", "users", "RENAME TABLE")
+	stop()
+
+	mockSlack.AssertNotCalled(t, "NotifyReplicaLag", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,171 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/database"
+)
+
+var createTableSelectPattern = regexp.MustCompile(`(?is)^\s*CREATE\s+(TEMPORARY\s+)?TABLE\b.*\bSELECT\b`)
+
+// checkReplicationCompatibility は replication_check が有効なとき、実行前に GTID の設定と
+// レプリケーションフィルタが計画したクエリと矛盾しないかを確認する。
+// pt-osc が途中で失敗したり、レプリカにだけ変更が届かなかったりするのを実行前に見つけるため。
+func (m *Manager) checkReplicationCompatibility(queries []QueryInfo) error {
+	if !m.config.Common.ReplicationCheck.Enabled {
+		return nil
+	}
+
+	settings, err := m.db.GetReplicationSettings()
+	if err != nil {
+		return fmt.Errorf("failed to check replication settings: %w", err)
+	}
+
+	var problems, warnings []string
+	for _, query := range queries {
+		if !createTableSelectPattern.MatchString(query.Query) || supportsAtomicCreateTableSelect(settings.Version) {
+			continue
+		}
+		message := fmt.Sprintf("CREATE TABLE ... SELECT is not allowed with enforce_gtid_consistency=%s before MySQL 8.0.21 (server: %s): %s",
+			settings.EnforceGTIDConsistency, settings.Version, query.Query)
+		switch strings.ToUpper(settings.EnforceGTIDConsistency) {
+		case "ON", "1":
+			problems = append(problems, message)
+		case "WARN", "2":
+			warnings = append(warnings, message)
+		}
+	}
+
+	var tables []string
+	for _, query := range queries {
+		if query.TableName != "" && !slices.Contains(tables, query.TableName) {
+			tables = append(tables, query.TableName)
+		}
+	}
+	for _, table := range tables {
+		// pt-osc は _new テーブルにコピーし、_old テーブルに退避する。これらがフィルタで除外されるとレプリカが壊れる
+		for _, name := range []string{table, fmt.Sprintf("_%s_new", table), fmt.Sprintf("_%s_old", table)} {
+			if reason := replicationFilterExclusion(settings, name); reason != "" {
+				problems = append(problems, fmt.Sprintf("%s.%s is not replicated: %s", settings.Schema, name, reason))
+			}
+		}
+	}
+
+	for _, warning := range warnings {
+		m.logger.Warn(warning)
+		if slackErr := m.slack.NotifyWarning("replication-check", "", warning); slackErr != nil {
+			m.logger.Errorf("Failed to send replication check warning notification: %v", slackErr)
+		}
+	}
+	if len(problems) == 0 {
+		m.logger.Infof("Replication check passed (gtid_mode=%s, enforce_gtid_consistency=%s)",
+			settings.GTIDMode, settings.EnforceGTIDConsistency)
+		return nil
+	}
+
+	errMsg := "replication check failed: " + strings.Join(problems, "; ")
+	m.logger.Warn(errMsg)
+	if slackErr := m.slack.NotifyWarning("replication-check", "", errMsg); slackErr != nil {
+		m.logger.Errorf("Failed to send replication check warning notification: %v", slackErr)
+	}
+	return fmt.Errorf("%s", errMsg)
+}
+
+// supportsAtomicCreateTableSelect は GTID 有効時でも CREATE TABLE ... SELECT を使えるバージョン (8.0.21+) かを返す
+func supportsAtomicCreateTableSelect(version string) bool {
+	var parts [3]int
+	for i, field := range strings.SplitN(version, ".", 3) {
+		digits := field
+		if end := strings.IndexFunc(field, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+			digits = field[:end]
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			break
+		}
+		parts[i] = n
+	}
+	if parts[0] != 8 {
+		return parts[0] > 8
+	}
+	if parts[1] != 0 {
+		return parts[1] > 0
+	}
+	return parts[2] >= 21
+}
+
+// replicationFilterExclusion はバイナリログまたはレプリケーションのフィルタでテーブルが除外される場合に、その理由を返す。
+// 判定は MySQL の評価順 (データベース単位のフィルタ、次にテーブル単位のフィルタ) に従う。
+func replicationFilterExclusion(settings *database.ReplicationSettings, table string) string {
+	schema := settings.Schema
+	if len(settings.BinlogDoDB) > 0 && !slices.Contains(settings.BinlogDoDB, schema) {
+		return fmt.Sprintf("binlog_do_db does not include %s", schema)
+	}
+	if slices.Contains(settings.BinlogIgnoreDB, schema) {
+		return fmt.Sprintf("binlog_ignore_db includes %s", schema)
+	}
+
+	filters := make(map[string][]string)
+	for _, filter := range settings.Filters {
+		name := strings.ToUpper(filter.Name)
+		filters[name] = append(filters[name], filter.Values()...)
+	}
+
+	if doDB := filters["REPLICATE_DO_DB"]; len(doDB) > 0 && !slices.Contains(doDB, schema) {
+		return fmt.Sprintf("replicate_do_db does not include %s", schema)
+	}
+	if slices.Contains(filters["REPLICATE_IGNORE_DB"], schema) {
+		return fmt.Sprintf("replicate_ignore_db includes %s", schema)
+	}
+
+	qualified := schema + "." + table
+	if slices.Contains(filters["REPLICATE_DO_TABLE"], qualified) {
+		return ""
+	}
+	if slices.Contains(filters["REPLICATE_IGNORE_TABLE"], qualified) {
+		return fmt.Sprintf("replicate_ignore_table includes %s", qualified)
+	}
+	for _, pattern := range filters["REPLICATE_WILD_DO_TABLE"] {
+		if matchWildTable(pattern, qualified) {
+			return ""
+		}
+	}
+	for _, pattern := range filters["REPLICATE_WILD_IGNORE_TABLE"] {
+		if matchWildTable(pattern, qualified) {
+			return fmt.Sprintf("replicate_wild_ignore_table %s matches", pattern)
+		}
+	}
+	if len(filters["REPLICATE_DO_TABLE"]) > 0 || len(filters["REPLICATE_WILD_DO_TABLE"]) > 0 {
+		return "no replicate_do_table or replicate_wild_do_table rule matches"
+	}
+	return ""
+}
+
+// matchWildTable は replicate_wild_*_table の LIKE 形式のパターン (% と _) で db.table を照合する
+func matchWildTable(pattern, qualified string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	matched, err := regexp.MatchString(b.String(), qualified)
+	return err == nil && matched
+}
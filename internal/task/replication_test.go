@@ -0,0 +1,125 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportsAtomicCreateTableSelect(t *testing.T) {
+	assert.False(t, supportsAtomicCreateTableSelect("5.7.44-log"))
+	assert.False(t, supportsAtomicCreateTableSelect("8.0.20"))
+	assert.True(t, supportsAtomicCreateTableSelect("8.0.21"))
+	assert.True(t, supportsAtomicCreateTableSelect("8.0.35-0ubuntu0.22.04.1"))
+	assert.True(t, supportsAtomicCreateTableSelect("8.4.2"))
+	assert.True(t, supportsAtomicCreateTableSelect("9.0.1"))
+}
+
+func TestReplicationFilterExclusion(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings database.ReplicationSettings
+		table    string
+		want     string
+	}{
+		{
+			name:     "no filters",
+			settings: database.ReplicationSettings{Schema: "app"},
+			table:    "users",
+		},
+		{
+			name:     "binlog_do_db excludes the schema",
+			settings: database.ReplicationSettings{Schema: "app", BinlogDoDB: []string{"other"}},
+			table:    "users",
+			want:     "binlog_do_db does not include app",
+		},
+		{
+			name:     "replicate_ignore_db",
+			settings: database.ReplicationSettings{Schema: "app", Filters: []database.ReplicationFilter{{Name: "REPLICATE_IGNORE_DB", Rule: "tmp,app"}}},
+			table:    "users",
+			want:     "replicate_ignore_db includes app",
+		},
+		{
+			name:     "replicate_do_table misses the pt-osc table",
+			settings: database.ReplicationSettings{Schema: "app", Filters: []database.ReplicationFilter{{Name: "REPLICATE_DO_TABLE", Rule: "app.users"}}},
+			table:    "_users_new",
+			want:     "no replicate_do_table or replicate_wild_do_table rule matches",
+		},
+		{
+			name:     "replicate_wild_do_table covers the pt-osc table",
+			settings: database.ReplicationSettings{Schema: "app", Filters: []database.ReplicationFilter{{Name: "REPLICATE_WILD_DO_TABLE", Rule: "app.%users%"}}},
+			table:    "_users_new",
+		},
+		{
+			name:     "replicate_wild_ignore_table",
+			settings: database.ReplicationSettings{Schema: "app", Filters: []database.ReplicationFilter{{Name: "REPLICATE_WILD_IGNORE_TABLE", Rule: "app.\\_%"}}},
+			table:    "_users_old",
+			want:     "replicate_wild_ignore_table app.\\_% matches",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, replicationFilterExclusion(&tt.settings, tt.table))
+		})
+	}
+}
+
+func TestCheckReplicationCompatibility(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	newManager := func(settings *database.ReplicationSettings, mockSlack *MockSlackNotifier) *Manager {
+		mockDB := &MockDBClient{}
+		mockDB.On("GetReplicationSettings").Return(settings, nil)
+		cfg := &config.Config{Common: config.CommonConfig{ReplicationCheck: config.ReplicationCheckConfig{Enabled: true}}}
+		return NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	}
+	queries := []QueryInfo{
+		{Query: "CREATE TABLE users_copy SELECT * FROM users", TableName: "users_copy"},
+		{Query: "ALTER TABLE users ADD COLUMN age INT", TableName: "users"},
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+		assert.NoError(t, manager.checkReplicationCompatibility(queries))
+	})
+
+	t.Run("CREATE TABLE ... SELECT with enforced GTID consistency", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "replication-check", "", mock.AnythingOfType("string")).Return(nil)
+		manager := newManager(&database.ReplicationSettings{Version: "5.7.44", Schema: "app", GTIDMode: "ON", EnforceGTIDConsistency: "ON"}, mockSlack)
+
+		err := manager.checkReplicationCompatibility(queries)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "CREATE TABLE ... SELECT is not allowed")
+	})
+
+	t.Run("CREATE TABLE ... SELECT is only a warning with WARN", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "replication-check", "", mock.AnythingOfType("string")).Return(nil).Once()
+		manager := newManager(&database.ReplicationSettings{Version: "5.7.44", Schema: "app", GTIDMode: "ON_PERMISSIVE", EnforceGTIDConsistency: "WARN"}, mockSlack)
+
+		assert.NoError(t, manager.checkReplicationCompatibility(queries))
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("filters exclude the pt-osc tables", func(t *testing.T) {
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyWarning", "replication-check", "", mock.AnythingOfType("string")).Return(nil)
+		manager := newManager(&database.ReplicationSettings{
+			Version: "8.0.35", Schema: "app", GTIDMode: "ON", EnforceGTIDConsistency: "ON",
+			Filters: []database.ReplicationFilter{{Name: "REPLICATE_DO_TABLE", Rule: "app.users,app.users_copy"}},
+		}, mockSlack)
+
+		err := manager.checkReplicationCompatibility(queries)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "app._users_new is not replicated")
+		assert.NotContains(t, err.Error(), "CREATE TABLE ... SELECT")
+	})
+}
@@ -0,0 +1,137 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// QueryStatus はクエリの実行結果の種別
+type QueryStatus string
+
+const (
+	QueryStatusSucceeded QueryStatus = "succeeded"
+	QueryStatusFailed    QueryStatus = "failed"
+	// 前のクエリの失敗で実行されなかった、または既に適用済みだったもの
+	QueryStatusSkipped QueryStatus = "skipped"
+)
+
+// DryRunStatus は dry-run で見つかった問題の重さ
+type DryRunStatus string
+
+const (
+	DryRunClean DryRunStatus = "clean"
+	// pt-osc の dry-run が警告を出したが、実行は止まらない
+	DryRunWarnings DryRunStatus = "warnings"
+	// 本番実行すれば失敗する
+	DryRunWouldFail DryRunStatus = "would-fail"
+)
+
+// RunResult は ExecuteAllTasksWithResult の実行結果。Queries はタスクファイルの順(シャードは展開後)に並ぶ。
+type RunResult struct {
+	Queries  []QueryResult
+	DryRun   bool
+	Duration time.Duration
+	// dry-run で pt-osc が出した警告 ("テーブル名: 警告" の形式)
+	Warnings []string
+}
+
+// DryRunStatus は失敗したクエリがあれば would-fail、警告があれば warnings、どちらもなければ clean を返す
+func (r *RunResult) DryRunStatus() DryRunStatus {
+	if _, failed, _ := r.Counts(); failed > 0 {
+		return DryRunWouldFail
+	}
+	if len(r.Warnings) > 0 {
+		return DryRunWarnings
+	}
+	return DryRunClean
+}
+
+func newRunResult(queries []QueryInfo, dryRun bool) *RunResult {
+	result := &RunResult{DryRun: dryRun, Queries: make([]QueryResult, 0, len(queries))}
+	for _, query := range queries {
+		result.Queries = append(result.Queries, QueryResult{
+			Query:     query.Query,
+			TableName: query.TableName,
+			Status:    QueryStatusSkipped,
+		})
+	}
+	return result
+}
+
+// recordGroup はテーブルのグループの結果を、そのグループにまとめたクエリ全てに反映する
+func (r *RunResult) recordGroup(group *TableGroup, duration time.Duration, err error) {
+	for _, i := range group.queryIndexes {
+		query := &r.Queries[i]
+		query.Method = group.Method
+		query.RowCount = group.RowCount
+		query.Duration = duration
+		query.setOutcome(err)
+		if err == nil && group.Method == "already-applied" {
+			query.Status = QueryStatusSkipped
+		}
+	}
+}
+
+func (r *RunResult) recordQuery(index int, method string, duration time.Duration, err error) {
+	query := &r.Queries[index]
+	query.Method = method
+	query.Duration = duration
+	query.setOutcome(err)
+}
+
+func (q *QueryResult) setOutcome(err error) {
+	q.Success = err == nil
+	q.Error = err
+	if err != nil {
+		q.Status = QueryStatusFailed
+	} else {
+		q.Status = QueryStatusSucceeded
+	}
+}
+
+// Counts は状態ごとのクエリ数を返す
+func (r *RunResult) Counts() (succeeded, failed, skipped int) {
+	for _, query := range r.Queries {
+		switch query.Status {
+		case QueryStatusSucceeded:
+			succeeded++
+		case QueryStatusFailed:
+			failed++
+		case QueryStatusSkipped:
+			skipped++
+		}
+	}
+	return succeeded, failed, skipped
+}
+
+// Format はクエリごとの結果を表にまとめる
+func (r *RunResult) Format() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tMETHOD\tROWS\tDURATION\tQUERY")
+	for _, query := range r.Queries {
+		method := query.Method
+		if method == "" {
+			method = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", query.Status, method, query.RowCount,
+			query.Duration.Round(time.Millisecond), strings.Join(strings.Fields(query.Query), " "))
+		if query.Error != nil {
+			fmt.Fprintf(w, "\t\t\t\terror: %v\n", query.Error)
+		}
+	}
+	_ = w.Flush()
+
+	for _, warning := range r.Warnings {
+		fmt.Fprintf(&b, "warning: %s\n", warning)
+	}
+
+	succeeded, failed, skipped := r.Counts()
+	fmt.Fprintf(&b, "%d succeeded, %d failed, %d skipped in %s", succeeded, failed, skipped, r.Duration.Round(time.Millisecond))
+	if r.DryRun {
+		fmt.Fprintf(&b, " (dry run: %s)", r.DryRunStatus())
+	}
+	return b.String()
+}
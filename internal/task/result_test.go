@@ -0,0 +1,99 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteAllTasksWithResult(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	queries := []string{
+		"ALTER TABLE users ADD COLUMN age INT",
+		"ALTER TABLE orders ADD COLUMN note TEXT",
+		"ALTER TABLE items ADD COLUMN price INT",
+	}
+	alterErr := errors.New("lock wait timeout")
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableStorage", mock.Anything).Return(&database.TableStorage{Engine: "InnoDB"}, nil).Maybe()
+	mockDB.On("GetTableStructure", mock.Anything).Return(&database.TableStructure{}, nil).Maybe()
+	mockDB.On("GetTableRowCounts", []string{"users", "orders", "items"}).Return(map[string]int64{"users": 100, "orders": 200, "items": 300}, nil)
+	mockDB.On("ExecuteAlterWithAlgorithm", "users", queries[0]).Return(&database.AlterAlgorithm{Algorithm: database.AlterAlgorithmInstant}, nil)
+	mockDB.On("ExecuteAlterWithAlgorithm", "orders", queries[1]).Return(nil, alterErr)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyAllTasksStart", 3).Return(nil)
+	mockSlack.On("NotifyStartWithQueryAndStorage", "alter-table", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSlack.On("NotifySuccessWithQueryAndAlgorithm", "alter-table", "users", mock.Anything, int64(100), mock.Anything, "instant").Return(nil)
+	mockSlack.On("NotifyFailureWithQuery", "alter-table", "orders", mock.Anything, int64(200), alterErr).Return(nil)
+	mockSlack.On("NotifyAllTasksFailure", 3, alterErr).Return(nil)
+
+	cfg := &config.Config{
+		Queries: queries,
+		Common:  config.CommonConfig{PtOscThreshold: 1000},
+		DSN:     "test-dsn",
+	}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	result, err := manager.ExecuteAllTasksWithResult()
+	require.ErrorIs(t, err, alterErr)
+	require.NotNil(t, result)
+	require.Len(t, result.Queries, 3)
+
+	assert.Equal(t, QueryStatusSucceeded, result.Queries[0].Status)
+	assert.Equal(t, "alter-table", result.Queries[0].Method)
+	assert.Equal(t, int64(100), result.Queries[0].RowCount)
+	assert.True(t, result.Queries[0].Success)
+
+	assert.Equal(t, QueryStatusFailed, result.Queries[1].Status)
+	assert.Equal(t, "orders", result.Queries[1].TableName)
+	assert.ErrorIs(t, result.Queries[1].Error, alterErr)
+
+	assert.Equal(t, QueryStatusSkipped, result.Queries[2].Status)
+	assert.Empty(t, result.Queries[2].Method)
+
+	succeeded, failed, skipped := result.Counts()
+	assert.Equal(t, []int{1, 1, 1}, []int{succeeded, failed, skipped})
+}
+
+func TestRunResultFormat(t *testing.T) {
+	result := &RunResult{
+		DryRun:   true,
+		Duration: 1500 * time.Millisecond,
+		Warnings: []string{"users: WARNING! Dropping the primary key can be dangerous"},
+		Queries: []QueryResult{
+			{Query: "ALTER TABLE users\n  ADD COLUMN age INT", Method: "pt-osc", Status: QueryStatusSucceeded, RowCount: 5000, Duration: time.Second},
+			{Query: "CREATE DATABASE other", Method: "non-table-query", Status: QueryStatusFailed, Error: errors.New("access denied")},
+			{Query: "DROP TABLE logs", Status: QueryStatusSkipped},
+		},
+	}
+
+	assert.Equal(t, `STATUS     METHOD           ROWS  DURATION  QUERY
+succeeded  pt-osc           5000  1s        ALTER TABLE users ADD COLUMN age INT
+failed     non-table-query  0     0s        CREATE DATABASE other
+                                            error: access denied
+skipped    -                0     0s        DROP TABLE logs
+warning: users: WARNING! Dropping the primary key can be dangerous
+1 succeeded, 1 failed, 1 skipped in 1.5s (dry run: would-fail)`, result.Format())
+}
+
+func TestRunResultDryRunStatus(t *testing.T) {
+	result := &RunResult{DryRun: true, Queries: []QueryResult{{Status: QueryStatusSucceeded}, {Status: QueryStatusSkipped}}}
+	assert.Equal(t, DryRunClean, result.DryRunStatus())
+
+	result.Warnings = []string{"users: --alter contains 'DROP PRIMARY KEY'"}
+	assert.Equal(t, DryRunWarnings, result.DryRunStatus())
+
+	result.Queries[1].Status = QueryStatusFailed
+	assert.Equal(t, DryRunWouldFail, result.DryRunStatus())
+}
@@ -0,0 +1,179 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/database"
+)
+
+const (
+	defaultRollingLagCheckInterval = 5 * time.Second
+	defaultRollingLagWaitTimeout   = 30 * time.Minute
+)
+
+// RollingHost はローリング実行の対象ホスト
+type RollingHost struct {
+	Name string
+	DB   database.Client
+}
+
+// ExecuteRollingTasks はレプリカを1台ずつ直接ALTERし、遅延が解消するのを待ってから次に進む。
+// 全レプリカ完了後、最後にプライマリ(Managerのdb)に適用する。pt-oscは使用しない。
+func (m *Manager) ExecuteRollingTasks(replicas []RollingHost) error {
+	return m.ExecuteRollingTasksContext(context.Background(), replicas)
+}
+
+// ExecuteRollingTasksContext は ctx がキャンセルされたらレプリカ遅延の待機をやめる ExecuteRollingTasks。
+// 実行中の ALTER は止めず、次のホストには進まない
+func (m *Manager) ExecuteRollingTasksContext(ctx context.Context, replicas []RollingHost) error {
+	queries, err := m.parseConfiguredQueries()
+	if err != nil {
+		return fmt.Errorf("failed to parse queries: %w", err)
+	}
+
+	checkInterval, err := resolveRollingDuration(m.config.Common.Rolling.LagCheckInterval, defaultRollingLagCheckInterval)
+	if err != nil {
+		return fmt.Errorf("invalid rolling.lag_check_interval: %w", err)
+	}
+	waitTimeout, err := resolveRollingDuration(m.config.Common.Rolling.LagWaitTimeout, defaultRollingLagWaitTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid rolling.lag_wait_timeout: %w", err)
+	}
+
+	m.logger.Infof("Starting rolling execution of %d queries across %d replicas and the primary", len(queries), len(replicas))
+
+	if err := m.slack.NotifyAllTasksStart(len(queries)); err != nil {
+		m.logger.Errorf("Failed to send all tasks start notification: %v", err)
+	}
+
+	start := time.Now()
+
+	for _, replica := range replicas {
+		if err := m.executeQueriesOnHost(replica, queries); err != nil {
+			if slackErr := m.slack.NotifyAllTasksFailure(len(queries), err); slackErr != nil {
+				m.logger.Errorf("Failed to send all tasks failure notification: %v", slackErr)
+			}
+			return fmt.Errorf("rolling execution failed on replica %s: %w", replica.Name, err)
+		}
+
+		if err := m.waitForReplicaLag(ctx, replica, checkInterval, waitTimeout); err != nil {
+			if slackErr := m.slack.NotifyAllTasksFailure(len(queries), err); slackErr != nil {
+				m.logger.Errorf("Failed to send all tasks failure notification: %v", slackErr)
+			}
+			return fmt.Errorf("rolling execution stopped after replica %s: %w", replica.Name, err)
+		}
+	}
+
+	primary := RollingHost{Name: "primary " + database.DescribeDSN(m.config.DSN), DB: m.db}
+	if err := m.executeQueriesOnHost(primary, queries); err != nil {
+		if slackErr := m.slack.NotifyAllTasksFailure(len(queries), err); slackErr != nil {
+			m.logger.Errorf("Failed to send all tasks failure notification: %v", slackErr)
+		}
+		return fmt.Errorf("rolling execution failed on primary: %w", err)
+	}
+
+	totalDuration := time.Since(start)
+
+	if err := m.slack.NotifyAllTasksSuccess(len(queries), totalDuration); err != nil {
+		m.logger.Errorf("Failed to send all tasks success notification: %v", err)
+	}
+
+	m.logger.Info("Rolling execution completed successfully")
+	return nil
+}
+
+func (m *Manager) executeQueriesOnHost(host RollingHost, queries []QueryInfo) error {
+	taskName := fmt.Sprintf("rolling [%s]", host.Name)
+	if m.dryRun {
+		taskName = fmt.Sprintf("rolling [%s] (DRY RUN)", host.Name)
+	}
+
+	m.logger.Infof("Applying %d queries to %s", len(queries), host.Name)
+
+	for _, queryInfo := range queries {
+		cleanedQuery := strings.ReplaceAll(queryInfo.Query, "`", "")
+		quotedQuery := fmt.Sprintf("`%s`", cleanedQuery)
+
+		if err := m.slack.NotifyStartWithQuery(taskName, queryInfo.TableName, quotedQuery, 0); err != nil {
+			m.logger.Errorf("Failed to send start notification: %v", err)
+		}
+
+		start := time.Now()
+		if err := m.executeQueryOnHost(host, &queryInfo, taskName); err != nil {
+			if slackErr := m.slack.NotifyFailureWithQuery(taskName, queryInfo.TableName, quotedQuery, 0, err); slackErr != nil {
+				m.logger.Errorf("Failed to send failure notification: %v", slackErr)
+			}
+			return err
+		}
+
+		duration := time.Since(start)
+		if err := m.slack.NotifySuccessWithQuery(taskName, queryInfo.TableName, quotedQuery, 0, duration); err != nil {
+			m.logger.Errorf("Failed to send success notification: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) executeQueryOnHost(host RollingHost, queryInfo *QueryInfo, taskName string) error {
+	if m.dryRun {
+		m.logger.Infof("[DRY RUN] Would execute SQL on %s: %s", host.Name, queryInfo.Query)
+		return nil
+	}
+
+	var err error
+	if m.config.Common.Rolling.EnableBinlog {
+		err = host.DB.ExecuteAlter(queryInfo.Query)
+	} else {
+		err = host.DB.ExecuteAlterWithoutBinlog(queryInfo.Query)
+	}
+	if err != nil {
+		return m.handleQueryError(taskName, queryInfo, err)
+	}
+	return nil
+}
+
+func (m *Manager) waitForReplicaLag(ctx context.Context, host RollingHost, checkInterval, waitTimeout time.Duration) error {
+	maxLag := m.config.Common.Rolling.MaxLagSeconds
+	deadline := time.Now().Add(waitTimeout)
+
+	for {
+		lag, err := host.DB.GetReplicaLagSeconds()
+		if err != nil {
+			return fmt.Errorf("failed to check replica lag on %s: %w", host.Name, err)
+		}
+
+		if lag <= maxLag {
+			m.logger.Infof("Replica %s lag %.0fs within threshold %.0fs", host.Name, lag, maxLag)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("replica %s lag %.0fs did not recover below %.0fs within %s", host.Name, lag, maxLag, waitTimeout)
+		}
+
+		m.logger.Infof("Replica %s lag %.0fs exceeds threshold %.0fs, waiting %s", host.Name, lag, maxLag, checkInterval)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for replica %s lag: %w", host.Name, ctx.Err())
+		case <-time.After(checkInterval):
+		}
+	}
+}
+
+func resolveRollingDuration(raw string, defaultValue time.Duration) (time.Duration, error) {
+	if raw == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive, got %s", raw)
+	}
+	return d, nil
+}
@@ -0,0 +1,174 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteRollingTasks(t *testing.T) {
+	query := "ALTER TABLE users ADD INDEX idx_email (email)"
+
+	newRollingConfig := func(rolling config.RollingConfig) *config.Config {
+		return &config.Config{
+			Queries: []string{query},
+			Common: config.CommonConfig{
+				Rolling: rolling,
+			},
+			DSN: "user:pass@tcp(primary:3306)/app",
+		}
+	}
+
+	t.Run("applies to replicas in order and finishes on the primary", func(t *testing.T) {
+		logger := logrus.New()
+		logger.SetLevel(logrus.FatalLevel)
+
+		var order []string
+		record := func(name string) func(mock.Arguments) {
+			return func(mock.Arguments) { order = append(order, name) }
+		}
+
+		primary := &MockDBClient{}
+		replica1 := &MockDBClient{}
+		replica2 := &MockDBClient{}
+
+		replica1.On("ExecuteAlterWithoutBinlog", query).Run(record("replica1")).Return(nil)
+		replica1.On("GetReplicaLagSeconds").Return(float64(12), nil).Once()
+		replica1.On("GetReplicaLagSeconds").Return(float64(0), nil).Once()
+		replica2.On("ExecuteAlterWithoutBinlog", query).Run(record("replica2")).Return(nil)
+		replica2.On("GetReplicaLagSeconds").Return(float64(0), nil).Once()
+		primary.On("ExecuteAlterWithoutBinlog", query).Run(record("primary")).Return(nil)
+
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyAllTasksStart", 1).Return(nil)
+		mockSlack.On("NotifyStartWithQuery", mock.Anything, "users", mock.Anything, int64(0)).Return(nil)
+		mockSlack.On("NotifySuccessWithQuery", mock.Anything, "users", mock.Anything, int64(0), mock.Anything).Return(nil)
+		mockSlack.On("NotifyAllTasksSuccess", 1, mock.Anything).Return(nil)
+
+		cfg := newRollingConfig(config.RollingConfig{MaxLagSeconds: 1, LagCheckInterval: "1ms"})
+		manager := NewManager(primary, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+		err := manager.ExecuteRollingTasks([]RollingHost{
+			{Name: "replica1", DB: replica1},
+			{Name: "replica2", DB: replica2},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"replica1", "replica2", "primary"}, order)
+		primary.AssertExpectations(t)
+		replica1.AssertExpectations(t)
+		replica2.AssertExpectations(t)
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("enable_binlog uses the regular execution path", func(t *testing.T) {
+		logger := logrus.New()
+		logger.SetLevel(logrus.FatalLevel)
+
+		primary := &MockDBClient{}
+		replica := &MockDBClient{}
+		replica.On("ExecuteAlter", query).Return(nil)
+		replica.On("GetReplicaLagSeconds").Return(float64(0), nil)
+		primary.On("ExecuteAlter", query).Return(nil)
+
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyAllTasksStart", 1).Return(nil)
+		mockSlack.On("NotifyStartWithQuery", mock.Anything, "users", mock.Anything, int64(0)).Return(nil)
+		mockSlack.On("NotifySuccessWithQuery", mock.Anything, "users", mock.Anything, int64(0), mock.Anything).Return(nil)
+		mockSlack.On("NotifyAllTasksSuccess", 1, mock.Anything).Return(nil)
+
+		cfg := newRollingConfig(config.RollingConfig{EnableBinlog: true})
+		manager := NewManager(primary, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+		err := manager.ExecuteRollingTasks([]RollingHost{{Name: "replica", DB: replica}})
+
+		require.NoError(t, err)
+		primary.AssertExpectations(t)
+		replica.AssertExpectations(t)
+	})
+
+	t.Run("failure on a replica stops before the primary", func(t *testing.T) {
+		logger := logrus.New()
+		logger.SetLevel(logrus.FatalLevel)
+
+		primary := &MockDBClient{}
+		replica := &MockDBClient{}
+		replica.On("ExecuteAlterWithoutBinlog", query).Return(errors.New("boom"))
+
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyAllTasksStart", 1).Return(nil)
+		mockSlack.On("NotifyStartWithQuery", mock.Anything, "users", mock.Anything, int64(0)).Return(nil)
+		mockSlack.On("NotifyFailureWithQuery", mock.Anything, "users", mock.Anything, int64(0), mock.Anything).Return(nil)
+		mockSlack.On("NotifyAllTasksFailure", 1, mock.Anything).Return(nil)
+
+		cfg := newRollingConfig(config.RollingConfig{})
+		manager := NewManager(primary, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+		err := manager.ExecuteRollingTasks([]RollingHost{{Name: "replica", DB: replica}})
+
+		assert.Error(t, err)
+		primary.AssertNotCalled(t, "ExecuteAlterWithoutBinlog", mock.Anything)
+		replica.AssertExpectations(t)
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("lag that never recovers times out", func(t *testing.T) {
+		logger := logrus.New()
+		logger.SetLevel(logrus.FatalLevel)
+
+		primary := &MockDBClient{}
+		replica := &MockDBClient{}
+		replica.On("ExecuteAlterWithoutBinlog", query).Return(nil)
+		replica.On("GetReplicaLagSeconds").Return(float64(100), nil)
+
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyAllTasksStart", 1).Return(nil)
+		mockSlack.On("NotifyStartWithQuery", mock.Anything, "users", mock.Anything, int64(0)).Return(nil)
+		mockSlack.On("NotifySuccessWithQuery", mock.Anything, "users", mock.Anything, int64(0), mock.Anything).Return(nil)
+		mockSlack.On("NotifyAllTasksFailure", 1, mock.Anything).Return(nil)
+
+		cfg := newRollingConfig(config.RollingConfig{MaxLagSeconds: 1, LagCheckInterval: "1ms", LagWaitTimeout: "5ms"})
+		manager := NewManager(primary, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+		err := manager.ExecuteRollingTasks([]RollingHost{{Name: "replica", DB: replica}})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "did not recover")
+		primary.AssertNotCalled(t, "ExecuteAlterWithoutBinlog", mock.Anything)
+	})
+
+	t.Run("canceled context stops waiting for lag", func(t *testing.T) {
+		logger := logrus.New()
+		logger.SetLevel(logrus.FatalLevel)
+
+		primary := &MockDBClient{}
+		replica := &MockDBClient{}
+		replica.On("ExecuteAlterWithoutBinlog", query).Return(nil)
+		replica.On("GetReplicaLagSeconds").Return(float64(100), nil)
+
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyAllTasksStart", 1).Return(nil)
+		mockSlack.On("NotifyStartWithQuery", mock.Anything, "users", mock.Anything, int64(0)).Return(nil)
+		mockSlack.On("NotifySuccessWithQuery", mock.Anything, "users", mock.Anything, int64(0), mock.Anything).Return(nil)
+		mockSlack.On("NotifyAllTasksFailure", 1, mock.Anything).Return(nil)
+
+		cfg := newRollingConfig(config.RollingConfig{MaxLagSeconds: 1, LagCheckInterval: "1h"})
+		manager := NewManager(primary, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		start := time.Now()
+		err := manager.ExecuteRollingTasksContext(ctx, []RollingHost{{Name: "replica", DB: replica}})
+
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), time.Minute)
+		primary.AssertNotCalled(t, "ExecuteAlterWithoutBinlog", mock.Anything)
+	})
+}
@@ -0,0 +1,56 @@
+package task
+
+// getTableRowCount はキャッシュ済みの行数があればそれを返し、なければDBから取得してキャッシュする。
+// キャッシュは1回の実行の中だけで使い、テーブルに書き込んだら invalidateRowCount で捨てる
+func (m *Manager) getTableRowCount(tableName string) (int64, error) {
+	if count, ok := m.rowCounts[tableName]; ok {
+		return count, nil
+	}
+
+	count, err := m.db.GetTableRowCount(tableName)
+	if err != nil {
+		return 0, err
+	}
+	m.rowCounts[tableName] = count
+	return count, nil
+}
+
+// prefetchRowCounts は対象テーブルの行数をまとめて取得してキャッシュする。
+// 失敗しても getTableRowCount がテーブルごとに取得するため、ログを出すだけにとどめる。
+func (m *Manager) prefetchRowCounts(groups []*TableGroup) {
+	tables := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if _, ok := m.rowCounts[group.TableName]; !ok {
+			tables = append(tables, group.TableName)
+		}
+	}
+	if len(tables) == 0 {
+		return
+	}
+
+	counts, err := m.db.GetTableRowCounts(tables)
+	if err != nil {
+		m.logger.Warnf("Failed to prefetch row counts, falling back to per-table lookups: %v", err)
+		return
+	}
+
+	for table, count := range counts {
+		m.rowCounts[table] = count
+	}
+	m.logger.Debugf("Prefetched row counts for %d of %d tables", len(counts), len(tables))
+}
+
+// resetRowCounts は行数のキャッシュを捨てる。serve のように Manager を使い回しても前の実行の行数を使わないよう、実行の始めに呼ぶ
+func (m *Manager) resetRowCounts() {
+	m.rowCounts = make(map[string]int64)
+}
+
+// invalidateRowCount は書き込んだテーブルの行数のキャッシュを捨てる。
+// どのテーブルに書いたか分からない場合(tableName が空)はすべて捨てる
+func (m *Manager) invalidateRowCount(tableName string) {
+	if tableName == "" {
+		m.resetRowCounts()
+		return
+	}
+	delete(m.rowCounts, tableName)
+}
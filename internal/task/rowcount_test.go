@@ -0,0 +1,105 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteAllTasks_RowCountCache(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableStorage", mock.Anything).Return(&database.TableStorage{Engine: "InnoDB"}, nil).Maybe()
+	mockDB.On("GetTableStructure", mock.Anything).Return(&database.TableStructure{}, nil).Maybe()
+	mockSlack := &MockSlackNotifier{}
+
+	queries := []string{
+		"ALTER TABLE table1 ADD COLUMN foo INT",
+		"ALTER TABLE table2 ADD COLUMN bar INT",
+	}
+
+	// table2 は統計情報が取れなかった想定で、個別取得にフォールバックする
+	mockDB.On("GetTableRowCounts", []string{"table1", "table2"}).Return(map[string]int64{"table1": 500}, nil).Once()
+	mockDB.On("GetTableRowCount", "table2").Return(int64(800), nil).Once()
+	mockDB.On("ExecuteAlterWithAlgorithm", "table1", queries[0]).Return(&database.AlterAlgorithm{Algorithm: database.AlterAlgorithmInstant}, nil)
+	mockDB.On("ExecuteAlterWithAlgorithm", "table2", queries[1]).Return(&database.AlterAlgorithm{Algorithm: database.AlterAlgorithmInstant}, nil)
+
+	mockSlack.On("NotifyAllTasksStart", 2).Return(nil)
+	mockSlack.On("NotifyStartWithQueryAndStorage", "alter-table", "table1", mock.Anything, int64(500), mock.Anything).Return(nil)
+	mockSlack.On("NotifySuccessWithQueryAndAlgorithm", "alter-table", "table1", mock.Anything, int64(500), mock.Anything, "instant").Return(nil)
+	mockSlack.On("NotifyStartWithQueryAndStorage", "alter-table", "table2", mock.Anything, int64(800), mock.Anything).Return(nil)
+	mockSlack.On("NotifySuccessWithQueryAndAlgorithm", "alter-table", "table2", mock.Anything, int64(800), mock.Anything, "instant").Return(nil)
+	mockSlack.On("NotifyAllTasksSuccess", 2, mock.Anything).Return(nil)
+
+	cfg := &config.Config{
+		Queries: queries,
+		Common: config.CommonConfig{
+			PtOscThreshold: 1000,
+		},
+		DSN: "test-dsn",
+	}
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	err := manager.ExecuteAllTasks()
+
+	require.NoError(t, err)
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "GetTableRowCount", "table1")
+	mockSlack.AssertExpectations(t)
+}
+
+func TestGetTableRowCount_PrefetchFailureFallsBack(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableRowCounts", []string{"users"}).Return(nil, errors.New("boom"))
+	mockDB.On("GetTableRowCount", "users").Return(int64(42), nil).Once()
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	manager.prefetchRowCounts([]*TableGroup{{TableName: "users"}})
+
+	for i := 0; i < 2; i++ {
+		count, err := manager.getTableRowCount("users")
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), count)
+	}
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetTableRowCount_InvalidatedAfterWrite(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	query := "DELETE FROM users WHERE id < 100"
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableRowCounts", []string{"users", "orders"}).Return(map[string]int64{"users": 500, "orders": 10}, nil).Once()
+	mockDB.On("ExecuteAlterContext", query).Return(nil).Once()
+	mockDB.On("GetTableRowCount", "users").Return(int64(400), nil).Once()
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	manager.prefetchRowCounts([]*TableGroup{{TableName: "users"}, {TableName: "orders"}})
+	require.NoError(t, manager.executeQuery(context.Background(), &QueryInfo{Query: query, QueryType: "DELETE", TableName: "users"}, "small-query"))
+
+	// 書き込んだテーブルだけ取り直す
+	count, err := manager.getTableRowCount("users")
+	require.NoError(t, err)
+	assert.Equal(t, int64(400), count)
+	count, err = manager.getTableRowCount("orders")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), count)
+
+	// 新しい実行では前の実行の行数を使わない
+	manager.resetRowCounts()
+	assert.Empty(t, manager.rowCounts)
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,95 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/events"
+)
+
+// rowCountDivergence は統計情報の行数 estimated が COUNT(*) の行数 exact からどれだけ(%)ずれているかを返す
+func rowCountDivergence(estimated, exact int64) float64 {
+	if exact == 0 {
+		if estimated == 0 {
+			return 0
+		}
+		return 100
+	}
+	return math.Abs(float64(estimated-exact)) / float64(exact) * 100
+}
+
+// verifyRowCount は row_count_verify が有効なら、方式の判定に使った行数を COUNT(*) で確かめる。
+// 差が max_divergence_percent を超えたら警告とイベントを送り、reevaluate なら COUNT(*) の行数で方式を選び直して返す。
+// COUNT(*) に失敗した場合は警告のログだけ出し、元の行数と方式のまま続ける。
+func (m *Manager) verifyRowCount(tableName, method string, estimated int64) (string, int64, error) {
+	verify := m.config.Common.RowCountVerify
+	if verify.MaxDivergencePercent <= 0 {
+		return method, estimated, nil
+	}
+	// force_method の場合は行数で方式を決めていない
+	if forced, _ := m.resolveForcedMethod(); forced != "" {
+		return method, estimated, nil
+	}
+	timeout, err := resolveTimeout("row_count_verify.count_timeout", verify.CountTimeout)
+	if err != nil {
+		return method, estimated, err
+	}
+
+	exact, err := m.db.CountTableRows(tableName, timeout)
+	if err != nil {
+		m.logger.Warnf("Could not verify row count of %s with COUNT(*), keeping the estimate %d: %v", tableName, estimated, err)
+		return method, estimated, nil
+	}
+
+	divergence := rowCountDivergence(estimated, exact)
+	if divergence <= verify.MaxDivergencePercent {
+		m.logger.Infof("Row count estimate of %s (%d) is within %.1f%% of COUNT(*) (%d)", tableName, estimated, verify.MaxDivergencePercent, exact)
+		return method, estimated, nil
+	}
+
+	m.publishRowCountDivergence(tableName, method, estimated, exact, divergence)
+	message := fmt.Sprintf("Row count estimate of %s was %d but COUNT(*) found %d (%.1f%% off, max_divergence_percent: %g); choosing %s may have been wrong",
+		tableName, estimated, exact, divergence, verify.MaxDivergencePercent, method)
+	if verify.Reevaluate {
+		m.rowCounts[tableName] = exact
+		reevaluated := m.chooseAlterMethod(tableName, exact)
+		if reevaluated != method {
+			message += fmt.Sprintf(". Method re-evaluated with COUNT(*): %s -> %s", method, reevaluated)
+		} else {
+			message += fmt.Sprintf(". Method re-evaluated with COUNT(*): still %s", method)
+		}
+		method, estimated = reevaluated, exact
+	}
+
+	m.logger.Warn(message)
+	if err := m.slack.NotifyWarning("row-count-verify", tableName, message); err != nil {
+		m.logger.Errorf("Failed to send warning notification: %v", err)
+	}
+	return method, estimated, nil
+}
+
+// publishRowCountDivergence は行数の見積もり違いをイベントとして送り、ダッシュボードで集計できるようにする
+func (m *Manager) publishRowCountDivergence(tableName, method string, estimated, exact int64, divergence float64) {
+	if m.events == nil || m.dryRun {
+		return
+	}
+
+	event := events.Event{
+		Phase:             events.PhaseRowCountDivergence,
+		Environment:       m.config.Environment,
+		Table:             tableName,
+		Method:            method,
+		RowCount:          exact,
+		EstimatedRowCount: estimated,
+		DivergencePercent: divergence,
+		Timestamp:         time.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := m.events.Publish(ctx, event); err != nil {
+		m.logger.Warnf("Failed to publish %s event for %s: %v", event.Phase, tableName, err)
+	}
+}
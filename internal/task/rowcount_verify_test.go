@@ -0,0 +1,123 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/events"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowCountDivergence(t *testing.T) {
+	assert.InDelta(t, 0.0, rowCountDivergence(0, 0), 0.001)
+	assert.InDelta(t, 100.0, rowCountDivergence(10, 0), 0.001)
+	assert.InDelta(t, 10.0, rowCountDivergence(900, 1000), 0.001)
+	assert.InDelta(t, 400.0, rowCountDivergence(5000, 1000), 0.001)
+}
+
+func TestVerifyRowCount(t *testing.T) {
+	tests := []struct {
+		name        string
+		verify      config.RowCountVerifyConfig
+		exact       int64
+		countErr    error
+		wantMethod  string
+		wantRows    int64
+		wantWarning string
+	}{
+		{
+			name:       "disabled",
+			verify:     config.RowCountVerifyConfig{},
+			wantMethod: "alter-table",
+			wantRows:   900,
+		},
+		{
+			name:       "within divergence",
+			verify:     config.RowCountVerifyConfig{MaxDivergencePercent: 20},
+			exact:      1000,
+			wantMethod: "alter-table",
+			wantRows:   900,
+		},
+		{
+			name:        "warns without reevaluation",
+			verify:      config.RowCountVerifyConfig{MaxDivergencePercent: 20},
+			exact:       5000,
+			wantMethod:  "alter-table",
+			wantRows:    900,
+			wantWarning: "Row count estimate of users was 900 but COUNT(*) found 5000 (82.0% off, max_divergence_percent: 20); choosing alter-table may have been wrong",
+		},
+		{
+			name:        "reevaluates the method",
+			verify:      config.RowCountVerifyConfig{MaxDivergencePercent: 20, Reevaluate: true, CountTimeout: "30s"},
+			exact:       5000,
+			wantMethod:  "pt-osc",
+			wantRows:    5000,
+			wantWarning: "Method re-evaluated with COUNT(*): alter-table -> pt-osc",
+		},
+		{
+			name:       "count failure keeps the estimate",
+			verify:     config.RowCountVerifyConfig{MaxDivergencePercent: 20, Reevaluate: true},
+			countErr:   errors.New("Query execution was interrupted"),
+			wantMethod: "alter-table",
+			wantRows:   900,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			mockDB := &MockDBClient{}
+			timeout, _ := time.ParseDuration(tt.verify.CountTimeout)
+			mockDB.On("CountTableRows", "users", timeout).Return(tt.exact, tt.countErr).Maybe()
+			mockSlack := &MockSlackNotifier{}
+			mockSlack.On("NotifyWarning", "row-count-verify", "users", mock.Anything).Return(nil)
+
+			cfg := &config.Config{Environment: "prod", Common: config.CommonConfig{PtOscThreshold: 1000, RowCountVerify: tt.verify}}
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+			publisher := &recordingPublisher{}
+			manager.SetEventPublisher(publisher)
+
+			method, rows, err := manager.verifyRowCount("users", "alter-table", 900)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMethod, method)
+			assert.Equal(t, tt.wantRows, rows)
+
+			if tt.wantWarning == "" {
+				mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+				assert.Empty(t, publisher.events)
+				return
+			}
+			mockSlack.AssertCalled(t, "NotifyWarning", "row-count-verify", "users", mock.MatchedBy(func(message string) bool {
+				return assert.Contains(t, message, tt.wantWarning)
+			}))
+			require.Len(t, publisher.events, 1)
+			event := publisher.events[0]
+			assert.Equal(t, events.PhaseRowCountDivergence, event.Phase)
+			assert.Equal(t, int64(900), event.EstimatedRowCount)
+			assert.Equal(t, int64(5000), event.RowCount)
+			assert.InDelta(t, 82.0, event.DivergencePercent, 0.001)
+		})
+	}
+}
+
+func TestVerifyRowCountSkipsForcedMethod(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	cfg := &config.Config{Common: config.CommonConfig{ForceMethod: "ptosc", RowCountVerify: config.RowCountVerifyConfig{MaxDivergencePercent: 20}}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	method, rows, err := manager.verifyRowCount("users", "pt-osc", 900)
+	require.NoError(t, err)
+	assert.Equal(t, "pt-osc", method)
+	assert.Equal(t, int64(900), rows)
+	mockDB.AssertNotCalled(t, "CountTableRows", mock.Anything, mock.Anything)
+}
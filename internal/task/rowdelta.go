@@ -0,0 +1,55 @@
+package task
+
+import (
+	"fmt"
+)
+
+// swap 前の行数チェックで許す差(%)。expected_row_delta_percent を指定したテーブルでは、想定した増減からの差に使う
+const rowCountDiffThresholdPercent = 5.0
+
+// expectedRowDelta は pt_osc.tables に指定したテーブルの想定の行数の増減(%)を返す
+func (m *Manager) expectedRowDelta(tableName string) (float64, bool) {
+	override, ok := m.config.Common.PtOsc.Tables[tableName]
+	if !ok || override.ExpectedRowDeltaPercent == nil {
+		return 0, false
+	}
+	return *override.ExpectedRowDeltaPercent, true
+}
+
+// rowDeltaPercent は元テーブルに対する _new テーブルの行数の増減(%)を返す。元テーブルが空で _new テーブルに行があれば 100 とする
+func rowDeltaPercent(originalCount, newCount int64) float64 {
+	if originalCount == 0 {
+		if newCount == 0 {
+			return 0
+		}
+		return 100
+	}
+	return float64(newCount-originalCount) / float64(originalCount) * 100
+}
+
+// checkExpectedRowDelta は行数の増減が expected_row_delta_percent から rowCountDiffThresholdPercent ポイント以内かを確かめる。
+// 通常の行数チェックの代わりに使い、行数が変わることを想定したテーブルの swap を止めないようにする
+func (m *Manager) checkExpectedRowDelta(tableName string, originalCount, newCount int64, expected float64) error {
+	delta := rowDeltaPercent(originalCount, newCount)
+	if diff := delta - expected; diff < -rowCountDiffThresholdPercent || diff > rowCountDiffThresholdPercent {
+		errMsg := fmt.Sprintf("row count changed by %+.2f%% but %+.2f%% was expected (tolerance: %.2f points), original=%d, new=%d",
+			delta, expected, rowCountDiffThresholdPercent, originalCount, newCount)
+
+		m.logger.Errorf("Row count check failed for table %s: %s", tableName, errMsg)
+
+		taskName := "swap-row-count-check"
+		if m.dryRun {
+			taskName = "swap-row-count-check (DRY RUN)"
+		}
+
+		if slackErr := m.slack.NotifyWarning(taskName, tableName, errMsg); slackErr != nil {
+			m.logger.Errorf("Failed to send row count check warning notification: %v", slackErr)
+		}
+
+		return fmt.Errorf("%w: %s", ErrRowCountMismatch, errMsg)
+	}
+
+	m.logger.Infof("Row count check passed for table %s: changed by %+.2f%%, expected %+.2f%% (expected_row_delta_percent)",
+		tableName, delta, expected)
+	return nil
+}
@@ -0,0 +1,61 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckRowCountDifferenceWithExpectedDelta(t *testing.T) {
+	expected := -20.0
+
+	tests := []struct {
+		name     string
+		newCount int64
+		wantErr  bool
+	}{
+		{name: "soft-deleted rows dropped as expected", newCount: 800},
+		{name: "within tolerance of the expected delta", newCount: 840},
+		{name: "unchanged row count", newCount: 1000, wantErr: true},
+		{name: "more rows dropped than expected", newCount: 700, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			mockDB := &MockDBClient{}
+			mockDB.On("GetTableRowCountForSwap", "users").Return(int64(1000), nil)
+			mockDB.On("GetNewTableRowCountForSwap", "users").Return(tt.newCount, nil)
+
+			mockSlack := &MockSlackNotifier{}
+			if tt.wantErr {
+				mockSlack.On("NotifyWarning", "swap-row-count-check", "users", mock.Anything).Return(nil).Once()
+			}
+
+			cfg := &config.Config{Common: config.CommonConfig{PtOsc: config.PtOscConfig{
+				Tables: map[string]config.PtOscTableConfig{"users": {ExpectedRowDeltaPercent: &expected}},
+			}}}
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+			err := manager.checkRowCountDifference("users")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrRowCountMismatch)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockSlack.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRowDeltaPercent(t *testing.T) {
+	assert.Equal(t, -20.0, rowDeltaPercent(1000, 800))
+	assert.Equal(t, 10.0, rowDeltaPercent(1000, 1100))
+	assert.Equal(t, 0.0, rowDeltaPercent(0, 0))
+	assert.Equal(t, 100.0, rowDeltaPercent(0, 5))
+}
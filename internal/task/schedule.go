@@ -0,0 +1,171 @@
+package task
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/followup"
+)
+
+const (
+	scheduleActionPurge    = "purge"
+	scheduleActionCleanup  = "cleanup"
+	scheduleActionSwap     = "swap"
+	scheduleActionOptimize = "optimize"
+	scheduleActionFollowUp = "follow-up"
+
+	scheduleLockPrefix = "alterguard:schedule:"
+	// MySQL の GET_LOCK が受け付ける名前の長さ
+	maxLockNameLength = 64
+)
+
+// ValidateScheduledJob はジョブの action と必要な項目が揃っているかを確認する
+func ValidateScheduledJob(job config.ScheduledJobConfig) error {
+	if job.Name == "" {
+		return fmt.Errorf("scheduled job name is required")
+	}
+	switch job.Action {
+	case scheduleActionPurge, scheduleActionSwap, scheduleActionOptimize:
+		if job.Table == "" {
+			return fmt.Errorf("scheduled job %s: table is required for %s", job.Name, job.Action)
+		}
+	case scheduleActionCleanup:
+		if job.Table == "" {
+			return fmt.Errorf("scheduled job %s: table is required for cleanup", job.Name)
+		}
+		if !job.DropTriggers && !job.DropTable {
+			return fmt.Errorf("scheduled job %s: cleanup requires drop_triggers or drop_table", job.Name)
+		}
+	case scheduleActionFollowUp:
+		if job.File == "" {
+			return fmt.Errorf("scheduled job %s: file is required for follow-up", job.Name)
+		}
+	default:
+		return fmt.Errorf("scheduled job %s: unknown action %q", job.Name, job.Action)
+	}
+	return nil
+}
+
+// RunScheduledJob はジョブを1回実行する。
+// 同じジョブを別のプロセスが実行中であれば、名前付きロックが取れないため何もせずに終わる。
+// ctx が終わるか(schedule の停止など)ロックを失うと、実行中の purge も止める。
+func (m *Manager) RunScheduledJob(ctx context.Context, job config.ScheduledJobConfig) error {
+	lockName := scheduleLockName(job.Name)
+	lock, acquired, err := m.db.AcquireNamedLock(lockName)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		m.logger.Infof("Skipping scheduled job %s: another process holds lock %s", job.Name, lockName)
+		return nil
+	}
+	// ロックのコネクションは同じユーザーなので、swap などの接続チェックで数えない
+	m.lockConnectionIDs = append(m.lockConnectionIDs, lock.ConnectionID())
+	defer func() {
+		m.lockConnectionIDs = m.lockConnectionIDs[:len(m.lockConnectionIDs)-1]
+		if err := lock.Release(); err != nil {
+			m.logger.Warnf("Failed to release lock for scheduled job %s: %v", job.Name, err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lock.Lost():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err = m.runScheduledAction(ctx, job)
+	if err != nil {
+		select {
+		case <-lock.Lost():
+			err = fmt.Errorf("lost lock %s: %w", lockName, err)
+		default:
+		}
+		if slackErr := m.slack.NotifyFailure("schedule: "+job.Name, job.Table, 0, err); slackErr != nil {
+			m.logger.Errorf("Failed to send failure notification: %v", slackErr)
+		}
+	}
+	return err
+}
+
+// scheduleLockName はジョブの名前付きロックの名前を返す。
+// GET_LOCK の名前は64文字までなので、長いジョブ名はハッシュにする
+func scheduleLockName(jobName string) string {
+	lockName := scheduleLockPrefix + jobName
+	if len(lockName) <= maxLockNameLength {
+		return lockName
+	}
+	sum := sha256.Sum256([]byte(jobName))
+	return scheduleLockPrefix + hex.EncodeToString(sum[:])[:maxLockNameLength-len(scheduleLockPrefix)]
+}
+
+func (m *Manager) runScheduledAction(ctx context.Context, job config.ScheduledJobConfig) error {
+	switch job.Action {
+	case scheduleActionPurge:
+		return m.PurgeOldTableContext(ctx, job.Table)
+	case scheduleActionCleanup:
+		return m.executeCleanupStep(followup.Step{
+			Action:       followup.ActionCleanup,
+			Table:        job.Table,
+			DropTriggers: job.DropTriggers,
+			DropTable:    job.DropTable,
+		})
+	case scheduleActionSwap:
+		return m.SwapTableContext(ctx, job.Table)
+	case scheduleActionOptimize:
+		return m.OptimizeTable(job.Table)
+	case scheduleActionFollowUp:
+		file, err := followup.Load(job.File)
+		if err != nil {
+			return err
+		}
+		if file.Environment != m.config.Environment {
+			return fmt.Errorf("follow-up file %s was generated for environment %q but the current environment is %q",
+				job.File, file.Environment, m.config.Environment)
+		}
+		return m.ExecuteFollowUp(file)
+	default:
+		return fmt.Errorf("unknown action %q", job.Action)
+	}
+}
+
+// OptimizeTable は OPTIMIZE TABLE でテーブルを再構築し、削除で断片化した領域を解放する
+func (m *Manager) OptimizeTable(tableName string) error {
+	optimizeSQL := fmt.Sprintf("OPTIMIZE TABLE %s", tableName)
+	cleanedQuery := strings.ReplaceAll(optimizeSQL, "`", "")
+	quotedQuery := fmt.Sprintf("`%s`", cleanedQuery)
+
+	taskName := "optimize"
+	if m.dryRun {
+		taskName = "optimize (DRY RUN)"
+	}
+
+	if err := m.slack.NotifyStartWithQuery(taskName, tableName, quotedQuery, 0); err != nil {
+		m.logger.Errorf("Failed to send start notification: %v", err)
+	}
+
+	start := time.Now()
+
+	if m.dryRun {
+		m.logger.Infof("[DRY RUN] Would execute SQL: %s", optimizeSQL)
+	} else if err := m.db.ExecuteAlter(optimizeSQL); err != nil {
+		if slackErr := m.slack.NotifyFailureWithQuery(taskName, tableName, quotedQuery, 0, err); slackErr != nil {
+			m.logger.Errorf("Failed to send failure notification: %v", slackErr)
+		}
+		return fmt.Errorf("optimize table failed: %w", err)
+	}
+
+	if err := m.slack.NotifySuccessWithQuery(taskName, tableName, quotedQuery, 0, time.Since(start)); err != nil {
+		m.logger.Errorf("Failed to send success notification: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateScheduledJob(t *testing.T) {
+	tests := []struct {
+		name        string
+		job         config.ScheduledJobConfig
+		expectError string
+	}{
+		{name: "purge", job: config.ScheduledJobConfig{Name: "nightly", Action: "purge", Table: "logs_old"}},
+		{name: "follow-up", job: config.ScheduledJobConfig{Name: "deferred", Action: "follow-up", File: "follow-up.yaml"}},
+		{name: "missing name", job: config.ScheduledJobConfig{Action: "purge", Table: "logs_old"}, expectError: "name is required"},
+		{name: "missing table", job: config.ScheduledJobConfig{Name: "weekly", Action: "optimize"}, expectError: "table is required"},
+		{name: "cleanup without targets", job: config.ScheduledJobConfig{Name: "cleanup", Action: "cleanup", Table: "users"}, expectError: "drop_triggers or drop_table"},
+		{name: "follow-up without file", job: config.ScheduledJobConfig{Name: "deferred", Action: "follow-up"}, expectError: "file is required"},
+		{name: "unknown action", job: config.ScheduledJobConfig{Name: "x", Action: "truncate", Table: "users"}, expectError: "unknown action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateScheduledJob(tt.job)
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			}
+		})
+	}
+}
+
+func TestRunScheduledJob(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	job := config.ScheduledJobConfig{Name: "weekly-optimize", Action: "optimize", Table: "logs"}
+	optimizeQuery := "`OPTIMIZE TABLE logs`"
+
+	t.Run("lock held by another process", func(t *testing.T) {
+		mockDB := &MockDBClient{}
+		mockDB.On("AcquireNamedLock", "alterguard:schedule:weekly-optimize").Return(nil, false, nil)
+		mockSlack := &MockSlackNotifier{}
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+		require.NoError(t, manager.RunScheduledJob(context.Background(), job))
+		mockDB.AssertNotCalled(t, "ExecuteAlter", mock.Anything)
+		mockSlack.AssertNotCalled(t, "NotifyStartWithQuery", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("optimize", func(t *testing.T) {
+		lock := newFakeNamedLock(7)
+		mockDB := &MockDBClient{}
+		mockDB.On("AcquireNamedLock", "alterguard:schedule:weekly-optimize").Return(lock, true, nil)
+		mockDB.On("ExecuteAlter", "OPTIMIZE TABLE logs").Return(nil)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyStartWithQuery", "optimize", "logs", optimizeQuery, int64(0)).Return(nil)
+		mockSlack.On("NotifySuccessWithQuery", "optimize", "logs", optimizeQuery, int64(0), mock.Anything).Return(nil)
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+		require.NoError(t, manager.RunScheduledJob(context.Background(), job))
+		assert.True(t, lock.released)
+		mockDB.AssertExpectations(t)
+		mockSlack.AssertExpectations(t)
+	})
+
+	t.Run("failure is notified", func(t *testing.T) {
+		optimizeErr := errors.New("table is locked")
+		mockDB := &MockDBClient{}
+		mockDB.On("AcquireNamedLock", "alterguard:schedule:weekly-optimize").Return(newFakeNamedLock(7), true, nil)
+		mockDB.On("ExecuteAlter", "OPTIMIZE TABLE logs").Return(optimizeErr)
+		mockSlack := &MockSlackNotifier{}
+		mockSlack.On("NotifyStartWithQuery", "optimize", "logs", optimizeQuery, int64(0)).Return(nil)
+		mockSlack.On("NotifyFailureWithQuery", "optimize", "logs", optimizeQuery, int64(0), optimizeErr).Return(nil)
+		mockSlack.On("NotifyFailure", "schedule: weekly-optimize", "logs", int64(0), mock.Anything).Return(nil)
+		manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+
+		err := manager.RunScheduledJob(context.Background(), job)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, optimizeErr)
+		mockSlack.AssertExpectations(t)
+	})
+}
+
+func TestScheduleLockName(t *testing.T) {
+	assert.Equal(t, "alterguard:schedule:weekly-optimize", scheduleLockName("weekly-optimize"))
+
+	long := scheduleLockName(strings.Repeat("purge-orders-archive-", 4))
+	assert.Len(t, long, maxLockNameLength)
+	assert.True(t, strings.HasPrefix(long, "alterguard:schedule:"))
+	assert.NotEqual(t, long, scheduleLockName(strings.Repeat("purge-orders-archive-", 5)))
+}
+
+func TestRunScheduledJob_PurgeStopsWithContext(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	job := config.ScheduledJobConfig{Name: "nightly-purge", Action: "purge", Table: "users_old"}
+
+	mockDB := &MockDBClient{}
+	mockDB.On("AcquireNamedLock", "alterguard:schedule:nightly-purge").Return(newFakeNamedLock(7), true, nil)
+	archiver := &contextPtArchiverExecutor{}
+	archiver.On("ExecutePurge", "users_old", mock.Anything, mock.Anything, false).Return(nil)
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifyFailureWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0), mock.Anything).Return(nil)
+	mockSlack.On("NotifyFailure", "schedule: nightly-purge", "users_old", int64(0), mock.Anything).Return(nil)
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, archiver, mockSlack, logger, &config.Config{}, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := manager.RunScheduledJob(ctx, job)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestRunScheduledJob_SwapExcludesLockConnection(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	job := config.ScheduledJobConfig{Name: "weekly-swap", Action: "swap", Table: "users"}
+
+	mockDB, mockSlack := newAnalyzeSwapMocks()
+	lock := newFakeNamedLock(42)
+	mockDB.On("AcquireNamedLock", "alterguard:schedule:weekly-swap").Return(lock, true, nil)
+	// ロックを保持しているコネクションは同じユーザーのセッションとして数えない
+	mockDB.On("HasOtherActiveConnections", []int64{42}).Return(false, "migrator", nil).Once()
+	mockDB.On("ExecuteAlter", "RENAME TABLE users TO users_old, _users_new TO users").Return(nil)
+	cfg := &config.Config{Common: config.CommonConfig{
+		DisableAnalyzeTable: true,
+		ConnectionCheck:     config.ConnectionCheckConfig{Enabled: true},
+	}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	require.NoError(t, manager.RunScheduledJob(context.Background(), job))
+	assert.True(t, lock.released)
+	assert.Empty(t, manager.lockConnectionIDs)
+	mockDB.AssertExpectations(t)
+}
+
+func TestRunScheduledJob_LostLockStopsPurge(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	job := config.ScheduledJobConfig{Name: "nightly-purge", Action: "purge", Table: "users_old"}
+
+	lock := newFakeNamedLock(7)
+	mockDB := &MockDBClient{}
+	mockDB.On("AcquireNamedLock", "alterguard:schedule:nightly-purge").Return(lock, true, nil)
+	archiver := &contextPtArchiverExecutor{}
+	archiver.On("ExecutePurge", "users_old", mock.Anything, mock.Anything, false).Run(func(mock.Arguments) {
+		close(lock.lost)
+	}).Return(nil)
+	archiver.waitForCancel = true
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0)).Return(nil)
+	mockSlack.On("NotifyFailureWithQuery", "pt-archiver", "users_old", mock.Anything, int64(0), mock.Anything).Return(nil)
+	mockSlack.On("NotifyFailure", "schedule: nightly-purge", "users_old", int64(0), mock.Anything).Return(nil)
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, archiver, mockSlack, logger, &config.Config{}, false)
+
+	err := manager.RunScheduledJob(context.Background(), job)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "lost lock alterguard:schedule:nightly-purge")
+	assert.True(t, lock.released)
+}
+
+// fakeNamedLock はテスト用の名前付きロック
+type fakeNamedLock struct {
+	connectionID int64
+	lost         chan struct{}
+	released     bool
+}
+
+func newFakeNamedLock(connectionID int64) *fakeNamedLock {
+	return &fakeNamedLock{connectionID: connectionID, lost: make(chan struct{})}
+}
+
+func (l *fakeNamedLock) ConnectionID() int64 {
+	return l.connectionID
+}
+
+func (l *fakeNamedLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *fakeNamedLock) Release() error {
+	l.released = true
+	return nil
+}
+
+// contextPtArchiverExecutor は渡された ctx が終わっていればそのエラーを返す
+type contextPtArchiverExecutor struct {
+	MockPtArchiverExecutor
+	// true なら ctx が終わるまで待ってから返す
+	waitForCancel bool
+}
+
+func (e *contextPtArchiverExecutor) ExecutePurge(ctx context.Context, tableName string, ptArchiverConfig config.PtArchiverConfig, dsn string, dryRun bool) error {
+	if err := e.MockPtArchiverExecutor.ExecutePurge(ctx, tableName, ptArchiverConfig, dsn, dryRun); err != nil {
+		return err
+	}
+	if e.waitForCancel {
+		<-ctx.Done()
+	}
+	return ctx.Err()
+}
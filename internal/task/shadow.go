@@ -0,0 +1,148 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultShadowSchema は validate で使うシャドウスキーマ名のデフォルト
+const DefaultShadowSchema = "_alterguard_shadow"
+
+var shadowSchemaNameRe = regexp.MustCompile(`^[A-Za-z0-9_$]+$`)
+
+// ShadowValidationResult はシャドウスキーマで1文を実行した結果
+type ShadowValidationResult struct {
+	Query     string
+	TableName string
+	// 実行しなかった理由。実行した場合は空
+	Skipped  string
+	Err      error
+	Duration time.Duration
+}
+
+// ShadowValidationPassed はエラーになった文がなければ true を返す
+func ShadowValidationPassed(results []ShadowValidationResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatShadowValidation は文ごとの結果を OK/FAIL/SKIP の行にまとめる
+func FormatShadowValidation(results []ShadowValidationResult) string {
+	var b strings.Builder
+	var ok, failed, skipped int
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			failed++
+			fmt.Fprintf(&b, "FAIL %s\n     %v\n", result.Query, result.Err)
+		case result.Skipped != "":
+			skipped++
+			fmt.Fprintf(&b, "SKIP %s\n     %s\n", result.Query, result.Skipped)
+		default:
+			ok++
+			fmt.Fprintf(&b, "OK   %s (%s)\n", result.Query, result.Duration.Round(time.Millisecond))
+		}
+	}
+	fmt.Fprintf(&b, "%d ok, %d failed, %d skipped", ok, failed, skipped)
+	return b.String()
+}
+
+// ValidateInShadowSchema は対象テーブルの構造だけをシャドウスキーマに複製し、タスクの全クエリをそこで先に実行する。
+// 本番のテーブルに触れる前に構文エラーや適用できない変更を見つけるためのもの。
+// 1文が失敗しても残りの文の検証を続け、文ごとの結果を返す。シャドウスキーマは最後に削除する。
+func (m *Manager) ValidateInShadowSchema(schema string) ([]ShadowValidationResult, error) {
+	if !shadowSchemaNameRe.MatchString(schema) {
+		return nil, fmt.Errorf("invalid shadow schema name %q", schema)
+	}
+	if current, err := m.extractDatabaseNameFromDSN(); err == nil && strings.EqualFold(current, schema) {
+		return nil, fmt.Errorf("shadow schema %s must differ from the target database", schema)
+	}
+
+	queries, err := m.parseConfiguredQueries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse queries: %w", err)
+	}
+
+	var tables []string
+	seen := make(map[string]bool)
+	for _, query := range queries {
+		if query.TableName == "" || strings.Contains(query.TableName, ".") || seen[query.TableName] {
+			continue
+		}
+		seen[query.TableName] = true
+		exists, err := m.db.TableExists(query.TableName)
+		if err != nil {
+			return nil, err
+		}
+		// タスク内の CREATE TABLE で作られるテーブルは複製しない
+		if exists {
+			tables = append(tables, query.TableName)
+		}
+	}
+
+	if m.dryRun {
+		m.logger.Infof("[DRY RUN] Would create shadow schema %s with the structure of %s and execute %d queries there",
+			schema, strings.Join(tables, ", "), len(queries))
+		results := make([]ShadowValidationResult, 0, len(queries))
+		for _, query := range queries {
+			results = append(results, ShadowValidationResult{Query: query.Query, TableName: query.TableName, Skipped: "dry run"})
+		}
+		return results, nil
+	}
+
+	// 既存のスキーマは検証後に削除してしまうので使わない
+	exists, err := m.db.SchemaExists(schema)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("shadow schema %s already exists; drop it or choose another --shadow-schema", schema)
+	}
+
+	start := time.Now()
+	m.logger.Infof("Creating shadow schema %s with the structure of %d tables", schema, len(tables))
+	if err := m.db.CreateShadowSchema(schema, tables); err != nil {
+		return nil, err
+	}
+	// 作成に成功したスキーマだけを削除する
+	defer func() {
+		if err := m.db.DropSchema(schema); err != nil {
+			m.logger.Errorf("Failed to drop shadow schema %s: %v", schema, err)
+		}
+	}()
+
+	results := make([]ShadowValidationResult, 0, len(queries))
+	for _, query := range queries {
+		result := ShadowValidationResult{Query: query.Query, TableName: query.TableName}
+		switch {
+		case query.TableName == "":
+			// CREATE DATABASE などはシャドウスキーマに閉じないため実行しない
+			result.Skipped = "not a table statement"
+		case strings.Contains(query.TableName, "."):
+			result.Skipped = "schema-qualified table names would bypass the shadow schema"
+		default:
+			queryStart := time.Now()
+			result.Err = m.db.ExecuteInSchema(schema, query.Query)
+			result.Duration = time.Since(queryStart)
+		}
+		if result.Err != nil {
+			m.logger.Errorf("Shadow validation failed: %s: %v", query.Query, result.Err)
+		} else if result.Skipped != "" {
+			m.logger.Warnf("Shadow validation skipped: %s (%s)", query.Query, result.Skipped)
+		} else {
+			m.logger.Infof("Shadow validation passed: %s", query.Query)
+		}
+		results = append(results, result)
+	}
+
+	if err := m.slack.NotifyShadowValidation(schema, FormatShadowValidation(results), !ShadowValidationPassed(results), time.Since(start)); err != nil {
+		m.logger.Errorf("Failed to send shadow validation notification: %v", err)
+	}
+	return results, nil
+}
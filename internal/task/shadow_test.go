@@ -0,0 +1,125 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateInShadowSchema(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	queries := []string{
+		"CREATE TABLE logs (id INT PRIMARY KEY)",
+		"ALTER TABLE users ADD COLUMN age INT",
+		"ALTER TABLE users ADD COLUMN age INT",
+		"ALTER TABLE logs ADD INDEX idx_id (id)",
+		"CREATE DATABASE other",
+		"ALTER TABLE other.users ADD COLUMN age INT",
+	}
+	duplicate := errors.New("Error 1060: Duplicate column name 'age'")
+
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "logs").Return(false, nil)
+	mockDB.On("TableExists", "users").Return(true, nil)
+	mockDB.On("SchemaExists", "_alterguard_shadow").Return(false, nil)
+	mockDB.On("CreateShadowSchema", "_alterguard_shadow", []string{"users"}).Return(nil)
+	mockDB.On("ExecuteInSchema", "_alterguard_shadow", queries[0]).Return(nil)
+	mockDB.On("ExecuteInSchema", "_alterguard_shadow", queries[1]).Return(nil).Once()
+	mockDB.On("ExecuteInSchema", "_alterguard_shadow", queries[2]).Return(duplicate).Once()
+	mockDB.On("ExecuteInSchema", "_alterguard_shadow", queries[3]).Return(nil)
+	mockDB.On("DropSchema", "_alterguard_shadow").Return(nil)
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyShadowValidation", "_alterguard_shadow", mock.Anything, true, mock.Anything).Return(nil)
+
+	cfg := &config.Config{Queries: queries, DSN: "user:pass@tcp(localhost:3306)/app"}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	results, err := manager.ValidateInShadowSchema(DefaultShadowSchema)
+	require.NoError(t, err)
+	require.Len(t, results, 6)
+
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, duplicate, results[2].Err)
+	assert.NoError(t, results[3].Err)
+	assert.Equal(t, "not a table statement", results[4].Skipped)
+	assert.NotEmpty(t, results[5].Skipped)
+	assert.False(t, ShadowValidationPassed(results))
+	assert.Contains(t, FormatShadowValidation(results), "3 ok, 1 failed, 2 skipped")
+
+	mockDB.AssertCalled(t, "DropSchema", "_alterguard_shadow")
+	mockDB.AssertNotCalled(t, "ExecuteInSchema", mock.Anything, "CREATE DATABASE other")
+	mockSlack.AssertExpectations(t)
+}
+
+func TestValidateInShadowSchema_RejectsUnsafeSchema(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{Queries: []string{"ALTER TABLE users ADD COLUMN age INT"}, DSN: "user:pass@tcp(localhost:3306)/app"}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	_, err := manager.ValidateInShadowSchema("app")
+	assert.Error(t, err)
+
+	_, err = manager.ValidateInShadowSchema("shadow`; DROP DATABASE app; --")
+	assert.Error(t, err)
+}
+
+func TestValidateInShadowSchema_RefusesExistingSchema(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "users").Return(true, nil)
+	mockDB.On("SchemaExists", "_alterguard_shadow").Return(true, nil)
+
+	cfg := &config.Config{Queries: []string{"ALTER TABLE users ADD COLUMN age INT"}, DSN: "user:pass@tcp(localhost:3306)/app"}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	_, err := manager.ValidateInShadowSchema(DefaultShadowSchema)
+	assert.ErrorContains(t, err, "already exists")
+	mockDB.AssertNotCalled(t, "CreateShadowSchema", mock.Anything, mock.Anything)
+	mockDB.AssertNotCalled(t, "DropSchema", mock.Anything)
+}
+
+func TestValidateInShadowSchema_CreateFailureDoesNotDrop(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "users").Return(true, nil)
+	mockDB.On("SchemaExists", "_alterguard_shadow").Return(false, nil)
+	mockDB.On("CreateShadowSchema", "_alterguard_shadow", []string{"users"}).Return(errors.New("Error 1007: Can't create database"))
+
+	cfg := &config.Config{Queries: []string{"ALTER TABLE users ADD COLUMN age INT"}, DSN: "user:pass@tcp(localhost:3306)/app"}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	_, err := manager.ValidateInShadowSchema(DefaultShadowSchema)
+	assert.Error(t, err)
+	mockDB.AssertNotCalled(t, "DropSchema", mock.Anything)
+}
+
+func TestValidateInShadowSchema_DryRun(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "users").Return(true, nil)
+
+	cfg := &config.Config{Queries: []string{"ALTER TABLE users ADD COLUMN age INT"}, DSN: "user:pass@tcp(localhost:3306)/app"}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, true)
+
+	results, err := manager.ValidateInShadowSchema(DefaultShadowSchema)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "dry run", results[0].Skipped)
+	mockDB.AssertNotCalled(t, "CreateShadowSchema", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,131 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// events_[000-255] のようなシャードテーブル表記
+var shardPatternRe = regexp.MustCompile(`^(.*)\[(\d+)-(\d+)\](.*)$`)
+
+// maxShardTables は1つのシャード表記から展開するテーブル数の上限。書き間違いで大量のクエリを作らないようにする
+const maxShardTables = 10000
+
+// シャード表記を置き換えるテーブル名の位置。extractTableName と同じ順に探す
+var shardStatementRes = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + "`" + `?([^` + "`" + `\s]+)`),
+	regexp.MustCompile(`(?i)ALTER\s+TABLE\s+` + "`" + `?([^` + "`" + `\s]+)`),
+	regexp.MustCompile(`(?i)DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?` + "`" + `?([^` + "`" + `\s]+)`),
+}
+
+// expandShardTableName はシャード表記のテーブル名を個別のテーブル名に展開する。
+// シャード表記でない場合は nil を返す。開始側の桁数でゼロ埋めする。
+func expandShardTableName(tableName string) ([]string, error) {
+	matches := shardPatternRe.FindStringSubmatch(tableName)
+	if matches == nil {
+		return nil, nil
+	}
+
+	prefix, startStr, endStr, suffix := matches[1], matches[2], matches[3], matches[4]
+	if strings.ContainsAny(prefix+suffix, "[]") {
+		return nil, fmt.Errorf("only one shard range is supported in table name: %s", tableName)
+	}
+
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard range start in %s: %w", tableName, err)
+	}
+	end, err := strconv.Atoi(endStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard range end in %s: %w", tableName, err)
+	}
+	if start > end {
+		return nil, fmt.Errorf("invalid shard range in %s: start %d is greater than end %d", tableName, start, end)
+	}
+	if count := end - start + 1; count > maxShardTables {
+		return nil, fmt.Errorf("invalid shard range in %s: it expands to %d tables, more than the limit of %d", tableName, count, maxShardTables)
+	}
+
+	width := len(startStr)
+	tables := make([]string, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		tables = append(tables, fmt.Sprintf("%s%0*d%s", prefix, width, i, suffix))
+	}
+	return tables, nil
+}
+
+// expandShardQuery はクエリ中のシャード表記のテーブル名を展開し、テーブルごとのクエリを返す
+func (m *Manager) expandShardQuery(query QueryInfo) ([]QueryInfo, error) {
+	tables, err := expandShardTableName(query.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if tables == nil {
+		return []QueryInfo{query}, nil
+	}
+
+	m.logger.Infof("Expanding sharded table %s into %d tables", query.TableName, len(tables))
+
+	result := make([]QueryInfo, 0, len(tables))
+	for _, table := range tables {
+		expanded, err := replaceStatementTable(query.Query, query.TableName, table)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, QueryInfo{
+			Query:         expanded,
+			QueryType:     query.QueryType,
+			TableName:     table,
+			ShardPattern:  query.TableName,
+			Isolated:      query.Isolated,
+			IgnoredErrors: query.IgnoredErrors,
+		})
+	}
+	return result, nil
+}
+
+// replaceStatementTable は CREATE TABLE / ALTER TABLE / DROP TABLE の直後にあるテーブル名だけを置き換える。
+// 列名や COMMENT の文字列などに同じ文字列があっても書き換えない
+func replaceStatementTable(query, from, to string) (string, error) {
+	for _, re := range shardStatementRes {
+		loc := re.FindStringSubmatchIndex(query)
+		if loc == nil {
+			continue
+		}
+		start, end := loc[2], loc[3]
+		if query[start:end] != from {
+			break
+		}
+		return query[:start] + to + query[end:], nil
+	}
+	return "", fmt.Errorf("could not find table %s after CREATE TABLE, ALTER TABLE or DROP TABLE in: %s", from, query)
+}
+
+// notifyShardSummaries はシャード表記ごとに実行方式の集計を通知する
+func (m *Manager) notifyShardSummaries(groups []*TableGroup, duration time.Duration) {
+	var patterns []string
+	summaries := make(map[string]map[string]int)
+	tableCounts := make(map[string]int)
+
+	for _, group := range groups {
+		if group.ShardPattern == "" {
+			continue
+		}
+		if _, ok := summaries[group.ShardPattern]; !ok {
+			patterns = append(patterns, group.ShardPattern)
+			summaries[group.ShardPattern] = make(map[string]int)
+		}
+		summaries[group.ShardPattern][group.Method]++
+		tableCounts[group.ShardPattern]++
+	}
+
+	for _, pattern := range patterns {
+		m.logger.Infof("Sharded tables %s completed: %d tables, methods: %v", pattern, tableCounts[pattern], summaries[pattern])
+		if err := m.slack.NotifyShardSummary(pattern, tableCounts[pattern], summaries[pattern], duration); err != nil {
+			m.logger.Errorf("Failed to send shard summary notification: %v", err)
+		}
+	}
+}
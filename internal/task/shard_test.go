@@ -0,0 +1,161 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandShardTableName(t *testing.T) {
+	tests := []struct {
+		name      string
+		tableName string
+		want      []string
+		wantLen   int
+		wantErr   bool
+	}{
+		{
+			name:      "not sharded",
+			tableName: "events",
+			want:      nil,
+		},
+		{
+			name:      "zero padded range",
+			tableName: "events_[000-002]",
+			want:      []string{"events_000", "events_001", "events_002"},
+		},
+		{
+			name:      "unpadded range with suffix",
+			tableName: "log_[8-10]_archive",
+			want:      []string{"log_8_archive", "log_9_archive", "log_10_archive"},
+		},
+		{
+			name:      "reversed range",
+			tableName: "events_[5-1]",
+			wantErr:   true,
+		},
+		{
+			name:      "multiple ranges",
+			tableName: "events_[0-1]_[0-1]",
+			wantErr:   true,
+		},
+		{
+			name:      "range at the limit",
+			tableName: "events_[1-10000]",
+			wantLen:   maxShardTables,
+		},
+		{
+			name:      "range over the limit",
+			tableName: "events_[0-10000]",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandShardTableName(tt.tableName)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantLen > 0 {
+				assert.Len(t, got, tt.wantLen)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReplaceStatementTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "column with the same name",
+			query: "ALTER TABLE events_[0-3] ADD COLUMN events_[0-3]_id INT",
+			want:  "ALTER TABLE events_001 ADD COLUMN events_[0-3]_id INT",
+		},
+		{
+			name:  "comment mentions the table first",
+			query: "/* events_[0-3] */ ALTER TABLE `events_[0-3]` ADD INDEX idx_created (created_at)",
+			want:  "/* events_[0-3] */ ALTER TABLE `events_001` ADD INDEX idx_created (created_at)",
+		},
+		{
+			name:  "create table",
+			query: "CREATE TABLE IF NOT EXISTS events_[0-3] (id INT, note VARCHAR(20) DEFAULT 'events_[0-3]')",
+			want:  "CREATE TABLE IF NOT EXISTS events_001 (id INT, note VARCHAR(20) DEFAULT 'events_[0-3]')",
+		},
+		{
+			name:  "drop table",
+			query: "DROP TABLE IF EXISTS events_[0-3]",
+			want:  "DROP TABLE IF EXISTS events_001",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := replaceStatementTable(tt.query, "events_[0-3]", "events_001")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := replaceStatementTable("ALTER TABLE users ADD COLUMN events_[0-3] INT", "events_[0-3]", "events_001")
+	assert.Error(t, err)
+}
+
+func TestExecuteAllTasks_ShardedTables(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableStorage", mock.Anything).Return(&database.TableStorage{Engine: "InnoDB"}, nil).Maybe()
+	mockDB.On("GetTableRowCounts", mock.Anything).Return(map[string]int64{}, nil).Maybe()
+	mockDB.On("GetTableStructure", mock.Anything).Return(&database.TableStructure{}, nil).Maybe()
+	mockPtOsc := &MockPtOscExecutor{}
+	mockSlack := &MockSlackNotifier{}
+
+	queries := []string{"ALTER TABLE `events_[00-02]` ADD COLUMN foo INT"}
+
+	mockDB.On("GetTableRowCount", "events_00").Return(int64(10), nil)
+	mockDB.On("GetTableRowCount", "events_01").Return(int64(20), nil)
+	mockDB.On("GetTableRowCount", "events_02").Return(int64(5000), nil)
+	mockDB.On("ExecuteAlterWithAlgorithm", "events_00", "ALTER TABLE events_00 ADD COLUMN foo INT").Return(&database.AlterAlgorithm{Algorithm: database.AlterAlgorithmInstant}, nil)
+	mockDB.On("ExecuteAlterWithAlgorithm", "events_01", "ALTER TABLE events_01 ADD COLUMN foo INT").Return(&database.AlterAlgorithm{Algorithm: database.AlterAlgorithmInstant}, nil)
+	mockDB.On("CheckNewTableExists", "events_02").Return(false, nil)
+	mockDB.On("GetNewTableRowCount", "events_02").Return(int64(5000), nil)
+	mockPtOsc.On("ExecuteAlter", "events_02", "ADD COLUMN foo INT", config.PtOscConfig{}, "test-dsn", false).Return(nil)
+
+	mockSlack.On("NotifyAllTasksStart", 3).Return(nil)
+	mockSlack.On("NotifyStartWithQuery", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSlack.On("NotifyStartWithQueryAndStorage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSlack.On("NotifySuccessWithQueryAndAlgorithm", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "instant").Return(nil)
+	mockSlack.On("NotifyPtOscCompletionWithNewTableCount", "pt-osc", "events_02", int64(5000), int64(5000), mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSlack.On("NotifyShardSummary", "events_[00-02]", 3, map[string]int{"alter-table": 2, "pt-osc": 1}, mock.Anything).Return(nil)
+	mockSlack.On("NotifyAllTasksSuccess", 3, mock.Anything).Return(nil)
+
+	cfg := &config.Config{
+		Queries: queries,
+		Common: config.CommonConfig{
+			PtOscThreshold: 1000,
+		},
+		DSN: "test-dsn",
+	}
+
+	manager := NewManager(mockDB, mockPtOsc, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	err := manager.ExecuteAllTasks()
+
+	require.NoError(t, err)
+	mockDB.AssertExpectations(t)
+	mockPtOsc.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+}
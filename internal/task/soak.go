@@ -0,0 +1,183 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSoakCheckInterval = 30 * time.Second
+	healthCheckTimeout       = 10 * time.Second
+)
+
+var healthHTTPClient = &http.Client{Timeout: healthCheckTimeout}
+
+// soakSettings は swap_soak を解釈した結果
+type soakSettings struct {
+	duration    time.Duration
+	interval    time.Duration
+	maxFailures int
+}
+
+// resolveSwapSoak は swap_soak を検証する。swap してから設定の誤りに気付かないよう、swap 前に呼ぶ。
+// 無効であれば nil を返す。
+func (m *Manager) resolveSwapSoak() (*soakSettings, error) {
+	soak := m.config.Common.SwapSoak
+	if soak.Duration == "" {
+		return nil, nil
+	}
+	if soak.HealthQuery == "" && soak.HealthURL == "" && !soak.ErrorLog.Enabled && soak.ErrorLog.RateURL == "" {
+		return nil, fmt.Errorf("swap_soak requires health_query or health_url, or error_log")
+	}
+	// 元に戻すと、swap 後にアプリケーションが書き込んだ行は _new 側に残り、戻したテーブルには無い
+	if !soak.AllowWriteLoss {
+		return nil, fmt.Errorf("swap_soak reverts the swap with RENAME, which drops writes made after the swap from the restored table; set swap_soak.allow_write_loss: true to accept this")
+	}
+	if soak.ErrorLog.MaxErrors < 0 {
+		return nil, fmt.Errorf("invalid swap_soak.error_log.max_errors %d (must be >= 0)", soak.ErrorLog.MaxErrors)
+	}
+
+	duration, err := resolveRollingDuration(soak.Duration, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid swap_soak.duration: %w", err)
+	}
+	interval, err := resolveRollingDuration(soak.CheckInterval, defaultSoakCheckInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid swap_soak.check_interval: %w", err)
+	}
+	maxFailures := soak.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = 1
+	}
+	return &soakSettings{duration: duration, interval: interval, maxFailures: maxFailures}, nil
+}
+
+// soakSwap は swap 後 swap_soak.duration の間ヘルスチェックを繰り返し、
+// 連続して max_failures 回失敗したら RENAME で元に戻す。
+// ctx がキャンセルされたら元に戻さずに待機をやめ、soak を終えられなかったことをエラーで返す
+func (m *Manager) soakSwap(ctx context.Context, tableName, oldTableName string, settings *soakSettings) error {
+	if settings == nil {
+		return nil
+	}
+	duration, interval, maxFailures := settings.duration, settings.interval, settings.maxFailures
+
+	m.logger.Infof("Soaking swap of %s for %s (check every %s)", tableName, duration, interval)
+	deadline := time.Now().Add(duration)
+	failures := 0
+	lastCheck := time.Now()
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			m.logger.Warnf("Soak of %s stopped before the %s soak period ended; the swap is kept", tableName, duration)
+			return fmt.Errorf("swap of %s completed but the soak was interrupted: %w", tableName, ctx.Err())
+		case <-time.After(interval):
+		}
+
+		err := m.checkSwapHealth()
+		if err == nil {
+			err = m.checkErrorVolume(tableName, time.Since(lastCheck))
+		}
+		lastCheck = time.Now()
+		if err != nil {
+			failures++
+			m.logger.Warnf("Swap health check failed for %s (%d/%d): %v", tableName, failures, maxFailures, err)
+			if failures >= maxFailures {
+				return m.revertSwap(tableName, oldTableName, err)
+			}
+			continue
+		}
+		failures = 0
+	}
+
+	m.logger.Infof("Swap of %s passed the %s soak period", tableName, duration)
+	return nil
+}
+
+func (m *Manager) checkSwapHealth() error {
+	soak := m.config.Common.SwapSoak
+
+	if soak.HealthQuery != "" {
+		healthy, err := m.db.EvaluateHealthQuery(soak.HealthQuery)
+		if err != nil {
+			return err
+		}
+		if !healthy {
+			return fmt.Errorf("health query returned a false value: %s", soak.HealthQuery)
+		}
+	}
+
+	if soak.HealthURL != "" {
+		resp, err := healthHTTPClient.Get(soak.HealthURL)
+		if err != nil {
+			return fmt.Errorf("health check request failed: %w", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("health check URL returned %s", resp.Status)
+		}
+	}
+
+	return nil
+}
+
+// checkErrorVolume は直近 window のエラー数が swap_soak.error_log.max_errors を超えていないかを確認する
+func (m *Manager) checkErrorVolume(tableName string, window time.Duration) error {
+	errorLog := m.config.Common.SwapSoak.ErrorLog
+
+	if errorLog.Enabled {
+		count, err := m.db.CountErrorLogEntries(tableName, window)
+		if err != nil {
+			return err
+		}
+		if count > int64(errorLog.MaxErrors) {
+			return fmt.Errorf("%d errors mentioning %s in the error log within %s (max %d)",
+				count, tableName, window.Round(time.Second), errorLog.MaxErrors)
+		}
+	}
+
+	if errorLog.RateURL != "" {
+		resp, err := healthHTTPClient.Get(errorLog.RateURL)
+		if err != nil {
+			return fmt.Errorf("error rate request failed: %w", err)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read error rate response: %w", err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("error rate URL returned %s", resp.Status)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(string(body)), 64)
+		if err != nil {
+			return fmt.Errorf("error rate URL returned a non-numeric body: %q", strings.TrimSpace(string(body)))
+		}
+		if rate > float64(errorLog.MaxErrors) {
+			return fmt.Errorf("error rate %g reported by %s exceeds %d", rate, errorLog.RateURL, errorLog.MaxErrors)
+		}
+	}
+
+	return nil
+}
+
+// revertSwap は swap と逆向きの RENAME で元のテーブルを戻す
+func (m *Manager) revertSwap(tableName, oldTableName string, reason error) error {
+	revertSQL := fmt.Sprintf("RENAME TABLE %s TO _%s_new, %s TO %s",
+		tableName, tableName, oldTableName, tableName)
+	m.logger.Warnf("Reverting swap of %s: %s (writes made after the swap stay in _%s_new)", tableName, revertSQL, tableName)
+
+	revertErr := m.db.ExecuteAlter(revertSQL)
+	if slackErr := m.slack.NotifySwapReverted(tableName, reason.Error(), revertErr); slackErr != nil {
+		m.logger.Errorf("Failed to send swap revert notification: %v", slackErr)
+	}
+	if revertErr != nil {
+		return fmt.Errorf("swap health check failed (%v) and revert failed: %w", reason, revertErr)
+	}
+	return fmt.Errorf("swap of %s reverted after failed health check: %w", tableName, reason)
+}
@@ -0,0 +1,176 @@
+package task
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSwapSoak(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	newManager := func(soak config.SwapSoakConfig) *Manager {
+		cfg := &config.Config{Common: config.CommonConfig{SwapSoak: soak}}
+		return NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+	}
+
+	settings, err := newManager(config.SwapSoakConfig{}).resolveSwapSoak()
+	require.NoError(t, err)
+	assert.Nil(t, settings)
+
+	_, err = newManager(config.SwapSoakConfig{Duration: "5m"}).resolveSwapSoak()
+	assert.ErrorContains(t, err, "requires health_query or health_url, or error_log")
+
+	_, err = newManager(config.SwapSoakConfig{Duration: "5m", HealthQuery: "SELECT 1"}).resolveSwapSoak()
+	assert.ErrorContains(t, err, "set swap_soak.allow_write_loss: true")
+
+	_, err = newManager(config.SwapSoakConfig{Duration: "5m", AllowWriteLoss: true, ErrorLog: config.SwapErrorLogConfig{Enabled: true, MaxErrors: -1}}).resolveSwapSoak()
+	assert.ErrorContains(t, err, "invalid swap_soak.error_log.max_errors")
+
+	_, err = newManager(config.SwapSoakConfig{Duration: "soon", HealthQuery: "SELECT 1", AllowWriteLoss: true}).resolveSwapSoak()
+	assert.ErrorContains(t, err, "invalid swap_soak.duration")
+
+	settings, err = newManager(config.SwapSoakConfig{Duration: "5m", HealthQuery: "SELECT 1", AllowWriteLoss: true}).resolveSwapSoak()
+	require.NoError(t, err)
+	assert.Equal(t, &soakSettings{duration: 5 * time.Minute, interval: defaultSoakCheckInterval, maxFailures: 1}, settings)
+}
+
+func TestSoakSwapRevertsOnFailedHealthQuery(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("EvaluateHealthQuery", "SELECT errors < 10 FROM app_health").Return(true, nil).Once()
+	mockDB.On("EvaluateHealthQuery", "SELECT errors < 10 FROM app_health").Return(false, nil).Twice()
+	mockDB.On("ExecuteAlter", "RENAME TABLE users TO _users_new, users_old TO users").Return(nil)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifySwapReverted", "users", mock.AnythingOfType("string"), nil).Return(nil)
+
+	cfg := &config.Config{Common: config.CommonConfig{SwapSoak: config.SwapSoakConfig{
+		HealthQuery: "SELECT errors < 10 FROM app_health",
+	}}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	err := manager.soakSwap(context.Background(), "users", "users_old", &soakSettings{duration: time.Minute, interval: time.Millisecond, maxFailures: 2})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "swap of users reverted after failed health check")
+	mockDB.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestSoakSwapPassesWithHealthyURL(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	mockDB := &MockDBClient{}
+	cfg := &config.Config{Common: config.CommonConfig{SwapSoak: config.SwapSoakConfig{HealthURL: server.URL}}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	err := manager.soakSwap(context.Background(), "users", "users_old", &soakSettings{duration: 20 * time.Millisecond, interval: 5 * time.Millisecond, maxFailures: 1})
+
+	require.NoError(t, err)
+	mockDB.AssertNotCalled(t, "ExecuteAlter", mock.Anything)
+}
+
+func TestSoakSwapStopsWhenCanceled(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	cfg := &config.Config{Common: config.CommonConfig{SwapSoak: config.SwapSoakConfig{HealthQuery: "SELECT 1"}}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	err := manager.soakSwap(ctx, "users", "users_old", &soakSettings{duration: time.Hour, interval: time.Hour, maxFailures: 1})
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "soak was interrupted")
+	assert.Less(t, time.Since(start), time.Minute)
+	mockDB.AssertNotCalled(t, "ExecuteAlter", mock.Anything)
+}
+
+func TestCheckSwapHealthURLFailure(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Common: config.CommonConfig{SwapSoak: config.SwapSoakConfig{HealthURL: server.URL}}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	err := manager.checkSwapHealth()
+	assert.ErrorContains(t, err, "503")
+}
+
+func TestSoakSwapRevertsOnErrorLogSpike(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("CountErrorLogEntries", "users", mock.AnythingOfType("time.Duration")).Return(int64(2), nil).Once()
+	mockDB.On("CountErrorLogEntries", "users", mock.AnythingOfType("time.Duration")).Return(int64(50), nil).Once()
+	mockDB.On("ExecuteAlter", "RENAME TABLE users TO _users_new, users_old TO users").Return(nil)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifySwapReverted", "users", mock.MatchedBy(func(reason string) bool {
+		return strings.Contains(reason, "50 errors mentioning users")
+	}), nil).Return(nil)
+
+	cfg := &config.Config{Common: config.CommonConfig{SwapSoak: config.SwapSoakConfig{
+		ErrorLog: config.SwapErrorLogConfig{Enabled: true, MaxErrors: 10},
+	}}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	err := manager.soakSwap(context.Background(), "users", "users_old", &soakSettings{duration: time.Minute, interval: time.Millisecond, maxFailures: 1})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "swap of users reverted")
+	mockDB.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestCheckErrorVolumeRateURL(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	body := "3"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body + "\n"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Common: config.CommonConfig{SwapSoak: config.SwapSoakConfig{
+		ErrorLog: config.SwapErrorLogConfig{RateURL: server.URL, MaxErrors: 5},
+	}}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	assert.NoError(t, manager.checkErrorVolume("users", time.Minute))
+
+	body = "12.5"
+	assert.ErrorContains(t, manager.checkErrorVolume("users", time.Minute), "error rate 12.5")
+
+	body = "many"
+	assert.ErrorContains(t, manager.checkErrorVolume("users", time.Minute), "non-numeric")
+}
@@ -0,0 +1,116 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pyama86/alterguard/internal/database"
+)
+
+// TableStatus は pt-osc 実行後の swap/cleanup 前後の状態確認に使うテーブルの状態
+type TableStatus struct {
+	TableName      string
+	Exists         bool
+	RowCount       int64
+	NewTableExists bool
+	NewRowCount    int64
+	OldTableExists bool
+	OldRowCount    int64
+}
+
+// GetTableStatus は元テーブル・_new テーブル・_old テーブルの有無と行数を取得する
+func (m *Manager) GetTableStatus(tableName string) (*TableStatus, error) {
+	status := &TableStatus{TableName: tableName}
+
+	tables := []struct {
+		name   string
+		exists *bool
+		count  *int64
+	}{
+		{name: tableName, exists: &status.Exists, count: &status.RowCount},
+		{name: fmt.Sprintf("_%s_new", tableName), exists: &status.NewTableExists, count: &status.NewRowCount},
+		{name: fmt.Sprintf("%s_old", tableName), exists: &status.OldTableExists, count: &status.OldRowCount},
+	}
+
+	for _, table := range tables {
+		exists, err := m.db.TableExists(table.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existence of %s: %w", table.name, err)
+		}
+		*table.exists = exists
+		if !exists {
+			continue
+		}
+		count, err := m.db.GetTableRowCount(table.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get row count of %s: %w", table.name, err)
+		}
+		*table.count = count
+	}
+
+	return status, nil
+}
+
+func (s *TableStatus) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Table: %s", s.TableName)
+	for _, line := range []struct {
+		label  string
+		exists bool
+		count  int64
+	}{
+		{label: s.TableName, exists: s.Exists, count: s.RowCount},
+		{label: fmt.Sprintf("_%s_new", s.TableName), exists: s.NewTableExists, count: s.NewRowCount},
+		{label: fmt.Sprintf("%s_old", s.TableName), exists: s.OldTableExists, count: s.OldRowCount},
+	} {
+		if line.exists {
+			fmt.Fprintf(&b, "\n%s: %d rows", line.label, line.count)
+		} else {
+			fmt.Fprintf(&b, "\n%s: not found", line.label)
+		}
+	}
+	return b.String()
+}
+
+// PtOscInventory はインスタンス上で実行中の pt-osc の一覧。タスクファイルにないテーブルや他のチームの pt-osc も含む
+type PtOscInventory struct {
+	Operations []database.PtOscOperation
+}
+
+// GetPtOscInventory はインスタンス全体で実行中の pt-osc を、トリガーと PROCESSLIST から探す
+func (m *Manager) GetPtOscInventory() (*PtOscInventory, error) {
+	operations, err := m.db.GetRunningPtOscOperations()
+	if err != nil {
+		return nil, err
+	}
+	return &PtOscInventory{Operations: operations}, nil
+}
+
+func (i *PtOscInventory) String() string {
+	if len(i.Operations) == 0 {
+		return "No pt-osc operations running on this instance"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Running pt-osc operations: %d", len(i.Operations))
+	for _, op := range i.Operations {
+		switch {
+		case op.Table == "":
+			fmt.Fprintf(&b, "\n%s (table unknown)", orDefault(op.Schema, "(no database)"))
+		case op.Schema == "":
+			fmt.Fprintf(&b, "\n%s", op.Table)
+		default:
+			fmt.Fprintf(&b, "\n%s.%s", op.Schema, op.Table)
+		}
+		if len(op.Triggers) > 0 {
+			fmt.Fprintf(&b, "\n  triggers: %s", strings.Join(op.Triggers, ", "))
+		}
+		if op.NewTableExists {
+			fmt.Fprintf(&b, "\n  _%s_new: exists", op.Table)
+		}
+		for _, process := range op.Processes {
+			fmt.Fprintf(&b, "\n  session: id=%d user=%s host=%s time=%ds query=%q", process.ID, process.User, process.Host, process.Time, process.Info)
+		}
+	}
+	return b.String()
+}
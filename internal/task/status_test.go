@@ -0,0 +1,69 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTableStatus(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("TableExists", "users").Return(true, nil)
+	mockDB.On("GetTableRowCount", "users").Return(int64(1000), nil)
+	mockDB.On("TableExists", "_users_new").Return(true, nil)
+	mockDB.On("GetTableRowCount", "_users_new").Return(int64(998), nil)
+	mockDB.On("TableExists", "users_old").Return(false, nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	status, err := manager.GetTableStatus("users")
+
+	require.NoError(t, err)
+	assert.Equal(t, &TableStatus{
+		TableName:      "users",
+		Exists:         true,
+		RowCount:       1000,
+		NewTableExists: true,
+		NewRowCount:    998,
+	}, status)
+	assert.Equal(t, "Table: users\nusers: 1000 rows\n_users_new: 998 rows\nusers_old: not found", status.String())
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetPtOscInventory(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetRunningPtOscOperations").Return([]database.PtOscOperation{
+		{
+			Schema:         "billing",
+			Table:          "invoices",
+			Triggers:       []string{"pt_osc_billing_invoices_del", "pt_osc_billing_invoices_ins"},
+			NewTableExists: true,
+			Processes:      []database.PtOscProcess{{ID: 42, User: "billing", Host: "10.0.0.5:50000", Time: 12, Info: "INSERT LOW_PRIORITY IGNORE INTO `billing`.`_invoices_new`"}},
+		},
+		{Schema: "logs", Processes: []database.PtOscProcess{{ID: 43, User: "ops"}}},
+	}, nil).Once()
+	mockDB.On("GetRunningPtOscOperations").Return([]database.PtOscOperation{}, nil).Once()
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+
+	inventory, err := manager.GetPtOscInventory()
+	require.NoError(t, err)
+	output := inventory.String()
+	assert.Contains(t, output, "Running pt-osc operations: 2")
+	assert.Contains(t, output, "billing.invoices\n  triggers: pt_osc_billing_invoices_del, pt_osc_billing_invoices_ins\n  _invoices_new: exists")
+	assert.Contains(t, output, "session: id=42 user=billing host=10.0.0.5:50000 time=12s")
+	assert.Contains(t, output, "logs (table unknown)")
+
+	inventory, err = manager.GetPtOscInventory()
+	require.NoError(t, err)
+	assert.Equal(t, "No pt-osc operations running on this instance", inventory.String())
+}
@@ -0,0 +1,61 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	stdinOverlapWarn  = "warn"
+	stdinOverlapAbort = "abort"
+)
+
+// checkStdinOverlap は --stdin とタスクファイルの両方が同じテーブルを変更していないかを確かめる。
+// 同じ変更を2つの経路で渡して二重に ALTER してしまうのを防ぐため、stdin_overlap が abort ならエラーにし、
+// それ以外は警告を通知して続ける
+func (m *Manager) checkStdinOverlap() error {
+	policy := m.config.Common.StdinOverlap
+	switch policy {
+	case "", stdinOverlapWarn, stdinOverlapAbort:
+	default:
+		return fmt.Errorf("invalid stdin_overlap %q (must be warn or abort)", policy)
+	}
+
+	stdinQueries := m.config.StdinQueries
+	fileCount := len(m.config.Queries) - len(stdinQueries)
+	if len(stdinQueries) == 0 || fileCount <= 0 {
+		return nil
+	}
+
+	fileTables := make(map[string]bool)
+	for _, query := range m.config.Queries[:fileCount] {
+		if tableName := m.extractTableName(query); tableName != "" {
+			fileTables[tableName] = true
+		}
+	}
+
+	var overlapping []string
+	seen := make(map[string]bool)
+	for _, query := range stdinQueries {
+		tableName := m.extractTableName(query)
+		if tableName == "" || !fileTables[tableName] || seen[tableName] {
+			continue
+		}
+		seen[tableName] = true
+		overlapping = append(overlapping, tableName)
+	}
+	if len(overlapping) == 0 {
+		return nil
+	}
+
+	tables := strings.Join(overlapping, ", ")
+	message := fmt.Sprintf("Both the tasks file and stdin change %s; the same change may be applied twice", tables)
+	if policy == stdinOverlapAbort {
+		return fmt.Errorf("%s (stdin_overlap: abort)", message)
+	}
+	m.logger.Warn(message)
+	if err := m.slack.NotifyWarning("stdin-overlap", tables, message); err != nil {
+		m.logger.Errorf("Failed to send warning notification: %v", err)
+	}
+	return nil
+}
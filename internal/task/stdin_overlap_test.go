@@ -0,0 +1,57 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckStdinOverlap(t *testing.T) {
+	fileQueries := []string{
+		"ALTER TABLE users ADD COLUMN name VARCHAR(10)",
+		"ALTER TABLE `orders` ADD INDEX idx_user (user_id)",
+	}
+
+	tests := []struct {
+		name         string
+		policy       string
+		stdinQueries []string
+		wantWarning  bool
+		wantErr      string
+	}{
+		{name: "no stdin", policy: "abort"},
+		{name: "different tables", stdinQueries: []string{"ALTER TABLE payments ADD COLUMN note TEXT"}},
+		{name: "overlap warns", stdinQueries: []string{"ALTER TABLE orders ADD COLUMN note TEXT"}, wantWarning: true},
+		{name: "overlap aborts", policy: "abort", stdinQueries: []string{"ALTER TABLE users ADD COLUMN age INT"}, wantErr: "Both the tasks file and stdin change users"},
+		{name: "invalid policy", policy: "ignore", wantErr: "invalid stdin_overlap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			cfg := &config.Config{
+				Common:       config.CommonConfig{StdinOverlap: tt.policy},
+				Queries:      append(append([]string{}, fileQueries...), tt.stdinQueries...),
+				StdinQueries: tt.stdinQueries,
+			}
+			mockSlack := &MockSlackNotifier{}
+			if tt.wantWarning {
+				mockSlack.On("NotifyWarning", "stdin-overlap", "orders", mock.Anything).Return(nil).Once()
+			}
+			manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+			err := manager.checkStdinOverlap()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockSlack.AssertExpectations(t)
+		})
+	}
+}
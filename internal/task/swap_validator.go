@@ -0,0 +1,170 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// swap_validators.blockers が有効なら、dry run だけの同じ確認は行わない
+const metadataLockBlockersCheck = "metadata lock blockers"
+
+// SwapValidator は swap の前に実行する検証。Validate がエラーを返すと swap しない。
+// 組み込みの検証のほかに、RegisterSwapValidator で Manager に手を入れずに独自の検証を足せる
+type SwapValidator interface {
+	Name() string
+	Validate(ctx context.Context, tableName string) error
+}
+
+type swapValidatorFunc struct {
+	name     string
+	validate func(ctx context.Context, tableName string) error
+}
+
+func (v swapValidatorFunc) Name() string {
+	return v.name
+}
+
+func (v swapValidatorFunc) Validate(ctx context.Context, tableName string) error {
+	return v.validate(ctx, tableName)
+}
+
+// NewSwapValidator は関数から SwapValidator を作る
+func NewSwapValidator(name string, validate func(ctx context.Context, tableName string) error) SwapValidator {
+	return swapValidatorFunc{name: name, validate: validate}
+}
+
+// RegisterSwapValidator は swap の前に実行する検証を追加する。組み込みの検証と swap_validators.assertions の後に、追加した順で実行する
+func (m *Manager) RegisterSwapValidator(validator SwapValidator) {
+	m.swapValidators = append(m.swapValidators, validator)
+}
+
+// swapValidatorsFor は tableName の swap の前に実行する検証を実行順に返す。
+// 行数チェックは常に行い、それ以外は swap_validators の設定と RegisterSwapValidator で足す
+func (m *Manager) swapValidatorsFor(tableName string) ([]SwapValidator, error) {
+	cfg := m.config.Common.SwapValidators
+	validators := []SwapValidator{
+		NewSwapValidator("row count", func(ctx context.Context, tableName string) error {
+			return m.checkRowCountWithRemediation(tableName)
+		}),
+	}
+
+	if cfg.Checksum {
+		timeout, err := resolveTimeout("swap_validators.checksum_timeout", cfg.ChecksumTimeout)
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, NewSwapValidator("checksum", func(ctx context.Context, tableName string) error {
+			return m.checkTableChecksum(tableName, timeout)
+		}))
+	}
+	if cfg.MaxReplicaLagSeconds < 0 {
+		return nil, fmt.Errorf("swap_validators.max_replica_lag_seconds must not be negative")
+	}
+	if cfg.MaxReplicaLagSeconds > 0 {
+		validators = append(validators, NewSwapValidator("max replica lag", func(ctx context.Context, tableName string) error {
+			return m.checkSwapReplicaLag(cfg.MaxReplicaLagSeconds)
+		}))
+	}
+	if cfg.Blockers {
+		validators = append(validators, NewSwapValidator(metadataLockBlockersCheck, func(ctx context.Context, tableName string) error {
+			return m.checkMetadataLockBlockers(tableName)
+		}))
+	}
+
+	for i, assertion := range cfg.Assertions {
+		if assertion.Name == "" || strings.TrimSpace(assertion.Query) == "" {
+			return nil, fmt.Errorf("swap_validators.assertions[%d] requires name and query", i)
+		}
+		if len(assertion.Tables) > 0 && !slices.Contains(assertion.Tables, tableName) {
+			continue
+		}
+		validators = append(validators, NewSwapValidator(assertion.Name, func(ctx context.Context, tableName string) error {
+			return m.checkSwapAssertion(tableName, assertion.Query)
+		}))
+	}
+
+	return append(validators, m.swapValidators...), nil
+}
+
+// swapValidatorChecks は swap の前の検証を事前チェックにする
+func (m *Manager) swapValidatorChecks(ctx context.Context, tableName string) ([]preCheck, error) {
+	validators, err := m.swapValidatorsFor(tableName)
+	if err != nil {
+		return nil, err
+	}
+	checks := make([]preCheck, 0, len(validators))
+	for _, validator := range validators {
+		checks = append(checks, preCheck{name: validator.Name(), run: func() error {
+			return validator.Validate(ctx, tableName)
+		}})
+	}
+	return checks, nil
+}
+
+// swapEnvironmentChecks は swap の dry run で追加するサーバーの状態の確認を返す。
+// swap_validators.blockers で本番でも確認する場合は、dry run だけの同じ確認を除く
+func (m *Manager) swapEnvironmentChecks(tableName string) []preCheck {
+	checks := m.dryRunEnvironmentChecks(tableName)
+	if !m.config.Common.SwapValidators.Blockers {
+		return checks
+	}
+	return slices.DeleteFunc(checks, func(check preCheck) bool {
+		return check.name == metadataLockBlockersCheck
+	})
+}
+
+// checkTableChecksum は元テーブルと _new テーブルの共通カラムのチェックサムを比べる。
+// expected_row_delta_percent を指定したテーブルは意図して行が違うので比べない
+func (m *Manager) checkTableChecksum(tableName string, timeout time.Duration) error {
+	if _, ok := m.expectedRowDelta(tableName); ok {
+		m.logger.Infof("Skipping checksum of %s because expected_row_delta_percent is set", tableName)
+		return nil
+	}
+
+	newTableName := fmt.Sprintf("_%s_new", tableName)
+	columns, err := m.commonColumns(tableName, newTableName)
+	if err != nil {
+		return fmt.Errorf("failed to get columns to checksum: %w", err)
+	}
+	original, shadow, err := m.db.CompareTableChecksums(tableName, newTableName, columns, timeout)
+	if err != nil {
+		return err
+	}
+	if original != shadow {
+		return fmt.Errorf("checksum of %s (%s) does not match %s (%s) over columns %s",
+			tableName, original, newTableName, shadow, strings.Join(columns, ", "))
+	}
+	m.logger.Infof("Checksum of %s matches %s: %s", tableName, newTableName, original)
+	return nil
+}
+
+// checkSwapReplicaLag は replica_lag.replicas の最大の遅延が maxLag 秒以内かを確かめる
+func (m *Manager) checkSwapReplicaLag(maxLag float64) error {
+	if len(m.lagReplicas) == 0 {
+		return fmt.Errorf("swap_validators.max_replica_lag_seconds requires replica_lag.replicas")
+	}
+	lag, ok := m.maxReplicaLag()
+	if !ok {
+		return fmt.Errorf("replica lag could not be measured on any of replica_lag.replicas")
+	}
+	if lag > maxLag {
+		return fmt.Errorf("replica lag is %.1fs (max_replica_lag_seconds: %g)", lag, maxLag)
+	}
+	return nil
+}
+
+// checkSwapAssertion は swap_validators.assertions のクエリを実行し、1行1列目が真でなければ失敗する
+func (m *Manager) checkSwapAssertion(tableName, query string) error {
+	query = strings.NewReplacer("<new_table>", fmt.Sprintf("_%s_new", tableName), "<table>", tableName).Replace(query)
+	ok, err := m.db.EvaluateHealthQuery(query)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("assertion returned a false value: %s", query)
+	}
+	return nil
+}
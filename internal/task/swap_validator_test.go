@@ -0,0 +1,90 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapValidatorsFor(t *testing.T) {
+	cfg := &config.Config{Common: config.CommonConfig{SwapValidators: config.SwapValidatorsConfig{
+		Checksum:             true,
+		MaxReplicaLagSeconds: 5,
+		Blockers:             true,
+		Assertions: []config.SwapAssertionConfig{
+			{Name: "no orphan orders", Query: "SELECT COUNT(*) = 0 FROM orders LEFT JOIN <new_table> u ON u.id = orders.user_id WHERE u.id IS NULL", Tables: []string{"users"}},
+			{Name: "payments only", Query: "SELECT 1", Tables: []string{"payments"}},
+		},
+	}}}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logrus.New(), cfg, false)
+	manager.RegisterSwapValidator(NewSwapValidator("custom", func(ctx context.Context, tableName string) error { return nil }))
+
+	validators, err := manager.swapValidatorsFor("users")
+	require.NoError(t, err)
+
+	var names []string
+	for _, validator := range validators {
+		names = append(names, validator.Name())
+	}
+	assert.Equal(t, []string{"row count", "checksum", "max replica lag", "metadata lock blockers", "no orphan orders", "custom"}, names)
+
+	// blockers を本番でも確認するなら、dry run だけの同じ確認は重ねない
+	for _, check := range manager.swapEnvironmentChecks("users") {
+		assert.NotEqual(t, metadataLockBlockersCheck, check.name)
+	}
+
+	cfg.Common.SwapValidators.Assertions = []config.SwapAssertionConfig{{Name: "missing query"}}
+	_, err = manager.swapValidatorsFor("users")
+	assert.ErrorContains(t, err, "requires name and query")
+}
+
+func TestSwapValidatorChecks(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableRowCountForSwap", "users").Return(int64(1000), nil)
+	mockDB.On("GetNewTableRowCountForSwap", "users").Return(int64(1000), nil)
+	mockDB.On("GetTableStructure", "users").Return(&database.TableStructure{Columns: []string{"id", "name", "legacy"}}, nil)
+	mockDB.On("GetTableStructure", "_users_new").Return(&database.TableStructure{Columns: []string{"id", "name", "age"}}, nil)
+	mockDB.On("CompareTableChecksums", "users", "_users_new", []string{"id", "name"}, time.Duration(0)).Return("1000/123", "1000/456", nil)
+	mockDB.On("EvaluateHealthQuery", "SELECT COUNT(*) FROM _users_new WHERE name IS NULL = 0").Return(true, nil)
+
+	cfg := &config.Config{Common: config.CommonConfig{SwapValidators: config.SwapValidatorsConfig{
+		Checksum:   true,
+		Assertions: []config.SwapAssertionConfig{{Name: "names filled", Query: "SELECT COUNT(*) FROM <new_table> WHERE name IS NULL = 0"}},
+	}}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+	customErr := errors.New("domain check failed")
+	manager.RegisterSwapValidator(NewSwapValidator("custom", func(ctx context.Context, tableName string) error { return customErr }))
+
+	checks, err := manager.swapValidatorChecks(context.Background(), "users")
+	require.NoError(t, err)
+	require.Len(t, checks, 4)
+
+	assert.NoError(t, checks[0].run())
+	assert.ErrorContains(t, checks[1].run(), "checksum of users (1000/123) does not match _users_new (1000/456)")
+	assert.NoError(t, checks[2].run())
+	assert.ErrorIs(t, checks[3].run(), customErr)
+	mockDB.AssertExpectations(t)
+}
+
+func TestCheckSwapReplicaLag(t *testing.T) {
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logrus.New(), &config.Config{}, false)
+	assert.ErrorContains(t, manager.checkSwapReplicaLag(5), "requires replica_lag.replicas")
+
+	replicaDB := &MockDBClient{}
+	replicaDB.On("GetReplicaLagSeconds").Return(2.0, nil).Once()
+	replicaDB.On("GetReplicaLagSeconds").Return(8.0, nil).Once()
+	manager.SetLagReplicas([]RollingHost{{Name: "replica1", DB: replicaDB}})
+
+	assert.NoError(t, manager.checkSwapReplicaLag(5))
+	assert.ErrorContains(t, manager.checkSwapReplicaLag(5), "replica lag is 8.0s")
+}
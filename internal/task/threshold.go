@@ -0,0 +1,87 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetThresholdOverrides には run の --pt-osc-threshold などで今回の実行だけ変えた閾値の説明を渡す。
+// 実行開始時に通知し、テーブルごとの判定のログにも残す。
+func (m *Manager) SetThresholdOverrides(overrides []string) {
+	m.thresholdOverrides = overrides
+}
+
+// resolveForcedMethod は force_method を実行方式の名前にする。指定がなければ空を返す
+func (m *Manager) resolveForcedMethod() (string, error) {
+	switch m.config.Common.ForceMethod {
+	case "":
+		return "", nil
+	case "ptosc":
+		return "pt-osc", nil
+	case "direct":
+		return "alter-table", nil
+	default:
+		return "", fmt.Errorf("invalid force_method %q (must be ptosc or direct)", m.config.Common.ForceMethod)
+	}
+}
+
+// chooseAlterMethod は行数が pt_osc_threshold を超えるか、サイズが pt_osc_size_threshold_mb を超える場合に pt-osc を選ぶ。
+// force_method が指定されていれば判定せずにその方式を使う
+func (m *Manager) chooseAlterMethod(tableName string, rowCount int64) string {
+	if forced, err := m.resolveForcedMethod(); err == nil && forced != "" {
+		m.logger.Warnf("Table %s has %d rows, but using %s because of force_method", tableName, rowCount, forced)
+		return forced
+	}
+
+	threshold := m.config.Common.PtOscThreshold
+	suffix := ""
+	if len(m.thresholdOverrides) > 0 {
+		suffix = " (overridden for this run)"
+	}
+	m.logger.Infof("Table %s has %d rows (threshold: %d)%s", tableName, rowCount, threshold, suffix)
+	if rowCount > threshold {
+		return "pt-osc"
+	}
+
+	sizeThreshold := m.config.Common.PtOscSizeThresholdMB
+	if sizeThreshold <= 0 {
+		return "alter-table"
+	}
+	sizeMB, err := m.db.GetTableSizeMB(tableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get table size for %s, deciding by row count only: %v", tableName, err)
+		return "alter-table"
+	}
+	m.logger.Infof("Table %s is %.1f MB (size threshold: %.1f MB)%s", tableName, sizeMB, sizeThreshold, suffix)
+	if sizeMB > sizeThreshold {
+		return "pt-osc"
+	}
+	return "alter-table"
+}
+
+func (m *Manager) notifyThresholdOverrides() {
+	if len(m.thresholdOverrides) == 0 {
+		return
+	}
+	message := "Thresholds overridden for this run: " + strings.Join(m.thresholdOverrides, ", ")
+	m.logger.Warn(message)
+	if err := m.slack.NotifyWarning("threshold-override", "", message); err != nil {
+		m.logger.Errorf("Failed to send warning notification: %v", err)
+	}
+}
+
+// notifyForcedMethod は行数による判定を無効にしたことを実行開始時に警告する
+func (m *Manager) notifyForcedMethod(forced string) {
+	if forced == "" {
+		return
+	}
+	message := fmt.Sprintf("⚠️ force_method=%s: the row count / size decision is bypassed and EVERY table is changed with %s",
+		m.config.Common.ForceMethod, forced)
+	if forced == "alter-table" {
+		message += ". Large tables will be locked or rebuilt by a direct ALTER"
+	}
+	m.logger.Warn(message)
+	if err := m.slack.NotifyWarning("force-method", "", message); err != nil {
+		m.logger.Errorf("Failed to send warning notification: %v", err)
+	}
+}
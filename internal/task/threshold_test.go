@@ -0,0 +1,126 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChooseAlterMethod(t *testing.T) {
+	tests := []struct {
+		name          string
+		threshold     int64
+		sizeThreshold float64
+		rowCount      int64
+		sizeMB        float64
+		want          string
+	}{
+		{name: "below row threshold", threshold: 1000, rowCount: 1000, want: "alter-table"},
+		{name: "above row threshold", threshold: 1000, rowCount: 1001, want: "pt-osc"},
+		{name: "above size threshold", threshold: 1000, sizeThreshold: 512, rowCount: 10, sizeMB: 600, want: "pt-osc"},
+		{name: "below size threshold", threshold: 1000, sizeThreshold: 512, rowCount: 10, sizeMB: 100, want: "alter-table"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			mockDB := &MockDBClient{}
+			mockDB.On("GetTableSizeMB", "users").Return(tt.sizeMB, nil).Maybe()
+
+			cfg := &config.Config{Common: config.CommonConfig{PtOscThreshold: tt.threshold, PtOscSizeThresholdMB: tt.sizeThreshold}}
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+			assert.Equal(t, tt.want, manager.chooseAlterMethod("users", tt.rowCount))
+			if tt.sizeThreshold == 0 {
+				mockDB.AssertNotCalled(t, "GetTableSizeMB", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestNotifyThresholdOverrides(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyWarning", "threshold-override", "", "Thresholds overridden for this run: pt_osc_threshold 1000 -> 5000 rows (--pt-osc-threshold)").Return(nil)
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+	manager.notifyThresholdOverrides()
+	mockSlack.AssertNotCalled(t, "NotifyWarning", mock.Anything, mock.Anything, mock.Anything)
+
+	manager.SetThresholdOverrides([]string{"pt_osc_threshold 1000 -> 5000 rows (--pt-osc-threshold)"})
+	manager.notifyThresholdOverrides()
+	mockSlack.AssertExpectations(t)
+}
+
+func TestChooseAlterMethod_ForceMethod(t *testing.T) {
+	tests := []struct {
+		forceMethod string
+		rowCount    int64
+		want        string
+	}{
+		{forceMethod: "ptosc", rowCount: 10, want: "pt-osc"},
+		{forceMethod: "direct", rowCount: 10_000_000, want: "alter-table"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.forceMethod, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			cfg := &config.Config{Common: config.CommonConfig{PtOscThreshold: 1000, PtOscSizeThresholdMB: 512, ForceMethod: tt.forceMethod}}
+			mockDB := &MockDBClient{}
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+			assert.Equal(t, tt.want, manager.chooseAlterMethod("users", tt.rowCount))
+			mockDB.AssertNotCalled(t, "GetTableSizeMB", mock.Anything)
+		})
+	}
+}
+
+func TestExecuteAllTasks_InvalidForceMethod(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{
+		Queries: []string{"ALTER TABLE users ADD COLUMN age INT"},
+		Common:  config.CommonConfig{ForceMethod: "gh-ost"},
+	}
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+
+	_, err := manager.ExecuteAllTasksWithResult()
+	assert.ErrorContains(t, err, `invalid force_method "gh-ost"`)
+}
+
+func TestExecuteTableGroup_ForceMethodSkipsRowCount(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableStructure", "users").Return(&database.TableStructure{Columns: []string{"id"}}, nil)
+	mockDB.On("GetTableStorage", "users").Return(&database.TableStorage{Engine: "InnoDB"}, nil)
+	mockDB.On("ExecuteAlterWithAlgorithm", "users", "ALTER TABLE users ADD COLUMN age INT").Return(nil, nil)
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStartWithQueryAndStorage", "alter-table", "users", mock.Anything, int64(0), mock.Anything).Return(nil)
+	mockSlack.On("NotifySuccessWithQueryAndAlgorithm", "alter-table", "users", mock.Anything, int64(0), mock.Anything, mock.Anything).Return(nil)
+
+	cfg := &config.Config{Common: config.CommonConfig{PtOscThreshold: 1000, ForceMethod: "direct"}, DSN: "test-dsn"}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	group := &TableGroup{TableName: "users", AlterParts: []string{"ADD COLUMN age INT"}}
+	require.NoError(t, manager.executeTableGroup(context.Background(), "users", group))
+
+	assert.Equal(t, "alter-table", group.Method)
+	mockDB.AssertNotCalled(t, "GetTableRowCount", mock.Anything)
+	mockDB.AssertNotCalled(t, "CountTableRows", mock.Anything, mock.Anything)
+	mockSlack.AssertExpectations(t)
+}
@@ -0,0 +1,97 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// resolveTimeout は task_timeout / run_timeout を解釈する。未設定なら0(無制限)。
+func resolveTimeout(name, raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s: must not be negative, got %s", name, raw)
+	}
+	return d, nil
+}
+
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// taskContext は run_timeout を持つ親contextに task_timeout を重ねたcontextを返す
+func (m *Manager) taskContext(parent context.Context) (context.Context, context.CancelFunc, error) {
+	taskTimeout, err := resolveTimeout("task_timeout", m.config.Common.TaskTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := withOptionalTimeout(parent, taskTimeout)
+	return ctx, cancel, nil
+}
+
+// handleTimeout はタイムアウトによる失敗であれば専用の通知を送り、後始末を実行する。
+// タイムアウト以外のエラーであれば false を返す。
+func (m *Manager) handleTimeout(parent context.Context, taskName, tableName string, err error, cleanup func()) bool {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	timeoutName := "task_timeout"
+	timeoutValue := m.config.Common.TaskTimeout
+	if parent.Err() != nil {
+		timeoutName = "run_timeout"
+		timeoutValue = m.config.Common.RunTimeout
+	}
+	timeout, _ := time.ParseDuration(timeoutValue)
+
+	m.logger.Errorf("%s exceeded (%s) for %s on table %s", timeoutName, timeoutValue, taskName, tableName)
+	if slackErr := m.slack.NotifyTimeout(taskName, tableName, timeout); slackErr != nil {
+		m.logger.Errorf("Failed to send timeout notification: %v", slackErr)
+	}
+
+	if cleanup != nil {
+		cleanup()
+	}
+	return true
+}
+
+// cleanupAfterPtOscTimeout は中断されたpt-oscが残したトリガーと新テーブルを削除する
+func (m *Manager) cleanupAfterPtOscTimeout(tableName string) {
+	m.logger.Warnf("Cleaning up pt-osc artifacts for %s after timeout", tableName)
+	if err := m.CleanupTriggers(tableName); err != nil {
+		m.logger.Errorf("Failed to clean up triggers for %s after timeout: %v", tableName, err)
+	}
+	if err := m.CleanupNewTable(tableName); err != nil {
+		m.logger.Errorf("Failed to clean up new table for %s after timeout: %v", tableName, err)
+	}
+}
+
+// checkRunDeadline は run_timeout を超過していればタイムアウトを通知してエラーを返す。
+// 呼び出し元 (operator の停止など) によるキャンセルはタイムアウトとして通知しない
+func (m *Manager) checkRunDeadline(ctx context.Context) error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) {
+		m.logger.Warn("Run canceled before all tasks finished")
+		return fmt.Errorf("run canceled: %w", err)
+	}
+
+	timeout, _ := time.ParseDuration(m.config.Common.RunTimeout)
+	m.logger.Errorf("run_timeout exceeded (%s)", m.config.Common.RunTimeout)
+	if slackErr := m.slack.NotifyTimeout("run", "", timeout); slackErr != nil {
+		m.logger.Errorf("Failed to send timeout notification: %v", slackErr)
+	}
+	return fmt.Errorf("run timed out: %w", err)
+}
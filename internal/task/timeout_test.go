@@ -0,0 +1,204 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "not set", raw: "", want: 0},
+		{name: "valid", raw: "2h", want: 2 * time.Hour},
+		{name: "invalid", raw: "soon", wantErr: true},
+		{name: "negative", raw: "-1s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveTimeout("task_timeout", tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExecuteAllTasks_TaskTimeout(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableStorage", mock.Anything).Return(&database.TableStorage{Engine: "InnoDB"}, nil).Maybe()
+	mockDB.On("GetTableRowCounts", mock.Anything).Return(map[string]int64{}, nil).Maybe()
+	mockDB.On("GetTableStructure", mock.Anything).Return(&database.TableStructure{}, nil).Maybe()
+	mockPtOsc := &MockPtOscExecutor{}
+	mockSlack := &MockSlackNotifier{}
+
+	queries := []string{"ALTER TABLE large_table ADD COLUMN foo INT"}
+
+	mockDB.On("GetTableRowCount", "large_table").Return(int64(5000), nil)
+	mockDB.On("CheckNewTableExists", "large_table").Return(false, nil)
+	timeoutErr := fmt.Errorf("pt-online-schema-change for table large_table was terminated: %w", context.DeadlineExceeded)
+	mockPtOsc.On("ExecuteAlter", "large_table", "ADD COLUMN foo INT", config.PtOscConfig{}, "user:pass@tcp(localhost:3306)/testdb", false).Return(timeoutErr)
+
+	// タイムアウト後の後始末
+	mockDB.On("GetTriggerNames", "large_table").Return([]string{"pt_osc_testdb_large_table_del", "pt_osc_testdb_large_table_upd", "pt_osc_testdb_large_table_ins"}, nil)
+	mockDB.On("ExecuteAlter", "DROP TRIGGER IF EXISTS pt_osc_testdb_large_table_del").Return(nil)
+	mockDB.On("ExecuteAlter", "DROP TRIGGER IF EXISTS pt_osc_testdb_large_table_upd").Return(nil)
+	mockDB.On("ExecuteAlter", "DROP TRIGGER IF EXISTS pt_osc_testdb_large_table_ins").Return(nil)
+	mockDB.On("ExecuteAlter", "DROP TABLE IF EXISTS _large_table_new").Return(nil)
+
+	mockSlack.On("NotifyAllTasksStart", 1).Return(nil)
+	mockSlack.On("NotifyStartWithQuery", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSlack.On("NotifyStartWithQueryAndStorage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSlack.On("NotifyTimeout", "pt-osc", "large_table", 30*time.Minute).Return(nil)
+	mockSlack.On("NotifyTriggerCleanupStart", mock.Anything, "large_table", mock.Anything).Return(nil)
+	mockSlack.On("NotifyTriggerCleanupSuccess", mock.Anything, "large_table", mock.Anything, mock.Anything).Return(nil)
+	mockSlack.On("NotifySuccessWithQuery", "new-table-cleanup", "large_table", mock.Anything, int64(0), mock.Anything).Return(nil)
+	mockSlack.On("NotifyAllTasksFailure", 1, mock.Anything).Return(nil)
+
+	cfg := &config.Config{
+		Queries: queries,
+		Common: config.CommonConfig{
+			PtOscThreshold: 1000,
+			TaskTimeout:    "30m",
+		},
+		DSN: "user:pass@tcp(localhost:3306)/testdb",
+	}
+
+	manager := NewManager(mockDB, mockPtOsc, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	err := manager.ExecuteAllTasks()
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	mockDB.AssertExpectations(t)
+	mockPtOsc.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+	mockSlack.AssertNotCalled(t, "NotifyFailureWithQueryAndLog", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExecuteAllTasks_InvalidRunTimeout(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := &config.Config{
+		Queries: []string{"ALTER TABLE t ADD COLUMN foo INT"},
+		Common: config.CommonConfig{
+			RunTimeout: "forever",
+		},
+	}
+
+	manager := NewManager(&MockDBClient{}, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, cfg, false)
+	err := manager.ExecuteAllTasks()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "run_timeout")
+}
+
+// deadlineDBClient は直接 ALTER に渡された context の期限を記録する
+type deadlineDBClient struct {
+	MockDBClient
+	deadline time.Time
+}
+
+func (d *deadlineDBClient) ExecuteAlterWithAlgorithm(ctx context.Context, tableName, alterStatement string) (*database.AlterAlgorithm, error) {
+	d.deadline, _ = ctx.Deadline()
+	return nil, fmt.Errorf("failed to execute ALTER statement [%s]: Error 1317: Query execution was interrupted (interrupted: %w)", alterStatement, context.DeadlineExceeded)
+}
+
+func TestExecuteAllTasks_TaskTimeoutBoundsDirectAlter(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &deadlineDBClient{}
+	mockDB.On("GetTableStorage", mock.Anything).Return(&database.TableStorage{Engine: "InnoDB"}, nil).Maybe()
+	mockDB.On("GetTableStructure", mock.Anything).Return(&database.TableStructure{}, nil).Maybe()
+	mockDB.On("GetTableRowCounts", []string{"users"}).Return(map[string]int64{"users": 500}, nil)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyAllTasksStart", 1).Return(nil)
+	mockSlack.On("NotifyStartWithQueryAndStorage", "alter-table", "users", mock.Anything, int64(500), mock.Anything).Return(nil)
+	mockSlack.On("NotifyFailureWithQuery", "alter-table", "users", mock.Anything, int64(500), mock.Anything).Return(nil)
+	mockSlack.On("NotifyTimeout", "alter-table", "users", 30*time.Minute).Return(nil)
+	mockSlack.On("NotifyAllTasksFailure", 1, mock.Anything).Return(nil)
+
+	cfg := &config.Config{
+		Queries: []string{"ALTER TABLE users ADD COLUMN foo INT"},
+		Common: config.CommonConfig{
+			PtOscThreshold: 1000,
+			TaskTimeout:    "30m",
+		},
+		DSN: "test-dsn",
+	}
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+	start := time.Now()
+	err := manager.ExecuteAllTasks()
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.WithinDuration(t, start.Add(30*time.Minute), mockDB.deadline, time.Minute)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestExecuteAllTasksContext_Canceled(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTableRowCounts", mock.Anything).Return(map[string]int64{}, nil).Maybe()
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyAllTasksStart", 1).Return(nil)
+	mockSlack.On("NotifyAllTasksFailure", 1, mock.Anything).Return(nil)
+
+	cfg := &config.Config{
+		Queries: []string{"ALTER TABLE users ADD COLUMN foo INT"},
+		Common:  config.CommonConfig{PtOscThreshold: 1000},
+		DSN:     "test-dsn",
+	}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := manager.ExecuteAllTasksContext(ctx)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "run canceled")
+	mockSlack.AssertNotCalled(t, "NotifyTimeout", mock.Anything, mock.Anything, mock.Anything)
+	mockDB.AssertNotCalled(t, "ExecuteAlterWithAlgorithm", mock.Anything, mock.Anything)
+}
+
+func TestSwapTableContext_CanceledBeforeRename(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB, mockSlack := newAnalyzeSwapMocks()
+	cfg := &config.Config{Common: config.CommonConfig{DisableAnalyzeTable: true}}
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := manager.SwapTableContext(ctx, "users")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	mockDB.AssertNotCalled(t, "ExecuteAlter", mock.Anything)
+	mockSlack.AssertNotCalled(t, "NotifyStartWithQuery", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,105 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// warmupNewTable は swap 前に _new テーブルのインデックスを走査してバッファプールに載せ、
+// swap 直後のキャッシュミスによる遅延を抑える。ウォームアップは補助的な処理のため、失敗しても swap は止めない。
+func (m *Manager) warmupNewTable(tableName, newTableName string) {
+	var timeout time.Duration
+	if raw := m.config.Common.SwapWarmup.Timeout; raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			m.logger.Warnf("Invalid swap_warmup.timeout %q, warming up without a time limit", raw)
+		} else {
+			timeout = parsed
+		}
+	}
+
+	indexes, err := m.warmupIndexes(tableName, newTableName)
+	if err != nil {
+		m.logger.Warnf("Skipping warmup of %s: %v", newTableName, err)
+		return
+	}
+	if len(indexes) == 0 {
+		return
+	}
+
+	if m.dryRun {
+		m.logger.Infof("[DRY RUN] Would warm up indexes of %s before swap: %s", newTableName, strings.Join(indexes, ", "))
+		return
+	}
+
+	start := time.Now()
+	for i, index := range indexes {
+		remaining := time.Duration(0)
+		if timeout > 0 {
+			remaining = timeout - time.Since(start)
+			if remaining <= 0 {
+				m.warnWarmupIncomplete(tableName, newTableName, indexes[i:], fmt.Sprintf("swap_warmup.timeout %s exceeded", timeout))
+				return
+			}
+		}
+
+		m.logger.Infof("Warming up index %s of %s (%d/%d)", index, newTableName, i+1, len(indexes))
+		rows, err := m.db.WarmupIndex(newTableName, index, remaining)
+		if err != nil {
+			m.warnWarmupIncomplete(tableName, newTableName, indexes[i:], err.Error())
+			return
+		}
+		if err := m.slack.NotifyWarmupProgress(tableName, index, i+1, len(indexes), rows, time.Since(start)); err != nil {
+			m.logger.Errorf("Failed to send warmup progress notification: %v", err)
+		}
+	}
+	m.logger.Infof("Warmed up %d indexes of %s in %s", len(indexes), newTableName, time.Since(start).Round(time.Second))
+}
+
+// warmupIndexes は読み込むインデックスを返す。主キーはデータ本体のため先頭にする。
+// swap_warmup.indexes に _new テーブルにないインデックスがあれば、警告して読み飛ばす
+func (m *Manager) warmupIndexes(tableName, newTableName string) ([]string, error) {
+	structure, err := m.db.GetTableStructure(newTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []string
+	for _, index := range structure.Indexes {
+		if strings.EqualFold(index, "PRIMARY") {
+			indexes = append([]string{index}, indexes...)
+		} else {
+			indexes = append(indexes, index)
+		}
+	}
+
+	configured := m.config.Common.SwapWarmup.Indexes
+	if len(configured) == 0 {
+		return indexes, nil
+	}
+
+	var selected []string
+	for _, name := range configured {
+		found := false
+		for _, index := range indexes {
+			if strings.EqualFold(index, name) {
+				selected = append(selected, index)
+				found = true
+				break
+			}
+		}
+		if !found {
+			m.logger.Warnf("swap_warmup.indexes: index %s does not exist on %s, skipping", name, newTableName)
+		}
+	}
+	return selected, nil
+}
+
+func (m *Manager) warnWarmupIncomplete(tableName, newTableName string, skipped []string, reason string) {
+	message := fmt.Sprintf("Warmup of %s stopped (%s); swapping without warming up: %s", newTableName, reason, strings.Join(skipped, ", "))
+	m.logger.Warn(message)
+	if err := m.slack.NotifyWarning("swap-warmup", tableName, message); err != nil {
+		m.logger.Errorf("Failed to send warning notification: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/pyama86/alterguard/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWarmupNewTable(t *testing.T) {
+	tests := []struct {
+		name        string
+		indexes     []string
+		failIndex   string
+		wantWarmed  []string
+		wantWarning bool
+	}{
+		{name: "all indexes with primary first", wantWarmed: []string{"PRIMARY", "idx_email", "idx_name"}},
+		{name: "configured indexes", indexes: []string{"idx_name", "idx_missing"}, wantWarmed: []string{"idx_name"}},
+		{name: "failure stops warmup", failIndex: "idx_email", wantWarmed: []string{"PRIMARY", "idx_email"}, wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			mockDB := &MockDBClient{}
+			mockDB.On("GetTableStructure", "_users_new").Return(&database.TableStructure{Indexes: []string{"idx_email", "idx_name", "PRIMARY"}}, nil)
+			var warmed []string
+			record := func(args mock.Arguments) { warmed = append(warmed, args.String(1)) }
+			for _, index := range []string{"PRIMARY", "idx_email", "idx_name"} {
+				if index == tt.failIndex {
+					mockDB.On("WarmupIndex", "_users_new", index, time.Duration(0)).Run(record).Return(int64(0), errors.New("Query execution was interrupted"))
+				} else {
+					mockDB.On("WarmupIndex", "_users_new", index, time.Duration(0)).Run(record).Return(int64(100), nil)
+				}
+			}
+			mockSlack := &MockSlackNotifier{}
+			mockSlack.On("NotifyWarmupProgress", "users", mock.Anything, mock.Anything, mock.Anything, int64(100), mock.Anything).Return(nil)
+			mockSlack.On("NotifyWarning", "swap-warmup", "users", mock.Anything).Return(nil)
+
+			cfg := &config.Config{Common: config.CommonConfig{SwapWarmup: config.SwapWarmupConfig{Enabled: true, Indexes: tt.indexes}}}
+			manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, cfg, false)
+
+			manager.warmupNewTable("users", "_users_new")
+
+			assert.Equal(t, tt.wantWarmed, warmed)
+			if tt.wantWarning {
+				mockSlack.AssertCalled(t, "NotifyWarning", "swap-warmup", "users", mock.Anything)
+			} else {
+				mockSlack.AssertNotCalled(t, "NotifyWarning", "swap-warmup", "users", mock.Anything)
+				mockSlack.AssertNumberOfCalls(t, "NotifyWarmupProgress", len(tt.wantWarmed))
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const ptOscTriggerPrefix = "pt_osc_"
+
+// migrationState は外部で実行中のpt-oscをデータベース側から観測した状態
+type migrationState struct {
+	triggers       []string
+	newTableExists bool
+	copyProcesses  int
+	copiedRows     int64
+}
+
+// attachable はアタッチする対象のpt-oscがありそうかを返す
+func (s *migrationState) attachable() bool {
+	return len(s.triggers) > 0 || s.copying(nil)
+}
+
+// copying は _new テーブルへのコピーが進んでいるかを返す。_new を参照するプロセスがあるか、
+// 前回の観測から _new の行数が変わっていればコピー中とみなす。
+// no_drop_triggers / no_swap_tables ではコピーの後もトリガーが残るため、トリガーの有無では判定しない
+func (s *migrationState) copying(previous *migrationState) bool {
+	if !s.newTableExists {
+		return false
+	}
+	return s.copyProcesses > 0 || (previous != nil && previous.newTableExists && s.copiedRows != previous.copiedRows)
+}
+
+// inspectMigration はpt-oscのトリガー・_newテーブル・_newテーブルを参照するプロセスと行数を調べる
+func (m *Manager) inspectMigration(tableName string) (*migrationState, error) {
+	state := &migrationState{}
+
+	triggers, err := m.db.GetTriggerNames(tableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, trigger := range triggers {
+		if strings.HasPrefix(trigger, ptOscTriggerPrefix) {
+			state.triggers = append(state.triggers, trigger)
+		}
+	}
+
+	newTableName := fmt.Sprintf("_%s_new", tableName)
+	exists, err := m.db.TableExists(newTableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existence of %s: %w", newTableName, err)
+	}
+	state.newTableExists = exists
+	if !exists {
+		return state, nil
+	}
+
+	processes, err := m.db.CountProcessesReferencingTable(newTableName)
+	if err != nil {
+		return nil, err
+	}
+	state.copyProcesses = processes
+
+	copiedRows, err := m.db.GetTableRowCount(newTableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get row count for %s: %w", newTableName, err)
+	}
+	state.copiedRows = copiedRows
+	return state, nil
+}
+
+// WatchMigration は別の端末やジョブで起動済みのpt-oscにアタッチし、
+// 終了するまで interval ごとに進捗を通知する。
+func (m *Manager) WatchMigration(ctx context.Context, tableName string, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("watch interval must be positive, got %s", interval)
+	}
+
+	state, err := m.inspectMigration(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to inspect migration on %s: %w", tableName, err)
+	}
+	if !state.attachable() {
+		return fmt.Errorf("no running migration found for table %s", tableName)
+	}
+
+	m.invalidateRowCount(tableName)
+	totalRows, err := m.getTableRowCount(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to get row count for %s: %w", tableName, err)
+	}
+
+	m.logger.Infof("Attached to running migration on %s (triggers: %v, copy processes: %d)",
+		tableName, state.triggers, state.copyProcesses)
+	if err := m.slack.NotifyStart("watch", tableName, totalRows); err != nil {
+		m.logger.Errorf("Failed to send start notification: %v", err)
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.logger.Infof("Stopped watching migration on %s: %v", tableName, ctx.Err())
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		current, err := m.inspectMigration(tableName)
+		if err != nil {
+			m.logger.Warnf("Failed to inspect migration on %s: %v", tableName, err)
+			continue
+		}
+
+		// 1回の間隔の間にコピーが進まなければ、終了したか止まっている
+		if !current.copying(state) {
+			return m.finishWatch(tableName, totalRows, current, interval, time.Since(start))
+		}
+		state = current
+
+		m.reportWatchProgress(tableName, totalRows, current.copiedRows, time.Since(start))
+	}
+}
+
+func (m *Manager) reportWatchProgress(tableName string, totalRows, copiedRows int64, elapsed time.Duration) {
+	newTableName := fmt.Sprintf("_%s_new", tableName)
+
+	sizeMB, err := m.db.GetTableSizeMB(newTableName)
+	if err != nil {
+		m.logger.Warnf("Failed to get table size for %s: %v", newTableName, err)
+		return
+	}
+
+	m.logger.Infof("Migration progress on %s: %d/%d rows copied, %s is %.1f MB, elapsed %s",
+		tableName, copiedRows, totalRows, newTableName, sizeMB, elapsed.Round(time.Second))
+	if err := m.slack.NotifyWatchProgress(tableName, copiedRows, totalRows, sizeMB, elapsed); err != nil {
+		m.logger.Errorf("Failed to send watch progress notification: %v", err)
+	}
+}
+
+// finishWatch はコピーが進まなくなった後の状態から結果を判定する。
+// トリガーか _newテーブルが残っていれば、no_drop_triggers / no_swap_tables で終了したか、
+// 異常終了・停止したものとして警告する。
+func (m *Manager) finishWatch(tableName string, totalRows int64, state *migrationState, interval, elapsed time.Duration) error {
+	if len(state.triggers) > 0 {
+		message := fmt.Sprintf("pt-osc triggers (%s) remain on %s but no copy into _%s_new was seen for %s. The migration either finished with no_drop_triggers/no_swap_tables or is stalled; check the pt-osc process before running `alterguard swap %s` or `alterguard cleanup %s --drop-triggers`.",
+			strings.Join(state.triggers, ", "), tableName, tableName, interval, tableName, tableName)
+		m.logger.Warn(message)
+		if err := m.slack.NotifyWarning("watch", tableName, message); err != nil {
+			m.logger.Errorf("Failed to send warning notification: %v", err)
+		}
+		return nil
+	}
+	if state.newTableExists {
+		message := fmt.Sprintf("pt-osc triggers are gone but _%s_new still exists. Run `alterguard swap %s` if the copy was intentionally left unswapped, otherwise inspect and run `alterguard cleanup %s --drop-new-table`.",
+			tableName, tableName, tableName)
+		m.logger.Warn(message)
+		if err := m.slack.NotifyWarning("watch", tableName, message); err != nil {
+			m.logger.Errorf("Failed to send warning notification: %v", err)
+		}
+		return nil
+	}
+
+	m.logger.Infof("Migration on %s finished after %s", tableName, elapsed.Round(time.Second))
+	if err := m.slack.NotifySuccess("watch", tableName, totalRows, elapsed); err != nil {
+		m.logger.Errorf("Failed to send success notification: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package task
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyama86/alterguard/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchMigrationNoRunningMigration(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTriggerNames", "users").Return([]string{"users_audit"}, nil)
+	mockDB.On("TableExists", "_users_new").Return(true, nil)
+	mockDB.On("CountProcessesReferencingTable", "_users_new").Return(0, nil)
+	mockDB.On("GetTableRowCount", "_users_new").Return(int64(0), nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, &MockSlackNotifier{}, logger, &config.Config{}, false)
+	err := manager.WatchMigration(context.Background(), "users", time.Millisecond)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no running migration found for table users")
+	mockDB.AssertExpectations(t)
+}
+
+func TestWatchMigrationReportsProgressUntilTriggersAreGone(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	triggers := []string{"pt_osc_app_users_del", "pt_osc_app_users_ins", "pt_osc_app_users_upd"}
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTriggerNames", "users").Return(triggers, nil).Twice()
+	mockDB.On("TableExists", "_users_new").Return(true, nil).Twice()
+	mockDB.On("CountProcessesReferencingTable", "_users_new").Return(1, nil).Twice()
+	mockDB.On("GetTableRowCount", "users").Return(int64(1000), nil)
+	mockDB.On("GetTableRowCount", "_users_new").Return(int64(400), nil)
+	mockDB.On("GetTableSizeMB", "_users_new").Return(12.5, nil)
+	mockDB.On("GetTriggerNames", "users").Return([]string{}, nil).Once()
+	mockDB.On("TableExists", "_users_new").Return(false, nil).Once()
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStart", "watch", "users", int64(1000)).Return(nil)
+	mockSlack.On("NotifyWatchProgress", "users", int64(400), int64(1000), 12.5, mock.AnythingOfType("time.Duration")).Return(nil).Once()
+	mockSlack.On("NotifySuccess", "watch", "users", int64(1000), mock.AnythingOfType("time.Duration")).Return(nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+	err := manager.WatchMigration(context.Background(), "users", time.Millisecond)
+
+	require.NoError(t, err)
+	mockDB.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestWatchMigrationWarnsWhenNewTableRemains(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTriggerNames", "users").Return([]string{"pt_osc_app_users_ins"}, nil).Once()
+	mockDB.On("GetTriggerNames", "users").Return([]string{}, nil).Once()
+	mockDB.On("TableExists", "_users_new").Return(true, nil)
+	mockDB.On("CountProcessesReferencingTable", "_users_new").Return(0, nil)
+	mockDB.On("GetTableRowCount", "users").Return(int64(1000), nil)
+	mockDB.On("GetTableRowCount", "_users_new").Return(int64(1000), nil)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStart", "watch", "users", int64(1000)).Return(nil)
+	mockSlack.On("NotifyWarning", "watch", "users", mock.MatchedBy(func(message string) bool {
+		return strings.Contains(message, "alterguard swap users")
+	})).Return(nil)
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+	err := manager.WatchMigration(context.Background(), "users", time.Millisecond)
+
+	require.NoError(t, err)
+	mockSlack.AssertExpectations(t)
+	mockSlack.AssertNotCalled(t, "NotifySuccess", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWatchMigrationFollowsCopyWithoutProcessUntilTriggersAreLeft(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// no_drop_triggers / no_swap_tables ではコピーが終わってもトリガーと _new が残る
+	triggers := []string{"pt_osc_app_users_ins"}
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTriggerNames", "users").Return(triggers, nil)
+	mockDB.On("TableExists", "_users_new").Return(true, nil)
+	// チャンクの合間でプロセスが見えなくても、行数が増えていればコピー中とみなす
+	mockDB.On("CountProcessesReferencingTable", "_users_new").Return(0, nil)
+	mockDB.On("GetTableRowCount", "users").Return(int64(1000), nil)
+	mockDB.On("GetTableRowCount", "_users_new").Return(int64(400), nil).Once()
+	mockDB.On("GetTableRowCount", "_users_new").Return(int64(1000), nil)
+	mockDB.On("GetTableSizeMB", "_users_new").Return(12.5, nil)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStart", "watch", "users", int64(1000)).Return(nil)
+	mockSlack.On("NotifyWatchProgress", "users", int64(1000), int64(1000), 12.5, mock.AnythingOfType("time.Duration")).Return(nil).Once()
+	mockSlack.On("NotifyWarning", "watch", "users", mock.MatchedBy(func(message string) bool {
+		return strings.Contains(message, "pt-osc triggers (pt_osc_app_users_ins) remain") && strings.Contains(message, "stalled")
+	})).Return(nil).Once()
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+	err := manager.WatchMigration(context.Background(), "users", time.Millisecond)
+
+	require.NoError(t, err)
+	mockSlack.AssertExpectations(t)
+	mockSlack.AssertNotCalled(t, "NotifySuccess", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWatchMigrationStopsOnContextCancel(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockDB := &MockDBClient{}
+	mockDB.On("GetTriggerNames", "users").Return([]string{"pt_osc_app_users_ins"}, nil)
+	mockDB.On("TableExists", "_users_new").Return(true, nil)
+	mockDB.On("CountProcessesReferencingTable", "_users_new").Return(1, nil)
+	mockDB.On("GetTableRowCount", "users").Return(int64(1000), nil)
+	mockDB.On("GetTableRowCount", "_users_new").Return(int64(400), nil)
+
+	mockSlack := &MockSlackNotifier{}
+	mockSlack.On("NotifyStart", "watch", "users", int64(1000)).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	manager := NewManager(mockDB, &MockPtOscExecutor{}, &MockPtArchiverExecutor{}, mockSlack, logger, &config.Config{}, false)
+	err := manager.WatchMigration(ctx, "users", time.Hour)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}